@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+
+	"github.com/spf13/cobra"
+)
+
+var attachCmd = &cobra.Command{
+	Use:   "attach",
+	Short: "Attach to the output of the container's main process",
+	Long: `Attach to the output of the workspace container's main process and exit
+with its exit code once it stops. Only useful for images whose entrypoint is
+the workload, which requires "overrideCommand": false. Ctrl-C detaches without
+stopping the container. Use 'crib shell' for keep-alive containers.`,
+	Args: noArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		u := newUI()
+
+		eng, _, store, err := newEngine()
+		if err != nil {
+			return err
+		}
+
+		ws, err := currentWorkspace(store, false)
+		if err != nil {
+			return err
+		}
+
+		u.Dim(versionString())
+
+		// No workspace lock: attaching can last for hours and other commands
+		// (e.g. crib down from another terminal) must still work.
+		u.Header("Attaching to container output (Ctrl-C to detach)")
+		err = eng.Attach(cmd.Context(), ws)
+		if errors.Is(err, context.Canceled) {
+			u.Dim("Detached; the container keeps running")
+			return nil
+		}
+		return err
+	},
+}
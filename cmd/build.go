@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"github.com/fgrehm/crib/internal/engine"
+	"github.com/spf13/cobra"
+)
+
+var (
+	buildImageNameFlag string
+	buildOutputFlag    string
+	buildPushFlag      bool
+	buildSaveFlag      string
+)
+
+var buildCmd = &cobra.Command{
+	Use:   "build",
+	Short: "Build the workspace image without starting a container",
+	Long: `Build the workspace image (Dockerfile plus features) without creating a
+container, e.g. to prebuild it in CI and distribute the result.
+
+  crib build                                              # build and load locally
+  crib build --image-name ghcr.io/org/app:dev --push      # build and push
+  crib build --output type=registry,ref=ghcr.io/org/app:dev
+  crib build --save app.tar                               # docker-loadable archive
+
+--output, --push and --save replace loading the image into the local image
+store and require Docker with buildx (podman supports --output only).
+Compose workspaces are not supported.`,
+	Args: noArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		opts := engine.BuildOptions{
+			ImageName: buildImageNameFlag,
+			Output:    buildOutputFlag,
+			Push:      buildPushFlag,
+			Save:      buildSaveFlag,
+		}
+		if err := opts.Validate(); err != nil {
+			return &errUsage{err: err}
+		}
+
+		u := newUI()
+
+		eng, _, store, err := newEngine()
+		if err != nil {
+			return err
+		}
+		progress, err := attachProgress(u, eng)
+		if err != nil {
+			return err
+		}
+		defer progress.Stop()
+		eng.SetVerbose(verboseFlag || debugFlag)
+		eng.SetCacheTo(cacheToForCommand(cmd))
+		eng.SetKeepGenerated(keepOverrideForCommand(cmd))
+		pull, _ := cmd.Flags().GetBool("pull")
+		eng.SetPull(pull)
+		if err := setBuildSecrets(cmd, eng); err != nil {
+			return err
+		}
+		if err := setBuildContexts(cmd, eng); err != nil {
+			return err
+		}
+
+		ws, err := currentWorkspace(store, true)
+		if err != nil {
+			return err
+		}
+		lock, err := store.Lock(cmd.Context(), ws.ID)
+		if err != nil {
+			return err
+		}
+		defer lock.Unlock() //nolint:errcheck // best-effort cleanup
+
+		u.Dim(versionString())
+		u.Header("Building image")
+
+		image, err := eng.Build(cmd.Context(), ws, opts)
+		progress.Stop()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case opts.Push:
+			u.Success("Pushed " + image)
+		case opts.Save != "":
+			u.Success("Saved " + image + " to " + opts.Save)
+		case opts.Output != "":
+			u.Success("Exported " + image)
+		default:
+			u.Success("Built " + image)
+		}
+		return nil
+	},
+}
+
+func init() {
+	buildCmd.Flags().StringVar(&buildImageNameFlag, "image-name", "", "tag the image with this name instead of crib's own (required by --push)")
+	buildCmd.Flags().StringVar(&buildOutputFlag, "output", "", "export the image with a BuildKit --output spec, e.g. type=registry,ref=ghcr.io/org/app:dev")
+	buildCmd.Flags().BoolVar(&buildPushFlag, "push", false, "push the image to the registry named by --image-name instead of loading it locally")
+	buildCmd.Flags().StringVar(&buildSaveFlag, "save", "", "write the image to a docker-loadable tar archive instead of loading it locally")
+	buildCmd.Flags().Bool("pull", false, "pull newer versions of the base image(s) before building")
+	addCacheToFlag(buildCmd)
+	addSecretFlag(buildCmd)
+	addBuildContextFlag(buildCmd)
+	addKeepOverrideFlag(buildCmd)
+}
@@ -27,7 +27,7 @@ var cacheListCmd = &cobra.Command{
 	RunE: func(cmd *cobra.Command, args []string) error {
 		u := newUI()
 
-		d, err := oci.NewOCIDriver(logger)
+		d, err := oci.NewOCIDriverFor(runtimeFlag, logger)
 		if err != nil {
 			return err
 		}
@@ -93,7 +93,7 @@ var cacheCleanCmd = &cobra.Command{
 	RunE: func(cmd *cobra.Command, args []string) error {
 		u := newUI()
 
-		d, err := oci.NewOCIDriver(logger)
+		d, err := oci.NewOCIDriverFor(runtimeFlag, logger)
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/fgrehm/crib/internal/workspace"
+	"github.com/spf13/cobra"
+)
+
+var (
+	cleanAllFlag    bool
+	cleanDryRunFlag bool
+)
+
+var cleanCmd = &cobra.Command{
+	Use:   "clean",
+	Short: "Remove temporary files crib left in the project",
+	Long: `Remove the temporary files crib writes into a project during builds
+(.crib-Dockerfile, the .crib-features directory and the compose overrides
+written by older versions) when a failed or killed run left them behind.
+
+The project root, the devcontainer config directory and the build context
+are checked. Only entries with those exact names are removed; nothing else
+is touched. Use --all to clean the projects of every known workspace and
+--dry-run to list the files without removing them.`,
+	Args: noArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		u := newUI()
+
+		eng, _, store, err := newEngine()
+		if err != nil {
+			return err
+		}
+
+		var workspaces []*workspace.Workspace
+		if cleanAllFlag {
+			ids, err := store.List()
+			if err != nil {
+				return err
+			}
+			for _, id := range ids {
+				ws, err := store.Load(id)
+				if err != nil {
+					u.Error(id + ": " + err.Error())
+					continue
+				}
+				workspaces = append(workspaces, ws)
+			}
+		} else {
+			ws, err := currentWorkspace(store, false)
+			if err != nil {
+				return err
+			}
+			workspaces = append(workspaces, ws)
+		}
+
+		verb := "Removed"
+		if cleanDryRunFlag {
+			verb = "Would remove"
+		}
+		total := 0
+		for _, ws := range workspaces {
+			// Hold the lock so a build in progress keeps its files.
+			lock, err := store.Lock(cmd.Context(), ws.ID)
+			if err != nil {
+				return err
+			}
+			removed, err := eng.Clean(ws, cleanDryRunFlag)
+			lock.Unlock() //nolint:errcheck // best-effort cleanup
+			for _, path := range removed {
+				u.Success(verb + " " + path)
+			}
+			total += len(removed)
+			if err != nil {
+				return fmt.Errorf("cleaning %s: %w", ws.ID, err)
+			}
+		}
+
+		if total == 0 {
+			u.Dim("Nothing to clean")
+		}
+		return nil
+	},
+}
+
+func init() {
+	cleanCmd.Flags().BoolVar(&cleanAllFlag, "all", false, "clean the projects of every known workspace")
+	cleanCmd.Flags().BoolVar(&cleanDryRunFlag, "dry-run", false, "list the files that would be removed without removing them")
+}
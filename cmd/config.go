@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect the devcontainer config",
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check the devcontainer config and the files it references",
+	Long: `Check the devcontainer config without building or starting anything.
+
+Verifies that exactly one of image, build.dockerfile, or dockerComposeFile is
+set, that the referenced Dockerfile, compose files, and local features exist,
+and that the compose service is defined. Exits non-zero when problems are found.`,
+	Args: noArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		u := newUI()
+
+		eng, _, store, err := newEngine()
+		if err != nil {
+			return err
+		}
+
+		ws, err := currentWorkspace(store, true)
+		if err != nil {
+			return err
+		}
+
+		problems, err := eng.Validate(cmd.Context(), ws)
+		if err != nil {
+			return err
+		}
+
+		path := filepath.Join(ws.Source, ws.DevContainerPath)
+		if len(problems) == 0 {
+			u.Success("Config is valid: " + path)
+			return nil
+		}
+		u.Header(path)
+		for _, p := range problems {
+			u.Error(p)
+		}
+		return fmt.Errorf("found %d problem(s) in the devcontainer config", len(problems))
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configValidateCmd)
+}
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/fgrehm/crib/internal/config"
+	"github.com/fgrehm/crib/internal/workspace"
+	"github.com/spf13/cobra"
+)
+
+var configsCmd = &cobra.Command{
+	Use:   "configs",
+	Short: "List the devcontainer configs found in the project",
+	Long: `List the devcontainer configs found in the project.
+
+A project can hold several configs under .devcontainer/<name>/devcontainer.json.
+Pick one with --config, e.g. crib up --config .devcontainer/python.`,
+	Args: noArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		u := newUI()
+
+		start := dirFlag
+		if start == "" {
+			cwd, err := os.Getwd()
+			if err != nil {
+				return fmt.Errorf("getting working directory: %w", err)
+			}
+			start = cwd
+		}
+		root, paths, err := findProjectConfigs(start)
+		if err != nil {
+			return err
+		}
+
+		rows := make([][]string, 0, len(paths))
+		for _, p := range paths {
+			rel, _ := filepath.Rel(root, p)
+			rows = append(rows, []string{filepath.Dir(rel), configName(p)})
+		}
+		u.Table([]string{"CONFIG", "NAME"}, rows)
+		return nil
+	},
+}
+
+// findProjectConfigs walks up from startDir to the first directory holding
+// any devcontainer config and returns it with all configs found there.
+func findProjectConfigs(startDir string) (string, []string, error) {
+	dir, err := filepath.Abs(startDir)
+	if err != nil {
+		return "", nil, fmt.Errorf("resolving start directory: %w", err)
+	}
+	for {
+		paths, err := config.FindAll(dir)
+		if err != nil {
+			return "", nil, err
+		}
+		if len(paths) > 0 {
+			return dir, paths, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil, workspace.ErrNoDevContainer
+		}
+		dir = parent
+	}
+}
+
+// configName returns the "name" property of the config at path, or "-" when
+// it is unset or the config cannot be parsed.
+func configName(path string) string {
+	cfg, err := config.Parse(path)
+	if err != nil || cfg.Name == "" {
+		return "-"
+	}
+	return cfg.Name
+}
+
+// pickConfig asks the user to choose one of the ambiguous configs in multi
+// and returns its path.
+func pickConfig(r io.Reader, w io.Writer, multi *config.ErrMultipleConfigs) (string, error) {
+	var prompt strings.Builder
+	prompt.WriteString("Multiple devcontainer configs found:\n")
+	for i, p := range multi.Candidates {
+		rel, _ := filepath.Rel(multi.Dir, p)
+		fmt.Fprintf(&prompt, "  %d) %s (%s)\n", i+1, filepath.Dir(rel), configName(p))
+	}
+	fmt.Fprintf(&prompt, "Select a config [1-%d]: ", len(multi.Candidates))
+	if _, err := io.WriteString(w, prompt.String()); err != nil {
+		return "", fmt.Errorf("writing config prompt: %w", err)
+	}
+
+	answer, _ := bufio.NewReader(r).ReadString('\n')
+	n, err := strconv.Atoi(strings.TrimSpace(answer))
+	if err != nil || n < 1 || n > len(multi.Candidates) {
+		return "", fmt.Errorf("invalid selection %q", strings.TrimSpace(answer))
+	}
+	return multi.Candidates[n-1], nil
+}
@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/fgrehm/crib/internal/config"
+)
+
+func writeNestedConfigs(t *testing.T, dir string) {
+	t.Helper()
+	configs := map[string]string{
+		"go":     `{"name": "Go", "image": "golang"}`,
+		"python": `{"image": "python"}`,
+	}
+	for sub, content := range configs {
+		p := filepath.Join(dir, ".devcontainer", sub)
+		if err := os.MkdirAll(p, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(p, "devcontainer.json"), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestFindProjectConfigs_Nested(t *testing.T) {
+	dir := t.TempDir()
+	writeNestedConfigs(t, dir)
+	sub := filepath.Join(dir, "src", "pkg")
+	if err := os.MkdirAll(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	root, paths, err := findProjectConfigs(sub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if root != dir {
+		t.Errorf("root = %q, want %q", root, dir)
+	}
+	want := []string{
+		filepath.Join(dir, ".devcontainer", "go", "devcontainer.json"),
+		filepath.Join(dir, ".devcontainer", "python", "devcontainer.json"),
+	}
+	if !slices.Equal(paths, want) {
+		t.Errorf("paths = %v, want %v", paths, want)
+	}
+	if got := configName(paths[0]); got != "Go" {
+		t.Errorf("configName = %q, want %q", got, "Go")
+	}
+	if got := configName(paths[1]); got != "-" {
+		t.Errorf("configName (unnamed) = %q, want %q", got, "-")
+	}
+}
+
+func TestPickConfig(t *testing.T) {
+	dir := t.TempDir()
+	writeNestedConfigs(t, dir)
+	_, err := config.Find(dir)
+	var multi *config.ErrMultipleConfigs
+	if !errors.As(err, &multi) {
+		t.Fatalf("expected *config.ErrMultipleConfigs, got %v", err)
+	}
+
+	var out bytes.Buffer
+	got, err := pickConfig(strings.NewReader("2\n"), &out, multi)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(dir, ".devcontainer", "python", "devcontainer.json"); got != want {
+		t.Errorf("picked %q, want %q", got, want)
+	}
+	if !strings.Contains(out.String(), "1) "+filepath.Join(".devcontainer", "go")+" (Go)") {
+		t.Errorf("prompt missing numbered entry:\n%s", out.String())
+	}
+
+	for _, answer := range []string{"0\n", "3\n", "go\n", ""} {
+		if _, err := pickConfig(strings.NewReader(answer), &out, multi); err == nil {
+			t.Errorf("answer %q: expected error", answer)
+		}
+	}
+
+	// A prompt that cannot be shown is not answered blindly.
+	pr, pw := io.Pipe()
+	_ = pr.Close()
+	if _, err := pickConfig(strings.NewReader("1\n"), pw, multi); err == nil {
+		t.Error("expected error when the prompt cannot be written")
+	}
+}
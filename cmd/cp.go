@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fgrehm/crib/internal/engine"
+	"github.com/spf13/cobra"
+)
+
+var cpNoCompressFlag bool
+
+var cpCmd = &cobra.Command{
+	Use:   "cp SRC DST",
+	Short: "Copy files between the host and the container",
+	Long: `Copy a file or directory between the host and the workspace container.
+Prefix the container side with ':' (relative paths resolve against the
+workspace folder). SRC is copied into the DST directory, keeping its name.
+
+  crib cp ./fixtures :/tmp        # host -> container (/tmp/fixtures)
+  crib cp :node_modules/.cache .  # container -> host (./.cache)
+
+Trees are streamed as a gzip-compressed tar archive, preserving file modes
+and symlinks. Use --no-compress for content that is already compressed.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		src, srcInContainer := parseCopyPath(args[0])
+		dst, dstInContainer := parseCopyPath(args[1])
+		if srcInContainer == dstInContainer {
+			return &errUsage{err: fmt.Errorf("exactly one of SRC or DST must be a container path (prefixed with ':')")}
+		}
+
+		eng, _, store, err := newEngine()
+		if err != nil {
+			return err
+		}
+
+		ws, err := currentWorkspace(store, false)
+		if err != nil {
+			return err
+		}
+
+		opts := engine.CopyOptions{Compress: !cpNoCompressFlag}
+		if srcInContainer {
+			return eng.CopyFromContainer(cmd.Context(), ws, src, dst, opts)
+		}
+		return eng.CopyToContainer(cmd.Context(), ws, src, dst, opts)
+	},
+}
+
+// parseCopyPath strips the ':' container marker from a cp argument and
+// reports whether it was present.
+func parseCopyPath(arg string) (string, bool) {
+	if p, ok := strings.CutPrefix(arg, ":"); ok {
+		if p == "" {
+			p = "."
+		}
+		return p, true
+	}
+	return arg, false
+}
+
+func init() {
+	cpCmd.Flags().BoolVar(&cpNoCompressFlag, "no-compress", false, "stream an uncompressed tar (for already-compressed content)")
+}
@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"github.com/fgrehm/crib/internal/engine"
+	"github.com/fgrehm/crib/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Show devcontainer.json changes since the last up",
+	Long: `Compare the config used by the last 'crib up' with the current
+devcontainer.json and show each changed property with its impact:
+
+  no-op          takes effect without touching the container
+  safe           'crib restart' recreates the container
+  needs rebuild  the image must be rebuilt with 'crib rebuild'`,
+	Args: noArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		u := newUI()
+
+		eng, _, store, err := newEngine()
+		if err != nil {
+			return err
+		}
+
+		ws, err := currentWorkspace(store, false)
+		if err != nil {
+			return err
+		}
+
+		result, err := eng.Diff(cmd.Context(), ws)
+		if err != nil {
+			return err
+		}
+
+		u.Header(ws.ID)
+		renderDiff(u, result)
+		return nil
+	},
+}
+
+// orDash renders unset values as "-" so table columns stay aligned.
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// renderDiff prints a table of changed fields with their impact followed by
+// a one-line summary of what `crib restart` would do.
+func renderDiff(u *ui.UI, result *engine.DiffResult) {
+	if len(result.Changes) == 0 {
+		u.Success("No changes since last up")
+		return
+	}
+
+	rows := make([][]string, 0, len(result.Changes))
+	for _, c := range result.Changes {
+		rows = append(rows, []string{c.Field, string(c.Impact), orDash(c.Stored), orDash(c.Current)})
+	}
+	u.Table([]string{"FIELD", "IMPACT", "LAST UP", "CURRENT"}, rows)
+
+	switch result.Impact {
+	case engine.ImpactRebuild:
+		u.Dim("Image-affecting changes; run 'crib rebuild'.")
+	case engine.ImpactRecreate:
+		u.Dim("'crib restart' will recreate the container.")
+	default:
+		u.Dim("No container changes needed.")
+	}
+}
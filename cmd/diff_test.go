@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/fgrehm/crib/internal/engine"
+	"github.com/fgrehm/crib/internal/ui"
+)
+
+func TestRenderDiff_ImageAndEnv(t *testing.T) {
+	var out bytes.Buffer
+	u := ui.New(&out, &out)
+
+	renderDiff(u, &engine.DiffResult{
+		Changes: []engine.FieldDiff{
+			{Field: "image", Impact: engine.ImpactRebuild, Stored: "node:18", Current: "node:20"},
+			{Field: "containerEnv", Impact: engine.ImpactRecreate, Stored: `{"FOO":"bar"}`, Current: `{"FOO":"baz"}`},
+		},
+		Impact: engine.ImpactRebuild,
+	})
+
+	got := out.String()
+	for _, want := range []string{
+		"FIELD         IMPACT         LAST UP        CURRENT",
+		"image         needs rebuild  node:18        node:20",
+		`containerEnv  safe           {"FOO":"bar"}  {"FOO":"baz"}`,
+		"run 'crib rebuild'",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestRenderDiff_NoChanges(t *testing.T) {
+	var out bytes.Buffer
+	renderDiff(ui.New(&out, &out), &engine.DiffResult{Impact: engine.ImpactNone})
+	if !strings.Contains(out.String(), "No changes since last up") {
+		t.Errorf("unexpected output: %q", out.String())
+	}
+}
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var diffFSCmd = &cobra.Command{
+	Use:   "diff-fs [PATH...]",
+	Short: "List files changed in the container since it was created",
+	Long: `List the files added (A), changed (C) or deleted (D) in the workspace
+container's filesystem relative to its image, like 'docker diff'. Use it to
+see what lifecycle hooks and manual changes did to the container before
+deciding what belongs in the Dockerfile or a feature.
+
+  crib diff-fs                   # every change
+  crib diff-fs /etc /home/vscode # only changes under these paths
+
+Volumes and bind mounts, including the workspace folder, are not part of
+the container's filesystem and never show up. Works on running and stopped
+containers.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		u := newUI()
+
+		eng, _, store, err := newEngine()
+		if err != nil {
+			return err
+		}
+
+		ws, err := currentWorkspace(store, false)
+		if err != nil {
+			return err
+		}
+
+		changes, err := eng.DiffFS(cmd.Context(), ws, args)
+		if err != nil {
+			return err
+		}
+		if len(changes) == 0 {
+			u.Dim("No changes since the container was created")
+			return nil
+		}
+		for _, c := range changes {
+			fmt.Fprintf(os.Stdout, "%s %s\n", c.Kind, c.Path)
+		}
+		return nil
+	},
+}
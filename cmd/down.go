@@ -1,13 +1,23 @@
 package cmd
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/fgrehm/crib/internal/engine"
+	"github.com/fgrehm/crib/internal/ui"
+	"github.com/fgrehm/crib/internal/workspace"
 	"github.com/spf13/cobra"
 )
 
+var downAllFlag bool
+
 var downCmd = &cobra.Command{
 	Use:   "down",
 	Short: "Stop and remove the workspace container",
-	Long:  "Stop and remove the workspace container. Hook markers are cleared so the next 'up' runs all lifecycle hooks. Use 'stop' for a non-destructive pause.",
+	Long:  "Stop and remove the workspace container. Hook markers are cleared so the next 'up' runs all lifecycle hooks. Use 'stop' for a non-destructive pause. Compose workspaces with \"shutdownAction\": \"stopCompose\" are only stopped, keeping their networks and volumes.",
 	Args:  noArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		u := newUI()
@@ -17,6 +27,11 @@ var downCmd = &cobra.Command{
 			return err
 		}
 
+		if downAllFlag {
+			u.Dim(versionString())
+			return downAll(cmd.Context(), u, store, eng.Shutdown)
+		}
+
 		ws, err := currentWorkspace(store, false)
 		if err != nil {
 			return err
@@ -29,7 +44,10 @@ var downCmd = &cobra.Command{
 
 		u.Dim(versionString())
 
-		if err := eng.Down(cmd.Context(), ws); err != nil {
+		started := time.Now()
+		err = eng.Shutdown(cmd.Context(), ws)
+		recordHistory(store, ws.ID, "down", started, "", err)
+		if err != nil {
 			return err
 		}
 
@@ -37,3 +55,58 @@ var downCmd = &cobra.Command{
 		return nil
 	},
 }
+
+func init() {
+	downCmd.Flags().BoolVar(&downAllFlag, "all", false, "stop and remove the containers of every workspace")
+}
+
+// downAll tears down every workspace in store with down, holding each
+// workspace's lock while it runs. A failure is reported and does not stop
+// the remaining workspaces; the returned error counts the failures.
+func downAll(ctx context.Context, u *ui.UI, store *workspace.Store, down func(context.Context, *workspace.Workspace) error) error {
+	ids, err := store.List()
+	if err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		u.Dim("No workspaces")
+		return nil
+	}
+
+	failed := 0
+	for _, id := range ids {
+		if err := downOne(ctx, store, id, down); err != nil {
+			var nc *engine.ErrNoContainer
+			if errors.As(err, &nc) {
+				u.Dim("  - " + id + ": no container")
+				continue
+			}
+			u.Error(id + ": " + err.Error())
+			failed++
+			continue
+		}
+		u.Success("Stopped " + id)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d workspaces could not be stopped", failed, len(ids))
+	}
+	return nil
+}
+
+// downOne loads and locks workspace id and tears it down with down.
+func downOne(ctx context.Context, store *workspace.Store, id string, down func(context.Context, *workspace.Workspace) error) error {
+	ws, err := store.Load(id)
+	if err != nil {
+		return err
+	}
+	lock, err := store.Lock(ctx, id)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock() //nolint:errcheck // best-effort cleanup
+	started := time.Now()
+	err = down(ctx, ws)
+	recordHistory(store, id, "down", started, "", err)
+	return err
+}
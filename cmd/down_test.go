@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/fgrehm/crib/internal/engine"
+	"github.com/fgrehm/crib/internal/ui"
+	"github.com/fgrehm/crib/internal/workspace"
+)
+
+func TestDownAll(t *testing.T) {
+	store := workspace.NewStoreAt(t.TempDir())
+	for _, id := range []string{"alpha", "bravo", "charlie", "delta"} {
+		if err := store.Save(&workspace.Workspace{ID: id, Source: "/src/" + id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var downed []string
+	down := func(_ context.Context, ws *workspace.Workspace) error {
+		downed = append(downed, ws.ID)
+		switch ws.ID {
+		case "bravo":
+			return errors.New("compose down failed")
+		case "delta":
+			return &engine.ErrNoContainer{WorkspaceID: ws.ID}
+		}
+		return nil
+	}
+
+	var out bytes.Buffer
+	err := downAll(context.Background(), ui.New(&out, &out), store, down)
+	if err == nil || !strings.Contains(err.Error(), "1 of 4") {
+		t.Errorf("error = %v, want one failure out of 4", err)
+	}
+	if want := []string{"alpha", "bravo", "charlie", "delta"}; !slices.Equal(downed, want) {
+		t.Errorf("downed = %v, want %v", downed, want)
+	}
+	for _, want := range []string{
+		"ok Stopped alpha",
+		"error: bravo: compose down failed",
+		"ok Stopped charlie",
+		"delta: no container",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, out.String())
+		}
+	}
+
+	// Each attempt is recorded, except for workspaces without a container.
+	for id, want := range map[string]string{"alpha": workspace.HistoryOK, "bravo": workspace.HistoryError, "delta": ""} {
+		entries, err := store.History(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got string
+		if len(entries) > 0 {
+			got = entries[len(entries)-1].Operation + " " + entries[len(entries)-1].Outcome
+		}
+		if want != "" {
+			want = "down " + want
+		}
+		if got != want {
+			t.Errorf("%s history = %q, want %q", id, got, want)
+		}
+	}
+}
+
+func TestDownAll_NoWorkspaces(t *testing.T) {
+	store := workspace.NewStoreAt(t.TempDir())
+	var out bytes.Buffer
+	err := downAll(context.Background(), ui.New(&out, &out), store, func(context.Context, *workspace.Workspace) error {
+		t.Error("down should not be called")
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "No workspaces") {
+		t.Errorf("output = %q, want 'No workspaces'", out.String())
+	}
+}
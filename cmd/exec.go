@@ -2,13 +2,20 @@ package cmd
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"strings"
 	"syscall"
 
 	"github.com/charmbracelet/x/term"
+	"github.com/fgrehm/crib/internal/driver"
+	"github.com/fgrehm/crib/internal/driver/oci"
+	"github.com/fgrehm/crib/internal/engine"
+	"github.com/fgrehm/crib/internal/ui"
+	"github.com/fgrehm/crib/internal/workspace"
 	"github.com/spf13/cobra"
 )
 
@@ -19,9 +26,30 @@ var execCmd = &cobra.Command{
 
 Use -- to separate crib flags from the container command:
   crib exec -- bash
-  crib exec -- bash -c "echo hello"`,
+  crib exec -- bash -c "echo hello"
+  crib exec -d -- npm run watch
+
+A stopped container is not started unless --up is given, in which case it is
+started the way 'crib up' resumes it (postStartCommand and postAttachCommand
+run) before the command. A workspace that has no container yet still needs
+'crib up'.
+
+The environment (PATH and friends) comes from the probe 'crib up' ran after
+the lifecycle hooks. --refresh-env probes it again and saves it first, so
+tools installed since then are found by this and later commands.`,
 	Args: cobra.ArbitraryArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		detach, _ := cmd.Flags().GetBool("detach")
+		interactive, _ := cmd.Flags().GetBool("interactive")
+		tty, _ := cmd.Flags().GetBool("tty")
+		modeArgs, err := execModeArgs(detach, interactive, tty, stdinIsTerminal())
+		if err != nil {
+			return err
+		}
+		if cmd.Flags().Changed("workdir-create") && !cmd.Flags().Changed("workdir") {
+			return &errUsage{err: fmt.Errorf("--workdir-create requires --workdir")}
+		}
+
 		eng, ociDrv, store, err := newEngine()
 		if err != nil {
 			return err
@@ -32,7 +60,12 @@ Use -- to separate crib flags from the container command:
 			return err
 		}
 
-		container, err := eng.RequireRunningContainer(cmd.Context(), ws)
+		var container *driver.ContainerDetails
+		if up, _ := cmd.Flags().GetBool("up"); up {
+			container, err = startForExec(cmd, eng, ociDrv, store, ws)
+		} else {
+			container, err = eng.RequireRunningContainer(cmd.Context(), ws)
+		}
 		if err != nil {
 			return err
 		}
@@ -48,16 +81,17 @@ Use -- to separate crib flags from the container command:
 		}
 
 		// Replace the current process with docker/podman exec.
-		// Only allocate stdin (-i) and pseudo-TTY (-t) when stdin is an
-		// interactive terminal. Omitting both allows non-interactive use
-		// (pipes, scripts, CI).
-		execArgs := []string{runtimeBin, "exec"}
-		if stdinIsTerminal() {
-			execArgs = append(execArgs, "-i", "-t")
-		}
+		execArgs := append([]string{runtimeBin, "exec"}, modeArgs...)
 
 		// Inject remoteEnv variables (before user-specified --env so user flags take precedence).
-		result, _ := store.LoadResult(ws.ID)
+		var result *workspace.Result
+		if refresh, _ := cmd.Flags().GetBool("refresh-env"); refresh {
+			if result, err = eng.RefreshEnv(cmd.Context(), ws, container.ID); err != nil {
+				return err
+			}
+		} else {
+			result, _ = store.LoadResult(ws.ID)
+		}
 
 		// Determine user: explicit --user flag, then live config, then stored result.
 		user, _ := cmd.Flags().GetString("user")
@@ -73,6 +107,11 @@ Use -- to separate crib flags from the container command:
 
 		// Add workdir: explicit flag takes precedence, otherwise use workspace folder.
 		workdir, _ := cmd.Flags().GetString("workdir")
+		if create, _ := cmd.Flags().GetBool("workdir-create"); create {
+			if err := eng.CreateWorkdir(cmd.Context(), ws, container.ID, user, workdir); err != nil {
+				return err
+			}
+		}
 		if workdir == "" && result != nil && result.WorkspaceFolder != "" {
 			workdir = result.WorkspaceFolder
 		}
@@ -102,18 +141,118 @@ Use -- to separate crib flags from the container command:
 		execArgs = append(execArgs, container.ID)
 		execArgs = append(execArgs, shellArgs...)
 
-		// syscall.Exec replaces the current process with the container runtime.
-		// On success it never returns; the only return path is an error.
-		return syscall.Exec(runtimeBin, execArgs, os.Environ())
+		// With --detach the runtime returns as soon as the command is
+		// launched, so the exit status reports whether launching succeeded.
+		return execRuntime(runtimeBin, execArgs)
 	},
 }
 
 func init() {
 	execCmd.Flags().StringP("user", "u", "", "Username or UID (format: \"<name|uid>[:<group|gid>]\")")
 	execCmd.Flags().StringP("workdir", "w", "", "Working directory inside the container")
+	execCmd.Flags().Bool("workdir-create", false, "Create the --workdir directory (as the exec user) if it does not exist")
 	execCmd.Flags().StringSliceP("env", "e", nil, "Set environment variables")
 	execCmd.Flags().StringSlice("env-file", nil, "Read in a file of environment variables")
 	execCmd.Flags().Bool("privileged", false, "Give extended privileges to the command")
+	execCmd.Flags().BoolP("detach", "d", false, "Run the command in the background and return immediately")
+	execCmd.Flags().BoolP("interactive", "i", false, "Keep stdin open (default: only when stdin is a terminal)")
+	execCmd.Flags().BoolP("tty", "t", false, "Allocate a pseudo-TTY (default: only when stdin is a terminal)")
+	execCmd.Flags().Bool("refresh-env", false, "Probe the container environment again and store it before running the command")
+	execCmd.Flags().Bool("up", false, "Start the container first if it is stopped (never creates one)")
+}
+
+// execRuntime runs the container runtime with args (args[0] being the
+// program name) in place of crib, so the command's exit status becomes
+// crib's. syscall.Exec replaces the current process and never returns on
+// success. Where the process cannot be replaced (e.g. on Windows), the
+// runtime runs as a child with crib's stdio instead and a non-zero exit is
+// returned as an *errExitStatus.
+func execRuntime(runtimeBin string, args []string) error {
+	err := syscall.Exec(runtimeBin, args, os.Environ())
+	logger.Debug("replacing the process failed, running the runtime as a child", "error", err)
+	return runRuntime(runtimeBin, args, os.Stdin, os.Stdout, os.Stderr)
+}
+
+// runRuntime runs runtimeBin as a child process and maps its exit status:
+// a non-zero exit becomes an *errExitStatus with the same code, and death
+// by a signal the shell's 128+signal code. When stdin is a terminal, its
+// resizes are forwarded to the child while it runs.
+func runRuntime(runtimeBin string, args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	c := exec.Command(runtimeBin, args[1:]...)
+	c.Stdin, c.Stdout, c.Stderr = stdin, stdout, stderr
+	if err := c.Start(); err != nil {
+		return err
+	}
+	if f, ok := stdin.(*os.File); ok {
+		defer watchResizes(f, c.Process)()
+	}
+	err := c.Wait()
+	var ee *exec.ExitError
+	if !errors.As(err, &ee) {
+		return err
+	}
+	code := ee.ExitCode()
+	if ws, ok := ee.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+		code = 128 + int(ws.Signal())
+	}
+	return &errExitStatus{code: code}
+}
+
+// startForExec returns the workspace container, starting it first when it
+// is stopped. Progress and hook output go to stderr so the command's stdout
+// stays clean for pipes. The workspace lock is released on return, before
+// the process is replaced by the runtime.
+func startForExec(cmd *cobra.Command, eng *engine.Engine, d *oci.OCIDriver, store *workspace.Store, ws *workspace.Workspace) (*driver.ContainerDetails, error) {
+	lock, err := store.Lock(cmd.Context(), ws.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Unlock() //nolint:errcheck // best-effort cleanup
+
+	u := ui.New(os.Stderr, os.Stderr)
+	eng.SetOutput(os.Stderr, os.Stderr)
+	eng.SetProgress(execProgress(u))
+	setupPlugins(cmd, eng, d)
+	return eng.EnsureRunningContainer(cmd.Context(), ws)
+}
+
+// execProgress reports engine progress while exec starts the container.
+// Step timings are only shown by 'crib up --timings', so they are dropped.
+func execProgress(u *ui.UI) func(engine.ProgressEvent) {
+	return func(ev engine.ProgressEvent) {
+		if ev.Phase == engine.PhaseTimings {
+			return
+		}
+		u.Dim(ev.Message)
+	}
+}
+
+// execModeArgs returns the runtime exec flags controlling stdin, the TTY,
+// and detaching. Without explicit flags, stdin (-i) and a pseudo-TTY (-t)
+// are only allocated when stdin is an interactive terminal, which allows
+// non-interactive use (pipes, scripts, CI). A detached command has no
+// terminal to talk to, so --detach rejects --interactive and --tty.
+func execModeArgs(detach, interactive, tty, stdinTerminal bool) ([]string, error) {
+	if detach {
+		if interactive || tty {
+			return nil, &errUsage{err: fmt.Errorf("--detach cannot be combined with --interactive or --tty")}
+		}
+		return []string{"-d"}, nil
+	}
+	if !interactive && !tty {
+		if stdinTerminal {
+			return []string{"-i", "-t"}, nil
+		}
+		return nil, nil
+	}
+	var args []string
+	if interactive {
+		args = append(args, "-i")
+	}
+	if tty {
+		args = append(args, "-t")
+	}
+	return args, nil
 }
 
 // stdinIsTerminal reports whether stdin is an interactive terminal.
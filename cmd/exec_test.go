@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os/exec"
+	"slices"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/fgrehm/crib/internal/engine"
+	"github.com/fgrehm/crib/internal/ui"
+)
+
+func TestExecModeArgs(t *testing.T) {
+	tests := []struct {
+		name          string
+		detach        bool
+		interactive   bool
+		tty           bool
+		stdinTerminal bool
+		want          []string
+	}{
+		{name: "terminal", stdinTerminal: true, want: []string{"-i", "-t"}},
+		{name: "pipe", want: nil},
+		{name: "explicit interactive", interactive: true, want: []string{"-i"}},
+		{name: "explicit tty on terminal", tty: true, stdinTerminal: true, want: []string{"-t"}},
+		{name: "detach", detach: true, want: []string{"-d"}},
+		{name: "detach from terminal", detach: true, stdinTerminal: true, want: []string{"-d"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := execModeArgs(tt.detach, tt.interactive, tt.tty, tt.stdinTerminal)
+			if err != nil {
+				t.Fatalf("execModeArgs: %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("execModeArgs = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecModeArgs_DetachRejectsStdinAndTTY(t *testing.T) {
+	for _, flags := range [][2]bool{{true, false}, {false, true}, {true, true}} {
+		_, err := execModeArgs(true, flags[0], flags[1], true)
+		var usage *errUsage
+		if !errors.As(err, &usage) {
+			t.Errorf("interactive=%v tty=%v: expected usage error, got %v", flags[0], flags[1], err)
+		}
+	}
+}
+
+func TestRunRuntime_ExitStatus(t *testing.T) {
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("sh not available")
+	}
+	tests := []struct {
+		script string
+		want   int // 0 means no error
+	}{
+		{"exit 0", 0},
+		{"exit 7", 7},
+		{"exit 255", 255},
+		{"kill -TERM $$", 128 + int(syscall.SIGTERM)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.script, func(t *testing.T) {
+			err := runRuntime(sh, []string{"sh", "-c", tt.script}, nil, io.Discard, io.Discard)
+			if tt.want == 0 {
+				if err != nil {
+					t.Fatalf("runRuntime: %v", err)
+				}
+				return
+			}
+			var es *errExitStatus
+			if !errors.As(err, &es) {
+				t.Fatalf("runRuntime error = %v, want *errExitStatus", err)
+			}
+			if es.code != tt.want {
+				t.Errorf("exit status = %d, want %d", es.code, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunRuntime_StartFailure(t *testing.T) {
+	err := runRuntime("/nonexistent/runtime", []string{"runtime", "exec"}, nil, io.Discard, io.Discard)
+	var es *errExitStatus
+	if err == nil || errors.As(err, &es) {
+		t.Errorf("runRuntime error = %v, want a start error", err)
+	}
+}
+
+func TestExecProgress_DropsTimings(t *testing.T) {
+	var buf bytes.Buffer
+	report := execProgress(ui.New(&buf, &buf))
+	report(engine.ProgressEvent{Phase: engine.PhaseTimings, Message: "env probe", Duration: time.Second})
+	report(engine.ProgressEvent{Phase: engine.PhaseCreate, Message: "Starting container..."})
+
+	out := buf.String()
+	if strings.Contains(out, "env probe") {
+		t.Errorf("timing event printed: %q", out)
+	}
+	if !strings.Contains(out, "Starting container...") {
+		t.Errorf("progress event missing: %q", out)
+	}
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"github.com/fgrehm/crib/internal/engine"
+	"github.com/spf13/cobra"
+)
+
+var exportOutputFlag string
+
+var exportCmd = &cobra.Command{
+	Use:   "export [TAG]",
+	Short: "Save the workspace container as an image or tarball",
+	Long: `Snapshot the workspace container, including changes made inside it since
+it was created, so it can be reused or shared.
+
+  crib export myapp:dev               # commit as a local image
+  crib export --output workspace.tar  # write the filesystem as a tar archive
+
+The committed image keeps the container's config (env, user, entrypoint) but
+not crib's workspace labels, so 'crib prune' and 'crib remove' leave it alone.
+A tar archive holds only the filesystem; load it with 'docker import'.
+Works on running and stopped containers; volumes are not included.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		opts := engine.ExportOptions{Output: exportOutputFlag}
+		if len(args) == 1 {
+			opts.Tag = args[0]
+		}
+		if err := opts.Validate(); err != nil {
+			return &errUsage{err: err}
+		}
+
+		u := newUI()
+
+		eng, _, store, err := newEngine()
+		if err != nil {
+			return err
+		}
+
+		ws, err := currentWorkspace(store, false)
+		if err != nil {
+			return err
+		}
+		lock, err := store.Lock(cmd.Context(), ws.ID)
+		if err != nil {
+			return err
+		}
+		defer lock.Unlock() //nolint:errcheck // best-effort cleanup
+
+		if err := eng.Export(cmd.Context(), ws, opts); err != nil {
+			return err
+		}
+
+		if opts.Output != "" {
+			u.Success("Exported " + ws.ID + " to " + opts.Output)
+		} else {
+			u.Success("Committed " + ws.ID + " as " + opts.Tag)
+		}
+		return nil
+	},
+}
+
+func init() {
+	exportCmd.Flags().StringVarP(&exportOutputFlag, "output", "o", "", "write the container filesystem to a tar archive instead of committing an image")
+}
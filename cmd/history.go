@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/fgrehm/crib/internal/engine"
+	"github.com/fgrehm/crib/internal/workspace"
+	"github.com/spf13/cobra"
+)
+
+var historyWorkspaceFlag string
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Show recent operations on the workspace",
+	Long: `Show recent up, down, rebuild and restart operations on the workspace,
+oldest first, with when they ran, how long they took and whether they
+succeeded. crib keeps the last 100 operations per workspace.
+
+Use --workspace to show the history of another workspace by ID (see
+'crib list').`,
+	Args: noArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		u := newUI()
+
+		store, err := workspace.NewStore()
+		if err != nil {
+			return err
+		}
+
+		var ws *workspace.Workspace
+		if historyWorkspaceFlag != "" {
+			ws, err = store.Load(historyWorkspaceFlag)
+		} else {
+			ws, err = currentWorkspace(store, false)
+		}
+		if err != nil {
+			return err
+		}
+
+		entries, err := store.History(ws.ID)
+		if err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			u.Dim("No recorded operations for " + ws.ID)
+			return nil
+		}
+
+		u.Table([]string{"TIME", "OPERATION", "OUTCOME", "DURATION", "DETAIL"}, historyRows(entries))
+		return nil
+	},
+}
+
+func init() {
+	historyCmd.Flags().StringVar(&historyWorkspaceFlag, "workspace", "", "show the history of the workspace with this ID instead of the current one")
+}
+
+// historyRows builds the crib history table rows for entries.
+func historyRows(entries []workspace.HistoryEntry) [][]string {
+	rows := make([][]string, 0, len(entries))
+	for _, e := range entries {
+		detail := e.Detail
+		if e.Error != "" {
+			detail = e.Error
+		}
+		if detail == "" {
+			detail = "-"
+		}
+		rows = append(rows, []string{
+			e.Time.Local().Format("2006-01-02 15:04:05"),
+			e.Operation,
+			e.Outcome,
+			e.Duration.Round(time.Second).String(),
+			detail,
+		})
+	}
+	return rows
+}
+
+// recordHistory appends the outcome of operation op on workspace id, which
+// started at started, to the workspace's history. A workspace without a
+// container is not recorded, since nothing happened to it. Failures to
+// record are only logged.
+func recordHistory(store *workspace.Store, id, op string, started time.Time, detail string, opErr error) {
+	var noContainer *engine.ErrNoContainer
+	if errors.As(opErr, &noContainer) {
+		return
+	}
+	entry := workspace.HistoryEntry{
+		Time:      started,
+		Operation: op,
+		Outcome:   workspace.HistoryOK,
+		Duration:  time.Since(started),
+		Detail:    detail,
+	}
+	if opErr != nil {
+		entry.Outcome = workspace.HistoryError
+		entry.Error = firstErrorLine(opErr)
+	}
+	if err := store.AppendHistory(id, entry); err != nil {
+		logger.Debug("failed to record history", "workspace", id, "error", err)
+	}
+}
+
+// firstErrorLine returns the first line of err's message, keeping history
+// entries to one line when errors embed command output.
+func firstErrorLine(err error) string {
+	line, _, _ := strings.Cut(err.Error(), "\n")
+	return line
+}
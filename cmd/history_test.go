@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/fgrehm/crib/internal/workspace"
+)
+
+func TestHistoryRows(t *testing.T) {
+	at := time.Date(2026, 5, 1, 12, 0, 0, 0, time.Local)
+	rows := historyRows([]workspace.HistoryEntry{
+		{Time: at, Operation: "up", Outcome: workspace.HistoryOK, Duration: 83400 * time.Millisecond, Detail: "--recreate"},
+		{Time: at, Operation: "restart", Outcome: workspace.HistoryError, Duration: time.Second, Error: "container setup: hook failed"},
+		{Time: at, Operation: "down", Outcome: workspace.HistoryOK},
+	})
+	want := [][]string{
+		{"2026-05-01 12:00:00", "up", "ok", "1m23s", "--recreate"},
+		{"2026-05-01 12:00:00", "restart", "error", "1s", "container setup: hook failed"},
+		{"2026-05-01 12:00:00", "down", "ok", "0s", "-"},
+	}
+	if !slices.EqualFunc(rows, want, slices.Equal) {
+		t.Errorf("rows = %q, want %q", rows, want)
+	}
+}
+
+func TestRecordHistory(t *testing.T) {
+	store := workspace.NewStoreAt(t.TempDir())
+	started := time.Now().Add(-2 * time.Second)
+
+	recordHistory(store, "ws1", "up", started, "--recreate", nil)
+	recordHistory(store, "ws1", "restart", started, "", errors.New("hook failed\nnpm ERR! details"))
+
+	entries, err := store.History("ws1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(entries))
+	}
+	if e := entries[0]; e.Operation != "up" || e.Outcome != workspace.HistoryOK || e.Detail != "--recreate" || e.Duration < 2*time.Second {
+		t.Errorf("first entry = %+v", e)
+	}
+	if e := entries[1]; e.Outcome != workspace.HistoryError || e.Error != "hook failed" {
+		t.Errorf("second entry = %+v, want the first error line", e)
+	}
+}
@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/fgrehm/crib/internal/driver/oci"
+	"github.com/fgrehm/crib/internal/ui"
+	"github.com/fgrehm/crib/internal/workspace"
+	"github.com/spf13/cobra"
+)
+
+var infoCmd = &cobra.Command{
+	Use:   "info",
+	Short: "Show crib's version and the detected container environment",
+	Long: `Show crib's version along with the container runtime and compose it
+detected, whether the runtime is rootless, the architecture images are built
+for and where crib keeps its state (CRIB_HOME).
+
+Include this output when reporting a bug. A probe that fails is reported in
+the output instead of failing the command.`,
+	Args: noArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var rt runtimeProbe
+		var cp composeProbe
+		d, composeHelper, rtErr := newRuntime()
+		if rtErr == nil {
+			rt = d
+			if composeHelper != nil {
+				cp = composeHelper
+			}
+		}
+		store, err := workspace.NewStore()
+		if err != nil {
+			return err
+		}
+
+		info := gatherInfo(cmd.Context(), rt, rtErr, cp, store)
+		printInfo(cmd.OutOrStdout(), newUI(), info)
+		return nil
+	},
+}
+
+// runtimeProbe is what crib info asks the container runtime. *oci.OCIDriver
+// implements it.
+type runtimeProbe interface {
+	Runtime() oci.Runtime
+	RuntimeCommand() string
+	RuntimeVersion(ctx context.Context) (string, error)
+	Rootless(ctx context.Context) (bool, error)
+	TargetArchitecture(ctx context.Context) (string, error)
+}
+
+// composeProbe is what crib info asks the compose CLI. *compose.Helper
+// implements it.
+type composeProbe interface {
+	CommandName() string
+	Version() string
+}
+
+// environmentInfo is what crib info reports.
+type environmentInfo struct {
+	Runtime        string // "docker" or "podman"; empty when none was found
+	RuntimeCommand string
+	RuntimeVersion string
+	RuntimeErr     error  // why no runtime was found
+	Rootless       string // "yes", "no" or "unknown"
+	Arch           string
+	Compose        string // e.g. "docker compose"; empty when unavailable
+	ComposeVersion string
+	Home           string
+	ExplicitHome   bool // Home comes from CRIB_HOME
+}
+
+// gatherInfo probes rt and cp. rt is nil when no runtime was found, with
+// the reason in rtErr; cp is nil when compose is not available.
+func gatherInfo(ctx context.Context, rt runtimeProbe, rtErr error, cp composeProbe, store *workspace.Store) environmentInfo {
+	info := environmentInfo{
+		RuntimeErr:   rtErr,
+		Home:         filepath.Dir(store.BaseDir()),
+		ExplicitHome: store.IsExplicitHome(),
+	}
+	if rt == nil {
+		return info
+	}
+
+	info.Runtime = rt.Runtime().String()
+	info.RuntimeCommand = rt.RuntimeCommand()
+	if v, err := rt.RuntimeVersion(ctx); err == nil {
+		info.RuntimeVersion = v
+	}
+	info.Rootless = "unknown"
+	if rootless, err := rt.Rootless(ctx); err == nil {
+		info.Rootless = "no"
+		if rootless {
+			info.Rootless = "yes"
+		}
+	}
+	info.Arch, _ = rt.TargetArchitecture(ctx)
+	if cp != nil {
+		info.Compose = cp.CommandName()
+		info.ComposeVersion = cp.Version()
+	}
+	return info
+}
+
+// printInfo writes info in the style of crib version: a version line
+// followed by one key per line.
+func printInfo(w io.Writer, u *ui.UI, info environmentInfo) {
+	_, _ = fmt.Fprintln(w, "crib "+version)
+	u.Keyval("commit", commit)
+	u.Keyval("go", runtime.Version())
+	u.Keyval("os", runtime.GOOS+"/"+runtime.GOARCH)
+
+	if info.Runtime == "" {
+		reason := "no runtime detected"
+		if info.RuntimeErr != nil {
+			reason = firstErrorLine(info.RuntimeErr)
+		}
+		u.Keyval("runtime", "not found: "+reason)
+	} else {
+		rt := []string{info.Runtime}
+		if info.RuntimeVersion != "" {
+			rt = append(rt, info.RuntimeVersion)
+		}
+		if info.RuntimeCommand != "" {
+			rt = append(rt, "("+info.RuntimeCommand+")")
+		}
+		u.Keyval("runtime", strings.Join(rt, " "))
+		u.Keyval("rootless", info.Rootless)
+		if info.Arch != "" {
+			u.Keyval("arch", info.Arch)
+		}
+		compose := "not available"
+		if info.Compose != "" {
+			compose = strings.TrimSpace(info.Compose + " " + info.ComposeVersion)
+		}
+		u.Keyval("compose", compose)
+	}
+
+	home := info.Home
+	if info.ExplicitHome {
+		home += " (CRIB_HOME)"
+	}
+	u.Keyval("home", home)
+}
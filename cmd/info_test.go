@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fgrehm/crib/internal/driver/oci"
+	"github.com/fgrehm/crib/internal/ui"
+	"github.com/fgrehm/crib/internal/workspace"
+)
+
+type fakeRuntimeProbe struct {
+	runtime     oci.Runtime
+	version     string
+	versionErr  error
+	rootless    bool
+	rootlessErr error
+	arch        string
+}
+
+func (f *fakeRuntimeProbe) Runtime() oci.Runtime   { return f.runtime }
+func (f *fakeRuntimeProbe) RuntimeCommand() string { return "/usr/bin/" + f.runtime.String() }
+func (f *fakeRuntimeProbe) RuntimeVersion(context.Context) (string, error) {
+	return f.version, f.versionErr
+}
+func (f *fakeRuntimeProbe) Rootless(context.Context) (bool, error) { return f.rootless, f.rootlessErr }
+func (f *fakeRuntimeProbe) TargetArchitecture(context.Context) (string, error) {
+	return f.arch, nil
+}
+
+type fakeComposeProbe struct{ name, version string }
+
+func (f fakeComposeProbe) CommandName() string { return f.name }
+func (f fakeComposeProbe) Version() string     { return f.version }
+
+func formatInfo(t *testing.T, rt runtimeProbe, rtErr error, cp composeProbe, store *workspace.Store) string {
+	t.Helper()
+	var buf bytes.Buffer
+	printInfo(&buf, ui.New(&buf, &buf), gatherInfo(context.Background(), rt, rtErr, cp, store))
+	return buf.String()
+}
+
+func TestInfo_Runtime(t *testing.T) {
+	t.Setenv("CRIB_HOME", t.TempDir())
+	store, err := workspace.NewStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rt := &fakeRuntimeProbe{runtime: oci.RuntimePodman, version: "5.2.1", rootless: true, arch: "arm64"}
+	out := formatInfo(t, rt, nil, fakeComposeProbe{"podman-compose", "1.2.0"}, store)
+
+	for _, want := range []string{
+		"crib " + version,
+		"podman 5.2.1 (/usr/bin/podman)",
+		"rootless",
+		"yes",
+		"arm64",
+		"podman-compose 1.2.0",
+		filepath.Dir(store.BaseDir()) + " (CRIB_HOME)",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestInfo_ProbeFailures(t *testing.T) {
+	store := workspace.NewStoreAt(filepath.Join(t.TempDir(), "workspaces"))
+	rt := &fakeRuntimeProbe{
+		runtime:     oci.RuntimeDocker,
+		versionErr:  errors.New("permission denied"),
+		rootlessErr: errors.New("permission denied"),
+		arch:        "amd64",
+	}
+	out := formatInfo(t, rt, nil, nil, store)
+
+	for _, want := range []string{"docker (/usr/bin/docker)", "unknown", "not available"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "CRIB_HOME") {
+		t.Errorf("default home reported as CRIB_HOME:\n%s", out)
+	}
+}
+
+func TestInfo_NoRuntime(t *testing.T) {
+	store := workspace.NewStoreAt(filepath.Join(t.TempDir(), "workspaces"))
+	out := formatInfo(t, nil, errors.New("no container runtime found\ninstall docker or podman"), nil, store)
+
+	if !strings.Contains(out, "not found: no container runtime found") {
+		t.Errorf("output missing runtime error:\n%s", out)
+	}
+	for _, unwanted := range []string{"rootless", "compose", "install docker"} {
+		if strings.Contains(out, unwanted) {
+			t.Errorf("output has %q without a runtime:\n%s", unwanted, out)
+		}
+	}
+}
@@ -30,18 +30,21 @@ var listCmd = &cobra.Command{
 			return nil
 		}
 
-		headers := []string{"WORKSPACE", "SOURCE"}
-		var rows [][]string
-		for _, id := range ids {
-			ws, err := store.Load(id)
-			if err != nil {
-				rows = append(rows, []string{id, fmt.Sprintf("(error: %v)", err)})
-				continue
-			}
-			rows = append(rows, []string{ws.ID, ws.Source})
-		}
-		u.Table(headers, rows)
-
+		u.Table([]string{"WORKSPACE", "NAME", "SOURCE"}, listRows(store, ids))
 		return nil
 	},
 }
+
+// listRows builds the crib list table rows for the workspaces in ids.
+func listRows(store *workspace.Store, ids []string) [][]string {
+	rows := make([][]string, 0, len(ids))
+	for _, id := range ids {
+		ws, err := store.Load(id)
+		if err != nil {
+			rows = append(rows, []string{id, "-", fmt.Sprintf("(error: %v)", err)})
+			continue
+		}
+		rows = append(rows, []string{ws.ID, displayWorkspaceName(store, ws.ID), ws.Source})
+	}
+	return rows
+}
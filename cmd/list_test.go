@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/fgrehm/crib/internal/workspace"
+)
+
+func TestListRows_ShowsStoredName(t *testing.T) {
+	store := workspace.NewStoreAt(t.TempDir())
+	for _, id := range []string{"api", "scratch", "fresh"} {
+		if err := store.Save(&workspace.Workspace{ID: id, Source: "/src/" + id, CreatedAt: time.Now()}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.SaveResult("api", &workspace.Result{Name: "Payments API"}); err != nil {
+		t.Fatal(err)
+	}
+	// Brought up, but devcontainer.json has no name.
+	if err := store.SaveResult("scratch", &workspace.Result{ContainerID: "c-1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	rows := listRows(store, []string{"api", "scratch", "fresh"})
+	want := [][]string{
+		{"api", "Payments API", "/src/api"},
+		{"scratch", "scratch", "/src/scratch"},
+		{"fresh", "fresh", "/src/fresh"},
+	}
+	if !slices.EqualFunc(rows, want, slices.Equal) {
+		t.Errorf("rows = %v, want %v", rows, want)
+	}
+}
@@ -1,7 +1,14 @@
 package cmd
 
 import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
 	"github.com/fgrehm/crib/internal/engine"
+	"github.com/fgrehm/crib/internal/ui"
+	"github.com/fgrehm/crib/internal/workspace"
 	"github.com/spf13/cobra"
 )
 
@@ -9,13 +16,50 @@ var (
 	logsFollowFlag bool
 	logsTailFlag   string
 	logsAllFlag    bool
+	logsHooksFlag  bool
+	logsHookFlag   string
+	logsJSONFlag   bool
 )
 
+// containerHookNames lists the lifecycle hooks whose output is persisted.
+var containerHookNames = []string{
+	"onCreateCommand",
+	"updateContentCommand",
+	"postCreateCommand",
+	"postStartCommand",
+	"postAttachCommand",
+}
+
 var logsCmd = &cobra.Command{
 	Use:   "logs",
 	Short: "Show container logs",
-	Args:  noArgs,
+	Long: `Show container logs.
+
+With --hooks, show the output of lifecycle hooks from the most recent run of
+each hook instead (crib keeps the last few runs of each hook). Use --hook to
+pick a single hook, e.g. --hook postCreateCommand.
+
+With --json, print one JSON object per line of output for CI systems:
+{"event":"log","stream":"stdout","timestamp":"...","service":"app","message":"..."}.
+The service is only set for compose workspaces.`,
+	Args: noArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		if logsHooksFlag || logsHookFlag != "" {
+			if logsHookFlag != "" && !slices.Contains(containerHookNames, logsHookFlag) {
+				return &errUsage{err: fmt.Errorf("unknown hook %q (valid: %v)", logsHookFlag, containerHookNames)}
+			}
+			// Hook logs live in the workspace store; no runtime needed.
+			store, err := workspace.NewStore()
+			if err != nil {
+				return err
+			}
+			ws, err := currentWorkspace(store, false)
+			if err != nil {
+				return err
+			}
+			return printHookLogs(newUI(), os.Stdout, store, ws.ID, logsHookFlag)
+		}
+
 		eng, _, store, err := newEngine()
 		if err != nil {
 			return err
@@ -34,6 +78,7 @@ var logsCmd = &cobra.Command{
 		return eng.Logs(cmd.Context(), ws, engine.LogsOptions{
 			Follow: logsFollowFlag,
 			Tail:   tail,
+			JSON:   logsJSONFlag,
 		})
 	},
 }
@@ -42,4 +87,48 @@ func init() {
 	logsCmd.Flags().BoolVarP(&logsFollowFlag, "follow", "f", false, "follow log output")
 	logsCmd.Flags().StringVar(&logsTailFlag, "tail", "", "number of lines to show from the end (default 50)")
 	logsCmd.Flags().BoolVarP(&logsAllFlag, "all", "a", false, "show all logs (no tail limit)")
+	logsCmd.Flags().BoolVar(&logsHooksFlag, "hooks", false, "show output from the latest run of each lifecycle hook")
+	logsCmd.Flags().StringVar(&logsHookFlag, "hook", "", "show output from the latest run of a single hook (implies --hooks)")
+	logsCmd.Flags().BoolVar(&logsJSONFlag, "json", false, "print each line as a JSON object with stream, timestamp and service")
+	logsCmd.MarkFlagsMutuallyExclusive("hooks", "follow")
+	logsCmd.MarkFlagsMutuallyExclusive("hook", "follow")
+	logsCmd.MarkFlagsMutuallyExclusive("hooks", "json")
+	logsCmd.MarkFlagsMutuallyExclusive("hook", "json")
+}
+
+// printHookLogs writes the latest persisted log of each hook (or only of
+// hook, when set) to w, in lifecycle order, each under a header naming the
+// hook and when it ran.
+func printHookLogs(u *ui.UI, w io.Writer, store *workspace.Store, wsID, hook string) error {
+	logs, err := store.HookLogs(wsID, hook)
+	if err != nil {
+		return err
+	}
+
+	latest := make(map[string]workspace.HookLog)
+	for _, l := range logs {
+		latest[l.Hook] = l // logs are oldest first
+	}
+	if len(latest) == 0 {
+		if hook != "" {
+			return fmt.Errorf("no logs recorded for %s", hook)
+		}
+		return fmt.Errorf("no hook logs recorded for workspace %s (run 'crib up' first)", wsID)
+	}
+
+	for _, name := range containerHookNames {
+		l, ok := latest[name]
+		if !ok {
+			continue
+		}
+		u.Header(fmt.Sprintf("%s (%s)", name, l.StartedAt.Local().Format("2006-01-02 15:04:05")))
+		data, err := os.ReadFile(l.Path)
+		if err != nil {
+			return fmt.Errorf("reading hook log: %w", err)
+		}
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+	}
+	return nil
 }
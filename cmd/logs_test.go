@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fgrehm/crib/internal/ui"
+	"github.com/fgrehm/crib/internal/workspace"
+)
+
+func writeHookLog(t *testing.T, store *workspace.Store, hook string, at time.Time, content string) {
+	t.Helper()
+	f, err := store.OpenHookLog("ws1", hook, at)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+	if _, err := f.WriteString(content); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPrintHookLogs_LatestPerHookInLifecycleOrder(t *testing.T) {
+	store := workspace.NewStoreAt(t.TempDir())
+	base := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	writeHookLog(t, store, "postCreateCommand", base, "old run\n")
+	writeHookLog(t, store, "postCreateCommand", base.Add(time.Hour), "npm ERR! network\n")
+	writeHookLog(t, store, "onCreateCommand", base.Add(time.Hour), "bundle install\n")
+
+	var out bytes.Buffer
+	if err := printHookLogs(ui.New(&out, &out), &out, store, "ws1", ""); err != nil {
+		t.Fatalf("printHookLogs: %v", err)
+	}
+
+	got := out.String()
+	if strings.Contains(got, "old run") {
+		t.Errorf("only the latest run should be shown, got:\n%s", got)
+	}
+	onCreate := strings.Index(got, "==> onCreateCommand")
+	postCreate := strings.Index(got, "==> postCreateCommand")
+	if onCreate < 0 || postCreate < 0 || onCreate > postCreate {
+		t.Errorf("expected onCreateCommand before postCreateCommand headers, got:\n%s", got)
+	}
+	if !strings.Contains(got, "npm ERR! network\n") || !strings.Contains(got, "bundle install\n") {
+		t.Errorf("missing hook output, got:\n%s", got)
+	}
+}
+
+func TestPrintHookLogs_SingleHook(t *testing.T) {
+	store := workspace.NewStoreAt(t.TempDir())
+	base := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	writeHookLog(t, store, "postCreateCommand", base, "post\n")
+	writeHookLog(t, store, "onCreateCommand", base, "on\n")
+
+	var out bytes.Buffer
+	if err := printHookLogs(ui.New(&out, &out), &out, store, "ws1", "postCreateCommand"); err != nil {
+		t.Fatalf("printHookLogs: %v", err)
+	}
+	if got := out.String(); strings.Contains(got, "onCreateCommand") || !strings.Contains(got, "post\n") {
+		t.Errorf("expected only postCreateCommand output, got:\n%s", got)
+	}
+}
+
+func TestPrintHookLogs_NoneRecorded(t *testing.T) {
+	store := workspace.NewStoreAt(t.TempDir())
+	var out bytes.Buffer
+	if err := printHookLogs(ui.New(&out, &out), &out, store, "ws1", ""); err == nil {
+		t.Error("expected error when no hook logs exist")
+	}
+}
@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var pauseCmd = &cobra.Command{
+	Use:   "pause",
+	Short: "Pause the workspace container",
+	Long:  "Freeze all processes in the workspace container without stopping it. For compose workspaces, all running services are paused. Use 'unpause' to resume.",
+	Args:  noArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		u := newUI()
+
+		eng, _, store, err := newEngine()
+		if err != nil {
+			return err
+		}
+
+		ws, err := currentWorkspace(store, false)
+		if err != nil {
+			return err
+		}
+		lock, err := store.Lock(cmd.Context(), ws.ID)
+		if err != nil {
+			return err
+		}
+		defer lock.Unlock() //nolint:errcheck // best-effort cleanup
+
+		u.Dim(versionString())
+
+		if err := eng.Pause(cmd.Context(), ws); err != nil {
+			return err
+		}
+
+		u.Success("Paused " + ws.ID)
+		return nil
+	},
+}
+
+var unpauseCmd = &cobra.Command{
+	Use:   "unpause",
+	Short: "Resume a paused workspace container",
+	Long:  "Resume all processes in a workspace container frozen by 'pause'. For compose workspaces, all paused services are resumed.",
+	Args:  noArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		u := newUI()
+
+		eng, _, store, err := newEngine()
+		if err != nil {
+			return err
+		}
+
+		ws, err := currentWorkspace(store, false)
+		if err != nil {
+			return err
+		}
+		lock, err := store.Lock(cmd.Context(), ws.ID)
+		if err != nil {
+			return err
+		}
+		defer lock.Unlock() //nolint:errcheck // best-effort cleanup
+
+		u.Dim(versionString())
+
+		if err := eng.Unpause(cmd.Context(), ws); err != nil {
+			return err
+		}
+
+		u.Success("Unpaused " + ws.ID)
+		return nil
+	},
+}
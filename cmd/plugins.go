@@ -27,6 +27,10 @@ func disabledPluginsForCommand(cmd *cobra.Command) []string {
 	return vals
 }
 
+// perExecutionFlags lists flags without a package-level variable whose
+// values must not leak across Execute() calls in the same process.
+var perExecutionFlags = []string{"disable-plugin", "cache-to", "add-host", "mount", "secret", "build-context", "gpus", "resource-limits", "auto-port", "cpuset", "cap-drop", "mount-docker-socket", "label-file", "tmpfs", "trust", "pull", "env-probe", "workspace-folder", "network"}
+
 // resetPerExecutionFlags clears parsed state for flags that must not leak
 // across Execute() calls in the same process. pflag retains the slice value
 // and the Changed bit between invocations of cobra's parser, so a stray
@@ -34,11 +38,15 @@ func disabledPluginsForCommand(cmd *cobra.Command) []string {
 // where the flag is absent.
 func resetPerExecutionFlags(cmd *cobra.Command) {
 	for _, c := range cmd.Commands() {
-		if f := c.Flags().Lookup("disable-plugin"); f != nil {
-			if sv, ok := f.Value.(pflag.SliceValue); ok {
-				_ = sv.Replace(nil)
+		for _, name := range perExecutionFlags {
+			if f := c.Flags().Lookup(name); f != nil {
+				if sv, ok := f.Value.(pflag.SliceValue); ok {
+					_ = sv.Replace(nil)
+				} else {
+					_ = f.Value.Set(f.DefValue)
+				}
+				f.Changed = false
 			}
-			f.Changed = false
 		}
 		resetPerExecutionFlags(c)
 	}
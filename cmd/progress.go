@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/fgrehm/crib/internal/engine"
+	"github.com/fgrehm/crib/internal/ui"
+	"github.com/spf13/cobra"
+)
+
+var progressFlag string
+
+// attachProgress creates the renderer selected by --progress and wires the
+// engine's progress events and subprocess output through it. Callers must
+// Stop the renderer before printing their own summary.
+func attachProgress(u *ui.UI, eng *engine.Engine) (ui.ProgressRenderer, error) {
+	mode, err := ui.ParseProgressMode(progressFlag)
+	if err != nil {
+		return nil, &errUsage{err: err}
+	}
+	p := u.NewProgress(mode)
+	eng.SetOutput(p.Writer(os.Stdout), p.Writer(os.Stderr))
+	eng.SetProgress(func(ev engine.ProgressEvent) {
+		if ev.Phase == engine.PhaseTimings {
+			p.Timing(ev.Message, ev.Duration)
+			return
+		}
+		p.Event(string(ev.Phase), ev.Message)
+	})
+	return p, nil
+}
+
+// addProgressFlag registers --progress on commands that report engine progress.
+func addProgressFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&progressFlag, "progress", string(ui.ProgressAuto), "progress output: auto, plain, or json")
+}
@@ -1,9 +1,13 @@
 package cmd
 
 import (
+	"context"
 	"os"
+	"time"
 
 	"github.com/fgrehm/crib/internal/engine"
+	"github.com/fgrehm/crib/internal/ui"
+	"github.com/fgrehm/crib/internal/workspace"
 	"github.com/spf13/cobra"
 )
 
@@ -18,10 +22,57 @@ var rebuildCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
-		eng.SetOutput(os.Stdout, os.Stderr)
+		progress, err := attachProgress(u, eng)
+		if err != nil {
+			return err
+		}
+		defer progress.Stop()
 		eng.SetVerbose(verboseFlag || debugFlag)
-		eng.SetProgress(func(ev engine.ProgressEvent) { u.Dim("  " + ev.Message) })
+		eng.SetHookRetries(hookRetriesFlag)
+		eng.SetWaitForTimeout(waitForTimeoutFlag, waitForFailFlag)
+		eng.SetCacheTo(cacheToForCommand(cmd))
+		eng.SetKeepGenerated(keepOverrideForCommand(cmd))
+		if err := eng.SetExtraHosts(addHostsForCommand(cmd)); err != nil {
+			return &errUsage{err: err}
+		}
+		if err := setExtraMounts(cmd, eng); err != nil {
+			return err
+		}
+		eng.SetGPUs(gpusForCommand(cmd))
+		eng.SetNetwork(networkForCommand(cmd))
+		eng.SetResourceLimits(resourceLimitsForCommand(cmd))
+		eng.SetAutoPort(autoPortForCommand(cmd))
+		if err := setEnvProbe(cmd, eng); err != nil {
+			return err
+		}
+		if err := setCPUSet(cmd, eng); err != nil {
+			return err
+		}
+		if err := setCapDrop(cmd, eng); err != nil {
+			return err
+		}
+		if err := setMountDockerSocket(cmd, eng); err != nil {
+			return err
+		}
+		if err := setLabelFiles(cmd, eng); err != nil {
+			return err
+		}
+		if err := eng.SetTmpfs(tmpfsForCommand(cmd)); err != nil {
+			return &errUsage{err: err}
+		}
+		if err := setWorkspaceFolder(cmd, eng); err != nil {
+			return err
+		}
+		pull, _ := cmd.Flags().GetBool("pull")
+		eng.SetPull(pull)
+		if err := setBuildSecrets(cmd, eng); err != nil {
+			return err
+		}
+		if err := setBuildContexts(cmd, eng); err != nil {
+			return err
+		}
 		setupPlugins(cmd, eng, d)
+		setTrustPrompt(cmd, eng, progress.Writer(os.Stderr))
 
 		ws, err := currentWorkspace(store, true)
 		if err != nil {
@@ -36,16 +87,15 @@ var rebuildCmd = &cobra.Command{
 		u.Dim(versionString())
 		u.Header("Rebuilding workspace")
 
-		// Discard snapshot so the rebuild starts from scratch.
-		eng.ClearSnapshot(cmd.Context(), ws)
-
-		if err := eng.Down(cmd.Context(), ws); err != nil {
-			logger.Debug("down before rebuild", "error", err)
-		} else {
-			u.Success("Container removed")
+		// Confirm dangerous settings before the old container is removed.
+		if err := eng.CheckTrust(ws); err != nil {
+			return err
 		}
 
-		result, err := eng.Up(cmd.Context(), ws, engine.UpOptions{Recreate: true})
+		started := time.Now()
+		result, err := rebuildWorkspace(cmd.Context(), u, eng, ws)
+		progress.Stop()
+		recordHistory(store, ws.ID, "rebuild", started, "", err)
 		if err != nil {
 			return err
 		}
@@ -64,6 +114,44 @@ var rebuildCmd = &cobra.Command{
 	},
 }
 
+// rebuildWorkspace removes the container of ws and brings it up again with
+// a fresh image. The snapshot is discarded so the rebuild starts from
+// scratch. Callers check trust first so that declining keeps the old
+// container.
+func rebuildWorkspace(ctx context.Context, u *ui.UI, eng *engine.Engine, ws *workspace.Workspace) (*engine.UpResult, error) {
+	eng.ClearSnapshot(ctx, ws)
+
+	if err := eng.Down(ctx, ws); err != nil {
+		logger.Debug("down before rebuild", "error", err)
+	} else {
+		u.Success("Container removed")
+	}
+
+	return eng.Up(ctx, ws, engine.UpOptions{Recreate: true, RecreateDeps: true})
+}
+
 func init() {
+	rebuildCmd.Flags().IntVar(&hookRetriesFlag, "hook-retries", 0, "retry failing create-time lifecycle hooks up to N times with backoff")
+	addWaitForTimeoutFlags(rebuildCmd)
+	rebuildCmd.Flags().Bool("pull", false, "pull newer versions of the base image(s) before building")
+	addCacheToFlag(rebuildCmd)
+	addSecretFlag(rebuildCmd)
+	addBuildContextFlag(rebuildCmd)
+	addKeepOverrideFlag(rebuildCmd)
+	addAddHostFlag(rebuildCmd)
+	addGPUsFlag(rebuildCmd)
+	addNetworkFlag(rebuildCmd)
+	addResourceLimitsFlag(rebuildCmd)
+	addAutoPortFlag(rebuildCmd)
+	addCPUSetFlag(rebuildCmd)
+	addCapDropFlag(rebuildCmd)
+	addMountDockerSocketFlag(rebuildCmd)
+	addLabelFileFlag(rebuildCmd)
+	addTmpfsFlag(rebuildCmd)
+	addEnvProbeFlag(rebuildCmd)
+	addWorkspaceFolderFlag(rebuildCmd)
+	addTrustFlag(rebuildCmd)
+	addMountFlag(rebuildCmd)
 	addPluginFlags(rebuildCmd)
+	addProgressFlag(rebuildCmd)
 }
@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"os"
+	"os/signal"
+
+	"github.com/charmbracelet/x/term"
+)
+
+// terminalResizer propagates a new terminal size to an exec session.
+type terminalResizer interface {
+	Resize(width, height int) error
+}
+
+// processResizer relays terminal resizes to a runtime CLI running as a
+// child of crib. The child shares crib's terminal, whose size the kernel
+// has already updated; the relayed signal tells it to read the new size and
+// resize its exec session.
+type processResizer struct {
+	p *os.Process
+}
+
+// terminalSize returns the size of the terminal on fd. ok is false when fd
+// is not a terminal or its size cannot be read.
+func terminalSize(fd uintptr) (width, height int, ok bool) {
+	w, h, err := term.GetSize(fd)
+	if err != nil || w <= 0 || h <= 0 {
+		return 0, 0, false
+	}
+	return w, h, true
+}
+
+// forwardResizes calls r.Resize with the current size every time a value
+// arrives on resized, until done is closed. The size at the start is the
+// baseline and unchanged sizes are skipped. Resize failures are only
+// logged: a missed resize must not end the session.
+func forwardResizes(resized <-chan os.Signal, done <-chan struct{}, size func() (int, int, bool), r terminalResizer) {
+	lastW, lastH, _ := size()
+	for {
+		select {
+		case <-done:
+			return
+		case <-resized:
+		}
+		w, h, ok := size()
+		if !ok || (w == lastW && h == lastH) {
+			continue
+		}
+		lastW, lastH = w, h
+		if err := r.Resize(w, h); err != nil {
+			logger.Debug("forwarding terminal resize failed", "width", w, "height", h, "error", err)
+		}
+	}
+}
+
+// watchResizes forwards resizes of the terminal on f to the runtime child
+// p until the returned stop function is called. Nothing is watched when f
+// is not a terminal.
+func watchResizes(f *os.File, p *os.Process) (stop func()) {
+	if !term.IsTerminal(f.Fd()) {
+		return func() {}
+	}
+	resized := make(chan os.Signal, 1)
+	notifyResize(resized)
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		size := func() (int, int, bool) { return terminalSize(f.Fd()) }
+		forwardResizes(resized, done, size, processResizer{p: p})
+	}()
+	return func() {
+		signal.Stop(resized)
+		close(done)
+		<-finished
+	}
+}
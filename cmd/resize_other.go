@@ -0,0 +1,14 @@
+//go:build !unix
+
+package cmd
+
+import "os"
+
+// notifyResize delivers nothing: there is no SIGWINCH on this platform and
+// the runtime CLI polls the console size itself.
+func notifyResize(chan<- os.Signal) {}
+
+// Resize is a no-op: the runtime CLI picks up console size changes itself.
+func (processResizer) Resize(int, int) error {
+	return nil
+}
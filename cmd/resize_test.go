@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"sync"
+	"testing"
+)
+
+type fakeResizer struct {
+	mu    sync.Mutex
+	sizes [][2]int
+	err   error
+}
+
+func (f *fakeResizer) Resize(width, height int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sizes = append(f.sizes, [2]int{width, height})
+	return f.err
+}
+
+func (f *fakeResizer) calls() [][2]int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([][2]int(nil), f.sizes...)
+}
+
+// runForwardResizes feeds one resize signal per size after the first (the
+// baseline) and returns the sizes the resizer saw.
+func runForwardResizes(t *testing.T, r *fakeResizer, sizes ...[2]int) [][2]int {
+	t.Helper()
+	var mu sync.Mutex
+	next := 0
+	size := func() (int, int, bool) {
+		mu.Lock()
+		defer mu.Unlock()
+		s := sizes[next]
+		if next < len(sizes)-1 {
+			next++
+		}
+		if s[0] == 0 {
+			return 0, 0, false
+		}
+		return s[0], s[1], true
+	}
+
+	resized := make(chan os.Signal)
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		forwardResizes(resized, done, size, r)
+	}()
+	for range sizes[1:] {
+		resized <- os.Interrupt
+	}
+	close(done)
+	<-finished
+	return r.calls()
+}
+
+func TestForwardResizes(t *testing.T) {
+	tests := []struct {
+		name  string
+		sizes [][2]int
+		want  [][2]int
+	}{
+		{name: "no resize", sizes: [][2]int{{80, 24}}, want: nil},
+		{name: "resize", sizes: [][2]int{{80, 24}, {120, 40}}, want: [][2]int{{120, 40}}},
+		{name: "unchanged size skipped", sizes: [][2]int{{80, 24}, {80, 24}, {100, 30}}, want: [][2]int{{100, 30}}},
+		{name: "unreadable size skipped", sizes: [][2]int{{80, 24}, {0, 0}, {90, 24}}, want: [][2]int{{90, 24}}},
+		{name: "repeated changes", sizes: [][2]int{{80, 24}, {100, 30}, {80, 24}}, want: [][2]int{{100, 30}, {80, 24}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := runForwardResizes(t, &fakeResizer{}, tt.sizes...)
+			if len(got) != len(tt.want) {
+				t.Fatalf("resizes = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("resizes = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestForwardResizes_ResizeErrorKeepsForwarding(t *testing.T) {
+	origLogger := logger
+	t.Cleanup(func() { logger = origLogger })
+	logger = slog.New(slog.DiscardHandler)
+
+	r := &fakeResizer{err: errors.New("exec session gone")}
+	got := runForwardResizes(t, r, [2]int{80, 24}, [2]int{100, 30}, [2]int{120, 40})
+	if len(got) != 2 {
+		t.Errorf("resizes = %v, want two attempts despite errors", got)
+	}
+}
+
+func TestTerminalSize_NotATerminal(t *testing.T) {
+	f, err := os.CreateTemp(t.TempDir(), "stdin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+
+	if w, h, ok := terminalSize(f.Fd()); ok {
+		t.Errorf("terminalSize = %dx%d, want not ok for a regular file", w, h)
+	}
+}
+
+func TestWatchResizes_NotATerminal(t *testing.T) {
+	f, err := os.CreateTemp(t.TempDir(), "stdin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+
+	stop := watchResizes(f, nil)
+	stop()
+}
@@ -0,0 +1,19 @@
+//go:build unix
+
+package cmd
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// notifyResize delivers terminal window changes (SIGWINCH) to c.
+func notifyResize(c chan<- os.Signal) {
+	signal.Notify(c, syscall.SIGWINCH)
+}
+
+// Resize relays SIGWINCH to the runtime child.
+func (r processResizer) Resize(int, int) error {
+	return r.p.Signal(syscall.SIGWINCH)
+}
@@ -2,8 +2,8 @@ package cmd
 
 import (
 	"os"
+	"time"
 
-	"github.com/fgrehm/crib/internal/engine"
 	"github.com/spf13/cobra"
 )
 
@@ -28,10 +28,46 @@ args), restart will ask you to run 'crib rebuild' instead.`,
 		if err != nil {
 			return err
 		}
-		eng.SetOutput(os.Stdout, os.Stderr)
+		progress, err := attachProgress(u, eng)
+		if err != nil {
+			return err
+		}
+		defer progress.Stop()
 		eng.SetVerbose(verboseFlag || debugFlag)
-		eng.SetProgress(func(ev engine.ProgressEvent) { u.Dim("  " + ev.Message) })
+		eng.SetKeepGenerated(keepOverrideForCommand(cmd))
+		if err := eng.SetExtraHosts(addHostsForCommand(cmd)); err != nil {
+			return &errUsage{err: err}
+		}
+		if err := setExtraMounts(cmd, eng); err != nil {
+			return err
+		}
+		eng.SetGPUs(gpusForCommand(cmd))
+		eng.SetNetwork(networkForCommand(cmd))
+		eng.SetResourceLimits(resourceLimitsForCommand(cmd))
+		eng.SetAutoPort(autoPortForCommand(cmd))
+		if err := setEnvProbe(cmd, eng); err != nil {
+			return err
+		}
+		if err := setCPUSet(cmd, eng); err != nil {
+			return err
+		}
+		if err := setCapDrop(cmd, eng); err != nil {
+			return err
+		}
+		if err := setMountDockerSocket(cmd, eng); err != nil {
+			return err
+		}
+		if err := setLabelFiles(cmd, eng); err != nil {
+			return err
+		}
+		if err := eng.SetTmpfs(tmpfsForCommand(cmd)); err != nil {
+			return &errUsage{err: err}
+		}
+		if err := setWorkspaceFolder(cmd, eng); err != nil {
+			return err
+		}
 		setupPlugins(cmd, eng, d)
+		setTrustPrompt(cmd, eng, progress.Writer(os.Stderr))
 
 		ws, err := currentWorkspace(store, false)
 		if err != nil {
@@ -46,7 +82,14 @@ args), restart will ask you to run 'crib rebuild' instead.`,
 		u.Dim(versionString())
 		u.Header("Restarting workspace")
 
+		started := time.Now()
 		result, err := eng.Restart(cmd.Context(), ws)
+		progress.Stop()
+		detail := ""
+		if result != nil && result.Recreated {
+			detail = "recreated container"
+		}
+		recordHistory(store, ws.ID, "restart", started, detail, err)
 		if err != nil {
 			if result != nil {
 				// Container is usable despite hook failure.
@@ -76,4 +119,20 @@ args), restart will ask you to run 'crib rebuild' instead.`,
 
 func init() {
 	addPluginFlags(restartCmd)
+	addKeepOverrideFlag(restartCmd)
+	addAddHostFlag(restartCmd)
+	addGPUsFlag(restartCmd)
+	addNetworkFlag(restartCmd)
+	addResourceLimitsFlag(restartCmd)
+	addAutoPortFlag(restartCmd)
+	addCPUSetFlag(restartCmd)
+	addCapDropFlag(restartCmd)
+	addMountDockerSocketFlag(restartCmd)
+	addLabelFileFlag(restartCmd)
+	addTmpfsFlag(restartCmd)
+	addEnvProbeFlag(restartCmd)
+	addWorkspaceFolderFlag(restartCmd)
+	addTrustFlag(restartCmd)
+	addMountFlag(restartCmd)
+	addProgressFlag(restartCmd)
 }
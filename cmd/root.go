@@ -9,11 +9,13 @@ import (
 	"os"
 	"os/signal"
 	"path/filepath"
+	"slices"
 	"strings"
 	"syscall"
 	"time"
 
 	"github.com/fgrehm/crib/internal/compose"
+	"github.com/fgrehm/crib/internal/config"
 	"github.com/fgrehm/crib/internal/driver"
 	"github.com/fgrehm/crib/internal/driver/oci"
 	"github.com/fgrehm/crib/internal/engine"
@@ -45,6 +47,16 @@ func displayContainerName(recorded, wsID string) string {
 	return "crib-" + wsID
 }
 
+// displayWorkspaceName returns the devcontainer.json name recorded for the
+// workspace by its last crib up, or the workspace ID when the config has no
+// name or the workspace has never been brought up.
+func displayWorkspaceName(store *workspace.Store, wsID string) string {
+	if stored, _ := store.LoadResult(wsID); stored != nil && stored.Name != "" {
+		return stored.Name
+	}
+	return wsID
+}
+
 // runtimeConfig holds configuration derived from the global config file and
 // the project's .cribrc, populated by PersistentPreRunE. Bundled into one
 // struct so the reset-before-load sequence is a single assignment and so
@@ -63,6 +75,8 @@ var (
 	verboseFlag   bool
 	configDirFlag string
 	dirFlag       string
+	nameFlag      string
+	runtimeFlag   string
 	logger        *slog.Logger
 	runtimeCfg    runtimeConfig
 )
@@ -125,6 +139,9 @@ var rootCmd = &cobra.Command{
 			runtimeCfg.projectPlugins = rc.Plugins.Disable
 			runtimeCfg.projectPluginsOff = rc.Plugins.DisableAll
 			runtimeCfg.projectWorkspace = rc.Workspace
+			if err := applyFlagDefaults(cmd, rc.Defaults); err != nil {
+				return &errUsage{err: fmt.Errorf(".cribrc: %w", err)}
+			}
 		}
 
 		return nil
@@ -138,35 +155,52 @@ func init() {
 	rootCmd.PersistentFlags().BoolVar(&verboseFlag, "verbose", false, "show detailed output from compose and build commands")
 	rootCmd.PersistentFlags().StringVarP(&configDirFlag, "config", "C", "", "devcontainer config directory (e.g. .devcontainer-custom)")
 	rootCmd.PersistentFlags().StringVarP(&dirFlag, "dir", "d", "", "project directory to operate on (defaults to current directory)")
+	rootCmd.PersistentFlags().StringVar(&nameFlag, "name", "", "workspace name (overrides the ID derived from the project directory)")
+	rootCmd.PersistentFlags().StringVar(&runtimeFlag, "runtime", "", "container runtime to use: docker or podman (or set CRIB_RUNTIME; auto-detected by default)")
 	rootCmd.MarkFlagsMutuallyExclusive("config", "dir")
 	rootCmd.SetFlagErrorFunc(func(_ *cobra.Command, err error) error {
 		return &errUsage{err: err}
 	})
 	rootCmd.SetVersionTemplate(fmt.Sprintf("crib version %s\n", version))
 	rootCmd.AddCommand(listCmd)
+	rootCmd.AddCommand(configsCmd)
+	rootCmd.AddCommand(configCmd)
 	rootCmd.AddCommand(statusCmd)
+	rootCmd.AddCommand(diffCmd)
+	rootCmd.AddCommand(diffFSCmd)
 	rootCmd.AddCommand(downCmd)
 	rootCmd.AddCommand(stopCmd)
+	rootCmd.AddCommand(pauseCmd)
+	rootCmd.AddCommand(unpauseCmd)
 	rootCmd.AddCommand(removeCmd)
 	rootCmd.AddCommand(execCmd)
 	rootCmd.AddCommand(runCmd)
 	rootCmd.AddCommand(shellCmd)
+	rootCmd.AddCommand(cpCmd)
+	rootCmd.AddCommand(exportCmd)
 	rootCmd.AddCommand(sshCmd)
+	rootCmd.AddCommand(buildCmd)
 	rootCmd.AddCommand(upCmd)
 	rootCmd.AddCommand(rebuildCmd)
 	rootCmd.AddCommand(restartCmd)
+	rootCmd.AddCommand(watchCmd)
 	rootCmd.AddCommand(logsCmd)
+	rootCmd.AddCommand(historyCmd)
+	rootCmd.AddCommand(attachCmd)
 	rootCmd.AddCommand(doctorCmd)
+	rootCmd.AddCommand(infoCmd)
 	rootCmd.AddCommand(cacheCmd)
 	rootCmd.AddCommand(pruneCmd)
+	rootCmd.AddCommand(cleanCmd)
 	rootCmd.AddCommand(versionCmd)
 }
 
 // Exit codes.
 const (
-	exitOK    = 0
-	exitError = 1
-	exitUsage = 2 // bad flags, unknown subcommand, missing required args
+	exitOK      = 0
+	exitError   = 1
+	exitUsage   = 2 // bad flags, unknown subcommand, missing required args
+	exitTimeout = 3 // `status --wait` or `up --timeout` deadline elapsed
 )
 
 // errUsage wraps an error to signal a usage mistake (exit code 2).
@@ -175,6 +209,13 @@ type errUsage struct{ err error }
 func (e *errUsage) Error() string { return e.err.Error() }
 func (e *errUsage) Unwrap() error { return e.err }
 
+// errExitStatus carries the exit status of a command crib ran for the user
+// (e.g. with crib exec) so it becomes crib's own. The command has already
+// reported its failure, so Execute prints nothing for it.
+type errExitStatus struct{ code int }
+
+func (e *errExitStatus) Error() string { return fmt.Sprintf("command exited with status %d", e.code) }
+
 // Execute runs the root command with signal handling and returns the exit code.
 func Execute() int {
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
@@ -193,18 +234,43 @@ func Execute() int {
 	}))
 	resetPerExecutionFlags(rootCmd)
 	if err := rootCmd.ExecuteContext(ctx); err != nil {
+		var es *errExitStatus
+		if errors.As(err, &es) {
+			return es.code
+		}
 		u := newUI()
 		u.Error(err.Error())
 		fmt.Fprintf(os.Stderr, "\ncrib %s (%s)\n", version, commit)
-		var ue *errUsage
-		if errors.As(err, &ue) {
-			return exitUsage
-		}
-		return exitError
+		return exitCode(err)
 	}
 	return exitOK
 }
 
+// exitCode maps a command error to the process exit code.
+func exitCode(err error) int {
+	var ue *errUsage
+	if errors.As(err, &ue) {
+		return exitUsage
+	}
+	var te *engine.ErrWaitTimeout
+	if errors.As(err, &te) {
+		return exitTimeout
+	}
+	var ute *engine.ErrUpTimeout
+	if errors.As(err, &ute) {
+		return exitTimeout
+	}
+	var xe *engine.ErrContainerExited
+	if errors.As(err, &xe) {
+		return xe.ExitCode
+	}
+	var es *errExitStatus
+	if errors.As(err, &es) {
+		return es.code
+	}
+	return exitError
+}
+
 // newUI creates a UI that writes to stdout and stderr.
 func newUI() *ui.UI {
 	return ui.New(os.Stdout, os.Stderr)
@@ -213,9 +279,9 @@ func newUI() *ui.UI {
 // newEngine creates the OCI driver, workspace store, and engine.
 // The compose helper is optional; nil is passed to the engine if compose is not available.
 func newEngine() (*engine.Engine, *oci.OCIDriver, *workspace.Store, error) {
-	d, err := oci.NewOCIDriver(logger)
+	d, composeHelper, err := newRuntime()
 	if err != nil {
-		return nil, nil, nil, fmt.Errorf("initializing container runtime: %w", err)
+		return nil, nil, nil, err
 	}
 
 	store, err := workspace.NewStore()
@@ -223,32 +289,60 @@ func newEngine() (*engine.Engine, *oci.OCIDriver, *workspace.Store, error) {
 		return nil, nil, nil, fmt.Errorf("initializing workspace store: %w", err)
 	}
 
+	eng := engine.New(d, composeHelper, store, logger)
+	return eng, d, store, nil
+}
+
+// newRuntime creates the OCI driver for the runtime picked with --runtime
+// (or CRIB_RUNTIME, or auto-detected) and a compose helper for the same
+// runtime. The compose helper is nil if compose is not available.
+func newRuntime() (*oci.OCIDriver, *compose.Helper, error) {
+	d, err := oci.NewOCIDriverFor(runtimeFlag, logger)
+	if err != nil {
+		return nil, nil, fmt.Errorf("initializing container runtime: %w", err)
+	}
+
 	composeHelper, err := compose.NewHelper(d.Runtime().String(), logger)
 	if err != nil {
 		logger.Debug("compose not available", "error", err)
 		composeHelper = nil
 	}
-
-	eng := engine.New(d, composeHelper, store, logger)
-	return eng, d, store, nil
+	return d, composeHelper, nil
 }
 
 // currentWorkspace resolves the workspace from the current directory,
 // or from the devcontainer config directory if --config / .cribrc is set,
-// or from an explicit project directory if --dir is set.
+// or from an explicit project directory if --dir is set. --name overrides
+// the derived workspace ID. When the project has several configs and none was
+// picked before, the user is prompted to choose one on a terminal.
 // If create is true and the workspace is not yet in the store, it creates one.
 func currentWorkspace(store *workspace.Store, create bool) (*workspace.Workspace, error) {
 	cwd, err := os.Getwd()
 	if err != nil {
 		return nil, fmt.Errorf("getting working directory: %w", err)
 	}
-	return workspace.Lookup(store, workspace.LookupOptions{
+	opts := workspace.LookupOptions{
 		ConfigDir: configDirFlag,
 		Dir:       dirFlag,
 		Cwd:       cwd,
 		Version:   version,
 		Create:    create,
-	}, logger)
+		Name:      nameFlag,
+	}
+	ws, err := workspace.Lookup(store, opts, logger)
+
+	// Several configs and no previous choice: ask when interactive,
+	// otherwise the error tells the user to pass --config.
+	var multi *config.ErrMultipleConfigs
+	if errors.As(err, &multi) && stdinIsTerminal() {
+		chosen, err := pickConfig(os.Stdin, os.Stderr, multi)
+		if err != nil {
+			return nil, err
+		}
+		opts.ConfigDir = filepath.Dir(chosen)
+		return workspace.Lookup(store, opts, logger)
+	}
+	return ws, err
 }
 
 // versionString returns a formatted version string for display.
@@ -296,6 +390,54 @@ func loadProjectCribRC() (*globalconfig.CribRC, error) {
 	return globalconfig.LoadCribRC(filepath.Join(dir, ".cribrc"))
 }
 
+// applyFlagDefaults sets flags on cmd from the .cribrc [defaults] table,
+// skipping any flag the user passed explicitly. Top-level keys apply to every
+// command that defines the flag; a nested table named after the command
+// (e.g. [defaults.up]) applies only to that command and wins over top-level
+// keys. Keys naming flags the command doesn't have are ignored, so one table
+// can serve several commands.
+func applyFlagDefaults(cmd *cobra.Command, defaults map[string]any) error {
+	values := make(map[string]any)
+	for name, v := range defaults {
+		if _, isTable := v.(map[string]any); !isTable {
+			values[name] = v
+		}
+	}
+	if scoped, ok := defaults[cmd.Name()].(map[string]any); ok {
+		maps.Copy(values, scoped)
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(values)) {
+		f := cmd.Flags().Lookup(name)
+		if f == nil || f.Changed {
+			continue
+		}
+		if err := setFlagDefault(cmd, name, values[name]); err != nil {
+			return fmt.Errorf("defaults.%s: %w", name, err)
+		}
+		// Keep Changed false so defaults behave like built-in defaults
+		// (e.g. for flag-dependency checks).
+		f.Changed = false
+		logger.Debug("applied flag default from .cribrc", "flag", name, "value", f.Value.String())
+	}
+	return nil
+}
+
+// setFlagDefault assigns a decoded TOML value to a flag. Arrays set each
+// element in turn so repeatable flags receive every entry.
+func setFlagDefault(cmd *cobra.Command, name string, v any) error {
+	items, ok := v.([]any)
+	if !ok {
+		items = []any{v}
+	}
+	for _, item := range items {
+		if err := cmd.Flags().Set(name, fmt.Sprint(item)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // mergeWorkspaceOptions overlays .cribrc's [workspace] section on top of the
 // global config's [workspace] section and returns the effective options to
 // pass to the engine. The backends treat this entire result as "lower
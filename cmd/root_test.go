@@ -1,10 +1,16 @@
 package cmd
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/fgrehm/crib/internal/engine"
 	"github.com/fgrehm/crib/internal/globalconfig"
 	"github.com/spf13/cobra"
 )
@@ -349,3 +355,217 @@ func TestResetPerExecutionFlags(t *testing.T) {
 		t.Errorf("third run: got %v, want [dotfiles] only", got)
 	}
 }
+
+func TestResetPerExecutionFlags_CacheTo(t *testing.T) {
+	root := &cobra.Command{Use: "test"}
+	sub := &cobra.Command{Use: "up", RunE: func(*cobra.Command, []string) error { return nil }}
+	addCacheToFlag(sub)
+	root.AddCommand(sub)
+
+	// Values contain commas and must not be split.
+	root.SetArgs([]string{"up", "--cache-to", "type=registry,ref=r/app:cache"})
+	if err := root.Execute(); err != nil {
+		t.Fatalf("first execute: %v", err)
+	}
+	if got := cacheToForCommand(sub); len(got) != 1 || got[0] != "type=registry,ref=r/app:cache" {
+		t.Fatalf("first run: got %v, want single unsplit value", got)
+	}
+
+	resetPerExecutionFlags(root)
+	root.SetArgs([]string{"up"})
+	if err := root.Execute(); err != nil {
+		t.Fatalf("second execute: %v", err)
+	}
+	if got := cacheToForCommand(sub); len(got) != 0 {
+		t.Errorf("second run: got %v, want empty (stale value leaked)", got)
+	}
+}
+
+func TestApplyFlagDefaults(t *testing.T) {
+	origLogger := logger
+	t.Cleanup(func() { logger = origLogger })
+	logger = slog.Default()
+
+	path := filepath.Join(t.TempDir(), ".cribrc")
+	content := `
+[defaults]
+hook-retries = 3
+progress = "plain"
+cache-to = ["type=registry,ref=r/app:cache", "type=local,dest=/tmp/c"]
+timeout = "5m"
+
+[defaults.up]
+progress = "json"
+`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	rc, err := globalconfig.LoadCribRC(path)
+	if err != nil {
+		t.Fatalf("LoadCribRC: %v", err)
+	}
+
+	newCmd := func() *cobra.Command {
+		c := &cobra.Command{Use: "up"}
+		c.Flags().Int("hook-retries", 0, "")
+		c.Flags().String("progress", "auto", "")
+		addCacheToFlag(c)
+		return c
+	}
+
+	t.Run("repo defaults applied", func(t *testing.T) {
+		c := newCmd()
+		if err := c.ParseFlags(nil); err != nil {
+			t.Fatal(err)
+		}
+		if err := applyFlagDefaults(c, rc.Defaults); err != nil {
+			t.Fatalf("applyFlagDefaults: %v", err)
+		}
+		if n, _ := c.Flags().GetInt("hook-retries"); n != 3 {
+			t.Errorf("hook-retries = %d, want 3", n)
+		}
+		// The [defaults.up] table wins over the top-level key.
+		if p, _ := c.Flags().GetString("progress"); p != "json" {
+			t.Errorf("progress = %q, want json", p)
+		}
+		if got := cacheToForCommand(c); len(got) != 2 {
+			t.Errorf("cache-to = %v, want both entries", got)
+		}
+		if c.Flags().Lookup("hook-retries").Changed {
+			t.Error("defaults should not mark flags as changed")
+		}
+	})
+
+	t.Run("CLI flag wins", func(t *testing.T) {
+		c := newCmd()
+		if err := c.ParseFlags([]string{"--hook-retries", "1"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := applyFlagDefaults(c, rc.Defaults); err != nil {
+			t.Fatalf("applyFlagDefaults: %v", err)
+		}
+		if n, _ := c.Flags().GetInt("hook-retries"); n != 1 {
+			t.Errorf("hook-retries = %d, want 1 (explicit flag)", n)
+		}
+	})
+
+	t.Run("invalid value", func(t *testing.T) {
+		c := newCmd()
+		if err := c.ParseFlags(nil); err != nil {
+			t.Fatal(err)
+		}
+		if err := applyFlagDefaults(c, map[string]any{"hook-retries": "lots"}); err == nil {
+			t.Error("expected error for non-numeric hook-retries")
+		}
+	})
+}
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"generic", errors.New("boom"), exitError},
+		{"usage", &errUsage{err: errors.New("bad flag")}, exitUsage},
+		{"wait timeout", fmt.Errorf("status: %w", &engine.ErrWaitTimeout{WorkspaceID: "ws"}), exitTimeout},
+		{"up timeout", &engine.ErrUpTimeout{WorkspaceID: "ws", Err: context.DeadlineExceeded}, exitTimeout},
+		{"container exited", &engine.ErrContainerExited{WorkspaceID: "ws", ExitCode: 42}, 42},
+		{"exit status", &errExitStatus{code: 7}, 7},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exitCode(tt.err); got != tt.want {
+				t.Errorf("exitCode = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeepOverrideForCommand(t *testing.T) {
+	newCmd := func(args ...string) *cobra.Command {
+		c := &cobra.Command{Use: "up"}
+		addKeepOverrideFlag(c)
+		if err := c.ParseFlags(args); err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	t.Setenv("CRIB_KEEP_OVERRIDE", "")
+	if keepOverrideForCommand(newCmd()) {
+		t.Error("keep = true without flag or env")
+	}
+	if !keepOverrideForCommand(newCmd("--keep-override")) {
+		t.Error("keep = false with --keep-override")
+	}
+	t.Setenv("CRIB_KEEP_OVERRIDE", "1")
+	if !keepOverrideForCommand(newCmd()) {
+		t.Error("keep = false with CRIB_KEEP_OVERRIDE=1")
+	}
+}
+
+func TestTrustPrompt(t *testing.T) {
+	settings := []string{"privileged", "mount: /var/run/docker.sock"}
+	tests := []struct {
+		answer      string
+		interactive bool
+		want        bool
+	}{
+		{"y\n", true, true},
+		{"YES\n", true, true},
+		{"n\n", true, false},
+		{"\n", true, false},
+		{"y\n", false, false},
+	}
+	for _, tt := range tests {
+		var out strings.Builder
+		got := trustPrompt(strings.NewReader(tt.answer), &out, tt.interactive, "myproj", settings)
+		if got != tt.want {
+			t.Errorf("answer %q, interactive %v: got %v, want %v", tt.answer, tt.interactive, got, tt.want)
+		}
+		if !strings.Contains(out.String(), "  - mount: /var/run/docker.sock") {
+			t.Errorf("prompt does not list the settings:\n%s", out.String())
+		}
+	}
+}
+
+func TestNewRuntime_HonorsRuntimeFlag(t *testing.T) {
+	// Fake docker and podman binaries that answer `version` and
+	// `compose version --short`.
+	bin := t.TempDir()
+	script := "#!/bin/sh\nif [ \"$1\" = compose ]; then echo 2.30.0; fi\nexit 0\n"
+	for _, name := range []string{"docker", "podman"} {
+		if err := os.WriteFile(filepath.Join(bin, name), []byte(script), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("PATH", bin)
+	t.Setenv("CRIB_RUNTIME", "")
+
+	origLogger, origRuntime := logger, runtimeFlag
+	t.Cleanup(func() { logger, runtimeFlag = origLogger, origRuntime })
+	logger = slog.Default()
+
+	for _, name := range []string{"docker", "podman"} {
+		runtimeFlag = name
+		d, composeHelper, err := newRuntime()
+		if err != nil {
+			t.Fatalf("--runtime %s: %v", name, err)
+		}
+		if got := d.Runtime().String(); got != name {
+			t.Errorf("--runtime %s: driver runtime = %q", name, got)
+		}
+		if composeHelper == nil || composeHelper.RuntimeCommand() != name {
+			t.Errorf("--runtime %s: compose helper does not use %s", name, name)
+		}
+	}
+
+	if err := os.Remove(filepath.Join(bin, "podman")); err != nil {
+		t.Fatal(err)
+	}
+	runtimeFlag = "podman"
+	if _, _, err := newRuntime(); err == nil || !strings.Contains(err.Error(), "--runtime podman but podman is not available") {
+		t.Errorf("expected unavailable runtime error, got %v", err)
+	}
+}
@@ -3,10 +3,8 @@ package cmd
 import (
 	"bytes"
 	"fmt"
-	"os"
 	"os/exec"
 	"strings"
-	"syscall"
 
 	"github.com/fgrehm/crib/internal/plugin"
 	"github.com/spf13/cobra"
@@ -45,7 +43,7 @@ Use -- to separate crib flags from the container command:
 		// Detect the user's shell in the container (same logic as crib shell).
 		var buf bytes.Buffer
 		detectionCmd := []string{"/bin/sh", "-c", "command -v zsh || command -v bash || command -v sh"}
-		_ = ociDrv.ExecContainer(cmd.Context(), ws.ID, container.ID, detectionCmd, nil, &buf, nil, nil, "")
+		_ = ociDrv.ExecContainer(cmd.Context(), ws.ID, container.ID, detectionCmd, nil, &buf, nil, nil, "", "")
 		shellPath := strings.TrimSpace(buf.String())
 		if shellPath == "" {
 			shellPath = "/bin/sh"
@@ -92,9 +90,7 @@ Use -- to separate crib flags from the container command:
 		escaped := plugin.ShellQuoteJoin(args)
 		execArgs = append(execArgs, container.ID, shellPath, "-lc", escaped)
 
-		// syscall.Exec replaces the current process with the container runtime.
-		// On success it never returns; the only return path is an error.
-		return syscall.Exec(runtimeBin, execArgs, os.Environ())
+		return execRuntime(runtimeBin, execArgs)
 	},
 }
 
@@ -3,10 +3,8 @@ package cmd
 import (
 	"bytes"
 	"fmt"
-	"os"
 	"os/exec"
 	"strings"
-	"syscall"
 
 	"github.com/spf13/cobra"
 )
@@ -20,7 +18,12 @@ var shellCmd = &cobra.Command{
 The shell command automatically detects the best available shell
 (zsh, bash, or sh in order of preference), sets the SHELL environment
 variable, and starts it as a login shell inside the running container.
-Working directory is set to the workspace folder if available.`,
+Working directory is set to the workspace folder if available.
+
+A login shell sources the profile files (/etc/profile, ~/.profile and
+friends), which is where many images and version managers extend PATH.
+Use --no-login for a faster interactive shell that skips them; the
+environment probed by 'crib up' is injected either way.`,
 	Args: func(cmd *cobra.Command, args []string) error {
 		if len(args) > 0 {
 			return fmt.Errorf("shell does not accept arguments (did you mean 'crib exec -- %s'?)", strings.Join(args, " "))
@@ -28,6 +31,11 @@ Working directory is set to the workspace folder if available.`,
 		return nil
 	},
 	RunE: func(cmd *cobra.Command, args []string) error {
+		login, err := shellLoginForCommand(cmd)
+		if err != nil {
+			return err
+		}
+
 		eng, ociDrv, store, err := newEngine()
 		if err != nil {
 			return err
@@ -46,7 +54,7 @@ Working directory is set to the workspace folder if available.`,
 		// Detect which shell is available in the container
 		var buf bytes.Buffer
 		detectionCmd := []string{"/bin/sh", "-c", "command -v zsh || command -v bash || command -v sh"}
-		_ = ociDrv.ExecContainer(cmd.Context(), ws.ID, container.ID, detectionCmd, nil, &buf, nil, nil, "")
+		_ = ociDrv.ExecContainer(cmd.Context(), ws.ID, container.ID, detectionCmd, nil, &buf, nil, nil, "", "")
 		shellPath := strings.TrimSpace(buf.String())
 		if shellPath == "" {
 			shellPath = "/bin/sh" // final fallback
@@ -82,14 +90,51 @@ Working directory is set to the workspace folder if available.`,
 			execArgs = append(execArgs, "-w", result.WorkspaceFolder)
 		}
 
-		execArgs = append(execArgs, container.ID, shellPath, "-l")
+		execArgs = append(execArgs, container.ID)
+		execArgs = append(execArgs, shellCommandArgs(shellPath, login)...)
 
-		// syscall.Exec replaces the current process with the container runtime.
-		// On success it never returns; the only return path is an error.
-		return syscall.Exec(runtimeBin, execArgs, os.Environ())
+		// The runtime CLI owns the terminal and forwards SIGWINCH resizes to
+		// the exec session. When it has to run as a child instead,
+		// execRuntime relays the resizes to it.
+		return execRuntime(runtimeBin, execArgs)
 	},
 }
 
+func init() {
+	addShellLoginFlags(shellCmd)
+}
+
+// addShellLoginFlags registers --login and --no-login.
+func addShellLoginFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("login", true, "start a login shell that sources profile files")
+	cmd.Flags().Bool("no-login", false, "start a non-login shell, skipping profile files")
+}
+
+// shellLoginForCommand reports whether the shell should be a login shell.
+// --login is the default; --no-login turns it off, and giving both is a
+// usage error.
+func shellLoginForCommand(cmd *cobra.Command) (bool, error) {
+	login, _ := cmd.Flags().GetBool("login")
+	noLogin, _ := cmd.Flags().GetBool("no-login")
+	if noLogin {
+		if cmd.Flags().Changed("login") && login {
+			return false, &errUsage{err: fmt.Errorf("--login and --no-login are mutually exclusive")}
+		}
+		return false, nil
+	}
+	return login, nil
+}
+
+// shellCommandArgs returns the command that starts shellPath, as a login
+// shell when login is set. The exec always allocates a TTY, so the shell is
+// interactive either way.
+func shellCommandArgs(shellPath string, login bool) []string {
+	if login {
+		return []string{shellPath, "-l"}
+	}
+	return []string{shellPath}
+}
+
 var sshCmd = &cobra.Command{
 	Use:    "ssh",
 	Short:  "Not actual SSH",
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func newShellFlagsCommand(t *testing.T, flags map[string]string) *cobra.Command {
+	t.Helper()
+	c := &cobra.Command{Use: "shell"}
+	addShellLoginFlags(c)
+	for name, value := range flags {
+		if err := c.Flags().Set(name, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return c
+}
+
+func TestShellLoginForCommand(t *testing.T) {
+	tests := []struct {
+		name  string
+		flags map[string]string
+		want  []string
+	}{
+		{name: "default", want: []string{"/bin/zsh", "-l"}},
+		{name: "login", flags: map[string]string{"login": "true"}, want: []string{"/bin/zsh", "-l"}},
+		{name: "no-login", flags: map[string]string{"no-login": "true"}, want: []string{"/bin/zsh"}},
+		{name: "login=false", flags: map[string]string{"login": "false"}, want: []string{"/bin/zsh"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			login, err := shellLoginForCommand(newShellFlagsCommand(t, tt.flags))
+			if err != nil {
+				t.Fatalf("shellLoginForCommand: %v", err)
+			}
+			if got := shellCommandArgs("/bin/zsh", login); !slices.Equal(got, tt.want) {
+				t.Errorf("shell command = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShellLoginForCommand_Conflict(t *testing.T) {
+	c := newShellFlagsCommand(t, map[string]string{"login": "true", "no-login": "true"})
+	_, err := shellLoginForCommand(c)
+	var usage *errUsage
+	if !errors.As(err, &usage) {
+		t.Fatalf("expected usage error, got %v", err)
+	}
+}
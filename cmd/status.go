@@ -1,22 +1,56 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"os"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/fgrehm/crib/internal/driver"
+	"github.com/fgrehm/crib/internal/ui"
 	"github.com/spf13/cobra"
 )
 
+var (
+	statusWaitFlag    bool
+	statusTimeoutFlag time.Duration
+	statusQuietFlag   bool
+	statusHookOutput  bool
+)
+
+// statusPollInterval is how often `crib status --wait` re-inspects the container.
+const statusPollInterval = time.Second
+
 var statusCmd = &cobra.Command{
 	Use:     "status",
 	Aliases: []string{"ps"},
 	Short:   "Show the status of the current workspace container",
-	Args:    noArgs,
+	Long: `Show the status of the current workspace container.
+
+With --wait, block until the container is running (and healthy, if it
+defines a healthcheck) before printing. Exits with code 3 if --timeout
+elapses first, so CI can gate on readiness after starting 'crib up' in the
+background.
+
+With --quiet, print only the container ID, one per line, for use in
+scripts such as 'docker inspect $(crib ps -q)'. For compose workspaces it
+prints the IDs of all running service containers. Exits non-zero when
+there is no container.
+
+With --hook-output, also print the last few KB of output from the most
+recent run of each lifecycle hook. Use 'crib logs --hooks' for the full
+output.`,
+	Args: noArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		u := newUI()
 
+		if cmd.Flags().Changed("timeout") && !statusWaitFlag {
+			return &errUsage{err: fmt.Errorf("--timeout requires --wait")}
+		}
+
 		eng, _, store, err := newEngine()
 		if err != nil {
 			return err
@@ -27,6 +61,22 @@ var statusCmd = &cobra.Command{
 			return err
 		}
 
+		if statusWaitFlag {
+			ctx, cancel := context.WithTimeout(cmd.Context(), statusTimeoutFlag)
+			defer cancel()
+			if _, err := eng.WaitReady(ctx, ws, statusPollInterval); err != nil {
+				return err
+			}
+		}
+
+		if statusQuietFlag {
+			ids, err := eng.ContainerIDs(cmd.Context(), ws)
+			if err != nil {
+				return err
+			}
+			return writeContainerIDs(os.Stdout, ids)
+		}
+
 		result, err := eng.Status(cmd.Context(), ws)
 		if err != nil {
 			return err
@@ -34,6 +84,7 @@ var statusCmd = &cobra.Command{
 
 		u.Dim(versionString())
 		u.Header(ws.ID)
+		fmt.Printf("%-12s%s\n", "name", displayWorkspaceName(store, ws.ID))
 		fmt.Printf("%-12s%s\n", "source", ws.Source)
 
 		if result.Container == nil {
@@ -42,11 +93,17 @@ var statusCmd = &cobra.Command{
 		}
 
 		var containerName string
+		var hookOutput map[string]string
 		if stored, _ := store.LoadResult(ws.ID); stored != nil {
 			containerName = stored.ContainerName
+			hookOutput = stored.HookOutput
 		}
 		fmt.Printf("%-12s%s\n", "container", displayContainerName(containerName, ws.ID))
-		fmt.Printf("%-12s%s\n", "status", u.StatusColor(result.Container.State.Status))
+		status := u.StatusColor(result.Container.State.Status)
+		if health := result.Container.State.Health; health != "" {
+			status += " (" + health + ")"
+		}
+		fmt.Printf("%-12s%s\n", "status", status)
 
 		if ports := formatPorts(result.Container.Ports); ports != "" {
 			fmt.Printf("%-12s%s\n", "ports", ports)
@@ -63,10 +120,57 @@ var statusCmd = &cobra.Command{
 			}
 		}
 
+		if statusHookOutput {
+			printHookOutput(u, os.Stdout, hookOutput)
+		}
+
 		return nil
 	},
 }
 
+func init() {
+	statusCmd.Flags().BoolVar(&statusWaitFlag, "wait", false, "wait until the container is running (and healthy, if it has a healthcheck)")
+	statusCmd.Flags().DurationVar(&statusTimeoutFlag, "timeout", 2*time.Minute, "how long --wait blocks before giving up")
+	statusCmd.Flags().BoolVarP(&statusQuietFlag, "quiet", "q", false, "only print container IDs")
+	statusCmd.Flags().BoolVar(&statusHookOutput, "hook-output", false, "show the tail of each lifecycle hook's latest output")
+	statusCmd.MarkFlagsMutuallyExclusive("quiet", "hook-output")
+}
+
+// printHookOutput writes the stored output tail of each lifecycle hook to w,
+// in lifecycle order, each under a header naming the hook.
+func printHookOutput(u *ui.UI, w io.Writer, output map[string]string) {
+	printed := false
+	for _, name := range containerHookNames {
+		out, ok := output[name]
+		if !ok {
+			continue
+		}
+		u.Header(name)
+		if out == "" {
+			u.Dim("(no output)")
+		} else {
+			_, _ = io.WriteString(w, out)
+			if !strings.HasSuffix(out, "\n") {
+				_, _ = io.WriteString(w, "\n")
+			}
+		}
+		printed = true
+	}
+	if !printed {
+		u.Dim("no hook output recorded")
+	}
+}
+
+// writeContainerIDs prints ids to w, one per line.
+func writeContainerIDs(w io.Writer, ids []string) error {
+	for _, id := range ids {
+		if _, err := fmt.Fprintln(w, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // formatPorts formats port bindings into a compact display string.
 // Example: "8080->8080/tcp, 9090->3000/tcp"
 func formatPorts(ports []driver.PortBinding) string {
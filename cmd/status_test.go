@@ -1,10 +1,13 @@
 package cmd
 
 import (
+	"bytes"
 	"testing"
 
 	"github.com/fgrehm/crib/internal/compose"
 	"github.com/fgrehm/crib/internal/driver"
+	"github.com/fgrehm/crib/internal/engine"
+	"github.com/fgrehm/crib/internal/ui"
 )
 
 func TestFormatPorts_Empty(t *testing.T) {
@@ -71,3 +74,40 @@ func TestComposePortsToDriver(t *testing.T) {
 		t.Errorf("got[1] = %+v", got[1])
 	}
 }
+
+func TestWriteContainerIDs(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeContainerIDs(&buf, []string{"abc123", "def456"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "abc123\ndef456\n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
+
+func TestExitCode_NoContainer(t *testing.T) {
+	if got := exitCode(&engine.ErrNoContainer{WorkspaceID: "ws"}); got == exitOK {
+		t.Error("missing container should exit non-zero")
+	}
+}
+
+func TestPrintHookOutput(t *testing.T) {
+	var out bytes.Buffer
+	printHookOutput(ui.New(&out, &out), &out, map[string]string{
+		"postStartCommand":  "",
+		"postCreateCommand": "added 42 packages",
+		"onCreateCommand":   "setting up\n",
+	})
+	want := "==> onCreateCommand\nsetting up\n" +
+		"==> postCreateCommand\nadded 42 packages\n" +
+		"==> postStartCommand\n(no output)\n"
+	if out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+
+	out.Reset()
+	printHookOutput(ui.New(&out, &out), &out, nil)
+	if out.String() != "no hook output recorded\n" {
+		t.Errorf("output = %q", out.String())
+	}
+}
@@ -1,14 +1,24 @@
 package cmd
 
 import (
+	"time"
+
 	"github.com/spf13/cobra"
 )
 
+var stopTimeoutFlag time.Duration
+
 var stopCmd = &cobra.Command{
 	Use:   "stop",
 	Short: "Stop the workspace container",
-	Long:  "Stop the workspace container without removing it. Hook markers are preserved so the next 'up' resumes with only postStartCommand and postAttachCommand. Use 'down' to remove the container and re-run all hooks on next 'up'.",
-	Args:  noArgs,
+	Long: `Stop the workspace container without removing it. Hook markers are preserved
+so the next 'up' resumes with only postStartCommand and postAttachCommand. Use
+'down' to remove the container and re-run all hooks on next 'up'.
+
+--stop-timeout sets how long the container (or compose services) get to exit
+after SIGTERM before they are killed, overriding customizations.crib.stopTimeout
+(in seconds). Without either, the runtime's default applies.`,
+	Args: noArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		u := newUI()
 
@@ -16,6 +26,11 @@ var stopCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
+		if cmd.Flags().Changed("stop-timeout") {
+			if err := eng.SetStopTimeout(stopTimeoutFlag); err != nil {
+				return &errUsage{err: err}
+			}
+		}
 
 		ws, err := currentWorkspace(store, false)
 		if err != nil {
@@ -37,3 +52,7 @@ var stopCmd = &cobra.Command{
 		return nil
 	},
 }
+
+func init() {
+	stopCmd.Flags().DurationVar(&stopTimeoutFlag, "stop-timeout", 0, "how long to wait for the container to exit before killing it, e.g. 30s (default: the runtime's)")
+}
@@ -1,29 +1,106 @@
 package cmd
 
 import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/fgrehm/crib/internal/engine"
+	"github.com/fgrehm/crib/internal/ui"
+	"github.com/fgrehm/crib/internal/workspace"
 	"github.com/spf13/cobra"
 )
 
-var recreateFlag bool
+var (
+	recreateFlag       string
+	recreateDepsFlag   bool
+	hookRetriesFlag    int
+	foregroundFlag     bool
+	upTimeoutFlag      time.Duration
+	upTimingsFlag      bool
+	waitForTimeoutFlag time.Duration
+	waitForFailFlag    bool
+	rebuildChangedFlag bool
+	replaceFlag        bool
+)
 
 var upCmd = &cobra.Command{
 	Use:   "up",
 	Short: "Create or start the workspace container",
 	Args:  noArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		opts, err := upOptionsForRecreate(recreateFlag)
+		if err != nil {
+			return err
+		}
+		if replaceFlag && (opts.Recreate || opts.RebuildImage) {
+			return &errUsage{err: errors.New("--replace and --recreate are mutually exclusive")}
+		}
+		opts.RecreateDeps = recreateDepsFlag
+		opts.RebuildIfChanged = rebuildChangedFlag
+		opts.Replace = replaceFlag
+		opts.Timeout = upTimeoutFlag
+
 		u := newUI()
 
 		eng, d, store, err := newEngine()
 		if err != nil {
 			return err
 		}
-		eng.SetOutput(os.Stdout, os.Stderr)
+		progress, err := attachProgress(u, eng)
+		if err != nil {
+			return err
+		}
+		defer progress.Stop()
 		eng.SetVerbose(verboseFlag || debugFlag)
-		eng.SetProgress(func(ev engine.ProgressEvent) { u.Dim("  " + ev.Message) })
+		eng.SetHookRetries(hookRetriesFlag)
+		eng.SetWaitForTimeout(waitForTimeoutFlag, waitForFailFlag)
+		eng.SetCacheTo(cacheToForCommand(cmd))
+		eng.SetKeepGenerated(keepOverrideForCommand(cmd))
+		if err := eng.SetExtraHosts(addHostsForCommand(cmd)); err != nil {
+			return &errUsage{err: err}
+		}
+		if err := setExtraMounts(cmd, eng); err != nil {
+			return err
+		}
+		eng.SetGPUs(gpusForCommand(cmd))
+		eng.SetNetwork(networkForCommand(cmd))
+		eng.SetResourceLimits(resourceLimitsForCommand(cmd))
+		eng.SetAutoPort(autoPortForCommand(cmd))
+		if err := setEnvProbe(cmd, eng); err != nil {
+			return err
+		}
+		if err := setCPUSet(cmd, eng); err != nil {
+			return err
+		}
+		if err := setCapDrop(cmd, eng); err != nil {
+			return err
+		}
+		if err := setMountDockerSocket(cmd, eng); err != nil {
+			return err
+		}
+		if err := setLabelFiles(cmd, eng); err != nil {
+			return err
+		}
+		if err := eng.SetTmpfs(tmpfsForCommand(cmd)); err != nil {
+			return &errUsage{err: err}
+		}
+		if err := setWorkspaceFolder(cmd, eng); err != nil {
+			return err
+		}
+		if err := setBuildSecrets(cmd, eng); err != nil {
+			return err
+		}
+		if err := setBuildContexts(cmd, eng); err != nil {
+			return err
+		}
 		setupPlugins(cmd, eng, d)
+		setTrustPrompt(cmd, eng, progress.Writer(os.Stderr))
 
 		ws, err := currentWorkspace(store, true)
 		if err != nil {
@@ -38,7 +115,10 @@ var upCmd = &cobra.Command{
 		u.Dim(versionString())
 		u.Header("Starting workspace")
 
-		result, err := eng.Up(cmd.Context(), ws, engine.UpOptions{Recreate: recreateFlag})
+		started := time.Now()
+		result, err := eng.Up(cmd.Context(), ws, opts)
+		progress.Stop()
+		recordHistory(store, ws.ID, "up", started, upHistoryDetail(opts), err)
 		if err != nil {
 			return err
 		}
@@ -52,12 +132,431 @@ var upCmd = &cobra.Command{
 		if ports := formatPorts(result.Ports); ports != "" {
 			u.Keyval("ports", ports)
 		}
+		if upTimingsFlag || verboseFlag || debugFlag {
+			printTimings(u, result.Timings, time.Since(started))
+		}
+
+		if foregroundFlag {
+			// Don't hold the workspace lock while streaming; other commands
+			// (e.g. crib down from another terminal) must still work.
+			lock.Unlock() //nolint:errcheck // best-effort cleanup
+			u.Header("Attaching to container output (Ctrl-C to detach)")
+			err := eng.Foreground(cmd.Context(), ws)
+			if errors.Is(err, context.Canceled) {
+				u.Dim("Detached; the container keeps running")
+				return nil
+			}
+			return err
+		}
 
 		return nil
 	},
 }
 
 func init() {
-	upCmd.Flags().BoolVar(&recreateFlag, "recreate", false, "recreate container even if one already exists")
+	upCmd.Flags().StringVar(&recreateFlag, "recreate", "", `recreate container even if one already exists; --recreate=image rebuilds the image first and keeps the container if the image is unchanged`)
+	upCmd.Flags().Lookup("recreate").NoOptDefVal = "container"
+	upCmd.Flags().BoolVar(&rebuildChangedFlag, "rebuild-if-changed", false, "rebuild the image and recreate the container when image, build, features, the Dockerfile or its build context changed since the last up")
+	upCmd.Flags().BoolVar(&replaceFlag, "replace", false, "remove the existing container and create a new one from the cached image, running only resume hooks when a snapshot exists")
+	upCmd.Flags().BoolVar(&recreateDepsFlag, "recreate-deps", false, "with --recreate on a compose workspace, also recreate the services the primary service depends on")
+	upCmd.Flags().IntVar(&hookRetriesFlag, "hook-retries", 0, "retry failing create-time lifecycle hooks up to N times with backoff")
+	addWaitForTimeoutFlags(upCmd)
+	upCmd.Flags().BoolVar(&upTimingsFlag, "timings", false, "print how long each step took (build, container creation, each hook, ...) once the workspace is ready")
+	upCmd.Flags().DurationVar(&upTimeoutFlag, "timeout", 0, "give up (and remove a half-created container) if up takes longer than this, e.g. 15m (0 means no limit)")
+	upCmd.Flags().BoolVar(&foregroundFlag, "foreground", false, "after setup, stream the container's output until it exits and return its exit code (requires overrideCommand: false)")
+	addCacheToFlag(upCmd)
+	addSecretFlag(upCmd)
+	addBuildContextFlag(upCmd)
+	addKeepOverrideFlag(upCmd)
+	addAddHostFlag(upCmd)
+	addGPUsFlag(upCmd)
+	addNetworkFlag(upCmd)
+	addResourceLimitsFlag(upCmd)
+	addAutoPortFlag(upCmd)
+	addCPUSetFlag(upCmd)
+	addCapDropFlag(upCmd)
+	addMountDockerSocketFlag(upCmd)
+	addLabelFileFlag(upCmd)
+	addTmpfsFlag(upCmd)
+	addEnvProbeFlag(upCmd)
+	addWorkspaceFolderFlag(upCmd)
+	addTrustFlag(upCmd)
+	addMountFlag(upCmd)
 	addPluginFlags(upCmd)
+	addProgressFlag(upCmd)
+}
+
+// upOptionsForRecreate maps the --recreate value to UpOptions: "container"
+// (the bare flag) recreates the container from the cached image, "image"
+// rebuilds the image and recreates the container only if it changed.
+func upOptionsForRecreate(mode string) (engine.UpOptions, error) {
+	switch mode {
+	case "":
+		return engine.UpOptions{}, nil
+	case "container":
+		return engine.UpOptions{Recreate: true}, nil
+	case "image":
+		return engine.UpOptions{RebuildImage: true}, nil
+	default:
+		return engine.UpOptions{}, &errUsage{err: fmt.Errorf("invalid --recreate value %q (want container or image)", mode)}
+	}
+}
+
+// upHistoryDetail describes the --recreate mode of an up for crib history.
+func upHistoryDetail(opts engine.UpOptions) string {
+	switch {
+	case opts.Recreate:
+		return "--recreate"
+	case opts.RebuildImage:
+		return "--recreate=image"
+	case opts.RebuildIfChanged:
+		return "--rebuild-if-changed"
+	case opts.Replace:
+		return "--replace"
+	}
+	return ""
+}
+
+// addWaitForTimeoutFlags registers --wait-for-timeout and
+// --wait-for-timeout-fail on commands that run create-time hooks.
+func addWaitForTimeoutFlags(cmd *cobra.Command) {
+	cmd.Flags().DurationVar(&waitForTimeoutFlag, "wait-for-timeout", 0, "report the container ready if the hooks up to waitFor take longer than this, e.g. 10m (0 means no limit); up still waits for the hooks to finish")
+	cmd.Flags().BoolVar(&waitForFailFlag, "wait-for-timeout-fail", false, "with --wait-for-timeout, abort the running hook and fail instead")
+}
+
+// addCacheToFlag registers the repeatable --cache-to flag on commands that
+// build images. Values contain commas, so it is a string array rather than
+// a comma-separated slice.
+func addCacheToFlag(cmd *cobra.Command) {
+	cmd.Flags().StringArray("cache-to", nil,
+		"export build cache to a target, e.g. type=registry,ref=ghcr.io/org/app:cache (repeatable; requires buildx)")
+}
+
+// cacheToForCommand returns the parsed --cache-to values for cmd.
+func cacheToForCommand(cmd *cobra.Command) []string {
+	vals, err := cmd.Flags().GetStringArray("cache-to")
+	if err != nil {
+		return nil
+	}
+	return vals
+}
+
+// addAddHostFlag registers the repeatable --add-host flag on commands that
+// create containers.
+func addAddHostFlag(cmd *cobra.Command) {
+	cmd.Flags().StringArray("add-host", nil,
+		"add a custom /etc/hosts entry to the container, e.g. api.local:10.0.0.5 (repeatable)")
+}
+
+// addHostsForCommand returns the parsed --add-host values for cmd.
+func addHostsForCommand(cmd *cobra.Command) []string {
+	vals, err := cmd.Flags().GetStringArray("add-host")
+	if err != nil {
+		return nil
+	}
+	return vals
+}
+
+// addGPUsFlag registers the --gpus flag on commands that create containers.
+func addGPUsFlag(cmd *cobra.Command) {
+	cmd.Flags().String("gpus", "",
+		`expose GPUs to the container, e.g. all or device=0,1 ("none" overrides hostRequirements.gpu)`)
+}
+
+// gpusForCommand returns the --gpus value for cmd.
+func gpusForCommand(cmd *cobra.Command) string {
+	gpus, _ := cmd.Flags().GetString("gpus")
+	return gpus
+}
+
+// addNetworkFlag registers the --network flag on commands that create
+// containers.
+func addNetworkFlag(cmd *cobra.Command) {
+	cmd.Flags().String("network", "",
+		"connect the container to an existing network (overrides customizations.crib.network)")
+}
+
+// networkForCommand returns the --network value for cmd.
+func networkForCommand(cmd *cobra.Command) string {
+	network, _ := cmd.Flags().GetString("network")
+	return network
+}
+
+// addResourceLimitsFlag registers the --resource-limits flag on commands
+// that create containers.
+func addResourceLimitsFlag(cmd *cobra.Command) {
+	cmd.Flags().Bool("resource-limits", false,
+		"limit the container to hostRequirements.cpus and hostRequirements.memory")
+}
+
+// resourceLimitsForCommand returns the --resource-limits value for cmd.
+func resourceLimitsForCommand(cmd *cobra.Command) bool {
+	v, _ := cmd.Flags().GetBool("resource-limits")
+	return v
+}
+
+// printTimings prints the duration of each step of an up, followed by the
+// total wall time.
+func printTimings(u *ui.UI, steps []engine.StepTiming, total time.Duration) {
+	rows := make([][]string, 0, len(steps)+1)
+	for _, s := range steps {
+		rows = append(rows, []string{s.Name, formatStepDuration(s.Duration)})
+	}
+	rows = append(rows, []string{"total", formatStepDuration(total)})
+	u.Header("Timings")
+	u.Table([]string{"STEP", "DURATION"}, rows)
+}
+
+// formatStepDuration rounds d for display: to the millisecond below a
+// second, to hundredths of a second above.
+func formatStepDuration(d time.Duration) string {
+	if d < time.Second {
+		return d.Round(time.Millisecond).String()
+	}
+	return d.Round(10 * time.Millisecond).String()
+}
+
+// addCPUSetFlag registers the --cpuset flag on commands that create
+// containers.
+func addCPUSetFlag(cmd *cobra.Command) {
+	cmd.Flags().String("cpuset", "",
+		"pin the container to these host CPUs, e.g. 0-3 or 0,2 (passed as --cpuset-cpus)")
+}
+
+// setCPUSet applies the --cpuset value to eng.
+func setCPUSet(cmd *cobra.Command, eng *engine.Engine) error {
+	cpus, _ := cmd.Flags().GetString("cpuset")
+	if err := eng.SetCPUSet(cpus); err != nil {
+		return &errUsage{err: err}
+	}
+	return nil
+}
+
+// addCapDropFlag registers the --cap-drop flag on commands that create
+// containers.
+func addCapDropFlag(cmd *cobra.Command) {
+	cmd.Flags().StringArray("cap-drop", nil,
+		"drop a Linux capability from the container, e.g. NET_RAW or ALL (repeatable)")
+}
+
+// setCapDrop applies the --cap-drop values to eng.
+func setCapDrop(cmd *cobra.Command, eng *engine.Engine) error {
+	caps, _ := cmd.Flags().GetStringArray("cap-drop")
+	if err := eng.SetCapDrop(caps); err != nil {
+		return &errUsage{err: err}
+	}
+	return nil
+}
+
+// addMountDockerSocketFlag registers --mount-docker-socket on commands that
+// create containers.
+func addMountDockerSocketFlag(cmd *cobra.Command) {
+	cmd.Flags().Bool("mount-docker-socket", false,
+		"bind the docker (or podman) socket into the container at /var/run/docker.sock; asks for trust unless --trust is set")
+}
+
+// setMountDockerSocket applies --mount-docker-socket to eng.
+func setMountDockerSocket(cmd *cobra.Command, eng *engine.Engine) error {
+	mount, _ := cmd.Flags().GetBool("mount-docker-socket")
+	return eng.SetMountDockerSocket(mount)
+}
+
+// addLabelFileFlag registers --label-file on commands that create
+// containers.
+func addLabelFileFlag(cmd *cobra.Command) {
+	cmd.Flags().StringArray("label-file", nil,
+		"add container labels from a file of KEY=VALUE lines (repeatable)")
+}
+
+// setLabelFiles reads the --label-file files into eng.
+func setLabelFiles(cmd *cobra.Command, eng *engine.Engine) error {
+	paths, _ := cmd.Flags().GetStringArray("label-file")
+	if err := eng.SetLabelFiles(paths); err != nil {
+		return &errUsage{err: err}
+	}
+	return nil
+}
+
+// addTmpfsFlag registers --tmpfs on commands that create containers.
+func addTmpfsFlag(cmd *cobra.Command) {
+	cmd.Flags().StringArray("tmpfs", nil,
+		"mount a tmpfs in the container, e.g. /tmp or /tmp:size=1g (repeatable)")
+}
+
+// tmpfsForCommand returns the --tmpfs values for cmd.
+func tmpfsForCommand(cmd *cobra.Command) []string {
+	v, _ := cmd.Flags().GetStringArray("tmpfs")
+	return v
+}
+
+// addAutoPortFlag registers the --auto-port flag on commands that create
+// containers.
+func addAutoPortFlag(cmd *cobra.Command) {
+	cmd.Flags().Bool("auto-port", false,
+		"publish forwarded ports whose host port is taken on a free host port instead of failing")
+}
+
+// autoPortForCommand returns the --auto-port value for cmd.
+func autoPortForCommand(cmd *cobra.Command) bool {
+	v, _ := cmd.Flags().GetBool("auto-port")
+	return v
+}
+
+// addEnvProbeFlag registers the --env-probe flag on commands that run
+// container setup.
+func addEnvProbeFlag(cmd *cobra.Command) {
+	cmd.Flags().String("env-probe", "",
+		"override userEnvProbe for this run: none, loginShell, interactiveShell or loginInteractiveShell")
+}
+
+// setEnvProbe passes the --env-probe value of cmd to eng.
+func setEnvProbe(cmd *cobra.Command, eng *engine.Engine) error {
+	probe, _ := cmd.Flags().GetString("env-probe")
+	if err := eng.SetEnvProbe(probe); err != nil {
+		return &errUsage{err: err}
+	}
+	return nil
+}
+
+// addWorkspaceFolderFlag registers the --workspace-folder flag on commands
+// that create containers.
+func addWorkspaceFolderFlag(cmd *cobra.Command) {
+	cmd.Flags().String("workspace-folder", "",
+		"mount the project at this absolute path in the container, overriding workspaceFolder")
+}
+
+// setWorkspaceFolder passes the --workspace-folder value of cmd to eng.
+func setWorkspaceFolder(cmd *cobra.Command, eng *engine.Engine) error {
+	dir, _ := cmd.Flags().GetString("workspace-folder")
+	if err := eng.SetWorkspaceFolder(dir); err != nil {
+		return &errUsage{err: err}
+	}
+	return nil
+}
+
+// addSecretFlag registers the repeatable --secret flag on commands that build
+// images.
+func addSecretFlag(cmd *cobra.Command) {
+	cmd.Flags().StringArray("secret", nil,
+		"expose a build secret to RUN --mount=type=secret steps, e.g. id=npmrc,src=$HOME/.npmrc (repeatable; requires BuildKit)")
+}
+
+// setBuildSecrets passes the --secret values of cmd to eng, resolving
+// relative sources against the current directory.
+func setBuildSecrets(cmd *cobra.Command, eng *engine.Engine) error {
+	specs, err := cmd.Flags().GetStringArray("secret")
+	if err != nil || len(specs) == 0 {
+		return nil
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("getting working directory: %w", err)
+	}
+	if err := eng.SetBuildSecrets(specs, cwd); err != nil {
+		return &errUsage{err: err}
+	}
+	return nil
+}
+
+// addBuildContextFlag registers the repeatable --build-context flag on
+// commands that build images.
+func addBuildContextFlag(cmd *cobra.Command) {
+	cmd.Flags().StringArray("build-context", nil,
+		"add a named build context for COPY --from=NAME, e.g. assets=../assets or base=docker-image://alpine (repeatable; requires BuildKit)")
+}
+
+// setBuildContexts passes the --build-context values of cmd to eng,
+// resolving relative paths against the current directory.
+func setBuildContexts(cmd *cobra.Command, eng *engine.Engine) error {
+	specs, err := cmd.Flags().GetStringArray("build-context")
+	if err != nil || len(specs) == 0 {
+		return nil
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("getting working directory: %w", err)
+	}
+	if err := eng.SetBuildContexts(specs, cwd); err != nil {
+		return &errUsage{err: err}
+	}
+	return nil
+}
+
+// addMountFlag registers the repeatable --mount flag on commands that create
+// containers.
+func addMountFlag(cmd *cobra.Command) {
+	cmd.Flags().StringArray("mount", nil,
+		"add an ad-hoc mount in devcontainer.json syntax, e.g. type=bind,src=./data,dst=/data (repeatable; relative sources resolve against the current directory)")
+}
+
+// setExtraMounts passes the --mount values of cmd to eng, resolving relative
+// bind sources against the current directory.
+func setExtraMounts(cmd *cobra.Command, eng *engine.Engine) error {
+	specs, err := cmd.Flags().GetStringArray("mount")
+	if err != nil || len(specs) == 0 {
+		return nil
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("getting working directory: %w", err)
+	}
+	if err := eng.SetExtraMounts(specs, cwd); err != nil {
+		return &errUsage{err: err}
+	}
+	return nil
+}
+
+// addTrustFlag registers --trust on commands that create containers.
+func addTrustFlag(cmd *cobra.Command) {
+	cmd.Flags().Bool("trust", false,
+		"allow privileged mode, dangerous capabilities and docker socket mounts requested by the config without prompting (or set CRIB_TRUST=1)")
+}
+
+// setTrustPrompt installs the prompt eng shows before creating a container
+// with dangerous settings. With --trust on cmd or CRIB_TRUST=1 in the
+// environment the settings are accepted without asking. The prompt is written to w (the progress-aware stderr) and
+// declined automatically when stdin is not a terminal.
+func setTrustPrompt(cmd *cobra.Command, eng *engine.Engine, w io.Writer) {
+	trust, _ := cmd.Flags().GetBool("trust")
+	trust = trust || os.Getenv("CRIB_TRUST") == "1"
+	eng.SetTrustPrompt(func(ws *workspace.Workspace, settings []string) (bool, error) {
+		if trust {
+			return true, nil
+		}
+		return trustPrompt(os.Stdin, w, stdinIsTerminal(), ws.ID, settings), nil
+	})
+}
+
+// trustPrompt lists the dangerous settings requested by workspace id and asks
+// whether to allow them. It returns false without asking when interactive is
+// false.
+func trustPrompt(r io.Reader, w io.Writer, interactive bool, id string, settings []string) bool {
+	fmt.Fprintf(w, "Workspace %s asks for elevated access to the host:\n", id)
+	for _, s := range settings {
+		fmt.Fprintf(w, "  - %s\n", s)
+	}
+	if !interactive {
+		return false
+	}
+	fmt.Fprint(w, "Only allow this for projects you trust. Continue? [y/N] ")
+	answer, _ := bufio.NewReader(r).ReadString('\n')
+	answer = strings.TrimSpace(strings.ToLower(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// addKeepOverrideFlag registers --keep-override on commands that generate
+// build files or compose overrides.
+func addKeepOverrideFlag(cmd *cobra.Command) {
+	cmd.Flags().Bool("keep-override", false,
+		"keep the generated .crib-Dockerfile and feature context and print the paths of generated files (or set CRIB_KEEP_OVERRIDE=1)")
+}
+
+// keepOverrideForCommand reports whether generated files should be kept,
+// either via --keep-override on cmd or CRIB_KEEP_OVERRIDE=1.
+func keepOverrideForCommand(cmd *cobra.Command) bool {
+	if os.Getenv("CRIB_KEEP_OVERRIDE") == "1" {
+		return true
+	}
+	keep, _ := cmd.Flags().GetBool("keep-override")
+	return keep
 }
@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/spf13/cobra"
+
+	"github.com/fgrehm/crib/internal/engine"
+)
+
+func TestUpOptionsForRecreate(t *testing.T) {
+	tests := []struct {
+		mode    string
+		want    engine.UpOptions
+		wantErr bool
+	}{
+		{"", engine.UpOptions{}, false},
+		{"container", engine.UpOptions{Recreate: true}, false},
+		{"image", engine.UpOptions{RebuildImage: true}, false},
+		{"volumes", engine.UpOptions{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			got, err := upOptionsForRecreate(tt.mode)
+			if tt.wantErr {
+				var usage *errUsage
+				if !errors.As(err, &usage) {
+					t.Fatalf("expected usage error, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("upOptionsForRecreate(%q) = %+v, want %+v", tt.mode, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpRecreateFlag_BareMeansContainer(t *testing.T) {
+	flag := upCmd.Flags().Lookup("recreate")
+	if flag.NoOptDefVal != "container" {
+		t.Errorf("bare --recreate = %q, want container", flag.NoOptDefVal)
+	}
+}
+
+func TestSetCPUSet_InvalidIsUsageError(t *testing.T) {
+	c := &cobra.Command{Use: "up"}
+	addCPUSetFlag(c)
+	if err := c.Flags().Set("cpuset", "0-"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Rejected while applying flags, before any container is created.
+	err := setCPUSet(c, engine.New(nil, nil, nil, slog.Default()))
+	var usage *errUsage
+	if !errors.As(err, &usage) {
+		t.Fatalf("expected usage error, got %v", err)
+	}
+}
+
+func TestSetWorkspaceFolder_RelativeIsUsageError(t *testing.T) {
+	c := &cobra.Command{Use: "up"}
+	addWorkspaceFolderFlag(c)
+	if err := c.Flags().Set("workspace-folder", "src"); err != nil {
+		t.Fatal(err)
+	}
+
+	err := setWorkspaceFolder(c, engine.New(nil, nil, nil, slog.Default()))
+	var usage *errUsage
+	if !errors.As(err, &usage) {
+		t.Fatalf("expected usage error, got %v", err)
+	}
+}
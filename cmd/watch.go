@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fgrehm/crib/internal/engine"
+	"github.com/fgrehm/crib/internal/ui"
+	"github.com/fgrehm/crib/internal/workspace"
+	"github.com/spf13/cobra"
+)
+
+// watchPollInterval is how often `crib watch` checks the config files.
+const watchPollInterval = 500 * time.Millisecond
+
+var watchDebounceFlag time.Duration
+
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Restart or rebuild the workspace when its config changes",
+	Long: `Watch devcontainer.json, the Dockerfile it builds from and its compose
+files, and apply changes to the running workspace as they are saved.
+
+Changes that only need a new container (mounts, ports, env, runArgs, compose
+file contents) restart the workspace like 'crib restart'. Changes to the
+image, Dockerfile, features or build args rebuild it like 'crib rebuild'.
+Anything else, such as an edited lifecycle hook, is reported and left for the
+next up. Saves are debounced: crib waits until the files have been quiet for
+--debounce before acting, so a burst of edits triggers one reload.
+
+A config that does not parse is reported and skipped until the next save.
+Stop watching with Ctrl-C.`,
+	Args: noArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if watchDebounceFlag < 0 {
+			return &errUsage{err: errors.New("--debounce must not be negative")}
+		}
+		u := newUI()
+
+		eng, d, store, err := newEngine()
+		if err != nil {
+			return err
+		}
+		eng.SetVerbose(verboseFlag || debugFlag)
+		setupPlugins(cmd, eng, d)
+
+		ws, err := currentWorkspace(store, false)
+		if err != nil {
+			return err
+		}
+
+		u.Dim(versionString())
+		u.Header("Watching workspace " + ws.ID)
+		files, err := eng.WatchFiles(ws)
+		if err != nil {
+			u.Error(err.Error())
+		}
+		for _, f := range files {
+			if rel, err := filepath.Rel(ws.Source, f); err == nil {
+				f = rel
+			}
+			u.Keyval("file", f)
+		}
+
+		return eng.Watch(cmd.Context(), ws, watchPollInterval, watchDebounceFlag, func(action engine.ReloadAction, _ []string) error {
+			reloadWorkspace(cmd, u, eng, store, ws, action)
+			return nil
+		})
+	},
+}
+
+// reloadWorkspace applies action to ws after its config files changed.
+// Failures are reported and leave crib watching for the next change.
+func reloadWorkspace(cmd *cobra.Command, u *ui.UI, eng *engine.Engine, store *workspace.Store, ws *workspace.Workspace, action engine.ReloadAction) {
+	if action == engine.ReloadNone {
+		u.Dim("Config changed, nothing to reload")
+		return
+	}
+
+	progress, err := attachProgress(u, eng)
+	if err != nil {
+		u.Error(err.Error())
+		return
+	}
+	defer progress.Stop()
+	setTrustPrompt(cmd, eng, progress.Writer(os.Stderr))
+
+	ctx := cmd.Context()
+	lock, err := store.Lock(ctx, ws.ID)
+	if err != nil {
+		progress.Stop()
+		u.Error(err.Error())
+		return
+	}
+	defer lock.Unlock() //nolint:errcheck // best-effort cleanup
+
+	started := time.Now()
+	switch action {
+	case engine.ReloadRebuild:
+		u.Header("Rebuilding workspace")
+		// Confirm dangerous settings before the old container is removed.
+		err = eng.CheckTrust(ws)
+		if err == nil {
+			_, err = rebuildWorkspace(ctx, u, eng, ws)
+		}
+		progress.Stop()
+		recordHistory(store, ws.ID, "rebuild", started, "watch", err)
+		if err == nil {
+			u.Success("Workspace rebuilt")
+		}
+	case engine.ReloadRestart:
+		u.Header("Restarting workspace")
+		var result *engine.RestartResult
+		result, err = eng.Restart(ctx, ws)
+		progress.Stop()
+		detail := "watch"
+		if result != nil && result.Recreated {
+			detail = "watch, recreated container"
+		}
+		recordHistory(store, ws.ID, "restart", started, detail, err)
+		if err == nil {
+			if result.Recreated {
+				u.Success("Workspace recreated")
+			} else {
+				u.Success("Workspace restarted")
+			}
+		}
+	}
+	if err != nil && ctx.Err() == nil {
+		u.Error(err.Error())
+	}
+}
+
+func init() {
+	watchCmd.Flags().DurationVar(&watchDebounceFlag, "debounce", time.Second,
+		"wait until the config files have not changed for this long before reloading")
+	addTrustFlag(watchCmd)
+	addPluginFlags(watchCmd)
+	addProgressFlag(watchCmd)
+}
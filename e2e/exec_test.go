@@ -0,0 +1,47 @@
+package e2e
+
+import (
+	"errors"
+	"os/exec"
+	"testing"
+)
+
+// TestE2EExecExitCode verifies that crib exec and crib run exit with the
+// container command's exit status so scripts and CI can gate on it.
+func TestE2EExecExitCode(t *testing.T) {
+	if !hasRuntime() {
+		t.Fatal("container runtime not available or not working (docker or podman required)")
+	}
+	t.Parallel()
+
+	projectDir := setupProject(t)
+	cribHome := t.TempDir()
+
+	t.Cleanup(func() {
+		cmd := cribCmd(projectDir, cribHome, "rm", "--force")
+		_ = cmd.Run()
+	})
+
+	mustRunCrib(t, projectDir, cribHome, "up")
+
+	for _, tc := range []struct {
+		name string
+		args []string
+	}{
+		{"exec", []string{"exec", "--", "sh", "-c", "exit 7"}},
+		{"run", []string{"run", "--", "exit", "7"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			out, err := runCrib(t, projectDir, cribHome, tc.args...)
+			var ee *exec.ExitError
+			if !errors.As(err, &ee) {
+				t.Fatalf("crib %v: want exit status 7, got %v\noutput: %s", tc.args, err, out)
+			}
+			if ee.ExitCode() != 7 {
+				t.Errorf("crib %v: exit status = %d, want 7\noutput: %s", tc.args, ee.ExitCode(), out)
+			}
+		})
+	}
+
+	mustRunCrib(t, projectDir, cribHome, "exec", "--", "true")
+}
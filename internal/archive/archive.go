@@ -0,0 +1,212 @@
+// Package archive streams directory trees as (optionally gzip-compressed)
+// tar archives. It is used by `crib cp` to move files through a container
+// exec's stdin/stdout without a temporary file on either side.
+package archive
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Pack writes src (a file, directory, or symlink) to w as a tar stream. Entry
+// names are relative to src's parent directory, so the archive's top-level
+// entry is filepath.Base(src). File modes and symlinks are preserved;
+// symlinks are archived as links, not followed.
+func Pack(w io.Writer, src string, compress bool) (err error) {
+	if compress {
+		gz := gzip.NewWriter(w)
+		defer func() {
+			if cerr := gz.Close(); err == nil {
+				err = cerr
+			}
+		}()
+		w = gz
+	}
+
+	tw := tar.NewWriter(w)
+	defer func() {
+		if cerr := tw.Close(); err == nil {
+			err = cerr
+		}
+	}()
+
+	src = filepath.Clean(src)
+	base := filepath.Dir(src)
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, walkErr error) error {
+		if walkErr != nil {
+			return walkErr
+		}
+		info, err := os.Lstat(path)
+		if err != nil {
+			return err
+		}
+
+		var link string
+		if info.Mode()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(path); err != nil {
+				return fmt.Errorf("reading symlink %s: %w", path, err)
+			}
+		}
+
+		hdr, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return fmt.Errorf("building tar header for %s: %w", path, err)
+		}
+		rel, err := filepath.Rel(base, path)
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.ToSlash(rel)
+		if info.IsDir() {
+			hdr.Name += "/"
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return fmt.Errorf("writing tar header for %s: %w", path, err)
+		}
+
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(tw, f)
+		_ = f.Close()
+		if err != nil {
+			return fmt.Errorf("archiving %s: %w", path, err)
+		}
+		return nil
+	})
+}
+
+// Unpack extracts a tar stream from r into dst, which is created if needed.
+// Regular files, directories, and symlinks are restored with their modes.
+// Entries that would land outside dst (including through a previously
+// extracted symlink) are rejected.
+func Unpack(r io.Reader, dst string, compress bool) error {
+	if compress {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return fmt.Errorf("creating gzip reader: %w", err)
+		}
+		defer func() { _ = gz.Close() }()
+		r = gz
+	}
+
+	dst, err := filepath.Abs(dst)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dst, 0o755); err != nil {
+		return fmt.Errorf("creating %s: %w", dst, err)
+	}
+
+	// Directory modes are applied last so read-only directories can still
+	// be populated.
+	type dirMode struct {
+		path string
+		mode os.FileMode
+	}
+	var dirs []dirMode
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("reading tar: %w", err)
+		}
+
+		name := strings.TrimPrefix(filepath.Clean("/"+hdr.Name), "/")
+		if name == "" || name == "." {
+			continue
+		}
+		target := filepath.Join(dst, name)
+		if err := checkWithin(dst, filepath.Dir(target)); err != nil {
+			return fmt.Errorf("tar entry %q: %w", hdr.Name, err)
+		}
+
+		mode := os.FileMode(hdr.Mode).Perm() //nolint:gosec // mode comes from the tar header
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0o755); err != nil {
+				return fmt.Errorf("creating directory %s: %w", target, err)
+			}
+			dirs = append(dirs, dirMode{target, mode})
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return fmt.Errorf("creating parent dir for %s: %w", target, err)
+			}
+			_ = os.Remove(target) // replace symlinks rather than writing through them
+			f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+			if err != nil {
+				return fmt.Errorf("creating file %s: %w", target, err)
+			}
+			if _, err := io.Copy(f, tr); err != nil { //nolint:gosec // size bounded by the user's own copy
+				_ = f.Close()
+				return fmt.Errorf("writing file %s: %w", target, err)
+			}
+			if err := f.Close(); err != nil {
+				return fmt.Errorf("writing file %s: %w", target, err)
+			}
+			if err := os.Chmod(target, mode); err != nil {
+				return fmt.Errorf("setting mode on %s: %w", target, err)
+			}
+		case tar.TypeSymlink:
+			if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return fmt.Errorf("creating parent dir for %s: %w", target, err)
+			}
+			_ = os.Remove(target)
+			if err := os.Symlink(hdr.Linkname, target); err != nil {
+				return fmt.Errorf("creating symlink %s: %w", target, err)
+			}
+		}
+	}
+
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if err := os.Chmod(dirs[i].path, dirs[i].mode); err != nil {
+			return fmt.Errorf("setting mode on %s: %w", dirs[i].path, err)
+		}
+	}
+	return nil
+}
+
+// checkWithin returns an error if dir, after resolving symlinks in the part
+// that already exists, is not root or a descendant of it.
+func checkWithin(root, dir string) error {
+	resolvedRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return err
+	}
+	// Walk up to the deepest existing ancestor; the rest will be created as
+	// plain directories.
+	existing := dir
+	for {
+		if _, err := os.Lstat(existing); err == nil {
+			break
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			break
+		}
+		existing = parent
+	}
+	resolved, err := filepath.EvalSymlinks(existing)
+	if err != nil {
+		return err
+	}
+	if resolved != resolvedRoot && !strings.HasPrefix(resolved, resolvedRoot+string(os.PathSeparator)) {
+		return fmt.Errorf("path escapes destination %s", root)
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package archive
+
+import (
+	"archive/tar"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// buildTree creates src/{top.txt, bin/run.sh (0755), nested/deep/file.txt, link -> top.txt}.
+func buildTree(t *testing.T) string {
+	t.Helper()
+	src := filepath.Join(t.TempDir(), "src")
+	mustWrite(t, filepath.Join(src, "top.txt"), "top", 0o644)
+	mustWrite(t, filepath.Join(src, "bin", "run.sh"), "#!/bin/sh\necho hi\n", 0o755)
+	mustWrite(t, filepath.Join(src, "nested", "deep", "file.txt"), "deep", 0o600)
+	if err := os.Symlink("top.txt", filepath.Join(src, "link")); err != nil {
+		t.Fatal(err)
+	}
+	return src
+}
+
+func mustWrite(t *testing.T, path, content string, mode os.FileMode) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), mode); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(path, mode); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPackUnpack_RoundTrip(t *testing.T) {
+	for _, compress := range []bool{true, false} {
+		name := "plain"
+		if compress {
+			name = "gzip"
+		}
+		t.Run(name, func(t *testing.T) {
+			src := buildTree(t)
+			var buf bytes.Buffer
+			if err := Pack(&buf, src, compress); err != nil {
+				t.Fatalf("Pack: %v", err)
+			}
+
+			dst := t.TempDir()
+			if err := Unpack(&buf, dst, compress); err != nil {
+				t.Fatalf("Unpack: %v", err)
+			}
+
+			out := filepath.Join(dst, "src")
+			for rel, want := range map[string]string{
+				"top.txt":              "top",
+				"bin/run.sh":           "#!/bin/sh\necho hi\n",
+				"nested/deep/file.txt": "deep",
+			} {
+				got, err := os.ReadFile(filepath.Join(out, rel))
+				if err != nil {
+					t.Fatalf("reading %s: %v", rel, err)
+				}
+				if string(got) != want {
+					t.Errorf("%s = %q, want %q", rel, got, want)
+				}
+			}
+
+			for rel, want := range map[string]os.FileMode{
+				"bin/run.sh":           0o755,
+				"nested/deep/file.txt": 0o600,
+				"top.txt":              0o644,
+			} {
+				info, err := os.Stat(filepath.Join(out, rel))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if info.Mode().Perm() != want {
+					t.Errorf("%s mode = %o, want %o", rel, info.Mode().Perm(), want)
+				}
+			}
+
+			link, err := os.Readlink(filepath.Join(out, "link"))
+			if err != nil {
+				t.Fatalf("link not preserved as symlink: %v", err)
+			}
+			if link != "top.txt" {
+				t.Errorf("link target = %q, want top.txt", link)
+			}
+		})
+	}
+}
+
+func TestPackUnpack_SingleFile(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "notes.txt")
+	mustWrite(t, src, "hello", 0o640)
+
+	var buf bytes.Buffer
+	if err := Pack(&buf, src, true); err != nil {
+		t.Fatal(err)
+	}
+	dst := t.TempDir()
+	if err := Unpack(&buf, dst, true); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := os.ReadFile(filepath.Join(dst, "notes.txt"))
+	if err != nil || string(got) != "hello" {
+		t.Errorf("notes.txt = %q, %v", got, err)
+	}
+}
+
+func TestUnpack_RejectsWriteThroughSymlink(t *testing.T) {
+	outside := t.TempDir()
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	_ = tw.WriteHeader(&tar.Header{Name: "escape", Typeflag: tar.TypeSymlink, Linkname: outside, Mode: 0o777})
+	_ = tw.WriteHeader(&tar.Header{Name: "escape/pwned", Typeflag: tar.TypeReg, Mode: 0o644, Size: 1})
+	_, _ = tw.Write([]byte("x"))
+	_ = tw.Close()
+
+	if err := Unpack(&buf, t.TempDir(), false); err == nil {
+		t.Fatal("expected error writing through a symlink that leaves the destination")
+	}
+	if _, err := os.Stat(filepath.Join(outside, "pwned")); err == nil {
+		t.Error("file was written outside the destination")
+	}
+}
@@ -7,9 +7,13 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"math"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 )
 
 const (
@@ -22,7 +26,11 @@ const (
 
 // Helper wraps the compose CLI for executing compose commands.
 type Helper struct {
-	// baseCommand is the runtime command (e.g. "docker" or "podman").
+	// runtime is the container runtime command (e.g. "docker" or "podman").
+	runtime string
+	// baseCommand is the executable compose commands run through: the
+	// runtime for the compose plugin, or "docker-compose" for the
+	// standalone binary.
 	baseCommand string
 	// argsPrefix is prepended to all compose commands (e.g. ["compose"]).
 	argsPrefix []string
@@ -35,38 +43,81 @@ type Helper struct {
 // probing for compose availability. Useful for cases where only the runtime
 // identity is needed (e.g. checking if Podman is in use).
 func NewHelperFromRuntime(runtimeCommand string) *Helper {
-	return &Helper{baseCommand: runtimeCommand}
+	return &Helper{runtime: runtimeCommand, baseCommand: runtimeCommand, logger: slog.Default()}
 }
 
+// standaloneCompose is the standalone Compose binary used when the Docker CLI
+// has no compose plugin.
+const standaloneCompose = "docker-compose"
+
 // NewHelper detects the compose CLI and returns a Helper.
-// It probes `<runtimeCommand> compose version --short` to verify availability.
+// It probes `<runtimeCommand> compose version --short` first and, for
+// Docker, falls back to the standalone `docker-compose version --short`.
+// The standalone binary must be Compose v2: v1 lacks `ps --format json` and
+// `up --wait`, and names built images differently.
 func NewHelper(runtimeCommand string, logger *slog.Logger) (*Helper, error) {
-	// Try `<cmd> compose version --short`.
-	// Stdout and stderr are captured separately so that warnings printed to
-	// stderr (e.g. podman-compose's "backed by docker-compose" notice) don't
-	// pollute the version string or the caller's terminal.
-	cmd := exec.Command(runtimeCommand, "compose", "version", "--short")
+	h := NewHelperFromRuntime(runtimeCommand)
+	h.logger = logger
+
+	version, pluginErr := probeCompose(runtimeCommand, "compose")
+	if pluginErr == nil {
+		h.argsPrefix = []string{"compose"}
+	} else {
+		if filepath.Base(runtimeCommand) != "docker" {
+			return nil, fmt.Errorf("%s compose not available: %w", runtimeCommand, pluginErr)
+		}
+		var standaloneErr error
+		version, standaloneErr = probeCompose(standaloneCompose)
+		if standaloneErr != nil {
+			return nil, fmt.Errorf("compose not available: neither `%s compose` (%w) nor `%s` (%w) could be run",
+				runtimeCommand, pluginErr, standaloneCompose, standaloneErr)
+		}
+		if strings.HasPrefix(strings.TrimPrefix(version, "v"), "1.") {
+			return nil, fmt.Errorf("%s %s is not supported: compose v2 required (install the docker compose plugin or docker-compose v2)", standaloneCompose, version)
+		}
+		h.baseCommand = standaloneCompose
+	}
+
+	h.version = version
+	logger.Info("detected compose", "command", h.CommandName(), "version", version)
+	return h, nil
+}
+
+// probeCompose runs `<name> [args...] version --short` and returns the
+// reported version. Stdout and stderr are captured separately so that
+// warnings printed to stderr (e.g. podman-compose's "backed by
+// docker-compose" notice) don't pollute the version string or the caller's
+// terminal.
+func probeCompose(name string, args ...string) (string, error) {
+	cmd := exec.Command(name, append(args, "version", "--short")...)
 	var stdoutBuf, stderrBuf bytes.Buffer
 	cmd.Stdout = &stdoutBuf
 	cmd.Stderr = &stderrBuf
 	if err := cmd.Run(); err != nil {
-		return nil, fmt.Errorf("%s compose not available: %w: %s", runtimeCommand, err, strings.TrimSpace(stderrBuf.String()))
+		if msg := strings.TrimSpace(stderrBuf.String()); msg != "" {
+			return "", fmt.Errorf("%w: %s", err, msg)
+		}
+		return "", err
 	}
-
 	// Take the first non-empty line in case the output contains extra lines.
-	version := firstLine(stdoutBuf.String())
-	logger.Info("detected compose", "command", runtimeCommand+" compose", "version", version)
-
-	h := NewHelperFromRuntime(runtimeCommand)
-	h.argsPrefix = []string{"compose"}
-	h.version = version
-	h.logger = logger
-	return h, nil
+	return firstLine(stdoutBuf.String()), nil
 }
 
 // RuntimeCommand returns the base runtime command (e.g. "docker" or "podman").
 func (h *Helper) RuntimeCommand() string {
-	return h.baseCommand
+	return h.runtime
+}
+
+// Version returns the compose version detected by NewHelper, e.g. "2.29.1".
+// It is empty for helpers created with NewHelperFromRuntime.
+func (h *Helper) Version() string {
+	return h.version
+}
+
+// CommandName returns the compose command as typed in a shell, e.g.
+// "docker compose" or "docker-compose".
+func (h *Helper) CommandName() string {
+	return strings.Join(append([]string{h.baseCommand}, h.argsPrefix...), " ")
 }
 
 // Run executes a compose command with the given args and I/O streams.
@@ -90,34 +141,89 @@ func (h *Helper) Run(ctx context.Context, args []string, stdin io.Reader, stdout
 	}
 
 	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("%s compose %v: %w: %s", h.baseCommand, args, err, stderrBuf.String())
+		return fmt.Errorf("%s %v: %w: %s", h.CommandName(), args, err, stderrBuf.String())
 	}
 	return nil
 }
 
-// Build runs `compose build` for the given project.
+// Build runs `compose build` for the given project. When pull is true,
+// newer versions of the services' base images are always pulled (`--pull`).
 // extraEnv is appended to the subprocess environment for variable substitution.
-func (h *Helper) Build(ctx context.Context, projectName string, files []string, services []string, stdout, stderr io.Writer, extraEnv []string) error {
+func (h *Helper) Build(ctx context.Context, projectName string, files []string, services []string, stdout, stderr io.Writer, extraEnv []string, pull bool) error {
 	args := projectArgs(projectName, files)
 	args = append(args, "build")
+	if pull {
+		args = append(args, "--pull")
+	}
 	args = append(args, services...)
 	return h.Run(ctx, args, nil, stdout, stderr, extraEnv)
 }
 
-// Up runs `compose up -d` for the given project.
+// SupportsWait reports whether `compose up --wait` is available, which needs
+// Docker Compose 2.1.1 or later; podman-compose does not have it.
+func (h *Helper) SupportsWait() bool {
+	if strings.Contains(filepath.Base(h.runtime), "podman") {
+		return false
+	}
+	var major, minor, patch int
+	_, _ = fmt.Sscanf(strings.TrimPrefix(h.version, "v"), "%d.%d.%d", &major, &minor, &patch)
+	return major > 2 || major == 2 && (minor > 1 || minor == 1 && patch >= 1)
+}
+
+// Up runs `compose up -d` for the given project. When noDeps is true,
+// services that the given services depend on are not started or recreated.
+// When wait is true, compose blocks until the services are running and
+// healthy (`--wait`); only pass it when SupportsWait is true.
 // extraEnv is appended to the subprocess environment for variable substitution.
-func (h *Helper) Up(ctx context.Context, projectName string, files []string, services []string, stdout, stderr io.Writer, extraEnv []string) error {
+func (h *Helper) Up(ctx context.Context, projectName string, files []string, services []string, stdout, stderr io.Writer, extraEnv []string, noDeps, wait bool) error {
 	args := projectArgs(projectName, files)
 	args = append(args, "up", "-d")
+	if noDeps {
+		args = append(args, "--no-deps")
+	}
+	if wait {
+		args = append(args, "--wait")
+	}
+	args = append(args, services...)
+	return h.Run(ctx, args, nil, stdout, stderr, extraEnv)
+}
+
+// Remove stops and removes the containers of the given services, leaving the
+// rest of the project running.
+// extraEnv is appended to the subprocess environment for variable substitution.
+func (h *Helper) Remove(ctx context.Context, projectName string, files []string, services []string, stdout, stderr io.Writer, extraEnv []string) error {
+	args := projectArgs(projectName, files)
+	args = append(args, "rm", "--stop", "--force")
 	args = append(args, services...)
 	return h.Run(ctx, args, nil, stdout, stderr, extraEnv)
 }
 
-// Stop runs `compose stop` for the given project.
+// Stop runs `compose stop` for the given project. A non-nil timeout is passed
+// as -t, rounded up to whole seconds.
 // extraEnv is appended to the subprocess environment for variable substitution.
-func (h *Helper) Stop(ctx context.Context, projectName string, files []string, stdout, stderr io.Writer, extraEnv []string) error {
+func (h *Helper) Stop(ctx context.Context, projectName string, files []string, stdout, stderr io.Writer, extraEnv []string, timeout *time.Duration) error {
 	args := projectArgs(projectName, files)
 	args = append(args, "stop")
+	if timeout != nil {
+		args = append(args, "-t", strconv.Itoa(int(math.Ceil(timeout.Seconds()))))
+	}
+	return h.Run(ctx, args, nil, stdout, stderr, extraEnv)
+}
+
+// Pause runs `compose pause` for the given project, freezing all running
+// services.
+// extraEnv is appended to the subprocess environment for variable substitution.
+func (h *Helper) Pause(ctx context.Context, projectName string, files []string, stdout, stderr io.Writer, extraEnv []string) error {
+	args := projectArgs(projectName, files)
+	args = append(args, "pause")
+	return h.Run(ctx, args, nil, stdout, stderr, extraEnv)
+}
+
+// Unpause runs `compose unpause` for the given project.
+// extraEnv is appended to the subprocess environment for variable substitution.
+func (h *Helper) Unpause(ctx context.Context, projectName string, files []string, stdout, stderr io.Writer, extraEnv []string) error {
+	args := projectArgs(projectName, files)
+	args = append(args, "unpause")
 	return h.Run(ctx, args, nil, stdout, stderr, extraEnv)
 }
 
@@ -130,9 +236,10 @@ func (h *Helper) Start(ctx context.Context, projectName string, files []string,
 	return h.Run(ctx, args, nil, stdout, stderr, extraEnv)
 }
 
-// Logs runs `compose logs` for the given project.
+// Logs runs `compose logs` for the given project. With timestamps, each line
+// carries its RFC 3339 timestamp after the container prefix.
 // extraEnv is appended to the subprocess environment for variable substitution.
-func (h *Helper) Logs(ctx context.Context, projectName string, files []string, follow bool, tail string, stdout, stderr io.Writer, extraEnv []string) error {
+func (h *Helper) Logs(ctx context.Context, projectName string, files []string, follow bool, tail string, timestamps bool, stdout, stderr io.Writer, extraEnv []string) error {
 	args := projectArgs(projectName, files)
 	args = append(args, "logs")
 	// Use container names as prefixes instead of container IDs.
@@ -144,6 +251,9 @@ func (h *Helper) Logs(ctx context.Context, projectName string, files []string, f
 	if tail != "" {
 		args = append(args, "--tail", tail)
 	}
+	if timestamps {
+		args = append(args, "--timestamps")
+	}
 	return h.Run(ctx, args, nil, stdout, stderr, extraEnv)
 }
 
@@ -176,7 +286,7 @@ func (h *Helper) ListContainers(ctx context.Context, projectName string, files [
 	cmd.Stderr = &stderrBuf
 
 	if err := cmd.Run(); err != nil {
-		return nil, fmt.Errorf("%s compose %v: %w: %s", h.baseCommand, args, err, stderrBuf.String())
+		return nil, fmt.Errorf("%s %v: %w: %s", h.CommandName(), args, err, stderrBuf.String())
 	}
 
 	return parseLines(stdoutBuf.String()), nil
@@ -201,7 +311,7 @@ func (h *Helper) FindServiceContainerID(ctx context.Context, projectName string,
 	cmd.Stderr = &stderrBuf
 
 	if err := cmd.Run(); err != nil {
-		return "", fmt.Errorf("%s compose ps: %w: %s", h.baseCommand, err, stderrBuf.String())
+		return "", fmt.Errorf("%s ps: %w: %s", h.CommandName(), err, stderrBuf.String())
 	}
 
 	var containers []struct {
@@ -226,7 +336,10 @@ func (h *Helper) FindServiceContainerID(ctx context.Context, projectName string,
 type ServiceStatus struct {
 	Service string
 	State   string
-	Ports   []PortBinding
+	// Health is "starting", "healthy" or "unhealthy" for services with a
+	// healthcheck, and empty otherwise.
+	Health string
+	Ports  []PortBinding
 }
 
 // PortBinding describes a published port mapping for a compose service.
@@ -253,14 +366,17 @@ func (h *Helper) ListServiceStatuses(ctx context.Context, projectName string, fi
 	cmd.Stderr = &stderrBuf
 
 	if err := cmd.Run(); err != nil {
-		return nil, fmt.Errorf("%s compose ps: %w: %s", h.baseCommand, err, stderrBuf.String())
+		return nil, fmt.Errorf("%s ps: %w: %s", h.CommandName(), err, stderrBuf.String())
 	}
 
 	// Parse JSON output. Both Docker and Podman output a JSON array of objects
-	// with "Labels", "State", and "Publishers" fields.
+	// with "Labels", "State", and "Publishers" fields. Docker reports health
+	// in "Health"; Podman only in "Status", e.g. "Up 5 seconds (healthy)".
 	var containers []struct {
 		Labels     map[string]string `json:"Labels"`
 		State      string            `json:"State"`
+		Health     string            `json:"Health"`
+		Status     string            `json:"Status"`
 		Publishers []struct {
 			URL           string `json:"URL"`
 			TargetPort    int    `json:"TargetPort"`
@@ -281,6 +397,10 @@ func (h *Helper) ListServiceStatuses(ctx context.Context, projectName string, fi
 		ss := ServiceStatus{
 			Service: svc,
 			State:   strings.ToLower(c.State),
+			Health:  strings.ToLower(c.Health),
+		}
+		if ss.Health == "" {
+			ss.Health = healthFromStatus(c.Status)
 		}
 		for _, p := range c.Publishers {
 			if p.PublishedPort == 0 {
@@ -299,6 +419,21 @@ func (h *Helper) ListServiceStatuses(ctx context.Context, projectName string, fi
 	return statuses, nil
 }
 
+// healthFromStatus extracts the health from a status line such as
+// "Up 5 seconds (healthy)" or "Up 2 seconds (health: starting)".
+func healthFromStatus(status string) string {
+	_, paren, ok := strings.Cut(status, "(")
+	if !ok {
+		return ""
+	}
+	health := strings.TrimPrefix(strings.TrimSuffix(paren, ")"), "health: ")
+	switch health {
+	case "starting", "healthy", "unhealthy":
+		return health
+	}
+	return ""
+}
+
 // ProjectName returns the compose project name for a workspace.
 // It respects the COMPOSE_PROJECT_NAME env var, falling back to "crib-<wsID>".
 func ProjectName(workspaceID string) string {
@@ -1,13 +1,17 @@
 package compose
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestProjectName_Default(t *testing.T) {
@@ -186,6 +190,55 @@ func TestFindServiceContainerID_MatchesService(t *testing.T) {
 	}
 }
 
+func TestBuild_Pull(t *testing.T) {
+	scriptPath := filepath.Join(t.TempDir(), "fake-compose")
+	if err := os.WriteFile(scriptPath, []byte("#!/bin/sh\necho \"$@\"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	h := &Helper{baseCommand: "/bin/sh", argsPrefix: []string{scriptPath}, logger: slog.Default()}
+
+	for _, tc := range []struct {
+		pull bool
+		want string
+	}{
+		{false, "--project-name myproj build app"},
+		{true, "--project-name myproj build --pull app"},
+	} {
+		var out bytes.Buffer
+		if err := h.Build(context.Background(), "myproj", nil, []string{"app"}, &out, io.Discard, nil, tc.pull); err != nil {
+			t.Fatalf("Build: %v", err)
+		}
+		if got := strings.TrimSpace(out.String()); got != tc.want {
+			t.Errorf("pull=%v: args = %q, want %q", tc.pull, got, tc.want)
+		}
+	}
+}
+
+func TestStop_Timeout(t *testing.T) {
+	scriptPath := filepath.Join(t.TempDir(), "fake-compose")
+	if err := os.WriteFile(scriptPath, []byte("#!/bin/sh\necho \"$@\"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	h := &Helper{baseCommand: "/bin/sh", argsPrefix: []string{scriptPath}, logger: slog.Default()}
+
+	timeout := 90 * time.Second
+	for _, tc := range []struct {
+		timeout *time.Duration
+		want    string
+	}{
+		{nil, "--project-name myproj stop"},
+		{&timeout, "--project-name myproj stop -t 90"},
+	} {
+		var out bytes.Buffer
+		if err := h.Stop(context.Background(), "myproj", nil, &out, io.Discard, nil, tc.timeout); err != nil {
+			t.Fatalf("Stop: %v", err)
+		}
+		if got := strings.TrimSpace(out.String()); got != tc.want {
+			t.Errorf("args = %q, want %q", got, tc.want)
+		}
+	}
+}
+
 func TestFindServiceContainerID_NotFound(t *testing.T) {
 	h := fakeJSONHelper(t, `[
 		{"Id":"aaa111","Labels":{"com.docker.compose.service":"postgres"}}
@@ -215,3 +268,135 @@ func TestFindServiceContainerID_DockerUppercaseID(t *testing.T) {
 		t.Errorf("got %q, want %q", id, "docker123")
 	}
 }
+
+// fakeBin writes an executable shell script named name into dir.
+func fakeBin(t *testing.T, dir, name, script string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+script+"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewHelper_PrefersComposePlugin(t *testing.T) {
+	dir := t.TempDir()
+	fakeBin(t, dir, "docker", `[ "$1" = compose ] && echo 2.29.1`)
+	fakeBin(t, dir, "docker-compose", `echo 1.29.2`)
+	t.Setenv("PATH", dir)
+
+	h, err := NewHelper("docker", slog.Default())
+	if err != nil {
+		t.Fatalf("NewHelper: %v", err)
+	}
+	if got := h.CommandName(); got != "docker compose" {
+		t.Errorf("command = %q, want %q", got, "docker compose")
+	}
+	if got := h.Version(); got != "2.29.1" {
+		t.Errorf("Version() = %q, want 2.29.1", got)
+	}
+}
+
+func TestNewHelper_FallsBackToStandalone(t *testing.T) {
+	dir := t.TempDir()
+	fakeBin(t, dir, "docker", `echo "docker: 'compose' is not a docker command." >&2; exit 1`)
+	fakeBin(t, dir, "docker-compose", `echo 2.29.1`)
+	t.Setenv("PATH", dir)
+
+	h, err := NewHelper("docker", slog.Default())
+	if err != nil {
+		t.Fatalf("NewHelper: %v", err)
+	}
+	if got := h.CommandName(); got != "docker-compose" {
+		t.Errorf("command = %q, want %q", got, "docker-compose")
+	}
+	if h.RuntimeCommand() != "docker" {
+		t.Errorf("RuntimeCommand() = %q, want docker", h.RuntimeCommand())
+	}
+	if got := h.Version(); got != "2.29.1" {
+		t.Errorf("Version() = %q, want 2.29.1", got)
+	}
+}
+
+func TestNewHelper_RejectsStandaloneV1(t *testing.T) {
+	dir := t.TempDir()
+	fakeBin(t, dir, "docker", `echo "docker: 'compose' is not a docker command." >&2; exit 1`)
+	fakeBin(t, dir, "docker-compose", `echo 1.29.2`)
+	t.Setenv("PATH", dir)
+
+	_, err := NewHelper("docker", slog.Default())
+	if err == nil {
+		t.Fatal("expected docker-compose 1.x to be rejected")
+	}
+	if !strings.Contains(err.Error(), "compose v2 required") {
+		t.Errorf("error %q should say compose v2 is required", err)
+	}
+}
+
+func TestNewHelper_NotFound(t *testing.T) {
+	dir := t.TempDir()
+	fakeBin(t, dir, "docker", `echo "docker: 'compose' is not a docker command." >&2; exit 1`)
+	t.Setenv("PATH", dir)
+
+	_, err := NewHelper("docker", slog.Default())
+	if err == nil {
+		t.Fatal("expected an error when no compose command is available")
+	}
+	for _, want := range []string{"docker compose", "docker-compose", "is not a docker command"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q should mention %q", err, want)
+		}
+	}
+}
+
+func TestNewHelper_PodmanDoesNotUseDockerCompose(t *testing.T) {
+	dir := t.TempDir()
+	fakeBin(t, dir, "podman", `exit 1`)
+	fakeBin(t, dir, "docker-compose", `echo 1.29.2`)
+	t.Setenv("PATH", dir)
+
+	if _, err := NewHelper("podman", slog.Default()); err == nil {
+		t.Fatal("expected podman without compose to be an error")
+	}
+}
+
+func TestListServiceStatuses_Health(t *testing.T) {
+	h := fakeJSONHelper(t, `[
+		{"State":"running","Health":"healthy","Labels":{"com.docker.compose.service":"db"}},
+		{"State":"running","Status":"Up 2 seconds (health: starting)","Labels":{"com.docker.compose.service":"cache"}},
+		{"State":"running","Status":"Up 9 seconds (unhealthy)","Labels":{"com.docker.compose.service":"api"}},
+		{"State":"running","Status":"Up 9 seconds","Labels":{"com.docker.compose.service":"app"}}
+	]`)
+
+	statuses, err := h.ListServiceStatuses(context.Background(), "myproj", nil, nil)
+	if err != nil {
+		t.Fatalf("ListServiceStatuses: %v", err)
+	}
+	want := map[string]string{"db": "healthy", "cache": "starting", "api": "unhealthy", "app": ""}
+	for _, s := range statuses {
+		if s.Health != want[s.Service] {
+			t.Errorf("%s: Health = %q, want %q", s.Service, s.Health, want[s.Service])
+		}
+	}
+	if len(statuses) != len(want) {
+		t.Errorf("got %d statuses, want %d", len(statuses), len(want))
+	}
+}
+
+func TestSupportsWait(t *testing.T) {
+	tests := []struct {
+		runtime, version string
+		want             bool
+	}{
+		{"docker", "2.29.1", true},
+		{"docker", "v2.1.1", true},
+		{"docker", "2.0.1", false},
+		{"podman", "1.0.6", false},
+		{"podman", "2.29.1", false},
+	}
+	for _, tt := range tests {
+		h := NewHelperFromRuntime(tt.runtime)
+		h.version = tt.version
+		if got := h.SupportsWait(); got != tt.want {
+			t.Errorf("SupportsWait(%s %s) = %v, want %v", tt.runtime, tt.version, got, tt.want)
+		}
+	}
+}
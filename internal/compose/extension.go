@@ -0,0 +1,78 @@
+package compose
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// ExtensionKey is the top-level compose extension that holds crib
+// settings. Compose ignores x- keys, so the files stay valid for other
+// tools.
+const ExtensionKey = "x-crib"
+
+// CribExtension holds the crib settings from the x-crib block of compose
+// files. They are defaults: the same keys in devcontainer.json win.
+type CribExtension struct {
+	// Service is the primary service, used when devcontainer.json does not
+	// set service.
+	Service string `yaml:"service"`
+
+	// RunServices are the services to start, used when devcontainer.json
+	// does not list any runServices.
+	RunServices []string `yaml:"runServices"`
+}
+
+// extensionKeys are the keys accepted in an x-crib block.
+var extensionKeys = map[string]bool{"service": true, "runServices": true}
+
+// ReadCribExtension reads the x-crib block from the compose files at paths.
+// Like compose merges files, keys set in later files override those of
+// earlier ones. The files are read as plain YAML: there is no variable
+// interpolation and missing files are skipped. Unknown keys are an error so
+// typos don't go unnoticed.
+func ReadCribExtension(paths []string) (*CribExtension, error) {
+	ext := &CribExtension{}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var doc struct {
+			Crib yaml.Node `yaml:"x-crib"`
+		}
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("reading %s: %w", path, err)
+		}
+		if doc.Crib.IsZero() {
+			continue
+		}
+		if doc.Crib.Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("%s in %s must be a mapping", ExtensionKey, path)
+		}
+		for i := 0; i < len(doc.Crib.Content); i += 2 {
+			if key := doc.Crib.Content[i].Value; !extensionKeys[key] {
+				return nil, fmt.Errorf("%s in %s: unknown key %q", ExtensionKey, path, key)
+			}
+		}
+
+		var file CribExtension
+		if err := doc.Crib.Decode(&file); err != nil {
+			return nil, fmt.Errorf("%s in %s: %w", ExtensionKey, path, err)
+		}
+		if file.Service != "" {
+			ext.Service = file.Service
+		}
+		if file.RunServices != nil {
+			ext.RunServices = file.RunServices
+		}
+	}
+	return ext, nil
+}
@@ -0,0 +1,88 @@
+package compose
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func writeComposeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadCribExtension(t *testing.T) {
+	dir := t.TempDir()
+	base := writeComposeFile(t, dir, "compose.yml", `
+x-crib:
+  service: app
+  runServices: [app, db]
+services:
+  app:
+    image: alpine
+  db:
+    image: postgres
+`)
+	override := writeComposeFile(t, dir, "compose.dev.yml", `
+x-crib:
+  service: dev
+services:
+  dev:
+    image: alpine
+`)
+	plain := writeComposeFile(t, dir, "compose.plain.yml", "services:\n  cache:\n    image: redis\n")
+
+	ext, err := ReadCribExtension([]string{base})
+	if err != nil {
+		t.Fatalf("ReadCribExtension: %v", err)
+	}
+	if ext.Service != "app" || !slices.Equal(ext.RunServices, []string{"app", "db"}) {
+		t.Errorf("extension = %+v, want service app and runServices [app db]", ext)
+	}
+
+	// Later files override the keys they set; files without x-crib and
+	// missing files change nothing.
+	ext, err = ReadCribExtension([]string{base, override, plain, filepath.Join(dir, "missing.yml")})
+	if err != nil {
+		t.Fatalf("ReadCribExtension: %v", err)
+	}
+	if ext.Service != "dev" || !slices.Equal(ext.RunServices, []string{"app", "db"}) {
+		t.Errorf("merged extension = %+v, want service dev and runServices [app db]", ext)
+	}
+
+	ext, err = ReadCribExtension([]string{plain})
+	if err != nil {
+		t.Fatalf("ReadCribExtension: %v", err)
+	}
+	if ext.Service != "" || ext.RunServices != nil {
+		t.Errorf("extension without x-crib = %+v, want empty", ext)
+	}
+}
+
+func TestReadCribExtension_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"unknown key", "x-crib:\n  servce: app\n", `unknown key "servce"`},
+		{"not a mapping", "x-crib: app\n", "must be a mapping"},
+		{"wrong type", "x-crib:\n  runServices: app\n", "x-crib in"},
+		{"bad yaml", "x-crib: [\n", "reading"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeComposeFile(t, t.TempDir(), "compose.yml", tt.content)
+			_, err := ReadCribExtension([]string{path})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -54,7 +54,7 @@ func TestIntegrationComposeUpDown(t *testing.T) {
 
 	// Bring up the project.
 	var stdout, stderr bytes.Buffer
-	if err := h.Up(ctx, projectName, []string{composePath}, nil, &stdout, &stderr, nil); err != nil {
+	if err := h.Up(ctx, projectName, []string{composePath}, nil, &stdout, &stderr, nil, false, false); err != nil {
 		t.Fatalf("Up: %v\nstdout: %s\nstderr: %s", err, stdout.String(), stderr.String())
 	}
 
@@ -75,7 +75,7 @@ func TestIntegrationComposeUpDown(t *testing.T) {
 	// Stop the project.
 	stdout.Reset()
 	stderr.Reset()
-	if err := h.Stop(ctx, projectName, []string{composePath}, &stdout, &stderr, nil); err != nil {
+	if err := h.Stop(ctx, projectName, []string{composePath}, &stdout, &stderr, nil, nil); err != nil {
 		t.Fatalf("Stop: %v\nstdout: %s\nstderr: %s", err, stdout.String(), stderr.String())
 	}
 
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/compose-spec/compose-go/v2/dotenv"
@@ -52,12 +53,106 @@ func GetServiceInfo(ctx context.Context, paths []string, serviceName string, env
 	return info, nil
 }
 
+// HealthyDependencies returns the sorted names of the services that
+// services, or any service they transitively depend on, wait for with a
+// depends_on condition of service_healthy. An empty services list means the
+// whole project.
+func HealthyDependencies(project *types.Project, services []string) []string {
+	var names []string
+	for _, svc := range withDependencies(project, services) {
+		for name, cfg := range svc.DependsOn {
+			if cfg.Condition == types.ServiceConditionHealthy && !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// DependsOnCompletion reports whether any of services, or any service they
+// transitively depend on, waits for a one-shot service with condition
+// service_completed_successfully. An empty services list means the whole
+// project.
+func DependsOnCompletion(project *types.Project, services []string) bool {
+	for _, svc := range withDependencies(project, services) {
+		for _, cfg := range svc.DependsOn {
+			if cfg.Condition == types.ServiceConditionCompletedSuccessfully {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// PullableImages returns the distinct images, sorted, that `compose up`
+// would pull for services: those of services with an image and no build
+// section whose pull_policy allows pulling. With withDeps, the services they
+// transitively depend on are included. An empty services list means the
+// whole project.
+func PullableImages(project *types.Project, services []string, withDeps bool) []string {
+	var svcs []types.ServiceConfig
+	if withDeps || len(services) == 0 {
+		svcs = withDependencies(project, services)
+	} else {
+		for _, name := range services {
+			if svc, ok := project.Services[name]; ok {
+				svcs = append(svcs, svc)
+			}
+		}
+	}
+
+	var images []string
+	for _, svc := range svcs {
+		if svc.Image == "" || svc.Build != nil {
+			continue
+		}
+		switch svc.PullPolicy {
+		case types.PullPolicyBuild, types.PullPolicyNever:
+			continue
+		}
+		if !slices.Contains(images, svc.Image) {
+			images = append(images, svc.Image)
+		}
+	}
+	slices.Sort(images)
+	return images
+}
+
+// withDependencies returns services plus every service they transitively
+// depend on. An empty services list means the whole project.
+func withDependencies(project *types.Project, services []string) []types.ServiceConfig {
+	if len(services) == 0 {
+		services = project.ServiceNames()
+	}
+	seen := make(map[string]bool)
+	var result []types.ServiceConfig
+	queue := slices.Clone(services)
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		svc, ok := project.Services[name]
+		if !ok {
+			continue
+		}
+		result = append(result, svc)
+		for dep := range svc.DependsOn {
+			queue = append(queue, dep)
+		}
+	}
+	return result
+}
+
 // BuiltImageName returns the expected image name for a compose-built service.
 // The separator between project and service differs by compose provider:
 // Docker Compose v2 uses "-", podman-compose uses "_".
 func (h *Helper) BuiltImageName(projectName, serviceName string) string {
 	sep := "-"
-	if strings.Contains(filepath.Base(h.baseCommand), "podman") {
+	if strings.Contains(filepath.Base(h.runtime), "podman") {
 		sep = "_"
 	}
 	return projectName + sep + serviceName
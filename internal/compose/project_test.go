@@ -4,6 +4,7 @@ import (
 	"context"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"testing"
 )
 
@@ -167,3 +168,71 @@ func TestBuiltImageName(t *testing.T) {
 		})
 	}
 }
+
+func TestHealthyDependencies(t *testing.T) {
+	_, thisFile, _, _ := runtime.Caller(0)
+	testdataDir := filepath.Join(filepath.Dir(thisFile), "testdata")
+
+	project, err := LoadProject(context.Background(), []string{filepath.Join(testdataDir, "healthy-compose.yml")}, nil, nil)
+	if err != nil {
+		t.Fatalf("LoadProject: %v", err)
+	}
+	tests := []struct {
+		name       string
+		services   []string
+		want       []string
+		completion bool
+	}{
+		{"transitive dependency", []string{"app"}, []string{"db"}, false},
+		{"direct dependency", []string{"api"}, []string{"db"}, false},
+		{"no dependencies", []string{"db"}, nil, false},
+		{"one-shot dependency", []string{"cache"}, nil, true},
+		{"whole project", nil, []string{"db"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HealthyDependencies(project, tt.services); !slices.Equal(got, tt.want) {
+				t.Errorf("HealthyDependencies(%v) = %v, want %v", tt.services, got, tt.want)
+			}
+			if got := DependsOnCompletion(project, tt.services); got != tt.completion {
+				t.Errorf("DependsOnCompletion(%v) = %v, want %v", tt.services, got, tt.completion)
+			}
+		})
+	}
+
+	simple, err := LoadProject(context.Background(), []string{filepath.Join(testdataDir, "simple-compose.yml")}, nil, nil)
+	if err != nil {
+		t.Fatalf("LoadProject: %v", err)
+	}
+	if got := HealthyDependencies(simple, []string{"app"}); len(got) != 0 {
+		t.Errorf("HealthyDependencies = %v for a project without depends_on", got)
+	}
+}
+
+func TestPullableImages(t *testing.T) {
+	_, thisFile, _, _ := runtime.Caller(0)
+	testdataDir := filepath.Join(filepath.Dir(thisFile), "testdata")
+
+	project, err := LoadProject(context.Background(), []string{filepath.Join(testdataDir, "images-compose.yml")}, nil, nil)
+	if err != nil {
+		t.Fatalf("LoadProject: %v", err)
+	}
+	tests := []struct {
+		name     string
+		services []string
+		withDeps bool
+		want     []string
+	}{
+		{"with dependencies", []string{"app"}, true, []string{"ghcr.io/org/api:1.2", "postgres:16"}},
+		{"without dependencies", []string{"app", "cache"}, false, []string{"redis:7"}},
+		{"whole project", nil, false, []string{"ghcr.io/org/api:1.2", "postgres:16", "redis:7"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := PullableImages(project, tt.services, tt.withDeps)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("PullableImages(%v, %v) = %v, want %v", tt.services, tt.withDeps, got, tt.want)
+			}
+		})
+	}
+}
@@ -1,11 +1,14 @@
 package config
 
-import "maps"
+import (
+	"maps"
+	"slices"
+)
 
 // MergeConfiguration merges a DevContainerConfig with image metadata entries
-// to produce a MergedDevContainerConfig. Metadata entries come from features
-// and the base image. The base config takes priority, then entries are applied
-// in reverse order (first entry = highest priority after base).
+// to produce a MergedDevContainerConfig. Metadata entries come from the base
+// image followed by features, so later entries take priority over earlier
+// ones. The base config takes priority over all of them.
 func MergeConfiguration(config *DevContainerConfig, imageMetadata []*ImageMetadata) *MergedDevContainerConfig {
 	// Build the full list: config metadata + image metadata entries.
 	// Reverse for priority (first = highest priority after base config).
@@ -110,6 +113,9 @@ func mergeNonComposeBase(dst *NonComposeBase, base *NonComposeBase, entries []*I
 	// CapAdd: union and deduplicate.
 	dst.CapAdd = mergeStringSlices(base.CapAdd, entries, func(e *ImageMetadata) []string { return e.CapAdd })
 
+	// CapDrop: union and deduplicate.
+	dst.CapDrop = mergeStringSlices(base.CapDrop, entries, func(e *ImageMetadata) []string { return e.CapDrop })
+
 	// SecurityOpt: union and deduplicate.
 	dst.SecurityOpt = mergeStringSlices(base.SecurityOpt, entries, func(e *ImageMetadata) []string { return e.SecurityOpt })
 }
@@ -172,10 +178,11 @@ func firstString[T any](entries []T, get func(T) string) string {
 	return ""
 }
 
-// mergeMaps merges string maps from entries. Later entries override earlier ones.
+// mergeMaps merges string maps from entries. Entries are in priority order,
+// so a key from an earlier entry wins over the same key in later ones.
 func mergeMaps[T any, V comparable](entries []T, get func(T) map[string]V) map[string]V {
 	var result map[string]V
-	for _, e := range entries {
+	for _, e := range slices.Backward(entries) {
 		m := get(e)
 		if len(m) == 0 {
 			continue
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"maps"
 	"testing"
 )
 
@@ -234,6 +235,31 @@ func TestMergeConfiguration_CapAdd(t *testing.T) {
 	}
 }
 
+func TestMergeConfiguration_CapDrop(t *testing.T) {
+	config := &DevContainerConfig{
+		NonComposeBase: NonComposeBase{
+			CapDrop: []string{"NET_RAW"},
+		},
+	}
+	metadata := []*ImageMetadata{
+		{NonComposeBase: NonComposeBase{
+			CapDrop: []string{"MKNOD", "NET_RAW"},
+		}},
+	}
+
+	merged := MergeConfiguration(config, metadata)
+
+	if len(merged.CapDrop) != 2 {
+		t.Fatalf("CapDrop = %v, want NET_RAW and MKNOD", merged.CapDrop)
+	}
+	want := map[string]bool{"NET_RAW": true, "MKNOD": true}
+	for _, c := range merged.CapDrop {
+		if !want[c] {
+			t.Errorf("unexpected cap %q", c)
+		}
+	}
+}
+
 func TestMergeConfiguration_PreservesOrigin(t *testing.T) {
 	config := &DevContainerConfig{
 		Origin: "/path/to/devcontainer.json",
@@ -245,3 +271,74 @@ func TestMergeConfiguration_PreservesOrigin(t *testing.T) {
 		t.Errorf("Origin = %q, want %q", merged.Origin, config.Origin)
 	}
 }
+
+// envPrecedenceMetadata returns image metadata in the order the engine builds
+// it: the base image's label entry first, then features in install order.
+func envPrecedenceMetadata(env func(map[string]string) *ImageMetadata) []*ImageMetadata {
+	return []*ImageMetadata{
+		env(map[string]string{"SHARED": "image", "IMAGE_FEATURE": "image", "IMAGE_ONLY": "image"}),
+		env(map[string]string{"SHARED": "feature-a", "IMAGE_FEATURE": "feature-a", "FEATURES": "feature-a", "FEATURE_ONLY": "feature-a"}),
+		env(map[string]string{"FEATURES": "feature-b"}),
+	}
+}
+
+func TestMergeConfiguration_EnvPrecedence(t *testing.T) {
+	want := map[string]string{
+		"SHARED":        "base",      // base config wins over features and image
+		"IMAGE_FEATURE": "feature-a", // features win over image metadata
+		"FEATURES":      "feature-b", // a later feature wins over an earlier one
+		"FEATURE_ONLY":  "feature-a", // keys only set by a feature survive
+		"IMAGE_ONLY":    "image",
+		"BASE_ONLY":     "base",
+	}
+	baseEnv := map[string]string{"SHARED": "base", "BASE_ONLY": "base"}
+
+	tests := []struct {
+		name   string
+		config *DevContainerConfig
+		meta   func(map[string]string) *ImageMetadata
+		got    func(*MergedDevContainerConfig) map[string]string
+	}{
+		{
+			name:   "containerEnv",
+			config: &DevContainerConfig{NonComposeBase: NonComposeBase{ContainerEnv: baseEnv}},
+			meta: func(env map[string]string) *ImageMetadata {
+				return &ImageMetadata{NonComposeBase: NonComposeBase{ContainerEnv: env}}
+			},
+			got: func(m *MergedDevContainerConfig) map[string]string { return m.ContainerEnv },
+		},
+		{
+			name:   "remoteEnv",
+			config: &DevContainerConfig{DevContainerConfigBase: DevContainerConfigBase{RemoteEnv: baseEnv}},
+			meta: func(env map[string]string) *ImageMetadata {
+				return &ImageMetadata{DevContainerConfigBase: DevContainerConfigBase{RemoteEnv: env}}
+			},
+			got: func(m *MergedDevContainerConfig) map[string]string { return m.RemoteEnv },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged := MergeConfiguration(tt.config, envPrecedenceMetadata(tt.meta))
+			got := tt.got(merged)
+			if !maps.Equal(got, want) {
+				t.Errorf("%s = %v, want %v", tt.name, got, want)
+			}
+		})
+	}
+}
+
+func TestMergeConfiguration_EnvWithoutBase(t *testing.T) {
+	config := &DevContainerConfig{}
+	metadata := envPrecedenceMetadata(func(env map[string]string) *ImageMetadata {
+		return &ImageMetadata{NonComposeBase: NonComposeBase{ContainerEnv: env}}
+	})
+
+	merged := MergeConfiguration(config, metadata)
+
+	if merged.ContainerEnv["SHARED"] != "feature-a" {
+		t.Errorf("ContainerEnv[SHARED] = %q, want %q (feature should win over image)", merged.ContainerEnv["SHARED"], "feature-a")
+	}
+	if merged.RemoteEnv != nil {
+		t.Errorf("RemoteEnv = %v, want nil", merged.RemoteEnv)
+	}
+}
@@ -5,36 +5,82 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/tidwall/jsonc"
 )
 
+// ErrMultipleConfigs is returned by Find when a folder has several
+// .devcontainer/{subfolder}/devcontainer.json configs and no default one to
+// prefer, so the caller has to pick.
+type ErrMultipleConfigs struct {
+	// Dir is the folder that was searched.
+	Dir string
+	// Candidates are the absolute config paths, sorted.
+	Candidates []string
+}
+
+func (e *ErrMultipleConfigs) Error() string {
+	rel := make([]string, len(e.Candidates))
+	for i, c := range e.Candidates {
+		rel[i] = c
+		if r, err := filepath.Rel(e.Dir, c); err == nil {
+			rel[i] = r
+		}
+	}
+	return fmt.Sprintf("multiple devcontainer configs found (%s); pick one with --config", strings.Join(rel, ", "))
+}
+
 // Find searches for a devcontainer.json starting from the given folder.
 // Search order:
+//
 //  1. .devcontainer/devcontainer.json
 //  2. .devcontainer.json
 //  3. .devcontainer/{subfolder}/devcontainer.json (one level deep)
 //
-// Returns the absolute path to the config file, or empty string if not found.
+// Returns the absolute path to the config file, or ErrNotFound when none of
+// the locations has one. When there is no config at 1 or 2 and more than one
+// subfolder config exists, returns *ErrMultipleConfigs.
 func Find(folder string) (string, error) {
 	absFolder, err := filepath.Abs(folder)
 	if err != nil {
 		return "", fmt.Errorf("resolving folder path: %w", err)
 	}
 
-	// 1. .devcontainer/devcontainer.json
-	p := filepath.Join(absFolder, ".devcontainer", "devcontainer.json")
-	if fileExists(p) {
-		return p, nil
+	candidates, err := FindAll(absFolder)
+	if err != nil {
+		return "", err
+	}
+	if len(candidates) == 0 {
+		return "", ErrNotFound
 	}
 
-	// 2. .devcontainer.json
-	p = filepath.Join(absFolder, ".devcontainer.json")
-	if fileExists(p) {
-		return p, nil
+	first := candidates[0]
+	if isDefaultConfigPath(absFolder, first) || len(candidates) == 1 {
+		return first, nil
 	}
+	return "", &ErrMultipleConfigs{Dir: absFolder, Candidates: candidates}
+}
 
-	// 3. .devcontainer/{subfolder}/devcontainer.json (one level deep)
+// FindAll returns every devcontainer.json in the given folder, in the
+// precedence order used by Find. Subfolder configs are sorted by name.
+func FindAll(folder string) ([]string, error) {
+	absFolder, err := filepath.Abs(folder)
+	if err != nil {
+		return nil, fmt.Errorf("resolving folder path: %w", err)
+	}
+
+	var found []string
+	for _, p := range []string{
+		filepath.Join(absFolder, ".devcontainer", "devcontainer.json"),
+		filepath.Join(absFolder, ".devcontainer.json"),
+	} {
+		if fileExists(p) {
+			found = append(found, p)
+		}
+	}
+
+	// os.ReadDir returns entries sorted by name.
 	devcontainerDir := filepath.Join(absFolder, ".devcontainer")
 	entries, err := os.ReadDir(devcontainerDir)
 	if err == nil {
@@ -42,14 +88,21 @@ func Find(folder string) (string, error) {
 			if !entry.IsDir() {
 				continue
 			}
-			p = filepath.Join(devcontainerDir, entry.Name(), "devcontainer.json")
+			p := filepath.Join(devcontainerDir, entry.Name(), "devcontainer.json")
 			if fileExists(p) {
-				return p, nil
+				found = append(found, p)
 			}
 		}
 	}
 
-	return "", nil
+	return found, nil
+}
+
+// isDefaultConfigPath reports whether path is one of the top-level config
+// locations that take precedence over subfolder configs.
+func isDefaultConfigPath(folder, path string) bool {
+	return path == filepath.Join(folder, ".devcontainer", "devcontainer.json") ||
+		path == filepath.Join(folder, ".devcontainer.json")
 }
 
 // Parse reads and parses a devcontainer.json file at the given path.
@@ -1,8 +1,10 @@
 package config
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 )
@@ -49,6 +51,25 @@ func TestFind(t *testing.T) {
 			},
 			filepath.Join(".devcontainer", "devcontainer.json"),
 		},
+		{
+			"prefers .devcontainer.json over subfolder configs",
+			func(t *testing.T, dir string) {
+				t.Helper()
+				mkdirAll(t, filepath.Join(dir, ".devcontainer", "python"))
+				writeFile(t, filepath.Join(dir, ".devcontainer", "python", "devcontainer.json"), `{"image":"python"}`)
+				writeFile(t, filepath.Join(dir, ".devcontainer.json"), `{"image":"ubuntu"}`)
+			},
+			".devcontainer.json",
+		},
+		{
+			"empty .devcontainer dir falls back to .devcontainer.json",
+			func(t *testing.T, dir string) {
+				t.Helper()
+				mkdirAll(t, filepath.Join(dir, ".devcontainer"))
+				writeFile(t, filepath.Join(dir, ".devcontainer.json"), `{"image":"ubuntu"}`)
+			},
+			".devcontainer.json",
+		},
 		{
 			"no config found",
 			func(t *testing.T, dir string) {
@@ -64,16 +85,15 @@ func TestFind(t *testing.T) {
 			tt.setup(t, dir)
 
 			got, err := Find(dir)
-			if err != nil {
-				t.Fatalf("unexpected error: %v", err)
-			}
-
 			if tt.wantFile == "" {
-				if got != "" {
-					t.Errorf("expected empty, got %q", got)
+				if !errors.Is(err, ErrNotFound) {
+					t.Errorf("expected ErrNotFound, got %q, %v", got, err)
 				}
 				return
 			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
 
 			want := filepath.Join(dir, tt.wantFile)
 			if got != want {
@@ -83,6 +103,65 @@ func TestFind(t *testing.T) {
 	}
 }
 
+func TestFind_MultipleSubfolderConfigs(t *testing.T) {
+	dir := t.TempDir()
+	for _, sub := range []string{"python", "go"} {
+		mkdirAll(t, filepath.Join(dir, ".devcontainer", sub))
+		writeFile(t, filepath.Join(dir, ".devcontainer", sub, "devcontainer.json"), `{"image":"`+sub+`"}`)
+	}
+
+	_, err := Find(dir)
+	var multi *ErrMultipleConfigs
+	if !errors.As(err, &multi) {
+		t.Fatalf("expected *ErrMultipleConfigs, got %v", err)
+	}
+	want := []string{
+		filepath.Join(dir, ".devcontainer", "go", "devcontainer.json"),
+		filepath.Join(dir, ".devcontainer", "python", "devcontainer.json"),
+	}
+	if !slices.Equal(multi.Candidates, want) {
+		t.Errorf("Candidates = %v, want %v", multi.Candidates, want)
+	}
+	if !strings.Contains(err.Error(), filepath.Join(".devcontainer", "go", "devcontainer.json")) {
+		t.Errorf("error should list relative candidates, got %q", err.Error())
+	}
+
+	// A default config resolves the ambiguity.
+	writeFile(t, filepath.Join(dir, ".devcontainer.json"), `{"image":"ubuntu"}`)
+	got, err := Find(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != filepath.Join(dir, ".devcontainer.json") {
+		t.Errorf("got %q, want .devcontainer.json", got)
+	}
+}
+
+func TestFindAll(t *testing.T) {
+	dir := t.TempDir()
+	mkdirAll(t, filepath.Join(dir, ".devcontainer", "python"))
+	mkdirAll(t, filepath.Join(dir, ".devcontainer", "go"))
+	mkdirAll(t, filepath.Join(dir, ".devcontainer", "empty"))
+	mkdirAll(t, filepath.Join(dir, ".devcontainer", "go", "nested"))
+	writeFile(t, filepath.Join(dir, ".devcontainer", "devcontainer.json"), `{"image":"ubuntu"}`)
+	writeFile(t, filepath.Join(dir, ".devcontainer", "python", "devcontainer.json"), `{"image":"python"}`)
+	writeFile(t, filepath.Join(dir, ".devcontainer", "go", "devcontainer.json"), `{"image":"golang"}`)
+	writeFile(t, filepath.Join(dir, ".devcontainer", "go", "nested", "devcontainer.json"), `{"image":"too-deep"}`)
+
+	got, err := FindAll(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		filepath.Join(dir, ".devcontainer", "devcontainer.json"),
+		filepath.Join(dir, ".devcontainer", "go", "devcontainer.json"),
+		filepath.Join(dir, ".devcontainer", "python", "devcontainer.json"),
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("FindAll = %v, want %v", got, want)
+	}
+}
+
 func TestParse_ImageBased(t *testing.T) {
 	config, err := Parse(testdataPath("minimal-image.json"))
 	if err != nil {
@@ -85,7 +85,7 @@ func normalizeConfigForHash(config *DevContainerConfig) ([]byte, error) {
 		Image:      config.Image,
 		Dockerfile: config.Dockerfile,
 		Context:    config.Context,
-		Build:      config.Build,
+		Build:      config.Build.WithoutCacheTo(),
 		Features:   config.Features,
 	}
 
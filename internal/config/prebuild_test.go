@@ -130,6 +130,47 @@ func TestCalculatePrebuildHash_ChangesWithBuildArgs(t *testing.T) {
 	}
 }
 
+func TestCalculatePrebuildHash_IgnoresCacheTo(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "file.txt"), "content")
+
+	hashFor := func(cacheTo StrArray) string {
+		t.Helper()
+		hash, err := CalculatePrebuildHash(PrebuildHashParams{
+			Config: &DevContainerConfig{
+				DockerfileContainer: DockerfileContainer{
+					Build: &ConfigBuildOptions{Target: "dev", CacheTo: cacheTo},
+				},
+			},
+			ContextPath: dir,
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return hash
+	}
+
+	if hashFor(nil) != hashFor(StrArray{"type=registry,ref=example.com/app:cache"}) {
+		t.Error("hash should not change when only cacheTo changes")
+	}
+
+	// A build block holding only cacheTo hashes like no build block.
+	hashes := make([]string, 0, 2)
+	for _, build := range []*ConfigBuildOptions{nil, {CacheTo: StrArray{"type=local,dest=/tmp/cache"}}} {
+		hash, err := CalculatePrebuildHash(PrebuildHashParams{
+			Config:      &DevContainerConfig{ImageContainer: ImageContainer{Image: "alpine"}, DockerfileContainer: DockerfileContainer{Build: build}},
+			ContextPath: dir,
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		hashes = append(hashes, hash)
+	}
+	if hashes[0] != hashes[1] {
+		t.Error("adding a build block with only cacheTo should not change the hash")
+	}
+}
+
 func TestNormalizeArchitecture(t *testing.T) {
 	tests := []struct {
 		input string
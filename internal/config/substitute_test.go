@@ -211,6 +211,34 @@ func TestSubstitute(t *testing.T) {
 				}
 			},
 		},
+		{
+			"runArgs elements",
+			&DevContainerConfig{
+				NonComposeBase: NonComposeBase{
+					RunArgs: []string{
+						"--volume", "${localWorkspaceFolder}/cache:/cache",
+						"--env=GOPATH=${localEnv:GOPATH}",
+						"--network=host",
+					},
+				},
+			},
+			func(t *testing.T, result *DevContainerConfig) {
+				t.Helper()
+				want := []string{
+					"--volume", "/home/user/myproject/cache:/cache",
+					"--env=GOPATH=/home/user/go",
+					"--network=host",
+				}
+				if len(result.RunArgs) != len(want) {
+					t.Fatalf("RunArgs = %v, want %v", result.RunArgs, want)
+				}
+				for i := range want {
+					if result.RunArgs[i] != want[i] {
+						t.Errorf("RunArgs[%d] = %q, want %q", i, result.RunArgs[i], want[i])
+					}
+				}
+			},
+		},
 		{
 			"no variables returns equivalent config",
 			&DevContainerConfig{
@@ -81,6 +81,7 @@ type NonComposeBase struct {
 	Init           *bool             `json:"init,omitempty"`
 	Privileged     *bool             `json:"privileged,omitempty"`
 	CapAdd         []string          `json:"capAdd,omitempty"`
+	CapDrop        []string          `json:"capDrop,omitempty"`
 	SecurityOpt    []string          `json:"securityOpt,omitempty"`
 	RunArgs        []string          `json:"runArgs,omitempty"`
 	WorkspaceMount string            `json:"workspaceMount,omitempty"`
@@ -112,6 +113,7 @@ type ConfigBuildOptions struct {
 	Args       map[string]*string `json:"args,omitempty"`
 	Target     string             `json:"target,omitempty"`
 	CacheFrom  StrArray           `json:"cacheFrom,omitempty"`
+	CacheTo    StrArray           `json:"cacheTo,omitempty"` // crib extension; export-only, does not affect the image
 	Options    []string           `json:"options,omitempty"`
 }
 
@@ -259,6 +261,21 @@ func GetDockerfilePath(config *DevContainerConfig) string {
 	return ""
 }
 
+// WithoutCacheTo returns b without cacheTo, which only controls where build
+// cache is exported and does not affect the image. A build block holding
+// nothing but cacheTo becomes nil, so it compares equal to no build block.
+func (b *ConfigBuildOptions) WithoutCacheTo() *ConfigBuildOptions {
+	if b == nil || len(b.CacheTo) == 0 {
+		return b
+	}
+	c := *b
+	c.CacheTo = nil
+	if c.Dockerfile == "" && c.Context == "" && len(c.Args) == 0 && c.Target == "" && len(c.CacheFrom) == 0 && len(c.Options) == 0 {
+		return nil
+	}
+	return &c
+}
+
 // --- Custom JSON types ---
 
 // StrArray accepts either a single string or an array of strings in JSON.
@@ -333,8 +350,50 @@ func (sa *StrIntArray) UnmarshalJSON(data []byte) error {
 //
 // All forms are normalized to map[string][]string.
 // For string and array forms, the key is empty string.
+//
+// An object whose keys are all host OS names ({"linux": ..., "darwin": ...})
+// is a per-OS hook rather than a set of parallel entries; see ForOS.
 type LifecycleHook map[string][]string
 
+// hostOSKeys are the object keys that make a LifecycleHook per-OS. They
+// match runtime.GOOS on the platforms crib runs on.
+var hostOSKeys = map[string]bool{
+	"linux":   true,
+	"darwin":  true,
+	"windows": true,
+	"freebsd": true,
+	"netbsd":  true,
+	"openbsd": true,
+}
+
+// IsPerOS reports whether l is an object-form hook keyed only by host OS
+// names.
+func (l LifecycleHook) IsPerOS() bool {
+	if len(l) == 0 {
+		return false
+	}
+	for k := range l {
+		if !hostOSKeys[k] {
+			return false
+		}
+	}
+	return true
+}
+
+// ForOS returns the command to run on goos. A per-OS hook yields its goos
+// entry as a single sequential command, or nil when there is none; any
+// other hook is returned unchanged.
+func (l LifecycleHook) ForOS(goos string) LifecycleHook {
+	if !l.IsPerOS() {
+		return l
+	}
+	cmd, ok := l[goos]
+	if !ok {
+		return nil
+	}
+	return LifecycleHook{"": cmd}
+}
+
 // UnmarshalJSON implements json.Unmarshaler.
 func (l *LifecycleHook) UnmarshalJSON(data []byte) error {
 	// Try single string: "command"
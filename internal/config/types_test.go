@@ -3,6 +3,7 @@ package config
 import (
 	"encoding/json"
 	"path/filepath"
+	"reflect"
 	"testing"
 )
 
@@ -489,3 +490,30 @@ func TestDevContainerConfig_FullUnmarshal(t *testing.T) {
 		t.Error("Customizations is nil")
 	}
 }
+
+func TestLifecycleHook_ForOS(t *testing.T) {
+	perOS := LifecycleHook{"linux": {"./init.sh"}, "darwin": {"bash", "init-mac.sh"}, "windows": {"init.cmd"}}
+	tests := []struct {
+		name string
+		hook LifecycleHook
+		goos string
+		want LifecycleHook
+	}{
+		{"linux", perOS, "linux", LifecycleHook{"": {"./init.sh"}}},
+		{"darwin", perOS, "darwin", LifecycleHook{"": {"bash", "init-mac.sh"}}},
+		{"windows", perOS, "windows", LifecycleHook{"": {"init.cmd"}}},
+		{"no entry for OS", LifecycleHook{"darwin": {"init-mac.sh"}}, "linux", nil},
+		{"string form", LifecycleHook{"": {"echo hi"}}, "linux", LifecycleHook{"": {"echo hi"}}},
+		{"parallel entries", LifecycleHook{"setup": {"a"}, "build": {"b"}}, "linux", LifecycleHook{"setup": {"a"}, "build": {"b"}}},
+		{"mixed keys are parallel entries", LifecycleHook{"linux": {"a"}, "build": {"b"}}, "darwin", LifecycleHook{"linux": {"a"}, "build": {"b"}}},
+		{"empty", nil, "linux", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.hook.ForOS(tt.goos)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ForOS(%q) = %v, want %v", tt.goos, got, tt.want)
+			}
+		})
+	}
+}
@@ -2,13 +2,21 @@ package driver
 
 import (
 	"context"
+	"errors"
 	"io"
+	"time"
 )
 
+// ErrCopyUnsupported is returned by CopyToContainer and CopyFromContainer when
+// the runtime has no native cp command. Callers fall back to streaming the
+// file over an exec.
+var ErrCopyUnsupported = errors.New("runtime does not support native copy")
+
 // LogsOptions controls container log output.
 type LogsOptions struct {
-	Follow bool   // stream logs as they are produced
-	Tail   string // number of lines from the end ("all" or a number)
+	Follow     bool   // stream logs as they are produced
+	Tail       string // number of lines from the end ("all" or a number)
+	Timestamps bool   // prefix each line with its RFC 3339 timestamp
 }
 
 // Driver abstracts the container runtime (Docker or Podman).
@@ -25,8 +33,16 @@ type Driver interface {
 	// StartContainer starts a stopped container.
 	StartContainer(ctx context.Context, workspaceID, containerID string) error
 
-	// StopContainer stops a running container.
-	StopContainer(ctx context.Context, workspaceID, containerID string) error
+	// StopContainer stops a running container. timeout is how long the
+	// runtime waits after SIGTERM before killing it; nil keeps the
+	// runtime's default.
+	StopContainer(ctx context.Context, workspaceID, containerID string, timeout *time.Duration) error
+
+	// PauseContainer freezes all processes in a running container.
+	PauseContainer(ctx context.Context, workspaceID, containerID string) error
+
+	// UnpauseContainer resumes a paused container.
+	UnpauseContainer(ctx context.Context, workspaceID, containerID string) error
 
 	// RestartContainer restarts a running or stopped container.
 	RestartContainer(ctx context.Context, workspaceID, containerID string) error
@@ -37,15 +53,33 @@ type Driver interface {
 	// ExecContainer runs a command inside a container with attached I/O.
 	// env is a list of KEY=VALUE pairs injected via -e flags.
 	// user overrides the exec user (e.g. "root"); pass "" to use the container default.
-	ExecContainer(ctx context.Context, workspaceID, containerID string, cmd []string, stdin io.Reader, stdout, stderr io.Writer, env []string, user string) error
+	// workdir sets the working directory (--workdir); pass "" to use the container default.
+	ExecContainer(ctx context.Context, workspaceID, containerID string, cmd []string, stdin io.Reader, stdout, stderr io.Writer, env []string, user, workdir string) error
 
 	// ContainerLogs returns the logs from a container.
 	// opts may be nil for default behavior (all logs, no follow).
 	ContainerLogs(ctx context.Context, workspaceID, containerID string, stdout, stderr io.Writer, opts *LogsOptions) error
 
+	// AttachContainer attaches to the output streams of a container's main
+	// process and blocks until it exits or ctx is cancelled. Signals are not
+	// proxied, so cancelling detaches without stopping the container.
+	AttachContainer(ctx context.Context, workspaceID, containerID string, stdout, stderr io.Writer) error
+
+	// CopyToContainer copies the host path src to dst inside the container
+	// using the runtime's native cp. File modes are preserved; the copy is
+	// owned by the container's root user. dst's parent directory must exist.
+	CopyToContainer(ctx context.Context, workspaceID, containerID, src, dst string) error
+
+	// CopyFromContainer copies src inside the container to the host path
+	// dst using the runtime's native cp, preserving file modes.
+	CopyFromContainer(ctx context.Context, workspaceID, containerID, src, dst string) error
+
 	// BuildImage builds a container image.
 	BuildImage(ctx context.Context, workspaceID string, options *BuildOptions) error
 
+	// PullImage pulls imageName from its registry, refreshing a local copy.
+	PullImage(ctx context.Context, imageName string, stdout, stderr io.Writer) error
+
 	// InspectImage returns details about a container image.
 	InspectImage(ctx context.Context, imageName string) (*ImageDetails, error)
 
@@ -59,6 +93,15 @@ type Driver interface {
 	// changes are passed as --change flags (e.g. "LABEL key=value").
 	CommitContainer(ctx context.Context, workspaceID, containerID, imageName string, changes []string) error
 
+	// ExportContainer writes a container's filesystem as a tar archive to
+	// the host path output.
+	ExportContainer(ctx context.Context, workspaceID, containerID, output string) error
+
+	// ContainerChanges lists the files added, changed or deleted in a
+	// container's filesystem relative to its image. Volumes and bind mounts
+	// are not included.
+	ContainerChanges(ctx context.Context, workspaceID, containerID string) ([]FileChange, error)
+
 	// RemoveImage removes a container image.
 	RemoveImage(ctx context.Context, imageName string) error
 
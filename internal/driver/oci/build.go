@@ -13,6 +13,8 @@ import (
 // BuildImage builds a container image from a Dockerfile.
 // For Docker, it tries `docker buildx build --load` first, falling back to `docker build`.
 // For Podman, it uses `podman build` directly.
+// Builds that export the image (opts.Output or opts.Push) don't fall back,
+// since the classic builder would quietly load the image locally instead.
 func (d *OCIDriver) BuildImage(ctx context.Context, workspaceID string, opts *driver.BuildOptions) error {
 	imageName := opts.Image
 	if imageName == "" {
@@ -29,7 +31,11 @@ func (d *OCIDriver) BuildImage(ctx context.Context, workspaceID string, opts *dr
 		// Try buildx first.
 		args := d.buildBuildArgs(imageName, opts, true)
 		if err := d.helper.Run(ctx, args, nil, stdout, stderr); err != nil {
+			if opts.Output != "" || opts.Push {
+				return fmt.Errorf("building image for workspace %s (exporting requires docker buildx): %w", workspaceID, err)
+			}
 			d.logger.Warn("buildx failed, falling back to docker build", "error", err)
+			d.warnCacheToIgnored(opts)
 			args = d.buildBuildArgs(imageName, opts, false)
 			if err := d.helper.Run(ctx, args, nil, stdout, stderr); err != nil {
 				return fmt.Errorf("building image for workspace %s: %w", workspaceID, err)
@@ -39,6 +45,10 @@ func (d *OCIDriver) BuildImage(ctx context.Context, workspaceID string, opts *dr
 	}
 
 	// Podman always uses plain build.
+	if opts.Push {
+		return fmt.Errorf("building image for workspace %s: pushing during the build requires docker buildx; build and then run 'podman push'", workspaceID)
+	}
+	d.warnCacheToIgnored(opts)
 	args := d.buildBuildArgs(imageName, opts, false)
 	if err := d.helper.Run(ctx, args, nil, stdout, stderr); err != nil {
 		return fmt.Errorf("building image for workspace %s: %w", workspaceID, err)
@@ -46,6 +56,14 @@ func (d *OCIDriver) BuildImage(ctx context.Context, workspaceID string, opts *dr
 	return nil
 }
 
+// warnCacheToIgnored logs a warning when cache export targets are set for a
+// build that does not go through buildx.
+func (d *OCIDriver) warnCacheToIgnored(opts *driver.BuildOptions) {
+	if len(opts.CacheTo) > 0 {
+		d.logger.Warn("cacheTo requires docker buildx, ignoring", "runtime", d.runtime.String(), "cacheTo", opts.CacheTo)
+	}
+}
+
 // buildWriters returns the stdout and stderr writers from opts, falling back to os.Stderr.
 func buildWriters(opts *driver.BuildOptions) (io.Writer, io.Writer) {
 	stdout := io.Writer(os.Stderr)
@@ -60,13 +78,25 @@ func buildWriters(opts *driver.BuildOptions) (io.Writer, io.Writer) {
 }
 
 // buildBuildArgs constructs the argument list for a build command.
-// When useBuildx is true, it uses `buildx build --load` (Docker only).
+// When useBuildx is true, it uses `buildx build` (Docker only), loading the
+// result into the local image store unless opts asks for an export.
 func (d *OCIDriver) buildBuildArgs(imageName string, opts *driver.BuildOptions, useBuildx bool) []string {
 	var args []string
 	if useBuildx {
-		args = []string{"buildx", "build", "--load"}
+		args = []string{"buildx", "build"}
+		switch {
+		case opts.Output != "":
+			args = append(args, "--output", opts.Output)
+		case opts.Push:
+			args = append(args, "--push")
+		default:
+			args = append(args, "--load")
+		}
 	} else {
 		args = []string{"build"}
+		if opts.Output != "" {
+			args = append(args, "--output", opts.Output)
+		}
 	}
 
 	// Dockerfile.
@@ -82,6 +112,11 @@ func (d *OCIDriver) buildBuildArgs(imageName string, opts *driver.BuildOptions,
 		args = append(args, "--target", opts.Target)
 	}
 
+	// Refresh the FROM images instead of reusing local copies.
+	if opts.Pull {
+		args = append(args, "--pull")
+	}
+
 	// Build args (sorted for determinism).
 	argKeys := make([]string, 0, len(opts.Args))
 	for k := range opts.Args {
@@ -97,6 +132,28 @@ func (d *OCIDriver) buildBuildArgs(imageName string, opts *driver.BuildOptions,
 		args = append(args, "--cache-from", c)
 	}
 
+	// Cache to. Exporting build cache requires BuildKit; the classic
+	// builder and podman reject or misinterpret the flag.
+	if useBuildx {
+		for _, c := range opts.CacheTo {
+			args = append(args, "--cache-to", c)
+		}
+	}
+
+	// Build secrets. Both buildx and podman accept the same
+	// id=...,src=... / id=...,env=... syntax; the secret is only mounted
+	// into RUN steps that ask for it and never stored in a layer.
+	for _, s := range opts.Secrets {
+		args = append(args, "--secret", s)
+	}
+
+	// Named build contexts, referenced from the Dockerfile as
+	// COPY --from=name or FROM name. Requires BuildKit (docker 23+ or
+	// buildx) or podman 4.3+.
+	for _, c := range opts.Contexts {
+		args = append(args, "--build-context", c)
+	}
+
 	// Labels (sorted for determinism).
 	labelKeys := make([]string, 0, len(opts.Labels))
 	for k := range opts.Labels {
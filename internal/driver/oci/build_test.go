@@ -1,6 +1,7 @@
 package oci
 
 import (
+	"context"
 	"strings"
 	"testing"
 
@@ -174,3 +175,121 @@ func TestBuildBuildArgs_NoBuildArgsNoTarget(t *testing.T) {
 		}
 	}
 }
+
+func TestBuildBuildArgs_CacheToOnlyWithBuildx(t *testing.T) {
+	d := newTestDockerDriver()
+
+	opts := &driver.BuildOptions{
+		Context: "/ctx",
+		CacheTo: []string{"type=registry,ref=ghcr.io/org/app:cache,mode=max"},
+	}
+
+	got := strings.Join(d.buildBuildArgs("img:latest", opts, true), " ")
+	assertContains(t, got, "--cache-to type=registry,ref=ghcr.io/org/app:cache,mode=max")
+	if !strings.HasSuffix(got, "/ctx") {
+		t.Errorf("expected context at end, got: %s", got)
+	}
+
+	got = strings.Join(d.buildBuildArgs("img:latest", opts, false), " ")
+	if strings.Contains(got, "--cache-to") {
+		t.Errorf("--cache-to should be dropped without buildx, got: %s", got)
+	}
+}
+
+func TestBuildBuildArgs_Pull(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		d      *OCIDriver
+		buildx bool
+	}{
+		{"buildx", newTestDockerDriver(), true},
+		{"plain", newTestDockerDriver(), false},
+		{"podman", newTestPodmanDriver(), false},
+	} {
+		got := strings.Join(tc.d.buildBuildArgs("img:latest", &driver.BuildOptions{Context: "/ctx", Pull: true}, tc.buildx), " ")
+		assertContains(t, got, "-t img:latest --pull")
+
+		got = strings.Join(tc.d.buildBuildArgs("img:latest", &driver.BuildOptions{Context: "/ctx"}, tc.buildx), " ")
+		if strings.Contains(got, "--pull") {
+			t.Errorf("%s: unexpected --pull without Pull: %s", tc.name, got)
+		}
+	}
+}
+
+func TestBuildBuildArgs_Export(t *testing.T) {
+	tests := []struct {
+		name   string
+		d      *OCIDriver
+		buildx bool
+		opts   *driver.BuildOptions
+		want   string
+	}{
+		{"buildx output", newTestDockerDriver(), true, &driver.BuildOptions{Output: "type=registry,ref=ghcr.io/org/app:dev"}, "buildx build --output type=registry,ref=ghcr.io/org/app:dev -t"},
+		{"buildx push", newTestDockerDriver(), true, &driver.BuildOptions{Push: true}, "buildx build --push -t"},
+		{"podman output", newTestPodmanDriver(), false, &driver.BuildOptions{Output: "type=tar,dest=/tmp/app.tar"}, "build --output type=tar,dest=/tmp/app.tar -t"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := strings.Join(tt.d.buildBuildArgs("img:latest", tt.opts, tt.buildx), " ")
+			assertContains(t, got, tt.want)
+			// Exporting replaces loading the image into the local store.
+			if strings.Contains(got, "--load") {
+				t.Errorf("export should not also --load, got: %s", got)
+			}
+		})
+	}
+}
+
+func TestBuildImage_PodmanRejectsPush(t *testing.T) {
+	d := newTestPodmanDriver()
+	err := d.BuildImage(context.Background(), "ws", &driver.BuildOptions{Image: "ghcr.io/org/app:dev", Push: true})
+	if err == nil || !strings.Contains(err.Error(), "podman push") {
+		t.Fatalf("expected push to be rejected, got %v", err)
+	}
+}
+
+func TestBuildBuildArgs_Contexts(t *testing.T) {
+	opts := &driver.BuildOptions{
+		Context:  "/ctx",
+		Contexts: []string{"assets=/home/me/assets", "base=docker-image://alpine:3.20"},
+	}
+
+	for _, tc := range []struct {
+		name   string
+		d      *OCIDriver
+		buildx bool
+	}{
+		{"buildx", newTestDockerDriver(), true},
+		{"plain", newTestDockerDriver(), false},
+		{"podman", newTestPodmanDriver(), false},
+	} {
+		got := strings.Join(tc.d.buildBuildArgs("img:latest", opts, tc.buildx), " ")
+		assertContains(t, got, "--build-context assets=/home/me/assets --build-context base=docker-image://alpine:3.20")
+		if !strings.HasSuffix(got, "/ctx") {
+			t.Errorf("%s: expected context at end, got: %s", tc.name, got)
+		}
+	}
+}
+
+func TestBuildBuildArgs_Secrets(t *testing.T) {
+	opts := &driver.BuildOptions{
+		Context: "/ctx",
+		Secrets: []string{"id=npmrc,src=/home/me/.npmrc", "id=token,env=GH_TOKEN"},
+	}
+
+	for _, tc := range []struct {
+		name   string
+		d      *OCIDriver
+		buildx bool
+	}{
+		{"buildx", newTestDockerDriver(), true},
+		{"plain", newTestDockerDriver(), false},
+		{"podman", newTestPodmanDriver(), false},
+	} {
+		got := strings.Join(tc.d.buildBuildArgs("img:latest", opts, tc.buildx), " ")
+		assertContains(t, got, "--secret id=npmrc,src=/home/me/.npmrc --secret id=token,env=GH_TOKEN")
+		if !strings.HasSuffix(got, "/ctx") {
+			t.Errorf("%s: expected context at end, got: %s", tc.name, got)
+		}
+	}
+}
@@ -5,10 +5,12 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"math"
 	"os"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/fgrehm/crib/internal/driver"
 )
@@ -58,11 +60,31 @@ func (d *OCIDriver) RunContainer(ctx context.Context, workspaceID string, option
 	name, args := d.buildRunArgs(workspaceID, options)
 	_, err := d.helper.Output(ctx, args...)
 	if err != nil {
+		if conflictErr := d.nameConflict(ctx, name); conflictErr != nil {
+			return "", conflictErr
+		}
 		return "", fmt.Errorf("running container for workspace %s: %w", workspaceID, err)
 	}
 	return name, nil
 }
 
+// nameConflict explains a failed run caused by a container crib did not
+// create holding the name. It returns nil when no container has the name
+// or when the holder carries the crib.workspace label, leaving the runtime's
+// own error as the best explanation.
+func (d *OCIDriver) nameConflict(ctx context.Context, name string) error {
+	var raw []inspectContainer
+	if err := d.helper.Inspect(ctx, []string{name}, "container", &raw); err != nil || len(raw) == 0 {
+		return nil
+	}
+	if raw[0].Config.Labels[LabelWorkspace] != "" {
+		return nil
+	}
+	return fmt.Errorf("container name %q is already used by a container crib did not create; "+
+		"remove it (%s rm -f %s), rename it (%s rename %s NEW_NAME), or pick another name with runArgs --name",
+		name, d.runtime, name, d.runtime, name)
+}
+
 // buildRunArgs constructs the `docker run` argument list and returns the
 // container name chosen for the run.
 func (d *OCIDriver) buildRunArgs(workspaceID string, opts *driver.RunOptions) (string, []string) {
@@ -110,10 +132,22 @@ func (d *OCIDriver) buildRunArgs(workspaceID string, opts *driver.RunOptions) (s
 
 	// Capabilities.
 	args = appendFlags(args, "--cap-add", opts.CapAdd)
+	args = appendFlags(args, "--cap-drop", opts.CapDrop)
 
 	// Security options.
 	args = appendFlags(args, "--security-opt", opts.SecurityOpt)
 
+	// Supplementary groups.
+	args = appendFlags(args, "--group-add", opts.GroupAdd)
+
+	// On SELinux-enforcing hosts Podman confines the container so it cannot
+	// read bind mounts such as the workspace. Disable labeling for the
+	// container unless the user configured SELinux labels themselves.
+	if d.runtime == RuntimePodman && SELinuxEnforcing() &&
+		!HasLabelSecurityOpt(opts.SecurityOpt) && !HasLabelSecurityOpt(securityOptArgs(extraArgs)) {
+		args = append(args, "--security-opt", "label=disable")
+	}
+
 	// Workspace mount.
 	if opts.WorkspaceMount.Target != "" {
 		args = append(args, "--mount", opts.WorkspaceMount.String())
@@ -124,9 +158,34 @@ func (d *OCIDriver) buildRunArgs(workspaceID string, opts *driver.RunOptions) (s
 		args = append(args, "--mount", m.String())
 	}
 
+	// Tmpfs mounts.
+	args = appendFlags(args, "--tmpfs", opts.Tmpfs)
+
 	// Published ports.
 	args = appendFlags(args, "--publish", opts.Ports)
 
+	// Extra /etc/hosts entries.
+	args = appendFlags(args, "--add-host", opts.ExtraHosts)
+
+	// Network.
+	if opts.Network != "" {
+		args = append(args, "--network", opts.Network)
+	}
+
+	// GPU passthrough.
+	args = append(args, d.gpuArgs(opts.GPUs)...)
+
+	// Resource limits.
+	if opts.CPUs != "" {
+		args = append(args, "--cpus", opts.CPUs)
+	}
+	if opts.Memory != "" {
+		args = append(args, "--memory", opts.Memory)
+	}
+	if opts.CPUSet != "" {
+		args = append(args, "--cpuset-cpus", opts.CPUSet)
+	}
+
 	// Entrypoint.
 	if opts.Entrypoint != "" {
 		args = append(args, "--entrypoint", opts.Entrypoint)
@@ -157,9 +216,26 @@ func (d *OCIDriver) StartContainer(ctx context.Context, _, containerID string) e
 	return err
 }
 
-// StopContainer stops a running container.
-func (d *OCIDriver) StopContainer(ctx context.Context, _, containerID string) error {
-	_, err := d.helper.Output(ctx, "stop", containerID)
+// StopContainer stops a running container, waiting timeout (rounded up to
+// whole seconds) before killing it when set.
+func (d *OCIDriver) StopContainer(ctx context.Context, _, containerID string, timeout *time.Duration) error {
+	args := []string{"stop"}
+	if timeout != nil {
+		args = append(args, "--time", strconv.Itoa(int(math.Ceil(timeout.Seconds()))))
+	}
+	_, err := d.helper.Output(ctx, append(args, containerID)...)
+	return err
+}
+
+// PauseContainer freezes all processes in a running container.
+func (d *OCIDriver) PauseContainer(ctx context.Context, _, containerID string) error {
+	_, err := d.helper.Output(ctx, "pause", containerID)
+	return err
+}
+
+// UnpauseContainer resumes a paused container.
+func (d *OCIDriver) UnpauseContainer(ctx context.Context, _, containerID string) error {
+	_, err := d.helper.Output(ctx, "unpause", containerID)
 	return err
 }
 
@@ -185,20 +261,29 @@ func (d *OCIDriver) DeleteContainer(ctx context.Context, _, containerID string)
 // ExecContainer runs a command inside a container with attached I/O.
 // env is injected as -e KEY=VALUE flags.
 // user overrides the exec user (e.g. "root"); empty string uses the container default.
-func (d *OCIDriver) ExecContainer(ctx context.Context, _, containerID string, cmd []string, stdin io.Reader, stdout, stderr io.Writer, env []string, user string) error {
+// workdir is passed as --workdir; empty string uses the container default.
+func (d *OCIDriver) ExecContainer(ctx context.Context, _, containerID string, cmd []string, stdin io.Reader, stdout, stderr io.Writer, env []string, user, workdir string) error {
+	args := buildExecArgs(containerID, cmd, stdin != nil, env, user, workdir)
+	return d.helper.Run(ctx, args, stdin, stdout, stderr)
+}
+
+// buildExecArgs constructs the `docker exec` argument list.
+func buildExecArgs(containerID string, cmd []string, interactive bool, env []string, user, workdir string) []string {
 	args := []string{"exec"}
-	if stdin != nil {
+	if interactive {
 		args = append(args, "-i")
 	}
 	if user != "" {
 		args = append(args, "--user", user)
 	}
+	if workdir != "" {
+		args = append(args, "--workdir", workdir)
+	}
 	for _, e := range env {
 		args = append(args, "-e", e)
 	}
 	args = append(args, containerID)
-	args = append(args, cmd...)
-	return d.helper.Run(ctx, args, stdin, stdout, stderr)
+	return append(args, cmd...)
 }
 
 // ContainerLogs returns the logs from a container.
@@ -212,11 +297,59 @@ func (d *OCIDriver) ContainerLogs(ctx context.Context, _, containerID string, st
 		if opts.Tail != "" {
 			args = append(args, "--tail", opts.Tail)
 		}
+		if opts.Timestamps {
+			args = append(args, "--timestamps")
+		}
 	}
 	args = append(args, containerID)
 	return d.helper.Run(ctx, args, nil, stdout, stderr)
 }
 
+// AttachContainer attaches to the output of a container's main process.
+// Stdin is never attached (crib does not create containers with an open
+// stdin) and signals are not proxied, so Ctrl-C detaches instead of
+// stopping the container.
+func (d *OCIDriver) AttachContainer(ctx context.Context, _, containerID string, stdout, stderr io.Writer) error {
+	return d.helper.Run(ctx, buildAttachArgs(containerID), nil, stdout, stderr)
+}
+
+// buildAttachArgs constructs the `docker attach` argument list.
+func buildAttachArgs(containerID string) []string {
+	return []string{"attach", "--no-stdin", "--sig-proxy=false", containerID}
+}
+
+// CopyToContainer copies the host path src to dst inside the container with
+// `docker cp` / `podman cp`.
+func (d *OCIDriver) CopyToContainer(ctx context.Context, workspaceID, containerID, src, dst string) error {
+	return d.copy(ctx, workspaceID, buildCopyArgs(src, containerID+":"+dst))
+}
+
+// CopyFromContainer copies src inside the container to the host path dst
+// with `docker cp` / `podman cp`.
+func (d *OCIDriver) CopyFromContainer(ctx context.Context, workspaceID, containerID, src, dst string) error {
+	return d.copy(ctx, workspaceID, buildCopyArgs(containerID+":"+src, dst))
+}
+
+// copy runs a cp command, reporting driver.ErrCopyUnsupported when the
+// runtime (e.g. an old or restricted remote client) has no cp subcommand.
+func (d *OCIDriver) copy(ctx context.Context, workspaceID string, args []string) error {
+	_, err := d.helper.Output(ctx, args...)
+	if err == nil {
+		return nil
+	}
+	msg := err.Error()
+	if strings.Contains(msg, "unknown command") || strings.Contains(msg, "unrecognized command") {
+		return fmt.Errorf("%w: %w", driver.ErrCopyUnsupported, err)
+	}
+	return fmt.Errorf("copying files for workspace %s: %w", workspaceID, err)
+}
+
+// buildCopyArgs constructs the `docker cp` argument list. Container paths
+// are given as CONTAINER:PATH.
+func buildCopyArgs(src, dst string) []string {
+	return []string{"cp", src, dst}
+}
+
 // ListContainers returns all containers with the crib.workspace label.
 func (d *OCIDriver) ListContainers(ctx context.Context) ([]driver.ContainerDetails, error) {
 	out, err := d.helper.Output(ctx,
@@ -247,27 +380,82 @@ func (d *OCIDriver) ListContainers(ctx context.Context) ([]driver.ContainerDetai
 // CommitContainer creates an image from a container's changes.
 // changes are passed as --change flags (e.g. "LABEL key=value").
 func (d *OCIDriver) CommitContainer(ctx context.Context, _, containerID, imageName string, changes []string) error {
+	_, err := d.helper.Output(ctx, commitArgs(containerID, imageName, changes)...)
+	if err != nil {
+		return fmt.Errorf("committing container %s as %s: %w", containerID, imageName, err)
+	}
+	return nil
+}
+
+// commitArgs builds the runtime arguments for CommitContainer.
+func commitArgs(containerID, imageName string, changes []string) []string {
 	args := []string{"commit"}
 	for _, c := range changes {
 		args = append(args, "--change", c)
 	}
-	args = append(args, containerID, imageName)
-	_, err := d.helper.Output(ctx, args...)
+	return append(args, containerID, imageName)
+}
+
+// ExportContainer writes a container's filesystem as a tar archive to the
+// host path output.
+func (d *OCIDriver) ExportContainer(ctx context.Context, _, containerID, output string) error {
+	_, err := d.helper.Output(ctx, exportArgs(containerID, output)...)
 	if err != nil {
-		return fmt.Errorf("committing container %s as %s: %w", containerID, imageName, err)
+		return fmt.Errorf("exporting container %s to %s: %w", containerID, output, err)
 	}
 	return nil
 }
 
+// exportArgs builds the runtime arguments for ExportContainer.
+func exportArgs(containerID, output string) []string {
+	return []string{"export", "--output", output, containerID}
+}
+
+// ContainerChanges lists the files added, changed or deleted in a
+// container's filesystem relative to its image, via docker/podman diff.
+func (d *OCIDriver) ContainerChanges(ctx context.Context, _, containerID string) ([]driver.FileChange, error) {
+	out, err := d.helper.Output(ctx, "diff", containerID)
+	if err != nil {
+		return nil, fmt.Errorf("listing changes in container %s: %w", containerID, err)
+	}
+	return parseChanges(string(out), d.logger), nil
+}
+
+// parseChanges parses docker/podman diff output, one "<kind> <path>" entry
+// per line. Lines with an unknown kind are skipped with a warning on logger.
+func parseChanges(out string, logger *slog.Logger) []driver.FileChange {
+	var changes []driver.FileChange
+	for _, line := range parseLines(out) {
+		kind, path, _ := strings.Cut(line, " ")
+		k := driver.ChangeKind(kind)
+		path = strings.TrimSpace(path)
+		if path == "" || (k != driver.ChangeAdded && k != driver.ChangeModified && k != driver.ChangeDeleted) {
+			logger.Warn("unexpected line in container diff output", "line", line)
+			continue
+		}
+		changes = append(changes, driver.FileChange{Kind: k, Path: path})
+	}
+	return changes
+}
+
 // inspectContainer is an intermediate struct for unmarshaling docker/podman
 // inspect JSON. It mirrors the fields we need from ContainerDetails plus the
 // nested NetworkSettings.Ports structure.
 type inspectContainer struct {
 	ID      string `json:"Id"`
 	Created string `json:"Created"`
+	Image   string `json:"Image"`
 	State   struct {
 		Status    string `json:"Status"`
 		StartedAt string `json:"StartedAt"`
+		ExitCode  int    `json:"ExitCode"`
+		Health    *struct {
+			Status string `json:"Status"`
+		} `json:"Health"`
+		// Healthcheck is the key older podman versions use for Health.
+		Healthcheck *struct {
+			Status string `json:"Status"`
+		} `json:"Healthcheck"`
 	} `json:"State"`
 	Config struct {
 		Labels map[string]string `json:"Labels"`
@@ -281,14 +469,29 @@ type inspectContainer struct {
 	} `json:"NetworkSettings"`
 }
 
+// healthStatus returns the healthcheck status, or "" when the container has
+// no healthcheck.
+func (ic *inspectContainer) healthStatus() string {
+	switch {
+	case ic.State.Health != nil:
+		return ic.State.Health.Status
+	case ic.State.Healthcheck != nil:
+		return ic.State.Healthcheck.Status
+	}
+	return ""
+}
+
 // toContainerDetails converts the intermediate inspect result to a driver.ContainerDetails.
 func (ic *inspectContainer) toContainerDetails() driver.ContainerDetails {
 	d := driver.ContainerDetails{
 		ID:      ic.ID,
 		Created: ic.Created,
+		Image:   ic.Image,
 		State: driver.ContainerState{
 			Status:    ic.State.Status,
 			StartedAt: ic.State.StartedAt,
+			Health:    ic.healthStatus(),
+			ExitCode:  ic.State.ExitCode,
 		},
 		Config: driver.ContainerConfig{
 			Labels: ic.Config.Labels,
@@ -350,6 +553,28 @@ func hasUsernsArg(args []string) bool {
 	return false
 }
 
+// gpuArgs returns the run flags exposing gpus (docker --gpus syntax). Docker
+// takes --gpus as is; Podman exposes NVIDIA GPUs through CDI devices, so
+// "device=0,1" becomes one --device nvidia.com/gpu=ID per GPU and anything
+// else (all, a count) exposes all of them.
+func (d *OCIDriver) gpuArgs(gpus string) []string {
+	if gpus == "" {
+		return nil
+	}
+	if d.runtime != RuntimePodman {
+		return []string{"--gpus", gpus}
+	}
+	ids := []string{"all"}
+	if list, ok := strings.CutPrefix(gpus, "device="); ok {
+		ids = strings.Split(list, ",")
+	}
+	var args []string
+	for _, id := range ids {
+		args = append(args, "--device", "nvidia.com/gpu="+strings.TrimSpace(id))
+	}
+	return args
+}
+
 // extractName removes --name/--name=value from args and returns the extracted
 // name plus the remaining args. Returns ("", nil) if no --name flag is present.
 // When --name is found, rest is always non-nil (even if empty or value is blank),
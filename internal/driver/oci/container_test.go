@@ -1,9 +1,16 @@
 package oci
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/fgrehm/crib/internal/config"
 	"github.com/fgrehm/crib/internal/driver"
@@ -25,6 +32,76 @@ func newTestPodmanDriver() *OCIDriver {
 	}
 }
 
+// newFakeRuntimeDriver returns a docker driver backed by a shell script
+// standing in for the runtime binary. `run` always fails with a name
+// conflict, `ps` lists the container when it carries the workspace label and
+// `inspect` reports the labels given.
+func newFakeRuntimeDriver(t *testing.T, labels string) *OCIDriver {
+	t.Helper()
+	inspect := `[{"Id":"abc123","Name":"/crib-ws","State":{"Status":"running"},"Config":{"Labels":` + labels + `}}]`
+	listed := ""
+	if strings.Contains(labels, LabelWorkspace) {
+		listed = "abc123"
+	}
+	script := "#!/bin/sh\n" +
+		"case \"$1\" in\n" +
+		"run) echo 'Error: the container name \"/crib-ws\" is already in use' >&2; exit 125;;\n" +
+		"ps) echo '" + listed + "';;\n" +
+		"inspect) echo '" + inspect + "';;\n" +
+		"esac\n"
+	bin := filepath.Join(t.TempDir(), "docker")
+	if err := os.WriteFile(bin, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return &OCIDriver{
+		helper:  NewHelper(bin, slog.Default()),
+		runtime: RuntimeDocker,
+		logger:  slog.Default(),
+	}
+}
+
+func TestRunContainer_NameConflictWithForeignContainer(t *testing.T) {
+	d := newFakeRuntimeDriver(t, `{"maintainer":"someone"}`)
+
+	_, err := d.RunContainer(context.Background(), "ws", &driver.RunOptions{Image: "alpine"})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	for _, want := range []string{`container name "crib-ws" is already used by a container crib did not create`, "docker rm -f crib-ws", "runArgs --name"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q should contain %q", err, want)
+		}
+	}
+
+	// A foreign container is not mistaken for the workspace's own.
+	details, err := d.FindContainer(context.Background(), "ws")
+	if err != nil || details != nil {
+		t.Errorf("FindContainer = %v, %v; want nil, nil", details, err)
+	}
+}
+
+func TestRunContainer_NameConflictWithCribContainer(t *testing.T) {
+	d := newFakeRuntimeDriver(t, `{"`+LabelWorkspace+`":"ws"}`)
+
+	_, err := d.RunContainer(context.Background(), "ws", &driver.RunOptions{Image: "alpine"})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if strings.Contains(err.Error(), "crib did not create") {
+		t.Errorf("crib-owned container should not be reported as foreign: %v", err)
+	}
+
+	// The engine looks containers up by label before creating one, so a
+	// crib-owned container is found and reused.
+	details, err := d.FindContainer(context.Background(), "ws")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if details == nil || details.ID != "abc123" {
+		t.Errorf("FindContainer = %+v, want container abc123", details)
+	}
+}
+
 func TestBuildRunArgs_Minimal(t *testing.T) {
 	d := newTestDockerDriver()
 
@@ -50,7 +127,9 @@ func TestBuildRunArgs_AllOptions(t *testing.T) {
 		Cmd:         []string{"-c", "sleep infinity"},
 		Env:         []string{"FOO=bar", "BAZ=qux"},
 		CapAdd:      []string{"SYS_PTRACE"},
+		CapDrop:     []string{"NET_RAW", "MKNOD"},
 		SecurityOpt: []string{"seccomp=unconfined"},
+		GroupAdd:    []string{"998"},
 		Labels:      map[string]string{"custom": "value"},
 		Privileged:  true,
 		Init:        true,
@@ -76,7 +155,9 @@ func TestBuildRunArgs_AllOptions(t *testing.T) {
 	assertContains(t, got, "--init")
 	assertContains(t, got, "--privileged")
 	assertContains(t, got, "--cap-add SYS_PTRACE")
+	assertContains(t, got, "--cap-drop NET_RAW --cap-drop MKNOD")
 	assertContains(t, got, "--security-opt seccomp=unconfined")
+	assertContains(t, got, "--group-add 998")
 	assertContains(t, got, "--mount type=bind,src=/home/user/project,dst=/workspaces/project")
 	assertContains(t, got, "--mount type=volume,src=mydata,dst=/data")
 	assertContains(t, got, "--entrypoint /bin/sh")
@@ -209,7 +290,7 @@ func TestBuildRunArgs_Ports(t *testing.T) {
 
 	opts := &driver.RunOptions{
 		Image: "alpine",
-		Ports: []string{"8080:8080", "9090:3000"},
+		Ports: []string{"8080:8080", "9090:3000", "53:53/udp"},
 	}
 
 	_, args := d.buildRunArgs("ws1", opts)
@@ -217,6 +298,7 @@ func TestBuildRunArgs_Ports(t *testing.T) {
 
 	assertContains(t, got, "--publish 8080:8080")
 	assertContains(t, got, "--publish 9090:3000")
+	assertContains(t, got, "--publish 53:53/udp")
 
 	// Ports should appear before the image name.
 	imageIdx := strings.Index(got, "alpine")
@@ -226,6 +308,113 @@ func TestBuildRunArgs_Ports(t *testing.T) {
 	}
 }
 
+func TestBuildRunArgs_ExtraHosts(t *testing.T) {
+	d := newTestDockerDriver()
+
+	opts := &driver.RunOptions{
+		Image:      "alpine",
+		ExtraHosts: []string{"api.local:10.0.0.5", "host.docker.internal:host-gateway"},
+	}
+
+	_, args := d.buildRunArgs("ws1", opts)
+	got := strings.Join(args, " ")
+
+	assertContains(t, got, "--add-host api.local:10.0.0.5")
+	assertContains(t, got, "--add-host host.docker.internal:host-gateway")
+	if strings.Index(got, "--add-host") > strings.Index(got, "alpine") {
+		t.Errorf("--add-host should appear before image, got: %s", got)
+	}
+}
+
+func TestBuildRunArgs_Tmpfs(t *testing.T) {
+	d := newTestDockerDriver()
+
+	opts := &driver.RunOptions{
+		Image: "alpine",
+		Tmpfs: []string{"/tmp:size=1g", "/run"},
+	}
+
+	_, args := d.buildRunArgs("ws1", opts)
+	got := strings.Join(args, " ")
+
+	assertContains(t, got, "--tmpfs /tmp:size=1g --tmpfs /run")
+	if strings.Index(got, "--tmpfs") > strings.Index(got, "alpine") {
+		t.Errorf("--tmpfs should appear before image, got: %s", got)
+	}
+}
+
+func TestBuildRunArgs_Network(t *testing.T) {
+	d := newTestDockerDriver()
+
+	_, args := d.buildRunArgs("ws1", &driver.RunOptions{Image: "alpine", Network: "other-stack_default"})
+	got := strings.Join(args, " ")
+
+	assertContains(t, got, "--network other-stack_default")
+	if strings.Index(got, "--network") > strings.Index(got, "alpine") {
+		t.Errorf("--network should appear before image, got: %s", got)
+	}
+
+	_, args = d.buildRunArgs("ws1", &driver.RunOptions{Image: "alpine"})
+	if got := strings.Join(args, " "); strings.Contains(got, "--network") {
+		t.Errorf("unexpected --network without a network, got: %s", got)
+	}
+}
+
+func TestBuildRunArgs_GPUs(t *testing.T) {
+	tests := []struct {
+		name string
+		d    *OCIDriver
+		gpus string
+		want string
+	}{
+		{"docker all", newTestDockerDriver(), "all", "--gpus all"},
+		{"docker devices", newTestDockerDriver(), "device=0,1", "--gpus device=0,1"},
+		{"podman all", newTestPodmanDriver(), "all", "--device nvidia.com/gpu=all"},
+		{"podman devices", newTestPodmanDriver(), "device=0,1", "--device nvidia.com/gpu=0 --device nvidia.com/gpu=1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, args := tt.d.buildRunArgs("ws1", &driver.RunOptions{Image: "alpine", GPUs: tt.gpus})
+			got := strings.Join(args, " ")
+			assertContains(t, got, tt.want)
+			if tt.d.runtime == RuntimePodman && strings.Contains(got, "--gpus") {
+				t.Errorf("podman should use CDI devices, not --gpus: %s", got)
+			}
+		})
+	}
+
+	_, args := newTestDockerDriver().buildRunArgs("ws1", &driver.RunOptions{Image: "alpine"})
+	if got := strings.Join(args, " "); strings.Contains(got, "--gpus") {
+		t.Errorf("no GPU flags expected without GPUs, got: %s", got)
+	}
+}
+
+func TestBuildRunArgs_ResourceLimits(t *testing.T) {
+	d := newTestDockerDriver()
+
+	_, args := d.buildRunArgs("ws1", &driver.RunOptions{Image: "alpine", CPUs: "4", Memory: "8589934592"})
+	got := strings.Join(args, " ")
+	assertContains(t, got, "--cpus 4")
+	assertContains(t, got, "--memory 8589934592")
+
+	_, args = d.buildRunArgs("ws1", &driver.RunOptions{Image: "alpine"})
+	if got := strings.Join(args, " "); strings.Contains(got, "--cpus") || strings.Contains(got, "--memory") {
+		t.Errorf("no resource limit flags expected without limits, got: %s", got)
+	}
+}
+
+func TestBuildRunArgs_CPUSet(t *testing.T) {
+	d := newTestDockerDriver()
+
+	_, args := d.buildRunArgs("ws1", &driver.RunOptions{Image: "alpine", CPUSet: "0-3,6"})
+	assertContains(t, strings.Join(args, " "), "--cpuset-cpus 0-3,6")
+
+	_, args = d.buildRunArgs("ws1", &driver.RunOptions{Image: "alpine"})
+	if got := strings.Join(args, " "); strings.Contains(got, "--cpuset-cpus") {
+		t.Errorf("no --cpuset-cpus expected without a cpuset, got: %s", got)
+	}
+}
+
 func TestBuildRunArgs_ExtraArgsPassthrough(t *testing.T) {
 	origGetuid := getuid
 	t.Cleanup(func() { getuid = origGetuid })
@@ -559,3 +748,286 @@ func assertContains(t *testing.T, s, substr string) {
 		t.Errorf("expected %q to contain %q", s, substr)
 	}
 }
+
+func TestInspectContainer_ToContainerDetails_Health(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		want string
+	}{
+		{"docker", `{"State":{"Status":"running","Health":{"Status":"healthy"}}}`, "healthy"},
+		{"old podman", `{"State":{"Status":"running","Healthcheck":{"Status":"starting"}}}`, "starting"},
+		{"no healthcheck", `{"State":{"Status":"running"}}`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ic inspectContainer
+			if err := json.Unmarshal([]byte(tt.json), &ic); err != nil {
+				t.Fatal(err)
+			}
+			if got := ic.toContainerDetails().State.Health; got != tt.want {
+				t.Errorf("Health = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInspectContainer_ToContainerDetails_ExitCode(t *testing.T) {
+	var ic inspectContainer
+	if err := json.Unmarshal([]byte(`{"State":{"Status":"exited","ExitCode":42}}`), &ic); err != nil {
+		t.Fatal(err)
+	}
+	if got := ic.toContainerDetails().State.ExitCode; got != 42 {
+		t.Errorf("ExitCode = %d, want 42", got)
+	}
+}
+
+func TestInspectContainer_ToContainerDetails_Image(t *testing.T) {
+	var ic inspectContainer
+	if err := json.Unmarshal([]byte(`{"Id":"c1","Image":"sha256:abc"}`), &ic); err != nil {
+		t.Fatal(err)
+	}
+	if got := ic.toContainerDetails().Image; got != "sha256:abc" {
+		t.Errorf("Image = %q, want sha256:abc", got)
+	}
+}
+
+func TestBuildExecArgs(t *testing.T) {
+	tests := []struct {
+		name        string
+		interactive bool
+		env         []string
+		user        string
+		workdir     string
+		want        []string
+	}{
+		{
+			name: "minimal",
+			want: []string{"exec", "c1", "ls", "-la"},
+		},
+		{
+			name:        "all options",
+			interactive: true,
+			env:         []string{"FOO=bar"},
+			user:        "vscode",
+			workdir:     "/workspaces/my project",
+			want:        []string{"exec", "-i", "--user", "vscode", "--workdir", "/workspaces/my project", "-e", "FOO=bar", "c1", "ls", "-la"},
+		},
+		{
+			name:    "workdir only",
+			workdir: "/tmp",
+			want:    []string{"exec", "--workdir", "/tmp", "c1", "ls", "-la"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := buildExecArgs("c1", []string{"ls", "-la"}, tt.interactive, tt.env, tt.user, tt.workdir)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("buildExecArgs = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildCopyArgs(t *testing.T) {
+	tests := []struct {
+		src, dst string
+		want     []string
+	}{
+		{"/home/me/.gitconfig", "abc123:/tmp/.crib-copy-1", []string{"cp", "/home/me/.gitconfig", "abc123:/tmp/.crib-copy-1"}},
+		{"abc123:/workspaces/app/log", "/tmp/out", []string{"cp", "abc123:/workspaces/app/log", "/tmp/out"}},
+	}
+	for _, tt := range tests {
+		if got := buildCopyArgs(tt.src, tt.dst); !slices.Equal(got, tt.want) {
+			t.Errorf("buildCopyArgs(%q, %q) = %v, want %v", tt.src, tt.dst, got, tt.want)
+		}
+	}
+}
+
+func TestCopyToContainer_NativeCp(t *testing.T) {
+	dir := t.TempDir()
+	argsFile := filepath.Join(dir, "args")
+	script := "#!/bin/sh\necho \"$@\" > " + argsFile + "\n"
+	bin := filepath.Join(dir, "docker")
+	if err := os.WriteFile(bin, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	d := &OCIDriver{helper: NewHelper(bin, slog.Default()), runtime: RuntimeDocker, logger: slog.Default()}
+
+	if err := d.CopyToContainer(context.Background(), "ws", "abc123", "/host/file", "/tmp/dst"); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := os.ReadFile(argsFile)
+	if want := "cp /host/file abc123:/tmp/dst\n"; string(got) != want {
+		t.Errorf("args = %q, want %q", got, want)
+	}
+}
+
+func TestCopyToContainer_Unsupported(t *testing.T) {
+	bin := filepath.Join(t.TempDir(), "docker")
+	script := "#!/bin/sh\necho 'Error: unknown command \"cp\" for \"docker\"' >&2\nexit 1\n"
+	if err := os.WriteFile(bin, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	d := &OCIDriver{helper: NewHelper(bin, slog.Default()), runtime: RuntimeDocker, logger: slog.Default()}
+
+	err := d.CopyToContainer(context.Background(), "ws", "abc123", "/host/file", "/tmp/dst")
+	if !errors.Is(err, driver.ErrCopyUnsupported) {
+		t.Errorf("expected ErrCopyUnsupported, got %v", err)
+	}
+}
+
+func TestBuildAttachArgs(t *testing.T) {
+	got := buildAttachArgs("c1")
+	want := []string{"attach", "--no-stdin", "--sig-proxy=false", "c1"}
+	if !slices.Equal(got, want) {
+		t.Errorf("buildAttachArgs = %v, want %v", got, want)
+	}
+}
+
+func TestBuildRunArgs_SELinuxLabelDisable(t *testing.T) {
+	origEnforcing := SELinuxEnforcing
+	t.Cleanup(func() { SELinuxEnforcing = origEnforcing })
+
+	// want counts "--security-opt label=disable" in the args, including one
+	// passed through from runArgs.
+	tests := []struct {
+		name      string
+		driver    *OCIDriver
+		enforcing bool
+		opts      *driver.RunOptions
+		want      int
+	}{
+		{"podman on SELinux host", newTestPodmanDriver(), true, &driver.RunOptions{Image: "alpine"}, 1},
+		{"podman without SELinux", newTestPodmanDriver(), false, &driver.RunOptions{Image: "alpine"}, 0},
+		{"docker on SELinux host", newTestDockerDriver(), true, &driver.RunOptions{Image: "alpine"}, 0},
+		{
+			"securityOpt label configured", newTestPodmanDriver(), true,
+			&driver.RunOptions{Image: "alpine", SecurityOpt: []string{"label=type:container_runtime_t"}}, 0,
+		},
+		{
+			"runArgs label configured", newTestPodmanDriver(), true,
+			&driver.RunOptions{Image: "alpine", ExtraArgs: []string{"--security-opt", "label=disable"}}, 1,
+		},
+		{
+			"unrelated securityOpt", newTestPodmanDriver(), true,
+			&driver.RunOptions{Image: "alpine", SecurityOpt: []string{"seccomp=unconfined"}}, 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SELinuxEnforcing = func() bool { return tt.enforcing }
+			_, args := tt.driver.buildRunArgs("ws1", tt.opts)
+			if got := strings.Count(strings.Join(args, " "), "--security-opt label=disable"); got != tt.want {
+				t.Errorf("label=disable occurrences = %d, want %d: %v", got, tt.want, args)
+			}
+		})
+	}
+}
+
+func TestHasLabelSecurityOpt(t *testing.T) {
+	if !HasLabelSecurityOpt(securityOptArgs([]string{"--cap-add", "SYS_PTRACE", "--security-opt=label=disable"})) {
+		t.Error("expected --security-opt=label=disable to be detected")
+	}
+	if HasLabelSecurityOpt([]string{"seccomp=unconfined", "apparmor=unconfined"}) {
+		t.Error("non-label security options should not be detected")
+	}
+}
+
+func TestCommitArgs(t *testing.T) {
+	got := commitArgs("c-1", "myapp:dev", []string{"LABEL a=1", "LABEL b="})
+	want := []string{"commit", "--change", "LABEL a=1", "--change", "LABEL b=", "c-1", "myapp:dev"}
+	if !slices.Equal(got, want) {
+		t.Errorf("commitArgs = %v, want %v", got, want)
+	}
+}
+
+func TestExportArgs(t *testing.T) {
+	got := exportArgs("c-1", "/tmp/out.tar")
+	want := []string{"export", "--output", "/tmp/out.tar", "c-1"}
+	if !slices.Equal(got, want) {
+		t.Errorf("exportArgs = %v, want %v", got, want)
+	}
+}
+
+func TestParseChanges(t *testing.T) {
+	out := "C /etc\n" +
+		"A /etc/apt/sources.list.d/nodesource.list\n" +
+		"D /etc/motd\n" +
+		"A /home/vscode/my notes.txt\n" +
+		"\n" +
+		"X /bogus\n" +
+		"A\n"
+	var logs strings.Builder
+	got := parseChanges(out, slog.New(slog.NewTextHandler(&logs, nil)))
+	want := []driver.FileChange{
+		{Kind: driver.ChangeModified, Path: "/etc"},
+		{Kind: driver.ChangeAdded, Path: "/etc/apt/sources.list.d/nodesource.list"},
+		{Kind: driver.ChangeDeleted, Path: "/etc/motd"},
+		{Kind: driver.ChangeAdded, Path: "/home/vscode/my notes.txt"},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("parseChanges = %v, want %v", got, want)
+	}
+	if !strings.Contains(logs.String(), "/bogus") {
+		t.Errorf("expected a warning for the unknown line, got logs %q", logs.String())
+	}
+
+	if got := parseChanges("", slog.Default()); len(got) != 0 {
+		t.Errorf("parseChanges(\"\") = %v, want none", got)
+	}
+}
+
+func TestContainerChanges(t *testing.T) {
+	script := "#!/bin/sh\n" +
+		"[ \"$1 $2\" = 'diff c-1' ] || { echo \"unexpected args: $*\" >&2; exit 1; }\n" +
+		"printf 'C /root\\nA /root/.bash_history\\n'\n"
+	bin := filepath.Join(t.TempDir(), "docker")
+	if err := os.WriteFile(bin, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	d := &OCIDriver{helper: NewHelper(bin, slog.Default()), runtime: RuntimeDocker, logger: slog.Default()}
+
+	got, err := d.ContainerChanges(context.Background(), "ws", "c-1")
+	if err != nil {
+		t.Fatalf("ContainerChanges: %v", err)
+	}
+	want := []driver.FileChange{
+		{Kind: driver.ChangeModified, Path: "/root"},
+		{Kind: driver.ChangeAdded, Path: "/root/.bash_history"},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("ContainerChanges = %v, want %v", got, want)
+	}
+}
+
+func TestStopContainer_Timeout(t *testing.T) {
+	// The fake runtime records its arguments.
+	dir := t.TempDir()
+	script := "#!/bin/sh\necho \"$@\" > " + filepath.Join(dir, "args") + "\n"
+	bin := filepath.Join(dir, "docker")
+	if err := os.WriteFile(bin, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	d := &OCIDriver{helper: NewHelper(bin, slog.Default()), runtime: RuntimeDocker, logger: slog.Default()}
+
+	timeout := 2500 * time.Millisecond
+	for _, tt := range []struct {
+		timeout *time.Duration
+		want    string
+	}{
+		{nil, "stop c-1"},
+		{&timeout, "stop --time 3 c-1"},
+	} {
+		if err := d.StopContainer(context.Background(), "ws", "c-1", tt.timeout); err != nil {
+			t.Fatalf("StopContainer: %v", err)
+		}
+		got, err := os.ReadFile(filepath.Join(dir, "args"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.TrimSpace(string(got)) != tt.want {
+			t.Errorf("args = %q, want %q", strings.TrimSpace(string(got)), tt.want)
+		}
+	}
+}
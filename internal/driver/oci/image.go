@@ -3,6 +3,7 @@ package oci
 import (
 	"context"
 	"fmt"
+	"io"
 	"strconv"
 	"strings"
 
@@ -21,6 +22,14 @@ func (d *OCIDriver) InspectImage(ctx context.Context, imageName string) (*driver
 	return &images[0], nil
 }
 
+// PullImage pulls imageName from its registry.
+func (d *OCIDriver) PullImage(ctx context.Context, imageName string, stdout, stderr io.Writer) error {
+	if err := d.helper.Run(ctx, []string{"pull", imageName}, nil, stdout, stderr); err != nil {
+		return fmt.Errorf("pulling image %s: %w", imageName, err)
+	}
+	return nil
+}
+
 // RemoveImage removes a container image.
 func (d *OCIDriver) RemoveImage(ctx context.Context, imageName string) error {
 	_, err := d.helper.Output(ctx, "rmi", imageName)
@@ -85,3 +85,15 @@ func TestParseImageList_NoneTag(t *testing.T) {
 		t.Errorf("Reference = %q, want crib-ws (no tag)", images[0].Reference)
 	}
 }
+
+// Images committed by crib export clear the crib.workspace label; they are
+// not crib-managed and must not show up for prune or remove.
+func TestParseImageList_SkipsClearedWorkspaceLabel(t *testing.T) {
+	output := "myapp\tdev\tsha256:1\t100\t\n" +
+		"crib-ws\ttag\tsha256:2\t100\tws\n"
+	images := parseImageList(output)
+
+	if len(images) != 1 || images[0].WorkspaceID != "ws" {
+		t.Errorf("images = %+v, want only the ws image", images)
+	}
+}
@@ -68,7 +68,7 @@ func TestIntegrationContainerLifecycle(t *testing.T) {
 	}
 
 	// Stop the container.
-	if err := d.StopContainer(ctx, wsID, container.ID); err != nil {
+	if err := d.StopContainer(ctx, wsID, container.ID, nil); err != nil {
 		t.Fatalf("StopContainer: %v", err)
 	}
 
@@ -188,7 +188,7 @@ func TestIntegrationExec(t *testing.T) {
 
 	// Exec a command.
 	var stdout bytes.Buffer
-	err = d.ExecContainer(ctx, wsID, container.ID, []string{"echo", "hello"}, nil, &stdout, nil, nil, "")
+	err = d.ExecContainer(ctx, wsID, container.ID, []string{"echo", "hello"}, nil, &stdout, nil, nil, "", "")
 	if err != nil {
 		t.Fatalf("ExecContainer: %v", err)
 	}
@@ -201,7 +201,7 @@ func TestIntegrationExec(t *testing.T) {
 	// Test exec with stdin.
 	var stdout2 bytes.Buffer
 	stdin := strings.NewReader("world\n")
-	err = d.ExecContainer(ctx, wsID, container.ID, []string{"cat"}, stdin, &stdout2, nil, nil, "")
+	err = d.ExecContainer(ctx, wsID, container.ID, []string{"cat"}, stdin, &stdout2, nil, nil, "", "")
 	if err != nil {
 		t.Fatalf("ExecContainer with stdin: %v", err)
 	}
@@ -294,7 +294,7 @@ func TestIntegrationRunContainerWithMounts(t *testing.T) {
 
 	// Verify the mount works by reading the test file.
 	var stdout bytes.Buffer
-	err = d.ExecContainer(ctx, wsID, container.ID, []string{"cat", "/workspaces/project/testfile"}, nil, &stdout, nil, nil, "")
+	err = d.ExecContainer(ctx, wsID, container.ID, []string{"cat", "/workspaces/project/testfile"}, nil, &stdout, nil, nil, "", "")
 	if err != nil {
 		t.Fatalf("ExecContainer: %v", err)
 	}
@@ -304,6 +304,26 @@ func TestIntegrationRunContainerWithMounts(t *testing.T) {
 	}
 }
 
+func TestIntegrationRuntimeVersion(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	ctx := context.Background()
+	d := newTestDriver(t)
+
+	v, err := d.RuntimeVersion(ctx)
+	if err != nil {
+		t.Fatalf("RuntimeVersion: %v", err)
+	}
+	if v == "" || v[0] < '0' || v[0] > '9' {
+		t.Errorf("RuntimeVersion = %q, want a version number", v)
+	}
+	if _, err := d.Rootless(ctx); err != nil {
+		t.Errorf("Rootless: %v", err)
+	}
+}
+
 func TestIntegrationTargetArchitecture(t *testing.T) {
 	if testing.Short() {
 		t.Skip("skipping integration test in short mode")
@@ -45,7 +45,14 @@ type OCIDriver struct {
 
 // NewOCIDriver creates an OCIDriver by auto-detecting the container runtime.
 func NewOCIDriver(logger *slog.Logger) (*OCIDriver, error) {
-	rt, cmd, err := detectRuntime()
+	return NewOCIDriverFor("", logger)
+}
+
+// NewOCIDriverFor creates an OCIDriver for the named runtime ("docker" or
+// "podman"). An empty name falls back to CRIB_RUNTIME and then to
+// auto-detection. It fails if the requested runtime is not available.
+func NewOCIDriverFor(name string, logger *slog.Logger) (*OCIDriver, error) {
+	rt, cmd, err := detectRuntime(name)
 	if err != nil {
 		return nil, err
 	}
@@ -62,6 +69,11 @@ func (d *OCIDriver) Runtime() Runtime {
 	return d.runtime
 }
 
+// RuntimeCommand returns the runtime command crib runs, as found on PATH.
+func (d *OCIDriver) RuntimeCommand() string {
+	return d.helper.Command()
+}
+
 // TargetArchitecture returns the architecture of the container runtime host.
 func (d *OCIDriver) TargetArchitecture(ctx context.Context) (string, error) {
 	var format string
@@ -85,34 +97,72 @@ func (d *OCIDriver) TargetArchitecture(ctx context.Context) (string, error) {
 	return arch, nil
 }
 
+// RuntimeVersion returns the version of the container engine: the daemon's
+// for Docker, which can differ from the CLI's, and podman's own for Podman.
+func (d *OCIDriver) RuntimeVersion(ctx context.Context) (string, error) {
+	format := "{{.Server.Version}}"
+	if d.runtime == RuntimePodman {
+		format = "{{.Client.Version}}"
+	}
+	out, err := d.helper.Output(ctx, "version", "--format", format)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// Rootless reports whether the container engine runs without root
+// privileges: rootless Podman, or Docker in rootless mode.
+func (d *OCIDriver) Rootless(ctx context.Context) (bool, error) {
+	if d.runtime == RuntimePodman {
+		out, err := d.helper.Output(ctx, "info", "--format", "{{.Host.Security.Rootless}}")
+		if err != nil {
+			return false, err
+		}
+		return strings.TrimSpace(string(out)) == "true", nil
+	}
+	out, err := d.helper.Output(ctx, "info", "--format", "{{json .SecurityOptions}}")
+	if err != nil {
+		return false, err
+	}
+	return strings.Contains(string(out), "name=rootless"), nil
+}
+
+// findRuntime locates a responsive runtime command. It is a variable so
+// tests can override it.
+var findRuntime = findResponsiveRuntime
+
 // detectRuntime checks for an available container runtime.
-// Priority: CRIB_RUNTIME env > podman > docker.
-func detectRuntime() (Runtime, string, error) {
-	if env := os.Getenv("CRIB_RUNTIME"); env != "" {
-		switch strings.ToLower(env) {
+// Priority: requested (--runtime) > CRIB_RUNTIME env > podman > docker.
+func detectRuntime(requested string) (Runtime, string, error) {
+	source := "--runtime " + requested
+	if requested == "" {
+		requested = os.Getenv("CRIB_RUNTIME")
+		source = "CRIB_RUNTIME=" + requested
+	}
+	if requested != "" {
+		var rt Runtime
+		switch strings.ToLower(requested) {
 		case "docker":
-			cmd, err := findResponsiveRuntime("docker")
-			if err != nil {
-				return 0, "", fmt.Errorf("CRIB_RUNTIME=docker but docker is not available: %w", err)
-			}
-			return RuntimeDocker, cmd, nil
+			rt = RuntimeDocker
 		case "podman":
-			cmd, err := findResponsiveRuntime("podman")
-			if err != nil {
-				return 0, "", fmt.Errorf("CRIB_RUNTIME=podman but podman is not available: %w", err)
-			}
-			return RuntimePodman, cmd, nil
+			rt = RuntimePodman
 		default:
-			return 0, "", fmt.Errorf("CRIB_RUNTIME=%q is not supported (use docker or podman)", env)
+			return 0, "", fmt.Errorf("%s is not supported (use docker or podman)", source)
+		}
+		cmd, err := findRuntime(rt.String())
+		if err != nil {
+			return 0, "", fmt.Errorf("%s but %s is not available: %w", source, rt, err)
 		}
+		return rt, cmd, nil
 	}
 
 	// Auto-detect: try podman first, then docker.
-	podmanCmd, podmanErr := findResponsiveRuntime("podman")
+	podmanCmd, podmanErr := findRuntime("podman")
 	if podmanErr == nil {
 		return RuntimePodman, podmanCmd, nil
 	}
-	dockerCmd, dockerErr := findResponsiveRuntime("docker")
+	dockerCmd, dockerErr := findRuntime("docker")
 	if dockerErr == nil {
 		return RuntimeDocker, dockerCmd, nil
 	}
@@ -1,6 +1,11 @@
 package oci
 
-import "testing"
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+)
 
 func TestContainerName(t *testing.T) {
 	tests := []struct {
@@ -57,3 +62,75 @@ func TestRuntimeString(t *testing.T) {
 		t.Errorf("RuntimePodman.String() = %q, want %q", got, "podman")
 	}
 }
+
+func TestDetectRuntime(t *testing.T) {
+	origFind := findRuntime
+	t.Cleanup(func() { findRuntime = origFind })
+
+	tests := []struct {
+		name      string
+		requested string
+		env       string
+		available []string
+		want      Runtime
+		wantErr   string
+	}{
+		{"auto prefers podman", "", "", []string{"docker", "podman"}, RuntimePodman, ""},
+		{"auto falls back to docker", "", "", []string{"docker"}, RuntimeDocker, ""},
+		{"flag forces docker", "docker", "", []string{"docker", "podman"}, RuntimeDocker, ""},
+		{"flag wins over env", "docker", "podman", []string{"docker", "podman"}, RuntimeDocker, ""},
+		{"env forces docker", "", "docker", []string{"docker", "podman"}, RuntimeDocker, ""},
+		{"flag is case-insensitive", "Podman", "", []string{"docker", "podman"}, RuntimePodman, ""},
+		{"flag runtime missing", "podman", "", []string{"docker"}, 0, "--runtime podman but podman is not available"},
+		{"env runtime missing", "", "docker", []string{"podman"}, 0, "CRIB_RUNTIME=docker but docker is not available"},
+		{"unsupported runtime", "containerd", "", []string{"docker"}, 0, "--runtime containerd is not supported"},
+		{"nothing available", "", "", nil, 0, "no container runtime found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("CRIB_RUNTIME", tt.env)
+			findRuntime = func(name string) (string, error) {
+				for _, a := range tt.available {
+					if a == name {
+						return "/usr/bin/" + name, nil
+					}
+				}
+				return "", fmt.Errorf("%s not found on PATH", name)
+			}
+
+			rt, cmd, err := detectRuntime(tt.requested)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rt != tt.want {
+				t.Errorf("runtime = %s, want %s", rt, tt.want)
+			}
+			if cmd != "/usr/bin/"+tt.want.String() {
+				t.Errorf("command = %q, want the %s binary", cmd, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewOCIDriverFor(t *testing.T) {
+	origFind := findRuntime
+	t.Cleanup(func() { findRuntime = origFind })
+	findRuntime = func(name string) (string, error) { return "/usr/bin/" + name, nil }
+
+	d, err := NewOCIDriverFor("docker", slog.Default())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Runtime() != RuntimeDocker {
+		t.Errorf("Runtime() = %s, want docker", d.Runtime())
+	}
+	if d.helper.command != "/usr/bin/docker" {
+		t.Errorf("helper command = %q, want /usr/bin/docker", d.helper.command)
+	}
+}
@@ -0,0 +1,44 @@
+package oci
+
+import (
+	"os"
+	"strings"
+)
+
+// selinuxEnforcePath is where the kernel reports the SELinux mode: "1" when
+// enforcing, "0" when permissive. It is absent when SELinux is disabled.
+const selinuxEnforcePath = "/sys/fs/selinux/enforce"
+
+// SELinuxEnforcing reports whether the host runs SELinux in enforcing mode.
+// On such hosts, containers cannot read bind-mounted host files unless the
+// files are relabeled or labeling is disabled for the container. It is a
+// variable so tests can override it.
+var SELinuxEnforcing = func() bool {
+	data, err := os.ReadFile(selinuxEnforcePath)
+	return err == nil && strings.TrimSpace(string(data)) == "1"
+}
+
+// HasLabelSecurityOpt reports whether any of opts configures SELinux
+// labeling (e.g. "label=disable" or "label=type:container_runtime_t").
+func HasLabelSecurityOpt(opts []string) bool {
+	for _, o := range opts {
+		if strings.HasPrefix(o, "label=") || strings.HasPrefix(o, "label:") {
+			return true
+		}
+	}
+	return false
+}
+
+// securityOptArgs returns the values of --security-opt flags in args, in
+// both "--security-opt value" and "--security-opt=value" forms.
+func securityOptArgs(args []string) []string {
+	var opts []string
+	for i, a := range args {
+		if v, ok := strings.CutPrefix(a, "--security-opt="); ok {
+			opts = append(opts, v)
+		} else if a == "--security-opt" && i+1 < len(args) {
+			opts = append(opts, args[i+1])
+		}
+	}
+	return opts
+}
@@ -11,6 +11,7 @@ import (
 type ContainerDetails struct {
 	ID      string
 	Created string
+	Image   string // ID of the image the container was created from
 	State   ContainerState
 	Config  ContainerConfig
 	Ports   []PortBinding
@@ -29,6 +30,12 @@ type PortBinding struct {
 type ContainerState struct {
 	Status    string
 	StartedAt string
+	// Health is the healthcheck status ("starting", "healthy", "unhealthy"),
+	// or empty when the container has no healthcheck.
+	Health string
+	// ExitCode is the exit code of the container's main process. Only
+	// meaningful once the container has exited.
+	ExitCode int
 }
 
 // IsRunning reports whether the container is in the running state.
@@ -36,6 +43,17 @@ func (s ContainerState) IsRunning() bool {
 	return strings.EqualFold(s.Status, "running")
 }
 
+// IsReady reports whether the container is running and, if it defines a
+// healthcheck, reported healthy.
+func (s ContainerState) IsReady() bool {
+	return s.IsRunning() && (s.Health == "" || strings.EqualFold(s.Health, "healthy"))
+}
+
+// IsPaused reports whether the container is paused.
+func (s ContainerState) IsPaused() bool {
+	return strings.EqualFold(s.Status, "paused")
+}
+
 // IsRemoving reports whether the container is in the process of being removed.
 func (s ContainerState) IsRemoving() bool {
 	return strings.EqualFold(s.Status, "removing")
@@ -70,13 +88,22 @@ type RunOptions struct {
 	Cmd            []string
 	Env            []string
 	CapAdd         []string
+	CapDrop        []string
 	SecurityOpt    []string
+	GroupAdd       []string // Supplementary groups for the container process (--group-add)
 	Labels         map[string]string
 	Privileged     bool
 	Init           bool
 	WorkspaceMount config.Mount
 	Mounts         []config.Mount
 	Ports          []string // Publish specs (e.g. "8080:8080")
+	ExtraHosts     []string // /etc/hosts entries as "name:ip"
+	Tmpfs          []string // tmpfs mounts as "path[:options]" (e.g. "/tmp:size=1g")
+	Network        string   // Network to connect the container to (--network); empty for the runtime default
+	GPUs           string   // GPUs to expose in docker --gpus syntax ("all", "device=0,1"); empty for none
+	CPUs           string   // CPU limit in --cpus syntax (e.g. "4"); empty for none
+	Memory         string   // Memory limit in --memory syntax (e.g. "8589934592"); empty for none
+	CPUSet         string   // CPUs the container may use in --cpuset-cpus syntax (e.g. "0-3"); empty for all
 	ExtraArgs      []string // Raw CLI args passed through from runArgs
 }
 
@@ -86,6 +113,23 @@ type VolumeInfo struct {
 	Size string // human-readable, best-effort (may be empty)
 }
 
+// ChangeKind is the kind of a filesystem change reported by
+// ContainerChanges, using the runtime's single-letter codes.
+type ChangeKind string
+
+const (
+	ChangeAdded    ChangeKind = "A"
+	ChangeModified ChangeKind = "C"
+	ChangeDeleted  ChangeKind = "D"
+)
+
+// FileChange is a path in a container's filesystem that differs from the
+// image it was created from.
+type FileChange struct {
+	Kind ChangeKind
+	Path string
+}
+
 // ImageInfo describes a crib-managed image discovered by label.
 type ImageInfo struct {
 	Reference   string // repo:tag (e.g. "crib-myws:crib-abc123")
@@ -103,6 +147,12 @@ type BuildOptions struct {
 	Args         map[string]string
 	Target       string
 	CacheFrom    []string
+	CacheTo      []string          // Cache export targets; only honored by buildx
+	Secrets      []string          // BuildKit secret specs (e.g. "id=npmrc,src=/home/me/.npmrc")
+	Contexts     []string          // Named build contexts (e.g. "assets=/home/me/assets")
+	Pull         bool              // Always pull newer versions of the FROM images (--pull)
+	Output       string            // BuildKit exporter spec (--output); replaces loading the image locally
+	Push         bool              // Push the image instead of loading it locally (--push)
 	Labels       map[string]string // Image labels (e.g. crib.workspace=wsID)
 	Options      []string          // Extra CLI flags from build.options
 	Stdout       io.Writer
@@ -42,7 +42,8 @@ type containerBackend interface {
 	createContainer(ctx context.Context, opts createOpts) (createContainerResult, error)
 
 	// deleteExisting removes all containers for the workspace.
-	// Single: driver.DeleteContainer. Compose: composeDown.
+	// Single: driver.DeleteContainer. Compose: composeDown, or only the
+	// primary service container when keepDeps is set.
 	deleteExisting(ctx context.Context) error
 
 	// restart restarts the container without recreation.
@@ -74,6 +75,7 @@ type createOpts struct {
 type createContainerResult struct {
 	ContainerID   string
 	ContainerName string
+	Ports         []string // publish specs in effect; nil when the backend does not track them
 }
 
 // Compile-time interface checks.
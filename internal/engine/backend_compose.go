@@ -3,9 +3,17 @@ package engine
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"slices"
 	"strings"
+	"sync"
+	"time"
 
+	"golang.org/x/sync/errgroup"
+
+	composehelper "github.com/fgrehm/crib/internal/compose"
 	"github.com/fgrehm/crib/internal/config"
 	"github.com/fgrehm/crib/internal/plugin"
 	"github.com/fgrehm/crib/internal/workspace"
@@ -18,6 +26,11 @@ type composeBackend struct {
 	cfg             *config.DevContainerConfig
 	workspaceFolder string
 	inv             composeInvocation
+
+	// keepDeps limits a recreate to the primary service: deleteExisting
+	// removes only its container and createContainer starts it with
+	// --no-deps, so dependency services keep running.
+	keepDeps bool
 }
 
 func (b *composeBackend) pluginUser(ctx context.Context, fallbacks ...string) string {
@@ -81,23 +94,32 @@ func (b *composeBackend) createContainer(ctx context.Context, opts createOpts) (
 			others := removeService(services, b.cfg.Service)
 			if len(others) > 0 {
 				b.e.reportProgress(PhaseBuild, "Building services...")
-				if err := b.e.compose.Build(ctx, b.inv.projectName, allFiles, others, b.e.stdout, b.e.stderr, b.inv.env); err != nil {
+				if err := b.e.compose.Build(ctx, b.inv.projectName, allFiles, others, b.e.stdout, b.e.stderr, b.inv.env, b.e.pull); err != nil {
 					return createContainerResult{}, fmt.Errorf("building compose services: %w", err)
 				}
 			}
 		} else {
 			b.e.reportProgress(PhaseBuild, "Building services...")
-			if err := b.e.compose.Build(ctx, b.inv.projectName, allFiles, nil, b.e.stdout, b.e.stderr, b.inv.env); err != nil {
+			if err := b.e.compose.Build(ctx, b.inv.projectName, allFiles, nil, b.e.stdout, b.e.stderr, b.inv.env, b.e.pull); err != nil {
 				return createContainerResult{}, fmt.Errorf("building compose services: %w", err)
 			}
 		}
 	}
 
+	b.e.prePullComposeImages(ctx, b.inv, allFiles, services, !b.keepDeps)
+
 	var stderrBuf bytes.Buffer
 	b.e.reportProgress(PhaseCreate, "Starting services...")
-	if err := b.e.compose.Up(ctx, b.inv.projectName, allFiles, services, b.e.composeStdout(), b.e.composeStderrTee(&stderrBuf), b.inv.env); err != nil {
+	healthy, oneShot := b.healthyDependencies(ctx, services)
+	wait := len(healthy) > 0 && !oneShot && b.e.compose.SupportsWait()
+	if err := b.e.compose.Up(ctx, b.inv.projectName, allFiles, services, b.e.composeStdout(), b.e.composeStderrTee(&stderrBuf), b.inv.env, b.keepDeps, wait); err != nil {
 		return createContainerResult{}, fmt.Errorf("starting compose services: %w", err)
 	}
+	if len(healthy) > 0 && !wait {
+		if err := b.waitHealthy(ctx, allFiles, healthy); err != nil {
+			return createContainerResult{}, err
+		}
+	}
 
 	containerID, err := b.findRunningContainer(ctx, "after up", stderrBuf.String())
 	if err != nil {
@@ -106,7 +128,121 @@ func (b *composeBackend) createContainer(ctx context.Context, opts createOpts) (
 	return createContainerResult{ContainerID: containerID}, nil
 }
 
+// healthyDependencies returns the services that services wait for with
+// condition service_healthy: compose may return before they are healthy,
+// and setup would then race them. oneShot reports a dependency with
+// condition service_completed_successfully, whose container exits and makes
+// `compose up --wait` fail.
+func (b *composeBackend) healthyDependencies(ctx context.Context, services []string) (healthy []string, oneShot bool) {
+	project, err := composehelper.LoadProject(ctx, b.inv.files, nil, b.inv.env)
+	if err != nil {
+		b.e.logger.Debug("loading compose project for depends_on check", "error", err)
+		return nil, false
+	}
+	return composehelper.HealthyDependencies(project, services), composehelper.DependsOnCompletion(project, services)
+}
+
+// healthPollInterval and healthWaitTimeout control how waitHealthy polls
+// `compose ps`. Package vars so tests can shorten them.
+var (
+	healthPollInterval = time.Second
+	healthWaitTimeout  = 5 * time.Minute
+)
+
+// waitHealthy polls the service statuses until every service in services
+// reports healthy, for compose providers without `up --wait` (or projects
+// where it can't be used). A service that turns unhealthy or stops fails
+// right away.
+func (b *composeBackend) waitHealthy(ctx context.Context, files, services []string) error {
+	b.e.reportProgress(PhaseCreate, "Waiting for "+strings.Join(services, ", ")+" to be healthy...")
+	ctx, cancel := context.WithTimeout(ctx, healthWaitTimeout)
+	defer cancel()
+	for {
+		statuses, err := b.e.compose.ListServiceStatuses(ctx, b.inv.projectName, files, b.inv.env)
+		if err != nil && ctx.Err() == nil {
+			return fmt.Errorf("checking service health: %w", err)
+		}
+		var pending []string
+		for _, name := range services {
+			i := slices.IndexFunc(statuses, func(s composehelper.ServiceStatus) bool { return s.Service == name })
+			switch {
+			case i < 0:
+				pending = append(pending, name)
+			case statuses[i].Health == "unhealthy":
+				return fmt.Errorf("service %s is unhealthy", name)
+			case statuses[i].State == "exited" || statuses[i].State == "dead":
+				return fmt.Errorf("service %s %s before becoming healthy", name, statuses[i].State)
+			case statuses[i].Health != "healthy":
+				pending = append(pending, name)
+			}
+		}
+		if len(pending) == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return fmt.Errorf("services %s not healthy after %s", strings.Join(pending, ", "), healthWaitTimeout)
+			}
+			return ctx.Err()
+		case <-time.After(healthPollInterval):
+		}
+	}
+}
+
+// maxParallelPulls bounds concurrent image pulls so a large stack does not
+// open a registry connection per service at once.
+const maxParallelPulls = 4
+
+// prePullComposeImages pulls the images `compose up` would pull for
+// services (and, withDeps, their dependencies) concurrently, since some
+// compose providers pull one service at a time. Images already present are
+// skipped unless --pull was given. Failures only log a warning: compose up
+// retries the pull and reports the error with its own context.
+func (e *Engine) prePullComposeImages(ctx context.Context, inv composeInvocation, files, services []string, withDeps bool) {
+	project, err := composehelper.LoadProject(ctx, files, nil, inv.env)
+	if err != nil {
+		e.logger.Debug("loading compose project for image pre-pull", "error", err)
+		return
+	}
+
+	var images []string
+	for _, img := range composehelper.PullableImages(project, services, withDeps) {
+		if !e.pull {
+			if _, err := e.driver.InspectImage(ctx, img); err == nil {
+				continue
+			}
+		}
+		images = append(images, img)
+	}
+	if len(images) == 0 {
+		return
+	}
+
+	e.reportProgress(PhaseBuild, "Pulling "+strings.Join(images, ", ")+"...")
+	var mu sync.Mutex // serializes progress reports
+	var g errgroup.Group
+	g.SetLimit(maxParallelPulls)
+	for _, img := range images {
+		g.Go(func() error {
+			if err := e.driver.PullImage(ctx, img, io.Discard, e.composeStderr()); err != nil {
+				e.logger.Warn("pre-pulling image failed, leaving it to compose", "image", img, "error", err)
+				return nil
+			}
+			mu.Lock()
+			e.reportProgress(PhaseBuild, "Pulled "+img)
+			mu.Unlock()
+			return nil
+		})
+	}
+	_ = g.Wait()
+}
+
 func (b *composeBackend) deleteExisting(ctx context.Context) error {
+	if b.keepDeps {
+		files := b.e.composeFilesWithOverride(b.inv.files, b.ws.ID)
+		return b.e.compose.Remove(ctx, b.inv.projectName, files, []string{b.cfg.Service}, b.e.composeStdout(), b.e.composeStderr(), b.inv.env)
+	}
 	return b.e.composeDown(ctx, b.inv, b.ws.ID, false)
 }
 
@@ -114,7 +250,7 @@ func (b *composeBackend) restart(ctx context.Context, containerID string, plugin
 	allFiles := b.prepareOverride(ctx, pluginResp)
 
 	b.e.reportProgress(PhaseRestart, "Stopping services...")
-	if err := b.e.compose.Stop(ctx, b.inv.projectName, allFiles, b.e.composeStdout(), b.e.composeStderr(), b.inv.env); err != nil {
+	if err := b.e.compose.Stop(ctx, b.inv.projectName, allFiles, b.e.composeStdout(), b.e.composeStderr(), b.inv.env, b.e.stopTimeoutFor(b.cfg)); err != nil {
 		b.e.logger.Warn("failed to stop services, proceeding with start", "error", err)
 	}
 
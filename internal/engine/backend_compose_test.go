@@ -1,11 +1,23 @@
 package engine
 
 import (
+	"bytes"
 	"context"
+	"fmt"
+	"io"
 	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/fgrehm/crib/internal/compose"
 	"github.com/fgrehm/crib/internal/config"
+	"github.com/fgrehm/crib/internal/driver"
+	"github.com/fgrehm/crib/internal/workspace"
 )
 
 func TestComposeBackend_CanResumeFromStored_ReturnsTrue(t *testing.T) {
@@ -86,3 +98,284 @@ func TestComposeBackend_PluginUser_Fallbacks(t *testing.T) {
 
 // compose nil guard for deleteExisting is handled structurally:
 // Up() and Restart() validate compose availability before creating the backend.
+
+// newEchoComposeBackend returns a compose backend whose compose commands are
+// echoed to the returned buffer instead of being executed.
+func newEchoComposeBackend(t *testing.T, keepDeps bool) (*composeBackend, *bytes.Buffer) {
+	t.Helper()
+	var stdout bytes.Buffer
+	eng := &Engine{
+		driver: &fixedFindContainerDriver{container: &driver.ContainerDetails{
+			ID:    "abc123",
+			State: driver.ContainerState{Status: "running"},
+		}},
+		compose: compose.NewHelperFromRuntime("echo"),
+		store:   workspace.NewStoreAt(t.TempDir()),
+		logger:  slog.Default(),
+		verbose: true,
+		stdout:  &stdout,
+		stderr:  &stdout,
+	}
+	cfg := &config.DevContainerConfig{}
+	cfg.Service = "app"
+	cfg.DockerComposeFile = []string{"docker-compose.yml"}
+	ws := &workspace.Workspace{ID: "test-ws", Source: t.TempDir()}
+	return &composeBackend{
+		e:        eng,
+		ws:       ws,
+		cfg:      cfg,
+		inv:      composeInvocation{projectName: "test-ws", files: []string{"docker-compose.yml"}},
+		keepDeps: keepDeps,
+	}, &stdout
+}
+
+func TestComposeBackend_DeleteExisting_KeepDepsRemovesPrimaryOnly(t *testing.T) {
+	b, out := newEchoComposeBackend(t, true)
+
+	if err := b.deleteExisting(context.Background()); err != nil {
+		t.Fatalf("deleteExisting: %v", err)
+	}
+	if got := strings.TrimSpace(out.String()); !strings.HasSuffix(got, "rm --stop --force app") {
+		t.Errorf("compose args = %q, want rm of the primary service", got)
+	}
+}
+
+func TestComposeBackend_DeleteExisting_RecreateDepsRunsDown(t *testing.T) {
+	b, out := newEchoComposeBackend(t, false)
+
+	if err := b.deleteExisting(context.Background()); err != nil {
+		t.Fatalf("deleteExisting: %v", err)
+	}
+	if got := strings.TrimSpace(out.String()); !strings.HasSuffix(got, " down") {
+		t.Errorf("compose args = %q, want down", got)
+	}
+}
+
+func TestComposeBackend_CreateContainer_NoDeps(t *testing.T) {
+	for _, keepDeps := range []bool{true, false} {
+		b, out := newEchoComposeBackend(t, keepDeps)
+
+		if _, err := b.createContainer(context.Background(), createOpts{skipBuild: true}); err != nil {
+			t.Fatalf("keepDeps=%v: createContainer: %v", keepDeps, err)
+		}
+
+		var upLine string
+		for line := range strings.SplitSeq(out.String(), "\n") {
+			if strings.Contains(line, " up -d") {
+				upLine = line
+			}
+		}
+		if upLine == "" {
+			t.Fatalf("keepDeps=%v: no compose up invocation in:\n%s", keepDeps, out.String())
+		}
+		if got := strings.Contains(upLine, "--no-deps"); got != keepDeps {
+			t.Errorf("keepDeps=%v: compose up = %q, --no-deps present = %v", keepDeps, upLine, got)
+		}
+		if !strings.HasSuffix(upLine, " app") {
+			t.Errorf("keepDeps=%v: compose up = %q, want primary service last", keepDeps, upLine)
+		}
+	}
+}
+
+// scriptComposeBackend returns a compose backend whose runtime is a shell
+// script named runtime that logs each call and reports compose version
+// "version". `ps --format json` lists db as starting on the first call and
+// healthy afterwards. The compose file is written from content.
+func scriptComposeBackend(t *testing.T, runtime, version, content string) (*composeBackend, string) {
+	t.Helper()
+	dir := t.TempDir()
+	log := filepath.Join(dir, "calls.log")
+	script := fmt.Sprintf(`#!/bin/sh
+echo "$*" >> %[1]q
+case "$*" in
+"compose version --short") echo %[2]s ;;
+*" ps --format json")
+  if [ -e %[1]q.ps ]; then health=healthy; else health=starting; touch %[1]q.ps; fi
+  echo '[{"State":"running","Health":"'$health'","Labels":{"com.docker.compose.service":"db"}}]' ;;
+esac
+`, log, version)
+	bin := filepath.Join(dir, runtime)
+	if err := os.WriteFile(bin, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	h, err := compose.NewHelper(bin, slog.Default())
+	if err != nil {
+		t.Fatalf("NewHelper: %v", err)
+	}
+
+	b, _ := newEchoComposeBackend(t, false)
+	b.e.compose = h
+	composeFile := filepath.Join(b.ws.Source, "docker-compose.yml")
+	if err := os.WriteFile(composeFile, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	b.inv.files = []string{composeFile}
+	return b, log
+}
+
+func TestComposeBackend_CreateContainer_WaitsForHealthyDeps(t *testing.T) {
+	defer func(d time.Duration) { healthPollInterval = d }(healthPollInterval)
+	healthPollInterval = time.Millisecond
+
+	const healthy = `services:
+  app:
+    image: alpine
+    depends_on:
+      db:
+        condition: service_healthy
+  db:
+    image: postgres
+`
+	const oneShot = healthy + `    depends_on:
+      migrate:
+        condition: service_completed_successfully
+  migrate:
+    image: alpine
+`
+	const started = `services:
+  app:
+    image: alpine
+    depends_on:
+      db:
+        condition: service_started
+  db:
+    image: postgres
+`
+	tests := []struct {
+		name, runtime, version, content string
+		wantWait, wantPoll              bool
+	}{
+		{"compose v2", "docker", "2.29.1", healthy, true, false},
+		{"compose v2 without health condition", "docker", "2.29.1", started, false, false},
+		{"compose v2 with one-shot dependency", "docker", "2.29.1", oneShot, false, true},
+		{"podman-compose", "podman", "1.0.6", healthy, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, log := scriptComposeBackend(t, tt.runtime, tt.version, tt.content)
+
+			if _, err := b.createContainer(context.Background(), createOpts{skipBuild: true}); err != nil {
+				t.Fatalf("createContainer: %v", err)
+			}
+
+			data, err := os.ReadFile(log)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var upLine string
+			var polls int
+			for line := range strings.SplitSeq(string(data), "\n") {
+				if strings.Contains(line, " up -d") {
+					upLine = line
+				}
+				if strings.HasSuffix(line, " ps --format json") {
+					polls++
+				}
+			}
+			if got := strings.Contains(upLine, "--wait"); got != tt.wantWait {
+				t.Errorf("compose up = %q, --wait present = %v, want %v", upLine, got, tt.wantWait)
+			}
+			// Polling stops once db turns healthy on the second call.
+			if wantPolls := map[bool]int{true: 2}[tt.wantPoll]; polls != wantPolls {
+				t.Errorf("compose ps polled %d times, want %d", polls, wantPolls)
+			}
+		})
+	}
+}
+
+func TestComposeBackend_WaitHealthy_Unhealthy(t *testing.T) {
+	runtime := filepath.Join(t.TempDir(), "docker")
+	script := `#!/bin/sh
+echo '[{"State":"running","Health":"unhealthy","Labels":{"com.docker.compose.service":"db"}}]'
+`
+	if err := os.WriteFile(runtime, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	b, _ := newEchoComposeBackend(t, false)
+	b.e.compose = compose.NewHelperFromRuntime(runtime)
+
+	err := b.waitHealthy(context.Background(), nil, []string{"db"})
+	if err == nil || !strings.Contains(err.Error(), "db is unhealthy") {
+		t.Errorf("waitHealthy error = %v, want db reported unhealthy", err)
+	}
+}
+
+// prePullDriver reports the images in present as available locally and
+// records every pull.
+type prePullDriver struct {
+	mockDriver
+	present map[string]bool
+	mu      sync.Mutex
+	pulled  []string
+}
+
+func (m *prePullDriver) InspectImage(_ context.Context, imageName string) (*driver.ImageDetails, error) {
+	if m.present[imageName] {
+		return &driver.ImageDetails{ID: "sha256:" + imageName}, nil
+	}
+	return nil, fmt.Errorf("no such image: %s", imageName)
+}
+
+func (m *prePullDriver) PullImage(_ context.Context, imageName string, _, _ io.Writer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pulled = append(m.pulled, imageName)
+	return nil
+}
+
+func TestPrePullComposeImages(t *testing.T) {
+	dir := t.TempDir()
+	composeFile := filepath.Join(dir, "docker-compose.yml")
+	content := `services:
+  app:
+    build: .
+    depends_on: [api, db]
+  api:
+    image: ghcr.io/org/api:1.2
+  db:
+    image: postgres:16
+  cache:
+    image: redis:7
+  search:
+    image: opensearch:2
+`
+	if err := os.WriteFile(composeFile, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		services []string
+		withDeps bool
+		pull     bool
+		want     []string
+	}{
+		{"dependencies, skipping present", []string{"app", "cache"}, true, false, []string{"ghcr.io/org/api:1.2", "redis:7"}},
+		{"without dependencies", []string{"app", "cache"}, false, false, []string{"redis:7"}},
+		{"all present", []string{"db"}, true, false, nil},
+		{"--pull refreshes present", []string{"db"}, true, true, []string{"postgres:16"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			drv := &prePullDriver{present: map[string]bool{"postgres:16": true}}
+			var progress []string
+			e := &Engine{driver: drv, logger: slog.Default(), pull: tt.pull}
+			e.SetProgress(func(ev ProgressEvent) { progress = append(progress, ev.Message) })
+
+			e.prePullComposeImages(context.Background(), composeInvocation{}, []string{composeFile}, tt.services, tt.withDeps)
+
+			slices.Sort(drv.pulled)
+			if !slices.Equal(drv.pulled, tt.want) {
+				t.Errorf("pulled = %v, want %v", drv.pulled, tt.want)
+			}
+			if len(tt.want) == 0 && len(progress) != 0 {
+				t.Errorf("progress = %v, want none when nothing is pulled", progress)
+			}
+			for _, img := range tt.want {
+				if !slices.Contains(progress, "Pulled "+img) {
+					t.Errorf("progress = %v, want a report for %s", progress, img)
+				}
+			}
+		})
+	}
+}
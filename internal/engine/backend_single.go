@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"maps"
 
 	"github.com/fgrehm/crib/internal/config"
 	"github.com/fgrehm/crib/internal/driver"
@@ -107,6 +108,10 @@ func (b *singleBackend) createContainer(ctx context.Context, opts createOpts) (c
 	if err != nil {
 		return createContainerResult{}, err
 	}
+	if runOpts.Ports, err = b.e.checkHostPorts(b.cfg, runOpts.Ports); err != nil {
+		return createContainerResult{}, err
+	}
+	maps.Copy(runOpts.Labels, b.e.labels)
 	if b.e.store.IsExplicitHome() {
 		runOpts.Labels[ocidriver.LabelHome] = b.e.store.BaseDir()
 	}
@@ -123,12 +128,26 @@ func (b *singleBackend) createContainer(ctx context.Context, opts createOpts) (c
 		claimed[runOpts.WorkspaceMount.Target] = true
 	}
 
+	// Ad-hoc --mount entries come right after the project's own mounts.
+	preCLI := len(runOpts.Mounts)
+	runOpts.Mounts = append(runOpts.Mounts, b.e.cliMounts()...)
+	runOpts.Mounts = filterMountsAfter(runOpts.Mounts, preCLI, claimed, "cli", b.e.logger)
+	if b.e.socketGroup != "" {
+		runOpts.GroupAdd = append(runOpts.GroupAdd, b.e.socketGroup)
+	}
+
 	globalWS := b.e.expandedGlobalWorkspace(b.ws, b.workspaceFolder)
 
-	// Prepend global env so project-level ContainerEnv (already present in
-	// runOpts.Env via buildRunOptions) wins on duplicate keys when the
-	// runtime resolves the final environment (later -e flags override).
-	applyGlobalEnv(runOpts, globalWS.Env)
+	// Env is layered global < features < .env < containerEnv < plugin, as
+	// in buildOverrideEnv for compose. The runtime resolves duplicate keys
+	// with the last -e flag, so the project's .env, feature env and then
+	// global env are each prepended to the ContainerEnv that buildRunOptions
+	// put in runOpts.Env; plugin env is appended below.
+	dotEnv, err := loadDotEnv(b.ws, b.cfg)
+	if err != nil {
+		return createContainerResult{}, err
+	}
+	applyGlobalEnv(runOpts, dotEnv)
 	if err := applyGlobalMounts(runOpts, globalWS.Mounts, claimed, b.e.logger); err != nil {
 		return createContainerResult{}, err
 	}
@@ -142,6 +161,7 @@ func (b *singleBackend) createContainer(ctx context.Context, opts createOpts) (c
 	preFeat := len(runOpts.Mounts)
 	applyFeatureMetadata(runOpts, opts.metadata, subCtx)
 	runOpts.Mounts = filterMountsAfter(runOpts.Mounts, preFeat, claimed, "feature", b.e.logger)
+	applyGlobalEnv(runOpts, globalWS.Env)
 
 	// Prepend global runArgs so project values (already in runOpts.ExtraArgs)
 	// win on conflict under the runtime's last-flag-wins semantics. Plugin
@@ -182,7 +202,7 @@ func (b *singleBackend) createContainer(ctx context.Context, opts createOpts) (c
 		return createContainerResult{}, fmt.Errorf("container not found after creation")
 	}
 
-	return createContainerResult{ContainerID: container.ID, ContainerName: name}, nil
+	return createContainerResult{ContainerID: container.ID, ContainerName: name, Ports: runOpts.Ports}, nil
 }
 
 func (b *singleBackend) deleteExisting(ctx context.Context) error {
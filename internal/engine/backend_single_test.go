@@ -2,8 +2,12 @@ package engine
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 
@@ -230,6 +234,64 @@ func TestSingleBackend_CreateContainer_AppliesFeatureMetadata(t *testing.T) {
 	}
 }
 
+func TestSingleBackend_CreateContainer_PortInUse(t *testing.T) {
+	store := workspace.NewStoreAt(t.TempDir())
+	ws := &workspace.Workspace{ID: "ws-ports", Source: "/home/user/project"}
+	if err := store.Save(ws); err != nil {
+		t.Fatal(err)
+	}
+	port := occupyPort(t, "127.0.0.1")
+	spec := fmt.Sprintf("127.0.0.1:%d:3000", port)
+
+	cfg := &config.DevContainerConfig{}
+	cfg.Image = "ubuntu:22.04"
+	cfg.ForwardPorts = config.StrIntArray{spec}
+
+	for _, autoPort := range []bool{false, true} {
+		t.Run(fmt.Sprintf("autoPort=%v", autoPort), func(t *testing.T) {
+			mockDrv := &snapshotUpMockDriver{containerID: "new-container"}
+			b := &singleBackend{
+				e: &Engine{
+					driver:   mockDrv,
+					store:    store,
+					logger:   slog.Default(),
+					stdout:   io.Discard,
+					stderr:   io.Discard,
+					progress: func(ProgressEvent) {},
+					autoPort: autoPort,
+				},
+				ws:              ws,
+				cfg:             cfg,
+				workspaceFolder: "/workspaces/project",
+			}
+
+			created, err := b.createContainer(context.Background(), createOpts{imageName: "ubuntu:22.04"})
+			if !autoPort {
+				if err == nil || !strings.Contains(err.Error(), spec) {
+					t.Fatalf("createContainer error = %v, want port conflict for %s", err, spec)
+				}
+				if len(mockDrv.runCalls) != 0 {
+					t.Error("RunContainer called despite the port conflict")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("createContainer: %v", err)
+			}
+			if len(mockDrv.runCalls) != 1 {
+				t.Fatalf("expected 1 RunContainer call, got %d", len(mockDrv.runCalls))
+			}
+			published := mockDrv.runCalls[0].Ports
+			if len(published) != 1 || published[0] == spec || !strings.HasSuffix(published[0], ":3000") {
+				t.Fatalf("published ports = %v, want %s remapped to a free host port", published, spec)
+			}
+			if !slices.Equal(created.Ports, published) {
+				t.Errorf("result ports = %v, want %v", created.Ports, published)
+			}
+		})
+	}
+}
+
 func TestSingleBackend_DeleteExisting_NoContainer(t *testing.T) {
 	// When no container exists, deleteExisting should not error.
 	drv := &mockDriver{}
@@ -602,6 +664,106 @@ func TestSingleBackend_CreateContainer_GlobalWorkspaceEnv(t *testing.T) {
 	}
 }
 
+func TestSingleBackend_CreateContainer_EnvPrecedence(t *testing.T) {
+	src := t.TempDir()
+	if err := os.WriteFile(filepath.Join(src, ".env"), []byte("FEATURE_VS_DOT=dotenv-wins\nDOT_VS_PROJECT=dotenv-loser\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ws := &workspace.Workspace{ID: "ws-env-order", Source: src}
+	mockDrv := &snapshotUpMockDriver{containerID: "new-container"}
+	eng := &Engine{
+		driver:   mockDrv,
+		store:    workspace.NewStoreAt(t.TempDir()),
+		logger:   slog.Default(),
+		stdout:   io.Discard,
+		stderr:   io.Discard,
+		progress: func(ProgressEvent) {},
+	}
+	eng.SetGlobalWorkspace(GlobalWorkspaceOptions{
+		Env: map[string]string{"GLOBAL_VS_FEATURE": "global-loser"},
+	})
+
+	cfg := &config.DevContainerConfig{}
+	cfg.Image = "alpine:3.20"
+	cfg.ContainerEnv = map[string]string{"DOT_VS_PROJECT": "project-wins", "FEATURE_VS_PROJECT": "project-wins"}
+	cfg.Customizations = map[string]any{"crib": map[string]any{"dotenv": true}}
+	metadata := []*config.ImageMetadata{{NonComposeBase: config.NonComposeBase{ContainerEnv: map[string]string{
+		"GLOBAL_VS_FEATURE":  "feature-wins",
+		"FEATURE_VS_DOT":     "feature-loser",
+		"FEATURE_VS_PROJECT": "feature-loser",
+	}}}}
+
+	b := &singleBackend{e: eng, ws: ws, cfg: cfg, workspaceFolder: "/workspaces/project"}
+	if _, err := b.createContainer(context.Background(), createOpts{imageName: "alpine:3.20", metadata: metadata}); err != nil {
+		t.Fatalf("createContainer: %v", err)
+	}
+
+	// The runtime resolves duplicate keys with the last -e flag.
+	final := map[string]string{}
+	for _, e := range mockDrv.runCalls[0].Env {
+		k, v, _ := strings.Cut(e, "=")
+		final[k] = v
+	}
+	want := map[string]string{
+		"GLOBAL_VS_FEATURE":  "feature-wins",
+		"FEATURE_VS_DOT":     "dotenv-wins",
+		"DOT_VS_PROJECT":     "project-wins",
+		"FEATURE_VS_PROJECT": "project-wins",
+	}
+	for k, v := range want {
+		if final[k] != v {
+			t.Errorf("%s = %q, want %q (Env: %v)", k, final[k], v, mockDrv.runCalls[0].Env)
+		}
+	}
+}
+
+func TestSingleBackend_CreateContainer_DotEnv(t *testing.T) {
+	for _, enabled := range []bool{true, false} {
+		t.Run(fmt.Sprintf("enabled=%v", enabled), func(t *testing.T) {
+			src := t.TempDir()
+			if err := os.WriteFile(filepath.Join(src, ".env"), []byte("DOT_ONLY=yes\nCONFLICT=dotenv-loser\n"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			store := workspace.NewStoreAt(t.TempDir())
+			ws := &workspace.Workspace{ID: "ws-dotenv", Source: src}
+
+			mockDrv := &snapshotUpMockDriver{containerID: "new-container"}
+			eng := &Engine{
+				driver:   mockDrv,
+				store:    store,
+				logger:   slog.Default(),
+				stdout:   io.Discard,
+				stderr:   io.Discard,
+				progress: func(ProgressEvent) {},
+			}
+
+			cfg := &config.DevContainerConfig{}
+			cfg.Image = "alpine:3.20"
+			cfg.ContainerEnv = map[string]string{"CONFLICT": "project-wins"}
+			cfg.Customizations = map[string]any{"crib": map[string]any{"dotenv": enabled}}
+
+			b := &singleBackend{e: eng, ws: ws, cfg: cfg, workspaceFolder: "/workspaces/project"}
+			if _, err := b.createContainer(context.Background(), createOpts{imageName: "alpine:3.20"}); err != nil {
+				t.Fatalf("createContainer: %v", err)
+			}
+
+			env := mockDrv.runCalls[0].Env
+			if got := slices.Contains(env, "DOT_ONLY=yes"); got != enabled {
+				t.Errorf("DOT_ONLY=yes in Env = %v, want %v: %v", got, enabled, env)
+			}
+			var lastConflict string
+			for _, e := range env {
+				if strings.HasPrefix(e, "CONFLICT=") {
+					lastConflict = e
+				}
+			}
+			if lastConflict != "CONFLICT=project-wins" {
+				t.Errorf("last CONFLICT entry = %q, want CONFLICT=project-wins (containerEnv must win over .env)", lastConflict)
+			}
+		})
+	}
+}
+
 func TestSingleBackend_CreateContainer_GlobalWorkspaceMountsAndRunArgs(t *testing.T) {
 	store := workspace.NewStoreAt(t.TempDir())
 	ws := &workspace.Workspace{ID: "ws-global-mounts", Source: "/home/user/project"}
@@ -673,6 +835,117 @@ func TestSingleBackend_CreateContainer_GlobalWorkspaceMountsAndRunArgs(t *testin
 	}
 }
 
+func TestSingleBackend_CreateContainer_CLIMounts(t *testing.T) {
+	store := workspace.NewStoreAt(t.TempDir())
+	ws := &workspace.Workspace{ID: "ws-cli-mounts", Source: "/home/user/project"}
+	if err := store.Save(ws); err != nil {
+		t.Fatal(err)
+	}
+
+	mockDrv := &snapshotUpMockDriver{containerID: "new-container"}
+	eng := &Engine{
+		driver:   mockDrv,
+		store:    store,
+		logger:   slog.Default(),
+		stdout:   io.Discard,
+		stderr:   io.Discard,
+		progress: func(ProgressEvent) {},
+	}
+	if err := eng.SetExtraMounts([]string{
+		"type=bind,src=./fixtures,dst=/fixtures,readonly",
+		"type=bind,src=/elsewhere,dst=/cache",
+	}, "/home/user/scratch"); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.DevContainerConfig{}
+	cfg.Image = "alpine:3.20"
+	cfg.Mounts = []config.Mount{{Type: "volume", Source: "project-cache", Target: "/cache"}}
+
+	b := &singleBackend{
+		e:               eng,
+		ws:              ws,
+		cfg:             cfg,
+		workspaceFolder: "/workspaces/project",
+	}
+	if _, err := b.createContainer(context.Background(), createOpts{imageName: "alpine:3.20"}); err != nil {
+		t.Fatalf("createContainer: %v", err)
+	}
+
+	want := []config.Mount{
+		{Type: "volume", Source: "project-cache", Target: "/cache"},
+		{Type: "bind", Source: "/home/user/scratch/fixtures", Target: "/fixtures", ReadOnly: true},
+	}
+	if got := mockDrv.runCalls[0].Mounts; !slices.Equal(got, want) {
+		t.Errorf("Mounts = %v, want %v (project mount keeps /cache)", got, want)
+	}
+}
+
+func TestSingleBackend_CreateContainer_LabelFiles(t *testing.T) {
+	store := workspace.NewStoreAt(t.TempDir())
+	ws := &workspace.Workspace{ID: "ws-label-file", Source: "/home/user/project"}
+	if err := store.Save(ws); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	first := filepath.Join(dir, "base.labels")
+	second := filepath.Join(dir, "ci.labels")
+	if err := os.WriteFile(first, []byte("team=platform\nci.run=1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(second, []byte("# from CI\nci.run=42\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	mockDrv := &snapshotUpMockDriver{containerID: "new-container"}
+	eng := &Engine{
+		driver:   mockDrv,
+		store:    store,
+		logger:   slog.Default(),
+		stdout:   io.Discard,
+		stderr:   io.Discard,
+		progress: func(ProgressEvent) {},
+	}
+	if err := eng.SetLabelFiles([]string{first, second}); err != nil {
+		t.Fatalf("SetLabelFiles: %v", err)
+	}
+
+	cfg := &config.DevContainerConfig{}
+	cfg.Image = "alpine:3.20"
+	b := &singleBackend{e: eng, ws: ws, cfg: cfg, workspaceFolder: "/workspaces/project"}
+	if _, err := b.createContainer(context.Background(), createOpts{imageName: "alpine:3.20"}); err != nil {
+		t.Fatalf("createContainer: %v", err)
+	}
+
+	labels := mockDrv.runCalls[0].Labels
+	if labels["team"] != "platform" || labels["ci.run"] != "42" {
+		t.Errorf("Labels = %v, want team=platform and ci.run=42 (later file wins)", labels)
+	}
+}
+
+func TestSetLabelFiles_Errors(t *testing.T) {
+	e := &Engine{}
+	if err := e.SetLabelFiles([]string{filepath.Join(t.TempDir(), "missing")}); err == nil {
+		t.Error("expected error for a missing label file")
+	}
+
+	reserved := filepath.Join(t.TempDir(), "labels")
+	if err := os.WriteFile(reserved, []byte("crib.home=/tmp\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.SetLabelFiles([]string{reserved}); err == nil {
+		t.Error("expected error for a reserved crib. label")
+	}
+}
+
+func TestSetExtraMounts_InvalidSpec(t *testing.T) {
+	e := &Engine{}
+	if err := e.SetExtraMounts([]string{"type=bind,src=/tmp"}, "/"); err == nil {
+		t.Error("expected error for mount without a target")
+	}
+}
+
 func TestSingleBackend_CreateContainer_GlobalWorkspaceVarSubstitution(t *testing.T) {
 	store := workspace.NewStoreAt(t.TempDir())
 	ws := &workspace.Workspace{ID: "ws-var-sub", Source: "/home/user/projects/myproject"}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"slices"
@@ -49,6 +50,13 @@ func (e *Engine) buildImage(ctx context.Context, ws *workspace.Workspace, cfg *c
 // buildFromImage handles the image-based devcontainer path.
 // If features are specified, generates a Dockerfile that extends the base image.
 func (e *Engine) buildFromImage(ctx context.Context, ws *workspace.Workspace, cfg *config.DevContainerConfig, features []*feature.FeatureSet, containerUser string) (*buildResult, error) {
+	if e.pull {
+		e.reportProgress(PhaseBuild, "Pulling "+cfg.Image+"...")
+		if err := e.driver.PullImage(ctx, cfg.Image, e.stdout, e.stderr); err != nil {
+			return nil, err
+		}
+	}
+
 	// Inspect image for metadata label and Config.User.
 	// Fail open: image may not be pulled yet; the build below will pull it.
 	var imageUser string
@@ -224,6 +232,123 @@ func (e *Engine) buildFromDockerfile(ctx context.Context, ws *workspace.Workspac
 	return result, nil
 }
 
+// buildSecrets returns the build secret specs for cfg: those from
+// customizations.crib.buildSecrets, with relative sources resolved against
+// the devcontainer.json directory, followed by the --secret values.
+func (e *Engine) buildSecrets(cfg *config.DevContainerConfig) ([]string, error) {
+	raw, ok := extractCribCustomizations(cfg)["buildSecrets"]
+	if !ok || raw == nil {
+		return e.secrets, nil
+	}
+	items, ok := raw.([]any)
+	if !ok {
+		return nil, fmt.Errorf("customizations.crib.buildSecrets must be an array of strings")
+	}
+	secrets := make([]string, 0, len(items)+len(e.secrets))
+	for _, item := range items {
+		spec, ok := item.(string)
+		if !ok {
+			return nil, fmt.Errorf("customizations.crib.buildSecrets must be an array of strings")
+		}
+		s, err := parseBuildSecret(spec, filepath.Dir(cfg.Origin))
+		if err != nil {
+			return nil, err
+		}
+		secrets = append(secrets, s)
+	}
+	return append(secrets, e.secrets...), nil
+}
+
+// parseBuildSecret validates a BuildKit secret spec ("id=NAME,src=PATH" or
+// "id=NAME,env=VAR") and returns it with a relative src made absolute
+// against baseDir. The source file must exist and be readable, so a typo
+// fails before the build starts rather than inside a RUN step.
+func parseBuildSecret(spec, baseDir string) (string, error) {
+	var id, src, env string
+	parts := strings.Split(spec, ",")
+	for i, part := range parts {
+		k, v, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch k {
+		case "id":
+			id = v
+		case "src", "source":
+			if v != "" && !filepath.IsAbs(v) {
+				v = filepath.Join(baseDir, v)
+			}
+			src = v
+			parts[i] = k + "=" + v
+		case "env":
+			env = v
+		}
+	}
+	if id == "" {
+		return "", fmt.Errorf("build secret %q: missing id", spec)
+	}
+	if src == "" && env == "" {
+		return "", fmt.Errorf("build secret %q: needs src=FILE or env=VAR", spec)
+	}
+	if src != "" {
+		f, err := os.Open(src)
+		if err != nil {
+			return "", fmt.Errorf("build secret %q: %w", id, err)
+		}
+		_ = f.Close()
+	}
+	return strings.Join(parts, ","), nil
+}
+
+// buildContexts returns the named build contexts for cfg: those from
+// customizations.crib.buildContexts, with relative paths resolved against
+// the devcontainer.json directory, followed by the --build-context values.
+func (e *Engine) buildContexts(cfg *config.DevContainerConfig) ([]string, error) {
+	raw, ok := extractCribCustomizations(cfg)["buildContexts"]
+	if !ok || raw == nil {
+		return e.contexts, nil
+	}
+	items, ok := raw.([]any)
+	if !ok {
+		return nil, fmt.Errorf("customizations.crib.buildContexts must be an array of strings")
+	}
+	contexts := make([]string, 0, len(items)+len(e.contexts))
+	for _, item := range items {
+		spec, ok := item.(string)
+		if !ok {
+			return nil, fmt.Errorf("customizations.crib.buildContexts must be an array of strings")
+		}
+		c, err := parseBuildContext(spec, filepath.Dir(cfg.Origin))
+		if err != nil {
+			return nil, err
+		}
+		contexts = append(contexts, c)
+	}
+	return append(contexts, e.contexts...), nil
+}
+
+// parseBuildContext validates a named build context spec ("name=value").
+// Values with a scheme (docker-image://, https://, oci-layout://, ...) are
+// passed through untouched; anything else is a local directory, resolved
+// against baseDir, that must exist.
+func parseBuildContext(spec, baseDir string) (string, error) {
+	name, value, _ := strings.Cut(spec, "=")
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", fmt.Errorf("build context %q: missing name (want name=path)", spec)
+	}
+	if value == "" {
+		return "", fmt.Errorf("build context %q: missing path (want name=path)", name)
+	}
+	if strings.Contains(value, "://") {
+		return name + "=" + value, nil
+	}
+	if !filepath.IsAbs(value) {
+		value = filepath.Join(baseDir, value)
+	}
+	if _, err := os.Stat(value); err != nil {
+		return "", fmt.Errorf("build context %q: %w", name, err)
+	}
+	return name + "=" + value, nil
+}
+
 // doBuild writes the final Dockerfile and invokes the driver to build.
 func (e *Engine) doBuild(ctx context.Context, ws *workspace.Workspace, cfg *config.DevContainerConfig, dockerfileContent string, features []*feature.FeatureSet, containerUser, remoteUser string) (*buildResult, error) {
 	contextPath := config.GetContextPath(cfg)
@@ -234,7 +359,11 @@ func (e *Engine) doBuild(ctx context.Context, ws *workspace.Workspace, cfg *conf
 		if err != nil {
 			return nil, fmt.Errorf("preparing feature context: %w", err)
 		}
-		defer func() { _ = os.RemoveAll(featuresDir) }()
+		if e.keepGenerated {
+			e.reportProgress(PhaseBuild, "Keeping feature context: "+featuresDir)
+		} else {
+			defer func() { _ = os.RemoveAll(featuresDir) }()
+		}
 	}
 
 	// Write the generated Dockerfile to the context.
@@ -242,7 +371,11 @@ func (e *Engine) doBuild(ctx context.Context, ws *workspace.Workspace, cfg *conf
 	if err := os.WriteFile(tmpDockerfile, []byte(dockerfileContent), 0o644); err != nil {
 		return nil, fmt.Errorf("writing generated Dockerfile: %w", err)
 	}
-	defer func() { _ = os.Remove(tmpDockerfile) }()
+	if e.keepGenerated {
+		e.reportProgress(PhaseBuild, "Keeping generated Dockerfile: "+tmpDockerfile)
+	} else {
+		defer func() { _ = os.Remove(tmpDockerfile) }()
+	}
 
 	// Persist a copy in workspace state for troubleshooting.
 	wsDir := e.store.WorkspaceDir(ws.ID)
@@ -264,6 +397,9 @@ func (e *Engine) doBuild(ctx context.Context, ws *workspace.Workspace, cfg *conf
 	}
 
 	imageName := ocidriver.ImageName(ws.ID, hash)
+	if e.buildOpts.ImageName != "" {
+		imageName = e.buildOpts.ImageName
+	}
 
 	// Collect feature metadata regardless of cache hit. Runtime capabilities
 	// (privileged, mounts, entrypoints) must be applied even when the image
@@ -277,8 +413,10 @@ func (e *Engine) doBuild(ctx context.Context, ws *workspace.Workspace, cfg *conf
 		}
 	}
 
-	// Check if image already exists.
-	if _, inspErr := e.driver.InspectImage(ctx, imageName); inspErr == nil {
+	// Check if image already exists. When pulling, the tag may point at a
+	// build from an older base image, so build again; --recreate=image
+	// asks for a fresh build outright.
+	if _, inspErr := e.driver.InspectImage(ctx, imageName); inspErr == nil && !e.pull && !e.forceBuild {
 		e.reportProgress(PhaseBuild, "Image cached, skipping build")
 		return &buildResult{
 			imageName:      imageName,
@@ -302,18 +440,41 @@ func (e *Engine) doBuild(ctx context.Context, ws *workspace.Workspace, cfg *conf
 		buildTarget = cfg.Build.Target
 	}
 
-	var cacheFrom []string
+	var cacheFrom, cacheTo []string
 	if cfg.Build != nil {
 		cacheFrom = cfg.Build.CacheFrom
+		cacheTo = cfg.Build.CacheTo
 	}
+	cacheTo = append(slices.Clone(cacheTo), e.cacheTo...)
 
 	var buildOptions []string
 	if cfg.Build != nil {
 		buildOptions = cfg.Build.Options
 	}
 
-	// Clean up previous build image if hash changed.
-	e.cleanupPreviousBuildImage(ctx, ws.ID, imageName)
+	secrets, err := e.buildSecrets(cfg)
+	if err != nil {
+		return nil, err
+	}
+	buildContexts, err := e.buildContexts(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// Clean up previous build image if hash changed. Images built under
+	// another name or exported elsewhere don't replace it.
+	if e.buildOpts.ImageName == "" && !e.buildOpts.exports() {
+		e.cleanupPreviousBuildImage(ctx, ws.ID, imageName)
+	}
+
+	// Watch the build output for feature markers so a failure can be
+	// attributed to the feature whose install step broke.
+	stdout, stderr := e.stdout, e.stderr
+	var detector feature.FailureDetector
+	if len(features) > 0 {
+		stdout = io.MultiWriter(stdout, &detector)
+		stderr = io.MultiWriter(stderr, &detector)
+	}
 
 	e.reportProgress(PhaseBuild, "Building image...")
 	err = e.driver.BuildImage(ctx, ws.ID, &driver.BuildOptions{
@@ -324,12 +485,21 @@ func (e *Engine) doBuild(ctx context.Context, ws *workspace.Workspace, cfg *conf
 		Args:         buildArgs,
 		Target:       buildTarget,
 		CacheFrom:    cacheFrom,
+		CacheTo:      cacheTo,
+		Secrets:      secrets,
+		Contexts:     buildContexts,
+		Pull:         e.pull,
+		Output:       e.buildOpts.output(),
+		Push:         e.buildOpts.Push,
 		Labels:       map[string]string{ocidriver.LabelWorkspace: ws.ID},
 		Options:      buildOptions,
-		Stdout:       e.stdout,
-		Stderr:       e.stderr,
+		Stdout:       stdout,
+		Stderr:       stderr,
 	})
 	if err != nil {
+		if id := detector.FailedFeature(); id != "" {
+			return nil, fmt.Errorf("building image: feature %s failed to install (rerun with --verbose to see the build output): %w", id, err)
+		}
 		return nil, fmt.Errorf("building image: %w", err)
 	}
 
@@ -379,13 +549,34 @@ func (e *Engine) resolveComposeContainerUser(ctx context.Context, cfg *config.De
 		return serviceUser
 	}
 	if baseImage != "" {
-		if details, err := e.driver.InspectImage(ctx, baseImage); err == nil && details != nil && details.Config.User != "" {
-			return userFromConfigUser(details.Config.User)
+		if user, ok := e.imageUser(ctx, baseImage); ok && user != "" {
+			return user
 		}
 	}
 	return "root"
 }
 
+// imageUser returns the user configured in image (Config.User, without the
+// group), inspecting it only the first time it is asked for during the
+// current Up or Restart. ok is false when the image cannot be inspected;
+// failures are not cached, since the image may be built or pulled later in
+// the same run.
+func (e *Engine) imageUser(ctx context.Context, image string) (user string, ok bool) {
+	if user, ok := e.imageUsers[image]; ok {
+		return user, true
+	}
+	details, err := e.driver.InspectImage(ctx, image)
+	if err != nil || details == nil {
+		return "", false
+	}
+	user = userFromConfigUser(details.Config.User)
+	if e.imageUsers == nil {
+		e.imageUsers = make(map[string]string)
+	}
+	e.imageUsers[image] = user
+	return user, true
+}
+
 // resolveFeatures resolves and orders features from the config.
 func (e *Engine) resolveFeatures(cfg *config.DevContainerConfig, configDir string) ([]*feature.FeatureSet, error) {
 	if len(cfg.Features) == 0 {
@@ -404,6 +595,9 @@ func (e *Engine) resolveFeatures(cfg *config.DevContainerConfig, configDir strin
 		if err != nil {
 			return nil, fmt.Errorf("resolving feature %q: %w", id, err)
 		}
+		if digest := resolver.Digest(id); digest != "" {
+			e.logger.Debug("resolved feature", "id", id, "digest", digest)
+		}
 
 		fc, err := feature.ParseFeatureConfig(folder)
 		if err != nil {
@@ -557,6 +751,7 @@ func featureToMetadata(f *feature.FeatureSet) *config.ImageMetadata {
 		Entrypoint: f.Config.Entrypoint,
 	}
 	m.CapAdd = f.Config.CapAdd
+	m.CapDrop = f.Config.CapDrop
 	m.SecurityOpt = f.Config.SecurityOpt
 	m.Init = f.Config.Init
 	m.Privileged = f.Config.Privileged
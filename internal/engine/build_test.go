@@ -3,7 +3,11 @@ package engine
 import (
 	"context"
 	"fmt"
+	"io"
 	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 
@@ -273,6 +277,61 @@ func TestResolveComposeContainerUser(t *testing.T) {
 	}
 }
 
+// inspectCountingDriver counts InspectImage calls per image. Images listed in
+// users exist with that Config.User; any other image is not found.
+type inspectCountingDriver struct {
+	mockDriver
+	users    map[string]string
+	inspects map[string]int
+}
+
+func (m *inspectCountingDriver) InspectImage(_ context.Context, name string) (*driver.ImageDetails, error) {
+	if m.inspects == nil {
+		m.inspects = make(map[string]int)
+	}
+	m.inspects[name]++
+	user, ok := m.users[name]
+	if !ok {
+		return nil, fmt.Errorf("image %s not found", name)
+	}
+	details := &driver.ImageDetails{}
+	details.Config.User = user
+	return details, nil
+}
+
+func TestResolveComposeContainerUser_CachesImageUser(t *testing.T) {
+	drv := &inspectCountingDriver{users: map[string]string{"app:dev": "dev:dev"}}
+	eng := &Engine{driver: drv, logger: slog.Default()}
+	cfg := &config.DevContainerConfig{}
+
+	for range 3 {
+		if got := eng.resolveComposeContainerUser(context.Background(), cfg, "", "app:dev"); got != "dev" {
+			t.Fatalf("resolveComposeContainerUser = %q, want dev", got)
+		}
+		if got := eng.resolveComposeContainerUser(context.Background(), cfg, "", "app:missing"); got != "root" {
+			t.Fatalf("resolveComposeContainerUser (missing image) = %q, want root", got)
+		}
+	}
+	if n := drv.inspects["app:dev"]; n != 1 {
+		t.Errorf("InspectImage(app:dev) called %d times, want 1", n)
+	}
+	// Failed inspects are retried: the image may be built later in the run.
+	if n := drv.inspects["app:missing"]; n != 3 {
+		t.Errorf("InspectImage(app:missing) called %d times, want 3", n)
+	}
+
+	// The cache does not outlive a run: Up starts with a fresh one, even if
+	// it fails right after.
+	ws := &workspace.Workspace{ID: "ws", Source: t.TempDir(), DevContainerPath: ".devcontainer/devcontainer.json"}
+	if _, err := eng.Up(context.Background(), ws, UpOptions{}); err == nil {
+		t.Fatal("Up without a config should fail")
+	}
+	eng.resolveComposeContainerUser(context.Background(), cfg, "", "app:dev")
+	if n := drv.inspects["app:dev"]; n != 2 {
+		t.Errorf("InspectImage(app:dev) called %d times after a new Up, want 2", n)
+	}
+}
+
 func TestParseImageMetadataLabel(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -541,3 +600,363 @@ func TestResolveContainerUser_FromConfig(t *testing.T) {
 		})
 	}
 }
+
+// failingBuildDriver fails every image build after writing output to the
+// build's stderr.
+type failingBuildDriver struct {
+	mockDriver
+	output string
+}
+
+func (m *failingBuildDriver) InspectImage(_ context.Context, name string) (*driver.ImageDetails, error) {
+	return nil, fmt.Errorf("image %s not found", name)
+}
+
+func (m *failingBuildDriver) BuildImage(_ context.Context, _ string, opts *driver.BuildOptions) error {
+	fmt.Fprint(opts.Stderr, m.output)
+	return fmt.Errorf("exit status 1")
+}
+
+func TestDoBuild_ReportsFailedFeature(t *testing.T) {
+	project := t.TempDir()
+	featureDir := filepath.Join(project, ".devcontainer", "tool")
+	if err := os.MkdirAll(featureDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(featureDir, "install.sh"), []byte("#!/bin/sh\nexit 1\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	drv := &failingBuildDriver{output: "#9 0.2 " + feature.FeatureMarkerPrefix + "./tool\n#9 ERROR: exit code: 1\n"}
+	eng := &Engine{
+		driver: drv,
+		store:  workspace.NewStoreAt(t.TempDir()),
+		logger: slog.Default(),
+		stdout: io.Discard,
+		stderr: io.Discard,
+	}
+	cfg := &config.DevContainerConfig{Origin: filepath.Join(project, ".devcontainer", "devcontainer.json")}
+	features := []*feature.FeatureSet{
+		{ConfigID: "./tool", Folder: featureDir, Config: &feature.FeatureConfig{ID: "tool"}},
+	}
+
+	_, err := eng.doBuild(context.Background(), &workspace.Workspace{ID: "ws"}, cfg, "FROM alpine\n", features, "root", "root")
+	if err == nil {
+		t.Fatal("expected build error")
+	}
+	if !strings.Contains(err.Error(), "feature ./tool failed to install") {
+		t.Errorf("error should name the failing feature, got: %v", err)
+	}
+}
+
+func TestDoBuild_KeepGenerated(t *testing.T) {
+	for _, keep := range []bool{true, false} {
+		project := t.TempDir()
+		featureDir := filepath.Join(project, ".devcontainer", "tool")
+		if err := os.MkdirAll(featureDir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(featureDir, "install.sh"), []byte("#!/bin/sh\n"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		var events []ProgressEvent
+		eng := &Engine{
+			driver:        &mockDriver{},
+			store:         workspace.NewStoreAt(t.TempDir()),
+			logger:        slog.Default(),
+			stdout:        io.Discard,
+			stderr:        io.Discard,
+			progress:      func(ev ProgressEvent) { events = append(events, ev) },
+			keepGenerated: keep,
+		}
+		contextPath := filepath.Join(project, ".devcontainer")
+		cfg := &config.DevContainerConfig{Origin: filepath.Join(contextPath, "devcontainer.json")}
+		features := []*feature.FeatureSet{
+			{ConfigID: "./tool", Folder: featureDir, Config: &feature.FeatureConfig{ID: "tool"}},
+		}
+
+		if _, err := eng.doBuild(context.Background(), &workspace.Workspace{ID: "ws"}, cfg, "FROM alpine\n", features, "root", "root"); err != nil {
+			t.Fatalf("keep=%v: doBuild: %v", keep, err)
+		}
+
+		dockerfile := filepath.Join(contextPath, ".crib-Dockerfile")
+		featuresDir := filepath.Join(contextPath, feature.ContextFeatureFolder)
+		for _, p := range []string{dockerfile, featuresDir} {
+			if _, err := os.Stat(p); (err == nil) != keep {
+				t.Errorf("keep=%v: %s exists = %v", keep, p, err == nil)
+			}
+		}
+
+		reported := false
+		for _, ev := range events {
+			if strings.Contains(ev.Message, dockerfile) {
+				reported = true
+			}
+		}
+		if reported != keep {
+			t.Errorf("keep=%v: Dockerfile path reported = %v, events: %+v", keep, reported, events)
+		}
+	}
+}
+
+// capturingBuildDriver records the options of the last image build.
+type capturingBuildDriver struct {
+	mockDriver
+	opts *driver.BuildOptions
+}
+
+func (m *capturingBuildDriver) InspectImage(_ context.Context, name string) (*driver.ImageDetails, error) {
+	return nil, fmt.Errorf("image %s not found", name)
+}
+
+func (m *capturingBuildDriver) BuildImage(_ context.Context, _ string, opts *driver.BuildOptions) error {
+	m.opts = opts
+	return nil
+}
+
+func TestDoBuild_ForwardsSecrets(t *testing.T) {
+	project := t.TempDir()
+	devcontainerDir := filepath.Join(project, ".devcontainer")
+	if err := os.MkdirAll(devcontainerDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(devcontainerDir, "npmrc"), []byte("token"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cliSecret := filepath.Join(t.TempDir(), "gh-token")
+	if err := os.WriteFile(cliSecret, []byte("token"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	drv := &capturingBuildDriver{}
+	eng := &Engine{
+		driver: drv,
+		store:  workspace.NewStoreAt(t.TempDir()),
+		logger: slog.Default(),
+		stdout: io.Discard,
+		stderr: io.Discard,
+	}
+	if err := eng.SetBuildSecrets([]string{"id=gh,src=gh-token"}, filepath.Dir(cliSecret)); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.DevContainerConfig{Origin: filepath.Join(devcontainerDir, "devcontainer.json")}
+	cfg.Customizations = map[string]any{"crib": map[string]any{
+		"buildSecrets": []any{"id=npmrc,src=npmrc"},
+	}}
+
+	if _, err := eng.doBuild(context.Background(), &workspace.Workspace{ID: "ws"}, cfg, "FROM alpine\n", nil, "root", "root"); err != nil {
+		t.Fatalf("doBuild: %v", err)
+	}
+	want := []string{
+		"id=npmrc,src=" + filepath.Join(devcontainerDir, "npmrc"),
+		"id=gh,src=" + cliSecret,
+	}
+	if drv.opts == nil {
+		t.Fatal("BuildImage was not called")
+	}
+	if !slices.Equal(drv.opts.Secrets, want) {
+		t.Errorf("Secrets = %v, want %v", drv.opts.Secrets, want)
+	}
+}
+
+// pullTrackingDriver reports every image as present and records pulls and
+// build options.
+type pullTrackingDriver struct {
+	mockDriver
+	pulled []string
+	opts   *driver.BuildOptions
+}
+
+func (m *pullTrackingDriver) PullImage(_ context.Context, name string, _, _ io.Writer) error {
+	m.pulled = append(m.pulled, name)
+	return nil
+}
+
+func (m *pullTrackingDriver) BuildImage(_ context.Context, _ string, opts *driver.BuildOptions) error {
+	m.opts = opts
+	return nil
+}
+
+func TestDoBuild_Pull(t *testing.T) {
+	for _, pull := range []bool{false, true} {
+		t.Run(fmt.Sprintf("pull=%v", pull), func(t *testing.T) {
+			drv := &pullTrackingDriver{}
+			eng := &Engine{
+				driver: drv,
+				store:  workspace.NewStoreAt(t.TempDir()),
+				logger: slog.Default(),
+				stdout: io.Discard,
+				stderr: io.Discard,
+			}
+			eng.SetPull(pull)
+			cfg := &config.DevContainerConfig{Origin: filepath.Join(t.TempDir(), "devcontainer.json")}
+
+			if _, err := eng.doBuild(context.Background(), &workspace.Workspace{ID: "ws"}, cfg, "FROM alpine\n", nil, "root", "root"); err != nil {
+				t.Fatalf("doBuild: %v", err)
+			}
+			// The built image is already cached; only --pull forces a build.
+			if !pull {
+				if drv.opts != nil {
+					t.Error("cached image should not be rebuilt without pull")
+				}
+				return
+			}
+			if drv.opts == nil {
+				t.Fatal("pull should rebuild even though the image is cached")
+			}
+			if !drv.opts.Pull {
+				t.Error("BuildOptions.Pull = false, want true")
+			}
+		})
+	}
+}
+
+func TestBuildFromImage_PullsBaseImage(t *testing.T) {
+	drv := &pullTrackingDriver{}
+	eng := &Engine{
+		driver: drv,
+		store:  workspace.NewStoreAt(t.TempDir()),
+		logger: slog.Default(),
+		stdout: io.Discard,
+		stderr: io.Discard,
+	}
+	eng.SetPull(true)
+	cfg := &config.DevContainerConfig{}
+	cfg.Image = "ruby:3.2"
+
+	result, err := eng.buildFromImage(context.Background(), &workspace.Workspace{ID: "ws"}, cfg, nil, "")
+	if err != nil {
+		t.Fatalf("buildFromImage: %v", err)
+	}
+	if !slices.Equal(drv.pulled, []string{"ruby:3.2"}) {
+		t.Errorf("pulled = %v, want [ruby:3.2]", drv.pulled)
+	}
+	if result.imageName != "ruby:3.2" {
+		t.Errorf("imageName = %q, want ruby:3.2", result.imageName)
+	}
+}
+
+func TestDoBuild_MissingSecretFile(t *testing.T) {
+	project := t.TempDir()
+	drv := &capturingBuildDriver{}
+	eng := &Engine{
+		driver: drv,
+		store:  workspace.NewStoreAt(t.TempDir()),
+		logger: slog.Default(),
+		stdout: io.Discard,
+		stderr: io.Discard,
+	}
+	cfg := &config.DevContainerConfig{Origin: filepath.Join(project, "devcontainer.json")}
+	cfg.Customizations = map[string]any{"crib": map[string]any{
+		"buildSecrets": []any{"id=npmrc,src=missing"},
+	}}
+
+	_, err := eng.doBuild(context.Background(), &workspace.Workspace{ID: "ws"}, cfg, "FROM alpine\n", nil, "root", "root")
+	if err == nil || !strings.Contains(err.Error(), `build secret "npmrc"`) {
+		t.Fatalf("expected missing secret error, got %v", err)
+	}
+	if drv.opts != nil {
+		t.Error("build should not run with a missing secret")
+	}
+}
+
+func TestSetBuildSecrets_Invalid(t *testing.T) {
+	eng := &Engine{}
+	for _, spec := range []string{
+		"src=/etc/hostname",
+		"id=npmrc",
+		"id=npmrc,src=" + filepath.Join(t.TempDir(), "missing"),
+	} {
+		if err := eng.SetBuildSecrets([]string{spec}, "/"); err == nil {
+			t.Errorf("SetBuildSecrets(%q): expected error", spec)
+		}
+	}
+	if err := eng.SetBuildSecrets([]string{"id=token,env=GH_TOKEN"}, "/"); err != nil {
+		t.Errorf("env secret: %v", err)
+	}
+}
+
+func TestDoBuild_ForwardsBuildContexts(t *testing.T) {
+	project := t.TempDir()
+	devcontainerDir := filepath.Join(project, ".devcontainer")
+	if err := os.MkdirAll(filepath.Join(project, "assets"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(devcontainerDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	cliContext := t.TempDir()
+
+	drv := &capturingBuildDriver{}
+	eng := &Engine{
+		driver: drv,
+		store:  workspace.NewStoreAt(t.TempDir()),
+		logger: slog.Default(),
+		stdout: io.Discard,
+		stderr: io.Discard,
+	}
+	if err := eng.SetBuildContexts([]string{"shared=" + filepath.Base(cliContext)}, filepath.Dir(cliContext)); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.DevContainerConfig{Origin: filepath.Join(devcontainerDir, "devcontainer.json")}
+	cfg.Customizations = map[string]any{"crib": map[string]any{
+		"buildContexts": []any{"assets=../assets", "base=docker-image://alpine:3.20"},
+	}}
+
+	if _, err := eng.doBuild(context.Background(), &workspace.Workspace{ID: "ws"}, cfg, "FROM alpine\n", nil, "root", "root"); err != nil {
+		t.Fatalf("doBuild: %v", err)
+	}
+	want := []string{
+		"assets=" + filepath.Join(project, "assets"),
+		"base=docker-image://alpine:3.20",
+		"shared=" + cliContext,
+	}
+	if drv.opts == nil {
+		t.Fatal("BuildImage was not called")
+	}
+	if !slices.Equal(drv.opts.Contexts, want) {
+		t.Errorf("Contexts = %v, want %v", drv.opts.Contexts, want)
+	}
+}
+
+func TestDoBuild_MissingBuildContext(t *testing.T) {
+	project := t.TempDir()
+	drv := &capturingBuildDriver{}
+	eng := &Engine{
+		driver: drv,
+		store:  workspace.NewStoreAt(t.TempDir()),
+		logger: slog.Default(),
+		stdout: io.Discard,
+		stderr: io.Discard,
+	}
+	cfg := &config.DevContainerConfig{Origin: filepath.Join(project, "devcontainer.json")}
+	cfg.Customizations = map[string]any{"crib": map[string]any{
+		"buildContexts": []any{"assets=missing"},
+	}}
+
+	_, err := eng.doBuild(context.Background(), &workspace.Workspace{ID: "ws"}, cfg, "FROM alpine\n", nil, "root", "root")
+	if err == nil || !strings.Contains(err.Error(), `build context "assets"`) {
+		t.Fatalf("expected missing build context error, got %v", err)
+	}
+	if drv.opts != nil {
+		t.Error("build should not run with a missing build context")
+	}
+}
+
+func TestSetBuildContexts_Invalid(t *testing.T) {
+	eng := &Engine{}
+	for _, spec := range []string{
+		"assets",
+		"=/tmp",
+		"assets=",
+		"assets=" + filepath.Join(t.TempDir(), "missing"),
+	} {
+		if err := eng.SetBuildContexts([]string{spec}, "/"); err == nil {
+			t.Errorf("SetBuildContexts(%q): expected error", spec)
+		}
+	}
+	if err := eng.SetBuildContexts([]string{"base=docker-image://alpine:3.20"}, "/"); err != nil {
+		t.Errorf("image context: %v", err)
+	}
+}
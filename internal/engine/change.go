@@ -19,84 +19,168 @@ const (
 	changeNeedsRebuild                         // Image, Dockerfile, features — full rebuild required.
 )
 
+// configField describes one devcontainer.json property tracked by change
+// detection: how to compare it, how to render it, and what changing it means
+// for an existing container.
+type configField struct {
+	name  string
+	kind  configChangeKind
+	equal func(a, b *config.DevContainerConfig) bool
+	value func(c *config.DevContainerConfig) any
+}
+
+// configFields lists every property inspected by detectConfigChange and
+// diffConfig. Fields with kind changeNone are reported by `crib diff` but do
+// not require a container recreate.
+//
+// RemoteEnv is intentionally absent. The stored config includes probed
+// environment values (from userEnvProbe) merged into RemoteEnv during setup,
+// which won't be present in a freshly parsed config. Also, remoteEnv is
+// injected at exec time via -e flags, so changes don't require container
+// recreation.
+var configFields = []configField{
+	// Image-affecting changes.
+	{"image", changeNeedsRebuild,
+		func(a, b *config.DevContainerConfig) bool { return a.Image == b.Image },
+		func(c *config.DevContainerConfig) any { return c.Image }},
+	{"dockerFile", changeNeedsRebuild,
+		func(a, b *config.DevContainerConfig) bool { return a.Dockerfile == b.Dockerfile },
+		func(c *config.DevContainerConfig) any { return c.Dockerfile }},
+	{"build", changeNeedsRebuild,
+		func(a, b *config.DevContainerConfig) bool { return buildOptsEqual(a.Build, b.Build) },
+		func(c *config.DevContainerConfig) any { return c.Build }},
+	{"features", changeNeedsRebuild,
+		func(a, b *config.DevContainerConfig) bool { return featuresEqual(a.Features, b.Features) },
+		func(c *config.DevContainerConfig) any { return c.Features }},
+
+	// Container runtime config: a recreate is sufficient.
+	{"containerEnv", changeSafe,
+		func(a, b *config.DevContainerConfig) bool { return stringMapsEqual(a.ContainerEnv, b.ContainerEnv) },
+		func(c *config.DevContainerConfig) any { return c.ContainerEnv }},
+	{"containerUser", changeSafe,
+		func(a, b *config.DevContainerConfig) bool { return a.ContainerUser == b.ContainerUser },
+		func(c *config.DevContainerConfig) any { return c.ContainerUser }},
+	{"remoteUser", changeSafe,
+		func(a, b *config.DevContainerConfig) bool { return a.RemoteUser == b.RemoteUser },
+		func(c *config.DevContainerConfig) any { return c.RemoteUser }},
+	{"workspaceMount", changeSafe,
+		func(a, b *config.DevContainerConfig) bool { return a.WorkspaceMount == b.WorkspaceMount },
+		func(c *config.DevContainerConfig) any { return c.WorkspaceMount }},
+	{"workspaceFolder", changeSafe,
+		func(a, b *config.DevContainerConfig) bool { return a.WorkspaceFolder == b.WorkspaceFolder },
+		func(c *config.DevContainerConfig) any { return c.WorkspaceFolder }},
+	{"mounts", changeSafe,
+		func(a, b *config.DevContainerConfig) bool { return mountsEqual(a.Mounts, b.Mounts) },
+		func(c *config.DevContainerConfig) any { return c.Mounts }},
+	{"runArgs", changeSafe,
+		func(a, b *config.DevContainerConfig) bool { return strSlicesEqual(a.RunArgs, b.RunArgs) },
+		func(c *config.DevContainerConfig) any { return c.RunArgs }},
+	{"appPort", changeSafe,
+		func(a, b *config.DevContainerConfig) bool { return strSlicesEqual(a.AppPort, b.AppPort) },
+		func(c *config.DevContainerConfig) any { return c.AppPort }},
+	{"forwardPorts", changeSafe,
+		func(a, b *config.DevContainerConfig) bool { return strSlicesEqual(a.ForwardPorts, b.ForwardPorts) },
+		func(c *config.DevContainerConfig) any { return c.ForwardPorts }},
+	{"init", changeSafe,
+		func(a, b *config.DevContainerConfig) bool { return boolPtrEqual(a.Init, b.Init) },
+		func(c *config.DevContainerConfig) any { return c.Init }},
+	{"privileged", changeSafe,
+		func(a, b *config.DevContainerConfig) bool { return boolPtrEqual(a.Privileged, b.Privileged) },
+		func(c *config.DevContainerConfig) any { return c.Privileged }},
+	{"capAdd", changeSafe,
+		func(a, b *config.DevContainerConfig) bool { return strSlicesEqual(a.CapAdd, b.CapAdd) },
+		func(c *config.DevContainerConfig) any { return c.CapAdd }},
+	{"capDrop", changeSafe,
+		func(a, b *config.DevContainerConfig) bool { return strSlicesEqual(a.CapDrop, b.CapDrop) },
+		func(c *config.DevContainerConfig) any { return c.CapDrop }},
+	{"securityOpt", changeSafe,
+		func(a, b *config.DevContainerConfig) bool { return strSlicesEqual(a.SecurityOpt, b.SecurityOpt) },
+		func(c *config.DevContainerConfig) any { return c.SecurityOpt }},
+	{"overrideCommand", changeSafe,
+		func(a, b *config.DevContainerConfig) bool { return boolPtrEqual(a.OverrideCommand, b.OverrideCommand) },
+		func(c *config.DevContainerConfig) any { return c.OverrideCommand }},
+
+	// Compose-specific safe changes.
+	{"dockerComposeFile", changeSafe,
+		func(a, b *config.DevContainerConfig) bool {
+			return strSlicesEqual(a.DockerComposeFile, b.DockerComposeFile)
+		},
+		func(c *config.DevContainerConfig) any { return c.DockerComposeFile }},
+	{"service", changeSafe,
+		func(a, b *config.DevContainerConfig) bool { return a.Service == b.Service },
+		func(c *config.DevContainerConfig) any { return c.Service }},
+	{"runServices", changeSafe,
+		func(a, b *config.DevContainerConfig) bool { return strSlicesEqual(a.RunServices, b.RunServices) },
+		func(c *config.DevContainerConfig) any { return c.RunServices }},
+
+	// No-op for the container: picked up on the next hook run or exec.
+	{"name", changeNone,
+		func(a, b *config.DevContainerConfig) bool { return a.Name == b.Name },
+		func(c *config.DevContainerConfig) any { return c.Name }},
+	{"userEnvProbe", changeNone,
+		func(a, b *config.DevContainerConfig) bool { return a.UserEnvProbe == b.UserEnvProbe },
+		func(c *config.DevContainerConfig) any { return c.UserEnvProbe }},
+	{"waitFor", changeNone,
+		func(a, b *config.DevContainerConfig) bool { return a.WaitFor == b.WaitFor },
+		func(c *config.DevContainerConfig) any { return c.WaitFor }},
+	{"onCreateCommand", changeNone,
+		func(a, b *config.DevContainerConfig) bool { return hooksEqual(a.OnCreateCommand, b.OnCreateCommand) },
+		func(c *config.DevContainerConfig) any { return c.OnCreateCommand }},
+	{"updateContentCommand", changeNone,
+		func(a, b *config.DevContainerConfig) bool {
+			return hooksEqual(a.UpdateContentCommand, b.UpdateContentCommand)
+		},
+		func(c *config.DevContainerConfig) any { return c.UpdateContentCommand }},
+	{"postCreateCommand", changeNone,
+		func(a, b *config.DevContainerConfig) bool {
+			return hooksEqual(a.PostCreateCommand, b.PostCreateCommand)
+		},
+		func(c *config.DevContainerConfig) any { return c.PostCreateCommand }},
+	{"postStartCommand", changeNone,
+		func(a, b *config.DevContainerConfig) bool { return hooksEqual(a.PostStartCommand, b.PostStartCommand) },
+		func(c *config.DevContainerConfig) any { return c.PostStartCommand }},
+	{"postAttachCommand", changeNone,
+		func(a, b *config.DevContainerConfig) bool {
+			return hooksEqual(a.PostAttachCommand, b.PostAttachCommand)
+		},
+		func(c *config.DevContainerConfig) any { return c.PostAttachCommand }},
+}
+
 // detectConfigChange compares a stored config with a freshly parsed config
 // and classifies the changes.
 func detectConfigChange(stored, current *config.DevContainerConfig) configChangeKind {
-	// Check image-affecting changes.
-	if stored.Image != current.Image {
-		return changeNeedsRebuild
-	}
-	if stored.Dockerfile != current.Dockerfile {
-		return changeNeedsRebuild
-	}
-	if !buildOptsEqual(stored.Build, current.Build) {
-		return changeNeedsRebuild
-	}
-	if !featuresEqual(stored.Features, current.Features) {
-		return changeNeedsRebuild
-	}
-
-	// Check safe changes (container runtime config).
-	if !stringMapsEqual(stored.ContainerEnv, current.ContainerEnv) {
-		return changeSafe
-	}
-	// Note: RemoteEnv is intentionally not compared here. The stored config
-	// includes probed environment values (from userEnvProbe) merged into
-	// RemoteEnv during setup, which won't be present in a freshly parsed
-	// config. Also, remoteEnv is injected at exec time via -e flags, so
-	// changes don't require container recreation.
-	if stored.ContainerUser != current.ContainerUser {
-		return changeSafe
-	}
-	if stored.RemoteUser != current.RemoteUser {
-		return changeSafe
-	}
-	if stored.WorkspaceMount != current.WorkspaceMount {
-		return changeSafe
-	}
-	if stored.WorkspaceFolder != current.WorkspaceFolder {
-		return changeSafe
-	}
-	if !mountsEqual(stored.Mounts, current.Mounts) {
-		return changeSafe
-	}
-	if !strSlicesEqual(stored.RunArgs, current.RunArgs) {
-		return changeSafe
-	}
-	if !strSlicesEqual([]string(stored.AppPort), []string(current.AppPort)) {
-		return changeSafe
-	}
-	if !strSlicesEqual([]string(stored.ForwardPorts), []string(current.ForwardPorts)) {
-		return changeSafe
-	}
-	if !boolPtrEqual(stored.Init, current.Init) {
-		return changeSafe
-	}
-	if !boolPtrEqual(stored.Privileged, current.Privileged) {
-		return changeSafe
-	}
-	if !strSlicesEqual(stored.CapAdd, current.CapAdd) {
-		return changeSafe
-	}
-	if !strSlicesEqual(stored.SecurityOpt, current.SecurityOpt) {
-		return changeSafe
-	}
-	if !boolPtrEqual(stored.OverrideCommand, current.OverrideCommand) {
-		return changeSafe
+	kind := changeNone
+	for _, f := range configFields {
+		if f.kind > kind && !f.equal(stored, current) {
+			kind = f.kind
+		}
 	}
+	return kind
+}
 
-	// Check compose-specific safe changes.
-	if !strSlicesEqual([]string(stored.DockerComposeFile), []string(current.DockerComposeFile)) {
-		return changeSafe
-	}
-	if stored.Service != current.Service {
-		return changeSafe
-	}
-	if !strSlicesEqual(stored.RunServices, current.RunServices) {
-		return changeSafe
-	}
+// fieldChange records a single property that differs between two configs.
+type fieldChange struct {
+	field           string
+	kind            configChangeKind
+	stored, current any
+}
 
-	return changeNone
+// diffConfig returns every tracked property that differs between stored and
+// current, in configFields order.
+func diffConfig(stored, current *config.DevContainerConfig) []fieldChange {
+	var changes []fieldChange
+	for _, f := range configFields {
+		if f.equal(stored, current) {
+			continue
+		}
+		changes = append(changes, fieldChange{
+			field:   f.name,
+			kind:    f.kind,
+			stored:  f.value(stored),
+			current: f.value(current),
+		})
+	}
+	return changes
 }
 
 // --- comparison helpers ---
@@ -147,7 +231,10 @@ func mountsEqual(a, b []config.Mount) bool {
 	return true
 }
 
+// buildOptsEqual compares build options, ignoring cacheTo: it only controls
+// where build cache is exported, so changing it needs no rebuild.
 func buildOptsEqual(a, b *config.ConfigBuildOptions) bool {
+	a, b = a.WithoutCacheTo(), b.WithoutCacheTo()
 	if a == nil && b == nil {
 		return true
 	}
@@ -186,6 +273,13 @@ func featuresEqual(a, b map[string]any) bool {
 	return reflect.DeepEqual(a, b)
 }
 
+func hooksEqual(a, b config.LifecycleHook) bool {
+	if len(a) == 0 && len(b) == 0 {
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
 // computeComposeFilesHash computes a short fingerprint (truncated SHA-256) of
 // the contents of all compose files. This catches changes inside compose files
 // (volumes, ports, env, etc.) that are invisible to detectConfigChange, which
@@ -0,0 +1,95 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/fgrehm/crib/internal/config"
+	"github.com/fgrehm/crib/internal/feature"
+	"github.com/fgrehm/crib/internal/workspace"
+)
+
+// generatedArtifacts lists the files crib writes into project directories
+// and normally removes once it is done with them, mapped to whether the
+// entry is a directory. A run killed midway can leave them behind. The
+// compose override names were used by older crib versions, which wrote
+// them next to the devcontainer config.
+var generatedArtifacts = map[string]bool{
+	".crib-Dockerfile":               false,
+	".crib-compose-override.yml":     false,
+	".crib-podman-down-override.yml": false,
+	feature.ContextFeatureFolder:     true,
+}
+
+// Clean removes the files crib generated for ws that a failed or killed run
+// left in the project: the project root, the devcontainer config directory
+// and, when the config can be parsed, the build context. Only entries with
+// one of the exact generatedArtifacts names (and the expected file type)
+// are touched. With dryRun, nothing is removed. It returns the paths that
+// were (or would be) removed.
+func (e *Engine) Clean(ws *workspace.Workspace, dryRun bool) ([]string, error) {
+	dirs := []string{ws.Source, configDir(ws)}
+	// A broken config should not keep its leftovers around, so parse
+	// errors only narrow the search.
+	if cfg, _, err := e.parseAndSubstitute(ws); err == nil {
+		if config.GetDockerfilePath(cfg) != "" {
+			dirs = append(dirs, config.GetContextPath(cfg))
+		}
+	}
+	return removeGeneratedArtifacts(dirs, dryRun)
+}
+
+// removeGeneratedArtifacts removes generatedArtifacts directly inside each
+// of dirs (not recursively). Symlinks and entries whose type does not match
+// are left alone.
+func removeGeneratedArtifacts(dirs []string, dryRun bool) ([]string, error) {
+	names := make([]string, 0, len(generatedArtifacts))
+	for name := range generatedArtifacts {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var removed []string
+	seen := make(map[string]bool)
+	for _, dir := range dirs {
+		dir = filepath.Clean(dir)
+		if seen[dir] {
+			continue
+		}
+		seen[dir] = true
+
+		for _, name := range names {
+			path := filepath.Join(dir, name)
+			fi, err := os.Lstat(path)
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			if err != nil {
+				return removed, fmt.Errorf("checking %s: %w", path, err)
+			}
+			if fi.Mode().Type() != modeFor(generatedArtifacts[name]) {
+				continue
+			}
+			if !dryRun {
+				if err := os.RemoveAll(path); err != nil {
+					return removed, fmt.Errorf("removing %s: %w", path, err)
+				}
+			}
+			removed = append(removed, path)
+		}
+	}
+	return removed, nil
+}
+
+// modeFor returns the file type bits expected for a directory or a regular
+// file.
+func modeFor(isDir bool) fs.FileMode {
+	if isDir {
+		return fs.ModeDir
+	}
+	return 0
+}
@@ -0,0 +1,129 @@
+package engine
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/fgrehm/crib/internal/workspace"
+)
+
+func writeFiles(t *testing.T, root string, files ...string) {
+	t.Helper()
+	for _, f := range files {
+		path := filepath.Join(root, f)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestClean(t *testing.T) {
+	project := t.TempDir()
+	writeFiles(t, project,
+		".devcontainer/devcontainer.json",
+		".devcontainer/Dockerfile",
+		// Leftovers in the config directory and the build context.
+		".devcontainer/.crib-compose-override.yml",
+		".devcontainer/.crib-podman-down-override.yml",
+		"app/.crib-Dockerfile",
+		"app/.crib-features/0-node/install.sh",
+		".crib-Dockerfile",
+		// Look-alikes and nested copies must survive.
+		".crib-Dockerfile.bak",
+		".crib-notes",
+		"src/.crib-Dockerfile",
+		"app/main.go",
+	)
+	if err := os.WriteFile(filepath.Join(project, ".devcontainer", "devcontainer.json"),
+		[]byte(`{"build": {"dockerfile": "Dockerfile", "context": "../app"}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// A directory named like a generated file and a symlink to a generated
+	// name are not crib's.
+	if err := os.Mkdir(filepath.Join(project, ".crib-compose-override.yml"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(project, "app"), filepath.Join(project, ".crib-features")); err != nil {
+		t.Fatal(err)
+	}
+
+	e := &Engine{logger: slog.Default()}
+	ws := &workspace.Workspace{ID: "ws", Source: project, DevContainerPath: ".devcontainer/devcontainer.json"}
+
+	want := []string{
+		filepath.Join(project, ".crib-Dockerfile"),
+		filepath.Join(project, ".devcontainer", ".crib-compose-override.yml"),
+		filepath.Join(project, ".devcontainer", ".crib-podman-down-override.yml"),
+		filepath.Join(project, "app", ".crib-Dockerfile"),
+		filepath.Join(project, "app", ".crib-features"),
+	}
+
+	preview, err := e.Clean(ws, true)
+	if err != nil {
+		t.Fatalf("Clean dry run: %v", err)
+	}
+	if !slices.Equal(preview, want) {
+		t.Errorf("dry run = %v, want %v", preview, want)
+	}
+	for _, p := range want {
+		if _, err := os.Lstat(p); err != nil {
+			t.Errorf("dry run removed %s", p)
+		}
+	}
+
+	removed, err := e.Clean(ws, false)
+	if err != nil {
+		t.Fatalf("Clean: %v", err)
+	}
+	if !slices.Equal(removed, want) {
+		t.Errorf("removed = %v, want %v", removed, want)
+	}
+	for _, p := range want {
+		if _, err := os.Lstat(p); err == nil {
+			t.Errorf("%s was not removed", p)
+		}
+	}
+	for _, keep := range []string{
+		".crib-Dockerfile.bak",
+		".crib-notes",
+		"src/.crib-Dockerfile",
+		"app/main.go",
+		".crib-compose-override.yml",
+		".crib-features",
+		".devcontainer/devcontainer.json",
+		".devcontainer/Dockerfile",
+	} {
+		if _, err := os.Lstat(filepath.Join(project, keep)); err != nil {
+			t.Errorf("%s should be kept: %v", keep, err)
+		}
+	}
+}
+
+func TestClean_UnparsableConfig(t *testing.T) {
+	project := t.TempDir()
+	writeFiles(t, project, ".devcontainer/.crib-Dockerfile", ".crib-Dockerfile")
+	if err := os.WriteFile(filepath.Join(project, ".devcontainer", "devcontainer.json"), []byte(`{`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	e := &Engine{logger: slog.Default()}
+	ws := &workspace.Workspace{ID: "ws", Source: project, DevContainerPath: ".devcontainer/devcontainer.json"}
+
+	removed, err := e.Clean(ws, false)
+	if err != nil {
+		t.Fatalf("Clean: %v", err)
+	}
+	want := []string{
+		filepath.Join(project, ".crib-Dockerfile"),
+		filepath.Join(project, ".devcontainer", ".crib-Dockerfile"),
+	}
+	if !slices.Equal(removed, want) {
+		t.Errorf("removed = %v, want %v", removed, want)
+	}
+}
@@ -9,7 +9,9 @@ import (
 	"os"
 	"path/filepath"
 	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	composetypes "github.com/compose-spec/compose-go/v2/types"
 
@@ -25,6 +27,9 @@ import (
 // getuid returns the current user's UID. It is a variable so tests can override it.
 var getuid = os.Getuid
 
+// getgid returns the current user's GID. It is a variable so tests can override it.
+var getgid = os.Getgid
+
 // buildComposeFeatures resolves features, determines the base image, and builds
 // a feature image on top.
 func (e *Engine) buildComposeFeatures(ctx context.Context, ws *workspace.Workspace, cfg *config.DevContainerConfig, inv composeInvocation) (*buildResult, error) {
@@ -38,7 +43,7 @@ func (e *Engine) buildComposeFeatures(ctx context.Context, ws *workspace.Workspa
 	if svcInfo.HasBuild {
 		// Build-based service: run compose build first to produce the base image.
 		e.reportProgress(PhaseBuild, "Building service...")
-		if err := e.compose.Build(ctx, inv.projectName, inv.files, []string{serviceName}, e.stdout, e.stderr, inv.env); err != nil {
+		if err := e.compose.Build(ctx, inv.projectName, inv.files, []string{serviceName}, e.stdout, e.stderr, inv.env, e.pull); err != nil {
 			return nil, fmt.Errorf("building compose service: %w", err)
 		}
 		if svcInfo.Image != "" {
@@ -47,6 +52,8 @@ func (e *Engine) buildComposeFeatures(ctx context.Context, ws *workspace.Workspa
 		} else {
 			baseImage = e.compose.BuiltImageName(inv.projectName, serviceName)
 		}
+		// The tag now points at the fresh build.
+		delete(e.imageUsers, baseImage)
 	} else {
 		baseImage = svcInfo.Image
 	}
@@ -131,6 +138,7 @@ type featureOverrides struct {
 	Privileged  bool
 	Init        bool
 	CapAdd      []string
+	CapDrop     []string
 	SecurityOpt []string
 	Env         map[string]string
 	Mounts      []config.Mount
@@ -156,6 +164,7 @@ func collectFeatureOverrides(metadata []*config.ImageMetadata, subCtx *config.Su
 			ov.Init = true
 		}
 		ov.CapAdd = append(ov.CapAdd, m.CapAdd...)
+		ov.CapDrop = append(ov.CapDrop, m.CapDrop...)
 		ov.SecurityOpt = append(ov.SecurityOpt, m.SecurityOpt...)
 		for k, v := range m.ContainerEnv {
 			ov.Env[k] = sub(v)
@@ -176,9 +185,9 @@ func collectFeatureOverrides(metadata []*config.ImageMetadata, subCtx *config.Su
 func (e *Engine) generateComposeOverride(ws *workspace.Workspace, cfg *config.DevContainerConfig, workspaceFolder string, composeFiles []string, featureImage string, pluginResp *plugin.PreContainerRunResponse, featureMetadata ...*config.ImageMetadata) (string, error) {
 	serviceName := cfg.Service
 
-	labels := composetypes.Labels{
-		ocidriver.LabelWorkspace: ws.ID,
-	}
+	labels := composetypes.Labels{}
+	maps.Copy(labels, e.labels)
+	labels[ocidriver.LabelWorkspace] = ws.ID
 	if e.store.IsExplicitHome() {
 		labels[ocidriver.LabelHome] = e.store.BaseDir()
 	}
@@ -202,17 +211,15 @@ func (e *Engine) generateComposeOverride(ws *workspace.Workspace, cfg *config.De
 
 	// Override entrypoint/command to keep the container alive.
 	overrideCommand := cfg.OverrideCommand == nil || *cfg.OverrideCommand
-	sleepCmd := sleepScript
 	if overrideCommand {
-		if hasFeatureEntrypoints {
-			// Feature entrypoints are baked into the image. Don't
-			// override entrypoint; only set command as a full command
-			// so the feature entrypoint can exec into it.
-			svc.Command = composetypes.ShellCommand{"/bin/sh", "-c", sleepCmd}
-		} else {
-			svc.Entrypoint = composetypes.ShellCommand{"/bin/sh"}
-			svc.Command = composetypes.ShellCommand{"-c", sleepCmd}
+		entrypoint, cmd, err := keepAliveCommand(cfg, hasFeatureEntrypoints)
+		if err != nil {
+			return "", err
+		}
+		if entrypoint != "" {
+			svc.Entrypoint = composetypes.ShellCommand{entrypoint}
 		}
+		svc.Command = composetypes.ShellCommand(cmd)
 	}
 
 	// Collect feature capabilities, env, and mounts.
@@ -230,10 +237,15 @@ func (e *Engine) generateComposeOverride(ws *workspace.Workspace, cfg *config.De
 		svc.Init = &initTrue
 	}
 	svc.CapAdd = featOv.CapAdd
+	svc.CapDrop = append(featOv.CapDrop, e.capDrop...)
 	svc.SecurityOpt = featOv.SecurityOpt
 
+	dotEnv, err := loadDotEnv(ws, cfg)
+	if err != nil {
+		return "", err
+	}
 	globalWS := e.expandedGlobalWorkspace(ws, workspaceFolder)
-	svc.Environment = buildOverrideEnv(cfg, featOv, pluginResp, globalWS.Env)
+	svc.Environment = buildOverrideEnv(cfg, featOv, pluginResp, globalWS.Env, dotEnv)
 
 	// Load existing volume targets from the user's compose files so we
 	// don't produce duplicate mount destinations in the override. Compose
@@ -245,19 +257,93 @@ func (e *Engine) generateComposeOverride(ws *workspace.Workspace, cfg *config.De
 	if err != nil {
 		return "", err
 	}
-	svc.Volumes = buildOverrideVolumes(ws, cfg, workspaceFolder, featOv, pluginResp, existingTargets, globalMounts, e.logger)
+	svc.Volumes = buildOverrideVolumes(ws, cfg, workspaceFolder, featOv, pluginResp, existingTargets, e.cliMounts(), globalMounts, e.logger)
+	if e.socketGroup != "" {
+		svc.GroupAdd = append(svc.GroupAdd, e.socketGroup)
+	}
 
 	// Auto-inject userns_mode for rootless Podman.
-	isPodman := e.isRootlessPodman() && !composeFilesContainUserns(composeFiles)
+	isPodman := e.isRootlessPodman() && !composeFilesContain(composeFiles, "userns_mode")
 	if isPodman {
 		svc.UserNSMode = "keep-id"
 	}
 
+	// Disable SELinux labeling on enforcing hosts so the service can read
+	// the workspace bind mount, unless the compose files already set
+	// security options.
+	if e.isPodmanCompose() && ocidriver.SELinuxEnforcing() &&
+		!ocidriver.HasLabelSecurityOpt(svc.SecurityOpt) && !composeFilesContain(composeFiles, "security_opt") {
+		svc.SecurityOpt = append(svc.SecurityOpt, "label=disable")
+	}
+
+	// Extra /etc/hosts entries.
+	hosts, err := e.extraHosts(cfg, composeFilesContain(composeFiles, hostDockerInternal))
+	if err != nil {
+		return "", err
+	}
+	for _, h := range hosts {
+		name, ip, _ := splitExtraHost(h)
+		if svc.ExtraHosts == nil {
+			svc.ExtraHosts = composetypes.HostsList{}
+		}
+		svc.ExtraHosts[name] = append(svc.ExtraHosts[name], ip)
+	}
+
+	// Tmpfs mounts, in the same "path[:options]" form compose accepts.
+	tmpfs, err := e.tmpfsMounts(cfg)
+	if err != nil {
+		return "", err
+	}
+	svc.Tmpfs = tmpfs
+
+	// Join the primary service to an existing network.
+	network, err := e.networkName(cfg)
+	if err != nil {
+		return "", err
+	}
+	if network != "" {
+		svc.Networks, err = e.overrideServiceNetworks(composeFiles, serviceName, composeEnv, network)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	// GPU passthrough, as a device reservation on the primary service.
+	if gpus := e.gpuRequest(cfg); gpus != "" {
+		svc.Deploy = gpuDeployConfig(svc.Deploy, gpus)
+	}
+
+	// CPU and memory limits from hostRequirements, when enabled.
+	cpus, memory, err := e.resourceLimitValues(cfg)
+	if err != nil {
+		return "", err
+	}
+	svc.CPUS = float32(cpus)
+	svc.MemLimit = composetypes.UnitBytes(memory)
+	svc.CPUSet = e.cpuset
+
 	project := &composetypes.Project{
 		Services: composetypes.Services{serviceName: svc},
 	}
 
+	// Publish "service:port" forwardPorts entries on the named services.
+	knownServices := e.composeServiceNames(composeFiles, composeEnv)
+	for name, ports := range composeServicePorts(cfg.ForwardPorts) {
+		if knownServices != nil && !knownServices[name] {
+			e.logger.Warn("ignoring forwardPorts entry for unknown compose service", "service", name)
+			continue
+		}
+		target := project.Services[name]
+		target.Ports = append(target.Ports, ports...)
+		project.Services[name] = target
+	}
+
 	project.Volumes = collectNamedVolumes(svc.Volumes)
+	if network != "" {
+		project.Networks = composetypes.Networks{
+			network: composetypes.NetworkConfig{Name: network, External: true},
+		}
+	}
 
 	// Disable podman-compose pod creation (incompatible with --userns).
 	if isPodman {
@@ -280,16 +366,21 @@ func (e *Engine) generateComposeOverride(ws *workspace.Workspace, cfg *config.De
 	if err := os.WriteFile(overridePath, yamlBytes, 0o644); err != nil {
 		return "", fmt.Errorf("writing compose override: %w", err)
 	}
+	if e.keepGenerated {
+		e.reportProgress(PhaseCreate, "Compose override: "+overridePath)
+	}
 
 	return overridePath, nil
 }
 
 // buildOverrideEnv merges environment variables from config, features, and
 // plugins into a single MappingWithEquals for the compose override. Global
-// workspace env is applied first (lowest priority), then project-level
-// ContainerEnv, then feature env, with plugin env applied last (highest
-// priority) on key conflicts.
-func buildOverrideEnv(cfg *config.DevContainerConfig, featOv featureOverrides, pluginResp *plugin.PreContainerRunResponse, globalEnv map[string]string) composetypes.MappingWithEquals {
+// workspace env is applied first (lowest priority), then feature env, then
+// the project's .env file, then project-level ContainerEnv (so
+// devcontainer.json wins over features), with plugin env applied last
+// (highest priority) on key conflicts. The single-container path layers env
+// in the same order.
+func buildOverrideEnv(cfg *config.DevContainerConfig, featOv featureOverrides, pluginResp *plugin.PreContainerRunResponse, globalEnv, dotEnv map[string]string) composetypes.MappingWithEquals {
 	env := composetypes.MappingWithEquals{}
 	addAll := func(src map[string]string) {
 		for k, v := range src {
@@ -298,8 +389,9 @@ func buildOverrideEnv(cfg *config.DevContainerConfig, featOv featureOverrides, p
 		}
 	}
 	addAll(globalEnv)
-	addAll(cfg.ContainerEnv)
 	addAll(featOv.Env)
+	addAll(dotEnv)
+	addAll(cfg.ContainerEnv)
 	if pluginResp != nil {
 		addAll(pluginResp.Env)
 	}
@@ -328,11 +420,12 @@ func parseGlobalMounts(specs []string) ([]config.Mount, error) {
 }
 
 // buildOverrideVolumes assembles the service volume list from the workspace
-// bind mount, feature mounts, plugin mounts, and global workspace mounts.
+// bind mount, project mounts, ad-hoc --mount entries, global workspace
+// mounts, feature mounts, and plugin mounts.
 // existingTargets contains volume targets already defined in the user's
 // compose files; mounts for those targets are skipped to avoid "duplicate
 // mount destination" errors when compose merges the files.
-func buildOverrideVolumes(ws *workspace.Workspace, cfg *config.DevContainerConfig, workspaceFolder string, featOv featureOverrides, pluginResp *plugin.PreContainerRunResponse, existingTargets map[string]bool, globalMounts []config.Mount, logger *slog.Logger) []composetypes.ServiceVolumeConfig {
+func buildOverrideVolumes(ws *workspace.Workspace, cfg *config.DevContainerConfig, workspaceFolder string, featOv featureOverrides, pluginResp *plugin.PreContainerRunResponse, existingTargets map[string]bool, cliMounts, globalMounts []config.Mount, logger *slog.Logger) []composetypes.ServiceVolumeConfig {
 	seenTargets := make(map[string]bool, len(existingTargets))
 	maps.Copy(seenTargets, existingTargets)
 
@@ -347,10 +440,27 @@ func buildOverrideVolumes(ws *workspace.Workspace, cfg *config.DevContainerConfi
 		} else {
 			vols = append(vols, composetypes.ServiceVolumeConfig{
 				Type: "bind", Source: ws.Source, Target: workspaceFolder,
+				ReadOnly: readonlyWorkspace(cfg),
 			})
 			seenTargets[workspaceFolder] = true
 		}
 	}
+	for _, m := range resolveMountSources(cfg.Mounts, ws.Source) {
+		if seenTargets[m.Target] {
+			warnSkip("project", m.Source, m.Target)
+			continue
+		}
+		vols = append(vols, toComposeVolume(m))
+		seenTargets[m.Target] = true
+	}
+	for _, m := range cliMounts {
+		if seenTargets[m.Target] {
+			warnSkip("cli", m.Source, m.Target)
+			continue
+		}
+		vols = append(vols, toComposeVolume(m))
+		seenTargets[m.Target] = true
+	}
 	for _, m := range globalMounts {
 		if seenTargets[m.Target] {
 			warnSkip("global", m.Source, m.Target)
@@ -421,6 +531,75 @@ func (e *Engine) existingVolumeTargets(composeFiles []string, service string, ex
 	return targets
 }
 
+// overrideServiceNetworks returns the networks the override attaches the
+// primary service to: network, plus "default" when the compose files leave
+// the service on the project's default network, since listing any network
+// in the override would otherwise take it off that one.
+func (e *Engine) overrideServiceNetworks(composeFiles []string, service string, extraEnv []string, network string) (map[string]*composetypes.ServiceNetworkConfig, error) {
+	if network == "host" || network == "none" || strings.Contains(network, ":") {
+		return nil, fmt.Errorf("network %q cannot be joined by a compose service; set network_mode in the compose files instead", network)
+	}
+	networks := map[string]*composetypes.ServiceNetworkConfig{network: nil}
+	keepDefault := true
+	if len(composeFiles) > 0 {
+		project, err := composehelper.LoadProject(context.Background(), composeFiles, nil, extraEnv)
+		if err != nil {
+			e.logger.Debug("failed to load compose files for networks", "error", err)
+		} else if svc, err := project.GetService(service); err == nil {
+			if svc.NetworkMode != "" {
+				return nil, fmt.Errorf("service %s sets network_mode %q and cannot join network %q", service, svc.NetworkMode, network)
+			}
+			_, keepDefault = svc.Networks["default"]
+		}
+	}
+	if keepDefault {
+		networks["default"] = nil
+	}
+	return networks, nil
+}
+
+// composeServiceNames returns the names of the services defined in the
+// compose files, or nil when they cannot be loaded.
+func (e *Engine) composeServiceNames(composeFiles []string, extraEnv []string) map[string]bool {
+	if len(composeFiles) == 0 {
+		return nil
+	}
+	project, err := composehelper.LoadProject(context.Background(), composeFiles, nil, extraEnv)
+	if err != nil {
+		e.logger.Debug("failed to load compose files for service names", "error", err)
+		return nil
+	}
+	names := make(map[string]bool, len(project.Services))
+	for name := range project.Services {
+		names[name] = true
+	}
+	return names
+}
+
+// composeServicePorts groups "service:port" forwardPorts entries by service
+// and converts each into a publish of the same port on the host, which is
+// how the spec's "db:5432" form forwards a port of a non-primary service.
+// Other entries are ignored: compose workspaces publish ports through the
+// compose files.
+func composeServicePorts(forwardPorts config.StrIntArray) map[string][]composetypes.ServicePortConfig {
+	result := make(map[string][]composetypes.ServicePortConfig)
+	for _, p := range forwardPorts {
+		service, port, ok := splitServicePort(p)
+		if !ok {
+			continue
+		}
+		number, proto := splitPortProtocol(port)
+		target, _ := strconv.ParseUint(number, 10, 32)
+		result[service] = append(result[service], composetypes.ServicePortConfig{
+			Mode:      "ingress",
+			Target:    uint32(target),
+			Published: number,
+			Protocol:  proto,
+		})
+	}
+	return result
+}
+
 // toComposeVolume converts a crib config.Mount to a compose ServiceVolumeConfig.
 func toComposeVolume(m config.Mount) composetypes.ServiceVolumeConfig {
 	typ := m.Type
@@ -434,9 +613,19 @@ func toComposeVolume(m config.Mount) composetypes.ServiceVolumeConfig {
 
 // composeStop wraps compose.Stop, including the persisted compose override
 // (which carries x-podman: {in_pod: false} for rootless Podman).
-func (e *Engine) composeStop(ctx context.Context, inv composeInvocation, wsID string) error {
+func (e *Engine) composeStop(ctx context.Context, inv composeInvocation, wsID string, timeout *time.Duration) error {
+	files := e.composeFilesWithOverride(inv.files, wsID)
+	return e.compose.Stop(ctx, inv.projectName, files, e.composeStdout(), e.composeStderr(), inv.env, timeout)
+}
+
+// composePause wraps compose.Pause/Unpause, including the persisted compose
+// override.
+func (e *Engine) composePause(ctx context.Context, inv composeInvocation, wsID string, pause bool) error {
 	files := e.composeFilesWithOverride(inv.files, wsID)
-	return e.compose.Stop(ctx, inv.projectName, files, e.composeStdout(), e.composeStderr(), inv.env)
+	if pause {
+		return e.compose.Pause(ctx, inv.projectName, files, e.composeStdout(), e.composeStderr(), inv.env)
+	}
+	return e.compose.Unpause(ctx, inv.projectName, files, e.composeStdout(), e.composeStderr(), inv.env)
 }
 
 // composeDown wraps compose.Down, including the persisted compose override.
@@ -459,22 +648,24 @@ func (e *Engine) composeFilesWithOverride(files []string, wsID string) []string
 // isRootlessPodman returns true when the compose runtime is Podman and the
 // current process is not running as root.
 func (e *Engine) isRootlessPodman() bool {
-	if e.compose == nil {
-		return false
-	}
-	return strings.Contains(e.compose.RuntimeCommand(), "podman") && getuid() != 0
+	return e.isPodmanCompose() && getuid() != 0
+}
+
+// isPodmanCompose returns true when compose runs through Podman.
+func (e *Engine) isPodmanCompose() bool {
+	return e.compose != nil && strings.Contains(e.compose.RuntimeCommand(), "podman")
 }
 
-// composeFilesContainUserns checks whether any of the given compose files
-// already contain a userns_mode directive. This is a simple text search to
-// avoid pulling in a full YAML parser.
-func composeFilesContainUserns(files []string) bool {
+// composeFilesContain checks whether any of the given compose files contain
+// directive (e.g. "userns_mode"). This is a simple text search to avoid
+// pulling in a full YAML parser.
+func composeFilesContain(files []string, directive string) bool {
 	for _, f := range files {
 		data, err := os.ReadFile(f)
 		if err != nil {
 			continue
 		}
-		if strings.Contains(string(data), "userns_mode") {
+		if strings.Contains(string(data), directive) {
 			return true
 		}
 	}
@@ -578,3 +769,25 @@ func removeService(services []string, name string) []string {
 	}
 	return result
 }
+
+// gpuDeployConfig adds an NVIDIA GPU device reservation for gpus (docker
+// --gpus syntax) to deploy, which compose translates to the same device
+// request as docker run --gpus.
+func gpuDeployConfig(deploy *composetypes.DeployConfig, gpus string) *composetypes.DeployConfig {
+	if deploy == nil {
+		deploy = &composetypes.DeployConfig{}
+	}
+	req := composetypes.DeviceRequest{Driver: "nvidia", Capabilities: []string{"gpu"}}
+	if list, ok := strings.CutPrefix(gpus, "device="); ok {
+		req.IDs = strings.Split(list, ",")
+	} else if n, err := strconv.Atoi(gpus); err == nil {
+		req.Count = composetypes.DeviceCount(n)
+	} else {
+		req.Count = -1 // all
+	}
+	if deploy.Resources.Reservations == nil {
+		deploy.Resources.Reservations = &composetypes.Resource{}
+	}
+	deploy.Resources.Reservations.Devices = append(deploy.Resources.Reservations.Devices, req)
+	return deploy
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 	"testing"
@@ -219,12 +220,12 @@ func TestIntegrationComposeRestartWithStoppedDeps(t *testing.T) {
 	projectName := compose.ProjectName(ws.ID)
 	devcontainerDir2 := filepath.Dir(filepath.Join(ws.Source, ws.DevContainerPath))
 	composeFile := filepath.Join(devcontainerDir2, "compose.yml")
-	if err := e.compose.Stop(ctx, projectName, []string{composeFile}, os.Stdout, os.Stderr, nil); err != nil {
+	if err := e.compose.Stop(ctx, projectName, []string{composeFile}, os.Stdout, os.Stderr, nil, nil); err != nil {
 		t.Fatalf("compose stop: %v", err)
 	}
 
 	// Remove the post-start marker so we can verify it runs on restart.
-	_ = e.driver.ExecContainer(ctx, ws.ID, result.ContainerID, []string{"rm", "-f", "/tmp/post-start-ran"}, nil, nil, nil, nil, "")
+	_ = e.driver.ExecContainer(ctx, ws.ID, result.ContainerID, []string{"rm", "-f", "/tmp/post-start-ran"}, nil, nil, nil, nil, "", "")
 
 	// Restart should succeed even though all services are stopped.
 	restartResult, err := e.Restart(ctx, ws)
@@ -279,9 +280,55 @@ func TestIntegrationComposeDownClearsMarkers(t *testing.T) {
 	}
 }
 
+// TestIntegrationComposeShutdownStopComposeKeepsNetwork verifies that a
+// workspace configured with shutdownAction "stopCompose" is only stopped on
+// shutdown, so its compose network and hook markers survive.
+func TestIntegrationComposeShutdownStopComposeKeepsNetwork(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	ctx := context.Background()
+	e, d, store := newTestEngineWithCompose(t)
+
+	projectDir := t.TempDir()
+	wsID := "test-compose-stopcompose"
+	ws := writeComposeDevcontainer(t, projectDir, wsID)
+
+	configContent := `{
+		"dockerComposeFile": "compose.yml",
+		"service": "app",
+		"overrideCommand": true,
+		"shutdownAction": "stopCompose",
+		"onCreateCommand": "touch /tmp/on-create-ran"
+	}`
+	if err := os.WriteFile(filepath.Join(projectDir, ".devcontainer", "devcontainer.json"), []byte(configContent), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { cleanupCompose(t, e, d, ws) })
+	cleanupCompose(t, e, d, ws)
+
+	if _, err := e.Up(ctx, ws, UpOptions{}); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+
+	if err := e.Shutdown(ctx, ws); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+
+	network := compose.ProjectName(wsID) + "_default"
+	if out, err := exec.Command(d.Runtime().String(), "network", "inspect", network).CombinedOutput(); err != nil {
+		t.Errorf("network %s should survive a stopCompose shutdown: %v\n%s", network, err, out)
+	}
+	if !store.IsHookDone(wsID, "onCreateCommand") {
+		t.Error("onCreateCommand marker should be kept after a stopCompose shutdown")
+	}
+}
+
 // checkFileExists verifies a file exists in the container.
 func checkFileExists(ctx context.Context, e *Engine, wsID, containerID, path string) error {
-	return e.driver.ExecContainer(ctx, wsID, containerID, []string{"test", "-f", path}, nil, nil, nil, nil, "")
+	return e.driver.ExecContainer(ctx, wsID, containerID, []string{"test", "-f", path}, nil, nil, nil, nil, "", "")
 }
 
 // TestIntegrationComposeRecreateRebuildsImage verifies that Up with Recreate: true
@@ -380,7 +427,7 @@ func TestIntegrationComposeRecreateRebuildsImage(t *testing.T) {
 // checkFileContent verifies a file exists in the container and contains the expected string.
 func checkFileContent(ctx context.Context, e *Engine, wsID, containerID, path, expected string) error {
 	var buf bytes.Buffer
-	if err := e.driver.ExecContainer(ctx, wsID, containerID, []string{"cat", path}, nil, &buf, nil, nil, ""); err != nil {
+	if err := e.driver.ExecContainer(ctx, wsID, containerID, []string{"cat", path}, nil, &buf, nil, nil, "", ""); err != nil {
 		return fmt.Errorf("reading %s: %w", path, err)
 	}
 	got := strings.TrimSpace(buf.String())
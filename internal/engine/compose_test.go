@@ -1,14 +1,18 @@
 package engine
 
 import (
+	"context"
+	"fmt"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 
 	"github.com/fgrehm/crib/internal/compose"
 	"github.com/fgrehm/crib/internal/config"
+	ocidriver "github.com/fgrehm/crib/internal/driver/oci"
 	"github.com/fgrehm/crib/internal/feature"
 	"github.com/fgrehm/crib/internal/plugin"
 	"github.com/fgrehm/crib/internal/workspace"
@@ -110,6 +114,29 @@ func TestGenerateComposeOverride_DockerSkipsUserns(t *testing.T) {
 	}
 }
 
+func TestGenerateComposeOverride_ReadonlyWorkspace(t *testing.T) {
+	ws := &workspace.Workspace{ID: "test-ws", Source: "/tmp/project"}
+	e := newComposeTestEngine(t, "docker", ws)
+
+	cfg := &config.DevContainerConfig{}
+	cfg.Service = "app"
+	cfg.Customizations = map[string]any{"crib": map[string]any{"readonlyWorkspace": true}}
+
+	path, err := e.generateComposeOverride(ws, cfg, "/workspaces/project", nil, "", nil)
+	if err != nil {
+		t.Fatalf("generateComposeOverride failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading override: %v", err)
+	}
+
+	if !strings.Contains(string(data), "read_only: true") {
+		t.Errorf("expected a read-only workspace volume, got:\n%s", data)
+	}
+}
+
 func TestGenerateComposeOverride_SkipsUsernsWhenAlreadySet(t *testing.T) {
 	origGetuid := getuid
 	t.Cleanup(func() { getuid = origGetuid })
@@ -472,10 +499,14 @@ func TestGenerateComposeOverride_FeatureCapabilities(t *testing.T) {
 				Privileged:  &priv,
 				Init:        &init,
 				CapAdd:      []string{"SYS_PTRACE", "NET_ADMIN"},
+				CapDrop:     []string{"MKNOD"},
 				SecurityOpt: []string{"seccomp=unconfined"},
 			},
 		},
 	}
+	if err := e.SetCapDrop([]string{"NET_RAW"}); err != nil {
+		t.Fatal(err)
+	}
 
 	path, err := e.generateComposeOverride(ws, cfg, "/workspaces/project", nil, "", nil, metadata...)
 	if err != nil {
@@ -500,6 +531,9 @@ func TestGenerateComposeOverride_FeatureCapabilities(t *testing.T) {
 	if !strings.Contains(content, "NET_ADMIN") {
 		t.Errorf("expected NET_ADMIN in cap_add, got:\n%s", content)
 	}
+	if !strings.Contains(content, "cap_drop:\n") || !strings.Contains(content, "MKNOD") || !strings.Contains(content, "NET_RAW") {
+		t.Errorf("expected MKNOD and NET_RAW in cap_drop, got:\n%s", content)
+	}
 	if !strings.Contains(content, "seccomp=unconfined") {
 		t.Errorf("expected seccomp=unconfined in security_opt, got:\n%s", content)
 	}
@@ -566,6 +600,38 @@ func TestGenerateComposeOverride_NoFeatureEntrypointSetsEntrypoint(t *testing.T)
 	}
 }
 
+func TestGenerateComposeOverride_CustomKeepAlive(t *testing.T) {
+	ws := &workspace.Workspace{ID: "test-ws", Source: "/tmp/project"}
+	e := newComposeTestEngine(t, "docker", ws)
+
+	cfg := &config.DevContainerConfig{}
+	cfg.Service = "app"
+	cfg.Customizations = map[string]any{
+		"crib": map[string]any{"keepAlive": []any{"/busybox", "sh", "-c", "sleep infinity"}},
+	}
+
+	path, err := e.generateComposeOverride(ws, cfg, "/workspaces/project", nil, "", nil)
+	if err != nil {
+		t.Fatalf("generateComposeOverride failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading override: %v", err)
+	}
+	content := string(data)
+
+	if !strings.Contains(content, "entrypoint:\n      - /busybox\n") {
+		t.Errorf("expected /busybox entrypoint, got:\n%s", content)
+	}
+	if !strings.Contains(content, "command:\n      - sh\n      - -c\n      - sleep infinity\n") {
+		t.Errorf("expected keep-alive command, got:\n%s", content)
+	}
+	if strings.Contains(content, "/bin/sh") {
+		t.Errorf("default /bin/sh keep-alive should be replaced, got:\n%s", content)
+	}
+}
+
 func TestGenerateComposeOverride_FeatureMounts(t *testing.T) {
 	ws := &workspace.Workspace{ID: "test-ws", Source: "/tmp/project"}
 	e := newComposeTestEngine(t, "docker", ws)
@@ -685,6 +751,47 @@ func TestGenerateComposeOverride_FeatureEnvMergedWithConfigAndPlugin(t *testing.
 	}
 }
 
+func TestGenerateComposeOverride_ConfigEnvWinsOverMetadataEnv(t *testing.T) {
+	ws := &workspace.Workspace{ID: "test-ws", Source: "/tmp/project"}
+	e := newComposeTestEngine(t, "docker", ws)
+
+	cfg := &config.DevContainerConfig{}
+	cfg.Service = "app"
+	cfg.ContainerEnv = map[string]string{"APP_ENV": "development"}
+
+	pluginResp := &plugin.PreContainerRunResponse{
+		Env: map[string]string{"HISTFILE": "/plugin/history"},
+	}
+	metadata := []*config.ImageMetadata{
+		{
+			NonComposeBase: config.NonComposeBase{
+				ContainerEnv: map[string]string{
+					"APP_ENV":  "production",
+					"HISTFILE": "/image/history",
+					"LANG":     "C.UTF-8",
+				},
+			},
+		},
+	}
+
+	path, err := e.generateComposeOverride(ws, cfg, "/workspaces/project", nil, "", pluginResp, metadata...)
+	if err != nil {
+		t.Fatalf("generateComposeOverride failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading override: %v", err)
+	}
+	content := string(data)
+
+	for _, want := range []string{"APP_ENV: development", "HISTFILE: /plugin/history", "LANG: C.UTF-8"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("expected %q in environment, got:\n%s", want, content)
+		}
+	}
+}
+
 // Regression: feature containerEnv (e.g. PATH=/nvm/bin:${PATH}) is baked into
 // the image via Dockerfile ENV. featureToMetadata must exclude it so
 // collectFeatureOverrides doesn't include it in the compose environment section,
@@ -909,6 +1016,173 @@ func TestGenerateComposeOverride_GlobalWorkspaceEnv(t *testing.T) {
 	}
 }
 
+func TestGenerateComposeOverride_DotEnv(t *testing.T) {
+	src := t.TempDir()
+	if err := os.WriteFile(filepath.Join(src, ".env"), []byte("DOT_ONLY=dot-value\nCONFLICT=dotenv-loser\nFEATURE_VS_DOT=dotenv-wins\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ws := &workspace.Workspace{ID: "test-ws", Source: src}
+	e := newComposeTestEngine(t, "docker", ws)
+
+	cfg := &config.DevContainerConfig{}
+	cfg.Service = "app"
+	cfg.ContainerEnv = map[string]string{"CONFLICT": "project-wins"}
+	cfg.Customizations = map[string]any{"crib": map[string]any{"dotenv": true}}
+	feature := &config.ImageMetadata{NonComposeBase: config.NonComposeBase{
+		ContainerEnv: map[string]string{"FEATURE_VS_DOT": "feature-loser"},
+	}}
+
+	path, err := e.generateComposeOverride(ws, cfg, "/workspaces/project", nil, "", nil, feature)
+	if err != nil {
+		t.Fatalf("generateComposeOverride: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := string(data)
+
+	if !strings.Contains(content, "DOT_ONLY: dot-value") {
+		t.Errorf("expected DOT_ONLY from .env in override, got:\n%s", content)
+	}
+	if !strings.Contains(content, "CONFLICT: project-wins") {
+		t.Errorf("expected CONFLICT=project-wins (containerEnv should win), got:\n%s", content)
+	}
+	if !strings.Contains(content, "FEATURE_VS_DOT: dotenv-wins") {
+		t.Errorf("expected FEATURE_VS_DOT=dotenv-wins (.env should win over features), got:\n%s", content)
+	}
+}
+
+func TestNewComposeInvocation_DotEnv(t *testing.T) {
+	src := t.TempDir()
+	if err := os.WriteFile(filepath.Join(src, ".env"), []byte("DOT_ONLY=dot-value\nFROM_SHELL=dotenv-loser\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("FROM_SHELL", "shell")
+	ws := &workspace.Workspace{ID: "test-ws", Source: src}
+	cfg := &config.DevContainerConfig{}
+	cfg.Service = "app"
+
+	if inv := newComposeInvocation(ws, cfg, "/workspaces/project"); slices.Contains(inv.env, "DOT_ONLY=dot-value") {
+		t.Errorf(".env loaded without customizations.crib.dotenv: %v", inv.env)
+	}
+
+	cfg.Customizations = map[string]any{"crib": map[string]any{"dotenv": true}}
+	inv := newComposeInvocation(ws, cfg, "/workspaces/project")
+	if !slices.Contains(inv.env, "DOT_ONLY=dot-value") {
+		t.Errorf("expected DOT_ONLY in compose env, got %v", inv.env)
+	}
+	if slices.Contains(inv.env, "FROM_SHELL=dotenv-loser") {
+		t.Errorf(".env value should not override crib's environment: %v", inv.env)
+	}
+}
+
+func TestComposeProjectName(t *testing.T) {
+	ws := &workspace.Workspace{ID: "test-ws"}
+	tests := []struct {
+		name       string
+		configured any
+		env        string
+		want       string
+	}{
+		{"default", nil, "", "crib-test-ws"},
+		{"configured", "shared-stack", "", "shared-stack"},
+		{"normalized", "My Stack!", "", "mystack"},
+		{"nothing valid left", "!!!", "", "crib-test-ws"},
+		{"not a string", true, "", "crib-test-ws"},
+		{"COMPOSE_PROJECT_NAME wins", "shared-stack", "from-env", "from-env"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("COMPOSE_PROJECT_NAME", tt.env)
+			cfg := &config.DevContainerConfig{}
+			if tt.configured != nil {
+				cfg.Customizations = map[string]any{"crib": map[string]any{"composeProjectName": tt.configured}}
+			}
+			if got := composeProjectName(ws, cfg); got != tt.want {
+				t.Errorf("composeProjectName = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// fakeComposeRuntime writes a stand-in container runtime that appends each
+// invocation to a log file, answers "ps --format json" with a container for
+// the app service and lists c-app and c-db for "ps -q". It returns the
+// runtime path and the log path.
+func fakeComposeRuntime(t *testing.T) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	log := filepath.Join(dir, "calls.log")
+	script := fmt.Sprintf(`#!/bin/sh
+echo "$*" >> %q
+case "$*" in
+*" ps --format json") echo '[{"Id":"c-app","Labels":{"com.docker.compose.service":"app"}}]' ;;
+*" ps -q") printf 'c-app\nc-db\n' ;;
+esac
+`, log)
+	runtime := filepath.Join(dir, "runtime")
+	if err := os.WriteFile(runtime, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return runtime, log
+}
+
+func TestComposeProjectName_UsedByComposeCommands(t *testing.T) {
+	t.Setenv("COMPOSE_PROJECT_NAME", "")
+	e, _, ws := newPauseTestEngine(t, "running")
+	result := &workspace.Result{
+		MergedConfig: []byte(`{"dockerComposeFile":["docker-compose.yml"],"service":"app","customizations":{"crib":{"composeProjectName":"shared-stack"}}}`),
+	}
+	if err := e.store.SaveResult(ws.ID, result); err != nil {
+		t.Fatal(err)
+	}
+	runtime, log := fakeComposeRuntime(t)
+	e.compose = compose.NewHelperFromRuntime(runtime)
+	ctx := context.Background()
+
+	if err := e.Pause(ctx, ws); err != nil {
+		t.Fatalf("Pause: %v", err)
+	}
+	if err := e.Logs(ctx, ws, LogsOptions{}); err != nil {
+		t.Fatalf("Logs: %v", err)
+	}
+	if err := e.Stop(ctx, ws); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	if err := e.Down(ctx, ws); err != nil {
+		t.Fatalf("Down: %v", err)
+	}
+
+	// Container lookup falls back to compose ps when the labels are not
+	// visible to the driver.
+	e.driver = &fixedFindContainerDriver{}
+	cfg := storedComposeConfig(result)
+	container, err := e.findComposeContainer(ctx, ws.ID, newComposeInvocation(ws, cfg, ""), "test")
+	if err != nil {
+		t.Fatalf("findComposeContainer: %v", err)
+	}
+	if container.ID != "c-app" {
+		t.Errorf("container ID = %q, want c-app", container.ID)
+	}
+
+	data, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls := strings.Split(strings.TrimSpace(string(data)), "\n")
+	for _, sub := range []string{"pause", "logs", "stop", "down", "ps --format json"} {
+		if !slices.ContainsFunc(calls, func(c string) bool { return strings.HasSuffix(c, sub) || strings.Contains(c, " "+sub+" ") }) {
+			t.Errorf("no %q call recorded in %v", sub, calls)
+		}
+	}
+	for _, c := range calls {
+		if !strings.HasPrefix(c, "--project-name shared-stack ") {
+			t.Errorf("call %q does not use the configured project name", c)
+		}
+	}
+}
+
 func TestGenerateComposeOverride_GlobalWorkspaceMounts(t *testing.T) {
 	ws := &workspace.Workspace{ID: "test-ws", Source: "/tmp/project"}
 	e := newComposeTestEngine(t, "docker", ws)
@@ -933,6 +1207,192 @@ func TestGenerateComposeOverride_GlobalWorkspaceMounts(t *testing.T) {
 	}
 }
 
+func TestGenerateComposeOverride_CLIMounts(t *testing.T) {
+	ws := &workspace.Workspace{ID: "test-ws", Source: "/tmp/project"}
+	e := newComposeTestEngine(t, "docker", ws)
+	if err := e.SetExtraMounts([]string{
+		"type=bind,src=./data,dst=/data",
+		"type=volume,source=scratch,target=/scratch",
+	}, "/home/me/experiments"); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.DevContainerConfig{}
+	cfg.Service = "app"
+
+	path, err := e.generateComposeOverride(ws, cfg, "/workspaces/project", nil, "", nil)
+	if err != nil {
+		t.Fatalf("generateComposeOverride: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := string(data)
+	if !strings.Contains(content, "source: /home/me/experiments/data") || !strings.Contains(content, "target: /data") {
+		t.Errorf("expected --mount bind with source resolved against the current directory, got:\n%s", content)
+	}
+	if !strings.Contains(content, "source: scratch") || !strings.Contains(content, "target: /scratch") {
+		t.Errorf("expected --mount volume in override, got:\n%s", content)
+	}
+}
+
+func TestGenerateComposeOverride_LabelFiles(t *testing.T) {
+	ws := &workspace.Workspace{ID: "test-ws", Source: "/tmp/project"}
+	e := newComposeTestEngine(t, "docker", ws)
+	labelFile := filepath.Join(t.TempDir(), "labels")
+	if err := os.WriteFile(labelFile, []byte("org.opencontainers.image.revision=abc123\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.SetLabelFiles([]string{labelFile}); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.DevContainerConfig{}
+	cfg.Service = "app"
+
+	path, err := e.generateComposeOverride(ws, cfg, "/workspaces/project", nil, "", nil)
+	if err != nil {
+		t.Fatalf("generateComposeOverride: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := string(data)
+	for _, want := range []string{"org.opencontainers.image.revision: abc123", "crib.workspace: test-ws"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("expected %q in override, got:\n%s", want, content)
+		}
+	}
+}
+
+func TestGenerateComposeOverride_DockerSocket(t *testing.T) {
+	ws := &workspace.Workspace{ID: "test-ws", Source: "/tmp/project"}
+	e := newComposeTestEngine(t, "docker", ws)
+	e.dockerSocket = &config.Mount{Type: "bind", Source: "/run/docker.sock", Target: "/var/run/docker.sock"}
+	e.socketGroup = "998"
+
+	cfg := &config.DevContainerConfig{}
+	cfg.Service = "app"
+
+	path, err := e.generateComposeOverride(ws, cfg, "/workspaces/project", nil, "", nil)
+	if err != nil {
+		t.Fatalf("generateComposeOverride: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := string(data)
+	if !strings.Contains(content, "source: /run/docker.sock") || !strings.Contains(content, "target: /var/run/docker.sock") {
+		t.Errorf("expected docker socket mount in override, got:\n%s", content)
+	}
+	if !strings.Contains(content, "group_add:") || !strings.Contains(content, "\"998\"") {
+		t.Errorf("expected socket group in group_add, got:\n%s", content)
+	}
+}
+
+func TestGenerateComposeOverride_GPUs(t *testing.T) {
+	ws := &workspace.Workspace{ID: "test-ws", Source: "/tmp/project"}
+	e := newComposeTestEngine(t, "docker", ws)
+
+	cfg := &config.DevContainerConfig{}
+	cfg.Service = "app"
+	cfg.HostRequirements = &config.HostRequirements{GPU: true}
+
+	path, err := e.generateComposeOverride(ws, cfg, "/workspaces/project", nil, "", nil)
+	if err != nil {
+		t.Fatalf("generateComposeOverride: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := string(data)
+	for _, want := range []string{"driver: nvidia", "- gpu", "count: -1"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("expected %q in override, got:\n%s", want, content)
+		}
+	}
+}
+
+func TestGenerateComposeOverride_ResourceLimits(t *testing.T) {
+	ws := &workspace.Workspace{ID: "test-ws", Source: "/tmp/project"}
+	e := newComposeTestEngine(t, "docker", ws)
+	e.SetResourceLimits(true)
+
+	cfg := &config.DevContainerConfig{}
+	cfg.Service = "app"
+	cfg.HostRequirements = &config.HostRequirements{CPUs: 2, Memory: "512mb"}
+
+	path, err := e.generateComposeOverride(ws, cfg, "/workspaces/project", nil, "", nil)
+	if err != nil {
+		t.Fatalf("generateComposeOverride: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := string(data)
+	for _, want := range []string{"cpus: 2", "mem_limit: \"536870912\""} {
+		if !strings.Contains(content, want) {
+			t.Errorf("expected %q in override, got:\n%s", want, content)
+		}
+	}
+}
+
+func TestGenerateComposeOverride_CPUSet(t *testing.T) {
+	ws := &workspace.Workspace{ID: "test-ws", Source: "/tmp/project"}
+	e := newComposeTestEngine(t, "docker", ws)
+	if err := e.SetCPUSet("2-3"); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.DevContainerConfig{}
+	cfg.Service = "app"
+
+	path, err := e.generateComposeOverride(ws, cfg, "/workspaces/project", nil, "", nil)
+	if err != nil {
+		t.Fatalf("generateComposeOverride: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "cpuset: 2-3") {
+		t.Errorf("expected cpuset in override, got:\n%s", data)
+	}
+}
+
+func TestGenerateComposeOverride_ProjectMountsResolveRelativeSources(t *testing.T) {
+	ws := &workspace.Workspace{ID: "test-ws", Source: "/tmp/project"}
+	e := newComposeTestEngine(t, "docker", ws)
+
+	cfg := &config.DevContainerConfig{}
+	cfg.Service = "app"
+	cfg.Mounts = []config.Mount{
+		{Type: "bind", Source: "./cache", Target: "/cache"},
+		{Type: "volume", Source: "app-data", Target: "/data"},
+	}
+
+	path, err := e.generateComposeOverride(ws, cfg, "/workspaces/project", nil, "", nil)
+	if err != nil {
+		t.Fatalf("generateComposeOverride: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := string(data)
+	if !strings.Contains(content, "source: /tmp/project/cache") || !strings.Contains(content, "target: /cache") {
+		t.Errorf("expected relative bind source resolved under project, got:\n%s", content)
+	}
+	if !strings.Contains(content, "source: app-data") {
+		t.Errorf("expected named volume left untouched, got:\n%s", content)
+	}
+}
+
 func TestGenerateComposeOverride_GlobalMountInvalidFails(t *testing.T) {
 	ws := &workspace.Workspace{ID: "test-ws", Source: "/tmp/project"}
 	e := newComposeTestEngine(t, "docker", ws)
@@ -947,3 +1407,320 @@ func TestGenerateComposeOverride_GlobalMountInvalidFails(t *testing.T) {
 		t.Fatal("expected error for invalid mount, got nil")
 	}
 }
+
+func TestGenerateComposeOverride_SELinuxLabelDisable(t *testing.T) {
+	origEnforcing := ocidriver.SELinuxEnforcing
+	t.Cleanup(func() { ocidriver.SELinuxEnforcing = origEnforcing })
+
+	userCompose := filepath.Join(t.TempDir(), "docker-compose.yml")
+	if err := os.WriteFile(userCompose, []byte("services:\n  app:\n    security_opt:\n      - label=type:spc_t\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		runtime      string
+		enforcing    bool
+		composeFiles []string
+		want         bool
+	}{
+		{"podman on SELinux host", "podman", true, nil, true},
+		{"podman without SELinux", "podman", false, nil, false},
+		{"docker on SELinux host", "docker", true, nil, false},
+		{"compose files set security_opt", "podman", true, []string{userCompose}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ocidriver.SELinuxEnforcing = func() bool { return tt.enforcing }
+			ws := &workspace.Workspace{ID: "test-ws", Source: "/tmp/project"}
+			e := newComposeTestEngine(t, tt.runtime, ws)
+			e.logger = slog.Default()
+			cfg := &config.DevContainerConfig{}
+			cfg.Service = "app"
+
+			path, err := e.generateComposeOverride(ws, cfg, "/workspaces/project", tt.composeFiles, "", nil)
+			if err != nil {
+				t.Fatalf("generateComposeOverride failed: %v", err)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("reading override: %v", err)
+			}
+			if got := strings.Contains(string(data), "label=disable"); got != tt.want {
+				t.Errorf("label=disable present = %v, want %v:\n%s", got, tt.want, data)
+			}
+		})
+	}
+}
+
+func TestGenerateComposeOverride_ServiceForwardPorts(t *testing.T) {
+	userCompose := filepath.Join(t.TempDir(), "docker-compose.yml")
+	content := "services:\n  app:\n    image: alpine\n  db:\n    image: postgres\n"
+	if err := os.WriteFile(userCompose, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	ws := &workspace.Workspace{ID: "test-ws", Source: "/tmp/project"}
+	e := newComposeTestEngine(t, "docker", ws)
+	e.logger = slog.Default()
+	cfg := &config.DevContainerConfig{}
+	cfg.Service = "app"
+	cfg.ForwardPorts = config.StrIntArray{"db:5432", "3000", "app:8080", "cache:6379"}
+
+	path, err := e.generateComposeOverride(ws, cfg, "/workspaces/project", []string{userCompose}, "", nil)
+	if err != nil {
+		t.Fatalf("generateComposeOverride failed: %v", err)
+	}
+
+	project, err := compose.LoadProject(context.Background(), []string{userCompose, path}, nil, nil)
+	if err != nil {
+		t.Fatalf("loading merged project: %v", err)
+	}
+	publishes := func(service string) []string {
+		svc, err := project.GetService(service)
+		if err != nil {
+			t.Fatalf("GetService(%s): %v", service, err)
+		}
+		var specs []string
+		for _, p := range svc.Ports {
+			specs = append(specs, fmt.Sprintf("%s:%d/%s", p.Published, p.Target, p.Protocol))
+		}
+		return specs
+	}
+	if got := publishes("db"); !slices.Equal(got, []string{"5432:5432/tcp"}) {
+		t.Errorf("db ports = %v, want [5432:5432/tcp]", got)
+	}
+	// Bare ports are left to the compose files; "app:8080" names the
+	// primary service explicitly.
+	if got := publishes("app"); !slices.Equal(got, []string{"8080:8080/tcp"}) {
+		t.Errorf("app ports = %v, want [8080:8080/tcp]", got)
+	}
+	if _, err := project.GetService("cache"); err == nil {
+		t.Error("unknown service cache should not be added to the override")
+	}
+}
+
+func TestGenerateComposeOverride_ExtraHosts(t *testing.T) {
+	origOS := hostOS
+	t.Cleanup(func() { hostOS = origOS })
+	hostOS = "linux"
+
+	ws := &workspace.Workspace{ID: "test-ws", Source: "/tmp/project"}
+	e := newComposeTestEngine(t, "docker", ws)
+	e.runtimeName = "docker"
+	if err := e.SetExtraHosts([]string{"db.local:10.0.0.6"}); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.DevContainerConfig{}
+	cfg.Service = "app"
+	cfg.Customizations = map[string]any{"crib": map[string]any{
+		"extraHosts":         []any{"api.local:10.0.0.5"},
+		"hostDockerInternal": true,
+	}}
+
+	path, err := e.generateComposeOverride(ws, cfg, "/workspaces/project", nil, "", nil)
+	if err != nil {
+		t.Fatalf("generateComposeOverride failed: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading override: %v", err)
+	}
+
+	for _, want := range []string{
+		"extra_hosts:",
+		"- api.local=10.0.0.5",
+		"- db.local=10.0.0.6",
+		"- host.docker.internal=host-gateway",
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("override missing %q, got:\n%s", want, data)
+		}
+	}
+}
+
+func TestGenerateComposeOverride_Tmpfs(t *testing.T) {
+	ws := &workspace.Workspace{ID: "test-ws", Source: "/tmp/project"}
+	e := newComposeTestEngine(t, "docker", ws)
+	if err := e.SetTmpfs([]string{"/tmp:size=1g"}); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.DevContainerConfig{}
+	cfg.Service = "app"
+	cfg.Customizations = map[string]any{"crib": map[string]any{"tmpfs": []any{"/run"}}}
+
+	path, err := e.generateComposeOverride(ws, cfg, "/workspaces/project", nil, "", nil)
+	if err != nil {
+		t.Fatalf("generateComposeOverride failed: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading override: %v", err)
+	}
+
+	for _, want := range []string{"tmpfs:", "- /run", "- /tmp:size=1g"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("override missing %q, got:\n%s", want, data)
+		}
+	}
+}
+
+func TestGenerateComposeOverride_ExtraHostsRespectsComposeFiles(t *testing.T) {
+	origOS := hostOS
+	t.Cleanup(func() { hostOS = origOS })
+	hostOS = "linux"
+
+	dir := t.TempDir()
+	composeFile := filepath.Join(dir, "compose.yml")
+	content := "services:\n  app:\n    image: alpine\n    extra_hosts:\n      - host.docker.internal:172.17.0.1\n"
+	if err := os.WriteFile(composeFile, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	ws := &workspace.Workspace{ID: "test-ws", Source: dir}
+	e := newComposeTestEngine(t, "docker", ws)
+	e.runtimeName = "docker"
+	e.logger = slog.Default()
+
+	cfg := &config.DevContainerConfig{}
+	cfg.Service = "app"
+
+	path, err := e.generateComposeOverride(ws, cfg, "/workspaces/project", []string{composeFile}, "", nil)
+	if err != nil {
+		t.Fatalf("generateComposeOverride failed: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading override: %v", err)
+	}
+	if strings.Contains(string(data), "extra_hosts") {
+		t.Errorf("extra_hosts should not be injected when compose files map host.docker.internal, got:\n%s", data)
+	}
+}
+
+func TestGenerateComposeOverride_Network(t *testing.T) {
+	tests := []struct {
+		name        string
+		compose     string
+		wantDefault bool
+	}{
+		{"implicit default network", "services:\n  app:\n    image: alpine\n", true},
+		{"explicit networks", "services:\n  app:\n    image: alpine\n    networks: [backend]\nnetworks:\n  backend: {}\n", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			composeFile := filepath.Join(dir, "compose.yml")
+			if err := os.WriteFile(composeFile, []byte(tt.compose), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			ws := &workspace.Workspace{ID: "test-ws", Source: dir}
+			e := newComposeTestEngine(t, "docker", ws)
+			e.SetNetwork("shared")
+
+			cfg := &config.DevContainerConfig{}
+			cfg.Service = "app"
+
+			path, err := e.generateComposeOverride(ws, cfg, "/workspaces/project", []string{composeFile}, "", nil)
+			if err != nil {
+				t.Fatalf("generateComposeOverride failed: %v", err)
+			}
+			project, err := compose.LoadProject(context.Background(), []string{composeFile, path}, nil, nil)
+			if err != nil {
+				t.Fatalf("loading merged project: %v", err)
+			}
+			svc, err := project.GetService("app")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := svc.Networks["shared"]; !ok {
+				t.Errorf("service networks = %v, want shared", svc.Networks)
+			}
+			if _, ok := svc.Networks["default"]; ok != tt.wantDefault {
+				t.Errorf("service networks = %v, default present = %v, want %v", svc.Networks, ok, tt.wantDefault)
+			}
+			if !tt.wantDefault {
+				if _, ok := svc.Networks["backend"]; !ok {
+					t.Errorf("service networks = %v, want backend kept", svc.Networks)
+				}
+			}
+			if net := project.Networks["shared"]; !bool(net.External) || net.Name != "shared" {
+				t.Errorf("top-level network = %+v, want external named shared", net)
+			}
+		})
+	}
+}
+
+func TestGenerateComposeOverride_NetworkErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		compose string
+		network string
+	}{
+		{"host network", "services:\n  app:\n    image: alpine\n", "host"},
+		{"container network", "services:\n  app:\n    image: alpine\n", "container:other"},
+		{"service network_mode", "services:\n  app:\n    image: alpine\n    network_mode: host\n", "shared"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			composeFile := filepath.Join(dir, "compose.yml")
+			if err := os.WriteFile(composeFile, []byte(tt.compose), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			ws := &workspace.Workspace{ID: "test-ws", Source: dir}
+			e := newComposeTestEngine(t, "docker", ws)
+			e.SetNetwork(tt.network)
+
+			cfg := &config.DevContainerConfig{}
+			cfg.Service = "app"
+
+			if _, err := e.generateComposeOverride(ws, cfg, "/workspaces/project", []string{composeFile}, "", nil); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestParseAndSubstitute_ComposeExtension(t *testing.T) {
+	tests := []struct {
+		name            string
+		config          string
+		wantService     string
+		wantRunServices []string
+	}{
+		{"defaults from x-crib", `{"dockerComposeFile": "compose.yml"}`, "app", []string{"app", "db"}},
+		{"devcontainer.json wins", `{"dockerComposeFile": "compose.yml", "service": "db", "runServices": ["db"]}`, "db", []string{"db"}},
+		{"partial override", `{"dockerComposeFile": "compose.yml", "service": "db"}`, "db", []string{"app", "db"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := t.TempDir()
+			dir := filepath.Join(src, ".devcontainer")
+			if err := os.MkdirAll(dir, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			compose := "x-crib:\n  service: app\n  runServices: [app, db]\nservices:\n  app:\n    image: alpine\n  db:\n    image: postgres\n"
+			if err := os.WriteFile(filepath.Join(dir, "compose.yml"), []byte(compose), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(dir, "devcontainer.json"), []byte(tt.config), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			ws := &workspace.Workspace{ID: "ws1", Source: src, DevContainerPath: ".devcontainer/devcontainer.json"}
+			cfg, _, err := (&Engine{}).parseAndSubstitute(ws)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.Service != tt.wantService {
+				t.Errorf("service = %q, want %q", cfg.Service, tt.wantService)
+			}
+			if !slices.Equal(cfg.RunServices, tt.wantRunServices) {
+				t.Errorf("runServices = %#v, want %#v", cfg.RunServices, tt.wantRunServices)
+			}
+		})
+	}
+}
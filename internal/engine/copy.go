@@ -0,0 +1,113 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/fgrehm/crib/internal/archive"
+	"github.com/fgrehm/crib/internal/workspace"
+)
+
+// CopyOptions controls the behavior of CopyToContainer and CopyFromContainer.
+type CopyOptions struct {
+	// Compress gzips the tar stream. Disable for content that is already
+	// compressed (images, archives) where gzip only costs CPU.
+	Compress bool
+}
+
+// CopyToContainer copies src (a host file or directory) into the container
+// directory dst, so the result lands at dst/<basename of src>. The tree is
+// streamed as a tar archive over the exec's stdin, so the container needs a
+// tar binary (and gzip support when compressing). Files are written as the
+// workspace's remote user. Relative dst paths are resolved against the
+// workspace folder.
+func (e *Engine) CopyToContainer(ctx context.Context, ws *workspace.Workspace, src, dst string, opts CopyOptions) error {
+	container, err := e.RequireRunningContainer(ctx, ws)
+	if err != nil {
+		return err
+	}
+	user, dst := e.copyTarget(ws, dst)
+
+	pr, pw := io.Pipe()
+	packErr := make(chan error, 1)
+	go func() {
+		err := archive.Pack(pw, src, opts.Compress)
+		_ = pw.CloseWithError(err)
+		packErr <- err
+	}()
+
+	cmd := []string{"sh", "-c", `mkdir -p "$1" && tar -x` + tarCompressFlag(opts) + `f - -C "$1"`, "sh", dst}
+	var stderr bytes.Buffer
+	execErr := e.driver.ExecContainer(ctx, ws.ID, container.ID, cmd, pr, io.Discard, &stderr, nil, user, "")
+	_ = pr.Close()
+
+	// A failed exec closes the pipe under Pack, so its io.ErrClosedPipe is
+	// only a symptom; report the exec's own error instead.
+	if err := <-packErr; err != nil && (execErr == nil || !errors.Is(err, io.ErrClosedPipe)) {
+		return fmt.Errorf("archiving %s: %w", src, err)
+	}
+	if execErr != nil {
+		return fmt.Errorf("extracting into container %s: %w: %s", dst, execErr, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// CopyFromContainer copies src (a file or directory inside the container)
+// into the host directory dst, so the result lands at dst/<basename of src>.
+// Modes and symlinks are preserved. Relative src paths are resolved against
+// the workspace folder.
+func (e *Engine) CopyFromContainer(ctx context.Context, ws *workspace.Workspace, src, dst string, opts CopyOptions) error {
+	container, err := e.RequireRunningContainer(ctx, ws)
+	if err != nil {
+		return err
+	}
+	user, src := e.copyTarget(ws, src)
+
+	cmd := []string{"tar", "-c" + tarCompressFlag(opts) + "f", "-", "-C", path.Dir(src), path.Base(src)}
+	pr, pw := io.Pipe()
+	var stderr bytes.Buffer
+	execErr := make(chan error, 1)
+	go func() {
+		err := e.driver.ExecContainer(ctx, ws.ID, container.ID, cmd, nil, pw, &stderr, nil, user, "")
+		_ = pw.CloseWithError(err)
+		execErr <- err
+	}()
+
+	unpackErr := archive.Unpack(pr, dst, opts.Compress)
+	// Unblock the exec if extraction stopped early.
+	_ = pr.CloseWithError(io.ErrClosedPipe)
+
+	if err := <-execErr; err != nil {
+		return fmt.Errorf("archiving %s in container: %w: %s", src, err, strings.TrimSpace(stderr.String()))
+	}
+	if unpackErr != nil {
+		return fmt.Errorf("extracting into %s: %w", dst, unpackErr)
+	}
+	return nil
+}
+
+// copyTarget returns the user to copy as and p resolved against the stored
+// workspace folder when relative.
+func (e *Engine) copyTarget(ws *workspace.Workspace, p string) (string, string) {
+	result, _ := e.store.LoadResult(ws.ID)
+	if result == nil {
+		return "", p
+	}
+	if !path.IsAbs(p) && result.WorkspaceFolder != "" {
+		p = path.Join(result.WorkspaceFolder, p)
+	}
+	return result.RemoteUser, p
+}
+
+// tarCompressFlag returns the tar flag letter for gzip compression.
+func tarCompressFlag(opts CopyOptions) string {
+	if opts.Compress {
+		return "z"
+	}
+	return ""
+}
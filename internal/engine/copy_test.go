@@ -0,0 +1,150 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/fgrehm/crib/internal/archive"
+	"github.com/fgrehm/crib/internal/driver"
+	"github.com/fgrehm/crib/internal/workspace"
+)
+
+// tarExecDriver records the stdin stream and user of each exec and writes a
+// canned archive to stdout, standing in for tar inside the container. With
+// err set, the exec fails right away with stderr, without reading stdin.
+type tarExecDriver struct {
+	fixedFindContainerDriver
+	stdout []byte
+	stdin  bytes.Buffer
+	cmd    []string
+	user   string
+	err    error
+	stderr string
+}
+
+func (d *tarExecDriver) ExecContainer(_ context.Context, _, _ string, cmd []string, stdin io.Reader, stdout, stderr io.Writer, _ []string, user, _ string) error {
+	d.cmd, d.user = cmd, user
+	if d.err != nil {
+		_, _ = io.WriteString(stderr, d.stderr)
+		return d.err
+	}
+	if stdin != nil {
+		if _, err := io.Copy(&d.stdin, stdin); err != nil {
+			return err
+		}
+	}
+	if stdout != nil {
+		_, err := stdout.Write(d.stdout)
+		return err
+	}
+	return nil
+}
+
+func newCopyTestEngine(t *testing.T, drv *tarExecDriver) (*Engine, *workspace.Workspace) {
+	t.Helper()
+	store := workspace.NewStoreAt(t.TempDir())
+	ws := &workspace.Workspace{ID: "ws-1", Source: t.TempDir()}
+	if err := store.Save(ws); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SaveResult(ws.ID, &workspace.Result{RemoteUser: "vscode", WorkspaceFolder: "/workspaces/proj"}); err != nil {
+		t.Fatal(err)
+	}
+	drv.container = &driver.ContainerDetails{ID: "abc123", State: driver.ContainerState{Status: "running"}}
+	return &Engine{driver: drv, store: store, logger: slog.Default()}, ws
+}
+
+func writeCopyTree(t *testing.T) string {
+	t.Helper()
+	src := filepath.Join(t.TempDir(), "data")
+	if err := os.MkdirAll(filepath.Join(src, "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "sub", "run.sh"), []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("sub/run.sh", filepath.Join(src, "run")); err != nil {
+		t.Fatal(err)
+	}
+	return src
+}
+
+func TestCopyToContainer_StreamsCompressedTar(t *testing.T) {
+	drv := &tarExecDriver{}
+	e, ws := newCopyTestEngine(t, drv)
+	src := writeCopyTree(t)
+
+	if err := e.CopyToContainer(context.Background(), ws, src, "fixtures", CopyOptions{Compress: true}); err != nil {
+		t.Fatalf("CopyToContainer: %v", err)
+	}
+
+	if drv.user != "vscode" {
+		t.Errorf("user = %q, want vscode", drv.user)
+	}
+	if got := drv.cmd[len(drv.cmd)-1]; got != "/workspaces/proj/fixtures" {
+		t.Errorf("destination = %q, want relative path resolved against workspace folder", got)
+	}
+	if !slices.ContainsFunc(drv.cmd, func(s string) bool { return strings.Contains(s, "tar -xzf") }) {
+		t.Errorf("expected gzip extraction, got cmd %v", drv.cmd)
+	}
+
+	// The stream the container received must unpack to the original tree.
+	out := t.TempDir()
+	if err := archive.Unpack(&drv.stdin, out, true); err != nil {
+		t.Fatalf("unpacking streamed archive: %v", err)
+	}
+	info, err := os.Stat(filepath.Join(out, "data", "sub", "run.sh"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o755 {
+		t.Errorf("run.sh mode = %o, want 755", info.Mode().Perm())
+	}
+	if link, err := os.Readlink(filepath.Join(out, "data", "run")); err != nil || link != "sub/run.sh" {
+		t.Errorf("symlink = %q, %v", link, err)
+	}
+}
+
+func TestCopyToContainer_ExecFailure(t *testing.T) {
+	drv := &tarExecDriver{err: errors.New("exit status 2"), stderr: "tar: not found\n"}
+	e, ws := newCopyTestEngine(t, drv)
+	src := writeCopyTree(t)
+
+	err := e.CopyToContainer(context.Background(), ws, src, "fixtures", CopyOptions{})
+	if err == nil {
+		t.Fatal("expected an error when the exec fails")
+	}
+	if !strings.Contains(err.Error(), "tar: not found") || strings.Contains(err.Error(), "closed pipe") {
+		t.Errorf("error = %q, want the exec's stderr rather than the closed pipe", err)
+	}
+}
+
+func TestCopyFromContainer_NoCompress(t *testing.T) {
+	var packed bytes.Buffer
+	if err := archive.Pack(&packed, writeCopyTree(t), false); err != nil {
+		t.Fatal(err)
+	}
+	drv := &tarExecDriver{stdout: packed.Bytes()}
+	e, ws := newCopyTestEngine(t, drv)
+	dst := t.TempDir()
+
+	if err := e.CopyFromContainer(context.Background(), ws, "/tmp/data", dst, CopyOptions{}); err != nil {
+		t.Fatalf("CopyFromContainer: %v", err)
+	}
+
+	wantCmd := []string{"tar", "-cf", "-", "-C", "/tmp", "data"}
+	if !slices.Equal(drv.cmd, wantCmd) {
+		t.Errorf("cmd = %v, want %v", drv.cmd, wantCmd)
+	}
+	if _, err := os.Stat(filepath.Join(dst, "data", "sub", "run.sh")); err != nil {
+		t.Errorf("expected extracted file: %v", err)
+	}
+}
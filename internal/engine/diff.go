@@ -0,0 +1,129 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/fgrehm/crib/internal/config"
+	"github.com/fgrehm/crib/internal/workspace"
+)
+
+// ChangeImpact describes what a config change means for an existing container.
+type ChangeImpact string
+
+const (
+	// ImpactNone means the change takes effect without touching the container.
+	ImpactNone ChangeImpact = "no-op"
+	// ImpactRecreate means `crib restart` recreates the container.
+	ImpactRecreate ChangeImpact = "safe"
+	// ImpactRebuild means the image must be rebuilt with `crib rebuild`.
+	ImpactRebuild ChangeImpact = "needs rebuild"
+)
+
+// impactOf maps the internal change classification to a ChangeImpact.
+func impactOf(kind configChangeKind) ChangeImpact {
+	switch kind {
+	case changeNeedsRebuild:
+		return ImpactRebuild
+	case changeSafe:
+		return ImpactRecreate
+	default:
+		return ImpactNone
+	}
+}
+
+// FieldDiff describes one devcontainer.json property that changed since the
+// last `crib up`. Stored and Current are JSON renderings of the values
+// ("" when unset).
+type FieldDiff struct {
+	Field   string
+	Impact  ChangeImpact
+	Stored  string
+	Current string
+}
+
+// DiffResult holds the outcome of a Diff operation.
+type DiffResult struct {
+	// Changes lists changed properties, image-affecting ones first.
+	Changes []FieldDiff
+
+	// Impact is the most severe impact across all changes. It matches the
+	// decision `crib restart` would make.
+	Impact ChangeImpact
+}
+
+// Diff compares the config stored by the last `crib up` with the current
+// devcontainer.json and reports per-field changes. Uses the same comparison
+// as Restart, so the overall impact predicts what `crib restart` will do.
+func (e *Engine) Diff(_ context.Context, ws *workspace.Workspace) (*DiffResult, error) {
+	storedResult, err := e.store.LoadResult(ws.ID)
+	if err != nil {
+		return nil, fmt.Errorf("loading workspace result: %w", err)
+	}
+	if storedResult == nil {
+		return nil, fmt.Errorf("no previous result found for workspace %s (run 'crib up' first)", ws.ID)
+	}
+
+	var storedCfg config.DevContainerConfig
+	if err := json.Unmarshal(storedResult.MergedConfig, &storedCfg); err != nil {
+		return nil, fmt.Errorf("unmarshaling stored config: %w", err)
+	}
+
+	cfg, _, err := e.parseAndSubstitute(ws)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := diffConfig(&storedCfg, cfg)
+
+	// Compose file contents are not part of devcontainer.json; mirror the
+	// hash check Restart performs.
+	if len(cfg.DockerComposeFile) > 0 && storedResult.ComposeFilesHash != "" {
+		files := resolveComposeFiles(configDir(ws), cfg.DockerComposeFile)
+		if current := computeComposeFilesHash(files); current != storedResult.ComposeFilesHash {
+			changes = append(changes, fieldChange{
+				field:   "dockerComposeFile (contents)",
+				kind:    changeSafe,
+				stored:  storedResult.ComposeFilesHash,
+				current: current,
+			})
+		}
+	}
+
+	return newDiffResult(changes), nil
+}
+
+// newDiffResult converts internal field changes into a DiffResult.
+func newDiffResult(changes []fieldChange) *DiffResult {
+	res := &DiffResult{Impact: ImpactNone}
+	worst := changeNone
+	for _, c := range changes {
+		res.Changes = append(res.Changes, FieldDiff{
+			Field:   c.field,
+			Impact:  impactOf(c.kind),
+			Stored:  renderDiffValue(c.stored),
+			Current: renderDiffValue(c.current),
+		})
+		worst = max(worst, c.kind)
+	}
+	res.Impact = impactOf(worst)
+	return res
+}
+
+// renderDiffValue formats a config value for display. Strings are shown
+// verbatim; everything else is compact JSON. Empty values render as "".
+func renderDiffValue(v any) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	switch string(data) {
+	case "null", "{}", "[]", `""`:
+		return ""
+	}
+	return string(data)
+}
@@ -0,0 +1,78 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/fgrehm/crib/internal/config"
+)
+
+func TestDiffConfig_ImageAndEnvChanges(t *testing.T) {
+	stored := &config.DevContainerConfig{}
+	stored.Image = "node:18"
+	stored.ContainerEnv = map[string]string{"FOO": "bar"}
+	stored.Name = "old"
+
+	current := &config.DevContainerConfig{}
+	current.Image = "node:20"
+	current.ContainerEnv = map[string]string{"FOO": "baz"}
+	current.Name = "new"
+
+	res := newDiffResult(diffConfig(stored, current))
+
+	want := []FieldDiff{
+		{Field: "image", Impact: ImpactRebuild, Stored: "node:18", Current: "node:20"},
+		{Field: "containerEnv", Impact: ImpactRecreate, Stored: `{"FOO":"bar"}`, Current: `{"FOO":"baz"}`},
+		{Field: "name", Impact: ImpactNone, Stored: "old", Current: "new"},
+	}
+	if len(res.Changes) != len(want) {
+		t.Fatalf("got %d changes, want %d: %+v", len(res.Changes), len(want), res.Changes)
+	}
+	for i := range want {
+		if res.Changes[i] != want[i] {
+			t.Errorf("Changes[%d] = %+v, want %+v", i, res.Changes[i], want[i])
+		}
+	}
+	if res.Impact != ImpactRebuild {
+		t.Errorf("Impact = %q, want %q", res.Impact, ImpactRebuild)
+	}
+}
+
+func TestDiffConfig_SafeOnly(t *testing.T) {
+	stored := &config.DevContainerConfig{}
+	current := &config.DevContainerConfig{}
+	current.ForwardPorts = []string{"3000"}
+
+	res := newDiffResult(diffConfig(stored, current))
+	if len(res.Changes) != 1 || res.Changes[0].Field != "forwardPorts" {
+		t.Fatalf("unexpected changes: %+v", res.Changes)
+	}
+	if res.Changes[0].Stored != "" || res.Changes[0].Current != `["3000"]` {
+		t.Errorf("unexpected rendering: %+v", res.Changes[0])
+	}
+	if res.Impact != ImpactRecreate {
+		t.Errorf("Impact = %q, want %q", res.Impact, ImpactRecreate)
+	}
+}
+
+func TestDiffConfig_NoChanges(t *testing.T) {
+	cfg := &config.DevContainerConfig{}
+	cfg.Image = "alpine"
+	res := newDiffResult(diffConfig(cfg, cfg))
+	if len(res.Changes) != 0 || res.Impact != ImpactNone {
+		t.Errorf("expected no changes, got %+v", res)
+	}
+}
+
+func TestDiffConfig_AgreesWithDetectConfigChange(t *testing.T) {
+	stored := &config.DevContainerConfig{}
+	current := &config.DevContainerConfig{}
+	current.PostStartCommand = config.LifecycleHook{"": {"echo hi"}}
+
+	if got := detectConfigChange(stored, current); got != changeNone {
+		t.Errorf("detectConfigChange = %v, want changeNone for hook-only change", got)
+	}
+	res := newDiffResult(diffConfig(stored, current))
+	if len(res.Changes) != 1 || res.Impact != ImpactNone {
+		t.Errorf("expected one no-op change, got %+v", res)
+	}
+}
@@ -0,0 +1,93 @@
+package engine
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/fgrehm/crib/internal/config"
+)
+
+// dockerSocketTarget is where --mount-docker-socket binds the host's
+// runtime socket. Podman's socket speaks the Docker API, so the docker CLI
+// and SDKs inside the container find either at the default path.
+const dockerSocketTarget = "/var/run/docker.sock"
+
+// SetMountDockerSocket binds the container runtime's API socket into
+// containers created by Up, Rebuild and Restart, for docker-in-docker style
+// workflows. The socket is looked up for the runtime set with SetRuntime.
+// When it is owned by a non-root group the container joins that group so
+// a non-root remote user can reach it. The mount gives the container
+// control over the host, so it goes through the trust prompt like a socket
+// mount from the config. It returns an error if the socket does not exist.
+func (e *Engine) SetMountDockerSocket(enabled bool) error {
+	e.dockerSocket = nil
+	e.socketGroup = ""
+	if !enabled {
+		return nil
+	}
+	path := runtimeSocketPath(e.runtimeName, os.Getenv, getuid())
+	fi, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("--mount-docker-socket: %s socket not found: %w", e.runtimeName, err)
+	}
+	if fi.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("--mount-docker-socket: %s is not a socket", path)
+	}
+	e.dockerSocket = &config.Mount{Type: "bind", Source: path, Target: dockerSocketTarget}
+	// Rootless podman owns its socket and maps the user into the container,
+	// so a host group would not mean anything there.
+	if gid, ok := fileGID(fi); ok && gid != 0 && !(e.runtimeName == "podman" && getuid() != 0) {
+		e.socketGroup = strconv.FormatUint(uint64(gid), 10)
+	}
+	return nil
+}
+
+// runtimeSocketPath returns the host path of the API socket for runtime.
+// DOCKER_HOST (docker) and CONTAINER_HOST (podman) win when they point at a
+// unix socket; otherwise the runtime's default location is used, which for
+// rootless podman lives under XDG_RUNTIME_DIR.
+func runtimeSocketPath(runtime string, getenv func(string) string, uid int) string {
+	if runtime == "podman" {
+		if p, ok := strings.CutPrefix(getenv("CONTAINER_HOST"), "unix://"); ok && p != "" {
+			return p
+		}
+		if uid == 0 {
+			return "/run/podman/podman.sock"
+		}
+		dir := getenv("XDG_RUNTIME_DIR")
+		if dir == "" {
+			dir = filepath.Join("/run/user", strconv.Itoa(uid))
+		}
+		return filepath.Join(dir, "podman", "podman.sock")
+	}
+	if p, ok := strings.CutPrefix(getenv("DOCKER_HOST"), "unix://"); ok && p != "" {
+		return p
+	}
+	return "/var/run/docker.sock"
+}
+
+// remoteRuntimeHost returns the DOCKER_HOST (docker) or CONTAINER_HOST
+// (podman) value when it points the runtime at another machine (tcp://,
+// ssh://, ...) rather than a local unix socket, and "" otherwise.
+func remoteRuntimeHost(runtime string, getenv func(string) string) string {
+	host := getenv("DOCKER_HOST")
+	if runtime == "podman" {
+		host = getenv("CONTAINER_HOST")
+	}
+	if host == "" || strings.HasPrefix(host, "unix://") {
+		return ""
+	}
+	return host
+}
+
+// cliMounts returns the mounts requested on the command line: --mount
+// entries followed by the runtime socket from --mount-docker-socket.
+func (e *Engine) cliMounts() []config.Mount {
+	if e.dockerSocket == nil {
+		return e.extraMounts
+	}
+	return append(append([]config.Mount(nil), e.extraMounts...), *e.dockerSocket)
+}
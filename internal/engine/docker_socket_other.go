@@ -0,0 +1,11 @@
+//go:build !unix
+
+package engine
+
+import "os"
+
+// fileGID reports no owning group: there are no unix groups to join on
+// this platform.
+func fileGID(os.FileInfo) (uint32, bool) {
+	return 0, false
+}
@@ -0,0 +1,226 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/fgrehm/crib/internal/config"
+	"github.com/fgrehm/crib/internal/workspace"
+)
+
+func TestRuntimeSocketPath(t *testing.T) {
+	tests := []struct {
+		name    string
+		runtime string
+		env     map[string]string
+		uid     int
+		want    string
+	}{
+		{"docker default", "docker", nil, 1000, "/var/run/docker.sock"},
+		{"docker host", "docker", map[string]string{"DOCKER_HOST": "unix:///home/me/.docker/run/docker.sock"}, 1000, "/home/me/.docker/run/docker.sock"},
+		{"docker tcp host", "docker", map[string]string{"DOCKER_HOST": "tcp://10.0.0.1:2375"}, 1000, "/var/run/docker.sock"},
+		{"podman rootful", "podman", nil, 0, "/run/podman/podman.sock"},
+		{"podman rootless", "podman", map[string]string{"XDG_RUNTIME_DIR": "/run/user/1000"}, 1000, "/run/user/1000/podman/podman.sock"},
+		{"podman rootless without XDG_RUNTIME_DIR", "podman", nil, 1001, "/run/user/1001/podman/podman.sock"},
+		{"podman container host", "podman", map[string]string{"CONTAINER_HOST": "unix:///tmp/podman.sock"}, 1000, "/tmp/podman.sock"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			getenv := func(k string) string { return tt.env[k] }
+			if got := runtimeSocketPath(tt.runtime, getenv, tt.uid); got != tt.want {
+				t.Errorf("runtimeSocketPath = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRemoteRuntimeHost(t *testing.T) {
+	tests := []struct {
+		name    string
+		runtime string
+		env     map[string]string
+		want    string
+	}{
+		{"docker unset", "docker", nil, ""},
+		{"docker unix", "docker", map[string]string{"DOCKER_HOST": "unix:///var/run/docker.sock"}, ""},
+		{"docker tcp", "docker", map[string]string{"DOCKER_HOST": "tcp://10.0.0.1:2375"}, "tcp://10.0.0.1:2375"},
+		{"docker ssh", "docker", map[string]string{"DOCKER_HOST": "ssh://me@builder"}, "ssh://me@builder"},
+		{"podman ignores DOCKER_HOST", "podman", map[string]string{"DOCKER_HOST": "tcp://10.0.0.1:2375"}, ""},
+		{"podman ssh", "podman", map[string]string{"CONTAINER_HOST": "ssh://me@builder/run/podman/podman.sock"}, "ssh://me@builder/run/podman/podman.sock"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			getenv := func(k string) string { return tt.env[k] }
+			if got := remoteRuntimeHost(tt.runtime, getenv); got != tt.want {
+				t.Errorf("remoteRuntimeHost = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// listenUnix creates a unix socket in a temp dir and returns its path.
+func listenUnix(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "docker.sock")
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Skipf("cannot create unix socket: %v", err)
+	}
+	t.Cleanup(func() { l.Close() })
+	return path
+}
+
+func TestSetMountDockerSocket(t *testing.T) {
+	sock := listenUnix(t)
+	t.Setenv("DOCKER_HOST", "unix://"+sock)
+
+	e := &Engine{}
+	e.SetRuntime("docker")
+	if err := e.SetExtraMounts([]string{"type=volume,src=cache,dst=/cache"}, "/"); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.SetMountDockerSocket(true); err != nil {
+		t.Fatalf("SetMountDockerSocket: %v", err)
+	}
+	want := []config.Mount{
+		{Type: "volume", Source: "cache", Target: "/cache"},
+		{Type: "bind", Source: sock, Target: "/var/run/docker.sock"},
+	}
+	if got := e.cliMounts(); !slices.Equal(got, want) {
+		t.Errorf("cliMounts = %v, want %v", got, want)
+	}
+
+	if err := e.SetMountDockerSocket(false); err != nil {
+		t.Fatal(err)
+	}
+	if e.dockerSocket != nil || len(e.cliMounts()) != 1 {
+		t.Errorf("disabling should drop the socket mount, got %v", e.cliMounts())
+	}
+}
+
+func TestSetMountDockerSocket_Group(t *testing.T) {
+	sock := listenUnix(t)
+	if err := os.Chown(sock, -1, 4242); err != nil {
+		t.Skipf("cannot change socket group: %v", err)
+	}
+	t.Setenv("DOCKER_HOST", "unix://"+sock)
+
+	e := &Engine{}
+	e.SetRuntime("docker")
+	if err := e.SetMountDockerSocket(true); err != nil {
+		t.Fatalf("SetMountDockerSocket: %v", err)
+	}
+	if e.socketGroup != "4242" {
+		t.Errorf("socketGroup = %q, want 4242", e.socketGroup)
+	}
+}
+
+func TestSetMountDockerSocket_Missing(t *testing.T) {
+	dir := t.TempDir()
+	e := &Engine{}
+	e.SetRuntime("docker")
+
+	t.Setenv("DOCKER_HOST", "unix://"+filepath.Join(dir, "missing.sock"))
+	if err := e.SetMountDockerSocket(true); err == nil {
+		t.Error("expected error for a missing socket")
+	}
+
+	regular := filepath.Join(dir, "docker.sock")
+	if err := os.WriteFile(regular, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("DOCKER_HOST", "unix://"+regular)
+	if err := e.SetMountDockerSocket(true); err == nil {
+		t.Error("expected error for a path that is not a socket")
+	}
+	if e.dockerSocket != nil {
+		t.Error("failed call should leave the socket unset")
+	}
+}
+
+func TestCheckTrust_DockerSocket(t *testing.T) {
+	store := workspace.NewStoreAt(t.TempDir())
+	ws := &workspace.Workspace{ID: "ws-socket", Source: t.TempDir()}
+	if err := store.Save(ws); err != nil {
+		t.Fatal(err)
+	}
+
+	var prompted [][]string
+	answer := false
+	e := &Engine{store: store, dockerSocket: &config.Mount{Type: "bind", Source: "/var/run/docker.sock", Target: "/var/run/docker.sock"}}
+	e.SetTrustPrompt(func(_ *workspace.Workspace, settings []string) (bool, error) {
+		prompted = append(prompted, settings)
+		return answer, nil
+	})
+
+	// The flag alone triggers the prompt; declining blocks the container.
+	cfg := &config.DevContainerConfig{}
+	err := e.checkTrust(ws, cfg, "", nil)
+	var untrusted *ErrUntrusted
+	if !errors.As(err, &untrusted) {
+		t.Fatalf("expected ErrUntrusted, got %v", err)
+	}
+	if want := []string{"mount: /var/run/docker.sock"}; !slices.Equal(untrusted.Settings, want) {
+		t.Errorf("untrusted settings = %q, want %q", untrusted.Settings, want)
+	}
+
+	// The same socket in the config is listed once.
+	cfg.Mounts = []config.Mount{{Type: "bind", Source: "/var/run/docker.sock", Target: "/var/run/docker.sock"}}
+	answer = true
+	if err := e.checkTrust(ws, cfg, "", nil); err != nil {
+		t.Fatalf("checkTrust: %v", err)
+	}
+	if last := prompted[len(prompted)-1]; len(last) != 1 {
+		t.Errorf("prompted for %q, want the socket once", last)
+	}
+
+	// Once accepted, the workspace remembers it.
+	if err := e.checkTrust(ws, &config.DevContainerConfig{}, "", nil); err != nil {
+		t.Fatalf("checkTrust on trusted workspace: %v", err)
+	}
+	if len(prompted) != 2 {
+		t.Errorf("prompted %d times, want 2", len(prompted))
+	}
+}
+
+func TestSingleBackend_CreateContainer_DockerSocket(t *testing.T) {
+	store := workspace.NewStoreAt(t.TempDir())
+	ws := &workspace.Workspace{ID: "ws-docker-socket", Source: "/home/user/project"}
+	if err := store.Save(ws); err != nil {
+		t.Fatal(err)
+	}
+
+	mockDrv := &snapshotUpMockDriver{containerID: "new-container"}
+	eng := &Engine{
+		driver:       mockDrv,
+		store:        store,
+		logger:       slog.Default(),
+		stdout:       io.Discard,
+		stderr:       io.Discard,
+		progress:     func(ProgressEvent) {},
+		dockerSocket: &config.Mount{Type: "bind", Source: "/run/docker.sock", Target: "/var/run/docker.sock"},
+		socketGroup:  "998",
+	}
+
+	cfg := &config.DevContainerConfig{}
+	cfg.Image = "alpine:3.20"
+	b := &singleBackend{e: eng, ws: ws, cfg: cfg, workspaceFolder: "/workspaces/project"}
+	if _, err := b.createContainer(context.Background(), createOpts{imageName: "alpine:3.20"}); err != nil {
+		t.Fatalf("createContainer: %v", err)
+	}
+
+	run := mockDrv.runCalls[0]
+	if !slices.Contains(run.Mounts, *eng.dockerSocket) {
+		t.Errorf("Mounts = %v, want the docker socket", run.Mounts)
+	}
+	if !slices.Equal(run.GroupAdd, []string{"998"}) {
+		t.Errorf("GroupAdd = %v, want [998]", run.GroupAdd)
+	}
+}
@@ -0,0 +1,63 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/compose-spec/compose-go/v2/dotenv"
+
+	"github.com/fgrehm/crib/internal/config"
+	"github.com/fgrehm/crib/internal/workspace"
+)
+
+// dotEnvFile is the project-root env file loaded when
+// customizations.crib.dotenv is true.
+const dotEnvFile = ".env"
+
+// dotEnvEnabled reports whether customizations.crib.dotenv opts the project
+// into loading its root .env file.
+func dotEnvEnabled(cfg *config.DevContainerConfig) bool {
+	v, _ := extractCribCustomizations(cfg)["dotenv"].(bool)
+	return v
+}
+
+// loadDotEnv returns the variables from the .env file at the project root
+// when the config opts in with customizations.crib.dotenv. A missing file is
+// not an error. The values sit below the config's own containerEnv, which
+// wins on duplicate keys.
+func loadDotEnv(ws *workspace.Workspace, cfg *config.DevContainerConfig) (map[string]string, error) {
+	if !dotEnvEnabled(cfg) {
+		return nil, nil
+	}
+	path := filepath.Join(ws.Source, dotEnvFile)
+	env, err := dotenv.Read(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	return env, nil
+}
+
+// dotEnvComposeEnv returns the .env variables as KEY=VALUE pairs for compose
+// subprocesses, so ${VAR} references in compose files resolve against them.
+// Variables already set in crib's own environment win, matching how compose
+// treats its own .env file. Errors are ignored here: Up reports them when it
+// generates the compose override.
+func dotEnvComposeEnv(ws *workspace.Workspace, cfg *config.DevContainerConfig) []string {
+	env, _ := loadDotEnv(ws, cfg)
+	var pairs []string
+	for k, v := range env {
+		if _, ok := os.LookupEnv(k); ok {
+			continue
+		}
+		pairs = append(pairs, k+"="+v)
+	}
+	slices.Sort(pairs)
+	return pairs
+}
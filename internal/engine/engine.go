@@ -4,14 +4,19 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"maps"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 	"time"
 
+	"github.com/compose-spec/compose-go/v2/loader"
+
 	"github.com/fgrehm/crib/internal/compose"
 	"github.com/fgrehm/crib/internal/config"
 	"github.com/fgrehm/crib/internal/driver"
@@ -43,9 +48,9 @@ type composeInvocation struct {
 func newComposeInvocation(ws *workspace.Workspace, cfg *config.DevContainerConfig, workspaceFolder string) composeInvocation {
 	cd := configDir(ws)
 	return composeInvocation{
-		projectName: compose.ProjectName(ws.ID),
+		projectName: composeProjectName(ws, cfg),
 		files:       resolveComposeFiles(cd, cfg.DockerComposeFile),
-		env:         devcontainerEnv(ws.ID, ws.Source, workspaceFolder),
+		env:         append(dotEnvComposeEnv(ws, cfg), devcontainerEnv(ws.ID, ws.Source, workspaceFolder)...),
 		service:     cfg.Service,
 	}
 }
@@ -58,12 +63,40 @@ type Engine struct {
 	plugins          *plugin.Manager
 	runtimeName      string
 	buildCacheMounts []string               // BuildKit cache mount targets for feature builds
+	cacheTo          []string               // extra --cache-to targets for image builds
+	addHosts         []string               // extra /etc/hosts entries ("name:ip") from the CLI
+	extraMounts      []config.Mount         // ad-hoc mounts from the CLI
+	tmpfs            []string               // tmpfs mounts ("path[:options]") from the CLI
+	labels           map[string]string      // extra container labels from --label-file
+	gpus             string                 // --gpus value; "none" disables hostRequirements.gpu
+	network          string                 // --network value; overrides customizations.crib.network when set
+	resourceLimits   bool                   // apply hostRequirements cpus/memory as container limits
+	autoPort         bool                   // publish forwarded ports on free host ports when theirs are taken
+	cpuset           string                 // --cpuset value; CPUs the container may run on
+	capDrop          []string               // capabilities from --cap-drop, dropped on top of capDrop
+	dockerSocket     *config.Mount          // runtime socket bind from --mount-docker-socket; nil when off
+	socketGroup      string                 // host GID owning the socket, added to the container's groups
+	stopTimeout      *time.Duration         // --stop-timeout value; overrides customizations.crib.stopTimeout when set
+	secrets          []string               // build secret specs from the CLI, sources made absolute
+	contexts         []string               // named build contexts ("name=value") from the CLI, local paths made absolute
+	pull             bool                   // refresh base images before building
+	forceBuild       bool                   // rebuild images even when a build for the config is cached
+	buildOpts        BuildOptions           // image name and export target while Build runs
+	envProbe         string                 // --env-probe value; overrides userEnvProbe when set
+	workspaceFolder  string                 // --workspace-folder value; overrides the config's workspaceFolder when set
 	globalWS         GlobalWorkspaceOptions // effective merged workspace options (global config + project .cribrc)
 	logger           *slog.Logger
 	stdout           io.Writer
 	stderr           io.Writer
 	verbose          bool
 	progress         func(ProgressEvent)
+	hookRetries      int // extra attempts for failing create-time hooks
+	waitForTimeout   time.Duration
+	waitForFail      bool
+	keepGenerated    bool // keep generated build files and report their paths
+	trustPrompt      TrustPrompt
+	imageUsers       map[string]string // image -> Config.User, cached for one Up/Restart
+	timings          *timingCollector  // step durations of the running Up; nil otherwise
 }
 
 // GlobalWorkspaceOptions carries the effective merged workspace options
@@ -174,12 +207,252 @@ func (e *Engine) SetRuntime(name string) {
 	e.runtimeName = name
 }
 
+// SetWaitForTimeout limits how long the lifecycle hooks up to the waitFor
+// stage may take on a fresh Up. When d expires crib warns and reports the
+// container ready while the hooks keep running, or, with fail, aborts the
+// running hook and returns ErrWaitForTimeout. Zero disables the limit.
+func (e *Engine) SetWaitForTimeout(d time.Duration, fail bool) {
+	e.waitForTimeout = max(d, 0)
+	e.waitForFail = fail
+}
+
+// SetHookRetries sets how many times a failing create-time lifecycle hook
+// (onCreate, updateContent, postCreate) is re-run before Up gives up.
+// Retries back off exponentially. Zero (the default) disables retries.
+func (e *Engine) SetHookRetries(n int) {
+	e.hookRetries = max(n, 0)
+}
+
 // SetBuildCacheMounts configures BuildKit cache mount targets for feature
 // install RUN instructions (e.g. "/var/cache/apt", "/root/.npm").
 func (e *Engine) SetBuildCacheMounts(mounts []string) {
 	e.buildCacheMounts = mounts
 }
 
+// SetCacheTo adds build cache export targets (e.g.
+// "type=registry,ref=ghcr.io/org/app:cache") passed to image builds as
+// --cache-to, on top of build.cacheTo from devcontainer.json.
+func (e *Engine) SetCacheTo(targets []string) {
+	e.cacheTo = targets
+}
+
+// SetKeepGenerated keeps the files crib generates for builds (.crib-Dockerfile
+// and the feature context in the build context) instead of removing them
+// afterwards, and reports their paths along with the compose override, so
+// users can inspect exactly what crib injected.
+func (e *Engine) SetKeepGenerated(v bool) {
+	e.keepGenerated = v
+}
+
+// SetExtraHosts adds /etc/hosts entries ("name:ip") to containers created
+// by Up, Rebuild and Restart, on top of customizations.crib.extraHosts.
+// It returns an error if an entry is malformed.
+func (e *Engine) SetExtraHosts(hosts []string) error {
+	for _, h := range hosts {
+		if _, _, err := splitExtraHost(h); err != nil {
+			return err
+		}
+	}
+	e.addHosts = hosts
+	return nil
+}
+
+// SetTmpfs mounts a tmpfs ("path[:options]", e.g. "/tmp:size=1g") in
+// containers created by Up, Rebuild and Restart, on top of
+// customizations.crib.tmpfs. It returns an error if an entry is malformed.
+func (e *Engine) SetTmpfs(specs []string) error {
+	for _, s := range specs {
+		if err := validateTmpfs(s); err != nil {
+			return err
+		}
+	}
+	e.tmpfs = specs
+	return nil
+}
+
+// SetGPUs sets the GPUs exposed to containers created by Up, Rebuild and
+// Restart, in docker --gpus syntax ("all", "device=0,1"). It overrides the
+// default from hostRequirements.gpu; "none" disables GPU passthrough.
+func (e *Engine) SetGPUs(gpus string) {
+	e.gpus = gpus
+}
+
+// SetNetwork connects containers created by Up, Rebuild and Restart to an
+// existing network, overriding customizations.crib.network. Single
+// containers run with --network; compose workspaces join the primary
+// service to it as an external network.
+func (e *Engine) SetNetwork(name string) {
+	e.network = name
+}
+
+// SetResourceLimits makes containers created by Up, Rebuild and Restart
+// enforce hostRequirements.cpus and hostRequirements.memory as CPU and
+// memory limits instead of treating them as informational.
+func (e *Engine) SetResourceLimits(v bool) {
+	e.resourceLimits = v
+}
+
+// SetCPUSet pins containers created by Up, Rebuild and Restart to the given
+// host CPUs, in --cpuset-cpus syntax ("0-3", "0,2,4-5"), e.g. for
+// reproducible benchmarks. An empty value leaves them unpinned. It returns
+// an error if cpus is not a valid CPU list.
+func (e *Engine) SetCPUSet(cpus string) error {
+	if err := validateCPUSet(cpus); err != nil {
+		return err
+	}
+	e.cpuset = cpus
+	return nil
+}
+
+// SetCapDrop drops the given Linux capabilities (e.g. NET_RAW, or ALL)
+// from containers created by Up, Rebuild and Restart, in addition to the
+// config's capDrop. It returns an error if a name is not a valid
+// capability.
+func (e *Engine) SetCapDrop(caps []string) error {
+	for _, c := range caps {
+		if err := validateCapability(c); err != nil {
+			return err
+		}
+	}
+	e.capDrop = caps
+	return nil
+}
+
+// SetStopTimeout sets how long Stop waits for the container (or compose
+// services) to exit after SIGTERM before killing them, overriding
+// customizations.crib.stopTimeout. It returns an error if d is negative.
+func (e *Engine) SetStopTimeout(d time.Duration) error {
+	if d < 0 {
+		return fmt.Errorf("invalid stop timeout %s: must not be negative", d)
+	}
+	e.stopTimeout = &d
+	return nil
+}
+
+// SetAutoPort makes containers created by Up, Rebuild and Restart publish a
+// forwarded port on a free host port when its own host port is taken,
+// instead of failing with ErrPortInUse. The new mapping is reported as
+// progress and shows up in the result's ports.
+func (e *Engine) SetAutoPort(v bool) {
+	e.autoPort = v
+}
+
+// SetPull makes Up refresh base images instead of reusing local copies: the
+// image from devcontainer.json is pulled again, image builds run with --pull
+// (so the FROM images of a Dockerfile are refreshed too) and the cached
+// build for the current config is not reused. The builder's layer cache
+// still skips unchanged steps when the base digest did not change.
+func (e *Engine) SetPull(v bool) {
+	e.pull = v
+}
+
+// SetEnvProbe overrides the config's userEnvProbe for this invocation. An
+// empty value keeps the config setting. It returns an error if v is not one
+// of none, loginShell, interactiveShell or loginInteractiveShell.
+func (e *Engine) SetEnvProbe(v string) error {
+	switch v {
+	case "", "none", "loginShell", "interactiveShell", "loginInteractiveShell":
+	default:
+		return fmt.Errorf("invalid env probe %q (want none, loginShell, interactiveShell or loginInteractiveShell)", v)
+	}
+	e.envProbe = v
+	return nil
+}
+
+// SetWorkspaceFolder overrides the config's workspaceFolder for this
+// invocation: the project is mounted there and ${containerWorkspaceFolder}
+// resolves to it. An empty value keeps the config setting. It returns an
+// error if dir is not an absolute container path.
+func (e *Engine) SetWorkspaceFolder(dir string) error {
+	if dir == "" {
+		e.workspaceFolder = ""
+		return nil
+	}
+	if !path.IsAbs(dir) {
+		return fmt.Errorf("invalid workspace folder %q: must be an absolute path", dir)
+	}
+	e.workspaceFolder = path.Clean(dir)
+	return nil
+}
+
+// SetBuildSecrets adds BuildKit build secrets (--secret specs such as
+// "id=npmrc,src=.npmrc") to image builds, on top of
+// customizations.crib.buildSecrets. Relative sources are resolved against
+// baseDir. It returns an error if a spec is malformed or its source file
+// cannot be read.
+func (e *Engine) SetBuildSecrets(specs []string, baseDir string) error {
+	secrets := make([]string, 0, len(specs))
+	for _, spec := range specs {
+		s, err := parseBuildSecret(spec, baseDir)
+		if err != nil {
+			return err
+		}
+		secrets = append(secrets, s)
+	}
+	e.secrets = secrets
+	return nil
+}
+
+// SetBuildContexts adds named build contexts (--build-context specs such as
+// "assets=../assets" or "base=docker-image://alpine:3.20") to image builds,
+// on top of customizations.crib.buildContexts. Relative paths are resolved
+// against baseDir. It returns an error if a spec is malformed or its local
+// path does not exist.
+func (e *Engine) SetBuildContexts(specs []string, baseDir string) error {
+	contexts := make([]string, 0, len(specs))
+	for _, spec := range specs {
+		c, err := parseBuildContext(spec, baseDir)
+		if err != nil {
+			return err
+		}
+		contexts = append(contexts, c)
+	}
+	e.contexts = contexts
+	return nil
+}
+
+// SetExtraMounts adds ad-hoc mounts (--mount specs in the devcontainer.json
+// mount syntax) to containers created by Up, Rebuild and Restart. Relative
+// bind sources are resolved against baseDir. It returns an error if a spec
+// is malformed.
+func (e *Engine) SetExtraMounts(specs []string, baseDir string) error {
+	mounts := make([]config.Mount, 0, len(specs))
+	for _, spec := range specs {
+		m, err := config.ParseMount(spec)
+		if err != nil {
+			return fmt.Errorf("--mount %q: %w", spec, err)
+		}
+		mounts = append(mounts, m)
+	}
+	e.extraMounts = resolveMountSources(mounts, baseDir)
+	return nil
+}
+
+// SetLabelFiles reads KEY=VALUE lines from each file in paths and adds them
+// as labels to containers created by Up, Rebuild and Restart. Blank lines
+// and # comments are skipped; later files win on duplicate keys. Labels in
+// the crib. namespace are reserved because crib finds its containers by
+// them. It returns an error if a file cannot be read.
+func (e *Engine) SetLabelFiles(paths []string) error {
+	var labels map[string]string
+	for _, p := range paths {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return fmt.Errorf("--label-file: %w", err)
+		}
+		parsed, err := parseLabelFile(string(data))
+		if err != nil {
+			return fmt.Errorf("--label-file %s: %w", p, err)
+		}
+		if labels == nil {
+			labels = make(map[string]string, len(parsed))
+		}
+		maps.Copy(labels, parsed)
+	}
+	e.labels = labels
+	return nil
+}
+
 // SetGlobalWorkspace stores global [workspace] options from the user config
 // so every subsequent Up / Restart applies them on top of project-level
 // settings. Project values win on key conflicts.
@@ -231,6 +504,38 @@ func (e *Engine) reportProgress(phase ProgressPhase, msg string) {
 type UpOptions struct {
 	// Recreate forces container recreation even if one already exists.
 	Recreate bool
+
+	// RebuildImage rebuilds the image even when a build for the current
+	// config is cached. An existing container is kept when it already runs
+	// the rebuilt image and recreated otherwise. Named volumes survive
+	// either way.
+	RebuildImage bool
+
+	// RecreateDeps also recreates the dependency services of a compose
+	// workspace when Recreate is set. When false and the primary container
+	// is running, only the primary service is recreated and its
+	// dependencies are left running. Ignored for single containers.
+	RecreateDeps bool
+
+	// RebuildIfChanged rebuilds the image and recreates the container (and
+	// compose project), as 'crib rebuild' would, when image-affecting
+	// fields (image, build, features) changed since the last up, or when
+	// the image built from the current Dockerfile and build context has a
+	// different name than the stored one. Otherwise the existing container
+	// is reused as usual.
+	RebuildIfChanged bool
+
+	// Replace removes an existing container and creates a new one from the
+	// cached image even when the config is unchanged, for a container that
+	// is wedged. Unlike Recreate it resumes from the snapshot when there is
+	// a valid one, so only resume hooks run, as restart's recreate does.
+	// Ignored when Recreate or RebuildImage is set.
+	Replace bool
+
+	// Timeout bounds the whole operation (builds, container creation, and
+	// lifecycle hooks). Zero means no limit. On expiry, a container created
+	// by this run is removed and ErrUpTimeout is returned.
+	Timeout time.Duration
 }
 
 // UpResult holds the outcome of a successful Up operation.
@@ -258,12 +563,69 @@ type UpResult struct {
 	// HasFeatureEntrypoints is true when the image has feature-declared
 	// entrypoints baked in. Persisted to result.json for restart paths.
 	HasFeatureEntrypoints bool
+
+	// Timings lists how long each step of the Up took (initializeCommand,
+	// image build, container creation, UID sync, environment probes and
+	// each lifecycle hook stage that ran), in the order they finished.
+	Timings []StepTiming
 }
 
+// upCleanupTimeout bounds how long removing a half-created container may
+// take after Up timed out.
+const upCleanupTimeout = 30 * time.Second
+
 // Up brings a devcontainer up for the given workspace.
 func (e *Engine) Up(ctx context.Context, ws *workspace.Workspace, opts UpOptions) (*UpResult, error) {
+	e.timings = newTimingCollector(e.progress)
+	defer func() { e.timings = nil }()
+
+	result, err := e.upWithTimeout(ctx, ws, opts)
+	if result != nil {
+		result.Timings = e.timings.list()
+	}
+	return result, err
+}
+
+// upWithTimeout runs up, bounded by opts.Timeout when it is set.
+func (e *Engine) upWithTimeout(ctx context.Context, ws *workspace.Workspace, opts UpOptions) (*UpResult, error) {
+	if opts.Timeout <= 0 {
+		return e.up(ctx, ws, opts, nil)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+
+	// up reports the backend it created a container with, so the partial
+	// container can be removed if the deadline hits midway.
+	var created containerBackend
+	result, err := e.up(ctx, ws, opts, &created)
+	if err == nil || !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return result, err
+	}
+
+	if created != nil {
+		e.logger.Debug("up timed out, removing partially created container", "workspace", ws.ID)
+		cleanupCtx, cleanupCancel := context.WithTimeout(context.WithoutCancel(ctx), upCleanupTimeout)
+		defer cleanupCancel()
+		if err := e.store.ClearHookMarkers(ws.ID); err != nil {
+			e.logger.Warn("failed to clear hook markers", "error", err)
+		}
+		if err := created.deleteExisting(cleanupCtx); err != nil {
+			e.logger.Warn("failed to remove container after timeout", "error", err)
+		}
+	}
+	return nil, &ErrUpTimeout{WorkspaceID: ws.ID, Timeout: opts.Timeout, Err: err}
+}
+
+// up implements Up. When created is non-nil, it is set to the backend before
+// a new container is created.
+func (e *Engine) up(ctx context.Context, ws *workspace.Workspace, opts UpOptions, created *containerBackend) (*UpResult, error) {
 	e.logger.Debug("up", "workspace", ws.ID, "source", ws.Source)
 
+	// Images may have been rebuilt or pulled since the last run.
+	e.imageUsers = nil
+	e.forceBuild = opts.RebuildImage
+
 	cfg, workspaceFolder, err := e.parseAndSubstitute(ws)
 	if err != nil {
 		return nil, err
@@ -275,10 +637,16 @@ func (e *Engine) Up(ctx context.Context, ws *workspace.Workspace, opts UpOptions
 			return nil, &ErrComposeNotAvailable{}
 		}
 		if cfg.Service == "" {
-			return nil, fmt.Errorf("dockerComposeFile is set but service is not specified")
+			return nil, &ErrComposeServiceMissing{}
 		}
 	}
 
+	// Confirm dangerous settings before anything runs on the host or the
+	// existing container is touched.
+	if err := e.checkTrust(ws, cfg, workspaceFolder, nil); err != nil {
+		return nil, err
+	}
+
 	// Run initializeCommand on the host before image build/pull.
 	if err := e.runInitializeCommand(ctx, ws, cfg); err != nil {
 		return nil, fmt.Errorf("initializeCommand: %w", err)
@@ -295,26 +663,137 @@ func (e *Engine) Up(ctx context.Context, ws *workspace.Workspace, opts UpOptions
 		return nil, fmt.Errorf("finding container: %w", err)
 	}
 
-	if container != nil && !opts.Recreate {
+	// Image-affecting edits since the last up turn this run into a rebuild.
+	// Config fields are compared first; otherwise the image is built (a
+	// cache hit when nothing changed) and its name, which carries a hash of
+	// the Dockerfile and build context, is compared with the stored one.
+	var prebuilt *buildResult
+	if opts.RebuildIfChanged && !opts.Recreate {
+		changed := e.imageConfigChanged(ws, cfg)
+		if changed {
+			e.forceBuild = true
+		} else if container != nil {
+			stop := e.timings.start("build")
+			prebuilt, err = b.buildImage(ctx)
+			stop()
+			if err != nil {
+				return nil, err
+			}
+			changed = e.builtImageChanged(ws, prebuilt)
+			if !changed {
+				prebuilt = nil
+			}
+		}
+		if changed {
+			e.reportProgress(PhaseBuild, "Image-affecting changes detected, rebuilding...")
+			e.clearSnapshot(ctx, ws)
+			opts.Recreate = true
+			opts.RecreateDeps = true
+		}
+	}
+
+	// Rebuild the image up front so an unchanged image keeps the container.
+	if container != nil && opts.RebuildImage && !opts.Recreate {
+		stop := e.timings.start("build")
+		prebuilt, err = b.buildImage(ctx)
+		stop()
+		if err != nil {
+			return nil, err
+		}
+		if e.containerRunsImage(ctx, container, prebuilt.imageName) {
+			e.reportProgress(PhaseBuild, "Image unchanged, keeping container")
+			return e.upExisting(ctx, ws, cfg, workspaceFolder, b, container)
+		}
+	}
+	recreate := opts.Recreate || prebuilt != nil
+	replace := opts.Replace && !recreate && !opts.RebuildImage
+
+	if container != nil && !recreate && !replace {
 		return e.upExisting(ctx, ws, cfg, workspaceFolder, b, container)
 	}
 
 	// Remove existing container if recreating.
-	if container != nil && opts.Recreate {
+	if container != nil && (recreate || replace) {
+		// A stopped primary container means its dependencies may be down
+		// too, so fall back to recreating the whole project.
+		if cb, ok := b.(*composeBackend); ok && !opts.RecreateDeps && container.State.IsRunning() {
+			cb.keepDeps = true
+		}
 		e.reportProgress(PhaseCreate, "Removing container...")
-		if err := e.store.ClearHookMarkers(ws.ID); err != nil {
-			e.logger.Warn("failed to clear hook markers", "error", err)
+		// A replaced container resuming from its snapshot keeps the
+		// markers; without one, the new container needs the full setup.
+		keepMarkers := false
+		if replace {
+			_, keepMarkers = e.validSnapshot(ctx, ws, cfg)
+		}
+		if !keepMarkers {
+			if err := e.store.ClearHookMarkers(ws.ID); err != nil {
+				e.logger.Warn("failed to clear hook markers", "error", err)
+			}
 		}
 		if err := b.deleteExisting(ctx); err != nil {
 			return nil, fmt.Errorf("deleting container for recreation: %w", err)
 		}
 	}
 
-	return e.upCreate(ctx, ws, cfg, workspaceFolder, b, opts.Recreate)
+	if created != nil {
+		*created = b
+	}
+	// A requested rebuild must not resume from a snapshot or stored image.
+	return e.upCreate(ctx, ws, cfg, workspaceFolder, b, recreate || opts.RebuildImage, prebuilt)
+}
+
+// imageConfigChanged reports whether cfg changes fields that need a new
+// image compared to the config stored by the last up. A workspace without
+// a stored config has nothing to compare against and reports false.
+func (e *Engine) imageConfigChanged(ws *workspace.Workspace, cfg *config.DevContainerConfig) bool {
+	stored, err := e.store.LoadResult(ws.ID)
+	if err != nil || stored == nil || len(stored.MergedConfig) == 0 {
+		return false
+	}
+	var storedCfg config.DevContainerConfig
+	if err := json.Unmarshal(stored.MergedConfig, &storedCfg); err != nil {
+		e.logger.Debug("failed to unmarshal stored config", "error", err)
+		return false
+	}
+	return detectConfigChange(&storedCfg, cfg) == changeNeedsRebuild
+}
+
+// builtImageChanged reports whether res names a different image than the
+// one recorded by the last up. Builds that produce no image of their own
+// (compose without features) and workspaces without a stored result
+// report false.
+func (e *Engine) builtImageChanged(ws *workspace.Workspace, res *buildResult) bool {
+	if res == nil || res.imageName == "" {
+		return false
+	}
+	stored, err := e.store.LoadResult(ws.ID)
+	if err != nil || stored == nil || stored.ImageName == "" {
+		return false
+	}
+	return stored.ImageName != res.imageName
+}
+
+// containerRunsImage reports whether container was created from imageName,
+// comparing image IDs. It returns false when either ID is unknown.
+func (e *Engine) containerRunsImage(ctx context.Context, container *driver.ContainerDetails, imageName string) bool {
+	if container.Image == "" || imageName == "" {
+		return false
+	}
+	details, err := e.driver.InspectImage(ctx, imageName)
+	if err != nil || details == nil {
+		return false
+	}
+	return details.ID == container.Image
 }
 
 // upExisting handles the case where a container already exists.
 func (e *Engine) upExisting(ctx context.Context, ws *workspace.Workspace, cfg *config.DevContainerConfig, workspaceFolder string, b containerBackend, container *driver.ContainerDetails) (*UpResult, error) {
+	// A paused container cannot be started; unpausing is left to the user.
+	if container.State.IsPaused() {
+		return nil, &ErrContainerPaused{WorkspaceID: ws.ID, ContainerID: container.ID}
+	}
+
 	// Load stored result for image name and feature entrypoints.
 	var storedResult *workspace.Result
 	var storedImageName string
@@ -352,9 +831,12 @@ func (e *Engine) upExisting(ctx context.Context, ws *workspace.Workspace, cfg *c
 		cc.remoteUser = storedResult.RemoteUser
 	}
 
-	if !container.State.IsRunning() {
+	alreadyRunning := container.State.IsRunning()
+	if !alreadyRunning {
 		e.reportProgress(PhaseCreate, "Starting container...")
+		stop := e.timings.start("start")
 		newID, err := b.start(ctx, container.ID, pluginResp)
+		stop()
 		if err != nil {
 			return nil, err
 		}
@@ -370,12 +852,15 @@ func (e *Engine) upExisting(ctx context.Context, ws *workspace.Workspace, cfg *c
 		pluginResp:              pluginResp,
 		storedResult:            storedResult,
 		fromSnapshot:            storedResult != nil,
+		alreadyRunning:          alreadyRunning,
 		shouldMergeFeatureHooks: false,
 	})
 }
 
-// upCreate handles creating a new container (no existing container or recreate).
-func (e *Engine) upCreate(ctx context.Context, ws *workspace.Workspace, cfg *config.DevContainerConfig, workspaceFolder string, b containerBackend, isRecreate bool) (*UpResult, error) {
+// upCreate handles creating a new container (no existing container or
+// recreate). prebuilt, when non-nil, is an image build already done for
+// this run.
+func (e *Engine) upCreate(ctx context.Context, ws *workspace.Workspace, cfg *config.DevContainerConfig, workspaceFolder string, b containerBackend, isRecreate bool, prebuilt *buildResult) (*UpResult, error) {
 	// Check for snapshot or stored result to resume from.
 	if !isRecreate {
 		if storedResult, loadErr := e.store.LoadResult(ws.ID); loadErr == nil && storedResult != nil {
@@ -391,8 +876,20 @@ func (e *Engine) upCreate(ctx context.Context, ws *workspace.Workspace, cfg *con
 	}
 
 	// Fresh build path.
-	buildRes, err := b.buildImage(ctx)
-	if err != nil {
+	buildRes := prebuilt
+	if buildRes == nil {
+		var err error
+		stop := e.timings.start("build")
+		buildRes, err = b.buildImage(ctx)
+		stop()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Features and the image's devcontainer.metadata are merged into the
+	// config; confirm what they add before creating the container.
+	if err := e.checkTrust(ws, cfg, workspaceFolder, buildRes.imageMetadata); err != nil {
 		return nil, err
 	}
 
@@ -405,12 +902,14 @@ func (e *Engine) upCreate(ctx context.Context, ws *workspace.Workspace, cfg *con
 		return nil, err
 	}
 
+	stop := e.timings.start("create")
 	created, err := b.createContainer(ctx, createOpts{
 		imageName:      buildRes.imageName,
 		hasEntrypoints: buildRes.hasEntrypoints,
 		metadata:       buildRes.imageMetadata,
 		pluginResp:     pluginResp,
 	})
+	stop()
 	if err != nil {
 		return nil, err
 	}
@@ -439,6 +938,7 @@ func (e *Engine) upCreate(ctx context.Context, ws *workspace.Workspace, cfg *con
 		pluginResp:              pluginResp,
 		imageMetadata:           buildRes.imageMetadata,
 		imageUser:               buildRes.imageUser,
+		ports:                   created.Ports,
 		shouldMergeFeatureHooks: true,
 	})
 }
@@ -456,12 +956,14 @@ func (e *Engine) upFromImage(ctx context.Context, ws *workspace.Workspace, cfg *
 
 	hasEntrypoints := storedResult.HasFeatureEntrypoints
 
+	stop := e.timings.start("create")
 	created, err := b.createContainer(ctx, createOpts{
 		imageName:      imageName,
 		hasEntrypoints: hasEntrypoints,
 		pluginResp:     pluginResp,
 		skipBuild:      true,
 	})
+	stop()
 	if err != nil {
 		return nil, err
 	}
@@ -490,6 +992,7 @@ func (e *Engine) upFromImage(ctx context.Context, ws *workspace.Workspace, cfg *
 		pluginResp:              pluginResp,
 		storedResult:            storedResult,
 		fromSnapshot:            isSnapshot,
+		ports:                   created.Ports,
 		shouldMergeFeatureHooks: false,
 	})
 }
@@ -514,6 +1017,7 @@ func (e *Engine) saveResult(ws *workspace.Workspace, cfg *config.DevContainerCon
 	mergedJSON, _ := json.Marshal(cfg)
 	wsResult.ContainerID = result.ContainerID
 	wsResult.ContainerName = result.ContainerName
+	wsResult.Name = cfg.Name
 	wsResult.ImageName = result.ImageName
 	wsResult.MergedConfig = mergedJSON
 	wsResult.WorkspaceFolder = result.WorkspaceFolder
@@ -569,6 +1073,19 @@ func (e *Engine) Down(ctx context.Context, ws *workspace.Workspace) error {
 	return e.driver.DeleteContainer(ctx, ws.ID, container.ID)
 }
 
+// Shutdown runs what "crib down" does for the workspace. Compose workspaces
+// whose stored config sets shutdownAction to "stopCompose" are stopped with
+// compose stop, keeping networks, volumes and hook markers so the next "up"
+// resumes quickly. Every other workspace is torn down with Down.
+func (e *Engine) Shutdown(ctx context.Context, ws *workspace.Workspace) error {
+	result, _ := e.store.LoadResult(ws.ID)
+	if cfg := storedComposeConfig(result); cfg != nil && cfg.ShutdownAction == "stopCompose" {
+		e.logger.Debug("shutdownAction is stopCompose, stopping instead of removing", "workspace", ws.ID)
+		return e.Stop(ctx, ws)
+	}
+	return e.Down(ctx, ws)
+}
+
 // Stop stops the container for the given workspace without removing it.
 // Hook markers are preserved so that a subsequent "up" runs only resume-flow
 // hooks (postStartCommand, postAttachCommand).
@@ -584,7 +1101,7 @@ func (e *Engine) Stop(ctx context.Context, ws *workspace.Workspace) error {
 	// For compose workspaces, use compose stop.
 	if cfg != nil {
 		inv := newComposeInvocation(ws, cfg, result.WorkspaceFolder)
-		return e.composeStop(ctx, inv, ws.ID)
+		return e.composeStop(ctx, inv, ws.ID, e.stopTimeoutFor(cfg))
 	}
 
 	// Non-compose path: stop the individual container.
@@ -601,7 +1118,79 @@ func (e *Engine) Stop(ctx context.Context, ws *workspace.Workspace) error {
 		return nil
 	}
 
-	return e.driver.StopContainer(ctx, ws.ID, container.ID)
+	return e.driver.StopContainer(ctx, ws.ID, container.ID, e.stopTimeoutFor(storedConfig(result)))
+}
+
+// stopTimeoutFor returns how long stopping the workspace may take before
+// the runtime kills it: --stop-timeout, then customizations.crib.stopTimeout
+// (in seconds) from cfg. nil leaves it to the runtime's default.
+func (e *Engine) stopTimeoutFor(cfg *config.DevContainerConfig) *time.Duration {
+	if e.stopTimeout != nil {
+		return e.stopTimeout
+	}
+	if cfg == nil {
+		return nil
+	}
+	raw, ok := extractCribCustomizations(cfg)["stopTimeout"]
+	if !ok {
+		return nil
+	}
+	secs, ok := raw.(float64)
+	if !ok || secs < 0 {
+		e.logger.Warn("ignoring customizations.crib.stopTimeout: must be a non-negative number of seconds", "value", raw)
+		return nil
+	}
+	d := time.Duration(secs * float64(time.Second))
+	return &d
+}
+
+// Pause freezes the workspace container without stopping it, so memory state
+// is kept. For compose workspaces, all running services are paused.
+func (e *Engine) Pause(ctx context.Context, ws *workspace.Workspace) error {
+	return e.setPaused(ctx, ws, true)
+}
+
+// Unpause resumes a workspace paused with Pause.
+func (e *Engine) Unpause(ctx context.Context, ws *workspace.Workspace) error {
+	return e.setPaused(ctx, ws, false)
+}
+
+func (e *Engine) setPaused(ctx context.Context, ws *workspace.Workspace, pause bool) error {
+	e.logger.Debug("set paused", "workspace", ws.ID, "pause", pause)
+
+	result, _ := e.store.LoadResult(ws.ID)
+	cfg := storedComposeConfig(result)
+	if cfg != nil && e.compose == nil {
+		return &ErrComposeNotAvailable{}
+	}
+	if cfg != nil {
+		inv := newComposeInvocation(ws, cfg, result.WorkspaceFolder)
+		return e.composePause(ctx, inv, ws.ID, pause)
+	}
+
+	container, err := e.driver.FindContainer(ctx, ws.ID)
+	if err != nil {
+		return fmt.Errorf("finding container: %w", err)
+	}
+	if container == nil {
+		return &ErrNoContainer{WorkspaceID: ws.ID}
+	}
+
+	if !pause {
+		if !container.State.IsPaused() {
+			e.logger.Debug("container not paused", "workspace", ws.ID, "status", container.State.Status)
+			return nil
+		}
+		return e.driver.UnpauseContainer(ctx, ws.ID, container.ID)
+	}
+	if container.State.IsPaused() {
+		e.logger.Debug("container already paused", "workspace", ws.ID)
+		return nil
+	}
+	if !container.State.IsRunning() {
+		return &ErrContainerStopped{WorkspaceID: ws.ID, ContainerID: container.ID}
+	}
+	return e.driver.PauseContainer(ctx, ws.ID, container.ID)
 }
 
 // RemovePreview describes what Remove() will delete.
@@ -715,6 +1304,69 @@ func (e *Engine) Status(ctx context.Context, ws *workspace.Workspace) (*StatusRe
 	return result, nil
 }
 
+// ContainerIDs returns the IDs of the workspace's containers: the workspace
+// container for single-container workspaces, or every running service
+// container for compose workspaces. It returns *ErrNoContainer when there
+// are none.
+func (e *Engine) ContainerIDs(ctx context.Context, ws *workspace.Workspace) ([]string, error) {
+	stored, _ := e.store.LoadResult(ws.ID)
+	if cfg := storedComposeConfig(stored); cfg != nil {
+		if e.compose == nil {
+			return nil, &ErrComposeNotAvailable{}
+		}
+		inv := newComposeInvocation(ws, cfg, stored.WorkspaceFolder)
+		ids, err := e.compose.ListContainers(ctx, inv.projectName, inv.files, inv.env)
+		if err != nil {
+			return nil, err
+		}
+		if len(ids) == 0 {
+			return nil, &ErrNoContainer{WorkspaceID: ws.ID}
+		}
+		return ids, nil
+	}
+
+	container, err := e.driver.FindContainer(ctx, ws.ID)
+	if err != nil {
+		return nil, fmt.Errorf("finding container: %w", err)
+	}
+	if container == nil {
+		return nil, &ErrNoContainer{WorkspaceID: ws.ID}
+	}
+	return []string{container.ID}, nil
+}
+
+// WaitReady polls the workspace container every interval until it is running
+// and, if it defines a healthcheck, healthy. It returns the ready container,
+// or *ErrWaitTimeout once ctx is done. Set a deadline on ctx to bound the wait.
+func (e *Engine) WaitReady(ctx context.Context, ws *workspace.Workspace, interval time.Duration) (*driver.ContainerDetails, error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	timeout := &ErrWaitTimeout{WorkspaceID: ws.ID}
+	for {
+		container, err := e.driver.FindContainer(ctx, ws.ID)
+		if err != nil && ctx.Err() == nil {
+			return nil, err
+		}
+		if container != nil && container.State.IsReady() {
+			return container, nil
+		}
+		if err == nil {
+			timeout.Status, timeout.Health = "", ""
+			if container != nil {
+				timeout.Status, timeout.Health = container.State.Status, container.State.Health
+			}
+			e.logger.Debug("waiting for container", "status", timeout.Status, "health", timeout.Health)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, timeout
+		case <-ticker.C:
+		}
+	}
+}
+
 // cleanupWorkspaceImages removes the build image and any remaining labeled
 // images for a workspace. Best-effort: failures are logged, not returned.
 func (e *Engine) cleanupWorkspaceImages(ctx context.Context, wsID string) {
@@ -743,8 +1395,9 @@ func (e *Engine) cleanupWorkspaceImages(ctx context.Context, wsID string) {
 }
 
 // RequireRunningContainer finds the container for the workspace and returns it
-// if it is running. Returns ErrNoContainer if no container exists, or
-// ErrContainerStopped if the container exists but is not running.
+// if it is running. Returns ErrNoContainer if no container exists,
+// ErrContainerPaused if it is paused, or ErrContainerStopped if it exists but
+// is not running.
 func (e *Engine) RequireRunningContainer(ctx context.Context, ws *workspace.Workspace) (*driver.ContainerDetails, error) {
 	container, err := e.driver.FindContainer(ctx, ws.ID)
 	if err != nil {
@@ -753,16 +1406,58 @@ func (e *Engine) RequireRunningContainer(ctx context.Context, ws *workspace.Work
 	if container == nil {
 		return nil, &ErrNoContainer{WorkspaceID: ws.ID}
 	}
+	if container.State.IsPaused() {
+		return nil, &ErrContainerPaused{WorkspaceID: ws.ID, ContainerID: container.ID}
+	}
 	if !container.State.IsRunning() {
 		return nil, &ErrContainerStopped{WorkspaceID: ws.ID, ContainerID: container.ID}
 	}
 	return container, nil
 }
 
+// EnsureRunningContainer is like RequireRunningContainer, but a stopped
+// container is started through the same resume flow as Up (postStartCommand
+// and postAttachCommand run) before it is returned. A workspace without a
+// container still returns ErrNoContainer, and a paused one ErrContainerPaused;
+// nothing is created or unpaused.
+func (e *Engine) EnsureRunningContainer(ctx context.Context, ws *workspace.Workspace) (*driver.ContainerDetails, error) {
+	container, err := e.RequireRunningContainer(ctx, ws)
+	var stopped *ErrContainerStopped
+	if !errors.As(err, &stopped) {
+		return container, err
+	}
+
+	e.logger.Debug("starting stopped container", "workspace", ws.ID, "containerID", stopped.ContainerID)
+	if _, err := e.Up(ctx, ws, UpOptions{}); err != nil {
+		return nil, err
+	}
+	return e.RequireRunningContainer(ctx, ws)
+}
+
+// CreateWorkdir creates dir in the container with `mkdir -p` as user, so a
+// command can run there and the directory ends up owned by them.
+func (e *Engine) CreateWorkdir(ctx context.Context, ws *workspace.Workspace, containerID, user, dir string) error {
+	var stderr bytes.Buffer
+	if err := e.driver.ExecContainer(ctx, ws.ID, containerID, []string{"mkdir", "-p", dir}, nil, io.Discard, &stderr, nil, user, ""); err != nil {
+		return fmt.Errorf("creating working directory %s: %w: %s", dir, err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
 // storedComposeConfig returns the stored DevContainerConfig if it is a compose
 // workspace, or nil otherwise. Returns nil when result is nil, MergedConfig is
 // missing, JSON is malformed, or DockerComposeFile is empty.
 func storedComposeConfig(result *workspace.Result) *config.DevContainerConfig {
+	cfg := storedConfig(result)
+	if cfg == nil || len(cfg.DockerComposeFile) == 0 {
+		return nil
+	}
+	return cfg
+}
+
+// storedConfig returns the DevContainerConfig stored by the last up, or nil
+// when result is nil, MergedConfig is missing or the JSON is malformed.
+func storedConfig(result *workspace.Result) *config.DevContainerConfig {
 	if result == nil {
 		return nil
 	}
@@ -770,9 +1465,6 @@ func storedComposeConfig(result *workspace.Result) *config.DevContainerConfig {
 	if err := json.Unmarshal(result.MergedConfig, &cfg); err != nil {
 		return nil
 	}
-	if len(cfg.DockerComposeFile) == 0 {
-		return nil
-	}
 	return &cfg
 }
 
@@ -787,6 +1479,9 @@ func (e *Engine) parseAndSubstitute(ws *workspace.Workspace) (*config.DevContain
 	if err != nil {
 		return nil, "", fmt.Errorf("parsing devcontainer config: %w", err)
 	}
+	if e.workspaceFolder != "" {
+		cfg.WorkspaceFolder = e.workspaceFolder
+	}
 
 	workspaceFolder := resolveWorkspaceFolder(cfg, ws.Source)
 	// Pre-expand local-path variables in workspaceFolder so the substitution
@@ -811,9 +1506,33 @@ func (e *Engine) parseAndSubstitute(ws *workspace.Workspace) (*config.DevContain
 	// other variables (e.g. ${devcontainerId}).
 	workspaceFolder = resolveWorkspaceFolder(cfg, ws.Source)
 
+	if err := applyComposeExtension(ws, cfg); err != nil {
+		return nil, "", err
+	}
+
 	return cfg, workspaceFolder, nil
 }
 
+// applyComposeExtension fills service and runServices from the x-crib block
+// of the compose files when devcontainer.json leaves them unset, so crib
+// settings can live next to the compose definition.
+func applyComposeExtension(ws *workspace.Workspace, cfg *config.DevContainerConfig) error {
+	if len(cfg.DockerComposeFile) == 0 || (cfg.Service != "" && len(cfg.RunServices) > 0) {
+		return nil
+	}
+	ext, err := compose.ReadCribExtension(resolveComposeFiles(configDir(ws), cfg.DockerComposeFile))
+	if err != nil {
+		return err
+	}
+	if cfg.Service == "" {
+		cfg.Service = ext.Service
+	}
+	if len(cfg.RunServices) == 0 {
+		cfg.RunServices = ext.RunServices
+	}
+	return nil
+}
+
 // configRemoteUser returns remoteUser from config, falling back to
 // containerUser. Returns empty string if neither is set.
 func configRemoteUser(cfg *config.DevContainerConfig) string {
@@ -855,6 +1574,20 @@ func resolveComposeFiles(cd string, paths []string) []string {
 	return files
 }
 
+// composeProjectName returns the compose project name for the workspace.
+// customizations.crib.composeProjectName replaces the default crib-<id>,
+// e.g. to keep two checkouts apart; it is normalized to the characters
+// compose allows. COMPOSE_PROJECT_NAME still wins, as it does for compose.
+func composeProjectName(ws *workspace.Workspace, cfg *config.DevContainerConfig) string {
+	if os.Getenv("COMPOSE_PROJECT_NAME") == "" {
+		name, _ := extractCribCustomizations(cfg)["composeProjectName"].(string)
+		if name = loader.NormalizeProjectName(name); name != "" {
+			return name
+		}
+	}
+	return compose.ProjectName(ws.ID)
+}
+
 // devcontainerEnv builds the devcontainer variable env slice for passing to
 // docker compose subprocesses so ${VAR} references in compose files resolve.
 func devcontainerEnv(workspaceID, localFolder, containerFolder string) []string {
@@ -6,9 +6,14 @@ import (
 	"errors"
 	"io"
 	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/fgrehm/crib/internal/compose"
 	"github.com/fgrehm/crib/internal/config"
 	"github.com/fgrehm/crib/internal/driver"
 	"github.com/fgrehm/crib/internal/workspace"
@@ -129,6 +134,17 @@ func TestRemove_ComposeMissing_ReturnsError(t *testing.T) {
 	}
 }
 
+func TestUp_ComposeServiceMissing(t *testing.T) {
+	e, ws := newUpTimeoutTestEngine(t, &mockDriver{}, `{"dockerComposeFile": "compose.yml"}`)
+	e.compose = compose.NewHelperFromRuntime("docker")
+
+	_, err := e.Up(context.Background(), ws, UpOptions{})
+	var target *ErrComposeServiceMissing
+	if !errors.As(err, &target) {
+		t.Errorf("expected ErrComposeServiceMissing, got: %v", err)
+	}
+}
+
 func TestDown_ClearsHookMarkers(t *testing.T) {
 	store := workspace.NewStoreAt(t.TempDir())
 
@@ -143,7 +159,7 @@ func TestDown_ClearsHookMarkers(t *testing.T) {
 
 	// Create hook markers.
 	for _, hook := range []string{"onCreateCommand", "updateContentCommand", "postCreateCommand"} {
-		if err := store.MarkHookDone(ws.ID, hook); err != nil {
+		if err := store.MarkHookDone(ws.ID, hook, ""); err != nil {
 			t.Fatal(err)
 		}
 	}
@@ -188,7 +204,7 @@ func TestStop_PreservesHookMarkers(t *testing.T) {
 
 	// Create hook markers.
 	for _, hook := range []string{"onCreateCommand", "updateContentCommand", "postCreateCommand"} {
-		if err := store.MarkHookDone(ws.ID, hook); err != nil {
+		if err := store.MarkHookDone(ws.ID, hook, ""); err != nil {
 			t.Fatal(err)
 		}
 	}
@@ -283,6 +299,163 @@ func TestStop_AlreadyStopped(t *testing.T) {
 	}
 }
 
+// stopTimeoutDriver records the timeout StopContainer was called with.
+type stopTimeoutDriver struct {
+	fixedFindContainerDriver
+	stopped bool
+	timeout *time.Duration
+}
+
+func (m *stopTimeoutDriver) StopContainer(_ context.Context, _, _ string, timeout *time.Duration) error {
+	m.stopped = true
+	m.timeout = timeout
+	return nil
+}
+
+func TestStop_Timeout(t *testing.T) {
+	tests := []struct {
+		name   string
+		flag   *time.Duration
+		stored string
+		want   string
+	}{
+		{name: "runtime default", stored: `{"image": "alpine"}`},
+		{name: "flag", flag: new(45 * time.Second), stored: `{"image": "alpine"}`, want: "45s"},
+		{name: "config", stored: `{"image": "alpine", "customizations": {"crib": {"stopTimeout": 120}}}`, want: "2m0s"},
+		{name: "flag wins over config", flag: new(5 * time.Second), stored: `{"image": "alpine", "customizations": {"crib": {"stopTimeout": 120}}}`, want: "5s"},
+		{name: "invalid config ignored", stored: `{"image": "alpine", "customizations": {"crib": {"stopTimeout": "soon"}}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := workspace.NewStoreAt(t.TempDir())
+			ws := &workspace.Workspace{ID: "test-stop-timeout", Source: t.TempDir()}
+			if err := store.Save(ws); err != nil {
+				t.Fatal(err)
+			}
+			if err := store.SaveResult(ws.ID, &workspace.Result{MergedConfig: []byte(tt.stored)}); err != nil {
+				t.Fatal(err)
+			}
+			drv := &stopTimeoutDriver{}
+			drv.container = &driver.ContainerDetails{ID: "abc123", State: driver.ContainerState{Status: "running"}}
+			e := &Engine{driver: drv, store: store, logger: slog.Default(), stdout: io.Discard, stderr: io.Discard}
+			if tt.flag != nil {
+				if err := e.SetStopTimeout(*tt.flag); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := e.Stop(context.Background(), ws); err != nil {
+				t.Fatalf("Stop: %v", err)
+			}
+			if !drv.stopped {
+				t.Fatal("StopContainer was not called")
+			}
+			got := ""
+			if drv.timeout != nil {
+				got = drv.timeout.String()
+			}
+			if got != tt.want {
+				t.Errorf("stop timeout = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStop_Compose_Timeout(t *testing.T) {
+	store := workspace.NewStoreAt(t.TempDir())
+	ws := &workspace.Workspace{ID: "test-stop-compose-timeout", Source: t.TempDir()}
+	if err := store.Save(ws); err != nil {
+		t.Fatal(err)
+	}
+	composeWorkspaceResult(t, store, ws.ID)
+
+	// "echo" stands in for the runtime so the compose invocation is written
+	// to stdout instead of executed.
+	var stdout bytes.Buffer
+	e := &Engine{
+		driver:  &mockDriver{},
+		store:   store,
+		compose: compose.NewHelperFromRuntime("echo"),
+		logger:  slog.Default(),
+		verbose: true,
+		stdout:  &stdout,
+		stderr:  io.Discard,
+	}
+	if err := e.SetStopTimeout(30 * time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := e.Stop(context.Background(), ws); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	if !strings.HasSuffix(strings.TrimSpace(stdout.String()), "stop -t 30") {
+		t.Errorf("compose args = %q, want trailing \"stop -t 30\"", stdout.String())
+	}
+}
+
+func TestSetStopTimeout_Negative(t *testing.T) {
+	e := &Engine{}
+	if err := e.SetStopTimeout(-time.Second); err == nil {
+		t.Error("SetStopTimeout(-1s) should fail")
+	}
+	if e.stopTimeout != nil {
+		t.Errorf("stopTimeout = %v, want unset after an invalid value", *e.stopTimeout)
+	}
+}
+
+func TestShutdown_ComposeRoutesOnShutdownAction(t *testing.T) {
+	tests := []struct {
+		name           string
+		shutdownAction string
+		wantCmd        string
+		wantMarkers    bool
+	}{
+		{"default removes", "", "down", false},
+		{"stopContainer removes", "stopContainer", "down", false},
+		{"stopCompose stops", "stopCompose", "stop", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			argsLog := filepath.Join(dir, "args.log")
+			script := filepath.Join(dir, "fake-compose")
+			body := "#!/bin/sh\necho \"$@\" >> " + argsLog + "\n"
+			if err := os.WriteFile(script, []byte(body), 0o755); err != nil {
+				t.Fatal(err)
+			}
+
+			ws := &workspace.Workspace{ID: "test-shutdown", Source: t.TempDir(), DevContainerPath: ".devcontainer/devcontainer.json"}
+			e := newComposeTestEngine(t, script, ws)
+			e.logger = slog.Default()
+			e.stdout, e.stderr = io.Discard, io.Discard
+
+			merged := `{"dockerComposeFile":["docker-compose.yml"],"service":"app","shutdownAction":"` + tt.shutdownAction + `"}`
+			if err := e.store.SaveResult(ws.ID, &workspace.Result{MergedConfig: []byte(merged)}); err != nil {
+				t.Fatal(err)
+			}
+			if err := e.store.MarkHookDone(ws.ID, "onCreateCommand", ""); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := e.Shutdown(context.Background(), ws); err != nil {
+				t.Fatalf("Shutdown: %v", err)
+			}
+
+			data, err := os.ReadFile(argsLog)
+			if err != nil {
+				t.Fatal(err)
+			}
+			args := strings.Fields(string(data))
+			if len(args) == 0 || args[len(args)-1] != tt.wantCmd {
+				t.Errorf("compose args = %q, want trailing %q", strings.TrimSpace(string(data)), tt.wantCmd)
+			}
+			if got := e.store.IsHookDone(ws.ID, "onCreateCommand"); got != tt.wantMarkers {
+				t.Errorf("onCreateCommand marker present = %v, want %v", got, tt.wantMarkers)
+			}
+		})
+	}
+}
+
 func TestRemove_DeletesWorkspaceState(t *testing.T) {
 	store := workspace.NewStoreAt(t.TempDir())
 
@@ -384,6 +557,179 @@ func TestRequireRunningContainer_Stopped(t *testing.T) {
 	}
 }
 
+func TestRequireRunningContainer_Paused(t *testing.T) {
+	store := workspace.NewStoreAt(t.TempDir())
+	ws := &workspace.Workspace{ID: "ws-4", Source: t.TempDir()}
+	drv := &fixedFindContainerDriver{
+		container: &driver.ContainerDetails{ID: "abc123", State: driver.ContainerState{Status: "paused"}},
+	}
+	eng := &Engine{driver: drv, store: store, logger: slog.Default()}
+
+	_, err := eng.RequireRunningContainer(context.Background(), ws)
+	var target *ErrContainerPaused
+	if !errors.As(err, &target) {
+		t.Fatalf("expected ErrContainerPaused, got: %v", err)
+	}
+	if !strings.Contains(err.Error(), "crib unpause") {
+		t.Errorf("error = %q, want a hint to run crib unpause", err)
+	}
+}
+
+// startableDriver reports a container that is stopped until StartContainer
+// is called.
+type startableDriver struct {
+	mockDriver
+	container  *driver.ContainerDetails
+	startCalls int
+	runCalls   int
+}
+
+func (m *startableDriver) RunContainer(_ context.Context, _ string, _ *driver.RunOptions) (string, error) {
+	m.runCalls++
+	return "new-container", nil
+}
+
+func (m *startableDriver) FindContainer(_ context.Context, _ string) (*driver.ContainerDetails, error) {
+	return m.container, nil
+}
+
+func (m *startableDriver) StartContainer(_ context.Context, _, _ string) error {
+	m.startCalls++
+	m.container.State.Status = "running"
+	return nil
+}
+
+func TestEnsureRunningContainer_StartsStopped(t *testing.T) {
+	source := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(source, ".devcontainer"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	cfgJSON := `{"image": "ruby:3.2", "remoteUser": "vscode", "postStartCommand": "echo postStart"}`
+	if err := os.WriteFile(filepath.Join(source, ".devcontainer", "devcontainer.json"), []byte(cfgJSON), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	store := workspace.NewStoreAt(t.TempDir())
+	ws := &workspace.Workspace{ID: "ws-ensure", Source: source, DevContainerPath: ".devcontainer/devcontainer.json"}
+	if err := store.Save(ws); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SaveResult(ws.ID, &workspace.Result{ImageName: "ruby:3.2", ContainerID: "c-1", RemoteUser: "vscode"}); err != nil {
+		t.Fatal(err)
+	}
+
+	drv := &startableDriver{container: &driver.ContainerDetails{ID: "c-1", State: driver.ContainerState{Status: "exited"}}}
+	eng := &Engine{
+		driver:      drv,
+		store:       store,
+		runtimeName: "docker",
+		logger:      slog.Default(),
+		stdout:      io.Discard,
+		stderr:      io.Discard,
+		progress:    func(ProgressEvent) {},
+	}
+
+	container, err := eng.EnsureRunningContainer(context.Background(), ws)
+	if err != nil {
+		t.Fatalf("EnsureRunningContainer: %v", err)
+	}
+	if container.ID != "c-1" || !container.State.IsRunning() {
+		t.Errorf("container = %+v, want running c-1", container)
+	}
+	if drv.startCalls != 1 {
+		t.Errorf("StartContainer calls = %d, want 1", drv.startCalls)
+	}
+	var ranPostStart bool
+	for _, call := range drv.execCalls {
+		ranPostStart = ranPostStart || strings.Contains(strings.Join(call.cmd, " "), "echo postStart")
+	}
+	if !ranPostStart {
+		t.Error("postStartCommand should run when a stopped container is started")
+	}
+}
+
+func TestEnsureRunningContainer_NoContainer(t *testing.T) {
+	store := workspace.NewStoreAt(t.TempDir())
+	ws := &workspace.Workspace{ID: "ws-ensure-none", Source: t.TempDir()}
+	if err := store.Save(ws); err != nil {
+		t.Fatal(err)
+	}
+	drv := &startableDriver{}
+	eng := &Engine{driver: drv, store: store, logger: slog.Default()}
+
+	_, err := eng.EnsureRunningContainer(context.Background(), ws)
+	var target *ErrNoContainer
+	if !errors.As(err, &target) {
+		t.Fatalf("expected ErrNoContainer, got: %v", err)
+	}
+	if drv.runCalls != 0 {
+		t.Error("EnsureRunningContainer must not create a container")
+	}
+}
+
+func TestEnsureRunningContainer_Paused(t *testing.T) {
+	store := workspace.NewStoreAt(t.TempDir())
+	ws := &workspace.Workspace{ID: "ws-ensure-paused", Source: t.TempDir()}
+	drv := &startableDriver{container: &driver.ContainerDetails{ID: "c-1", State: driver.ContainerState{Status: "paused"}}}
+	eng := &Engine{driver: drv, store: store, logger: slog.Default()}
+
+	_, err := eng.EnsureRunningContainer(context.Background(), ws)
+	var target *ErrContainerPaused
+	if !errors.As(err, &target) {
+		t.Fatalf("expected ErrContainerPaused, got: %v", err)
+	}
+	if drv.startCalls != 0 {
+		t.Error("EnsureRunningContainer must not start a paused container")
+	}
+}
+
+func TestUp_PausedContainer(t *testing.T) {
+	e, ws := newUpTimeoutTestEngine(t, &startableDriver{
+		container: &driver.ContainerDetails{ID: "c-1", State: driver.ContainerState{Status: "paused"}},
+	}, `{"image": "alpine"}`)
+
+	_, err := e.Up(context.Background(), ws, UpOptions{})
+	var target *ErrContainerPaused
+	if !errors.As(err, &target) {
+		t.Fatalf("expected ErrContainerPaused, got: %v", err)
+	}
+}
+
+// workdirDriver records each exec'd command with its user, failing with
+// err when it is set.
+type workdirDriver struct {
+	mockDriver
+	err   error
+	calls []string // "user: cmd"
+}
+
+func (m *workdirDriver) ExecContainer(_ context.Context, _, _ string, cmd []string, _ io.Reader, _, stderr io.Writer, _ []string, user, _ string) error {
+	m.calls = append(m.calls, user+": "+strings.Join(cmd, " "))
+	if m.err != nil {
+		_, _ = io.WriteString(stderr, "mkdir: permission denied\n")
+	}
+	return m.err
+}
+
+func TestCreateWorkdir(t *testing.T) {
+	ws := &workspace.Workspace{ID: "ws-1"}
+	drv := &workdirDriver{}
+	eng := &Engine{driver: drv, logger: slog.Default()}
+
+	if err := eng.CreateWorkdir(context.Background(), ws, "c-1", "node", "/data/out"); err != nil {
+		t.Fatalf("CreateWorkdir: %v", err)
+	}
+	if want := []string{"node: mkdir -p /data/out"}; !slices.Equal(drv.calls, want) {
+		t.Errorf("exec calls = %v, want %v", drv.calls, want)
+	}
+
+	drv.err = errors.New("exit status 1")
+	err := eng.CreateWorkdir(context.Background(), ws, "c-1", "node", "/data/out")
+	if err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Errorf("CreateWorkdir error = %v, want mkdir's stderr", err)
+	}
+}
+
 func TestEnsureContainerRunning_Running(t *testing.T) {
 	eng := &Engine{driver: &mockDriver{}, logger: slog.Default()}
 
@@ -594,3 +940,136 @@ func TestNewComposeInvocation_IncludesService(t *testing.T) {
 		t.Errorf("inv.service = %q, want %q", inv.service, "rails-app")
 	}
 }
+
+func TestContainerIDs_Single(t *testing.T) {
+	e, _, ws := newPauseTestEngine(t, "exited")
+
+	ids, err := e.ContainerIDs(context.Background(), ws)
+	if err != nil {
+		t.Fatalf("ContainerIDs: %v", err)
+	}
+	if !slices.Equal(ids, []string{"abc123"}) {
+		t.Errorf("ids = %v, want [abc123]", ids)
+	}
+}
+
+func TestContainerIDs_NoContainer(t *testing.T) {
+	e, _, ws := newPauseTestEngine(t, "")
+
+	_, err := e.ContainerIDs(context.Background(), ws)
+	var target *ErrNoContainer
+	if !errors.As(err, &target) {
+		t.Errorf("expected ErrNoContainer, got: %v", err)
+	}
+}
+
+func TestContainerIDs_Compose(t *testing.T) {
+	e, _, ws := newPauseTestEngine(t, "running")
+	composeWorkspaceResult(t, e.store, ws.ID)
+	runtime, _ := fakeComposeRuntime(t)
+	e.compose = compose.NewHelperFromRuntime(runtime)
+
+	ids, err := e.ContainerIDs(context.Background(), ws)
+	if err != nil {
+		t.Fatalf("ContainerIDs: %v", err)
+	}
+	if !slices.Equal(ids, []string{"c-app", "c-db"}) {
+		t.Errorf("ids = %v, want [c-app c-db]", ids)
+	}
+}
+
+func TestContainerIDs_ComposeNothingRunning(t *testing.T) {
+	e, _, ws := newPauseTestEngine(t, "")
+	composeWorkspaceResult(t, e.store, ws.ID)
+	// "true" stands in for the runtime: compose ps -q succeeds with no output.
+	e.compose = compose.NewHelperFromRuntime("true")
+
+	_, err := e.ContainerIDs(context.Background(), ws)
+	var target *ErrNoContainer
+	if !errors.As(err, &target) {
+		t.Errorf("expected ErrNoContainer, got: %v", err)
+	}
+}
+
+// sequenceFindContainerDriver returns the next container state on each
+// FindContainer call, repeating the last one once the sequence is exhausted.
+type sequenceFindContainerDriver struct {
+	mockDriver
+	states []*driver.ContainerDetails
+	calls  int
+}
+
+func (m *sequenceFindContainerDriver) FindContainer(_ context.Context, _ string) (*driver.ContainerDetails, error) {
+	i := min(m.calls, len(m.states)-1)
+	m.calls++
+	return m.states[i], nil
+}
+
+func withState(status, health string) *driver.ContainerDetails {
+	return &driver.ContainerDetails{ID: "abc123", State: driver.ContainerState{Status: status, Health: health}}
+}
+
+func TestWaitReady_TransitionsToHealthy(t *testing.T) {
+	drv := &sequenceFindContainerDriver{states: []*driver.ContainerDetails{
+		nil,
+		withState("created", ""),
+		withState("running", "starting"),
+		withState("running", "healthy"),
+	}}
+	e := &Engine{driver: drv, logger: slog.Default()}
+
+	got, err := e.WaitReady(context.Background(), &workspace.Workspace{ID: "ws-1"}, time.Millisecond)
+	if err != nil {
+		t.Fatalf("WaitReady: %v", err)
+	}
+	if got.State.Health != "healthy" {
+		t.Errorf("returned health = %q, want healthy", got.State.Health)
+	}
+	if drv.calls != 4 {
+		t.Errorf("FindContainer calls = %d, want 4", drv.calls)
+	}
+}
+
+func TestWaitReady_RunningWithoutHealthcheck(t *testing.T) {
+	drv := &sequenceFindContainerDriver{states: []*driver.ContainerDetails{withState("running", "")}}
+	e := &Engine{driver: drv, logger: slog.Default()}
+
+	if _, err := e.WaitReady(context.Background(), &workspace.Workspace{ID: "ws-1"}, time.Hour); err != nil {
+		t.Fatalf("WaitReady: %v", err)
+	}
+}
+
+func TestWaitReady_Timeout(t *testing.T) {
+	drv := &sequenceFindContainerDriver{states: []*driver.ContainerDetails{withState("running", "unhealthy")}}
+	e := &Engine{driver: drv, logger: slog.Default()}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := e.WaitReady(ctx, &workspace.Workspace{ID: "ws-1"}, time.Millisecond)
+
+	var timeout *ErrWaitTimeout
+	if !errors.As(err, &timeout) {
+		t.Fatalf("expected *ErrWaitTimeout, got %T: %v", err, err)
+	}
+	if timeout.Status != "running" || timeout.Health != "unhealthy" {
+		t.Errorf("last state = %s/%s, want running/unhealthy", timeout.Status, timeout.Health)
+	}
+}
+
+func TestSaveResult_StoresConfigName(t *testing.T) {
+	store := workspace.NewStoreAt(t.TempDir())
+	ws := &workspace.Workspace{ID: "ws-name", Source: "/home/user/project"}
+	e := &Engine{store: store, logger: slog.Default()}
+
+	cfg := &config.DevContainerConfig{}
+	cfg.Name = "Payments API"
+	e.saveResult(ws, cfg, &UpResult{ContainerID: "c-1"})
+
+	stored, err := store.LoadResult(ws.ID)
+	if err != nil || stored == nil {
+		t.Fatalf("LoadResult: %v, %v", stored, err)
+	}
+	if stored.Name != "Payments API" {
+		t.Errorf("Name = %q, want %q", stored.Name, "Payments API")
+	}
+}
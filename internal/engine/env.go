@@ -1,6 +1,7 @@
 package engine
 
 import (
+	"fmt"
 	"maps"
 	"strings"
 
@@ -22,6 +23,50 @@ func parseEnvLines(output string) map[string]string {
 	return env
 }
 
+// parseLabelFile parses a --label-file: KEY=VALUE lines as read by
+// parseEnvLines, with blank lines and # comments skipped. Keys in the crib.
+// namespace are rejected.
+func parseLabelFile(content string) (map[string]string, error) {
+	var lines []string
+	for line := range strings.SplitSeq(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	labels := parseEnvLines(strings.Join(lines, "\n"))
+	for k := range labels {
+		if strings.HasPrefix(k, "crib.") {
+			return nil, fmt.Errorf("label %q: the crib. prefix is reserved", k)
+		}
+	}
+	return labels, nil
+}
+
+// parseEtcEnvironment parses /etc/environment content as pam_env reads it:
+// KEY=VALUE lines, optionally prefixed with "export", with values optionally
+// wrapped in matching quotes. Blank lines and # comments are skipped.
+func parseEtcEnvironment(content string) map[string]string {
+	env := make(map[string]string)
+	for line := range strings.SplitSeq(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimSpace(strings.TrimPrefix(line, "export "))
+		k, v, ok := strings.Cut(line, "=")
+		if !ok || k == "" || strings.ContainsAny(k, " \t") {
+			continue
+		}
+		if len(v) >= 2 && (v[0] == '"' || v[0] == '\'') && v[len(v)-1] == v[0] {
+			v = v[1 : len(v)-1]
+		}
+		env[k] = v
+	}
+	return env
+}
+
 // envMap returns the current process environment as a map.
 func envMap() map[string]string {
 	return config.EnvMap()
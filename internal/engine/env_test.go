@@ -1,6 +1,7 @@
 package engine
 
 import (
+	"maps"
 	"sort"
 	"testing"
 )
@@ -116,3 +117,28 @@ func TestFilterProbedEnv_SkipsNoisyHostVars(t *testing.T) {
 		t.Errorf("HOME should be included, got %q", result["HOME"])
 	}
 }
+
+func TestParseLabelFile(t *testing.T) {
+	got, err := parseLabelFile(`# provenance
+org.opencontainers.image.revision=abc123
+  ci.pipeline=https://ci.example.com/runs/42?a=b
+
+no-value-line
+ci.empty=
+`)
+	if err != nil {
+		t.Fatalf("parseLabelFile: %v", err)
+	}
+	want := map[string]string{
+		"org.opencontainers.image.revision": "abc123",
+		"ci.pipeline":                       "https://ci.example.com/runs/42?a=b",
+		"ci.empty":                          "",
+	}
+	if !maps.Equal(got, want) {
+		t.Errorf("parseLabelFile = %v, want %v", got, want)
+	}
+
+	if _, err := parseLabelFile("crib.workspace=other\n"); err == nil {
+		t.Error("expected error for a label in the crib. namespace")
+	}
+}
@@ -1,6 +1,10 @@
 package engine
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+	"time"
+)
 
 // ErrNoContainer is returned when a workspace operation requires a container
 // but none exists.
@@ -23,10 +27,119 @@ func (e *ErrContainerStopped) Error() string {
 	return "container is stopped (run 'crib up' to start it)"
 }
 
+// ErrContainerPaused is returned when a workspace operation requires a running
+// container but the container is paused.
+type ErrContainerPaused struct {
+	WorkspaceID string
+	ContainerID string
+}
+
+func (e *ErrContainerPaused) Error() string {
+	return "container is paused (run 'crib unpause' to resume it)"
+}
+
+// ErrWaitTimeout is returned by WaitReady when the container does not become
+// ready before the deadline. Status and Health describe the last observed
+// state ("" when no container was found).
+type ErrWaitTimeout struct {
+	WorkspaceID string
+	Status      string
+	Health      string
+}
+
+func (e *ErrWaitTimeout) Error() string {
+	switch {
+	case e.Status == "":
+		return fmt.Sprintf("timed out waiting for workspace %s: no container found", e.WorkspaceID)
+	case e.Health != "":
+		return fmt.Sprintf("timed out waiting for workspace %s: container is %s (%s)", e.WorkspaceID, e.Status, e.Health)
+	default:
+		return fmt.Sprintf("timed out waiting for workspace %s: container is %s", e.WorkspaceID, e.Status)
+	}
+}
+
+// ErrWaitForTimeout is returned when the lifecycle hooks up to the waitFor
+// stage do not finish within the waitFor timeout and the timeout is set to
+// fail. Stage is the stage that was running when it expired.
+type ErrWaitForTimeout struct {
+	Stage   string
+	WaitFor string
+	Timeout time.Duration
+}
+
+func (e *ErrWaitForTimeout) Error() string {
+	return fmt.Sprintf("%s did not finish within the %s waitFor timeout (waitFor: %s)", e.Stage, e.Timeout, e.WaitFor)
+}
+
+// ErrContainerExited is returned by Foreground and Attach when the
+// container's main process exits with a non-zero code.
+type ErrContainerExited struct {
+	WorkspaceID string
+	ExitCode    int
+}
+
+func (e *ErrContainerExited) Error() string {
+	return fmt.Sprintf("container for workspace %s exited with code %d", e.WorkspaceID, e.ExitCode)
+}
+
+// ErrUpTimeout is returned by Up when the operation does not finish within
+// UpOptions.Timeout. Err is the failure observed when the deadline hit.
+type ErrUpTimeout struct {
+	WorkspaceID string
+	Timeout     time.Duration
+	Err         error
+}
+
+func (e *ErrUpTimeout) Error() string {
+	return fmt.Sprintf("up for workspace %s timed out after %s: %v", e.WorkspaceID, e.Timeout, e.Err)
+}
+
+func (e *ErrUpTimeout) Unwrap() error {
+	return e.Err
+}
+
+// ErrUntrusted is returned when the user declines the trust prompt for a
+// workspace whose config requests dangerous settings.
+type ErrUntrusted struct {
+	WorkspaceID string
+	Settings    []string
+}
+
+func (e *ErrUntrusted) Error() string {
+	return fmt.Sprintf("workspace %s was not trusted to use %s (rerun with --trust to allow)", e.WorkspaceID, strings.Join(e.Settings, ", "))
+}
+
 // ErrComposeNotAvailable is returned when an operation requires docker compose
 // or podman compose but neither is installed.
 type ErrComposeNotAvailable struct{}
 
 func (e *ErrComposeNotAvailable) Error() string {
-	return "compose is not available (install docker compose or podman compose)"
+	return "compose is not available (install the docker compose plugin, docker-compose v2 or podman compose; run with --debug for details)"
+}
+
+// ErrComposeServiceMissing is returned when dockerComposeFile is set but no
+// service was named in devcontainer.json or an x-crib block.
+type ErrComposeServiceMissing struct{}
+
+func (e *ErrComposeServiceMissing) Error() string {
+	return "dockerComposeFile is set but service is not specified (set it in devcontainer.json or under x-crib in a compose file)"
+}
+
+// ErrPortInUse is returned when a host port published from forwardPorts or
+// appPort is already taken on the host and auto-port is off, or the port's
+// portsAttributes set requireLocalPort.
+type ErrPortInUse struct {
+	Spec             string // publish spec, e.g. "8080:8080"
+	HostPort         int
+	Protocol         string
+	RequireLocalPort bool // the port may not move to another host port
+}
+
+func (e *ErrPortInUse) Error() string {
+	if e.RequireLocalPort {
+		return fmt.Sprintf("host port %d/%s for %q is already in use and portsAttributes requires it (stop whatever is using it)",
+			e.HostPort, e.Protocol, e.Spec)
+	}
+	return fmt.Sprintf("host port %d/%s for %q is already in use (stop whatever is using it, change forwardPorts/appPort, or rerun with --auto-port to pick a free port)",
+		e.HostPort, e.Protocol, e.Spec)
 }
@@ -0,0 +1,74 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/google/go-containerregistry/pkg/name"
+
+	ocidriver "github.com/fgrehm/crib/internal/driver/oci"
+	"github.com/fgrehm/crib/internal/workspace"
+)
+
+// ExportOptions controls Export. Exactly one of Tag and Output must be set.
+type ExportOptions struct {
+	// Tag commits the container as an image with this reference.
+	Tag string
+
+	// Output writes the container's filesystem as a tar archive to this
+	// host path instead.
+	Output string
+}
+
+// Validate checks that exactly one of Tag and Output is set, that Tag is a
+// valid image reference and that Output names a file in an existing
+// directory.
+func (o ExportOptions) Validate() error {
+	if (o.Tag == "") == (o.Output == "") {
+		return errors.New("specify either an image tag or --output")
+	}
+	if o.Tag != "" {
+		if _, err := name.NewTag(o.Tag); err != nil {
+			return fmt.Errorf("invalid image tag %q: %w", o.Tag, err)
+		}
+		return nil
+	}
+	if fi, err := os.Stat(o.Output); err == nil && fi.IsDir() {
+		return fmt.Errorf("output %s is a directory", o.Output)
+	}
+	if fi, err := os.Stat(filepath.Dir(o.Output)); err != nil || !fi.IsDir() {
+		return fmt.Errorf("output directory %s does not exist", filepath.Dir(o.Output))
+	}
+	return nil
+}
+
+// Export snapshots the workspace container, running or stopped, either as an
+// image tagged opts.Tag or as a tar archive of its filesystem at
+// opts.Output. Committed images get crib's workspace labels blanked (a
+// commit cannot remove a label), which 'crib prune' and 'crib remove' take
+// to mean the image is not crib-managed.
+func (e *Engine) Export(ctx context.Context, ws *workspace.Workspace, opts ExportOptions) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	container, err := e.driver.FindContainer(ctx, ws.ID)
+	if err != nil {
+		return fmt.Errorf("finding container: %w", err)
+	}
+	if container == nil {
+		return &ErrNoContainer{WorkspaceID: ws.ID}
+	}
+
+	if opts.Output != "" {
+		return e.driver.ExportContainer(ctx, ws.ID, container.ID, opts.Output)
+	}
+
+	changes := []string{
+		"LABEL " + ocidriver.LabelWorkspace + "=",
+		"LABEL " + ocidriver.LabelHome + "=",
+	}
+	return e.driver.CommitContainer(ctx, ws.ID, container.ID, opts.Tag, changes)
+}
@@ -0,0 +1,105 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/fgrehm/crib/internal/driver"
+	"github.com/fgrehm/crib/internal/workspace"
+)
+
+// exportTrackingDriver records ExportContainer calls on top of the snapshot
+// mock's commit tracking.
+type exportTrackingDriver struct {
+	*snapshotMockDriver
+	exported map[string]string // containerID -> output
+}
+
+func (m *exportTrackingDriver) ExportContainer(_ context.Context, _, containerID, output string) error {
+	m.exported[containerID] = output
+	return nil
+}
+
+func newExportTestEngine(container *driver.ContainerDetails) (*Engine, *exportTrackingDriver) {
+	snap := newSnapshotMockDriver()
+	snap.findResult = container
+	d := &exportTrackingDriver{snapshotMockDriver: snap, exported: make(map[string]string)}
+	return &Engine{driver: d, logger: slog.Default()}, d
+}
+
+func TestExport_CommitsWithoutWorkspaceLabels(t *testing.T) {
+	eng, d := newExportTestEngine(&driver.ContainerDetails{ID: "c-1", State: driver.ContainerState{Status: "exited"}})
+	ws := &workspace.Workspace{ID: "ws-1"}
+
+	if err := eng.Export(context.Background(), ws, ExportOptions{Tag: "myapp:dev"}); err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	if d.committed["c-1"] != "myapp:dev" {
+		t.Errorf("committed = %v, want c-1 -> myapp:dev", d.committed)
+	}
+	want := []string{"LABEL crib.workspace=", "LABEL crib.home="}
+	if !slices.Equal(d.lastChanges, want) {
+		t.Errorf("changes = %v, want %v", d.lastChanges, want)
+	}
+}
+
+func TestExport_Output(t *testing.T) {
+	eng, d := newExportTestEngine(&driver.ContainerDetails{ID: "c-1"})
+	ws := &workspace.Workspace{ID: "ws-1"}
+	out := filepath.Join(t.TempDir(), "ws.tar")
+
+	if err := eng.Export(context.Background(), ws, ExportOptions{Output: out}); err != nil {
+		t.Fatalf("Export: %v", err)
+	}
+	if d.exported["c-1"] != out {
+		t.Errorf("exported = %v, want c-1 -> %s", d.exported, out)
+	}
+	if len(d.committed) != 0 {
+		t.Errorf("unexpected commit: %v", d.committed)
+	}
+}
+
+func TestExport_NoContainer(t *testing.T) {
+	eng, _ := newExportTestEngine(nil)
+	err := eng.Export(context.Background(), &workspace.Workspace{ID: "ws-1"}, ExportOptions{Tag: "myapp"})
+	var noContainer *ErrNoContainer
+	if !errors.As(err, &noContainer) {
+		t.Fatalf("expected ErrNoContainer, got %v", err)
+	}
+}
+
+func TestExportOptions_Validate(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "existing.tar"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		opts    ExportOptions
+		wantErr bool
+	}{
+		{"tag", ExportOptions{Tag: "myapp:dev"}, false},
+		{"tag without version", ExportOptions{Tag: "ghcr.io/org/myapp"}, false},
+		{"output", ExportOptions{Output: filepath.Join(dir, "ws.tar")}, false},
+		{"overwrite output", ExportOptions{Output: filepath.Join(dir, "existing.tar")}, false},
+		{"neither", ExportOptions{}, true},
+		{"both", ExportOptions{Tag: "myapp", Output: filepath.Join(dir, "ws.tar")}, true},
+		{"invalid tag", ExportOptions{Tag: "My App"}, true},
+		{"output is a directory", ExportOptions{Output: dir}, true},
+		{"missing output directory", ExportOptions{Output: filepath.Join(dir, "nope", "ws.tar")}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.opts.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
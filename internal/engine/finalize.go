@@ -18,6 +18,7 @@ type finalizeOpts struct {
 	storedResult            *workspace.Result               // non-nil for snapshot/stored resume
 	fromSnapshot            bool                            // true = restore env + resume hooks
 	skipVolumeChown         bool                            // true for restart (volumes exist)
+	alreadyRunning          bool                            // container was running before this run; skip postStart on resume
 	shouldMergeFeatureHooks bool                            // true when imageMetadata carries fresh feature
 	// lifecycle hooks that must be merged and stored.
 	// Set on first creation (build or image inspection) so
@@ -25,6 +26,7 @@ type finalizeOpts struct {
 	// resume paths that restore stored hooks.
 	imageMetadata []*config.ImageMetadata // metadata for user inference and hook merging
 	imageUser     string                  // Config.User from image inspect (Dockerfile USER fallback)
+	ports         []string                // published specs from createContainer; nil = from config
 }
 
 // finalize runs post-creation/post-restart steps: plugin file copies, volume
@@ -76,13 +78,17 @@ func (e *Engine) finalize(ctx context.Context, ws *workspace.Workspace, cfg *con
 	}
 
 	// 3. Build result (shared across both paths).
+	ports := opts.ports
+	if ports == nil {
+		ports = collectPorts(cfg.ForwardPorts, cfg.AppPort)
+	}
 	result := &UpResult{
 		ContainerID:           cc.containerID,
 		ContainerName:         cc.containerName,
 		ImageName:             opts.imageName,
 		WorkspaceFolder:       cc.workspaceFolder,
 		RemoteUser:            cc.remoteUser,
-		Ports:                 portSpecToBindings(collectPorts(cfg.ForwardPorts, cfg.AppPort)),
+		Ports:                 portSpecToBindings(ports),
 		HasFeatureEntrypoints: opts.hasEntrypoints,
 	}
 
@@ -110,11 +116,24 @@ func (e *Engine) finalizeFromSnapshotPath(ctx context.Context, ws *workspace.Wor
 	// Early save so crib exec/shell work while resume hooks run.
 	e.saveResult(ws, cfg, result)
 
-	// Run only resume-flow hooks (create-time effects are in the snapshot).
+	// Run only resume-flow hooks (create-time effects are in the snapshot),
+	// plus any create-time stage whose commands were edited since it ran.
 	// Include stored feature hooks so features' postStart/postAttach run too.
 	hooks := hookSetWithStoredFeatures(cfg, opts.storedResult)
-	runner := e.newLifecycleRunner(ws, cc, cfg.RemoteEnv)
-	if err := runner.runResumeHooks(ctx, hooks, cc.workspaceFolder); err != nil {
+	runner := e.newLifecycleRunner(ws, cc, cfg.RemoteEnv, e.customHookEnv(cfg))
+	reran, err := runner.runChangedCreateHooks(ctx, hooks, cc.workspaceFolder)
+	if err != nil {
+		e.logger.Warn("re-running changed create hooks failed", "error", err)
+	}
+	// postStartCommand runs each time the container starts, so an up that
+	// found the container already running only runs postAttachCommand,
+	// unless a create-time stage (e.g. updateContentCommand after a pull)
+	// just re-ran and postStart should follow it.
+	if opts.alreadyRunning && !reran {
+		if err := runner.runStage(ctx, "postAttachCommand", hooks.PostAttach, cc.workspaceFolder); err != nil {
+			e.logger.Warn("resume hooks failed", "error", err)
+		}
+	} else if err := runner.runResumeHooks(ctx, hooks, cc.workspaceFolder); err != nil {
 		e.logger.Warn("resume hooks failed", "error", err)
 	}
 
@@ -6,6 +6,7 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 
@@ -147,6 +148,73 @@ func TestFinalize_FreshSetup_CallsSetupContainerAndCommitsSnapshot(t *testing.T)
 	}
 }
 
+func TestFinalize_FreshSetup_HookEnvOnlyDuringHooks(t *testing.T) {
+	store := workspace.NewStoreAt(t.TempDir())
+	ws := &workspace.Workspace{ID: "ws-fin-hookenv", Source: "/home/user/project"}
+	if err := store.Save(ws); err != nil {
+		t.Fatal(err)
+	}
+
+	mockDrv := &mockDriver{responses: map[string]string{}}
+	eng := &Engine{
+		driver:   mockDrv,
+		store:    store,
+		logger:   slog.Default(),
+		stdout:   io.Discard,
+		stderr:   io.Discard,
+		progress: func(ProgressEvent) {},
+	}
+
+	cfg := &config.DevContainerConfig{}
+	cfg.RemoteUser = "vscode"
+	cfg.RemoteEnv = map[string]string{"EDITOR": "vim"}
+	cfg.OnCreateCommand = config.LifecycleHook{"": {"npm ci"}}
+	cfg.Customizations = map[string]any{
+		"crib": map[string]any{
+			"hookEnv": map[string]any{"NPM_TOKEN": "secret", "IGNORED": 42},
+		},
+	}
+
+	_, err := eng.finalize(context.Background(), ws, cfg, finalizeOpts{
+		cc: containerContext{
+			workspaceID:     ws.ID,
+			containerID:     "container-1",
+			workspaceFolder: "/workspaces/project",
+		},
+		imageName: "ubuntu:22.04",
+	})
+	if err != nil {
+		t.Fatalf("finalize: %v", err)
+	}
+
+	var hookEnv []string
+	for _, call := range mockDrv.execCalls {
+		if strings.Contains(strings.Join(call.cmd, " "), "npm ci") {
+			hookEnv = call.env
+		}
+	}
+	if !slices.Contains(hookEnv, "NPM_TOKEN=secret") {
+		t.Errorf("hook env = %v, want NPM_TOKEN=secret", hookEnv)
+	}
+	if !slices.Contains(hookEnv, "EDITOR=vim") {
+		t.Errorf("hook env = %v, want remoteEnv entries too", hookEnv)
+	}
+	if slices.ContainsFunc(hookEnv, func(e string) bool { return strings.HasPrefix(e, "IGNORED=") }) {
+		t.Errorf("non-string hookEnv value should be skipped, got %v", hookEnv)
+	}
+
+	saved, err := store.LoadResult(ws.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := saved.RemoteEnv["NPM_TOKEN"]; ok {
+		t.Errorf("hookEnv leaked into stored RemoteEnv: %v", saved.RemoteEnv)
+	}
+	if saved.RemoteEnv["EDITOR"] != "vim" {
+		t.Errorf("stored RemoteEnv = %v, want EDITOR=vim", saved.RemoteEnv)
+	}
+}
+
 func TestFinalize_FromSnapshot_RestoresStoredEnvAndRunsResumeHooks(t *testing.T) {
 	store := workspace.NewStoreAt(t.TempDir())
 	ws := &workspace.Workspace{ID: "ws-fin-snap", Source: "/home/user/project"}
@@ -0,0 +1,52 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/fgrehm/crib/internal/driver"
+	"github.com/fgrehm/crib/internal/workspace"
+)
+
+// DiffFS lists the files added, changed or deleted in the workspace
+// container's filesystem since it was created from its image, running or
+// stopped. When paths are given, only changes at or below one of them are
+// returned. Volumes and bind mounts (including the workspace folder) are
+// not part of the container's filesystem, so they never show up.
+func (e *Engine) DiffFS(ctx context.Context, ws *workspace.Workspace, paths []string) ([]driver.FileChange, error) {
+	container, err := e.driver.FindContainer(ctx, ws.ID)
+	if err != nil {
+		return nil, fmt.Errorf("finding container: %w", err)
+	}
+	if container == nil {
+		return nil, &ErrNoContainer{WorkspaceID: ws.ID}
+	}
+
+	changes, err := e.driver.ContainerChanges(ctx, ws.ID, container.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return changes, nil
+	}
+	var filtered []driver.FileChange
+	for _, c := range changes {
+		if underAnyPath(c.Path, paths) {
+			filtered = append(filtered, c)
+		}
+	}
+	return filtered, nil
+}
+
+// underAnyPath reports whether p is one of roots or inside one of them.
+func underAnyPath(p string, roots []string) bool {
+	for _, root := range roots {
+		root = path.Clean("/" + root)
+		if root == "/" || p == root || strings.HasPrefix(p, root+"/") {
+			return true
+		}
+	}
+	return false
+}
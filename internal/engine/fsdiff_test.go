@@ -0,0 +1,79 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"testing"
+
+	"github.com/fgrehm/crib/internal/driver"
+	"github.com/fgrehm/crib/internal/workspace"
+)
+
+// changesDriver returns a fixed container and filesystem diff.
+type changesDriver struct {
+	mockDriver
+	container *driver.ContainerDetails
+	changes   []driver.FileChange
+	diffedID  string
+}
+
+func (m *changesDriver) FindContainer(_ context.Context, _ string) (*driver.ContainerDetails, error) {
+	return m.container, nil
+}
+
+func (m *changesDriver) ContainerChanges(_ context.Context, _, containerID string) ([]driver.FileChange, error) {
+	m.diffedID = containerID
+	return m.changes, nil
+}
+
+func TestDiffFS(t *testing.T) {
+	changes := []driver.FileChange{
+		{Kind: driver.ChangeModified, Path: "/etc"},
+		{Kind: driver.ChangeAdded, Path: "/etc/profile.d/nvm.sh"},
+		{Kind: driver.ChangeModified, Path: "/home/vscode"},
+		{Kind: driver.ChangeAdded, Path: "/home/vscode/.npmrc"},
+		{Kind: driver.ChangeDeleted, Path: "/etcetera"},
+		{Kind: driver.ChangeAdded, Path: "/tmp/build.log"},
+	}
+	tests := []struct {
+		name  string
+		paths []string
+		want  []driver.FileChange
+	}{
+		{"all", nil, changes},
+		{"root", []string{"/"}, changes},
+		{"one dir", []string{"/etc"}, changes[:2]},
+		{"several dirs", []string{"/home/vscode/", "tmp"}, []driver.FileChange{changes[2], changes[3], changes[5]}},
+		{"file", []string{"/home/vscode/.npmrc"}, changes[3:4]},
+		{"no match", []string{"/opt"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &changesDriver{container: &driver.ContainerDetails{ID: "c-1"}, changes: changes}
+			e := &Engine{driver: d, logger: slog.Default()}
+
+			got, err := e.DiffFS(context.Background(), &workspace.Workspace{ID: "ws"}, tt.paths)
+			if err != nil {
+				t.Fatalf("DiffFS: %v", err)
+			}
+			if d.diffedID != "c-1" {
+				t.Errorf("diffed container %q, want c-1", d.diffedID)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("DiffFS(%v) = %v, want %v", tt.paths, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffFS_NoContainer(t *testing.T) {
+	e := &Engine{driver: &changesDriver{}, logger: slog.Default()}
+
+	_, err := e.DiffFS(context.Background(), &workspace.Workspace{ID: "ws"}, nil)
+	var noContainer *ErrNoContainer
+	if !errors.As(err, &noContainer) {
+		t.Errorf("error = %v, want ErrNoContainer", err)
+	}
+}
@@ -78,7 +78,7 @@ func TestIntegrationGlobalWorkspaceEnv(t *testing.T) {
 	check := func(name, want string) {
 		t.Helper()
 		var stdout bytes.Buffer
-		if err := d.ExecContainer(ctx, wsID, container.ID, []string{"printenv", name}, nil, &stdout, nil, nil, ""); err != nil {
+		if err := d.ExecContainer(ctx, wsID, container.ID, []string{"printenv", name}, nil, &stdout, nil, nil, "", ""); err != nil {
 			t.Fatalf("printenv %s: %v", name, err)
 		}
 		got := strings.TrimSpace(stdout.String())
@@ -168,7 +168,7 @@ func TestIntegrationGlobalWorkspaceMountsAndRunArgs(t *testing.T) {
 	readMarker := func(path string) string {
 		t.Helper()
 		var stdout bytes.Buffer
-		if err := d.ExecContainer(ctx, wsID, container.ID, []string{"cat", path}, nil, &stdout, nil, nil, ""); err != nil {
+		if err := d.ExecContainer(ctx, wsID, container.ID, []string{"cat", path}, nil, &stdout, nil, nil, "", ""); err != nil {
 			t.Fatalf("cat %s: %v", path, err)
 		}
 		return strings.TrimSpace(stdout.String())
@@ -183,7 +183,7 @@ func TestIntegrationGlobalWorkspaceMountsAndRunArgs(t *testing.T) {
 
 	// Verify global runArgs reached the runtime by checking hostname.
 	var stdout bytes.Buffer
-	if err := d.ExecContainer(ctx, wsID, container.ID, []string{"hostname"}, nil, &stdout, nil, nil, ""); err != nil {
+	if err := d.ExecContainer(ctx, wsID, container.ID, []string{"hostname"}, nil, &stdout, nil, nil, "", ""); err != nil {
 		t.Fatalf("hostname: %v", err)
 	}
 	if got := strings.TrimSpace(stdout.String()); got != "crib-global-test" {
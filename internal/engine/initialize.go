@@ -3,7 +3,10 @@ package engine
 import (
 	"context"
 	"fmt"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"runtime"
 
 	"github.com/fgrehm/crib/internal/config"
 	"github.com/fgrehm/crib/internal/workspace"
@@ -11,16 +14,24 @@ import (
 
 // runInitializeCommand executes the initializeCommand lifecycle hook on the
 // host before image build/pull. Per the devcontainer spec, this runs on the
-// host machine (not in a container) on every "up" invocation.
-// Object-form hooks (named entries) run in parallel per the spec.
+// host machine (not in a container) on every "up" invocation, with the
+// project root as its working directory.
+// Object-form hooks (named entries) run in parallel per the spec, except
+// that an object keyed by host OS names runs only the entry for
+// runtime.GOOS.
 func (e *Engine) runInitializeCommand(ctx context.Context, ws *workspace.Workspace, cfg *config.DevContainerConfig) error {
-	if len(cfg.InitializeCommand) == 0 {
+	hook := cfg.InitializeCommand.ForOS(runtime.GOOS)
+	if len(hook) == 0 {
+		if len(cfg.InitializeCommand) > 0 {
+			e.logger.Debug("no initializeCommand for host OS", "os", runtime.GOOS)
+		}
 		return nil
 	}
 
 	e.reportProgress(PhaseInit, "Running initializeCommand...")
+	defer e.timings.start("initializeCommand")()
 
-	return dispatchHook(ctx, cfg.InitializeCommand, func(ctx context.Context, hookName string, cmdParts []string) error {
+	return dispatchHook(ctx, hook, func(ctx context.Context, hookName string, cmdParts []string) error {
 		return e.execInitCmd(ctx, ws, "initializeCommand", hookName, cmdParts)
 	})
 }
@@ -46,6 +57,7 @@ func (e *Engine) execInitCmd(ctx context.Context, ws *workspace.Workspace, hookS
 	}
 
 	cmd.Dir = ws.Source
+	cmd.Env = initCommandEnv(ws)
 	cmd.Stdout = e.stdout
 	cmd.Stderr = e.stderr
 
@@ -56,3 +68,14 @@ func (e *Engine) execInitCmd(ctx context.Context, ws *workspace.Workspace, hookS
 	}
 	return nil
 }
+
+// initCommandEnv returns the host environment plus the devcontainer
+// variables describing the workspace, so scripts don't have to rely on
+// ${...} substitution in devcontainer.json to locate the project.
+func initCommandEnv(ws *workspace.Workspace) []string {
+	return append(os.Environ(),
+		"DEVCONTAINER_ID="+ws.ID,
+		"LOCAL_WORKSPACE_FOLDER="+ws.Source,
+		"LOCAL_WORKSPACE_FOLDER_BASENAME="+filepath.Base(ws.Source),
+	)
+}
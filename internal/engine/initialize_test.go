@@ -5,6 +5,8 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
 
 	"github.com/fgrehm/crib/internal/config"
@@ -147,7 +149,7 @@ func TestRunInitializeCommand_Object_FailureReturnsError(t *testing.T) {
 
 func TestRunInitializeCommand_WorkingDirectory(t *testing.T) {
 	tmpDir := t.TempDir()
-	marker := filepath.Join(tmpDir, "pwd-check")
+	t.Setenv("CRIB_TEST_HOST_VAR", "from-host")
 
 	e := &Engine{
 		logger: slog.Default(),
@@ -155,26 +157,86 @@ func TestRunInitializeCommand_WorkingDirectory(t *testing.T) {
 		stderr: os.Stderr,
 	}
 
-	ws := &workspace.Workspace{Source: tmpDir}
+	ws := &workspace.Workspace{ID: "myproj", Source: tmpDir}
 	cfg := &config.DevContainerConfig{}
-	// Use pwd to verify working directory, write it to a file.
+	// Relative output paths land in the project root, not crib's own CWD.
 	cfg.InitializeCommand = config.LifecycleHook{
-		"": {"sh -c 'pwd > " + marker + "'"},
+		"": {`pwd > pwd-check && echo "$CRIB_TEST_HOST_VAR $DEVCONTAINER_ID $LOCAL_WORKSPACE_FOLDER_BASENAME" > env-check`},
 	}
 
 	if err := e.runInitializeCommand(context.Background(), ws, cfg); err != nil {
 		t.Fatalf("runInitializeCommand: %v", err)
 	}
 
-	data, err := os.ReadFile(marker)
+	data, err := os.ReadFile(filepath.Join(tmpDir, "pwd-check"))
 	if err != nil {
 		t.Fatalf("reading marker file: %v", err)
 	}
-
-	got := string(data)
-	// Trim newline.
-	got = got[:len(got)-1]
-	if got != tmpDir {
+	if got := strings.TrimSpace(string(data)); got != tmpDir {
 		t.Errorf("working directory = %q, want %q", got, tmpDir)
 	}
+
+	data, err = os.ReadFile(filepath.Join(tmpDir, "env-check"))
+	if err != nil {
+		t.Fatalf("reading env file: %v", err)
+	}
+	want := "from-host myproj " + filepath.Base(tmpDir)
+	if got := strings.TrimSpace(string(data)); got != want {
+		t.Errorf("env = %q, want %q", got, want)
+	}
+}
+
+func TestRunInitializeCommand_PerOS(t *testing.T) {
+	tmpDir := t.TempDir()
+	marker := filepath.Join(tmpDir, "init-os-ran")
+	otherMarker := filepath.Join(tmpDir, "init-other-os-ran")
+	otherOS := "windows"
+	if runtime.GOOS == otherOS {
+		otherOS = "linux"
+	}
+
+	e := &Engine{
+		logger: slog.Default(),
+		stdout: os.Stdout,
+		stderr: os.Stderr,
+	}
+
+	ws := &workspace.Workspace{Source: tmpDir}
+	cfg := &config.DevContainerConfig{}
+	cfg.InitializeCommand = config.LifecycleHook{
+		runtime.GOOS: {"touch", marker},
+		otherOS:      {"touch", otherMarker},
+	}
+
+	if err := e.runInitializeCommand(context.Background(), ws, cfg); err != nil {
+		t.Fatalf("runInitializeCommand: %v", err)
+	}
+
+	if _, err := os.Stat(marker); err != nil {
+		t.Errorf("expected the %s command to run: %v", runtime.GOOS, err)
+	}
+	if _, err := os.Stat(otherMarker); err == nil {
+		t.Errorf("the %s command should not run on %s", otherOS, runtime.GOOS)
+	}
+}
+
+func TestRunInitializeCommand_PerOSWithoutMatch(t *testing.T) {
+	otherOS := "windows"
+	if runtime.GOOS == otherOS {
+		otherOS = "linux"
+	}
+
+	e := &Engine{
+		logger: slog.Default(),
+		stdout: os.Stdout,
+		stderr: os.Stderr,
+	}
+
+	ws := &workspace.Workspace{Source: t.TempDir()}
+	cfg := &config.DevContainerConfig{}
+	cfg.InitializeCommand = config.LifecycleHook{otherOS: {"false"}}
+
+	if err := e.runInitializeCommand(context.Background(), ws, cfg); err != nil {
+		t.Fatalf("expected no command to run, got: %v", err)
+	}
 }
@@ -3,6 +3,7 @@ package engine
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
@@ -160,7 +161,7 @@ func TestIntegrationUpImageBased(t *testing.T) {
 
 	// Verify the container env was set.
 	var stdout bytes.Buffer
-	err = d.ExecContainer(ctx, wsID, container.ID, []string{"printenv", "CRIB_TEST"}, nil, &stdout, nil, nil, "")
+	err = d.ExecContainer(ctx, wsID, container.ID, []string{"printenv", "CRIB_TEST"}, nil, &stdout, nil, nil, "", "")
 	if err != nil {
 		t.Fatalf("ExecContainer: %v", err)
 	}
@@ -250,19 +251,177 @@ func TestIntegrationUpWithLifecycleHooks(t *testing.T) {
 
 	// Verify onCreate hook ran.
 	var stdout bytes.Buffer
-	err = d.ExecContainer(ctx, wsID, result.ContainerID, []string{"test", "-f", "/tmp/on-create-ran"}, nil, &stdout, nil, nil, "")
+	err = d.ExecContainer(ctx, wsID, result.ContainerID, []string{"test", "-f", "/tmp/on-create-ran"}, nil, &stdout, nil, nil, "", "")
 	if err != nil {
 		t.Errorf("onCreate hook did not run: %v", err)
 	}
 
 	// Verify postStart hook ran.
 	stdout.Reset()
-	err = d.ExecContainer(ctx, wsID, result.ContainerID, []string{"test", "-f", "/tmp/post-start-ran"}, nil, &stdout, nil, nil, "")
+	err = d.ExecContainer(ctx, wsID, result.ContainerID, []string{"test", "-f", "/tmp/post-start-ran"}, nil, &stdout, nil, nil, "", "")
 	if err != nil {
 		t.Errorf("postStart hook did not run: %v", err)
 	}
 }
 
+// TestIntegrationEnsureRunningContainerStartsStopped covers "crib exec --up":
+// a stopped container is rejected by RequireRunningContainer, and
+// EnsureRunningContainer starts it (running postStartCommand) so a command
+// can be executed.
+func TestIntegrationEnsureRunningContainerStartsStopped(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+
+	ctx := context.Background()
+	e, d, _ := newTestEngine(t)
+
+	projectDir := t.TempDir()
+	devcontainerDir := filepath.Join(projectDir, ".devcontainer")
+	if err := os.MkdirAll(devcontainerDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	configContent := `{
+		"image": "alpine:3.20",
+		"overrideCommand": true,
+		"postStartCommand": "echo started >> /tmp/post-start-runs"
+	}`
+	if err := os.WriteFile(filepath.Join(devcontainerDir, "devcontainer.json"), []byte(configContent), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	wsID := "test-engine-exec-up"
+	ws := &workspace.Workspace{
+		ID:               wsID,
+		Source:           projectDir,
+		DevContainerPath: ".devcontainer/devcontainer.json",
+		CreatedAt:        time.Now(),
+		LastUsedAt:       time.Now(),
+	}
+
+	_ = d.DeleteContainer(ctx, wsID, oci.ContainerName(wsID))
+	t.Cleanup(func() {
+		_ = d.DeleteContainer(ctx, wsID, oci.ContainerName(wsID))
+		cleanupWorkspaceImages(t, d, wsID)
+	})
+
+	// No container yet: nothing is created.
+	if _, err := e.EnsureRunningContainer(ctx, ws); err == nil {
+		t.Fatal("EnsureRunningContainer should fail before the first up")
+	}
+
+	if _, err := e.Up(ctx, ws, UpOptions{}); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	if err := e.Stop(ctx, ws); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+
+	var stopped *ErrContainerStopped
+	if _, err := e.RequireRunningContainer(ctx, ws); !errors.As(err, &stopped) {
+		t.Fatalf("RequireRunningContainer on a stopped container: got %v, want ErrContainerStopped", err)
+	}
+
+	container, err := e.EnsureRunningContainer(ctx, ws)
+	if err != nil {
+		t.Fatalf("EnsureRunningContainer: %v", err)
+	}
+	var stdout bytes.Buffer
+	if err := d.ExecContainer(ctx, wsID, container.ID, []string{"echo", "hello"}, nil, &stdout, nil, nil, "", ""); err != nil {
+		t.Fatalf("exec after start: %v", err)
+	}
+	if got := strings.TrimSpace(stdout.String()); got != "hello" {
+		t.Errorf("exec output = %q, want %q", got, "hello")
+	}
+	stdout.Reset()
+	if err := d.ExecContainer(ctx, wsID, container.ID, []string{"sh", "-c", "wc -l < /tmp/post-start-runs"}, nil, &stdout, nil, nil, "", ""); err != nil {
+		t.Fatalf("reading postStart runs: %v", err)
+	}
+	if got := strings.TrimSpace(stdout.String()); got != "2" {
+		t.Errorf("postStartCommand ran %s times, want 2 (up and start)", got)
+	}
+}
+
+func TestIntegrationUpRerunsUpdateContentAfterPull(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping integration test in short mode")
+	}
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("skipping: git not available")
+	}
+
+	ctx := context.Background()
+	e, d, _ := newTestEngine(t)
+
+	projectDir := t.TempDir()
+	devcontainerDir := filepath.Join(projectDir, ".devcontainer")
+	if err := os.MkdirAll(devcontainerDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	configContent := `{
+		"image": "alpine:3.20",
+		"overrideCommand": true,
+		"onCreateCommand": "echo x >> /tmp/on-create-runs",
+		"updateContentCommand": "echo x >> /tmp/update-content-runs"
+	}`
+	if err := os.WriteFile(filepath.Join(devcontainerDir, "devcontainer.json"), []byte(configContent), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", projectDir, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+	git("init", "-q")
+	git("add", ".")
+	git("commit", "-q", "-m", "initial")
+
+	wsID := "test-engine-update-content"
+	ws := &workspace.Workspace{
+		ID:               wsID,
+		Source:           projectDir,
+		DevContainerPath: ".devcontainer/devcontainer.json",
+		CreatedAt:        time.Now(),
+		LastUsedAt:       time.Now(),
+	}
+
+	_ = d.DeleteContainer(ctx, wsID, oci.ContainerName(wsID))
+	t.Cleanup(func() {
+		_ = d.DeleteContainer(ctx, wsID, oci.ContainerName(wsID))
+		cleanupWorkspaceImages(t, d, wsID)
+	})
+
+	if _, err := e.Up(ctx, ws, UpOptions{}); err != nil {
+		t.Fatalf("first Up: %v", err)
+	}
+
+	// Simulate pulling new code, then bring the running workspace up again.
+	git("commit", "-q", "--allow-empty", "-m", "pulled")
+	result, err := e.Up(ctx, ws, UpOptions{})
+	if err != nil {
+		t.Fatalf("second Up: %v", err)
+	}
+
+	countRuns := func(file string) int {
+		t.Helper()
+		var stdout bytes.Buffer
+		if err := d.ExecContainer(ctx, wsID, result.ContainerID, []string{"wc", "-l", file}, nil, &stdout, nil, nil, "", ""); err != nil {
+			t.Fatalf("reading %s: %v", file, err)
+		}
+		n, _ := strconv.Atoi(strings.Fields(stdout.String())[0])
+		return n
+	}
+	if got := countRuns("/tmp/on-create-runs"); got != 1 {
+		t.Errorf("onCreateCommand ran %d times, want 1", got)
+	}
+	if got := countRuns("/tmp/update-content-runs"); got != 2 {
+		t.Errorf("updateContentCommand ran %d times, want 2", got)
+	}
+}
+
 func TestIntegrationUpWithInitializeCommand(t *testing.T) {
 	if testing.Short() {
 		t.Skip("skipping integration test in short mode")
@@ -463,7 +622,7 @@ USER dev
 	// This confirms syncRemoteUserUID resolved any UID conflicts (e.g., the ubuntu user
 	// at UID 1000) and successfully called usermod to reassign dev.
 	var stdout bytes.Buffer
-	if err := d.ExecContainer(ctx, wsID, result.ContainerID, []string{"id", "-u", "dev"}, nil, &stdout, nil, nil, ""); err != nil {
+	if err := d.ExecContainer(ctx, wsID, result.ContainerID, []string{"id", "-u", "dev"}, nil, &stdout, nil, nil, "", ""); err != nil {
 		t.Fatalf("id -u dev: %v", err)
 	}
 	gotUID, err := strconv.Atoi(strings.TrimSpace(stdout.String()))
@@ -476,7 +635,7 @@ USER dev
 
 	// Verify the dev group GID matches the host GID.
 	stdout.Reset()
-	if err := d.ExecContainer(ctx, wsID, result.ContainerID, []string{"id", "-g", "dev"}, nil, &stdout, nil, nil, ""); err != nil {
+	if err := d.ExecContainer(ctx, wsID, result.ContainerID, []string{"id", "-g", "dev"}, nil, &stdout, nil, nil, "", ""); err != nil {
 		t.Fatalf("id -g dev: %v", err)
 	}
 	gotGID, err := strconv.Atoi(strings.TrimSpace(stdout.String()))
@@ -492,7 +651,7 @@ USER dev
 	// invariant we care about regardless of how the runtime maps UIDs.
 	stdout.Reset()
 	probeFile := fmt.Sprintf("%s/probe.txt", result.WorkspaceFolder)
-	if err := d.ExecContainer(ctx, wsID, result.ContainerID, []string{"cat", probeFile}, nil, &stdout, nil, nil, "dev"); err != nil {
+	if err := d.ExecContainer(ctx, wsID, result.ContainerID, []string{"cat", probeFile}, nil, &stdout, nil, nil, "dev", ""); err != nil {
 		t.Fatalf("dev user cannot read bind-mounted file %s: %v", probeFile, err)
 	}
 	if got := strings.TrimSpace(stdout.String()); got != "ok" {
@@ -550,7 +709,7 @@ func TestIntegrationUpWithPlugins(t *testing.T) {
 
 	// Verify HISTFILE is set inside the container.
 	var stdout bytes.Buffer
-	err = d.ExecContainer(ctx, wsID, result.ContainerID, []string{"printenv", "HISTFILE"}, nil, &stdout, nil, nil, "")
+	err = d.ExecContainer(ctx, wsID, result.ContainerID, []string{"printenv", "HISTFILE"}, nil, &stdout, nil, nil, "", "")
 	if err != nil {
 		t.Fatalf("printenv HISTFILE: %v", err)
 	}
@@ -678,7 +837,7 @@ func TestIntegrationSnapshot(t *testing.T) {
 
 	// Verify onCreate hook ran.
 	var stdout bytes.Buffer
-	if err := d.ExecContainer(ctx, wsID, result.ContainerID, []string{"test", "-f", "/tmp/snapshot-marker"}, nil, &stdout, nil, nil, ""); err != nil {
+	if err := d.ExecContainer(ctx, wsID, result.ContainerID, []string{"test", "-f", "/tmp/snapshot-marker"}, nil, &stdout, nil, nil, "", ""); err != nil {
 		t.Errorf("onCreate hook did not run: %v", err)
 	}
 
@@ -784,14 +943,14 @@ func TestIntegrationFeatureLifecycleHooks(t *testing.T) {
 
 	// Verify feature onCreateCommand ran.
 	var stdout bytes.Buffer
-	if err := d.ExecContainer(ctx, wsID, result.ContainerID, []string{"cat", "/tmp/feature-oncreate-ran"}, nil, &stdout, nil, nil, ""); err != nil {
+	if err := d.ExecContainer(ctx, wsID, result.ContainerID, []string{"cat", "/tmp/feature-oncreate-ran"}, nil, &stdout, nil, nil, "", ""); err != nil {
 		t.Errorf("feature onCreateCommand did not run: %v", err)
 	}
 
 	// Verify user onCreateCommand ran AND that it ran after the feature hook
 	// (it checks for /tmp/feature-oncreate-ran before writing its own marker).
 	stdout.Reset()
-	if err := d.ExecContainer(ctx, wsID, result.ContainerID, []string{"cat", "/tmp/user-oncreate-ran"}, nil, &stdout, nil, nil, ""); err != nil {
+	if err := d.ExecContainer(ctx, wsID, result.ContainerID, []string{"cat", "/tmp/user-oncreate-ran"}, nil, &stdout, nil, nil, "", ""); err != nil {
 		t.Errorf("user onCreateCommand did not run (or ran before feature hook): %v", err)
 	} else if got := strings.TrimSpace(stdout.String()); got != "user-after-feature" {
 		t.Errorf("user onCreateCommand output = %q, want 'user-after-feature'", got)
@@ -799,13 +958,13 @@ func TestIntegrationFeatureLifecycleHooks(t *testing.T) {
 
 	// Verify feature postStartCommand ran.
 	stdout.Reset()
-	if err := d.ExecContainer(ctx, wsID, result.ContainerID, []string{"test", "-f", "/tmp/feature-poststart-ran"}, nil, &stdout, nil, nil, ""); err != nil {
+	if err := d.ExecContainer(ctx, wsID, result.ContainerID, []string{"test", "-f", "/tmp/feature-poststart-ran"}, nil, &stdout, nil, nil, "", ""); err != nil {
 		t.Errorf("feature postStartCommand did not run: %v", err)
 	}
 
 	// Verify user postStartCommand ran.
 	stdout.Reset()
-	if err := d.ExecContainer(ctx, wsID, result.ContainerID, []string{"test", "-f", "/tmp/user-poststart-ran"}, nil, &stdout, nil, nil, ""); err != nil {
+	if err := d.ExecContainer(ctx, wsID, result.ContainerID, []string{"test", "-f", "/tmp/user-poststart-ran"}, nil, &stdout, nil, nil, "", ""); err != nil {
 		t.Errorf("user postStartCommand did not run: %v", err)
 	}
 
@@ -829,7 +988,7 @@ func TestIntegrationFeatureLifecycleHooks(t *testing.T) {
 	// --- Resume path: Down + Up should run feature postStartCommand again ---
 
 	// Remove the postStart markers so we can verify they re-run.
-	_ = d.ExecContainer(ctx, wsID, result.ContainerID, []string{"rm", "-f", "/tmp/feature-poststart-ran", "/tmp/user-poststart-ran"}, nil, nil, nil, nil, "")
+	_ = d.ExecContainer(ctx, wsID, result.ContainerID, []string{"rm", "-f", "/tmp/feature-poststart-ran", "/tmp/user-poststart-ran"}, nil, nil, nil, nil, "", "")
 
 	if err := e.Down(ctx, ws); err != nil {
 		t.Fatalf("Down: %v", err)
@@ -842,13 +1001,13 @@ func TestIntegrationFeatureLifecycleHooks(t *testing.T) {
 
 	// Feature postStartCommand should have run again on resume.
 	stdout.Reset()
-	if err := d.ExecContainer(ctx, wsID, result2.ContainerID, []string{"test", "-f", "/tmp/feature-poststart-ran"}, nil, &stdout, nil, nil, ""); err != nil {
+	if err := d.ExecContainer(ctx, wsID, result2.ContainerID, []string{"test", "-f", "/tmp/feature-poststart-ran"}, nil, &stdout, nil, nil, "", ""); err != nil {
 		t.Errorf("feature postStartCommand did not run on resume: %v", err)
 	}
 
 	// User postStartCommand should have run again on resume.
 	stdout.Reset()
-	if err := d.ExecContainer(ctx, wsID, result2.ContainerID, []string{"test", "-f", "/tmp/user-poststart-ran"}, nil, &stdout, nil, nil, ""); err != nil {
+	if err := d.ExecContainer(ctx, wsID, result2.ContainerID, []string{"test", "-f", "/tmp/user-poststart-ran"}, nil, &stdout, nil, nil, "", ""); err != nil {
 		t.Errorf("user postStartCommand did not run on resume: %v", err)
 	}
 
@@ -969,7 +1128,7 @@ func TestIntegrationImageMetadataLabel(t *testing.T) {
 	t.Cleanup(func() {
 		_ = d.ExecContainer(ctx, wsID, result.ContainerID,
 			[]string{"chmod", "-R", "a+rwX", result.WorkspaceFolder},
-			nil, io.Discard, io.Discard, nil, "root")
+			nil, io.Discard, io.Discard, nil, "root", "")
 	})
 
 	if result.RemoteUser != "testlabeluser" {
@@ -1035,7 +1194,7 @@ USER nonroot
 	t.Cleanup(func() {
 		_ = d.ExecContainer(ctx, wsID, result.ContainerID,
 			[]string{"chmod", "-R", "a+rwX", result.WorkspaceFolder},
-			nil, io.Discard, io.Discard, nil, "root")
+			nil, io.Discard, io.Discard, nil, "root", "")
 	})
 
 	if result.RemoteUser != "nonroot" {
@@ -1110,7 +1269,7 @@ func TestIntegrationImageBasedMetadataLabel(t *testing.T) {
 	t.Cleanup(func() {
 		_ = d.ExecContainer(ctx, wsID, result.ContainerID,
 			[]string{"chmod", "-R", "a+rwX", result.WorkspaceFolder},
-			nil, io.Discard, io.Discard, nil, "root")
+			nil, io.Discard, io.Discard, nil, "root", "")
 	})
 
 	if result.RemoteUser != "imguser" {
@@ -1218,7 +1377,7 @@ func TestIntegrationResumePreservesMetadataUser(t *testing.T) {
 		if latestResult != nil {
 			_ = d.ExecContainer(ctx, wsID, latestResult.ContainerID,
 				[]string{"chmod", "-R", "a+rwX", latestResult.WorkspaceFolder},
-				nil, io.Discard, io.Discard, nil, "root")
+				nil, io.Discard, io.Discard, nil, "root", "")
 		}
 
 		// The explicit config value should take precedence over the metadata label.
@@ -1246,7 +1405,7 @@ func TestIntegrationResumePreservesMetadataUser(t *testing.T) {
 		if latestResult != nil {
 			_ = d.ExecContainer(ctx, wsID, latestResult.ContainerID,
 				[]string{"chmod", "-R", "a+rwX", latestResult.WorkspaceFolder},
-				nil, io.Discard, io.Discard, nil, "root")
+				nil, io.Discard, io.Discard, nil, "root", "")
 		}
 	})
 }
@@ -1327,7 +1486,7 @@ func TestIntegrationRestartRecreatePreservesUser(t *testing.T) {
 	// runner (a different UID) write the config file (Docker chown is real).
 	_ = d.ExecContainer(ctx, wsID, upResult.ContainerID,
 		[]string{"chmod", "-R", "a+rwX", upResult.WorkspaceFolder},
-		nil, io.Discard, io.Discard, nil, "root")
+		nil, io.Discard, io.Discard, nil, "root", "")
 
 	// Step 2: safe config change (add a containerEnv entry) → changeSafe →
 	// restartRecreate path. remoteUser must be preserved from storedResult.
@@ -1347,7 +1506,7 @@ func TestIntegrationRestartRecreatePreservesUser(t *testing.T) {
 	t.Cleanup(func() {
 		_ = d.ExecContainer(ctx, wsID, restartResult.ContainerID,
 			[]string{"chmod", "-R", "a+rwX", restartResult.WorkspaceFolder},
-			nil, io.Discard, io.Discard, nil, "root")
+			nil, io.Discard, io.Discard, nil, "root", "")
 	})
 }
 
@@ -1437,7 +1596,7 @@ func TestIntegrationFeaturesPreserveBaseImageMetadata(t *testing.T) {
 	t.Cleanup(func() {
 		_ = d.ExecContainer(ctx, wsID, result.ContainerID,
 			[]string{"chmod", "-R", "a+rwX", result.WorkspaceFolder},
-			nil, io.Discard, io.Discard, nil, "root")
+			nil, io.Discard, io.Discard, nil, "root", "")
 	})
 
 	if result.RemoteUser != "featurebaseuser" {
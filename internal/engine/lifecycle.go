@@ -1,10 +1,20 @@
 package engine
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"maps"
+	"os/exec"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 
 	"golang.org/x/sync/errgroup"
 
@@ -20,31 +30,103 @@ type lifecycleRunner struct {
 	store       *workspace.Store
 	workspaceID string
 	containerID string
+	source      string // project root on the host, for contentRevision
 	remoteUser  string
 	remoteEnv   map[string]string
 	logger      *slog.Logger
 	stdout      io.Writer
 	stderr      io.Writer
 	progress    func(ProgressEvent)
+	timings     *timingCollector // records each stage that runs; may be nil
 	verbose     bool
+
+	// hookRetries is the number of extra attempts for a failing hook in a
+	// marker-guarded stage. Zero disables retries.
+	hookRetries int
+	// retryDelay is the initial backoff between attempts; it doubles after
+	// each failure.
+	retryDelay time.Duration
+
+	// outputTails captures the end of each stage's output in this run, for
+	// workspace.Result.HookOutput.
+	outputTails map[string]*tailBuffer
+
+	// startedAt identifies this run in hook log file names. Set lazily on
+	// the first hook so every stage of one Up shares the same timestamp.
+	startedAt time.Time
+
+	// waitForTimeout bounds how long the hooks up to the waitFor stage may
+	// run. When it expires the container is reported ready anyway while the
+	// hooks keep running, or, with waitForFail, the running hook is aborted
+	// and ErrWaitForTimeout returned. Zero disables the limit.
+	waitForTimeout time.Duration
+	waitForFail    bool
+
+	readyMu       sync.Mutex
+	ready         bool        // "Container ready." was emitted
+	readyDeadline time.Time   // set by armWaitForTimeout; zero when disarmed
+	readyTimer    *time.Timer // warns and signals ready when the timeout hits
 }
 
+// defaultHookRetryDelay is the initial backoff between hook retry attempts.
+const defaultHookRetryDelay = 2 * time.Second
+
 // newLifecycleRunner creates a lifecycleRunner from the engine's dependencies,
-// a container context, and the resolved remote environment.
-func (e *Engine) newLifecycleRunner(ws *workspace.Workspace, cc containerContext, remoteEnv map[string]string) *lifecycleRunner {
+// a container context, and the resolved remote environment. hookEnv is layered
+// on top of remoteEnv for hook commands only; remoteEnv itself is not
+// modified, so hookEnv never reaches the persisted environment.
+func (e *Engine) newLifecycleRunner(ws *workspace.Workspace, cc containerContext, remoteEnv, hookEnv map[string]string) *lifecycleRunner {
+	if len(hookEnv) > 0 {
+		merged := make(map[string]string, len(remoteEnv)+len(hookEnv))
+		maps.Copy(merged, remoteEnv)
+		maps.Copy(merged, hookEnv)
+		remoteEnv = merged
+	}
 	return &lifecycleRunner{
 		driver:      e.driver,
 		store:       e.store,
 		workspaceID: ws.ID,
 		containerID: cc.containerID,
+		source:      ws.Source,
 		remoteUser:  cc.remoteUser,
 		remoteEnv:   remoteEnv,
 		logger:      e.logger,
 		stdout:      e.stdout,
 		stderr:      e.stderr,
 		progress:    e.progress,
+		timings:     e.timings,
 		verbose:     e.verbose,
+		hookRetries: e.hookRetries,
+		retryDelay:  defaultHookRetryDelay,
+
+		waitForTimeout: e.waitForTimeout,
+		waitForFail:    e.waitForFail,
+	}
+}
+
+// customHookEnv reads customizations.crib.hookEnv: variables set for
+// lifecycle hooks only, e.g. CI tokens that should not leak into crib shell or
+// crib exec sessions. Non-string values are skipped with a warning.
+func (e *Engine) customHookEnv(cfg *config.DevContainerConfig) map[string]string {
+	raw, ok := extractCribCustomizations(cfg)["hookEnv"]
+	if !ok || raw == nil {
+		return nil
+	}
+	m, ok := raw.(map[string]any)
+	if !ok {
+		e.logger.Warn("ignoring customizations.crib.hookEnv: must be an object of strings")
+		return nil
 	}
+	env := make(map[string]string, len(m))
+	for k, v := range m {
+		s, ok := v.(string)
+		if !ok {
+			e.logger.Warn("ignoring non-string customizations.crib.hookEnv value", "name", k)
+			continue
+		}
+		env[k] = s
+	}
+	return env
 }
 
 // hookSet holds the merged lifecycle hook lists for all stages. Each list
@@ -102,31 +184,37 @@ func hookSetFromConfig(cfg *config.DevContainerConfig) *hookSet {
 //
 // Callers are responsible for running start-time hooks (runStartHooks) after
 // any post-create work (e.g. plugin PostContainerCreate dispatch).
-func (r *lifecycleRunner) runCreateHooks(ctx context.Context, hooks *hookSet, workspaceFolder string) error {
+func (r *lifecycleRunner) runCreateHooks(ctx context.Context, hooks *hookSet, workspaceFolder string) (err error) {
 	waitFor := hooks.WaitFor
 	if waitFor == "" {
 		waitFor = "updateContentCommand"
 	}
+	r.armWaitForTimeout(waitFor)
+	defer func() {
+		// Start hooks won't run, so nothing can reach the waitFor stage.
+		if err != nil {
+			r.disarmWaitForTimeout()
+		}
+	}()
 
 	// onCreate hooks: run only once (marker file prevents re-execution).
-	if err := r.runStageWithMarker(ctx, "onCreateCommand", hooks.OnCreate, workspaceFolder); err != nil {
+	if err := r.runBeforeReady(ctx, "onCreateCommand", waitFor, func(ctx context.Context) error {
+		return r.runStageWithMarker(ctx, "onCreateCommand", hooks.OnCreate, workspaceFolder)
+	}); err != nil {
 		return err
 	}
-	r.signalReadyAt("onCreateCommand", waitFor)
 
 	// updateContent hooks.
-	if err := r.runStageWithMarker(ctx, "updateContentCommand", hooks.UpdateContent, workspaceFolder); err != nil {
+	if err := r.runBeforeReady(ctx, "updateContentCommand", waitFor, func(ctx context.Context) error {
+		return r.runStageWithMarker(ctx, "updateContentCommand", hooks.UpdateContent, workspaceFolder)
+	}); err != nil {
 		return err
 	}
-	r.signalReadyAt("updateContentCommand", waitFor)
 
 	// postCreate hooks: run only once.
-	if err := r.runStageWithMarker(ctx, "postCreateCommand", hooks.PostCreate, workspaceFolder); err != nil {
-		return err
-	}
-	r.signalReadyAt("postCreateCommand", waitFor)
-
-	return nil
+	return r.runBeforeReady(ctx, "postCreateCommand", waitFor, func(ctx context.Context) error {
+		return r.runStageWithMarker(ctx, "postCreateCommand", hooks.PostCreate, workspaceFolder)
+	})
 }
 
 // runStartHooks executes the start-time lifecycle hooks: postStartCommand and
@@ -137,27 +225,116 @@ func (r *lifecycleRunner) runStartHooks(ctx context.Context, hooks *hookSet, wor
 	if waitFor == "" {
 		waitFor = "updateContentCommand"
 	}
+	defer r.disarmWaitForTimeout()
 
-	if err := r.runStage(ctx, "postStartCommand", hooks.PostStart, workspaceFolder); err != nil {
+	if err := r.runBeforeReady(ctx, "postStartCommand", waitFor, func(ctx context.Context) error {
+		return r.runStage(ctx, "postStartCommand", hooks.PostStart, workspaceFolder)
+	}); err != nil {
 		return err
 	}
-	r.signalReadyAt("postStartCommand", waitFor)
 
-	if err := r.runStage(ctx, "postAttachCommand", hooks.PostAttach, workspaceFolder); err != nil {
+	return r.runBeforeReady(ctx, "postAttachCommand", waitFor, func(ctx context.Context) error {
+		return r.runStage(ctx, "postAttachCommand", hooks.PostAttach, workspaceFolder)
+	})
+}
+
+// runBeforeReady runs one stage and then signals readiness if it is the
+// waitFor stage. While the container is not ready yet and waitForFail is
+// set, the stage runs under the waitFor deadline; hitting it aborts the
+// running hook and returns ErrWaitForTimeout.
+func (r *lifecycleRunner) runBeforeReady(ctx context.Context, stage, waitFor string, run func(context.Context) error) error {
+	stageCtx, cancel := r.waitForContext(ctx)
+	defer cancel()
+	if err := run(stageCtx); err != nil {
+		if errors.Is(stageCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil {
+			return &ErrWaitForTimeout{Stage: stage, WaitFor: waitFor, Timeout: r.waitForTimeout}
+		}
 		return err
 	}
-	r.signalReadyAt("postAttachCommand", waitFor)
-
+	r.signalReadyAt(stage, waitFor)
 	return nil
 }
 
+// waitForStages are the stages runCreateHooks and runStartHooks signal
+// readiness after. waitFor "initializeCommand" is signaled by Up itself.
+var waitForStages = []string{
+	"onCreateCommand", "updateContentCommand", "postCreateCommand",
+	"postStartCommand", "postAttachCommand",
+}
+
+// armWaitForTimeout starts the waitFor timeout for a fresh run of the hooks.
+// Without waitForFail, a timer reports the container ready with a warning
+// once it expires; otherwise waitForContext enforces the deadline.
+func (r *lifecycleRunner) armWaitForTimeout(waitFor string) {
+	if r.waitForTimeout <= 0 || !slices.Contains(waitForStages, waitFor) {
+		return
+	}
+	r.readyMu.Lock()
+	defer r.readyMu.Unlock()
+	r.readyDeadline = time.Now().Add(r.waitForTimeout)
+	if r.waitForFail {
+		return
+	}
+	r.readyTimer = time.AfterFunc(r.waitForTimeout, func() {
+		r.logger.Warn("waitFor timeout hit, reporting container ready", "waitFor", waitFor, "timeout", r.waitForTimeout)
+		r.signalReady(fmt.Sprintf("%s still running after %s; the container is usable but setup continues", waitFor, r.waitForTimeout))
+	})
+}
+
+// disarmWaitForTimeout stops the waitFor timer once no more stages can
+// reach the waitFor stage.
+func (r *lifecycleRunner) disarmWaitForTimeout() {
+	r.readyMu.Lock()
+	defer r.readyMu.Unlock()
+	if r.readyTimer != nil {
+		r.readyTimer.Stop()
+	}
+	r.readyDeadline = time.Time{}
+}
+
+// waitForContext returns ctx bounded by the waitFor deadline when the
+// timeout is set to fail and the container is not ready yet.
+func (r *lifecycleRunner) waitForContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	r.readyMu.Lock()
+	deadline, ready := r.readyDeadline, r.ready
+	r.readyMu.Unlock()
+	if !r.waitForFail || ready || deadline.IsZero() {
+		return ctx, func() {}
+	}
+	return context.WithDeadline(ctx, deadline)
+}
+
 // signalReadyAt emits a "Container ready." progress event when stage matches waitFor.
 func (r *lifecycleRunner) signalReadyAt(stage, waitFor string) {
-	if stage == waitFor && r.progress != nil {
-		r.progress(ProgressEvent{Phase: PhaseHooks, Message: "Container ready."})
+	if stage == waitFor {
+		r.signalReady("")
 	}
 }
 
+// signalReady emits "Container ready." once per run, preceded by warning
+// when it is not empty. Later calls are no-ops, so the stage that finally
+// finishes after a waitFor timeout does not signal again.
+func (r *lifecycleRunner) signalReady(warning string) {
+	r.readyMu.Lock()
+	if r.ready {
+		r.readyMu.Unlock()
+		return
+	}
+	r.ready = true
+	if r.readyTimer != nil {
+		r.readyTimer.Stop()
+	}
+	r.readyMu.Unlock()
+
+	if r.progress == nil {
+		return
+	}
+	if warning != "" {
+		r.progress(ProgressEvent{Phase: PhaseHooks, Message: warning})
+	}
+	r.progress(ProgressEvent{Phase: PhaseHooks, Message: "Container ready."})
+}
+
 // runResumeHooks executes only the resume-flow lifecycle hooks (postStartCommand
 // and postAttachCommand). Per the devcontainer spec, these are the only hooks
 // that run when a container is restarted (as opposed to freshly created).
@@ -168,9 +345,42 @@ func (r *lifecycleRunner) runResumeHooks(ctx context.Context, hooks *hookSet, wo
 	return r.runStage(ctx, "postAttachCommand", hooks.PostAttach, workspaceFolder)
 }
 
+// runChangedCreateHooks re-runs the create-time stages that already ran but
+// whose commands were edited since, and updateContentCommand when the
+// project's content revision moved (see stageHash). Stages that never ran
+// are left alone: on resume, the existing container already reflects the
+// create flow. It reports whether any stage ran.
+func (r *lifecycleRunner) runChangedCreateHooks(ctx context.Context, hooks *hookSet, workspaceFolder string) (bool, error) {
+	stages := []struct {
+		name  string
+		hooks []config.LifecycleHook
+	}{
+		{"onCreateCommand", hooks.OnCreate},
+		{"updateContentCommand", hooks.UpdateContent},
+		{"postCreateCommand", hooks.PostCreate},
+	}
+	ran := false
+	for _, st := range stages {
+		if len(st.hooks) == 0 || !r.store.IsHookDone(r.workspaceID, st.name) {
+			continue
+		}
+		if prev := r.store.HookDoneHash(r.workspaceID, st.name); prev == "" || prev == r.stageHash(st.name, st.hooks) {
+			continue
+		}
+		if err := r.runStageWithMarker(ctx, st.name, st.hooks, workspaceFolder); err != nil {
+			return ran, err
+		}
+		ran = true
+	}
+	return ran, nil
+}
+
 // runStage dispatches a merged list of hooks for a stage. The list typically
 // contains feature hooks first (in installation order) then the user hook.
 func (r *lifecycleRunner) runStage(ctx context.Context, name string, hooks []config.LifecycleHook, workspaceFolder string) error {
+	if len(hooks) > 0 {
+		defer r.timings.start(name)()
+	}
 	for _, h := range hooks {
 		if err := r.runHook(ctx, name, h, workspaceFolder); err != nil {
 			return err
@@ -180,27 +390,63 @@ func (r *lifecycleRunner) runStage(ctx context.Context, name string, hooks []con
 }
 
 // runStageWithMarker dispatches a merged hook list, using a host-side marker
-// file to ensure the entire stage only runs once.
+// file to ensure the entire stage only runs once. The marker records a hash of
+// the hook list, so editing the stage's commands makes it run again.
 func (r *lifecycleRunner) runStageWithMarker(ctx context.Context, name string, hooks []config.LifecycleHook, workspaceFolder string) error {
 	if len(hooks) == 0 {
 		return nil
 	}
 
+	hash := r.stageHash(name, hooks)
 	if r.store.IsHookDone(r.workspaceID, name) {
-		r.logger.Debug("skipping hook (already ran)", "hook", name)
-		return nil
+		// Markers written before hashes were recorded are empty; keep
+		// treating them as done rather than re-running on upgrade.
+		prev := r.store.HookDoneHash(r.workspaceID, name)
+		if prev == "" || prev == hash {
+			r.logger.Debug("skipping hook (already ran)", "hook", name)
+			return nil
+		}
+		r.logger.Debug("hook changed since it last ran, re-running", "hook", name)
 	}
+	defer r.timings.start(name)()
 
-	if err := r.runStage(ctx, name, hooks, workspaceFolder); err != nil {
-		return err
+	for _, h := range hooks {
+		if err := r.runHookWithRetry(ctx, name, h, workspaceFolder); err != nil {
+			return err
+		}
 	}
 
-	if err := r.store.MarkHookDone(r.workspaceID, name); err != nil {
+	if err := r.store.MarkHookDone(r.workspaceID, name, hash); err != nil {
 		r.logger.Warn("failed to write hook marker", "hook", name, "error", err)
 	}
 	return nil
 }
 
+// runHookWithRetry runs a hook, re-running it up to r.hookRetries times with
+// exponential backoff when it fails. Only used for marker-guarded stages, so
+// a hook that already completed is never retried.
+func (r *lifecycleRunner) runHookWithRetry(ctx context.Context, name string, hook config.LifecycleHook, workspaceFolder string) error {
+	delay := r.retryDelay
+	for attempt := 0; ; attempt++ {
+		err := r.runHook(ctx, name, hook, workspaceFolder)
+		if err == nil || attempt >= r.hookRetries || ctx.Err() != nil {
+			return err
+		}
+
+		r.logger.Warn("lifecycle hook failed, retrying", "hook", name, "attempt", attempt+1, "retries", r.hookRetries, "error", err)
+		if r.progress != nil {
+			r.progress(ProgressEvent{Phase: PhaseHooks, Message: fmt.Sprintf("%s failed, retrying in %s (%d/%d)...", name, delay, attempt+1, r.hookRetries)})
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
 // dispatchHook runs a LifecycleHook's entries using executor.
 // String/array hooks (stored under the "" key) call executor once, sequentially.
 // Object hooks (named entries) call executor for each entry in parallel via errgroup;
@@ -232,13 +478,122 @@ func (r *lifecycleRunner) runHook(ctx context.Context, name string, hook config.
 	}
 	r.logger.Debug("running lifecycle hook", "hook", name)
 
-	return dispatchHook(ctx, hook, func(ctx context.Context, hookName string, cmdParts []string) error {
-		return r.execHookCmd(ctx, name, hookName, cmdParts, workspaceFolder)
+	stdout, stderr, closeLog := r.hookLogWriters(name)
+	defer closeLog()
+	tail := r.outputTail(name)
+	stdout, stderr = io.MultiWriter(stdout, tail), io.MultiWriter(stderr, tail)
+
+	err := dispatchHook(ctx, hook, func(ctx context.Context, hookName string, cmdParts []string) error {
+		return r.execHookCmd(ctx, name, hookName, cmdParts, workspaceFolder, stdout, stderr)
 	})
+	r.saveHookOutput(name, tail.String())
+	return err
+}
+
+// hookOutputTailSize bounds the output kept per stage in
+// workspace.Result.HookOutput.
+const hookOutputTailSize = 4 << 10
+
+// outputTail returns the tail buffer for a stage, shared by all hooks of the
+// stage in this run so features' output and the user's hook end up together.
+func (r *lifecycleRunner) outputTail(name string) *tailBuffer {
+	if r.outputTails == nil {
+		r.outputTails = make(map[string]*tailBuffer)
+	}
+	tail, ok := r.outputTails[name]
+	if !ok {
+		tail = &tailBuffer{max: hookOutputTailSize}
+		r.outputTails[name] = tail
+	}
+	return tail
+}
+
+// saveHookOutput stores a stage's output tail in the workspace result. It
+// runs after every hook, failed ones included, so the output that explains a
+// failure is kept.
+func (r *lifecycleRunner) saveHookOutput(name, output string) {
+	result, err := r.store.LoadResult(r.workspaceID)
+	if err != nil {
+		r.logger.Warn("failed to load result for hook output, skipping", "hook", name, "error", err)
+		return
+	}
+	if result == nil {
+		result = &workspace.Result{}
+	}
+	if result.HookOutput == nil {
+		result.HookOutput = make(map[string]string)
+	}
+	result.HookOutput[name] = output
+	if err := r.store.SaveResult(r.workspaceID, result); err != nil {
+		r.logger.Warn("failed to store hook output", "hook", name, "error", err)
+	}
+}
+
+// tailBuffer is a writer that keeps only the last max bytes written to it.
+// Object-form entries run in parallel, so writes are serialized.
+type tailBuffer struct {
+	mu        sync.Mutex
+	max       int
+	buf       []byte
+	truncated bool
+}
+
+func (t *tailBuffer) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.buf = append(t.buf, p...)
+	if over := len(t.buf) - t.max; over > 0 {
+		t.buf = append(t.buf[:0], t.buf[over:]...)
+		t.truncated = true
+	}
+	return len(p), nil
+}
+
+// String returns the captured output. Once earlier output was dropped, it
+// starts at the first complete line so the tail doesn't open mid-line.
+func (t *tailBuffer) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out := t.buf
+	if t.truncated {
+		if i := bytes.IndexByte(out, '\n'); i >= 0 && i < len(out)-1 {
+			out = out[i+1:]
+		}
+	}
+	return string(out)
+}
+
+// hookLogWriters returns stdout/stderr writers that also append to the
+// stage's persisted log (see `crib logs --hooks`), plus a function closing
+// the log. Falls back to the plain writers if the log can't be opened.
+func (r *lifecycleRunner) hookLogWriters(name string) (io.Writer, io.Writer, func()) {
+	if r.startedAt.IsZero() {
+		r.startedAt = time.Now()
+	}
+	f, err := r.store.OpenHookLog(r.workspaceID, name, r.startedAt)
+	if err != nil {
+		r.logger.Warn("failed to open hook log", "hook", name, "error", err)
+		return r.stdout, r.stderr, func() {}
+	}
+	// Object-form entries run in parallel and share the file.
+	log := &lockedWriter{w: f}
+	return io.MultiWriter(r.stdout, log), io.MultiWriter(r.stderr, log), func() { _ = f.Close() }
+}
+
+// lockedWriter serializes writes from concurrent hook entries.
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (l *lockedWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.w.Write(p)
 }
 
 // execHookCmd executes a single hook command inside the container.
-func (r *lifecycleRunner) execHookCmd(ctx context.Context, hookStage, hookName string, cmdParts []string, workspaceFolder string) error {
+func (r *lifecycleRunner) execHookCmd(ctx context.Context, hookStage, hookName string, cmdParts []string, workspaceFolder string, stdout, stderr io.Writer) error {
 	if len(cmdParts) == 0 {
 		return nil
 	}
@@ -260,25 +615,68 @@ func (r *lifecycleRunner) execHookCmd(ctx context.Context, hookStage, hookName s
 		cmdStr = plugin.ShellQuoteJoin(cmdParts)
 	}
 
-	// Wrap with user switch and working directory.
-	execCmd := r.wrapCommand(cmdStr, workspaceFolder)
+	// The working directory and user are set by the driver (--workdir,
+	// --user), so the command string is the only thing the shell parses.
+	execCmd := r.wrapCommand(cmdStr)
 
 	r.logger.Debug("executing hook command", "hook", label, "cmd", execCmd)
 	if r.verbose {
 		_, _ = fmt.Fprintf(r.stderr, "  $ %s\n", cmdStr)
 	}
-	if err := r.driver.ExecContainer(ctx, r.workspaceID, r.containerID, execCmd, nil, r.stdout, r.stderr, envSlice(r.remoteEnv), r.remoteUser); err != nil {
+	if err := r.driver.ExecContainer(ctx, r.workspaceID, r.containerID, execCmd, nil, stdout, stderr, envSlice(r.remoteEnv), r.remoteUser, workspaceFolder); err != nil {
+		// The runtime refuses to exec in a missing --workdir with an opaque
+		// chdir error, so name the directory instead.
+		if workspaceFolder != "" && !r.dirExists(ctx, workspaceFolder) {
+			return fmt.Errorf("lifecycle hook %q failed: workspace folder %s does not exist in the container (check workspaceFolder and workspaceMount): %w", label, workspaceFolder, err)
+		}
 		return fmt.Errorf("lifecycle hook %q failed: %w", label, err)
 	}
 	return nil
 }
 
-// wrapCommand wraps a command string to run in the workspace folder.
-// User switching is handled at the driver level via --user.
-func (r *lifecycleRunner) wrapCommand(cmdStr string, workspaceFolder string) []string {
-	inner := cmdStr
-	if workspaceFolder != "" {
-		inner = fmt.Sprintf("cd %q 2>/dev/null; %s", workspaceFolder, inner)
+// dirExists reports whether dir is a directory inside the container.
+func (r *lifecycleRunner) dirExists(ctx context.Context, dir string) bool {
+	err := r.driver.ExecContainer(ctx, r.workspaceID, r.containerID, []string{"test", "-d", dir}, nil, io.Discard, io.Discard, nil, "", "")
+	return err == nil
+}
+
+// wrapCommand wraps a command string in a shell. The working directory and
+// user are handled at the driver level via --workdir and --user.
+func (r *lifecycleRunner) wrapCommand(cmdStr string) []string {
+	return []string{"sh", "-c", cmdStr}
+}
+
+// stageHash returns the marker hash for a marker-guarded stage. For
+// updateContentCommand it also covers the project's content revision, so the
+// stage runs again once new code is checked out (per the spec, it runs when
+// the source tree has new content, not only at creation).
+func (r *lifecycleRunner) stageHash(name string, hooks []config.LifecycleHook) string {
+	hash := hooksHash(hooks)
+	if name != "updateContentCommand" || r.source == "" {
+		return hash
 	}
-	return []string{"sh", "-c", inner}
+	rev := contentRevision(r.source)
+	if rev == "" {
+		return hash
+	}
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(hash+"\n"+rev)))
+}
+
+// contentRevision identifies the content checked out in the project at dir:
+// the git HEAD commit, or "" for projects that are not git repositories.
+// A variable so tests can stub it.
+var contentRevision = func(dir string) string {
+	out, err := exec.Command("git", "-C", dir, "rev-parse", "HEAD").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// hooksHash returns a stable hash of a stage's hook list, used to detect
+// edits to marker-guarded hooks.
+func hooksHash(hooks []config.LifecycleHook) string {
+	// json.Marshal sorts map keys, so equal hooks always hash the same.
+	data, _ := json.Marshal(hooks)
+	return fmt.Sprintf("%x", sha256.Sum256(data))
 }
@@ -1,54 +1,74 @@
 package engine
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"os"
+	"path/filepath"
 	"reflect"
+	"slices"
 	"sort"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/fgrehm/crib/internal/config"
 	"github.com/fgrehm/crib/internal/workspace"
 )
 
-func TestWrapCommand_AsRoot(t *testing.T) {
-	r := &lifecycleRunner{remoteUser: "root"}
-	cmd := r.wrapCommand("echo hello", "/workspaces/project")
+func TestWrapCommand(t *testing.T) {
+	r := &lifecycleRunner{remoteUser: "vscode"}
+	cmd := r.wrapCommand("echo hello")
 
-	if len(cmd) != 3 || cmd[0] != "sh" || cmd[1] != "-c" {
-		t.Errorf("expected [sh -c <script>] wrapper, got %v", cmd)
-	}
-	if !strings.Contains(cmd[2], "echo hello") {
-		t.Errorf("expected command in wrapper, got %v", cmd)
-	}
-	if !strings.Contains(cmd[2], "cd \"/workspaces/project\"") {
-		t.Errorf("expected cd in wrapper, got %v", cmd)
+	// The working directory and user are set by the driver, not the wrapper.
+	if !reflect.DeepEqual(cmd, []string{"sh", "-c", "echo hello"}) {
+		t.Errorf("expected [sh -c echo hello], got %v", cmd)
 	}
 }
 
-func TestWrapCommand_AsUser(t *testing.T) {
-	// User switching is handled at the driver level via --user, not by wrapping with su.
-	r := &lifecycleRunner{remoteUser: "vscode"}
-	cmd := r.wrapCommand("echo hello", "/workspaces/project")
+func TestExecHookCmd_PassesWorkdirToDriver(t *testing.T) {
+	mock := &mockDriver{}
+	r, _, _ := newTestRunner(t, mock)
 
-	if len(cmd) != 3 || cmd[0] != "sh" || cmd[1] != "-c" {
-		t.Errorf("expected [sh -c <script>] wrapper, got %v", cmd)
+	workspaceFolder := "/workspaces/my project's $dir"
+	if err := r.execHookCmd(context.Background(), "postCreateCommand", "", []string{"make setup"}, workspaceFolder, io.Discard, io.Discard); err != nil {
+		t.Fatal(err)
 	}
-	if !strings.Contains(cmd[2], "echo hello") {
-		t.Errorf("expected command in wrapper, got %v", cmd)
+
+	if len(mock.execCalls) != 1 {
+		t.Fatalf("expected 1 exec call, got %d", len(mock.execCalls))
+	}
+	call := mock.execCalls[0]
+	if call.workdir != workspaceFolder {
+		t.Errorf("workdir = %q, want %q", call.workdir, workspaceFolder)
+	}
+	if strings.Contains(strings.Join(call.cmd, " "), "cd ") {
+		t.Errorf("command should not cd into the workspace folder, got %v", call.cmd)
 	}
 }
 
-func TestWrapCommand_EmptyWorkspaceFolder(t *testing.T) {
-	r := &lifecycleRunner{remoteUser: "root"}
-	cmd := r.wrapCommand("echo test", "")
+func TestExecHookCmd_MissingWorkdir(t *testing.T) {
+	for _, missing := range []bool{false, true} {
+		chdirErr := fmt.Errorf("OCI runtime exec failed: chdir: no such file or directory")
+		mock := &mockDriver{errors: map[string]error{"sh -c make setup": chdirErr}}
+		if missing {
+			mock.errors["test -d /workspaces/gone"] = fmt.Errorf("exit status 1")
+		}
+		r, _, _ := newTestRunner(t, mock)
 
-	// Should not contain cd when workspace folder is empty.
-	if strings.Contains(strings.Join(cmd, " "), "cd ") {
-		t.Errorf("should not cd when workspace folder is empty, got %v", cmd)
+		err := r.execHookCmd(context.Background(), "postCreateCommand", "", []string{"make setup"}, "/workspaces/gone", io.Discard, io.Discard)
+		if !errors.Is(err, chdirErr) {
+			t.Fatalf("missing=%v: err = %v, want it to wrap the exec error", missing, err)
+		}
+		named := strings.Contains(err.Error(), "workspace folder /workspaces/gone does not exist")
+		if named != missing {
+			t.Errorf("missing=%v: err = %v", missing, err)
+		}
 	}
 }
 
@@ -134,6 +154,110 @@ func TestRunHook_Sequential_String(t *testing.T) {
 	}
 }
 
+func TestRunHook_PersistsOutputToHookLog(t *testing.T) {
+	mock := &mockDriver{responses: map[string]string{
+		"sh -c npm install": "added 42 packages\n",
+	}}
+	r, store, wsID := newTestRunner(t, mock)
+	var stdout bytes.Buffer
+	r.stdout = &stdout
+
+	hook := config.LifecycleHook{"": {"npm install"}}
+	if err := r.runHook(context.Background(), "postCreateCommand", hook, ""); err != nil {
+		t.Fatalf("runHook: %v", err)
+	}
+
+	// Output still streams to the terminal...
+	if stdout.String() != "added 42 packages\n" {
+		t.Errorf("stdout = %q", stdout.String())
+	}
+
+	// ...and is persisted under the workspace dir for `crib logs --hooks`.
+	logs, err := store.HookLogs(wsID, "postCreateCommand")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logs) != 1 {
+		t.Fatalf("expected 1 hook log, got %d", len(logs))
+	}
+	if dir := filepath.Join(store.WorkspaceDir(wsID), "hook-logs"); filepath.Dir(logs[0].Path) != dir {
+		t.Errorf("log path = %s, want under %s", logs[0].Path, dir)
+	}
+	data, err := os.ReadFile(logs[0].Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "added 42 packages\n" {
+		t.Errorf("hook log = %q", data)
+	}
+}
+
+func TestRunHook_StoresOutputTail(t *testing.T) {
+	var out strings.Builder
+	for i := range 500 {
+		fmt.Fprintf(&out, "installing package %d\n", i)
+	}
+	mock := &mockDriver{responses: map[string]string{
+		"sh -c ./setup.sh": out.String(),
+		"sh -c echo done":  "done\n",
+	}}
+	r, store, wsID := newTestRunner(t, mock)
+	var stdout bytes.Buffer
+	r.stdout = &stdout
+
+	// Feature hook first, then the user's hook, as in a merged stage.
+	for _, hook := range []config.LifecycleHook{{"": {"./setup.sh"}}, {"": {"echo done"}}} {
+		if err := r.runHook(context.Background(), "postCreateCommand", hook, ""); err != nil {
+			t.Fatalf("runHook: %v", err)
+		}
+	}
+
+	if stdout.String() != out.String()+"done\n" {
+		t.Errorf("stdout should still receive the full output, got %d bytes", stdout.Len())
+	}
+
+	result, err := store.LoadResult(wsID)
+	if err != nil || result == nil {
+		t.Fatalf("LoadResult: %v, %v", result, err)
+	}
+	got := result.HookOutput["postCreateCommand"]
+	if len(got) > hookOutputTailSize {
+		t.Errorf("stored output is %d bytes, want at most %d", len(got), hookOutputTailSize)
+	}
+	if !strings.HasSuffix(got, "installing package 499\ndone\n") {
+		t.Errorf("stored output should end with the stage's last lines, got ...%q", got[max(0, len(got)-60):])
+	}
+	if !strings.HasPrefix(got, "installing package ") {
+		t.Errorf("stored output should start at a line boundary, got %q...", got[:min(len(got), 40)])
+	}
+	if strings.Contains(got, "installing package 0\n") {
+		t.Error("stored output should drop the beginning of long output")
+	}
+}
+
+func TestTailBuffer(t *testing.T) {
+	tail := &tailBuffer{max: 10}
+	_, _ = tail.Write([]byte("abc\n"))
+	if got := tail.String(); got != "abc\n" {
+		t.Errorf("String() = %q, want %q", got, "abc\n")
+	}
+	_, _ = tail.Write([]byte("defgh\nij\n"))
+	// Keeps "\ndefgh\nij\n", whose first line is the (empty) end of "abc".
+	if got := tail.String(); got != "defgh\nij\n" {
+		t.Errorf("String() = %q, want %q", got, "defgh\nij\n")
+	}
+	_, _ = tail.Write([]byte("k\n"))
+	// Keeps "fgh\nij\nk\n" and drops the partial first line.
+	if got := tail.String(); got != "ij\nk\n" {
+		t.Errorf("String() = %q, want %q", got, "ij\nk\n")
+	}
+	tail = &tailBuffer{max: 4}
+	_, _ = tail.Write([]byte("no newlines here"))
+	if got := tail.String(); got != "here" {
+		t.Errorf("String() = %q, want %q", got, "here")
+	}
+}
+
 func TestRunHook_Sequential_Array(t *testing.T) {
 	// Array-form hook: each element is shell-quoted before joining.
 	// Arguments with spaces must arrive as single tokens inside sh -c.
@@ -436,6 +560,152 @@ func TestRunLifecycleHooks_WaitFor_PostStart(t *testing.T) {
 	}
 }
 
+// blockingHookDriver blocks exec calls for the "sleep forever" hook until
+// release is closed or the exec context is cancelled.
+type blockingHookDriver struct {
+	*mockDriver
+	release chan struct{}
+}
+
+func (d *blockingHookDriver) ExecContainer(ctx context.Context, workspaceID, containerID string, cmd []string, stdin io.Reader, stdout, stderr io.Writer, env []string, user, workdir string) error {
+	if strings.Join(cmd, " ") == "sh -c sleep forever" {
+		select {
+		case <-d.release:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return d.mockDriver.ExecContainer(ctx, workspaceID, containerID, cmd, stdin, stdout, stderr, env, user, workdir)
+}
+
+// syncProgress collects progress messages from the hook goroutine and the
+// waitFor timer.
+type syncProgress struct {
+	mu   sync.Mutex
+	msgs []string
+}
+
+func (p *syncProgress) record(ev ProgressEvent) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.msgs = append(p.msgs, ev.Message)
+}
+
+func (p *syncProgress) snapshot() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return slices.Clone(p.msgs)
+}
+
+func (p *syncProgress) count(msg string) int {
+	n := 0
+	for _, m := range p.snapshot() {
+		if m == msg {
+			n++
+		}
+	}
+	return n
+}
+
+func TestRunLifecycleHooks_WaitForTimeout_SignalsReady(t *testing.T) {
+	mock := &mockDriver{}
+	r, store, wsID := newTestRunner(t, mock)
+	d := &blockingHookDriver{mockDriver: mock, release: make(chan struct{})}
+	r.driver = d
+	r.waitForTimeout = 20 * time.Millisecond
+	progress := &syncProgress{}
+	r.progress = progress.record
+
+	hooks := &hookSet{
+		OnCreate:   []config.LifecycleHook{{"": {"sleep forever"}}},
+		PostCreate: []config.LifecycleHook{{"": {"echo postcreate"}}},
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- runAllHooks(r, context.Background(), hooks, "") }()
+
+	// The hook keeps running past the timeout; readiness is reported anyway.
+	deadline := time.Now().Add(5 * time.Second)
+	for progress.count("Container ready.") == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Container ready. not reported after waitFor timeout: %v", progress.snapshot())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	select {
+	case err := <-done:
+		t.Fatalf("hooks returned before the blocked hook finished: %v", err)
+	default:
+	}
+
+	close(d.release)
+	if err := <-done; err != nil {
+		t.Fatalf("runAllHooks: %v", err)
+	}
+
+	msgs := progress.snapshot()
+	if n := progress.count("Container ready."); n != 1 {
+		t.Errorf("Container ready. emitted %d times, want 1: %v", n, msgs)
+	}
+	if indexOfMsg(msgs, func(m string) bool { return strings.Contains(m, "updateContentCommand still running after") }) < 0 {
+		t.Errorf("expected a waitFor timeout warning, got %v", msgs)
+	}
+	if !store.IsHookDone(wsID, "onCreateCommand") {
+		t.Error("onCreateCommand marker should be set once the slow hook finishes")
+	}
+}
+
+func TestRunLifecycleHooks_WaitForTimeout_Fail(t *testing.T) {
+	mock := &mockDriver{}
+	r, store, wsID := newTestRunner(t, mock)
+	r.driver = &blockingHookDriver{mockDriver: mock, release: make(chan struct{})}
+	r.waitForTimeout = 20 * time.Millisecond
+	r.waitForFail = true
+	progress := &syncProgress{}
+	r.progress = progress.record
+
+	hooks := &hookSet{
+		OnCreate:   []config.LifecycleHook{{"": {"echo create"}}},
+		PostCreate: []config.LifecycleHook{{"": {"sleep forever"}}},
+		WaitFor:    "postCreateCommand",
+	}
+
+	err := runAllHooks(r, context.Background(), hooks, "")
+	var timeoutErr *ErrWaitForTimeout
+	if !errors.As(err, &timeoutErr) {
+		t.Fatalf("expected ErrWaitForTimeout, got %v", err)
+	}
+	if timeoutErr.Stage != "postCreateCommand" || timeoutErr.WaitFor != "postCreateCommand" {
+		t.Errorf("ErrWaitForTimeout = %+v, want stage and waitFor postCreateCommand", timeoutErr)
+	}
+	if n := progress.count("Container ready."); n != 0 {
+		t.Errorf("Container ready. should not be reported on failure: %v", progress.snapshot())
+	}
+	if !store.IsHookDone(wsID, "onCreateCommand") {
+		t.Error("onCreateCommand finished before the timeout and should keep its marker")
+	}
+	if store.IsHookDone(wsID, "postCreateCommand") {
+		t.Error("aborted postCreateCommand must not be marked done")
+	}
+}
+
+func TestRunLifecycleHooks_WaitForTimeout_NotHit(t *testing.T) {
+	mock := &mockDriver{}
+	r, _, _ := newTestRunner(t, mock)
+	r.waitForTimeout = time.Hour
+	r.waitForFail = true
+	progress := &syncProgress{}
+	r.progress = progress.record
+
+	hooks := &hookSet{OnCreate: []config.LifecycleHook{{"": {"echo create"}}}}
+	if err := runAllHooks(r, context.Background(), hooks, ""); err != nil {
+		t.Fatalf("runAllHooks: %v", err)
+	}
+	if n := progress.count("Container ready."); n != 1 {
+		t.Errorf("Container ready. emitted %d times, want 1: %v", n, progress.snapshot())
+	}
+}
+
 func TestRunLifecycleHooks_FeatureHooksBeforeUser(t *testing.T) {
 	// Feature hooks should execute before user hooks at each stage.
 	// The merged hookSet contains feature hooks first, user hook last.
@@ -729,3 +999,195 @@ func TestRunStartHooks_OnlyRunsStartTimeStages(t *testing.T) {
 		t.Errorf("expected [postStart postAttach], got %v", ran)
 	}
 }
+
+// --- hook retry tests ---
+
+// flakyMock returns a mockDriver whose cmdKey fails for the first failures
+// attempts and succeeds afterwards.
+func flakyMock(cmdKey string, failures int) *mockDriver {
+	mock := &mockDriver{errors: map[string]error{cmdKey: fmt.Errorf("network unreachable")}}
+	attempts := 0
+	mock.execCallback = func(cmd []string) {
+		if strings.Join(cmd, " ") != cmdKey {
+			return
+		}
+		attempts++
+		if attempts > failures {
+			delete(mock.errors, cmdKey)
+		}
+	}
+	return mock
+}
+
+func TestRunCreateHooks_RetriesTransientFailure(t *testing.T) {
+	mock := flakyMock("sh -c npm install", 1)
+	r, store, wsID := newTestRunner(t, mock)
+	r.hookRetries = 2
+
+	var msgs []string
+	r.progress = collectProgress(&msgs)
+
+	hooks := &hookSet{PostCreate: []config.LifecycleHook{{"": {"npm install"}}}}
+	if err := r.runCreateHooks(context.Background(), hooks, ""); err != nil {
+		t.Fatalf("runCreateHooks: %v", err)
+	}
+
+	if len(mock.execCalls) != 2 {
+		t.Errorf("expected 2 exec calls (fail + retry), got %d", len(mock.execCalls))
+	}
+	if !store.IsHookDone(wsID, "postCreateCommand") {
+		t.Error("expected postCreateCommand marker to be set after successful retry")
+	}
+	if indexOfMsg(msgs, func(m string) bool { return strings.Contains(m, "retrying") }) < 0 {
+		t.Errorf("expected a retry progress message, got %v", msgs)
+	}
+}
+
+func TestRunCreateHooks_RetriesExhausted(t *testing.T) {
+	mock := flakyMock("sh -c npm install", 5)
+	r, store, wsID := newTestRunner(t, mock)
+	r.hookRetries = 2
+
+	hooks := &hookSet{PostCreate: []config.LifecycleHook{{"": {"npm install"}}}}
+	if err := r.runCreateHooks(context.Background(), hooks, ""); err == nil {
+		t.Fatal("expected error after retries are exhausted")
+	}
+
+	if len(mock.execCalls) != 3 {
+		t.Errorf("expected 3 exec calls (1 + 2 retries), got %d", len(mock.execCalls))
+	}
+	if store.IsHookDone(wsID, "postCreateCommand") {
+		t.Error("marker should not be set when the hook ultimately fails")
+	}
+}
+
+func TestRunCreateHooks_NoRetriesByDefault(t *testing.T) {
+	mock := flakyMock("sh -c npm install", 1)
+	r, _, _ := newTestRunner(t, mock)
+
+	hooks := &hookSet{PostCreate: []config.LifecycleHook{{"": {"npm install"}}}}
+	if err := r.runCreateHooks(context.Background(), hooks, ""); err == nil {
+		t.Fatal("expected error without retries")
+	}
+	if len(mock.execCalls) != 1 {
+		t.Errorf("expected 1 exec call, got %d", len(mock.execCalls))
+	}
+}
+
+func TestRunCreateHooks_DoesNotRetryCompletedStage(t *testing.T) {
+	mock := flakyMock("sh -c npm install", 1)
+	r, store, wsID := newTestRunner(t, mock)
+	r.hookRetries = 3
+	if err := store.MarkHookDone(wsID, "postCreateCommand", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	hooks := &hookSet{PostCreate: []config.LifecycleHook{{"": {"npm install"}}}}
+	if err := r.runCreateHooks(context.Background(), hooks, ""); err != nil {
+		t.Fatalf("runCreateHooks: %v", err)
+	}
+	if len(mock.execCalls) != 0 {
+		t.Errorf("expected no exec calls for a completed stage, got %d", len(mock.execCalls))
+	}
+}
+
+func TestRunCreateHooks_EditedHookReruns(t *testing.T) {
+	mock := &mockDriver{}
+	r, _, _ := newTestRunner(t, mock)
+
+	hooks := &hookSet{PostCreate: []config.LifecycleHook{{"": {"npm install"}}}}
+	for range 2 {
+		if err := r.runCreateHooks(context.Background(), hooks, ""); err != nil {
+			t.Fatalf("runCreateHooks: %v", err)
+		}
+	}
+	if len(mock.execCalls) != 1 {
+		t.Fatalf("unchanged hook should run once, got %d exec calls", len(mock.execCalls))
+	}
+
+	hooks.PostCreate = []config.LifecycleHook{{"": {"npm ci"}}}
+	if err := r.runCreateHooks(context.Background(), hooks, ""); err != nil {
+		t.Fatalf("runCreateHooks: %v", err)
+	}
+	if len(mock.execCalls) != 2 {
+		t.Fatalf("edited hook should re-run, got %d exec calls", len(mock.execCalls))
+	}
+	if got := strings.Join(mock.execCalls[1].cmd, " "); got != "sh -c npm ci" {
+		t.Errorf("re-run cmd = %q, want %q", got, "sh -c npm ci")
+	}
+}
+
+func TestRunChangedCreateHooks(t *testing.T) {
+	mock := &mockDriver{}
+	r, store, wsID := newTestRunner(t, mock)
+
+	original := []config.LifecycleHook{{"": {"make setup"}}}
+	if err := store.MarkHookDone(wsID, "onCreateCommand", hooksHash(original)); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.MarkHookDone(wsID, "postCreateCommand", hooksHash(original)); err != nil {
+		t.Fatal(err)
+	}
+
+	hooks := &hookSet{
+		OnCreate:      original,                                          // unchanged: skipped
+		UpdateContent: []config.LifecycleHook{{"": {"git pull"}}},        // never ran: skipped
+		PostCreate:    []config.LifecycleHook{{"": {"make setup seed"}}}, // edited: re-run
+	}
+	ran, err := r.runChangedCreateHooks(context.Background(), hooks, "")
+	if err != nil {
+		t.Fatalf("runChangedCreateHooks: %v", err)
+	}
+	if !ran {
+		t.Error("runChangedCreateHooks should report that a stage ran")
+	}
+
+	if len(mock.execCalls) != 1 {
+		t.Fatalf("expected only the edited stage to run, got %d exec calls", len(mock.execCalls))
+	}
+	if got := strings.Join(mock.execCalls[0].cmd, " "); got != "sh -c make setup seed" {
+		t.Errorf("cmd = %q, want %q", got, "sh -c make setup seed")
+	}
+	if got := store.HookDoneHash(wsID, "postCreateCommand"); got != hooksHash(hooks.PostCreate) {
+		t.Errorf("postCreateCommand marker hash = %q, want the new hash", got)
+	}
+}
+
+func TestRunChangedCreateHooks_ContentRevision(t *testing.T) {
+	rev := "aaa111"
+	orig := contentRevision
+	contentRevision = func(string) string { return rev }
+	t.Cleanup(func() { contentRevision = orig })
+
+	mock := &mockDriver{}
+	r, _, _ := newTestRunner(t, mock)
+	r.source = t.TempDir()
+
+	hooks := &hookSet{
+		OnCreate:      []config.LifecycleHook{{"": {"make setup"}}},
+		UpdateContent: []config.LifecycleHook{{"": {"bundle install"}}},
+	}
+	if err := r.runCreateHooks(context.Background(), hooks, ""); err != nil {
+		t.Fatalf("runCreateHooks: %v", err)
+	}
+	mock.execCalls = nil
+
+	// Same checkout: nothing to re-run.
+	ran, err := r.runChangedCreateHooks(context.Background(), hooks, "")
+	if err != nil || ran || len(mock.execCalls) != 0 {
+		t.Fatalf("unchanged revision: ran = %v, err = %v, exec calls = %d", ran, err, len(mock.execCalls))
+	}
+
+	// New commit checked out: updateContentCommand re-runs, onCreate does not.
+	rev = "bbb222"
+	ran, err = r.runChangedCreateHooks(context.Background(), hooks, "")
+	if err != nil {
+		t.Fatalf("runChangedCreateHooks: %v", err)
+	}
+	if !ran || len(mock.execCalls) != 1 {
+		t.Fatalf("new revision: ran = %v, exec calls = %d, want 1", ran, len(mock.execCalls))
+	}
+	if got := strings.Join(mock.execCalls[0].cmd, " "); got != "sh -c bundle install" {
+		t.Errorf("cmd = %q, want %q", got, "sh -c bundle install")
+	}
+}
@@ -1,11 +1,16 @@
 package engine
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
-	"github.com/fgrehm/crib/internal/compose"
 	"github.com/fgrehm/crib/internal/config"
 	"github.com/fgrehm/crib/internal/driver"
 	"github.com/fgrehm/crib/internal/workspace"
@@ -15,6 +20,7 @@ import (
 type LogsOptions struct {
 	Follow bool   // stream logs as they are produced
 	Tail   string // number of lines from the end ("all" or a number)
+	JSON   bool   // write one JSON object per line (see logLine) instead of raw output
 }
 
 // Logs streams container logs for the given workspace.
@@ -58,18 +64,239 @@ func (e *Engine) logsSingle(ctx context.Context, ws *workspace.Workspace, stored
 	}
 
 	driverOpts := &driver.LogsOptions{
-		Follow: opts.Follow,
-		Tail:   opts.Tail,
+		Follow:     opts.Follow,
+		Tail:       opts.Tail,
+		Timestamps: opts.JSON,
 	}
-	return e.driver.ContainerLogs(ctx, ws.ID, container.ID, e.stdout, e.stderr, driverOpts)
+	stdout, stderr, flush := e.logWriters(opts, "")
+	defer flush()
+	return e.driver.ContainerLogs(ctx, ws.ID, container.ID, stdout, stderr, driverOpts)
 }
 
 // logsCompose streams logs from all compose services.
 func (e *Engine) logsCompose(ctx context.Context, ws *workspace.Workspace, storedResult *workspace.Result, cfg *config.DevContainerConfig, opts LogsOptions) error {
-	cd := configDir(ws)
-	composeFiles := resolveComposeFiles(cd, cfg.DockerComposeFile)
-	projectName := compose.ProjectName(ws.ID)
-	env := devcontainerEnv(ws.ID, ws.Source, storedResult.WorkspaceFolder)
+	inv := newComposeInvocation(ws, cfg, storedResult.WorkspaceFolder)
+	stdout, stderr, flush := e.logWriters(opts, inv.projectName)
+	defer flush()
+	return e.compose.Logs(ctx, inv.projectName, inv.files, opts.Follow, opts.Tail, opts.JSON, stdout, stderr, inv.env)
+}
+
+// logWriters returns the writers Logs streams the runtime's stdout and
+// stderr to. With opts.JSON both are converted to logLine objects on
+// e.stdout; composeProject names the compose project whose container
+// prefixes identify the service, and is empty for single containers. The
+// returned function writes out a trailing line without a newline.
+func (e *Engine) logWriters(opts LogsOptions, composeProject string) (io.Writer, io.Writer, func()) {
+	if !opts.JSON {
+		return e.stdout, e.stderr, func() {}
+	}
+	enc := &logEncoder{enc: json.NewEncoder(e.stdout), composeProject: composeProject}
+	stdout := &logLineWriter{enc: enc, stream: "stdout"}
+	stderr := &logLineWriter{enc: enc, stream: "stderr"}
+	return stdout, stderr, func() {
+		stdout.flush()
+		stderr.flush()
+	}
+}
+
+// logLine is one line of container output as written by `crib logs --json`.
+type logLine struct {
+	Event     string `json:"event"`               // always "log"
+	Stream    string `json:"stream"`              // "stdout" or "stderr"
+	Timestamp string `json:"timestamp,omitempty"` // RFC 3339, when the runtime reported one
+	Service   string `json:"service,omitempty"`   // compose service name
+	Message   string `json:"message"`
+}
+
+// parseLogLine converts a line of `logs --timestamps` output into a
+// logLine. For compose workspaces (composeProject set) the "name | "
+// container prefix is stripped and mapped to the service name. Lines
+// without a leading timestamp are kept whole with no timestamp.
+func parseLogLine(line, stream, composeProject string) logLine {
+	out := logLine{Event: "log", Stream: stream}
+	line = strings.TrimSuffix(line, "\r")
+
+	if composeProject != "" {
+		if i := strings.Index(line, " |"); i >= 0 {
+			out.Service = composeServiceName(strings.TrimSpace(line[:i]), composeProject)
+			line = strings.TrimPrefix(line[i+2:], " ")
+		}
+	}
 
-	return e.compose.Logs(ctx, projectName, composeFiles, opts.Follow, opts.Tail, e.stdout, e.stderr, env)
+	ts, msg, _ := strings.Cut(line, " ")
+	if _, err := time.Parse(time.RFC3339Nano, ts); err == nil {
+		out.Timestamp = ts
+		line = msg
+	}
+	out.Message = line
+	return out
+}
+
+// composeServiceName derives the service from a compose logs prefix: the
+// container name ("<project>-<service>-<n>", with "_" separators for
+// podman-compose) or the "<service>-<n>" short form Docker Compose prints.
+func composeServiceName(prefix, project string) string {
+	name := strings.Trim(prefix, "[]")
+	for _, sep := range []string{"-", "_"} {
+		name = strings.TrimPrefix(name, project+sep)
+	}
+	if i := strings.LastIndexAny(name, "-_"); i > 0 {
+		if _, err := strconv.Atoi(name[i+1:]); err == nil {
+			name = name[:i]
+		}
+	}
+	return name
+}
+
+// logEncoder serializes logLine objects from the stdout and stderr writers
+// onto a single stream.
+type logEncoder struct {
+	mu             sync.Mutex
+	enc            *json.Encoder
+	composeProject string
+}
+
+func (l *logEncoder) encode(line, stream string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.enc.Encode(parseLogLine(line, stream, l.composeProject))
+}
+
+// logLineWriter buffers one stream of log output and encodes each complete
+// line.
+type logLineWriter struct {
+	enc    *logEncoder
+	stream string
+	buf    []byte
+}
+
+func (w *logLineWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		line := string(w.buf[:i])
+		w.buf = w.buf[i+1:]
+		if err := w.enc.encode(line, w.stream); err != nil {
+			return len(p), err
+		}
+	}
+}
+
+// flush encodes a trailing partial line, if any.
+func (w *logLineWriter) flush() {
+	if len(w.buf) > 0 {
+		_ = w.enc.encode(string(w.buf), w.stream)
+		w.buf = nil
+	}
+}
+
+// Foreground attaches to the output of the workspace container's main
+// process until it exits, right after Up, so output from before setup
+// finished is not replayed. It returns nil when the process exits cleanly
+// and *ErrContainerExited carrying the exit code otherwise, also when the
+// process already exited during setup. The config must set overrideCommand
+// to false: with the default keep-alive command the container never exits
+// on its own.
+func (e *Engine) Foreground(ctx context.Context, ws *workspace.Workspace) error {
+	keepAlive, err := e.storedOverrideCommand(ws)
+	if err != nil {
+		return err
+	}
+	if keepAlive {
+		return fmt.Errorf("foreground mode requires \"overrideCommand\": false (the container runs a keep-alive command otherwise)")
+	}
+
+	container, err := e.driver.FindContainer(ctx, ws.ID)
+	if err != nil {
+		return fmt.Errorf("finding container: %w", err)
+	}
+	if container == nil {
+		return &ErrNoContainer{WorkspaceID: ws.ID}
+	}
+	if !container.State.IsRunning() {
+		return exitResult(ws, container)
+	}
+	return e.attachUntilExit(ctx, ws, container)
+}
+
+// Attach attaches to the output of the workspace container's main process
+// until it exits. Like Foreground it does not replay earlier output. It
+// returns nil when the process exits cleanly and *ErrContainerExited
+// carrying the exit code otherwise. Containers running crib's keep-alive
+// command (overrideCommand unset or true) have nothing to attach to.
+func (e *Engine) Attach(ctx context.Context, ws *workspace.Workspace) error {
+	keepAlive, err := e.storedOverrideCommand(ws)
+	if err != nil {
+		return err
+	}
+	if keepAlive {
+		return fmt.Errorf("attach requires \"overrideCommand\": false (the container runs a keep-alive command otherwise); use 'crib shell' instead")
+	}
+
+	container, err := e.driver.FindContainer(ctx, ws.ID)
+	if err != nil {
+		return fmt.Errorf("finding container: %w", err)
+	}
+	if container == nil {
+		return &ErrNoContainer{WorkspaceID: ws.ID}
+	}
+	if !container.State.IsRunning() {
+		return &ErrContainerStopped{WorkspaceID: ws.ID, ContainerID: container.ID}
+	}
+	return e.attachUntilExit(ctx, ws, container)
+}
+
+// attachUntilExit attaches to the running container's main process and,
+// once it exits, reports its exit code through exitResult.
+func (e *Engine) attachUntilExit(ctx context.Context, ws *workspace.Workspace, container *driver.ContainerDetails) error {
+	if err := e.driver.AttachContainer(ctx, ws.ID, container.ID, e.stdout, e.stderr); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		// The runtime exits with the container's exit code, which surfaces
+		// as an error; fall through and report it from the container state.
+		e.logger.Debug("attach ended with error", "error", err)
+	}
+
+	container, err := e.driver.FindContainer(ctx, ws.ID)
+	if err != nil {
+		return fmt.Errorf("finding container: %w", err)
+	}
+	if container == nil {
+		return &ErrNoContainer{WorkspaceID: ws.ID}
+	}
+	if container.State.IsRunning() {
+		return fmt.Errorf("attach ended but container is still running")
+	}
+	return exitResult(ws, container)
+}
+
+// exitResult returns *ErrContainerExited for a container whose main process
+// exited with a non-zero code, and nil otherwise.
+func exitResult(ws *workspace.Workspace, container *driver.ContainerDetails) error {
+	if code := container.State.ExitCode; code != 0 {
+		return &ErrContainerExited{WorkspaceID: ws.ID, ExitCode: code}
+	}
+	return nil
+}
+
+// storedOverrideCommand reports whether the config stored by the last
+// 'crib up' runs the container with crib's keep-alive command, i.e.
+// overrideCommand is unset or true.
+func (e *Engine) storedOverrideCommand(ws *workspace.Workspace) (bool, error) {
+	storedResult, err := e.store.LoadResult(ws.ID)
+	if err != nil {
+		return false, fmt.Errorf("loading workspace result: %w", err)
+	}
+	if storedResult == nil {
+		return false, fmt.Errorf("no previous result found for workspace %s (run 'crib up' first)", ws.ID)
+	}
+	var cfg config.DevContainerConfig
+	if err := json.Unmarshal(storedResult.MergedConfig, &cfg); err != nil {
+		return false, fmt.Errorf("unmarshaling stored config: %w", err)
+	}
+	return cfg.OverrideCommand == nil || *cfg.OverrideCommand, nil
 }
@@ -7,6 +7,7 @@ import (
 	"errors"
 	"io"
 	"log/slog"
+	"strings"
 	"testing"
 
 	"github.com/fgrehm/crib/internal/config"
@@ -88,6 +89,109 @@ func TestLogs_SingleContainer(t *testing.T) {
 	}
 }
 
+func TestParseLogLine(t *testing.T) {
+	const ts = "2026-05-02T10:15:30.123456789Z"
+	tests := []struct {
+		name    string
+		line    string
+		stream  string
+		project string
+		want    logLine
+	}{
+		{
+			name: "single container", line: ts + " listening on :3000", stream: "stdout",
+			want: logLine{Event: "log", Stream: "stdout", Timestamp: ts, Message: "listening on :3000"},
+		},
+		{
+			name: "no timestamp", line: "plain output", stream: "stderr",
+			want: logLine{Event: "log", Stream: "stderr", Message: "plain output"},
+		},
+		{
+			name: "empty message", line: ts, stream: "stdout",
+			want: logLine{Event: "log", Stream: "stdout", Timestamp: ts},
+		},
+		{
+			name: "carriage return", line: ts + " progress\r", stream: "stdout",
+			want: logLine{Event: "log", Stream: "stdout", Timestamp: ts, Message: "progress"},
+		},
+		{
+			name: "compose short prefix", line: "app-1  | " + ts + " ready", stream: "stdout", project: "crib-ws",
+			want: logLine{Event: "log", Stream: "stdout", Timestamp: ts, Service: "app", Message: "ready"},
+		},
+		{
+			name: "compose container name", line: "crib-ws-db-1  | " + ts + " accepting connections", stream: "stdout", project: "crib-ws",
+			want: logLine{Event: "log", Stream: "stdout", Timestamp: ts, Service: "db", Message: "accepting connections"},
+		},
+		{
+			name: "podman-compose container name", line: "[crib-ws_my-api_1] | " + ts + " started", stream: "stdout", project: "crib-ws",
+			want: logLine{Event: "log", Stream: "stdout", Timestamp: ts, Service: "my-api", Message: "started"},
+		},
+		{
+			name: "compose empty line", line: "app-1  |", stream: "stdout", project: "crib-ws",
+			want: logLine{Event: "log", Stream: "stdout", Service: "app"},
+		},
+		{
+			name: "pipe in single container output", line: "a | b", stream: "stdout",
+			want: logLine{Event: "log", Stream: "stdout", Message: "a | b"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseLogLine(tt.line, tt.stream, tt.project); got != tt.want {
+				t.Errorf("parseLogLine(%q) = %+v, want %+v", tt.line, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLogLineWriter_SplitsLines(t *testing.T) {
+	var out bytes.Buffer
+	e := &Engine{stdout: &out, stderr: io.Discard}
+	stdout, stderr, flush := e.logWriters(LogsOptions{JSON: true}, "")
+
+	io.WriteString(stdout, "2026-05-02T10:15:30Z first\n2026-05-02T10:15:31Z sec")
+	io.WriteString(stderr, "2026-05-02T10:15:32Z oops\n")
+	io.WriteString(stdout, "ond\ntrailing")
+	flush()
+
+	want := `{"event":"log","stream":"stdout","timestamp":"2026-05-02T10:15:30Z","message":"first"}
+{"event":"log","stream":"stderr","timestamp":"2026-05-02T10:15:32Z","message":"oops"}
+{"event":"log","stream":"stdout","timestamp":"2026-05-02T10:15:31Z","message":"second"}
+{"event":"log","stream":"stdout","message":"trailing"}
+`
+	if out.String() != want {
+		t.Errorf("output =\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+func TestLogs_JSON(t *testing.T) {
+	store := workspace.NewStoreAt(t.TempDir())
+	ws := &workspace.Workspace{ID: "ws-logs-json", Source: "/home/user/project"}
+	if err := store.Save(ws); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SaveResult(ws.ID, &workspace.Result{MergedConfig: []byte(`{"image":"ubuntu:22.04"}`)}); err != nil {
+		t.Fatal(err)
+	}
+	mockDrv := &logsMockDriver{
+		container: &driver.ContainerDetails{ID: "container-1", State: driver.ContainerState{Status: "running"}},
+	}
+	var stdout bytes.Buffer
+	eng := &Engine{driver: mockDrv, store: store, logger: slog.Default(), stdout: &stdout, stderr: io.Discard}
+
+	if err := eng.Logs(context.Background(), ws, LogsOptions{JSON: true}); err != nil {
+		t.Fatalf("Logs: %v", err)
+	}
+
+	if !mockDrv.logsOpts.Timestamps {
+		t.Error("expected Timestamps=true with JSON output")
+	}
+	want := `{"event":"log","stream":"stdout","message":"test log output"}` + "\n"
+	if stdout.String() != want {
+		t.Errorf("stdout = %q, want %q", stdout.String(), want)
+	}
+}
+
 func TestLogs_ComposeMissing_ReturnsError(t *testing.T) {
 	store := workspace.NewStoreAt(t.TempDir())
 	ws := &workspace.Workspace{ID: "ws-logs-compose-nil", Source: "/home/user/project"}
@@ -139,3 +243,153 @@ func TestLogs_NoResult(t *testing.T) {
 		t.Fatal("expected error for missing result")
 	}
 }
+
+func newForegroundTestEngine(t *testing.T, drv driver.Driver, mergedConfig string) (*Engine, *workspace.Workspace, *bytes.Buffer) {
+	t.Helper()
+	store := workspace.NewStoreAt(t.TempDir())
+	ws := &workspace.Workspace{ID: "ws-fg", Source: "/home/user/project"}
+	if err := store.Save(ws); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SaveResult(ws.ID, &workspace.Result{
+		ContainerID:  "container-1",
+		MergedConfig: []byte(mergedConfig),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	var stdout bytes.Buffer
+	return &Engine{
+		driver: drv,
+		store:  store,
+		logger: slog.Default(),
+		stdout: &stdout,
+		stderr: io.Discard,
+	}, ws, &stdout
+}
+
+func TestForeground_ProxiesExitCode(t *testing.T) {
+	drv := &attachMockDriver{exitCode: 42}
+	drv.container = &driver.ContainerDetails{ID: "container-1", State: driver.ContainerState{Status: "running"}}
+	eng, ws, stdout := newForegroundTestEngine(t, drv, `{"image":"nginx","overrideCommand":false}`)
+
+	err := eng.Foreground(context.Background(), ws)
+
+	var exited *ErrContainerExited
+	if !errors.As(err, &exited) {
+		t.Fatalf("expected *ErrContainerExited, got %T: %v", err, err)
+	}
+	if exited.ExitCode != 42 {
+		t.Errorf("ExitCode = %d, want 42", exited.ExitCode)
+	}
+	if drv.logsCalled {
+		t.Error("ContainerLogs should not be called: following logs replays the history")
+	}
+	if stdout.String() != "attached output\n" {
+		t.Errorf("stdout = %q, want attached output", stdout.String())
+	}
+}
+
+func TestForeground_CleanExit(t *testing.T) {
+	drv := &attachMockDriver{}
+	drv.container = &driver.ContainerDetails{ID: "container-1", State: driver.ContainerState{Status: "running"}}
+	eng, ws, _ := newForegroundTestEngine(t, drv, `{"image":"nginx","overrideCommand":false}`)
+
+	if err := eng.Foreground(context.Background(), ws); err != nil {
+		t.Fatalf("Foreground: %v", err)
+	}
+}
+
+func TestForeground_ExitedDuringSetup(t *testing.T) {
+	drv := &attachMockDriver{}
+	drv.container = &driver.ContainerDetails{ID: "container-1", State: driver.ContainerState{Status: "exited", ExitCode: 3}}
+	eng, ws, _ := newForegroundTestEngine(t, drv, `{"image":"nginx","overrideCommand":false}`)
+
+	err := eng.Foreground(context.Background(), ws)
+	var exited *ErrContainerExited
+	if !errors.As(err, &exited) || exited.ExitCode != 3 {
+		t.Fatalf("expected *ErrContainerExited with code 3, got %v", err)
+	}
+	if drv.attachedID != "" {
+		t.Error("AttachContainer should not be called for an exited container")
+	}
+}
+
+func TestForeground_RequiresOverrideCommandFalse(t *testing.T) {
+	drv := &attachMockDriver{}
+	drv.container = &driver.ContainerDetails{ID: "container-1", State: driver.ContainerState{Status: "running"}}
+	eng, ws, _ := newForegroundTestEngine(t, drv, `{"image":"alpine"}`)
+
+	err := eng.Foreground(context.Background(), ws)
+	if err == nil || !strings.Contains(err.Error(), "overrideCommand") {
+		t.Fatalf("expected overrideCommand error, got %v", err)
+	}
+	if drv.attachedID != "" {
+		t.Error("AttachContainer should not be called")
+	}
+}
+
+// attachMockDriver records AttachContainer calls and simulates the main
+// process exiting while attached.
+type attachMockDriver struct {
+	logsMockDriver
+	attachedID string
+	exitCode   int
+}
+
+func (m *attachMockDriver) AttachContainer(_ context.Context, _, containerID string, stdout, _ io.Writer) error {
+	m.attachedID = containerID
+	io.WriteString(stdout, "attached output\n")
+	m.container = &driver.ContainerDetails{
+		ID:    containerID,
+		State: driver.ContainerState{Status: "exited", ExitCode: m.exitCode},
+	}
+	return nil
+}
+
+func TestAttach_TargetsWorkspaceContainer(t *testing.T) {
+	drv := &attachMockDriver{exitCode: 7}
+	drv.container = &driver.ContainerDetails{ID: "container-1", State: driver.ContainerState{Status: "running"}}
+	eng, ws, stdout := newForegroundTestEngine(t, drv, `{"image":"nginx","overrideCommand":false}`)
+
+	err := eng.Attach(context.Background(), ws)
+
+	var exited *ErrContainerExited
+	if !errors.As(err, &exited) || exited.ExitCode != 7 {
+		t.Fatalf("expected *ErrContainerExited with code 7, got %v", err)
+	}
+	if drv.attachedID != "container-1" {
+		t.Errorf("attached to %q, want container-1", drv.attachedID)
+	}
+	if drv.logsCalled {
+		t.Error("ContainerLogs should not be called")
+	}
+	if stdout.String() != "attached output\n" {
+		t.Errorf("stdout = %q, want attached output", stdout.String())
+	}
+}
+
+func TestAttach_KeepAliveSuggestsShell(t *testing.T) {
+	drv := &attachMockDriver{}
+	drv.container = &driver.ContainerDetails{ID: "container-1", State: driver.ContainerState{Status: "running"}}
+	eng, ws, _ := newForegroundTestEngine(t, drv, `{"image":"alpine"}`)
+
+	err := eng.Attach(context.Background(), ws)
+	if err == nil || !strings.Contains(err.Error(), "crib shell") {
+		t.Fatalf("expected error suggesting crib shell, got %v", err)
+	}
+	if drv.attachedID != "" {
+		t.Error("AttachContainer should not be called")
+	}
+}
+
+func TestAttach_StoppedContainer(t *testing.T) {
+	drv := &attachMockDriver{}
+	drv.container = &driver.ContainerDetails{ID: "container-1", State: driver.ContainerState{Status: "exited"}}
+	eng, ws, _ := newForegroundTestEngine(t, drv, `{"image":"nginx","overrideCommand":false}`)
+
+	err := eng.Attach(context.Background(), ws)
+	var stopped *ErrContainerStopped
+	if !errors.As(err, &stopped) {
+		t.Fatalf("expected *ErrContainerStopped, got %v", err)
+	}
+}
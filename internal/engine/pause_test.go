@@ -0,0 +1,151 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/fgrehm/crib/internal/compose"
+	"github.com/fgrehm/crib/internal/driver"
+	"github.com/fgrehm/crib/internal/workspace"
+)
+
+// pauseRecordingDriver records pause/unpause calls.
+type pauseRecordingDriver struct {
+	fixedFindContainerDriver
+	calls []string
+}
+
+func (m *pauseRecordingDriver) PauseContainer(_ context.Context, _, containerID string) error {
+	m.calls = append(m.calls, "pause "+containerID)
+	return nil
+}
+
+func (m *pauseRecordingDriver) UnpauseContainer(_ context.Context, _, containerID string) error {
+	m.calls = append(m.calls, "unpause "+containerID)
+	return nil
+}
+
+func newPauseTestEngine(t *testing.T, status string) (*Engine, *pauseRecordingDriver, *workspace.Workspace) {
+	t.Helper()
+	store := workspace.NewStoreAt(t.TempDir())
+	ws := &workspace.Workspace{ID: "test-pause", Source: t.TempDir()}
+	if err := store.Save(ws); err != nil {
+		t.Fatal(err)
+	}
+	drv := &pauseRecordingDriver{}
+	if status != "" {
+		drv.container = &driver.ContainerDetails{ID: "abc123", State: driver.ContainerState{Status: status}}
+	}
+	return &Engine{driver: drv, store: store, logger: slog.Default(), stdout: io.Discard, stderr: io.Discard}, drv, ws
+}
+
+func TestPause_SingleContainer(t *testing.T) {
+	e, drv, ws := newPauseTestEngine(t, "running")
+
+	if err := e.Pause(context.Background(), ws); err != nil {
+		t.Fatalf("Pause: %v", err)
+	}
+	if len(drv.calls) != 1 || drv.calls[0] != "pause abc123" {
+		t.Errorf("calls = %v, want [pause abc123]", drv.calls)
+	}
+}
+
+func TestUnpause_SingleContainer(t *testing.T) {
+	e, drv, ws := newPauseTestEngine(t, "paused")
+
+	if err := e.Unpause(context.Background(), ws); err != nil {
+		t.Fatalf("Unpause: %v", err)
+	}
+	if len(drv.calls) != 1 || drv.calls[0] != "unpause abc123" {
+		t.Errorf("calls = %v, want [unpause abc123]", drv.calls)
+	}
+}
+
+func TestPause_AlreadyInTargetState(t *testing.T) {
+	e, drv, ws := newPauseTestEngine(t, "paused")
+	if err := e.Pause(context.Background(), ws); err != nil {
+		t.Fatalf("Pause on paused container: %v", err)
+	}
+
+	e, drv2, ws := newPauseTestEngine(t, "running")
+	if err := e.Unpause(context.Background(), ws); err != nil {
+		t.Fatalf("Unpause on running container: %v", err)
+	}
+
+	if len(drv.calls)+len(drv2.calls) != 0 {
+		t.Errorf("expected no runtime calls, got %v %v", drv.calls, drv2.calls)
+	}
+}
+
+func TestPause_StoppedContainer(t *testing.T) {
+	e, _, ws := newPauseTestEngine(t, "exited")
+
+	err := e.Pause(context.Background(), ws)
+	var target *ErrContainerStopped
+	if !errors.As(err, &target) {
+		t.Errorf("expected ErrContainerStopped, got: %v", err)
+	}
+}
+
+func TestPause_NoContainer(t *testing.T) {
+	e, _, ws := newPauseTestEngine(t, "")
+
+	err := e.Pause(context.Background(), ws)
+	var target *ErrNoContainer
+	if !errors.As(err, &target) {
+		t.Errorf("expected ErrNoContainer, got: %v", err)
+	}
+}
+
+func TestPause_Compose(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		run  func(*Engine, context.Context, *workspace.Workspace) error
+		want string
+	}{
+		{"pause", (*Engine).Pause, "pause"},
+		{"unpause", (*Engine).Unpause, "unpause"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			e, drv, ws := newPauseTestEngine(t, "running")
+			composeWorkspaceResult(t, e.store, ws.ID)
+
+			// "echo" stands in for the runtime so the compose invocation is
+			// written to stdout instead of executed.
+			var stdout bytes.Buffer
+			e.compose = compose.NewHelperFromRuntime("echo")
+			e.verbose = true
+			e.stdout = &stdout
+
+			if err := tt.run(e, context.Background(), ws); err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+			args := strings.Fields(stdout.String())
+			if len(args) == 0 || args[len(args)-1] != tt.want {
+				t.Errorf("compose args = %v, want trailing %q", args, tt.want)
+			}
+			if !strings.Contains(stdout.String(), "--project-name") {
+				t.Errorf("expected project args, got %q", stdout.String())
+			}
+			if len(drv.calls) != 0 {
+				t.Errorf("driver should not be used for compose workspaces, got %v", drv.calls)
+			}
+		})
+	}
+}
+
+func TestPause_ComposeMissing_ReturnsError(t *testing.T) {
+	e, _, ws := newPauseTestEngine(t, "running")
+	composeWorkspaceResult(t, e.store, ws.ID)
+
+	err := e.Pause(context.Background(), ws)
+	var target *ErrComposeNotAvailable
+	if !errors.As(err, &target) {
+		t.Errorf("expected ErrComposeNotAvailable, got: %v", err)
+	}
+}
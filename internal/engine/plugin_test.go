@@ -2,6 +2,7 @@ package engine
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
@@ -264,9 +265,9 @@ func TestExecPluginCopies_ExecFailure(t *testing.T) {
 
 	eng.execPluginCopies(context.Background(), containerContext{workspaceID: "ws-1", containerID: "c-1"}, copies)
 
-	// After first exec failure, remaining copies should be skipped.
-	if failDrv.execCount != 1 {
-		t.Errorf("expected 1 exec attempt (bail out after failure), got %d", failDrv.execCount)
+	// A failed copy does not stop the remaining ones.
+	if failDrv.execCount != 2 {
+		t.Errorf("expected 2 exec attempts (one per copy), got %d", failDrv.execCount)
 	}
 }
 
@@ -306,6 +307,68 @@ func TestExecPluginCopies_IfNotExists(t *testing.T) {
 	}
 }
 
+// nativeCopyDriver extends mockDriver with a working native cp.
+type nativeCopyDriver struct {
+	mockDriver
+	copies [][2]string // src, dst
+}
+
+func (m *nativeCopyDriver) CopyToContainer(_ context.Context, _, _, src, dst string) error {
+	m.copies = append(m.copies, [2]string{src, dst})
+	return nil
+}
+
+func TestExecPluginCopies_NativeCopy(t *testing.T) {
+	staging := t.TempDir()
+	srcFile := filepath.Join(staging, "test.json")
+	if err := os.WriteFile(srcFile, []byte(`{"key":"value"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	drv := &nativeCopyDriver{}
+	eng := &Engine{
+		driver: drv,
+		logger: slog.Default(),
+	}
+
+	copies := []plugin.FileCopy{
+		{Source: srcFile, Target: "/home/vscode/.config/test.json", Mode: "0600", User: "vscode", IfNotExists: true},
+	}
+
+	eng.execPluginCopies(context.Background(), containerContext{workspaceID: "ws-1", containerID: "c-1"}, copies)
+
+	if len(drv.copies) != 1 {
+		t.Fatalf("expected 1 native copy, got %d", len(drv.copies))
+	}
+	src, tmp := drv.copies[0][0], drv.copies[0][1]
+	if src != srcFile {
+		t.Errorf("copy source = %q, want %q", src, srcFile)
+	}
+	if !strings.HasPrefix(tmp, "/home/vscode/.config/.crib-copy-") {
+		t.Errorf("copy destination = %q, want a .crib-copy- path next to the target", tmp)
+	}
+
+	// One exec moves the file into place and fixes up mode and owner.
+	if len(drv.execCalls) != 1 {
+		t.Fatalf("expected 1 exec call, got %d", len(drv.execCalls))
+	}
+	cmdStr := strings.Join(drv.execCalls[0].cmd, " ")
+	for _, want := range []string{
+		"trap \"rm -f '" + tmp + "'\" EXIT",
+		"[ -f '/home/vscode/.config/test.json' ] ||",
+		"mkdir -p '/home/vscode/.config' && mv -f '" + tmp + "' '/home/vscode/.config/test.json'",
+		"chmod '0600' '/home/vscode/.config/test.json'",
+		"chown 'vscode:' '/home/vscode/.config' '/home/vscode/.config/test.json'",
+	} {
+		if !strings.Contains(cmdStr, want) {
+			t.Errorf("command missing %q, got: %s", want, cmdStr)
+		}
+	}
+	if strings.Contains(cmdStr, "cat >") {
+		t.Errorf("native copy should not stream via cat, got: %s", cmdStr)
+	}
+}
+
 func TestDispatchPlugins_ExplicitRemoteUserOverride(t *testing.T) {
 	store := workspace.NewStoreAt(t.TempDir())
 	ws := &workspace.Workspace{ID: "ws-1", Source: "/home/user/project"}
@@ -373,7 +436,7 @@ type failingExecDriver struct {
 	execCount int
 }
 
-func (f *failingExecDriver) ExecContainer(ctx context.Context, workspaceID, containerID string, cmd []string, stdin io.Reader, stdout, stderr io.Writer, env []string, user string) error {
+func (f *failingExecDriver) ExecContainer(ctx context.Context, workspaceID, containerID string, cmd []string, stdin io.Reader, stdout, stderr io.Writer, env []string, user, workdir string) error {
 	f.execCount++
 	return fmt.Errorf("exec failed")
 }
@@ -399,9 +462,53 @@ func TestExecPluginCopies_MissingSourceThenExecFailure(t *testing.T) {
 
 	eng.execPluginCopies(context.Background(), containerContext{workspaceID: "ws-1", containerID: "c-1"}, copies)
 
-	// Missing source is skipped (continue), good.json triggers exec which fails,
-	// other.json should NOT be attempted (bail out after first exec failure).
-	if failDrv.execCount != 1 {
-		t.Errorf("expected 1 exec attempt (skip missing, bail after first exec fail), got %d", failDrv.execCount)
+	// Missing source is skipped, good.json triggers an exec which fails,
+	// and other.json is still attempted.
+	if failDrv.execCount != 2 {
+		t.Errorf("expected 2 exec attempts (skip missing, keep going after a failure), got %d", failDrv.execCount)
+	}
+}
+
+// flakyCopyDriver fails native copies into the given directory, as docker cp
+// does for a path on a tmpfs or whose parent is missing.
+type flakyCopyDriver struct {
+	nativeCopyDriver
+	failDir string
+}
+
+func (m *flakyCopyDriver) CopyToContainer(ctx context.Context, wsID, cID, src, dst string) error {
+	if filepath.Dir(dst) == m.failDir {
+		return errors.New("copy failed")
+	}
+	return m.nativeCopyDriver.CopyToContainer(ctx, wsID, cID, src, dst)
+}
+
+func TestExecPluginCopies_NativeFailureFallsBackPerCopy(t *testing.T) {
+	staging := t.TempDir()
+	srcFile := filepath.Join(staging, "test.json")
+	if err := os.WriteFile(srcFile, []byte(`{}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	drv := &flakyCopyDriver{failDir: "/scratch"}
+	eng := &Engine{driver: drv, logger: slog.Default()}
+	copies := []plugin.FileCopy{
+		{Source: srcFile, Target: "/scratch/a.json"},
+		{Source: srcFile, Target: "/home/vscode/b.json"},
+	}
+
+	eng.execPluginCopies(context.Background(), containerContext{workspaceID: "ws-1", containerID: "c-1"}, copies)
+
+	var streamed, moved bool
+	for _, c := range drv.execCalls {
+		cmd := strings.Join(c.cmd, " ")
+		streamed = streamed || strings.Contains(cmd, "cat > '/scratch/a.json'")
+		moved = moved || strings.Contains(cmd, "' '/home/vscode/b.json'") && strings.Contains(cmd, "mv -f")
+	}
+	if !streamed {
+		t.Errorf("failed native copy was not retried over exec: %v", drv.execCalls)
+	}
+	if !moved {
+		t.Errorf("copy after a failure did not use native cp: %v", drv.execCalls)
 	}
 }
@@ -0,0 +1,169 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/fgrehm/crib/internal/config"
+)
+
+// publishSpec is a parsed "[ip:]hostPort:containerPort[/proto]" publish spec.
+type publishSpec struct {
+	ip            string // as written, e.g. "127.0.0.1" or "[::1]"; empty for all interfaces
+	hostPort      int
+	containerPort string
+	proto         string
+}
+
+// parsePublishSpec parses a spec produced by collectPorts. ok is false for
+// specs without a single fixed host port (ranges, "ip::port"), which are
+// left to the runtime.
+func parsePublishSpec(spec string) (publishSpec, bool) {
+	ports, proto := splitPortProtocol(spec)
+	i := strings.LastIndex(ports, ":")
+	if i < 0 {
+		return publishSpec{}, false
+	}
+	p := publishSpec{containerPort: ports[i+1:], proto: proto}
+	host := ports[:i]
+	if j := strings.LastIndex(host, ":"); j >= 0 {
+		p.ip, host = host[:j], host[j+1:]
+	}
+	n, err := strconv.Atoi(host)
+	if err != nil || n <= 0 {
+		return publishSpec{}, false
+	}
+	p.hostPort = n
+	return p, true
+}
+
+func (p publishSpec) String() string {
+	s := strconv.Itoa(p.hostPort) + ":" + p.containerPort
+	if p.ip != "" {
+		s = p.ip + ":" + s
+	}
+	if p.proto != "tcp" {
+		s += "/" + p.proto
+	}
+	return s
+}
+
+// portAvailable reports whether port can be bound on the host for proto
+// ("tcp" or "udp"). An empty ip checks all interfaces, like the runtime's
+// default publish address. A privileged port crib itself may not bind
+// counts as available: the runtime may still be allowed to.
+func portAvailable(ip string, port int, proto string) bool {
+	addr := net.JoinHostPort(strings.Trim(ip, "[]"), strconv.Itoa(port))
+	if proto == "udp" {
+		conn, err := net.ListenPacket("udp", addr)
+		if err != nil {
+			return errors.Is(err, os.ErrPermission)
+		}
+		_ = conn.Close()
+		return true
+	}
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return errors.Is(err, os.ErrPermission)
+	}
+	_ = l.Close()
+	return true
+}
+
+// portAttributes returns the portsAttributes entry for containerPort: the
+// key naming the port itself, else the first "from-to" range containing it,
+// else otherPortsAttributes.
+func portAttributes(cfg *config.DevContainerConfig, containerPort string) config.PortAttribute {
+	if attr, ok := cfg.PortsAttributes[containerPort]; ok {
+		return attr
+	}
+	if n, err := strconv.Atoi(containerPort); err == nil {
+		keys := make([]string, 0, len(cfg.PortsAttributes))
+		for k := range cfg.PortsAttributes {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		for _, k := range keys {
+			from, to, ok := strings.Cut(k, "-")
+			lo, err1 := strconv.Atoi(from)
+			hi, err2 := strconv.Atoi(to)
+			if ok && err1 == nil && err2 == nil && lo <= n && n <= hi {
+				return cfg.PortsAttributes[k]
+			}
+		}
+	}
+	if cfg.OtherPortsAttributes != nil {
+		return *cfg.OtherPortsAttributes
+	}
+	return config.PortAttribute{}
+}
+
+// freePort asks the kernel for an unused port on ip for proto.
+func freePort(ip, proto string) (int, error) {
+	addr := net.JoinHostPort(strings.Trim(ip, "[]"), "0")
+	if proto == "udp" {
+		conn, err := net.ListenPacket("udp", addr)
+		if err != nil {
+			return 0, err
+		}
+		defer conn.Close() //nolint:errcheck // probe socket
+		return conn.LocalAddr().(*net.UDPAddr).Port, nil
+	}
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close() //nolint:errcheck // probe socket
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
+// checkHostPorts verifies that the host ports of the publish specs are free
+// before the container is created, so a conflict names the port instead of
+// surfacing as an opaque runtime error. With auto-port, taken ports are
+// replaced by free ones and the new mapping is reported, except for ports
+// whose portsAttributes set requireLocalPort, which must keep the container
+// port as host port. Privileged host ports are reported unless their
+// attributes set elevateIfNeeded. When the runtime runs on another machine
+// the ports are published there, so local availability says nothing and
+// the check is skipped. It returns the specs to publish.
+func (e *Engine) checkHostPorts(cfg *config.DevContainerConfig, specs []string) ([]string, error) {
+	remote := remoteRuntimeHost(e.runtimeName, os.Getenv)
+	if remote != "" && len(specs) > 0 {
+		e.logger.Debug("runtime is remote, skipping host port check", "host", remote)
+	}
+	result := make([]string, 0, len(specs))
+	for _, spec := range specs {
+		p, ok := parsePublishSpec(spec)
+		if !ok {
+			result = append(result, spec)
+			continue
+		}
+		attr := portAttributes(cfg, p.containerPort)
+		if attr.RequireLocalPort && strconv.Itoa(p.hostPort) != p.containerPort {
+			return nil, fmt.Errorf("port %s sets requireLocalPort in portsAttributes, but %q publishes it on host port %d", p.containerPort, spec, p.hostPort)
+		}
+		if p.hostPort < 1024 && !attr.ElevateIfNeeded {
+			e.reportProgress(PhaseCreate, fmt.Sprintf("Host port %d is privileged, binding it may need a rootful runtime (set elevateIfNeeded in portsAttributes to hide this)", p.hostPort))
+		}
+		if remote != "" || portAvailable(p.ip, p.hostPort, p.proto) {
+			result = append(result, spec)
+			continue
+		}
+		if !e.autoPort || attr.RequireLocalPort {
+			return nil, &ErrPortInUse{Spec: spec, HostPort: p.hostPort, Protocol: p.proto, RequireLocalPort: attr.RequireLocalPort}
+		}
+		port, err := freePort(p.ip, p.proto)
+		if err != nil {
+			return nil, fmt.Errorf("finding a free host port for %s: %w", spec, err)
+		}
+		e.reportProgress(PhaseCreate, fmt.Sprintf("Host port %d is in use, forwarding port %s from host port %d", p.hostPort, p.containerPort, port))
+		p.hostPort = port
+		result = append(result, p.String())
+	}
+	return result, nil
+}
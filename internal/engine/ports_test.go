@@ -0,0 +1,239 @@
+package engine
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/fgrehm/crib/internal/config"
+)
+
+// occupyPort listens on a free TCP port on ip for the duration of the test
+// and returns it.
+func occupyPort(t *testing.T, ip string) int {
+	t.Helper()
+	l, err := net.Listen("tcp", net.JoinHostPort(ip, "0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = l.Close() })
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+func TestParsePublishSpec(t *testing.T) {
+	tests := []struct {
+		spec string
+		want publishSpec
+		ok   bool
+	}{
+		{"8080:3000", publishSpec{hostPort: 8080, containerPort: "3000", proto: "tcp"}, true},
+		{"53:53/udp", publishSpec{hostPort: 53, containerPort: "53", proto: "udp"}, true},
+		{"127.0.0.1:8080:80", publishSpec{ip: "127.0.0.1", hostPort: 8080, containerPort: "80", proto: "tcp"}, true},
+		{"[::1]:8080:80", publishSpec{ip: "[::1]", hostPort: 8080, containerPort: "80", proto: "tcp"}, true},
+		{"8000-8010:8000-8010", publishSpec{}, false},
+		{"127.0.0.1::80", publishSpec{}, false},
+		{"80", publishSpec{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, ok := parsePublishSpec(tt.spec)
+			if ok != tt.ok || got != tt.want {
+				t.Fatalf("parsePublishSpec(%q) = %+v, %v; want %+v, %v", tt.spec, got, ok, tt.want, tt.ok)
+			}
+			if ok && got.String() != tt.spec {
+				t.Errorf("String() = %q, want %q", got.String(), tt.spec)
+			}
+		})
+	}
+}
+
+func TestPortAvailable(t *testing.T) {
+	port := occupyPort(t, "127.0.0.1")
+	if portAvailable("127.0.0.1", port, "tcp") {
+		t.Errorf("port %d reported available while in use", port)
+	}
+	free, err := freePort("127.0.0.1", "tcp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !portAvailable("127.0.0.1", free, "tcp") {
+		t.Errorf("free port %d reported in use", free)
+	}
+}
+
+func TestCheckHostPorts_Conflict(t *testing.T) {
+	port := occupyPort(t, "127.0.0.1")
+	spec := "127.0.0.1:" + strconv.Itoa(port) + ":80"
+
+	e := &Engine{logger: slog.Default(), stdout: io.Discard, stderr: io.Discard}
+	_, err := e.checkHostPorts(&config.DevContainerConfig{}, []string{"8000-8010:8000-8010", spec})
+
+	var inUse *ErrPortInUse
+	if !errors.As(err, &inUse) {
+		t.Fatalf("error = %v, want ErrPortInUse", err)
+	}
+	if inUse.HostPort != port || inUse.Spec != spec || inUse.Protocol != "tcp" {
+		t.Errorf("ErrPortInUse = %+v, want port %d for %s", inUse, port, spec)
+	}
+	for _, want := range []string{strconv.Itoa(port) + "/tcp", "--auto-port", "forwardPorts"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+}
+
+func TestCheckHostPorts_RemoteRuntime(t *testing.T) {
+	t.Setenv("DOCKER_HOST", "ssh://builder.example.com")
+	port := occupyPort(t, "127.0.0.1")
+	spec := "127.0.0.1:" + strconv.Itoa(port) + ":80"
+
+	e := &Engine{runtimeName: "docker", autoPort: true, logger: slog.Default(), stdout: io.Discard, stderr: io.Discard}
+	got, err := e.checkHostPorts(&config.DevContainerConfig{}, []string{spec})
+	if err != nil {
+		t.Fatalf("checkHostPorts: %v", err)
+	}
+	if !slices.Equal(got, []string{spec}) {
+		t.Errorf("specs = %v, want %v unchanged (the local port says nothing about the remote host)", got, spec)
+	}
+}
+
+func TestCheckHostPorts_AutoPort(t *testing.T) {
+	port := occupyPort(t, "127.0.0.1")
+	free, err := freePort("127.0.0.1", "tcp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	freeSpec := "127.0.0.1:" + strconv.Itoa(free) + ":9000"
+
+	var events []ProgressEvent
+	e := &Engine{
+		logger:   slog.Default(),
+		stdout:   io.Discard,
+		stderr:   io.Discard,
+		autoPort: true,
+		progress: func(ev ProgressEvent) { events = append(events, ev) },
+	}
+	got, err := e.checkHostPorts(&config.DevContainerConfig{}, []string{"127.0.0.1:" + strconv.Itoa(port) + ":80", freeSpec})
+	if err != nil {
+		t.Fatalf("checkHostPorts: %v", err)
+	}
+	if len(got) != 2 || got[1] != freeSpec {
+		t.Fatalf("specs = %v, want the free spec %s kept", got, freeSpec)
+	}
+
+	p, ok := parsePublishSpec(got[0])
+	if !ok || p.ip != "127.0.0.1" || p.containerPort != "80" {
+		t.Fatalf("remapped spec = %q, want 127.0.0.1:<port>:80", got[0])
+	}
+	if p.hostPort == port {
+		t.Errorf("remapped spec kept the taken port %d", port)
+	}
+	if !portAvailable(p.ip, p.hostPort, "tcp") {
+		t.Errorf("remapped port %d is not free", p.hostPort)
+	}
+
+	if len(events) != 1 || !strings.Contains(events[0].Message, strconv.Itoa(p.hostPort)) {
+		t.Errorf("progress = %+v, want one event reporting port %d", events, p.hostPort)
+	}
+}
+
+func TestPortAttributes(t *testing.T) {
+	cfg := &config.DevContainerConfig{}
+	cfg.PortsAttributes = map[string]config.PortAttribute{
+		"3000":      {Label: "app"},
+		"9000-9100": {Label: "range"},
+	}
+	other := &config.PortAttribute{Label: "other"}
+
+	for _, tt := range []struct {
+		port  string
+		other *config.PortAttribute
+		want  string
+	}{
+		{"3000", nil, "app"},
+		{"9050", nil, "range"},
+		{"9101", nil, ""},
+		{"9101", other, "other"},
+		{"3000", other, "app"},
+	} {
+		cfg.OtherPortsAttributes = tt.other
+		if got := portAttributes(cfg, tt.port).Label; got != tt.want {
+			t.Errorf("portAttributes(%s, other=%v) label = %q, want %q", tt.port, tt.other != nil, got, tt.want)
+		}
+	}
+}
+
+func TestCheckHostPorts_PrivilegedPort(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		elevate  bool
+		wantWarn bool
+	}{
+		{"warns by default", false, true},
+		{"elevateIfNeeded hides the warning", true, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.DevContainerConfig{}
+			cfg.PortsAttributes = map[string]config.PortAttribute{"1": {ElevateIfNeeded: tt.elevate}}
+
+			var events []ProgressEvent
+			e := &Engine{
+				logger:   slog.Default(),
+				progress: func(ev ProgressEvent) { events = append(events, ev) },
+			}
+			got, err := e.checkHostPorts(cfg, []string{"127.0.0.1:1:1"})
+			if err != nil {
+				t.Fatalf("checkHostPorts: %v", err)
+			}
+			if len(got) != 1 || got[0] != "127.0.0.1:1:1" {
+				t.Errorf("specs = %v, want the privileged spec kept", got)
+			}
+			warned := len(events) == 1 && strings.Contains(events[0].Message, "privileged")
+			if warned != tt.wantWarn {
+				t.Errorf("progress = %+v, want privileged-port warning: %v", events, tt.wantWarn)
+			}
+		})
+	}
+}
+
+func TestCheckHostPorts_RequireLocalPort(t *testing.T) {
+	cfg := &config.DevContainerConfig{}
+	cfg.PortsAttributes = map[string]config.PortAttribute{"3000": {RequireLocalPort: true}}
+	e := &Engine{logger: slog.Default(), autoPort: true}
+
+	// The host port must be the container port.
+	_, err := e.checkHostPorts(cfg, []string{"127.0.0.1:8080:3000"})
+	if err == nil || !strings.Contains(err.Error(), "requireLocalPort") {
+		t.Errorf("error = %v, want a requireLocalPort mismatch", err)
+	}
+
+	// A taken port is not moved, even with auto-port.
+	port := occupyPort(t, "127.0.0.1")
+	p := strconv.Itoa(port)
+	cfg.PortsAttributes = map[string]config.PortAttribute{p: {RequireLocalPort: true}}
+	spec := "127.0.0.1:" + p + ":" + p
+	_, err = e.checkHostPorts(cfg, []string{spec})
+	var inUse *ErrPortInUse
+	if !errors.As(err, &inUse) || !inUse.RequireLocalPort {
+		t.Fatalf("error = %v, want ErrPortInUse for a required port", err)
+	}
+	if strings.Contains(err.Error(), "--auto-port") {
+		t.Errorf("error %q should not suggest --auto-port for a required port", err)
+	}
+
+	// A matching, free port passes.
+	free, err := freePort("127.0.0.1", "tcp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := strconv.Itoa(free)
+	cfg.PortsAttributes = map[string]config.PortAttribute{f: {RequireLocalPort: true}}
+	if got, err := e.checkHostPorts(cfg, []string{"127.0.0.1:" + f + ":" + f}); err != nil || len(got) != 1 {
+		t.Errorf("checkHostPorts = %v, %v; want the spec kept", got, err)
+	}
+}
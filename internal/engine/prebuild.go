@@ -0,0 +1,115 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/google/go-containerregistry/pkg/name"
+
+	"github.com/fgrehm/crib/internal/workspace"
+)
+
+// BuildOptions controls Build. At most one of Output, Push and Save may be
+// set; without any of them the image is loaded into the local image store.
+type BuildOptions struct {
+	// ImageName tags the built image with this reference instead of crib's
+	// own crib-<workspace>:<hash> name. Required by Push.
+	ImageName string
+
+	// Output is a BuildKit exporter spec (e.g.
+	// "type=registry,ref=ghcr.io/org/app:dev"), passed as --output.
+	Output string
+
+	// Push pushes the image to the registry named by ImageName.
+	Push bool
+
+	// Save writes the image as a docker-loadable tar archive to this host
+	// path.
+	Save string
+}
+
+// exports reports whether the image goes somewhere other than the local
+// image store.
+func (o BuildOptions) exports() bool {
+	return o.Output != "" || o.Push || o.Save != ""
+}
+
+// output returns the --output spec for the build, with Save expressed as a
+// docker exporter.
+func (o BuildOptions) output() string {
+	if o.Save != "" {
+		dest, err := filepath.Abs(o.Save)
+		if err != nil {
+			dest = o.Save
+		}
+		return "type=docker,dest=" + dest
+	}
+	return o.Output
+}
+
+// Validate checks that at most one of Output, Push and Save is set, that
+// ImageName is a valid image reference (and set when pushing) and that Save
+// names a file in an existing directory.
+func (o BuildOptions) Validate() error {
+	n := 0
+	for _, set := range []bool{o.Output != "", o.Push, o.Save != ""} {
+		if set {
+			n++
+		}
+	}
+	if n > 1 {
+		return errors.New("--output, --push and --save are mutually exclusive")
+	}
+	if o.Push && o.ImageName == "" {
+		return errors.New("--push needs --image-name to know where to push")
+	}
+	if o.ImageName != "" {
+		if _, err := name.NewTag(o.ImageName); err != nil {
+			return fmt.Errorf("invalid image name %q: %w", o.ImageName, err)
+		}
+	}
+	if o.Save != "" {
+		if fi, err := os.Stat(o.Save); err == nil && fi.IsDir() {
+			return fmt.Errorf("save path %s is a directory", o.Save)
+		}
+		if fi, err := os.Stat(filepath.Dir(o.Save)); err != nil || !fi.IsDir() {
+			return fmt.Errorf("save directory %s does not exist", filepath.Dir(o.Save))
+		}
+	}
+	return nil
+}
+
+// Build builds the workspace image without creating a container, for CI
+// pipelines that build once and distribute the result. The image is
+// rebuilt whenever it is renamed or exported, since a cached local image
+// has nothing to push. It returns the name of the built image.
+func (e *Engine) Build(ctx context.Context, ws *workspace.Workspace, opts BuildOptions) (string, error) {
+	if err := opts.Validate(); err != nil {
+		return "", err
+	}
+	e.logger.Debug("build", "workspace", ws.ID, "source", ws.Source)
+
+	cfg, workspaceFolder, err := e.parseAndSubstitute(ws)
+	if err != nil {
+		return "", err
+	}
+	if len(cfg.DockerComposeFile) > 0 {
+		return "", errors.New("crib build does not support docker compose workspaces; use 'docker compose build' for the services")
+	}
+
+	e.buildOpts = opts
+	e.forceBuild = e.forceBuild || opts.ImageName != "" || opts.exports()
+	defer func() { e.buildOpts = BuildOptions{} }()
+
+	result, err := e.newBackend(ws, cfg, workspaceFolder).buildImage(ctx)
+	if err != nil {
+		return "", err
+	}
+	if cfg.Image != "" && result.imageName == cfg.Image && (opts.ImageName != "" || opts.exports()) {
+		return "", fmt.Errorf("nothing to build: the config uses image %s without features", cfg.Image)
+	}
+	return result.imageName, nil
+}
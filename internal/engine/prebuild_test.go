@@ -0,0 +1,133 @@
+package engine
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fgrehm/crib/internal/driver"
+)
+
+// prebuildDriver reports every image as cached and records build options.
+type prebuildDriver struct {
+	mockDriver
+	builds []*driver.BuildOptions
+}
+
+func (m *prebuildDriver) InspectImage(_ context.Context, _ string) (*driver.ImageDetails, error) {
+	return &driver.ImageDetails{ID: "sha256:cached"}, nil
+}
+
+func (m *prebuildDriver) BuildImage(_ context.Context, _ string, opts *driver.BuildOptions) error {
+	m.builds = append(m.builds, opts)
+	return nil
+}
+
+func TestBuild_ForwardsExport(t *testing.T) {
+	save := filepath.Join(t.TempDir(), "app.tar")
+	tests := []struct {
+		name       string
+		opts       BuildOptions
+		wantImage  string
+		wantOutput string
+		wantPush   bool
+	}{
+		{"push", BuildOptions{ImageName: "ghcr.io/org/app:dev", Push: true}, "ghcr.io/org/app:dev", "", true},
+		{"output", BuildOptions{Output: "type=registry,ref=ghcr.io/org/app:dev"}, "", "type=registry,ref=ghcr.io/org/app:dev", false},
+		{"save", BuildOptions{Save: save}, "", "type=docker,dest=" + save, false},
+		{"image name", BuildOptions{ImageName: "app:dev"}, "app:dev", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			drv := &prebuildDriver{}
+			e, ws := newUpTimeoutTestEngine(t, drv, `{"build": {"dockerfile": "Dockerfile"}}`)
+
+			image, err := e.Build(context.Background(), ws, tt.opts)
+			if err != nil {
+				t.Fatalf("Build: %v", err)
+			}
+			// Exports must rebuild even though the image is cached locally.
+			if len(drv.builds) != 1 {
+				t.Fatalf("builds = %d, want 1", len(drv.builds))
+			}
+			got := drv.builds[0]
+			if tt.wantImage != "" && (got.Image != tt.wantImage || image != tt.wantImage) {
+				t.Errorf("image = %q (returned %q), want %q", got.Image, image, tt.wantImage)
+			}
+			if tt.wantImage == "" && !strings.HasPrefix(got.Image, "crib-"+ws.ID+":") {
+				t.Errorf("image = %q, want crib's own name", got.Image)
+			}
+			if got.Output != tt.wantOutput {
+				t.Errorf("Output = %q, want %q", got.Output, tt.wantOutput)
+			}
+			if got.Push != tt.wantPush {
+				t.Errorf("Push = %v, want %v", got.Push, tt.wantPush)
+			}
+			if e.buildOpts != (BuildOptions{}) {
+				t.Errorf("build options leaked past Build: %+v", e.buildOpts)
+			}
+		})
+	}
+}
+
+func TestBuild_UsesCachedImage(t *testing.T) {
+	drv := &prebuildDriver{}
+	e, ws := newUpTimeoutTestEngine(t, drv, `{"build": {"dockerfile": "Dockerfile"}}`)
+
+	image, err := e.Build(context.Background(), ws, BuildOptions{})
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	if len(drv.builds) != 0 {
+		t.Errorf("builds = %d, want the cached image to be reused", len(drv.builds))
+	}
+	if !strings.HasPrefix(image, "crib-"+ws.ID+":") {
+		t.Errorf("image = %q, want crib's own name", image)
+	}
+}
+
+func TestBuild_ImageWithoutFeatures(t *testing.T) {
+	drv := &prebuildDriver{}
+	e, ws := newUpTimeoutTestEngine(t, drv, `{"image": "alpine:3.20"}`)
+
+	_, err := e.Build(context.Background(), ws, BuildOptions{ImageName: "ghcr.io/org/app:dev", Push: true})
+	if err == nil || !strings.Contains(err.Error(), "nothing to build") {
+		t.Fatalf("expected nothing to build error, got %v", err)
+	}
+}
+
+func TestBuildOptions_Validate(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "existing.tar"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		opts    BuildOptions
+		wantErr bool
+	}{
+		{"load locally", BuildOptions{}, false},
+		{"image name", BuildOptions{ImageName: "app:dev"}, false},
+		{"push", BuildOptions{ImageName: "ghcr.io/org/app:dev", Push: true}, false},
+		{"output", BuildOptions{Output: "type=registry,ref=ghcr.io/org/app:dev"}, false},
+		{"save", BuildOptions{Save: filepath.Join(dir, "app.tar")}, false},
+		{"overwrite save", BuildOptions{Save: filepath.Join(dir, "existing.tar")}, false},
+		{"push without image name", BuildOptions{Push: true}, true},
+		{"push and output", BuildOptions{ImageName: "app:dev", Push: true, Output: "type=local,dest=out"}, true},
+		{"output and save", BuildOptions{Output: "type=local,dest=out", Save: filepath.Join(dir, "app.tar")}, true},
+		{"invalid image name", BuildOptions{ImageName: "My App"}, true},
+		{"save is a directory", BuildOptions{Save: dir}, true},
+		{"missing save directory", BuildOptions{Save: filepath.Join(dir, "nope", "app.tar")}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.opts.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
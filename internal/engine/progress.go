@@ -1,5 +1,7 @@
 package engine
 
+import "time"
+
 // ProgressPhase identifies a stage of the devcontainer lifecycle.
 type ProgressPhase string
 
@@ -10,10 +12,15 @@ const (
 	PhasePlugins ProgressPhase = "plugins"
 	PhaseHooks   ProgressPhase = "hooks"
 	PhaseRestart ProgressPhase = "restart"
+
+	// PhaseTimings events report a finished step of Up: Message names the
+	// step and Duration says how long it took.
+	PhaseTimings ProgressPhase = "timings"
 )
 
 // ProgressEvent carries a structured progress update from the engine.
 type ProgressEvent struct {
-	Phase   ProgressPhase
-	Message string
+	Phase    ProgressPhase
+	Message  string
+	Duration time.Duration // set for PhaseTimings events
 }
@@ -2,7 +2,9 @@ package engine
 
 import (
 	"context"
+	"slices"
 
+	"github.com/fgrehm/crib/internal/driver"
 	ocidriver "github.com/fgrehm/crib/internal/driver/oci"
 )
 
@@ -53,6 +55,11 @@ func (e *Engine) PruneImages(ctx context.Context, opts PruneOptions) (*PruneResu
 	}
 	active := make(map[string]*activeSet)
 
+	// Images committed by 'crib export' keep the label key with an empty
+	// value (runtimes cannot remove a label on commit). They belong to the
+	// user, so they are not crib's to prune.
+	images = slices.DeleteFunc(images, func(img driver.ImageInfo) bool { return img.WorkspaceID == "" })
+
 	for _, img := range images {
 		wsID := img.WorkspaceID
 		if _, ok := active[wsID]; ok {
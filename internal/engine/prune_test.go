@@ -187,3 +187,28 @@ func TestPruneImages_RemoveFailure_Continues(t *testing.T) {
 		t.Errorf("result.Errors = %d, want 1", len(result.Errors))
 	}
 }
+
+func TestPruneImages_KeepsExportedImages(t *testing.T) {
+	store := workspace.NewStoreAt(t.TempDir())
+
+	// crib export blanks the workspace label; the label filter still
+	// matches on the key.
+	md := &imageTrackingDriver{
+		images: []driver.ImageInfo{
+			{Reference: "crib-gone-ws:crib-abc", ID: "sha256:orphan", Size: 500, WorkspaceID: "gone-ws"},
+			{Reference: "myapp:dev", ID: "sha256:exported", Size: 700, WorkspaceID: ""},
+		},
+	}
+	eng := &Engine{driver: md, store: store, logger: slog.Default()}
+
+	result, err := eng.PruneImages(context.Background(), PruneOptions{})
+	if err != nil {
+		t.Fatalf("PruneImages: %v", err)
+	}
+	if len(md.removedImages) != 1 || md.removedImages[0] != "crib-gone-ws:crib-abc" {
+		t.Errorf("removed %v, want only the orphan image", md.removedImages)
+	}
+	if len(result.Removed) != 1 {
+		t.Errorf("result.Removed = %d, want 1", len(result.Removed))
+	}
+}
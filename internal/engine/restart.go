@@ -45,6 +45,9 @@ type RestartResult struct {
 func (e *Engine) Restart(ctx context.Context, ws *workspace.Workspace) (*RestartResult, error) {
 	e.logger.Debug("restart", "workspace", ws.ID)
 
+	// Images may have been rebuilt or pulled since the last run.
+	e.imageUsers = nil
+
 	// Load stored result to get the previous config.
 	storedResult, err := e.store.LoadResult(ws.ID)
 	if err != nil {
@@ -66,7 +69,7 @@ func (e *Engine) Restart(ctx context.Context, ws *workspace.Workspace) (*Restart
 			return nil, &ErrComposeNotAvailable{}
 		}
 		if cfg.Service == "" {
-			return nil, fmt.Errorf("dockerComposeFile is set but service is not specified")
+			return nil, &ErrComposeServiceMissing{}
 		}
 	}
 
@@ -105,6 +108,9 @@ func (e *Engine) Restart(ctx context.Context, ws *workspace.Workspace) (*Restart
 		return nil, fmt.Errorf("config changes require a full rebuild (image, Dockerfile, or features changed); run 'crib rebuild' instead")
 
 	case changeSafe:
+		if err := e.checkTrust(ws, cfg, workspaceFolder, nil); err != nil {
+			return nil, err
+		}
 		e.reportProgress(PhaseRestart, "Config changes detected, recreating container...")
 		result, err := e.restartRecreate(ctx, ws, cfg, workspaceFolder, b, storedResult)
 		if result != nil {
@@ -214,6 +220,10 @@ func (e *Engine) restartRecreate(ctx context.Context, ws *workspace.Workspace, c
 		}
 	}
 
+	if err := e.checkTrust(ws, cfg, workspaceFolder, metadata); err != nil {
+		return nil, err
+	}
+
 	// Dispatch plugins. Backend handles config-vs-fallback precedence.
 	// Fallback chain: metadata remoteUser → image Config.User → stored result.
 	pluginUser := b.pluginUser(ctx,
@@ -268,6 +278,7 @@ func (e *Engine) restartRecreate(ctx context.Context, ws *workspace.Workspace, c
 		fromSnapshot:            hasSnapshot,
 		imageMetadata:           metadata,
 		imageUser:               imageUser,
+		ports:                   created.Ports,
 		shouldMergeFeatureHooks: imgResult.needsBuild,
 	})
 	if err != nil {
@@ -326,7 +337,10 @@ func resolveConfigEnvFromStored(cfg *config.DevContainerConfig, storedEnv map[st
 		return cfg.RemoteEnv
 	}
 	resolvedEnv := resolved.RemoteEnv
-	// Also resolve bare ${VAR} references (e.g. ${PATH}).
-	resolveBareVarRefs(resolvedEnv, storedEnv)
+	// Also resolve bare ${VAR} references (e.g. ${A}/x or ${PATH}). A cycle
+	// would have failed the up that stored the env; keep the raw values.
+	if err := resolveBareVarRefs(resolvedEnv, storedEnv); err != nil {
+		return cfg.RemoteEnv
+	}
 	return resolvedEnv
 }
@@ -62,7 +62,7 @@ func TestIntegrationRestartSimple(t *testing.T) {
 
 	// Remove the marker files so we can verify which hooks run on restart.
 	for _, f := range []string{"/tmp/on-create-ran", "/tmp/post-start-ran"} {
-		_ = d.ExecContainer(ctx, wsID, result.ContainerID, []string{"rm", "-f", f}, nil, nil, nil, nil, "")
+		_ = d.ExecContainer(ctx, wsID, result.ContainerID, []string{"rm", "-f", f}, nil, nil, nil, nil, "", "")
 	}
 
 	// Restart with unchanged config.
@@ -93,13 +93,13 @@ func TestIntegrationRestartSimple(t *testing.T) {
 	}
 
 	// postStartCommand should have run (resume flow).
-	err = d.ExecContainer(ctx, wsID, restartResult.ContainerID, []string{"test", "-f", "/tmp/post-start-ran"}, nil, nil, nil, nil, "")
+	err = d.ExecContainer(ctx, wsID, restartResult.ContainerID, []string{"test", "-f", "/tmp/post-start-ran"}, nil, nil, nil, nil, "", "")
 	if err != nil {
 		t.Error("postStartCommand did not run on restart")
 	}
 
 	// onCreateCommand should NOT have run again (creation-only hook).
-	err = d.ExecContainer(ctx, wsID, restartResult.ContainerID, []string{"test", "-f", "/tmp/on-create-ran"}, nil, nil, nil, nil, "")
+	err = d.ExecContainer(ctx, wsID, restartResult.ContainerID, []string{"test", "-f", "/tmp/on-create-ran"}, nil, nil, nil, nil, "", "")
 	if err == nil {
 		t.Error("onCreateCommand ran again on restart, expected it to be skipped")
 	}
@@ -160,7 +160,7 @@ func TestIntegrationRestartSafeChange(t *testing.T) {
 
 	// Verify original env var.
 	var stdout bytes.Buffer
-	err = d.ExecContainer(ctx, wsID, originalContainerID, []string{"printenv", "MY_VAR"}, nil, &stdout, nil, nil, "")
+	err = d.ExecContainer(ctx, wsID, originalContainerID, []string{"printenv", "MY_VAR"}, nil, &stdout, nil, nil, "", "")
 	if err != nil {
 		t.Fatalf("printenv MY_VAR: %v", err)
 	}
@@ -199,7 +199,7 @@ func TestIntegrationRestartSafeChange(t *testing.T) {
 
 	// Verify updated env var is present.
 	stdout.Reset()
-	err = d.ExecContainer(ctx, wsID, restartResult.ContainerID, []string{"printenv", "MY_VAR"}, nil, &stdout, nil, nil, "")
+	err = d.ExecContainer(ctx, wsID, restartResult.ContainerID, []string{"printenv", "MY_VAR"}, nil, &stdout, nil, nil, "", "")
 	if err != nil {
 		t.Fatalf("printenv MY_VAR after restart: %v", err)
 	}
@@ -208,7 +208,7 @@ func TestIntegrationRestartSafeChange(t *testing.T) {
 	}
 
 	// postStartCommand should have run (resume flow).
-	err = d.ExecContainer(ctx, wsID, restartResult.ContainerID, []string{"test", "-f", "/tmp/post-start-ran"}, nil, nil, nil, nil, "")
+	err = d.ExecContainer(ctx, wsID, restartResult.ContainerID, []string{"test", "-f", "/tmp/post-start-ran"}, nil, nil, nil, nil, "", "")
 	if err != nil {
 		t.Error("postStartCommand did not run after recreate")
 	}
@@ -405,7 +405,7 @@ func TestIntegrationRestartMountChange(t *testing.T) {
 
 	// Verify the mount is functional.
 	var stdout bytes.Buffer
-	err = d.ExecContainer(ctx, wsID, restartResult.ContainerID, []string{"cat", "/extra/hello.txt"}, nil, &stdout, nil, nil, "")
+	err = d.ExecContainer(ctx, wsID, restartResult.ContainerID, []string{"cat", "/extra/hello.txt"}, nil, &stdout, nil, nil, "", "")
 	if err != nil {
 		t.Fatalf("cat /extra/hello.txt: %v", err)
 	}
@@ -9,6 +9,7 @@ import (
 	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/fgrehm/crib/internal/config"
 	"github.com/fgrehm/crib/internal/driver"
@@ -77,6 +78,25 @@ func TestDetectConfigChange_BuildArgsChanged(t *testing.T) {
 	}
 }
 
+func TestDetectConfigChange_CacheToChanged(t *testing.T) {
+	stored := &config.DevContainerConfig{}
+	stored.Image = "alpine"
+
+	current := &config.DevContainerConfig{}
+	current.Image = "alpine"
+	current.Build = &config.ConfigBuildOptions{CacheTo: config.StrArray{"type=registry,ref=example.com/app:cache"}}
+
+	if got := detectConfigChange(stored, current); got != changeNone {
+		t.Errorf("adding cacheTo: expected changeNone, got %d", got)
+	}
+
+	stored.Build = &config.ConfigBuildOptions{Target: "dev", CacheTo: config.StrArray{"type=local,dest=/tmp/cache"}}
+	current.Build = &config.ConfigBuildOptions{Target: "dev"}
+	if got := detectConfigChange(stored, current); got != changeNone {
+		t.Errorf("removing cacheTo: expected changeNone, got %d", got)
+	}
+}
+
 func TestDetectConfigChange_EnvChanged(t *testing.T) {
 	stored := &config.DevContainerConfig{}
 	stored.ContainerEnv = map[string]string{"FOO": "bar"}
@@ -271,12 +291,16 @@ func (m *restartMockDriver) RunContainer(_ context.Context, wsID string, opts *d
 
 func (m *restartMockDriver) DeleteContainer(_ context.Context, _, _ string) error { return nil }
 func (m *restartMockDriver) StartContainer(_ context.Context, _, _ string) error  { return nil }
-func (m *restartMockDriver) StopContainer(_ context.Context, _, _ string) error   { return nil }
+func (m *restartMockDriver) StopContainer(_ context.Context, _, _ string, _ *time.Duration) error {
+	return nil
+}
+func (m *restartMockDriver) PauseContainer(_ context.Context, _, _ string) error   { return nil }
+func (m *restartMockDriver) UnpauseContainer(_ context.Context, _, _ string) error { return nil }
 func (m *restartMockDriver) RestartContainer(_ context.Context, _, _ string) error {
 	return nil
 }
 
-func (m *restartMockDriver) ExecContainer(_ context.Context, _, _ string, cmd []string, _ io.Reader, _ io.Writer, _ io.Writer, env []string, _ string) error {
+func (m *restartMockDriver) ExecContainer(_ context.Context, _, _ string, cmd []string, _ io.Reader, _ io.Writer, _ io.Writer, env []string, _, _ string) error {
 	m.mu.Lock()
 	m.execCalls = append(m.execCalls, mockExecCall{cmd: cmd, env: env})
 	m.mu.Unlock()
@@ -286,6 +310,15 @@ func (m *restartMockDriver) ExecContainer(_ context.Context, _, _ string, cmd []
 func (m *restartMockDriver) ContainerLogs(_ context.Context, _, _ string, _, _ io.Writer, _ *driver.LogsOptions) error {
 	return nil
 }
+func (m *restartMockDriver) AttachContainer(_ context.Context, _, _ string, _, _ io.Writer) error {
+	return nil
+}
+func (m *restartMockDriver) CopyToContainer(_ context.Context, _, _, _, _ string) error {
+	return driver.ErrCopyUnsupported
+}
+func (m *restartMockDriver) CopyFromContainer(_ context.Context, _, _, _, _ string) error {
+	return driver.ErrCopyUnsupported
+}
 func (m *restartMockDriver) BuildImage(_ context.Context, _ string, _ *driver.BuildOptions) error {
 	return nil
 }
@@ -301,7 +334,11 @@ func (m *restartMockDriver) ListContainers(_ context.Context) ([]driver.Containe
 func (m *restartMockDriver) CommitContainer(_ context.Context, _, _, _ string, _ []string) error {
 	return nil
 }
-func (m *restartMockDriver) RemoveImage(_ context.Context, _ string) error { return nil }
+func (m *restartMockDriver) ExportContainer(_ context.Context, _, _, _ string) error {
+	return nil
+}
+func (m *restartMockDriver) PullImage(_ context.Context, _ string, _, _ io.Writer) error { return nil }
+func (m *restartMockDriver) RemoveImage(_ context.Context, _ string) error               { return nil }
 func (m *restartMockDriver) ListImages(_ context.Context, _ string) ([]driver.ImageInfo, error) {
 	return nil, nil
 }
@@ -309,6 +346,9 @@ func (m *restartMockDriver) ListVolumes(_ context.Context, _ string) ([]driver.V
 	return nil, nil
 }
 func (m *restartMockDriver) RemoveVolume(_ context.Context, _ string) error { return nil }
+func (m *restartMockDriver) ContainerChanges(_ context.Context, _, _ string) ([]driver.FileChange, error) {
+	return nil, nil
+}
 
 func TestRestartRecreateSingle_RunsPlugins(t *testing.T) {
 	store := workspace.NewStoreAt(t.TempDir())
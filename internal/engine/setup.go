@@ -5,9 +5,11 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"maps"
 	"os"
+	"path"
 	"regexp"
-	"runtime"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -18,6 +20,7 @@ import (
 
 // setupContainer performs post-creation container setup via docker exec.
 // This includes:
+//   - Creating a missing remoteUser (opt-in)
 //   - Resolving ${containerEnv:VAR} references in remoteEnv
 //   - Synchronizing the container user's UID/GID with the host
 //   - Chowning the workspace directory to the remote user
@@ -27,21 +30,40 @@ import (
 // should assign it to cfg.RemoteEnv for persistence; setupContainer itself
 // does not mutate cfg.RemoteEnv.
 func (e *Engine) setupContainer(ctx context.Context, ws *workspace.Workspace, cfg *config.DevContainerConfig, cc containerContext, envb *EnvBuilder, hooks *hookSet) (map[string]string, error) {
+	// Create a missing remoteUser first (opt-in) so the steps below, including
+	// the remoteEnv probe, can run as that user.
+	if err := e.createRemoteUser(ctx, cc, cfg); err != nil {
+		return nil, err
+	}
+
 	// Resolve ${containerEnv:VAR} in remoteEnv by probing the container environment.
 	// Also captures the container's base PATH for later merging.
 	var containerPATH string
 	resolvedConfigEnv := cfg.RemoteEnv
 	if len(cfg.RemoteEnv) > 0 {
-		resolvedConfigEnv, containerPATH = e.resolveRemoteEnv(ctx, cc, cfg)
+		var err error
+		resolvedConfigEnv, containerPATH, err = e.resolveRemoteEnv(ctx, cc, cfg)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// A read-only workspace can't be chowned, and matching UIDs would only
+	// matter for writing to it.
+	readOnly := readonlyWorkspace(cfg)
+	if readOnly {
+		e.logger.Debug("workspace is mounted read-only, skipping UID sync and chown")
 	}
 
 	// Sync container user UID/GID with host before chowning.
 	// uidsSynced is true when UIDs are confirmed to match (either already did, or were synced),
 	// meaning chownWorkspace is not needed for bind mounts (rootless podman limitation).
 	uidsSynced := false
-	if cc.remoteUser != "" && cc.remoteUser != "root" {
+	if cc.remoteUser != "" && cc.remoteUser != "root" && !readOnly {
 		var err error
+		stop := e.timings.start("uid sync")
 		uidsSynced, err = e.syncRemoteUserUID(ctx, cc, cfg)
+		stop()
 		if err != nil {
 			e.logger.Warn("failed to sync remote user UID/GID", "error", err)
 		}
@@ -50,7 +72,7 @@ func (e *Engine) setupContainer(ctx context.Context, ws *workspace.Workspace, cf
 	// Chown workspace directory to remote user, unless UIDs are already in sync.
 	// When UIDs match, bind-mount files are already accessible and chown would fail
 	// on rootless Podman (no CAP_CHOWN over bind-mounted files).
-	if cc.remoteUser != "" && cc.remoteUser != "root" && !uidsSynced {
+	if cc.remoteUser != "" && cc.remoteUser != "root" && !uidsSynced && !readOnly {
 		if err := e.chownWorkspace(ctx, cc); err != nil {
 			e.logger.Warn("failed to chown workspace", "error", err)
 		}
@@ -65,7 +87,8 @@ func (e *Engine) setupContainer(ctx context.Context, ws *workspace.Workspace, cf
 	// dropping entries that Docker images add via ENV (e.g. /usr/local/bundle/bin
 	// in ruby images). We merge these back after probing.
 	// Skip entirely when userEnvProbe is "none" since no login shell runs.
-	if containerPATH == "" && cfg.UserEnvProbe != "none" {
+	probe := e.userEnvProbe(cfg)
+	if containerPATH == "" && probe != "none" {
 		containerPATH = e.probeContainerPATH(ctx, cc)
 	}
 	envb.SetContainerPATH(containerPATH)
@@ -73,12 +96,14 @@ func (e *Engine) setupContainer(ctx context.Context, ws *workspace.Workspace, cf
 	// Pre-hook environment probe: captures PATH and other vars from shell
 	// profile files (e.g. mise, rbenv, nvm) so lifecycle hooks have the
 	// user's full environment.
-	probedEnv := e.probeUserEnv(ctx, cc, cfg.UserEnvProbe)
+	stop := e.timings.start("env probe")
+	probedEnv := e.probeUserEnv(ctx, cc, probe)
+	stop()
 	envb.SetProbed(probedEnv)
 	preHookEnv := envb.Build()
 
 	// Run create-time lifecycle hooks (onCreate, updateContent, postCreate).
-	runner := e.newLifecycleRunner(ws, cc, preHookEnv)
+	runner := e.newLifecycleRunner(ws, cc, preHookEnv, e.customHookEnv(cfg))
 	hookErr := runner.runCreateHooks(ctx, hooks, cc.workspaceFolder)
 
 	// PostContainerCreate plugins (e.g. dotfiles installation).
@@ -112,58 +137,176 @@ func (e *Engine) setupContainer(ctx context.Context, ws *workspace.Workspace, cf
 	// Post-hook environment probe: re-captures the environment to pick up
 	// any changes from lifecycle hooks (e.g. tools installed via mise, nvm).
 	// This is what gets persisted for crib shell/exec.
-	postProbe := e.probeUserEnv(ctx, cc, cfg.UserEnvProbe)
+	stop = e.timings.start("env probe (after hooks)")
+	postProbe := e.probeUserEnv(ctx, cc, probe)
+	stop()
 	envb.SetProbed(postProbe)
 
 	return envb.Build(), hookErr
 }
 
 // resolveRemoteEnv resolves ${containerEnv:VAR} references in cfg.RemoteEnv by
-// probing the container's runtime environment. Returns the resolved env map and
-// the container's base PATH for use in PATH preservation.
+// probing the container's runtime environment, along with bare ${VAR}
+// references to other remoteEnv keys or container variables. Returns the
+// resolved env map and the container's base PATH for use in PATH
+// preservation, or an error if remoteEnv references form a cycle. When the
+// probed env has no HOME, the remote user's home is filled in so values like
+// ${containerEnv:HOME}/go still resolve.
 // Per the devcontainer spec, remoteEnv is injected by the tool (not written to
 // /etc/environment) and ${containerEnv:VAR} is only valid in remoteEnv.
-func (e *Engine) resolveRemoteEnv(ctx context.Context, cc containerContext, cfg *config.DevContainerConfig) (map[string]string, string) {
+func (e *Engine) resolveRemoteEnv(ctx context.Context, cc containerContext, cfg *config.DevContainerConfig) (map[string]string, string, error) {
 	var buf bytes.Buffer
-	if err := e.driver.ExecContainer(ctx, cc.workspaceID, cc.containerID, []string{"env"}, nil, &buf, io.Discard, nil, ""); err != nil {
+	if err := e.driver.ExecContainer(ctx, cc.workspaceID, cc.containerID, []string{"env"}, nil, &buf, io.Discard, nil, "", ""); err != nil {
 		e.logger.Warn("failed to probe container environment for remoteEnv resolution", "error", err)
-		return cfg.RemoteEnv, ""
+		return cfg.RemoteEnv, "", nil
 	}
 
 	containerEnv := parseEnvLines(buf.String())
+	if containerEnv["HOME"] == "" {
+		containerEnv["HOME"] = e.remoteUserHome(ctx, cc)
+	}
 	resolved, err := config.SubstituteContainerEnv(containerEnv, cfg)
 	if err != nil {
 		e.logger.Warn("failed to resolve remoteEnv container variables", "error", err)
-		return cfg.RemoteEnv, containerEnv["PATH"]
+		return cfg.RemoteEnv, containerEnv["PATH"], nil
 	}
 
 	resolvedEnv := resolved.RemoteEnv
 
-	// Resolve bare ${VAR} references (e.g. ${PATH}) as container env lookups.
-	// Many devcontainer.json files use ${PATH} instead of ${containerEnv:PATH}
-	// in remoteEnv. Since exec -e doesn't do shell expansion, we must resolve
-	// these ourselves.
-	resolveBareVarRefs(resolvedEnv, containerEnv)
+	// Resolve bare ${VAR} references: other remoteEnv keys first, then
+	// container env lookups (e.g. ${PATH}). Many devcontainer.json files use
+	// ${PATH} instead of ${containerEnv:PATH} in remoteEnv. Since exec -e
+	// doesn't do shell expansion, we must resolve these ourselves.
+	if err := resolveBareVarRefs(resolvedEnv, containerEnv); err != nil {
+		return nil, "", err
+	}
 
-	return resolvedEnv, containerEnv["PATH"]
+	return resolvedEnv, containerEnv["PATH"], nil
 }
 
 // bareVarRe matches ${VARNAME} where VARNAME contains no colons (i.e. not
 // a namespaced reference like ${containerEnv:PATH} or ${localEnv:HOME}).
 var bareVarRe = regexp.MustCompile(`\$\{([^:}]+)\}`)
 
-// resolveBareVarRefs replaces bare ${VAR} references in env values with the
-// corresponding value from containerEnv. This handles the common pattern of
-// writing ${PATH} in remoteEnv instead of ${containerEnv:PATH}.
-func resolveBareVarRefs(env map[string]string, containerEnv map[string]string) {
-	for k, v := range env {
-		env[k] = bareVarRe.ReplaceAllStringFunc(v, func(match string) string {
+// resolveBareVarRefs replaces bare ${VAR} references in env values. A
+// reference to another key of env resolves to that key's (resolved) value,
+// so remoteEnv entries can build on each other (B=${A}/x). Anything else,
+// including a key referring to itself (PATH=${PATH}:/x), resolves to the
+// value from containerEnv. This handles the common pattern of writing
+// ${PATH} in remoteEnv instead of ${containerEnv:PATH}. Unknown references
+// are left as-is. It returns an error, leaving env untouched, if references
+// between keys form a cycle.
+func resolveBareVarRefs(env map[string]string, containerEnv map[string]string) error {
+	resolved := make(map[string]string, len(env))
+	visiting := make(map[string]bool)
+	var path []string
+
+	var resolve func(key string) error
+	resolve = func(key string) error {
+		if _, ok := resolved[key]; ok {
+			return nil
+		}
+		if visiting[key] {
+			start := slices.Index(path, key)
+			cycle := append(slices.Clone(path[start:]), key)
+			return fmt.Errorf("remoteEnv references form a cycle: %s", strings.Join(cycle, " -> "))
+		}
+		visiting[key] = true
+		path = append(path, key)
+
+		var err error
+		value := bareVarRe.ReplaceAllStringFunc(env[key], func(match string) string {
 			name := match[2 : len(match)-1]
+			if _, ok := env[name]; ok && name != key {
+				if rerr := resolve(name); rerr != nil {
+					if err == nil {
+						err = rerr
+					}
+					return match
+				}
+				return resolved[name]
+			}
 			if val, ok := containerEnv[name]; ok {
 				return val
 			}
 			return match
 		})
+		if err != nil {
+			return err
+		}
+
+		path = path[:len(path)-1]
+		delete(visiting, key)
+		resolved[key] = value
+		return nil
+	}
+
+	// Walk keys in a stable order so a cycle is always reported the same way.
+	for _, key := range slices.Sorted(maps.Keys(env)) {
+		if err := resolve(key); err != nil {
+			return err
+		}
+	}
+	maps.Copy(env, resolved)
+	return nil
+}
+
+// createRemoteUserEnabled reports whether customizations.crib.createRemoteUser
+// opts the project in to creating a remoteUser missing from the image.
+func createRemoteUserEnabled(cfg *config.DevContainerConfig) bool {
+	v, _ := extractCribCustomizations(cfg)["createRemoteUser"].(bool)
+	return v
+}
+
+// createRemoteUser adds cc.remoteUser, with a home directory, when the
+// config opts in and the user does not exist in the container. On Linux
+// hosts, unless updateRemoteUserUID is false, the user gets the host UID
+// and GID so syncRemoteUserUID has nothing left to do; an ID already taken
+// by another user is left for it to resolve. Existing users are untouched.
+func (e *Engine) createRemoteUser(ctx context.Context, cc containerContext, cfg *config.DevContainerConfig) error {
+	if !createRemoteUserEnabled(cfg) || cc.remoteUser == "" || cc.remoteUser == "root" {
+		return nil
+	}
+	if _, err := e.execGetUserID(ctx, cc, "u"); err == nil {
+		return nil
+	}
+
+	e.reportProgress(PhaseCreate, "Creating user "+cc.remoteUser+"...")
+	cmd := []string{"useradd", "--create-home"}
+	hostUID, hostGID := getuid(), getgid()
+	matchHost := hostOS == "linux" && hostUID != 0 &&
+		(cfg.UpdateRemoteUserUID == nil || *cfg.UpdateRemoteUserUID)
+	if matchHost {
+		if group, _ := e.execFindGroupByGID(ctx, cc, hostGID); group == "" {
+			groupCmd := []string{"groupadd", "-g", strconv.Itoa(hostGID), cc.remoteUser}
+			var stderr bytes.Buffer
+			if err := e.driver.ExecContainer(ctx, cc.workspaceID, cc.containerID, groupCmd, nil, io.Discard, &stderr, nil, "root", ""); err != nil {
+				return fmt.Errorf("creating group for remote user %q: %w: %s", cc.remoteUser, err, strings.TrimSpace(stderr.String()))
+			}
+		}
+		cmd = append(cmd, "-g", strconv.Itoa(hostGID))
+		if owner, _ := e.execFindUserByUID(ctx, cc, hostUID); owner == "" {
+			cmd = append(cmd, "-u", strconv.Itoa(hostUID))
+		}
+	}
+	cmd = append(cmd, cc.remoteUser)
+
+	var stderr bytes.Buffer
+	if err := e.driver.ExecContainer(ctx, cc.workspaceID, cc.containerID, cmd, nil, io.Discard, &stderr, nil, "root", ""); err != nil {
+		return fmt.Errorf("creating remote user %q (requires useradd in the image): %w: %s", cc.remoteUser, err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// hostOSName returns a readable name for a GOOS value.
+func hostOSName(goos string) string {
+	switch goos {
+	case "darwin":
+		return "macOS"
+	case "windows":
+		return "Windows"
+	default:
+		return goos
 	}
 }
 
@@ -177,13 +320,19 @@ func (e *Engine) syncRemoteUserUID(ctx context.Context, cc containerContext, cfg
 		return false, nil
 	}
 
-	// Guard: only on Linux.
-	if runtime.GOOS != "linux" {
+	// Guard: skip if remoteUser is empty or root.
+	if cc.remoteUser == "" || cc.remoteUser == "root" {
 		return false, nil
 	}
 
-	// Guard: skip if remoteUser is empty or root.
-	if cc.remoteUser == "" || cc.remoteUser == "root" {
+	// Guard: only on Linux. Elsewhere the runtime's VM translates ownership
+	// on bind mounts, so say why nothing happens instead of staying silent
+	// when users look into permission problems. Setup only runs when the
+	// container is created, so this is shown once per container.
+	if hostOS != "linux" {
+		e.reportProgress(PhaseCreate, fmt.Sprintf(
+			"Skipping UID/GID sync for %s on %s: the container runtime's VM maps file ownership on bind mounts (set updateRemoteUserUID to false to hide this)",
+			cc.remoteUser, hostOSName(hostOS)))
 		return false, nil
 	}
 
@@ -227,7 +376,7 @@ func (e *Engine) syncRemoteUserUID(ctx context.Context, cc containerContext, cfg
 			if freeGID, err := e.execFindFreeGID(ctx, cc); err == nil {
 				moveCmd := []string{"groupmod", "-g", strconv.Itoa(freeGID), conflict}
 				var moveStderr bytes.Buffer
-				if err := e.driver.ExecContainer(ctx, cc.workspaceID, cc.containerID, moveCmd, nil, io.Discard, &moveStderr, nil, "root"); err != nil {
+				if err := e.driver.ExecContainer(ctx, cc.workspaceID, cc.containerID, moveCmd, nil, io.Discard, &moveStderr, nil, "root", ""); err != nil {
 					e.logger.Warn("failed to move conflicting group", "group", conflict, "error", err, "stderr", moveStderr.String())
 				}
 			}
@@ -235,7 +384,7 @@ func (e *Engine) syncRemoteUserUID(ctx context.Context, cc containerContext, cfg
 
 		cmd := []string{"groupmod", "-g", strconv.Itoa(hostGID), groupName}
 		var stderr bytes.Buffer
-		if err := e.driver.ExecContainer(ctx, cc.workspaceID, cc.containerID, cmd, nil, io.Discard, &stderr, nil, "root"); err != nil {
+		if err := e.driver.ExecContainer(ctx, cc.workspaceID, cc.containerID, cmd, nil, io.Discard, &stderr, nil, "root", ""); err != nil {
 			e.logger.Warn("failed to sync group GID", "group", groupName, "gid", hostGID, "error", err, "stderr", stderr.String())
 			syncOK = false
 		}
@@ -250,7 +399,7 @@ func (e *Engine) syncRemoteUserUID(ctx context.Context, cc containerContext, cfg
 			if freeUID, err := e.execFindFreeUID(ctx, cc); err == nil {
 				moveCmd := []string{"usermod", "-u", strconv.Itoa(freeUID), conflict}
 				var moveStderr bytes.Buffer
-				if err := e.driver.ExecContainer(ctx, cc.workspaceID, cc.containerID, moveCmd, nil, io.Discard, &moveStderr, nil, "root"); err != nil {
+				if err := e.driver.ExecContainer(ctx, cc.workspaceID, cc.containerID, moveCmd, nil, io.Discard, &moveStderr, nil, "root", ""); err != nil {
 					e.logger.Warn("failed to move conflicting user", "user", conflict, "error", err, "stderr", moveStderr.String())
 				}
 			}
@@ -258,7 +407,7 @@ func (e *Engine) syncRemoteUserUID(ctx context.Context, cc containerContext, cfg
 
 		cmd := []string{"usermod", "-u", strconv.Itoa(hostUID), cc.remoteUser}
 		var stderr bytes.Buffer
-		if err := e.driver.ExecContainer(ctx, cc.workspaceID, cc.containerID, cmd, nil, io.Discard, &stderr, nil, "root"); err != nil {
+		if err := e.driver.ExecContainer(ctx, cc.workspaceID, cc.containerID, cmd, nil, io.Discard, &stderr, nil, "root", ""); err != nil {
 			e.logger.Warn("failed to sync user UID", "user", cc.remoteUser, "uid", hostUID, "error", err, "stderr", stderr.String())
 			syncOK = false
 		}
@@ -269,7 +418,7 @@ func (e *Engine) syncRemoteUserUID(ctx context.Context, cc containerContext, cfg
 	if imageUID != hostUID {
 		cmd := []string{"find", homeDir, "-user", strconv.Itoa(imageUID), "-exec", "chown", "-h", strconv.Itoa(hostUID), "{}", "+"}
 		var stderr bytes.Buffer
-		if err := e.driver.ExecContainer(ctx, cc.workspaceID, cc.containerID, cmd, nil, io.Discard, &stderr, nil, "root"); err != nil {
+		if err := e.driver.ExecContainer(ctx, cc.workspaceID, cc.containerID, cmd, nil, io.Discard, &stderr, nil, "root", ""); err != nil {
 			e.logger.Warn("failed to re-own files after UID sync", "user", cc.remoteUser, "dir", homeDir, "error", err, "stderr", stderr.String())
 		}
 	}
@@ -277,7 +426,7 @@ func (e *Engine) syncRemoteUserUID(ctx context.Context, cc containerContext, cfg
 	if imageGID != hostGID {
 		cmd := []string{"find", homeDir, "-group", strconv.Itoa(imageGID), "-exec", "chgrp", "-h", strconv.Itoa(hostGID), "{}", "+"}
 		var stderr bytes.Buffer
-		if err := e.driver.ExecContainer(ctx, cc.workspaceID, cc.containerID, cmd, nil, io.Discard, &stderr, nil, "root"); err != nil {
+		if err := e.driver.ExecContainer(ctx, cc.workspaceID, cc.containerID, cmd, nil, io.Discard, &stderr, nil, "root", ""); err != nil {
 			e.logger.Warn("failed to re-own files after GID sync", "user", cc.remoteUser, "dir", homeDir, "error", err, "stderr", stderr.String())
 		}
 	}
@@ -289,7 +438,7 @@ func (e *Engine) syncRemoteUserUID(ctx context.Context, cc containerContext, cfg
 func (e *Engine) execGetUserID(ctx context.Context, cc containerContext, flag string) (int, error) {
 	cmd := []string{"id", "-" + flag, cc.remoteUser}
 	var stdout, stderr bytes.Buffer
-	if err := e.driver.ExecContainer(ctx, cc.workspaceID, cc.containerID, cmd, nil, &stdout, &stderr, nil, ""); err != nil {
+	if err := e.driver.ExecContainer(ctx, cc.workspaceID, cc.containerID, cmd, nil, &stdout, &stderr, nil, "", ""); err != nil {
 		return 0, fmt.Errorf("id -%s %s: %w: %s", flag, cc.remoteUser, err, stderr.String())
 	}
 
@@ -305,7 +454,7 @@ func (e *Engine) execGetUserID(ctx context.Context, cc containerContext, flag st
 func (e *Engine) execGetGroupName(ctx context.Context, cc containerContext) (string, error) {
 	cmd := []string{"id", "-gn", cc.remoteUser}
 	var stdout, stderr bytes.Buffer
-	if err := e.driver.ExecContainer(ctx, cc.workspaceID, cc.containerID, cmd, nil, &stdout, &stderr, nil, ""); err != nil {
+	if err := e.driver.ExecContainer(ctx, cc.workspaceID, cc.containerID, cmd, nil, &stdout, &stderr, nil, "", ""); err != nil {
 		return "", fmt.Errorf("id -gn %s: %w: %s", cc.remoteUser, err, stderr.String())
 	}
 
@@ -320,7 +469,7 @@ func (e *Engine) execGetGroupName(ctx context.Context, cc containerContext) (str
 func (e *Engine) execFindUserByUID(ctx context.Context, cc containerContext, uid int) (string, error) { //nolint:unparam // error swallowed intentionally: getent non-zero means not found
 	cmd := []string{"getent", "passwd", strconv.Itoa(uid)}
 	var stdout bytes.Buffer
-	if err := e.driver.ExecContainer(ctx, cc.workspaceID, cc.containerID, cmd, nil, &stdout, io.Discard, nil, ""); err != nil {
+	if err := e.driver.ExecContainer(ctx, cc.workspaceID, cc.containerID, cmd, nil, &stdout, io.Discard, nil, "", ""); err != nil {
 		return "", nil // getent exits non-zero when not found; treat as "not found"
 	}
 	// Output: "username:x:uid:gid:comment:home:shell"
@@ -332,7 +481,7 @@ func (e *Engine) execFindUserByUID(ctx context.Context, cc containerContext, uid
 func (e *Engine) execFindGroupByGID(ctx context.Context, cc containerContext, gid int) (string, error) { //nolint:unparam // error swallowed intentionally: getent non-zero means not found
 	cmd := []string{"getent", "group", strconv.Itoa(gid)}
 	var stdout bytes.Buffer
-	if err := e.driver.ExecContainer(ctx, cc.workspaceID, cc.containerID, cmd, nil, &stdout, io.Discard, nil, ""); err != nil {
+	if err := e.driver.ExecContainer(ctx, cc.workspaceID, cc.containerID, cmd, nil, &stdout, io.Discard, nil, "", ""); err != nil {
 		return "", nil // getent exits non-zero when not found; treat as "not found"
 	}
 	// Output: "groupname:x:gid:members"
@@ -344,7 +493,7 @@ func (e *Engine) execFindGroupByGID(ctx context.Context, cc containerContext, gi
 func (e *Engine) execFindFreeUID(ctx context.Context, cc containerContext) (int, error) {
 	cmd := []string{"awk", "-F:", "BEGIN{max=0}{if($3+0>max)max=$3}END{print max+1}", "/etc/passwd"}
 	var stdout, stderr bytes.Buffer
-	if err := e.driver.ExecContainer(ctx, cc.workspaceID, cc.containerID, cmd, nil, &stdout, &stderr, nil, ""); err != nil {
+	if err := e.driver.ExecContainer(ctx, cc.workspaceID, cc.containerID, cmd, nil, &stdout, &stderr, nil, "", ""); err != nil {
 		return 0, fmt.Errorf("awk /etc/passwd: %w: %s", err, stderr.String())
 	}
 	return strconv.Atoi(strings.TrimSpace(stdout.String()))
@@ -354,7 +503,7 @@ func (e *Engine) execFindFreeUID(ctx context.Context, cc containerContext) (int,
 func (e *Engine) execFindFreeGID(ctx context.Context, cc containerContext) (int, error) {
 	cmd := []string{"awk", "-F:", "BEGIN{max=0}{if($3+0>max)max=$3}END{print max+1}", "/etc/group"}
 	var stdout, stderr bytes.Buffer
-	if err := e.driver.ExecContainer(ctx, cc.workspaceID, cc.containerID, cmd, nil, &stdout, &stderr, nil, ""); err != nil {
+	if err := e.driver.ExecContainer(ctx, cc.workspaceID, cc.containerID, cmd, nil, &stdout, &stderr, nil, "", ""); err != nil {
 		return 0, fmt.Errorf("awk /etc/group: %w: %s", err, stderr.String())
 	}
 	return strconv.Atoi(strings.TrimSpace(stdout.String()))
@@ -365,7 +514,7 @@ func (e *Engine) execFindFreeGID(ctx context.Context, cc containerContext) (int,
 func (e *Engine) chownWorkspace(ctx context.Context, cc containerContext) error {
 	cmd := []string{"chown", "-R", cc.remoteUser + ":", cc.workspaceFolder}
 	var stderr bytes.Buffer
-	if err := e.driver.ExecContainer(ctx, cc.workspaceID, cc.containerID, cmd, nil, io.Discard, &stderr, nil, "root"); err != nil {
+	if err := e.driver.ExecContainer(ctx, cc.workspaceID, cc.containerID, cmd, nil, io.Discard, &stderr, nil, "root", ""); err != nil {
 		return fmt.Errorf("chowning workspace: %w: %s", err, stderr.String())
 	}
 	return nil
@@ -376,13 +525,22 @@ func (e *Engine) chownWorkspace(ctx context.Context, cc containerContext) error
 // directive) before a login shell's /etc/profile can reset them.
 func (e *Engine) probeContainerPATH(ctx context.Context, cc containerContext) string {
 	var stdout bytes.Buffer
-	if err := e.driver.ExecContainer(ctx, cc.workspaceID, cc.containerID, []string{"printenv", "PATH"}, nil, &stdout, io.Discard, nil, ""); err != nil {
+	if err := e.driver.ExecContainer(ctx, cc.workspaceID, cc.containerID, []string{"printenv", "PATH"}, nil, &stdout, io.Discard, nil, "", ""); err != nil {
 		e.logger.Debug("failed to probe container PATH", "error", err)
 		return ""
 	}
 	return strings.TrimSpace(stdout.String())
 }
 
+// userEnvProbe returns the probe mode for cfg, preferring the --env-probe
+// override over the config's userEnvProbe.
+func (e *Engine) userEnvProbe(cfg *config.DevContainerConfig) string {
+	if e.envProbe != "" {
+		return e.envProbe
+	}
+	return cfg.UserEnvProbe
+}
+
 // probeUserEnv probes the container user's environment using the shell type
 // specified by userEnvProbe. Returns the probed environment variables, or nil
 // if probing is skipped or fails.
@@ -395,30 +553,132 @@ func (e *Engine) probeUserEnv(ctx context.Context, cc containerContext, userEnvP
 		return nil
 	}
 
-	shell := e.detectUserShell(ctx, cc)
-
-	var shellArgs []string
 	switch probe {
-	case "loginShell":
-		shellArgs = []string{shell, "-l", "-c", "env"}
-	case "interactiveShell":
-		shellArgs = []string{shell, "-i", "-c", "env"}
-	case "loginInteractiveShell":
-		shellArgs = []string{shell, "-l", "-i", "-c", "env"}
+	case "loginShell", "interactiveShell", "loginInteractiveShell":
 	default:
 		e.logger.Warn("unknown userEnvProbe value, using loginInteractiveShell", "value", probe)
-		shellArgs = []string{shell, "-l", "-i", "-c", "env"}
+		probe = "loginInteractiveShell"
 	}
 
+	shell := e.detectUserShell(ctx, cc)
+	shellArgs := probeShellArgs(shell, probe)
+
 	e.logger.Debug("probing user environment", "probe", probe, "shell", shell)
 
 	var stdout bytes.Buffer
-	if err := e.driver.ExecContainer(ctx, cc.workspaceID, cc.containerID, shellArgs, nil, &stdout, io.Discard, nil, cc.remoteUser); err != nil {
+	if err := e.driver.ExecContainer(ctx, cc.workspaceID, cc.containerID, shellArgs, nil, &stdout, io.Discard, nil, cc.remoteUser, ""); err != nil {
 		e.logger.Warn("userEnvProbe failed", "probe", probe, "shell", shell, "error", err)
 		return nil
 	}
 
-	return parseEnvLines(stdout.String())
+	env := parseEnvLines(stdout.String())
+
+	// pam_env applies /etc/environment to login sessions, but exec never
+	// goes through PAM, so images that only set variables there would lose
+	// them. They sit beneath the shell's values, which win.
+	for k, v := range e.readEtcEnvironment(ctx, cc) {
+		if _, ok := env[k]; !ok {
+			env[k] = v
+		}
+	}
+	return env
+}
+
+// probeShellArgs returns the command that prints the environment of shell
+// started in the given probe mode. bash, zsh and sh take -l and -i as is.
+// fish sources config.fish whether or not it is interactive, so it only
+// gets -l.
+func probeShellArgs(shell, probe string) []string {
+	login := probe == "loginShell" || probe == "loginInteractiveShell"
+	interactive := probe == "interactiveShell" || probe == "loginInteractiveShell"
+	if path.Base(shell) == "fish" {
+		interactive = false
+	}
+
+	args := []string{shell}
+	if login {
+		args = append(args, "-l")
+	}
+	if interactive {
+		args = append(args, "-i")
+	}
+	return append(args, "-c", "env")
+}
+
+// readEtcEnvironment returns the variables set in the container's
+// /etc/environment, or nil if it is missing or unreadable.
+func (e *Engine) readEtcEnvironment(ctx context.Context, cc containerContext) map[string]string {
+	var stdout bytes.Buffer
+	if err := e.driver.ExecContainer(ctx, cc.workspaceID, cc.containerID, []string{"cat", "/etc/environment"}, nil, &stdout, io.Discard, nil, "", ""); err != nil {
+		e.logger.Debug("reading /etc/environment failed", "error", err)
+		return nil
+	}
+	return parseEtcEnvironment(stdout.String())
+}
+
+// RefreshEnv probes the remote user's environment in containerID again and
+// stores the result as the workspace's remoteEnv, so tools installed since
+// the last up (e.g. with mise or nvm) reach later exec sessions. Variables
+// only the stored env has, such as those set by plugins, are kept and its
+// PATH entries are appended; probed values win otherwise. It returns the
+// updated result.
+func (e *Engine) RefreshEnv(ctx context.Context, ws *workspace.Workspace, containerID string) (*workspace.Result, error) {
+	result, err := e.store.LoadResult(ws.ID)
+	if err != nil {
+		return nil, fmt.Errorf("loading workspace result: %w", err)
+	}
+	if result == nil {
+		return nil, fmt.Errorf("workspace %s has no stored environment, run 'crib up' first", ws.ID)
+	}
+	cfg, _, err := e.parseAndSubstitute(ws)
+	if err != nil {
+		return nil, err
+	}
+
+	cc := containerContext{
+		workspaceID:     ws.ID,
+		containerID:     containerID,
+		remoteUser:      configRemoteUser(cfg),
+		workspaceFolder: result.WorkspaceFolder,
+	}
+	if cc.remoteUser == "" {
+		cc.remoteUser = result.RemoteUser
+	}
+
+	probe := e.userEnvProbe(cfg)
+	if probe == "none" {
+		return result, nil
+	}
+	configEnv, containerPATH := cfg.RemoteEnv, ""
+	if len(cfg.RemoteEnv) > 0 {
+		if configEnv, containerPATH, err = e.resolveRemoteEnv(ctx, cc, cfg); err != nil {
+			return nil, err
+		}
+	}
+	if containerPATH == "" {
+		containerPATH = e.probeContainerPATH(ctx, cc)
+	}
+	probed := e.probeUserEnv(ctx, cc, probe)
+	if probed == nil {
+		return nil, fmt.Errorf("probing the environment of %s failed, keeping the stored one", cc.remoteUser)
+	}
+
+	layer := copyStringMap(result.RemoteEnv)
+	if layer == nil {
+		layer = make(map[string]string, len(probed))
+	}
+	maps.Copy(layer, probed)
+	envb := NewEnvBuilder(configEnv)
+	envb.SetProbed(layer)
+	envb.SetContainerPATH(containerPATH)
+	env := envb.Build()
+	preserveContainerPATH(env, result.RemoteEnv["PATH"])
+
+	result.RemoteEnv = env
+	if err := e.store.SaveResult(ws.ID, result); err != nil {
+		return nil, fmt.Errorf("saving workspace result: %w", err)
+	}
+	return result, nil
 }
 
 // detectUserShell determines the remote user's login shell by parsing
@@ -426,7 +686,7 @@ func (e *Engine) probeUserEnv(ctx context.Context, cc containerContext, userEnvP
 func (e *Engine) detectUserShell(ctx context.Context, cc containerContext) string {
 	var stdout bytes.Buffer
 	cmd := []string{"getent", "passwd", cc.remoteUser}
-	if err := e.driver.ExecContainer(ctx, cc.workspaceID, cc.containerID, cmd, nil, &stdout, io.Discard, nil, ""); err != nil {
+	if err := e.driver.ExecContainer(ctx, cc.workspaceID, cc.containerID, cmd, nil, &stdout, io.Discard, nil, "", ""); err != nil {
 		e.logger.Debug("getent passwd failed, trying shell fallbacks", "user", cc.remoteUser, "error", err)
 		return e.detectShellFallback(ctx, cc)
 	}
@@ -435,7 +695,7 @@ func (e *Engine) detectUserShell(ctx context.Context, cc containerContext) strin
 	parts := strings.Split(strings.TrimSpace(stdout.String()), ":")
 	if len(parts) >= 7 && parts[6] != "" {
 		shell := parts[6]
-		if err := e.driver.ExecContainer(ctx, cc.workspaceID, cc.containerID, []string{"test", "-x", shell}, nil, io.Discard, io.Discard, nil, ""); err == nil {
+		if err := e.driver.ExecContainer(ctx, cc.workspaceID, cc.containerID, []string{"test", "-x", shell}, nil, io.Discard, io.Discard, nil, "", ""); err == nil {
 			return shell
 		}
 		e.logger.Debug("user shell not executable, trying fallbacks", "shell", shell)
@@ -444,41 +704,51 @@ func (e *Engine) detectUserShell(ctx context.Context, cc containerContext) strin
 	return e.detectShellFallback(ctx, cc)
 }
 
+// remoteUserHome returns the remote user's home directory from getent
+// passwd, falling back to /root for root and /home/<user> otherwise.
+func (e *Engine) remoteUserHome(ctx context.Context, cc containerContext) string {
+	var stdout bytes.Buffer
+	cmd := []string{"getent", "passwd", plugin.InferOwner(cc.remoteUser)}
+	if err := e.driver.ExecContainer(ctx, cc.workspaceID, cc.containerID, cmd, nil, &stdout, io.Discard, nil, "", ""); err == nil {
+		// Format: username:x:uid:gid:comment:home:shell
+		parts := strings.Split(strings.TrimSpace(stdout.String()), ":")
+		if len(parts) >= 6 && parts[5] != "" {
+			return parts[5]
+		}
+	} else {
+		e.logger.Debug("getent passwd failed, inferring home", "user", cc.remoteUser, "error", err)
+	}
+	return plugin.InferRemoteHome(cc.remoteUser)
+}
+
 // detectShellFallback tries common shells in preference order.
 func (e *Engine) detectShellFallback(ctx context.Context, cc containerContext) string {
 	for _, shell := range []string{"/bin/bash", "/bin/sh"} {
-		if err := e.driver.ExecContainer(ctx, cc.workspaceID, cc.containerID, []string{"test", "-x", shell}, nil, io.Discard, io.Discard, nil, ""); err == nil {
+		if err := e.driver.ExecContainer(ctx, cc.workspaceID, cc.containerID, []string{"test", "-x", shell}, nil, io.Discard, io.Discard, nil, "", ""); err == nil {
 			return shell
 		}
 	}
 	return "/bin/sh"
 }
 
-// prepareExecCmd wraps cmd with cd if workDir is specified and resolves the user.
-func (e *Engine) prepareExecCmd(cc containerContext, cmd []string, user, workDir string) ([]string, string) {
-	execCmd := cmd
-	if workDir != "" {
-		inner := fmt.Sprintf("cd %q 2>/dev/null; %s", workDir, plugin.ShellQuoteJoin(cmd))
-		execCmd = []string{"sh", "-c", inner}
-	}
+// pluginExecUser returns user, defaulting to the container's remote user.
+func pluginExecUser(cc containerContext, user string) string {
 	if user == "" {
-		user = cc.remoteUser
+		return cc.remoteUser
 	}
-	return execCmd, user
+	return user
 }
 
 // execInContainer runs a command inside the container and returns combined
 // stdout+stderr. Used as the ExecFunc adapter for PostContainerCreate plugins.
 func (e *Engine) execInContainer(ctx context.Context, cc containerContext, cmd []string, user string, workDir string, env map[string]string) ([]byte, error) {
-	execCmd, execUser := e.prepareExecCmd(cc, cmd, user, workDir)
 	var buf bytes.Buffer
-	err := e.driver.ExecContainer(ctx, cc.workspaceID, cc.containerID, execCmd, nil, &buf, &buf, envSlice(env), execUser)
+	err := e.driver.ExecContainer(ctx, cc.workspaceID, cc.containerID, cmd, nil, &buf, &buf, envSlice(env), pluginExecUser(cc, user), workDir)
 	return buf.Bytes(), err
 }
 
 // streamExecInContainer runs a command inside the container, streaming output
 // to the provided writers. Used for long-running plugin commands (e.g. dotfiles).
 func (e *Engine) streamExecInContainer(ctx context.Context, cc containerContext, cmd []string, user string, workDir string, env map[string]string, stdout, stderr io.Writer) error {
-	execCmd, execUser := e.prepareExecCmd(cc, cmd, user, workDir)
-	return e.driver.ExecContainer(ctx, cc.workspaceID, cc.containerID, execCmd, nil, stdout, stderr, envSlice(env), execUser)
+	return e.driver.ExecContainer(ctx, cc.workspaceID, cc.containerID, cmd, nil, stdout, stderr, envSlice(env), pluginExecUser(cc, user), workDir)
 }
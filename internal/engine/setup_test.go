@@ -7,14 +7,17 @@ import (
 	"log/slog"
 	"os"
 	"reflect"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/fgrehm/crib/internal/config"
 	"github.com/fgrehm/crib/internal/driver"
+	"github.com/fgrehm/crib/internal/workspace"
 )
 
 // mockDriver implements the Driver interface for testing.
@@ -27,8 +30,9 @@ type mockDriver struct {
 }
 
 type mockExecCall struct {
-	cmd []string
-	env []string
+	cmd     []string
+	env     []string
+	workdir string
 }
 
 func (m *mockDriver) FindContainer(ctx context.Context, workspaceID string) (*driver.ContainerDetails, error) {
@@ -43,7 +47,15 @@ func (m *mockDriver) StartContainer(ctx context.Context, workspaceID, containerI
 	return nil
 }
 
-func (m *mockDriver) StopContainer(ctx context.Context, workspaceID, containerID string) error {
+func (m *mockDriver) StopContainer(ctx context.Context, workspaceID, containerID string, timeout *time.Duration) error {
+	return nil
+}
+
+func (m *mockDriver) PauseContainer(ctx context.Context, workspaceID, containerID string) error {
+	return nil
+}
+
+func (m *mockDriver) UnpauseContainer(ctx context.Context, workspaceID, containerID string) error {
 	return nil
 }
 
@@ -55,9 +67,9 @@ func (m *mockDriver) DeleteContainer(ctx context.Context, workspaceID, container
 	return nil
 }
 
-func (m *mockDriver) ExecContainer(ctx context.Context, workspaceID, containerID string, cmd []string, stdin io.Reader, stdout, stderr io.Writer, env []string, user string) error {
+func (m *mockDriver) ExecContainer(ctx context.Context, workspaceID, containerID string, cmd []string, stdin io.Reader, stdout, stderr io.Writer, env []string, user, workdir string) error {
 	m.mu.Lock()
-	m.execCalls = append(m.execCalls, mockExecCall{cmd: cmd, env: env})
+	m.execCalls = append(m.execCalls, mockExecCall{cmd: cmd, env: env, workdir: workdir})
 	cb := m.execCallback
 	m.mu.Unlock()
 
@@ -103,6 +115,20 @@ func (m *mockDriver) ContainerLogs(ctx context.Context, workspaceID, containerID
 	return nil
 }
 
+func (m *mockDriver) AttachContainer(ctx context.Context, workspaceID, containerID string, stdout, stderr io.Writer) error {
+	return nil
+}
+
+// CopyToContainer reports no native cp, so copies go through ExecContainer
+// and show up in execCalls.
+func (m *mockDriver) CopyToContainer(ctx context.Context, workspaceID, containerID, src, dst string) error {
+	return driver.ErrCopyUnsupported
+}
+
+func (m *mockDriver) CopyFromContainer(ctx context.Context, workspaceID, containerID, src, dst string) error {
+	return driver.ErrCopyUnsupported
+}
+
 func (m *mockDriver) ListContainers(ctx context.Context) ([]driver.ContainerDetails, error) {
 	return nil, nil
 }
@@ -111,6 +137,14 @@ func (m *mockDriver) CommitContainer(ctx context.Context, workspaceID, container
 	return nil
 }
 
+func (m *mockDriver) ExportContainer(ctx context.Context, workspaceID, containerID, output string) error {
+	return nil
+}
+
+func (m *mockDriver) PullImage(ctx context.Context, imageName string, stdout, stderr io.Writer) error {
+	return nil
+}
+
 func (m *mockDriver) RemoveImage(ctx context.Context, imageName string) error {
 	return nil
 }
@@ -139,6 +173,10 @@ func (m *mockDriver) RemoveVolume(ctx context.Context, name string) error {
 	return nil
 }
 
+func (m *mockDriver) ContainerChanges(ctx context.Context, workspaceID, containerID string) ([]driver.FileChange, error) {
+	return nil, nil
+}
+
 // imageTrackingDriver extends mockDriver to track RemoveImage and ListImages
 // calls. Used by build, remove, and prune tests.
 type imageTrackingDriver struct {
@@ -368,6 +406,229 @@ func TestSyncRemoteUserUID_UIDsMatch(t *testing.T) {
 	}
 }
 
+func TestSyncRemoteUserUID_NonLinuxHost(t *testing.T) {
+	// Off Linux the sync is skipped with an explanation instead of silently.
+	origOS := hostOS
+	t.Cleanup(func() { hostOS = origOS })
+	hostOS = "darwin"
+
+	mockDrv := &mockDriver{responses: map[string]string{}}
+	var events []ProgressEvent
+	engine := &Engine{driver: mockDrv, logger: slog.Default(), progress: func(ev ProgressEvent) { events = append(events, ev) }}
+
+	inSync, err := engine.syncRemoteUserUID(context.Background(), containerContext{workspaceID: "ws-1", containerID: "container-1", remoteUser: "vscode"}, &config.DevContainerConfig{})
+	if err != nil {
+		t.Fatalf("syncRemoteUserUID failed: %v", err)
+	}
+	if inSync {
+		t.Error("syncRemoteUserUID should return inSync=false off Linux")
+	}
+	if len(mockDrv.execCalls) != 0 {
+		t.Errorf("syncRemoteUserUID should not exec off Linux, got %d calls", len(mockDrv.execCalls))
+	}
+	if len(events) != 1 {
+		t.Fatalf("progress events = %v, want one explaining the skip", events)
+	}
+	for _, want := range []string{"vscode", "macOS", "VM", "updateRemoteUserUID"} {
+		if !strings.Contains(events[0].Message, want) {
+			t.Errorf("message %q should mention %q", events[0].Message, want)
+		}
+	}
+
+	// Nothing to explain when the sync is turned off or there is no user
+	// to sync.
+	events = nil
+	disabled := false
+	cfg := &config.DevContainerConfig{}
+	cfg.UpdateRemoteUserUID = &disabled
+	if _, err := engine.syncRemoteUserUID(context.Background(), containerContext{remoteUser: "vscode"}, cfg); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := engine.syncRemoteUserUID(context.Background(), containerContext{remoteUser: "root"}, &config.DevContainerConfig{}); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 0 {
+		t.Errorf("progress events = %v, want none", events)
+	}
+}
+
+func createRemoteUserConfig() *config.DevContainerConfig {
+	cfg := &config.DevContainerConfig{}
+	cfg.Customizations = map[string]any{"crib": map[string]any{"createRemoteUser": true}}
+	return cfg
+}
+
+// execCommands returns the exec'd commands joined with spaces.
+func execCommands(m *mockDriver) []string {
+	var cmds []string
+	for _, c := range m.execCalls {
+		cmds = append(cmds, strings.Join(c.cmd, " "))
+	}
+	return cmds
+}
+
+func TestCreateRemoteUser_Missing(t *testing.T) {
+	origOS := hostOS
+	t.Cleanup(func() { hostOS = origOS })
+	hostOS = "linux"
+	origGetuid, origGetgid := getuid, getgid
+	t.Cleanup(func() { getuid, getgid = origGetuid, origGetgid })
+	getuid = func() int { return 1234 }
+	getgid = func() int { return 2345 }
+	uid, gid := "1234", "2345"
+	mockDrv := &mockDriver{
+		responses: map[string]string{},
+		errors:    map[string]error{"id -u dev": fmt.Errorf("id: 'dev': no such user")},
+	}
+	eng := &Engine{driver: mockDrv, logger: slog.Default()}
+
+	cc := containerContext{workspaceID: "ws-1", containerID: "c-1", remoteUser: "dev"}
+	if err := eng.createRemoteUser(context.Background(), cc, createRemoteUserConfig()); err != nil {
+		t.Fatalf("createRemoteUser: %v", err)
+	}
+
+	cmds := execCommands(mockDrv)
+	for _, want := range []string{
+		"groupadd -g " + gid + " dev",
+		"useradd --create-home -g " + gid + " -u " + uid + " dev",
+	} {
+		if !slices.Contains(cmds, want) {
+			t.Errorf("exec calls = %v, want %q", cmds, want)
+		}
+	}
+}
+
+func TestCreateRemoteUser_HostIDsTaken(t *testing.T) {
+	origOS := hostOS
+	t.Cleanup(func() { hostOS = origOS })
+	hostOS = "linux"
+	origGetuid, origGetgid := getuid, getgid
+	t.Cleanup(func() { getuid, getgid = origGetuid, origGetgid })
+	getuid = func() int { return 1234 }
+	getgid = func() int { return 2345 }
+	uid, gid := "1234", "2345"
+	mockDrv := &mockDriver{
+		responses: map[string]string{
+			"getent group " + gid:  "ubuntu:x:" + gid + ":\n",
+			"getent passwd " + uid: "ubuntu:x:" + uid + ":" + gid + "::/home/ubuntu:/bin/bash\n",
+		},
+		errors: map[string]error{"id -u dev": fmt.Errorf("id: 'dev': no such user")},
+	}
+	eng := &Engine{driver: mockDrv, logger: slog.Default()}
+
+	cc := containerContext{workspaceID: "ws-1", containerID: "c-1", remoteUser: "dev"}
+	if err := eng.createRemoteUser(context.Background(), cc, createRemoteUserConfig()); err != nil {
+		t.Fatalf("createRemoteUser: %v", err)
+	}
+
+	cmds := execCommands(mockDrv)
+	want := "useradd --create-home -g " + gid + " dev"
+	if !slices.Contains(cmds, want) {
+		t.Errorf("exec calls = %v, want %q (UID left for the sync to resolve)", cmds, want)
+	}
+	for _, c := range cmds {
+		if strings.HasPrefix(c, "groupadd") {
+			t.Errorf("unexpected %q: the host GID already has a group", c)
+		}
+	}
+}
+
+func TestCreateRemoteUser_NoHostIDsWhenSyncDisabled(t *testing.T) {
+	mockDrv := &mockDriver{
+		responses: map[string]string{},
+		errors:    map[string]error{"id -u dev": fmt.Errorf("id: 'dev': no such user")},
+	}
+	eng := &Engine{driver: mockDrv, logger: slog.Default()}
+	cfg := createRemoteUserConfig()
+	disabled := false
+	cfg.UpdateRemoteUserUID = &disabled
+
+	cc := containerContext{workspaceID: "ws-1", containerID: "c-1", remoteUser: "dev"}
+	if err := eng.createRemoteUser(context.Background(), cc, cfg); err != nil {
+		t.Fatalf("createRemoteUser: %v", err)
+	}
+
+	cmds := execCommands(mockDrv)
+	if !slices.Equal(cmds, []string{"id -u dev", "useradd --create-home dev"}) {
+		t.Errorf("exec calls = %v, want id then a plain useradd", cmds)
+	}
+}
+
+func TestCreateRemoteUser_NoHostIDsOffLinux(t *testing.T) {
+	origOS := hostOS
+	t.Cleanup(func() { hostOS = origOS })
+	hostOS = "darwin"
+	origGetuid, origGetgid := getuid, getgid
+	t.Cleanup(func() { getuid, getgid = origGetuid, origGetgid })
+	getuid = func() int { return 1234 }
+	getgid = func() int { return 2345 }
+	mockDrv := &mockDriver{
+		responses: map[string]string{},
+		errors:    map[string]error{"id -u dev": fmt.Errorf("id: 'dev': no such user")},
+	}
+	eng := &Engine{driver: mockDrv, logger: slog.Default()}
+
+	cc := containerContext{workspaceID: "ws-1", containerID: "c-1", remoteUser: "dev"}
+	if err := eng.createRemoteUser(context.Background(), cc, createRemoteUserConfig()); err != nil {
+		t.Fatalf("createRemoteUser: %v", err)
+	}
+
+	cmds := execCommands(mockDrv)
+	if !slices.Equal(cmds, []string{"id -u dev", "useradd --create-home dev"}) {
+		t.Errorf("exec calls = %v, want id then a plain useradd", cmds)
+	}
+}
+
+func TestCreateRemoteUser_ExistingUserUntouched(t *testing.T) {
+	mockDrv := &mockDriver{responses: map[string]string{"id -u vscode": "1000\n"}}
+	eng := &Engine{driver: mockDrv, logger: slog.Default()}
+
+	cc := containerContext{workspaceID: "ws-1", containerID: "c-1", remoteUser: "vscode"}
+	if err := eng.createRemoteUser(context.Background(), cc, createRemoteUserConfig()); err != nil {
+		t.Fatalf("createRemoteUser: %v", err)
+	}
+
+	if cmds := execCommands(mockDrv); !slices.Equal(cmds, []string{"id -u vscode"}) {
+		t.Errorf("exec calls = %v, want only the existence check", cmds)
+	}
+}
+
+func TestCreateRemoteUser_NotEnabled(t *testing.T) {
+	mockDrv := &mockDriver{
+		responses: map[string]string{},
+		errors:    map[string]error{"id -u dev": fmt.Errorf("id: 'dev': no such user")},
+	}
+	eng := &Engine{driver: mockDrv, logger: slog.Default()}
+
+	cc := containerContext{workspaceID: "ws-1", containerID: "c-1", remoteUser: "dev"}
+	if err := eng.createRemoteUser(context.Background(), cc, &config.DevContainerConfig{}); err != nil {
+		t.Fatalf("createRemoteUser: %v", err)
+	}
+	if len(mockDrv.execCalls) != 0 {
+		t.Errorf("exec calls = %v, want none without createRemoteUser", execCommands(mockDrv))
+	}
+}
+
+func TestCreateRemoteUser_UseraddFails(t *testing.T) {
+	mockDrv := &mockDriver{
+		responses: map[string]string{},
+		errors: map[string]error{
+			"id -u dev":                 fmt.Errorf("id: 'dev': no such user"),
+			"useradd --create-home dev": fmt.Errorf("exit status 127"),
+		},
+	}
+	eng := &Engine{driver: mockDrv, logger: slog.Default()}
+	cfg := createRemoteUserConfig()
+	disabled := false
+	cfg.UpdateRemoteUserUID = &disabled
+
+	cc := containerContext{workspaceID: "ws-1", containerID: "c-1", remoteUser: "dev"}
+	err := eng.createRemoteUser(context.Background(), cc, cfg)
+	if err == nil || !strings.Contains(err.Error(), "useradd") {
+		t.Fatalf("createRemoteUser error = %v, want a useradd failure", err)
+	}
+}
+
 func TestDetectUserShell_FromGetent(t *testing.T) {
 	mockDrv := &mockDriver{
 		responses: map[string]string{
@@ -468,6 +729,137 @@ func TestProbeUserEnv_DefaultIsLoginInteractiveShell(t *testing.T) {
 	}
 }
 
+func TestProbeUserEnv_MergesEtcEnvironment(t *testing.T) {
+	mockDrv := &mockDriver{
+		responses: map[string]string{
+			"getent passwd vscode":   "vscode:x:1000:1000::/home/vscode:/bin/bash\n",
+			"/bin/bash -l -i -c env": "PATH=/usr/local/bin:/usr/bin\nSHARED=shell\n",
+			"cat /etc/environment":   "# system-wide\nPATH=\"/usr/bin\"\nONLY_ETC=\"etc value\"\nSHARED=etc\n",
+		},
+	}
+	eng := &Engine{driver: mockDrv, logger: slog.Default()}
+
+	result := eng.probeUserEnv(context.Background(), containerContext{workspaceID: "ws-1", containerID: "c-1", remoteUser: "vscode"}, "loginInteractiveShell")
+	want := map[string]string{
+		"PATH":     "/usr/local/bin:/usr/bin",
+		"SHARED":   "shell",
+		"ONLY_ETC": "etc value",
+	}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("probeUserEnv = %v, want %v", result, want)
+	}
+}
+
+func TestProbeUserEnv_NoEtcEnvironmentWhenProbeFails(t *testing.T) {
+	mockDrv := &mockDriver{
+		responses: map[string]string{
+			"getent passwd vscode": "vscode:x:1000:1000::/home/vscode:/bin/bash\n",
+			"cat /etc/environment": "ONLY_ETC=1\n",
+		},
+		errors: map[string]error{"/bin/bash -l -i -c env": fmt.Errorf("profile broke")},
+	}
+	eng := &Engine{driver: mockDrv, logger: slog.Default()}
+
+	if result := eng.probeUserEnv(context.Background(), containerContext{workspaceID: "ws-1", containerID: "c-1", remoteUser: "vscode"}, ""); result != nil {
+		t.Errorf("probeUserEnv = %v, want nil when the shell probe fails", result)
+	}
+}
+
+func TestRefreshEnv_UpdatesStoredEnv(t *testing.T) {
+	mockDrv := &mockDriver{
+		responses: map[string]string{
+			"getent passwd vscode":   "vscode:x:1000:1000::/home/vscode:/bin/bash\n",
+			"printenv PATH":          "/usr/local/go/bin:/usr/bin\n",
+			"/bin/bash -l -i -c env": "PATH=/home/vscode/.local/share/mise/shims:/usr/bin\nNEW_TOOL=1\nSHLVL=1\n",
+		},
+	}
+	eng, ws := newUpTimeoutTestEngine(t, mockDrv, `{"image": "alpine", "remoteUser": "vscode"}`)
+	if err := eng.store.SaveResult(ws.ID, &workspace.Result{
+		ContainerID: "c-1",
+		RemoteUser:  "vscode",
+		RemoteEnv:   map[string]string{"PATH": "/plugin/bin:/usr/bin", "SSH_AUTH_SOCK": "/tmp/ssh.sock"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := eng.RefreshEnv(context.Background(), ws, "c-1"); err != nil {
+		t.Fatalf("RefreshEnv: %v", err)
+	}
+
+	probed := false
+	for _, call := range mockDrv.execCalls {
+		if strings.Join(call.cmd, " ") == "/bin/bash -l -i -c env" {
+			probed = true
+		}
+	}
+	if !probed {
+		t.Error("RefreshEnv should probe the user environment")
+	}
+	stored, err := eng.store.LoadResult(ws.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"PATH":          "/home/vscode/.local/share/mise/shims:/usr/bin:/usr/local/go/bin:/plugin/bin",
+		"NEW_TOOL":      "1",
+		"SSH_AUTH_SOCK": "/tmp/ssh.sock",
+	}
+	if !reflect.DeepEqual(stored.RemoteEnv, want) {
+		t.Errorf("stored remoteEnv = %v, want %v", stored.RemoteEnv, want)
+	}
+}
+
+func TestRefreshEnv_ProbeFailureKeepsStoredEnv(t *testing.T) {
+	mockDrv := &mockDriver{
+		responses: map[string]string{
+			"getent passwd vscode": "vscode:x:1000:1000::/home/vscode:/bin/bash\n",
+		},
+		errors: map[string]error{"/bin/bash -l -i -c env": fmt.Errorf("profile broke")},
+	}
+	eng, ws := newUpTimeoutTestEngine(t, mockDrv, `{"image": "alpine", "remoteUser": "vscode"}`)
+	storedEnv := map[string]string{"PATH": "/usr/bin"}
+	if err := eng.store.SaveResult(ws.ID, &workspace.Result{ContainerID: "c-1", RemoteEnv: storedEnv}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := eng.RefreshEnv(context.Background(), ws, "c-1"); err == nil {
+		t.Fatal("RefreshEnv should fail when the probe fails")
+	}
+	stored, err := eng.store.LoadResult(ws.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(stored.RemoteEnv, storedEnv) {
+		t.Errorf("stored remoteEnv = %v, want it unchanged", stored.RemoteEnv)
+	}
+}
+
+func TestParseEtcEnvironment(t *testing.T) {
+	input := `# comment
+PLAIN=value
+DOUBLE="quoted value"
+SINGLE='single'
+export EXPORTED=yes
+  INDENTED=ok
+EMPTY=
+HALF="open
+not a variable
+BAD KEY=x
+`
+	want := map[string]string{
+		"PLAIN":    "value",
+		"DOUBLE":   "quoted value",
+		"SINGLE":   "single",
+		"EXPORTED": "yes",
+		"INDENTED": "ok",
+		"EMPTY":    "",
+		"HALF":     `"open`,
+	}
+	if got := parseEtcEnvironment(input); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseEtcEnvironment = %v, want %v", got, want)
+	}
+}
+
 func TestProbeUserEnv_LoginShell(t *testing.T) {
 	mockDrv := &mockDriver{
 		responses: map[string]string{
@@ -504,6 +896,44 @@ func TestProbeUserEnv_InteractiveShell(t *testing.T) {
 	}
 }
 
+func TestProbeShellArgs(t *testing.T) {
+	tests := []struct {
+		shell string
+		probe string
+		want  string
+	}{
+		{"/bin/bash", "loginInteractiveShell", "/bin/bash -l -i -c env"},
+		{"/bin/zsh", "loginInteractiveShell", "/bin/zsh -l -i -c env"},
+		{"/usr/bin/zsh", "loginShell", "/usr/bin/zsh -l -c env"},
+		{"/bin/zsh", "interactiveShell", "/bin/zsh -i -c env"},
+		{"/usr/bin/fish", "loginInteractiveShell", "/usr/bin/fish -l -c env"},
+		{"/usr/bin/fish", "loginShell", "/usr/bin/fish -l -c env"},
+		{"/usr/bin/fish", "interactiveShell", "/usr/bin/fish -c env"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.shell+" "+tt.probe, func(t *testing.T) {
+			if got := strings.Join(probeShellArgs(tt.shell, tt.probe), " "); got != tt.want {
+				t.Errorf("probeShellArgs = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProbeUserEnv_FishShell(t *testing.T) {
+	mockDrv := &mockDriver{
+		responses: map[string]string{
+			"getent passwd vscode":    "vscode:x:1000:1000::/home/vscode:/usr/bin/fish\n",
+			"/usr/bin/fish -l -c env": "PATH=/home/vscode/.local/bin:/usr/bin\n",
+		},
+	}
+	eng := &Engine{driver: mockDrv, logger: slog.Default()}
+
+	result := eng.probeUserEnv(context.Background(), containerContext{workspaceID: "ws-1", containerID: "c-1", remoteUser: "vscode"}, "loginInteractiveShell")
+	if result["PATH"] != "/home/vscode/.local/bin:/usr/bin" {
+		t.Errorf("PATH = %q, want the fish login PATH (calls: %v)", result["PATH"], execCommands(mockDrv))
+	}
+}
+
 func TestProbeUserEnv_ProbeFails_ReturnsNil(t *testing.T) {
 	mockDrv := &mockDriver{
 		responses: map[string]string{
@@ -521,6 +951,145 @@ func TestProbeUserEnv_ProbeFails_ReturnsNil(t *testing.T) {
 	}
 }
 
+// runSetupWithEnvProbe runs setupContainer for a root remote user with
+// userEnvProbe set to cfgProbe and the engine override set to cliProbe.
+func runSetupWithEnvProbe(t *testing.T, mockDrv *mockDriver, cfgProbe, cliProbe string) map[string]string {
+	t.Helper()
+	eng := &Engine{
+		driver: mockDrv,
+		store:  workspace.NewStoreAt(t.TempDir()),
+		logger: slog.Default(),
+		stdout: io.Discard,
+		stderr: io.Discard,
+	}
+	if err := eng.SetEnvProbe(cliProbe); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.DevContainerConfig{}
+	cfg.UserEnvProbe = cfgProbe
+	ws := &workspace.Workspace{ID: "ws-1"}
+	cc := containerContext{workspaceID: "ws-1", containerID: "c-1", remoteUser: "root"}
+
+	env, err := eng.setupContainer(context.Background(), ws, cfg, cc, NewEnvBuilder(nil), &hookSet{})
+	if err != nil {
+		t.Fatalf("setupContainer: %v", err)
+	}
+	return env
+}
+
+func TestSetupContainer_EnvProbeOverridesConfig(t *testing.T) {
+	mockDrv := &mockDriver{
+		responses: map[string]string{
+			"getent passwd root":  "root:x:0:0::/root:/bin/bash\n",
+			"/bin/bash -l -c env": "FOO=login\n",
+		},
+	}
+
+	env := runSetupWithEnvProbe(t, mockDrv, "interactiveShell", "loginShell")
+	if env["FOO"] != "login" {
+		t.Errorf("FOO = %q, want login (probed with loginShell)", env["FOO"])
+	}
+	for _, c := range mockDrv.execCalls {
+		if slices.Equal(c.cmd, []string{"/bin/bash", "-i", "-c", "env"}) {
+			t.Errorf("config probe ran despite --env-probe override: %v", c.cmd)
+		}
+	}
+}
+
+func TestSetupContainer_EnvProbeNoneSkipsProbing(t *testing.T) {
+	mockDrv := &mockDriver{responses: map[string]string{}}
+
+	runSetupWithEnvProbe(t, mockDrv, "loginShell", "none")
+	for _, c := range mockDrv.execCalls {
+		if c.cmd[len(c.cmd)-1] == "env" || c.cmd[0] == "getent" {
+			t.Errorf("unexpected probe exec with --env-probe none: %v", c.cmd)
+		}
+	}
+}
+
+func TestSetupContainer_ReadonlyWorkspaceSkipsChownAndUIDSync(t *testing.T) {
+	origOS := hostOS
+	t.Cleanup(func() { hostOS = origOS })
+	hostOS = "linux"
+
+	mockDrv := &mockDriver{responses: map[string]string{}}
+	eng := &Engine{
+		driver: mockDrv,
+		store:  workspace.NewStoreAt(t.TempDir()),
+		logger: slog.Default(),
+		stdout: io.Discard,
+		stderr: io.Discard,
+	}
+	cfg := &config.DevContainerConfig{}
+	cfg.UserEnvProbe = "none"
+	cfg.Customizations = map[string]any{"crib": map[string]any{"readonlyWorkspace": true}}
+	ws := &workspace.Workspace{ID: "ws-1"}
+	cc := containerContext{workspaceID: "ws-1", containerID: "c-1", remoteUser: "vscode", workspaceFolder: "/workspaces/project"}
+
+	if _, err := eng.setupContainer(context.Background(), ws, cfg, cc, NewEnvBuilder(nil), &hookSet{}); err != nil {
+		t.Fatalf("setupContainer: %v", err)
+	}
+	for _, c := range mockDrv.execCalls {
+		if c.cmd[0] == "chown" || c.cmd[0] == "id" || c.cmd[0] == "usermod" {
+			t.Errorf("unexpected exec on read-only workspace: %v", c.cmd)
+		}
+	}
+
+	// The same setup with a writable workspace chowns it.
+	mockDrv.execCalls = nil
+	cfg.Customizations = nil
+	if _, err := eng.setupContainer(context.Background(), ws, cfg, cc, NewEnvBuilder(nil), &hookSet{}); err != nil {
+		t.Fatalf("setupContainer: %v", err)
+	}
+	chowned := slices.ContainsFunc(mockDrv.execCalls, func(c mockExecCall) bool {
+		return c.cmd[0] == "chown" && c.cmd[len(c.cmd)-1] == "/workspaces/project"
+	})
+	if !chowned {
+		t.Error("writable workspace was not chowned")
+	}
+}
+
+func TestSetupContainer_CreatesRemoteUserBeforeResolvingRemoteEnv(t *testing.T) {
+	mockDrv := &mockDriver{
+		responses: map[string]string{"env": "PATH=/usr/bin\n"},
+		errors:    map[string]error{"id -u dev": fmt.Errorf("id: 'dev': no such user")},
+	}
+	eng := &Engine{
+		driver: mockDrv,
+		store:  workspace.NewStoreAt(t.TempDir()),
+		logger: slog.Default(),
+		stdout: io.Discard,
+		stderr: io.Discard,
+	}
+	cfg := createRemoteUserConfig()
+	disabled := false
+	cfg.UpdateRemoteUserUID = &disabled
+	cfg.UserEnvProbe = "none"
+	cfg.RemoteEnv = map[string]string{"BASE_PATH": "${containerEnv:PATH}"}
+	ws := &workspace.Workspace{ID: "ws-1"}
+	cc := containerContext{workspaceID: "ws-1", containerID: "c-1", remoteUser: "dev"}
+
+	if _, err := eng.setupContainer(context.Background(), ws, cfg, cc, NewEnvBuilder(nil), &hookSet{}); err != nil {
+		t.Fatalf("setupContainer: %v", err)
+	}
+	cmds := execCommands(mockDrv)
+	useradd := slices.Index(cmds, "useradd --create-home dev")
+	probe := slices.Index(cmds, "env")
+	if useradd < 0 || probe < 0 || useradd > probe {
+		t.Errorf("exec calls = %v, want useradd before the remoteEnv probe", cmds)
+	}
+}
+
+func TestSetEnvProbe_Invalid(t *testing.T) {
+	eng := &Engine{}
+	if err := eng.SetEnvProbe("bash"); err == nil {
+		t.Fatal("expected error for invalid env probe")
+	}
+	if err := eng.SetEnvProbe("none"); err != nil {
+		t.Fatalf("SetEnvProbe(none): %v", err)
+	}
+}
+
 func TestFilterProbedEnv_ProbedOnly(t *testing.T) {
 	probed := map[string]string{"PATH": "/usr/bin:/custom", "HOME": "/home/user"}
 	result := filterProbedEnv(probed)
@@ -747,12 +1316,39 @@ func TestResolveBareVarRefs(t *testing.T) {
 			env:  map[string]string{"PLAIN": "hello"},
 			want: map[string]string{"PLAIN": "hello"},
 		},
+		{
+			name: "resolves references to other keys",
+			env:  map[string]string{"A": "1", "B": "${A}/x"},
+			want: map[string]string{"A": "1", "B": "1/x"},
+		},
+		{
+			name: "resolves chained references in dependency order",
+			env: map[string]string{
+				"APP_BIN":  "${APP_ROOT}/bin",
+				"APP_ROOT": "${TOOLS}/app",
+				"TOOLS":    "${HOME}/tools",
+				"PATH":     "${APP_BIN}:${PATH}",
+			},
+			want: map[string]string{
+				"APP_BIN":  "/home/vscode/tools/app/bin",
+				"APP_ROOT": "/home/vscode/tools/app",
+				"TOOLS":    "/home/vscode/tools",
+				"PATH":     "/home/vscode/tools/app/bin:/usr/local/bin:/usr/bin:/bin",
+			},
+		},
+		{
+			name: "other keys take precedence over the container env",
+			env:  map[string]string{"HOME": "/workspace/home", "CACHE": "${HOME}/.cache"},
+			want: map[string]string{"HOME": "/workspace/home", "CACHE": "/workspace/home/.cache"},
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			env := copyStringMap(tt.env)
-			resolveBareVarRefs(env, containerEnv)
+			if err := resolveBareVarRefs(env, containerEnv); err != nil {
+				t.Fatalf("resolveBareVarRefs: %v", err)
+			}
 			if !reflect.DeepEqual(env, tt.want) {
 				t.Errorf("resolveBareVarRefs() = %v, want %v", env, tt.want)
 			}
@@ -760,9 +1356,100 @@ func TestResolveBareVarRefs(t *testing.T) {
 	}
 }
 
+func TestResolveBareVarRefs_Cycle(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantErr string
+	}{
+		{
+			name:    "two keys",
+			env:     map[string]string{"A": "${B}", "B": "${A}/x"},
+			wantErr: "remoteEnv references form a cycle: A -> B -> A",
+		},
+		{
+			name:    "through a chain",
+			env:     map[string]string{"A": "${B}", "B": "${C}", "C": "x:${A}", "D": "${A}"},
+			wantErr: "remoteEnv references form a cycle: A -> B -> C -> A",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := copyStringMap(tt.env)
+			err := resolveBareVarRefs(env, map[string]string{"A": "from-container"})
+			if err == nil || err.Error() != tt.wantErr {
+				t.Fatalf("error = %v, want %q", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(env, tt.env) {
+				t.Errorf("env modified on error: %v", env)
+			}
+		})
+	}
+}
+
 func TestFilterProbedEnv_Nil(t *testing.T) {
 	result := filterProbedEnv(nil)
 	if result != nil {
 		t.Errorf("filterProbedEnv(nil) = %v, want nil", result)
 	}
 }
+
+func TestResolveRemoteEnv_HomeReference(t *testing.T) {
+	tests := []struct {
+		name      string
+		user      string
+		responses map[string]string
+		want      string
+	}{
+		{
+			name:      "probed HOME",
+			user:      "vscode",
+			responses: map[string]string{"env": "HOME=/home/probed\nPATH=/usr/bin\n"},
+			want:      "/home/probed/go",
+		},
+		{
+			name: "passwd home",
+			user: "dev",
+			responses: map[string]string{
+				"env":               "PATH=/usr/bin\n",
+				"getent passwd dev": "dev:x:1000:1000::/srv/dev:/bin/bash\n",
+			},
+			want: "/srv/dev/go",
+		},
+		{
+			name:      "inferred user home",
+			user:      "vscode",
+			responses: map[string]string{"env": "PATH=/usr/bin\n"},
+			want:      "/home/vscode/go",
+		},
+		{
+			name:      "inferred root home",
+			user:      "",
+			responses: map[string]string{"env": "PATH=/usr/bin\n"},
+			want:      "/root/go",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDrv := &mockDriver{responses: tt.responses}
+			eng := &Engine{driver: mockDrv, logger: slog.Default()}
+			cfg := &config.DevContainerConfig{}
+			cfg.RemoteEnv = map[string]string{
+				"GOPATH": "${containerEnv:HOME}/go",
+				"GOBIN":  "${GOPATH}/bin",
+			}
+			cc := containerContext{workspaceID: "ws-1", containerID: "c-1", remoteUser: tt.user}
+
+			env, _, err := eng.resolveRemoteEnv(context.Background(), cc, cfg)
+			if err != nil {
+				t.Fatalf("resolveRemoteEnv: %v", err)
+			}
+			if env["GOPATH"] != tt.want {
+				t.Errorf("GOPATH = %q, want %q", env["GOPATH"], tt.want)
+			}
+			if env["GOBIN"] != tt.want+"/bin" {
+				t.Errorf("GOBIN = %q, want %q", env["GOBIN"], tt.want+"/bin")
+			}
+		})
+	}
+}
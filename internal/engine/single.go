@@ -3,10 +3,17 @@ package engine
 import (
 	"bytes"
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
+	"maps"
+	"net"
 	"os"
 	"path/filepath"
+	"runtime"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -30,6 +37,181 @@ var defaultCmd = []string{"-c", sleepScript}
 // The feature entrypoint chains via exec "$@", so CMD must be a full command.
 var featureCmd = []string{"/bin/sh", "-c", sleepScript}
 
+// keepAliveCommand returns the entrypoint and command that keep the container
+// alive when overrideCommand is not false. customizations.crib.keepAlive
+// replaces the default /bin/sh sleep loop for images without /bin/sh, e.g.
+// ["/busybox", "sh", "-c", "sleep infinity"] or ["tail", "-f", "/dev/null"].
+//
+// When features bake an ENTRYPOINT into the image, the entrypoint is left
+// alone and the full keep-alive command becomes CMD, since feature
+// entrypoints chain via exec "$@": they start their daemons, then exec into
+// the keep-alive.
+func keepAliveCommand(cfg *config.DevContainerConfig, hasFeatureEntrypoints bool) (string, []string, error) {
+	custom, err := customKeepAlive(cfg)
+	if err != nil {
+		return "", nil, err
+	}
+	switch {
+	case custom != nil && hasFeatureEntrypoints:
+		return "", custom, nil
+	case custom != nil:
+		return custom[0], custom[1:], nil
+	case hasFeatureEntrypoints:
+		return "", featureCmd, nil
+	default:
+		return defaultEntrypoint, defaultCmd, nil
+	}
+}
+
+// customKeepAlive reads customizations.crib.keepAlive, returning nil when it
+// is not set.
+func customKeepAlive(cfg *config.DevContainerConfig) ([]string, error) {
+	raw, ok := extractCribCustomizations(cfg)["keepAlive"]
+	if !ok || raw == nil {
+		return nil, nil
+	}
+	items, ok := raw.([]any)
+	if !ok || len(items) == 0 {
+		return nil, fmt.Errorf("customizations.crib.keepAlive must be a non-empty array of strings")
+	}
+	cmd := make([]string, len(items))
+	for i, item := range items {
+		s, ok := item.(string)
+		if !ok || s == "" {
+			return nil, fmt.Errorf("customizations.crib.keepAlive must be a non-empty array of strings")
+		}
+		cmd[i] = s
+	}
+	return cmd, nil
+}
+
+// hostOS is the host operating system. It is a variable so tests can
+// override it.
+var hostOS = runtime.GOOS
+
+// hostDockerInternal is the name Docker Desktop resolves to the host. Docker
+// Engine on Linux does not add it; customizations.crib.hostDockerInternal
+// has crib map it to the host gateway.
+const hostDockerInternal = "host.docker.internal"
+
+// extraHosts returns the /etc/hosts entries for the container: those from
+// customizations.crib.extraHosts followed by the --add-host values. When
+// customizations.crib.hostDockerInternal is true and the runtime is Docker on
+// Linux, host.docker.internal is mapped to the host gateway unless an entry
+// for it exists or userMapped reports that the user's own runtime config
+// already mentions it.
+func (e *Engine) extraHosts(cfg *config.DevContainerConfig, userMapped bool) ([]string, error) {
+	var hosts []string
+	if raw, ok := extractCribCustomizations(cfg)["extraHosts"]; ok && raw != nil {
+		items, ok := raw.([]any)
+		if !ok {
+			return nil, fmt.Errorf("customizations.crib.extraHosts must be an array of \"name:ip\" strings")
+		}
+		for _, item := range items {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("customizations.crib.extraHosts must be an array of \"name:ip\" strings")
+			}
+			hosts = append(hosts, s)
+		}
+	}
+	hosts = append(hosts, e.addHosts...)
+
+	for _, h := range hosts {
+		name, _, err := splitExtraHost(h)
+		if err != nil {
+			return nil, err
+		}
+		if name == hostDockerInternal {
+			userMapped = true
+		}
+	}
+	auto, _ := extractCribCustomizations(cfg)["hostDockerInternal"].(bool)
+	if auto && e.runtimeName == "docker" && hostOS == "linux" && !userMapped {
+		hosts = append(hosts, hostDockerInternal+":host-gateway")
+	}
+	return hosts, nil
+}
+
+// tmpfsMounts returns the tmpfs mounts for the container, as
+// "path[:options]": those from customizations.crib.tmpfs followed by the
+// --tmpfs values. A --tmpfs entry replaces a config entry for the same path.
+func (e *Engine) tmpfsMounts(cfg *config.DevContainerConfig) ([]string, error) {
+	const errFormat = "customizations.crib.tmpfs must be an array of \"path[:options]\" strings"
+	var specs []string
+	if raw, ok := extractCribCustomizations(cfg)["tmpfs"]; ok && raw != nil {
+		items, ok := raw.([]any)
+		if !ok {
+			return nil, errors.New(errFormat)
+		}
+		for _, item := range items {
+			s, ok := item.(string)
+			if !ok {
+				return nil, errors.New(errFormat)
+			}
+			if err := validateTmpfs(s); err != nil {
+				return nil, err
+			}
+			specs = append(specs, s)
+		}
+	}
+	for _, s := range e.tmpfs {
+		path, _, _ := strings.Cut(s, ":")
+		specs = slices.DeleteFunc(specs, func(c string) bool {
+			p, _, _ := strings.Cut(c, ":")
+			return p == path
+		})
+		specs = append(specs, s)
+	}
+	return specs, nil
+}
+
+// validateTmpfs checks a "path[:options]" tmpfs entry: the path must be
+// absolute and options, when given, not empty.
+func validateTmpfs(spec string) error {
+	path, opts, hasOpts := strings.Cut(spec, ":")
+	if !strings.HasPrefix(path, "/") {
+		return fmt.Errorf("invalid tmpfs %q: path must be absolute (e.g. /tmp or /tmp:size=1g)", spec)
+	}
+	if hasOpts && opts == "" {
+		return fmt.Errorf("invalid tmpfs %q: empty options after ':'", spec)
+	}
+	return nil
+}
+
+// networkName returns the network to connect the container to: the
+// --network value, or customizations.crib.network. It is empty when neither
+// is set.
+func (e *Engine) networkName(cfg *config.DevContainerConfig) (string, error) {
+	if e.network != "" {
+		return e.network, nil
+	}
+	raw, ok := extractCribCustomizations(cfg)["network"]
+	if !ok || raw == nil {
+		return "", nil
+	}
+	name, ok := raw.(string)
+	if !ok {
+		return "", fmt.Errorf("customizations.crib.network must be a string")
+	}
+	return name, nil
+}
+
+// splitExtraHost splits a "name:ip" hosts entry. The IP may be an IPv6
+// address or the special value "host-gateway".
+func splitExtraHost(entry string) (string, string, error) {
+	name, ip, ok := strings.Cut(entry, ":")
+	if !ok || name == "" || (ip != "host-gateway" && net.ParseIP(ip) == nil) {
+		return "", "", fmt.Errorf("invalid extra host %q: expected name:ip (e.g. api.local:10.0.0.5 or name:host-gateway)", entry)
+	}
+	return name, ip, nil
+}
+
+// mentionsHostDockerInternal reports whether s refers to host.docker.internal.
+func mentionsHostDockerInternal(s string) bool {
+	return strings.Contains(s, hostDockerInternal)
+}
+
 // buildRunOptions constructs RunOptions from the devcontainer config.
 // hasFeatureEntrypoints indicates the image has feature-declared entrypoints
 // baked in via ENTRYPOINT; when true, overrideCommand only sets CMD.
@@ -47,21 +229,17 @@ func (e *Engine) buildRunOptions(cfg *config.DevContainerConfig, imageName, proj
 	// Entrypoint and command.
 	overrideCommand := cfg.OverrideCommand == nil || *cfg.OverrideCommand
 	if overrideCommand {
-		if hasFeatureEntrypoints {
-			// Feature entrypoints are baked into the image as ENTRYPOINT.
-			// They chain via exec "$@", so we only set CMD to keep the
-			// container alive. The entrypoint starts its daemons, then
-			// execs into the sleep loop.
-			opts.Cmd = featureCmd
-		} else {
-			opts.Entrypoint = defaultEntrypoint
-			opts.Cmd = defaultCmd
+		entrypoint, cmd, err := keepAliveCommand(cfg, hasFeatureEntrypoints)
+		if err != nil {
+			return nil, err
 		}
+		opts.Entrypoint = entrypoint
+		opts.Cmd = cmd
 	}
 
-	// Environment variables.
-	for k, v := range cfg.ContainerEnv {
-		opts.Env = append(opts.Env, k+"="+v)
+	// Environment variables, sorted by key so run args are stable.
+	for _, k := range slices.Sorted(maps.Keys(cfg.ContainerEnv)) {
+		opts.Env = append(opts.Env, k+"="+cfg.ContainerEnv[k])
 	}
 
 	// Init process.
@@ -76,6 +254,7 @@ func (e *Engine) buildRunOptions(cfg *config.DevContainerConfig, imageName, proj
 
 	// Capabilities.
 	opts.CapAdd = cfg.CapAdd
+	opts.CapDrop = append(slices.Clone(cfg.CapDrop), e.capDrop...)
 
 	// Security options.
 	opts.SecurityOpt = cfg.SecurityOpt
@@ -86,6 +265,10 @@ func (e *Engine) buildRunOptions(cfg *config.DevContainerConfig, imageName, proj
 		if err != nil {
 			return nil, fmt.Errorf("parsing workspace mount: %w", err)
 		}
+		if e.workspaceFolder != "" {
+			// --workspace-folder moves the mount along with the folder.
+			mount.Target = workspaceFolder
+		}
 		opts.WorkspaceMount = mount
 	} else {
 		// Default workspace mount: bind the project root to the workspace folder.
@@ -95,12 +278,55 @@ func (e *Engine) buildRunOptions(cfg *config.DevContainerConfig, imageName, proj
 			Target: workspaceFolder,
 		}
 	}
+	if readonlyWorkspace(cfg) {
+		opts.WorkspaceMount.ReadOnly = true
+	}
 
 	// Additional mounts.
-	opts.Mounts = cfg.Mounts
+	opts.Mounts = resolveMountSources(cfg.Mounts, projectRoot)
 
 	// Published ports from forwardPorts and appPort.
 	opts.Ports = collectPorts(cfg.ForwardPorts, cfg.AppPort)
+	for _, p := range cfg.ForwardPorts {
+		if service, _, ok := splitServicePort(p); ok {
+			e.logger.Warn("ignoring forwardPorts entry for a compose service in a non-compose workspace", "port", p, "service", service)
+		}
+	}
+
+	// Extra /etc/hosts entries.
+	hosts, err := e.extraHosts(cfg, slices.ContainsFunc(cfg.RunArgs, mentionsHostDockerInternal))
+	if err != nil {
+		return nil, err
+	}
+	opts.ExtraHosts = hosts
+
+	// Tmpfs mounts.
+	if opts.Tmpfs, err = e.tmpfsMounts(cfg); err != nil {
+		return nil, err
+	}
+
+	// Network.
+	network, err := e.networkName(cfg)
+	if err != nil {
+		return nil, err
+	}
+	opts.Network = network
+
+	// GPU passthrough.
+	opts.GPUs = e.gpuRequest(cfg)
+
+	// CPU and memory limits from hostRequirements, when enabled.
+	cpus, memory, err := e.resourceLimitValues(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if cpus > 0 {
+		opts.CPUs = strconv.Itoa(cpus)
+	}
+	if memory > 0 {
+		opts.Memory = strconv.FormatInt(memory, 10)
+	}
+	opts.CPUSet = e.cpuset
 
 	// Passthrough CLI args from runArgs.
 	opts.ExtraArgs = cfg.RunArgs
@@ -108,10 +334,158 @@ func (e *Engine) buildRunOptions(cfg *config.DevContainerConfig, imageName, proj
 	return opts, nil
 }
 
+// gpuRequest returns the GPUs to expose to the container: the --gpus value,
+// or "all" when hostRequirements.gpu requires a GPU. An "optional" GPU is
+// not requested, since the run fails outright on hosts without one.
+func (e *Engine) gpuRequest(cfg *config.DevContainerConfig) string {
+	switch e.gpus {
+	case "none":
+		return ""
+	case "":
+		if cfg.HostRequirements != nil && requiresGPU(cfg.HostRequirements.GPU) {
+			return "all"
+		}
+		return ""
+	default:
+		return e.gpus
+	}
+}
+
+// resourceLimitValues returns the CPU count and memory limit in bytes to
+// enforce on the container: hostRequirements.cpus and
+// hostRequirements.memory when --resource-limits is set, zero otherwise.
+func (e *Engine) resourceLimitValues(cfg *config.DevContainerConfig) (int, int64, error) {
+	if !e.resourceLimits || cfg.HostRequirements == nil {
+		return 0, 0, nil
+	}
+	var memory int64
+	if cfg.HostRequirements.Memory != "" {
+		var err error
+		if memory, err = parseMemorySize(cfg.HostRequirements.Memory); err != nil {
+			return 0, 0, fmt.Errorf("hostRequirements.memory: %w", err)
+		}
+	}
+	return cfg.HostRequirements.CPUs, memory, nil
+}
+
+// readonlyWorkspace reports whether the workspace is mounted read-only,
+// either through the readonly option of workspaceMount or with
+// customizations.crib.readonlyWorkspace for the default mount.
+func readonlyWorkspace(cfg *config.DevContainerConfig) bool {
+	if v, _ := extractCribCustomizations(cfg)["readonlyWorkspace"].(bool); v {
+		return true
+	}
+	if cfg.WorkspaceMount == "" {
+		return false
+	}
+	m, err := config.ParseMount(cfg.WorkspaceMount)
+	return err == nil && m.ReadOnly
+}
+
+// validateCPUSet checks a --cpuset-cpus list: comma-separated CPU numbers
+// or ascending "first-last" ranges. An empty value is valid.
+func validateCPUSet(cpus string) error {
+	if cpus == "" {
+		return nil
+	}
+	for part := range strings.SplitSeq(cpus, ",") {
+		first, last, isRange := strings.Cut(part, "-")
+		lo, err := strconv.ParseUint(first, 10, 16)
+		if err != nil {
+			return fmt.Errorf("invalid cpuset %q: %q is not a CPU number or range", cpus, part)
+		}
+		if !isRange {
+			continue
+		}
+		hi, err := strconv.ParseUint(last, 10, 16)
+		if err != nil || hi < lo {
+			return fmt.Errorf("invalid cpuset %q: %q is not a CPU number or range", cpus, part)
+		}
+	}
+	return nil
+}
+
+// validateCapability checks a Linux capability name as accepted by
+// --cap-drop: letters and underscores, with or without the CAP_ prefix, or
+// ALL.
+func validateCapability(name string) error {
+	if name == "" || strings.TrimLeft(strings.ToUpper(name), "ABCDEFGHIJKLMNOPQRSTUVWXYZ_") != "" {
+		return fmt.Errorf("invalid capability %q", name)
+	}
+	return nil
+}
+
+// memorySizeUnits maps the hostRequirements memory units to their size in
+// bytes. Like docker --memory, units are binary (1gb is 1024 mb).
+var memorySizeUnits = map[string]int64{
+	"":   1,
+	"b":  1,
+	"k":  1 << 10,
+	"kb": 1 << 10,
+	"m":  1 << 20,
+	"mb": 1 << 20,
+	"g":  1 << 30,
+	"gb": 1 << 30,
+	"t":  1 << 40,
+	"tb": 1 << 40,
+}
+
+// parseMemorySize parses a hostRequirements memory string such as "8gb",
+// "512mb" or "1.5GB" into bytes.
+func parseMemorySize(s string) (int64, error) {
+	v := strings.ToLower(strings.TrimSpace(s))
+	i := strings.IndexFunc(v, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+	if i < 0 {
+		i = len(v)
+	}
+	unit, ok := memorySizeUnits[strings.TrimSpace(v[i:])]
+	if !ok {
+		return 0, fmt.Errorf("invalid memory size %q (want a number with kb, mb, gb or tb)", s)
+	}
+	n, err := strconv.ParseFloat(v[:i], 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid memory size %q (want a number with kb, mb, gb or tb)", s)
+	}
+	return int64(n * float64(unit)), nil
+}
+
+// requiresGPU reports whether a hostRequirements.gpu value asks for a GPU:
+// true, or an object with cores/memory requirements.
+func requiresGPU(v any) bool {
+	switch gpu := v.(type) {
+	case bool:
+		return gpu
+	case map[string]any:
+		return true
+	default:
+		return false
+	}
+}
+
+// resolveMountSources returns a copy of mounts with relative bind-mount
+// sources (e.g. "./data") made absolute against projectRoot. Without this the
+// runtime would resolve them against crib's working directory. Named volumes
+// and absolute paths are left untouched.
+func resolveMountSources(mounts []config.Mount, projectRoot string) []config.Mount {
+	if len(mounts) == 0 {
+		return mounts
+	}
+	out := make([]config.Mount, len(mounts))
+	for i, m := range mounts {
+		if m.Type == "bind" && m.Source != "" && !filepath.IsAbs(m.Source) {
+			m.Source = filepath.Join(projectRoot, m.Source)
+		}
+		out[i] = m
+	}
+	return out
+}
+
 // applyFeatureMetadata merges feature-declared runtime capabilities into the
 // run options using collectFeatureOverrides for the metadata extraction.
-// subCtx is used to substitute variables (e.g. ${devcontainerId}) in mount
-// sources and containerEnv values. If nil, no substitution is performed.
+// Feature env is prepended, so env already in opts (the project's) wins on
+// duplicate keys. subCtx is used to substitute variables (e.g.
+// ${devcontainerId}) in mount sources and containerEnv values. If nil, no
+// substitution is performed.
 func applyFeatureMetadata(opts *driver.RunOptions, metadata []*config.ImageMetadata, subCtx *config.SubstitutionContext) {
 	ov := collectFeatureOverrides(metadata, subCtx)
 	if ov.Privileged {
@@ -121,11 +495,14 @@ func applyFeatureMetadata(opts *driver.RunOptions, metadata []*config.ImageMetad
 		opts.Init = true
 	}
 	opts.CapAdd = append(opts.CapAdd, ov.CapAdd...)
+	opts.CapDrop = append(opts.CapDrop, ov.CapDrop...)
 	opts.SecurityOpt = append(opts.SecurityOpt, ov.SecurityOpt...)
 	opts.Mounts = append(opts.Mounts, ov.Mounts...)
-	for k, v := range ov.Env {
-		opts.Env = append(opts.Env, k+"="+v)
+	featureEnv := make([]string, 0, len(ov.Env)+len(opts.Env))
+	for _, k := range slices.Sorted(maps.Keys(ov.Env)) {
+		featureEnv = append(featureEnv, k+"="+ov.Env[k])
 	}
+	opts.Env = append(featureEnv, opts.Env...)
 }
 
 // chownPluginVolumes changes ownership of plugin volume mounts to the
@@ -137,7 +514,7 @@ func (e *Engine) chownPluginVolumes(ctx context.Context, cc containerContext, mo
 			continue
 		}
 		cmd := []string{"chown", cc.remoteUser + ":", m.Target}
-		if err := e.driver.ExecContainer(ctx, cc.workspaceID, cc.containerID, cmd, nil, io.Discard, io.Discard, nil, "root"); err != nil {
+		if err := e.driver.ExecContainer(ctx, cc.workspaceID, cc.containerID, cmd, nil, io.Discard, io.Discard, nil, "root", ""); err != nil {
 			e.logger.Debug("chown plugin volume failed", "target", m.Target, "error", err)
 		}
 	}
@@ -147,7 +524,7 @@ func (e *Engine) chownPluginVolumes(ctx context.Context, cc containerContext, mo
 // user. Returns empty string on failure or if the user is root.
 func (e *Engine) detectContainerUser(ctx context.Context, cc containerContext) string {
 	var stdout bytes.Buffer
-	if err := e.driver.ExecContainer(ctx, cc.workspaceID, cc.containerID, []string{"whoami"}, nil, &stdout, io.Discard, nil, ""); err != nil {
+	if err := e.driver.ExecContainer(ctx, cc.workspaceID, cc.containerID, []string{"whoami"}, nil, &stdout, io.Discard, nil, "", ""); err != nil {
 		return ""
 	}
 	user := strings.TrimSpace(stdout.String())
@@ -158,16 +535,26 @@ func (e *Engine) detectContainerUser(ctx context.Context, cc containerContext) s
 }
 
 // collectPorts combines forwardPorts and appPort into publish specs.
-// Bare numbers become "port:port"; entries with ":" pass through as-is.
-// Duplicates are removed (first occurrence wins).
+// Bare numbers and "localhost:port" become "port:port"; other entries with
+// ":" pass through as-is.
+// A protocol suffix ("53/udp", "53:53/udp") is kept on the spec; "/tcp" is
+// the default and is dropped. Duplicates are removed (first occurrence wins),
+// so tcp and udp bindings of the same port are both kept. Compose
+// "service:port" entries are skipped; see composeServicePorts.
 func collectPorts(forwardPorts, appPort config.StrIntArray) []string {
 	seen := make(map[string]bool)
 	var result []string
 	for _, list := range []config.StrIntArray{forwardPorts, appPort} {
 		for _, p := range list {
-			spec := p
-			if !strings.Contains(p, ":") {
-				spec = p + ":" + p
+			if _, _, ok := splitServicePort(p); ok {
+				continue
+			}
+			spec, proto := splitPortProtocol(strings.TrimPrefix(p, "localhost:"))
+			if !strings.Contains(spec, ":") {
+				spec = spec + ":" + spec
+			}
+			if proto != "tcp" {
+				spec += "/" + proto
 			}
 			if !seen[spec] {
 				seen[spec] = true
@@ -178,6 +565,35 @@ func collectPorts(forwardPorts, appPort config.StrIntArray) []string {
 	return result
 }
 
+// splitPortProtocol splits a "/proto" suffix off a port spec, defaulting the
+// protocol to "tcp".
+func splitPortProtocol(spec string) (string, string) {
+	if port, proto, ok := strings.Cut(spec, "/"); ok && proto != "" {
+		return port, strings.ToLower(proto)
+	}
+	return spec, "tcp"
+}
+
+// splitServicePort splits a compose "service:port" forwardPorts entry (e.g.
+// "db:5432" or "dns:53/udp") into the service name and the port with its
+// protocol suffix. ok is false for publish specs such as "8080", "8080:80",
+// or "127.0.0.1:8080:80", and for "localhost:port", which names a port of
+// the dev container itself.
+func splitServicePort(spec string) (service, port string, ok bool) {
+	service, port, ok = strings.Cut(spec, ":")
+	if !ok || service == "" || service == "localhost" || strings.Contains(port, ":") {
+		return "", "", false
+	}
+	if _, err := strconv.Atoi(service); err == nil || net.ParseIP(service) != nil {
+		return "", "", false
+	}
+	number, _ := splitPortProtocol(port)
+	if _, err := strconv.Atoi(number); err != nil {
+		return "", "", false
+	}
+	return service, port, true
+}
+
 // portSpecToBindings converts publish spec strings (e.g. "8080:3000") into
 // driver.PortBinding values for display purposes. Specs that cannot be parsed
 // as simple integer ports (e.g. range specs like "8000-8010:8000-8010") are
@@ -185,20 +601,21 @@ func collectPorts(forwardPorts, appPort config.StrIntArray) []string {
 func portSpecToBindings(specs []string) []driver.PortBinding {
 	var result []driver.PortBinding
 	for _, spec := range specs {
-		host, container, _ := strings.Cut(spec, ":")
+		ports, proto := splitPortProtocol(spec)
+		host, container, _ := strings.Cut(ports, ":")
 		hostPort, errH := strconv.Atoi(host)
 		containerPort, errC := strconv.Atoi(container)
 		if errH != nil || errC != nil {
 			result = append(result, driver.PortBinding{
-				RawSpec:  spec,
-				Protocol: "tcp",
+				RawSpec:  ports,
+				Protocol: proto,
 			})
 			continue
 		}
 		result = append(result, driver.PortBinding{
 			HostPort:      hostPort,
 			ContainerPort: containerPort,
-			Protocol:      "tcp",
+			Protocol:      proto,
 		})
 	}
 	return result
@@ -254,45 +671,97 @@ func (e *Engine) dispatchPlugins(ctx context.Context, ws *workspace.Workspace, c
 	return resp, nil
 }
 
-// execPluginCopies copies staged files into the container via exec.
-// All values are shell-escaped before embedding in single-quoted arguments.
+// execPluginCopies copies staged files into the container. Each file goes
+// in with the runtime's native cp (see pluginCopyNative), falling back to
+// streaming it over an exec when the runtime has no cp command or the native
+// copy fails (e.g. a target on a tmpfs, which cp cannot see). A missing
+// source or a failed copy is logged and the remaining copies still run.
 func (e *Engine) execPluginCopies(ctx context.Context, cc containerContext, copies []plugin.FileCopy) {
+	native := true
 	for _, cp := range copies {
-		data, err := os.ReadFile(cp.Source)
-		if err != nil {
+		if _, err := os.Stat(cp.Source); err != nil {
 			e.logger.Warn("plugin copy: failed to read source", "source", cp.Source, "error", err)
 			continue
 		}
 
-		// Build a shell command that creates the parent dir and writes the file.
-		// Values are shell-escaped and single-quoted to handle paths with
-		// spaces, special chars, or single quotes.
-		dir := plugin.ShellQuote(filepath.Dir(cp.Target))
-		target := plugin.ShellQuote(cp.Target)
-		writeCmd := fmt.Sprintf("mkdir -p '%s' && cat > '%s'", dir, target)
-		if cp.Mode != "" {
-			writeCmd += fmt.Sprintf(" && chmod '%s' '%s'", plugin.ShellQuote(cp.Mode), target)
-		}
-		if cp.User != "" {
-			owner := plugin.ShellQuote(cp.User)
-			writeCmd += fmt.Sprintf(" && chown '%s:' '%s' '%s'", owner, dir, target)
+		if native {
+			err := e.pluginCopyNative(ctx, cc, cp)
+			if err == nil {
+				continue
+			}
+			if errors.Is(err, driver.ErrCopyUnsupported) {
+				e.logger.Debug("plugin copy: native cp unavailable, falling back to exec", "error", err)
+				native = false
+			} else {
+				e.logger.Debug("plugin copy: native cp failed, retrying over exec", "target", cp.Target, "error", err)
+			}
 		}
 
-		var shellCmd string
-		if cp.IfNotExists {
-			shellCmd = fmt.Sprintf("[ -f '%s' ] || { %s; }", target, writeCmd)
-		} else {
-			shellCmd = writeCmd
+		if err := e.pluginCopyExec(ctx, cc, cp); err != nil {
+			e.logger.Warn("plugin copy: failed", "target", cp.Target, "error", err)
 		}
+	}
+}
 
-		err = e.driver.ExecContainer(ctx, cc.workspaceID, cc.containerID,
-			[]string{"sh", "-c", shellCmd},
-			bytes.NewReader(data), io.Discard, io.Discard, nil, "root")
-		if err != nil {
-			e.logger.Warn("plugin copy: exec failed, skipping remaining copies", "target", cp.Target, "error", err)
-			return
+// pluginCopyNative copies cp.Source with the runtime's cp to a temporary file
+// next to the target, then moves it into place as root, applying cp's mode
+// and owner and honoring IfNotExists. Staging in the target's directory
+// rather than /tmp keeps the copy off a tmpfs mounted there, which cp writes
+// underneath where the file cannot be seen.
+func (e *Engine) pluginCopyNative(ctx context.Context, cc containerContext, cp plugin.FileCopy) error {
+	var suffix [8]byte
+	_, _ = rand.Read(suffix[:])
+	dir := filepath.Dir(cp.Target)
+	tmp := filepath.Join(dir, ".crib-copy-"+hex.EncodeToString(suffix[:]))
+
+	err := e.driver.CopyToContainer(ctx, cc.workspaceID, cc.containerID, cp.Source, tmp)
+	if err != nil && !errors.Is(err, driver.ErrCopyUnsupported) {
+		// The target's directory may not exist yet.
+		mkdir := []string{"mkdir", "-p", dir}
+		if e.driver.ExecContainer(ctx, cc.workspaceID, cc.containerID, mkdir, nil, io.Discard, io.Discard, nil, "root", "") == nil {
+			err = e.driver.CopyToContainer(ctx, cc.workspaceID, cc.containerID, cp.Source, tmp)
 		}
 	}
+	if err != nil {
+		return err
+	}
+	shellCmd := fmt.Sprintf("trap \"rm -f '%s'\" EXIT; %s", tmp, pluginCopyShellCmd(cp, fmt.Sprintf("mv -f '%s'", tmp)))
+	return e.driver.ExecContainer(ctx, cc.workspaceID, cc.containerID,
+		[]string{"sh", "-c", shellCmd}, nil, io.Discard, io.Discard, nil, "root", "")
+}
+
+// pluginCopyExec writes cp.Source into the container by piping it to cat
+// over an exec. Used when the runtime has no native cp.
+func (e *Engine) pluginCopyExec(ctx context.Context, cc containerContext, cp plugin.FileCopy) error {
+	data, err := os.ReadFile(cp.Source)
+	if err != nil {
+		return err
+	}
+	return e.driver.ExecContainer(ctx, cc.workspaceID, cc.containerID,
+		[]string{"sh", "-c", pluginCopyShellCmd(cp, "cat >")},
+		bytes.NewReader(data), io.Discard, io.Discard, nil, "root", "")
+}
+
+// pluginCopyShellCmd returns the shell command that creates cp's parent
+// directory, writes the target with place (e.g. "cat >" or "mv -f 'tmp'")
+// and applies its mode and owner, guarded by an existence check for
+// IfNotExists copies. Values are shell-escaped and single-quoted to handle
+// paths with spaces, special chars, or single quotes.
+func pluginCopyShellCmd(cp plugin.FileCopy, place string) string {
+	dir := plugin.ShellQuote(filepath.Dir(cp.Target))
+	target := plugin.ShellQuote(cp.Target)
+	writeCmd := fmt.Sprintf("mkdir -p '%s' && %s '%s'", dir, place, target)
+	if cp.Mode != "" {
+		writeCmd += fmt.Sprintf(" && chmod '%s' '%s'", plugin.ShellQuote(cp.Mode), target)
+	}
+	if cp.User != "" {
+		owner := plugin.ShellQuote(cp.User)
+		writeCmd += fmt.Sprintf(" && chown '%s:' '%s' '%s'", owner, dir, target)
+	}
+	if cp.IfNotExists {
+		return fmt.Sprintf("[ -f '%s' ] || { %s; }", target, writeCmd)
+	}
+	return writeCmd
 }
 
 // extractCribCustomizations returns the customizations.crib map from a
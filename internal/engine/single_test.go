@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 
@@ -61,6 +63,31 @@ func TestBuildRunOptions_OverrideCommandFalse(t *testing.T) {
 	}
 }
 
+func TestBuildRunOptions_RelativeMountSources(t *testing.T) {
+	e := &Engine{}
+	cfg := &config.DevContainerConfig{}
+	cfg.Mounts = []config.Mount{
+		{Type: "bind", Source: "./cache", Target: "/cache"},
+		{Type: "bind", Source: "/abs/data", Target: "/data"},
+		{Type: "volume", Source: "node-modules", Target: "/node_modules"},
+	}
+
+	opts, err := e.buildRunOptions(cfg, "alpine:3.20", "/project", "/workspaces/project", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"/project/cache", "/abs/data", "node-modules"}
+	for i, m := range opts.Mounts {
+		if m.Source != want[i] {
+			t.Errorf("Mounts[%d].Source = %q, want %q", i, m.Source, want[i])
+		}
+	}
+	if cfg.Mounts[0].Source != "./cache" {
+		t.Errorf("config mounts were mutated: %q", cfg.Mounts[0].Source)
+	}
+}
+
 func TestBuildRunOptions_WithContainerUser(t *testing.T) {
 	e := &Engine{}
 	cfg := &config.DevContainerConfig{}
@@ -105,6 +132,45 @@ func TestBuildRunOptions_WithSecurityOpts(t *testing.T) {
 	}
 }
 
+func TestBuildRunOptions_CapDrop(t *testing.T) {
+	e := &Engine{}
+	if err := e.SetCapDrop([]string{"MKNOD"}); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.DevContainerConfig{}
+	cfg.CapDrop = []string{"NET_RAW"}
+
+	opts, err := e.buildRunOptions(cfg, "alpine:3.20", "/project", "/workspaces/project", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	applyFeatureMetadata(opts, []*config.ImageMetadata{
+		{NonComposeBase: config.NonComposeBase{CapDrop: []string{"SYS_CHROOT"}}},
+	}, nil)
+
+	want := []string{"NET_RAW", "MKNOD", "SYS_CHROOT"}
+	if !slices.Equal(opts.CapDrop, want) {
+		t.Errorf("CapDrop = %v, want %v", opts.CapDrop, want)
+	}
+	if !slices.Equal(cfg.CapDrop, []string{"NET_RAW"}) {
+		t.Errorf("config capDrop modified: %v", cfg.CapDrop)
+	}
+}
+
+func TestSetCapDrop_Invalid(t *testing.T) {
+	e := &Engine{}
+	for _, c := range []string{"", "NET-RAW", "cap net_raw", "SYS_PTRACE=1"} {
+		if err := e.SetCapDrop([]string{c}); err == nil {
+			t.Errorf("SetCapDrop(%q): expected error", c)
+		}
+	}
+	for _, c := range []string{"ALL", "net_raw", "CAP_SYS_ADMIN"} {
+		if err := e.SetCapDrop([]string{c}); err != nil {
+			t.Errorf("SetCapDrop(%q): %v", c, err)
+		}
+	}
+}
+
 func TestBuildRunOptions_CustomWorkspaceMount(t *testing.T) {
 	e := &Engine{}
 	cfg := &config.DevContainerConfig{}
@@ -123,18 +189,53 @@ func TestBuildRunOptions_CustomWorkspaceMount(t *testing.T) {
 	}
 }
 
-func TestBuildRunOptions_ContainerEnv(t *testing.T) {
+func TestBuildRunOptions_ReadonlyWorkspace(t *testing.T) {
 	e := &Engine{}
-	cfg := &config.DevContainerConfig{}
-	cfg.ContainerEnv = map[string]string{"FOO": "bar"}
 
-	opts, err := e.buildRunOptions(cfg, "alpine:3.20", "/project", "/workspaces/project", false)
+	custom := &config.DevContainerConfig{}
+	custom.Customizations = map[string]any{"crib": map[string]any{"readonlyWorkspace": true}}
+	mount := &config.DevContainerConfig{}
+	mount.WorkspaceMount = "type=bind,src=/custom/src,dst=/custom/dst,readonly"
+
+	for name, cfg := range map[string]*config.DevContainerConfig{"customization": custom, "workspaceMount": mount} {
+		t.Run(name, func(t *testing.T) {
+			opts, err := e.buildRunOptions(cfg, "alpine:3.20", "/project", "/workspaces/project", false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !opts.WorkspaceMount.ReadOnly {
+				t.Errorf("WorkspaceMount = %+v, want read-only", opts.WorkspaceMount)
+			}
+			if !strings.HasSuffix(opts.WorkspaceMount.String(), ",readonly") {
+				t.Errorf("mount spec = %q, want readonly option", opts.WorkspaceMount.String())
+			}
+		})
+	}
+
+	opts, err := e.buildRunOptions(&config.DevContainerConfig{}, "alpine:3.20", "/project", "/workspaces/project", false)
 	if err != nil {
 		t.Fatal(err)
 	}
+	if opts.WorkspaceMount.ReadOnly {
+		t.Error("default workspace mount should be writable")
+	}
+}
 
-	if len(opts.Env) != 1 || opts.Env[0] != "FOO=bar" {
-		t.Errorf("Env = %v, want [FOO=bar]", opts.Env)
+func TestBuildRunOptions_ContainerEnv(t *testing.T) {
+	e := &Engine{}
+	cfg := &config.DevContainerConfig{}
+	cfg.ContainerEnv = map[string]string{"FOO": "bar", "ZED": "1", "APP_ENV": "dev", "MID": "x=y"}
+	want := []string{"APP_ENV=dev", "FOO=bar", "MID=x=y", "ZED=1"}
+
+	// Repeat to catch map iteration order leaking into the run args.
+	for range 20 {
+		opts, err := e.buildRunOptions(cfg, "alpine:3.20", "/project", "/workspaces/project", false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(opts.Env, want) {
+			t.Fatalf("Env = %v, want %v", opts.Env, want)
+		}
 	}
 }
 
@@ -162,6 +263,143 @@ func TestBuildRunOptions_RunArgsPassthrough(t *testing.T) {
 	}
 }
 
+func TestBuildRunOptions_ResourceLimits(t *testing.T) {
+	cfg := &config.DevContainerConfig{}
+	cfg.HostRequirements = &config.HostRequirements{CPUs: 4, Memory: "8gb"}
+
+	opts, err := (&Engine{}).buildRunOptions(cfg, "alpine:3.20", "/project", "/workspaces/project", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.CPUs != "" || opts.Memory != "" {
+		t.Errorf("limits applied without --resource-limits: cpus=%q memory=%q", opts.CPUs, opts.Memory)
+	}
+
+	opts, err = (&Engine{resourceLimits: true}).buildRunOptions(cfg, "alpine:3.20", "/project", "/workspaces/project", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.CPUs != "4" {
+		t.Errorf("CPUs = %q, want 4", opts.CPUs)
+	}
+	if opts.Memory != "8589934592" {
+		t.Errorf("Memory = %q, want 8589934592", opts.Memory)
+	}
+
+	cfg.HostRequirements.Memory = "lots"
+	if _, err := (&Engine{resourceLimits: true}).buildRunOptions(cfg, "alpine:3.20", "/project", "/workspaces/project", false); err == nil {
+		t.Error("expected error for invalid hostRequirements.memory")
+	}
+}
+
+func TestBuildRunOptions_CPUSet(t *testing.T) {
+	e := &Engine{}
+	if err := e.SetCPUSet("0-3"); err != nil {
+		t.Fatal(err)
+	}
+	opts, err := e.buildRunOptions(&config.DevContainerConfig{}, "alpine:3.20", "/project", "/workspaces/project", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.CPUSet != "0-3" {
+		t.Errorf("CPUSet = %q, want 0-3", opts.CPUSet)
+	}
+}
+
+func TestSetCPUSet(t *testing.T) {
+	for _, valid := range []string{"", "0", "0-3", "0,2", "0-1,4-5,7", "3-3"} {
+		e := &Engine{}
+		if err := e.SetCPUSet(valid); err != nil {
+			t.Errorf("SetCPUSet(%q): %v", valid, err)
+		}
+		if e.cpuset != valid {
+			t.Errorf("cpuset = %q, want %q", e.cpuset, valid)
+		}
+	}
+	for _, invalid := range []string{"all", "-1", "1-", "3-1", "0,,2", "0, 1", "1.5", "0-3,"} {
+		e := &Engine{cpuset: "0"}
+		if err := e.SetCPUSet(invalid); err == nil || !strings.Contains(err.Error(), "invalid cpuset") {
+			t.Errorf("SetCPUSet(%q) = %v, want invalid cpuset error", invalid, err)
+		}
+		if e.cpuset != "0" {
+			t.Errorf("SetCPUSet(%q) changed cpuset to %q", invalid, e.cpuset)
+		}
+	}
+}
+
+func TestParseMemorySize(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int64
+		wantErr bool
+	}{
+		{"8gb", 8 << 30, false},
+		{"8GB", 8 << 30, false},
+		{"512mb", 512 << 20, false},
+		{"1.5gb", 3 << 29, false},
+		{"4g", 4 << 30, false},
+		{"2 tb", 2 << 40, false},
+		{"64kb", 64 << 10, false},
+		{"1024", 1024, false},
+		{"", 0, true},
+		{"gb", 0, true},
+		{"8pb", 0, true},
+		{"0gb", 0, true},
+		{"-1gb", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := parseMemorySize(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseMemorySize(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseMemorySize(%q) = %d, want %d", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildRunOptions_RunArgsSubstituted(t *testing.T) {
+	t.Setenv("CRIB_TEST_CACHE", "/var/cache/crib")
+
+	src := t.TempDir()
+	devcontainerDir := filepath.Join(src, ".devcontainer")
+	if err := os.MkdirAll(devcontainerDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	configContent := `{
+		"image": "alpine:3.20",
+		"runArgs": [
+			"--volume", "${localWorkspaceFolder}/cache:/cache",
+			"--volume=${localEnv:CRIB_TEST_CACHE}:/shared"
+		]
+	}`
+	if err := os.WriteFile(filepath.Join(devcontainerDir, "devcontainer.json"), []byte(configContent), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	e := &Engine{}
+	ws := &workspace.Workspace{ID: "ws1", Source: src, DevContainerPath: ".devcontainer/devcontainer.json"}
+	cfg, workspaceFolder, err := e.parseAndSubstitute(ws)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts, err := e.buildRunOptions(cfg, "alpine:3.20", src, workspaceFolder, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"--volume", src + "/cache:/cache",
+		"--volume=/var/cache/crib:/shared",
+	}
+	if !slices.Equal(opts.ExtraArgs, want) {
+		t.Errorf("ExtraArgs = %v, want %v", opts.ExtraArgs, want)
+	}
+}
+
 func TestBuildRunOptions_NoRunArgs(t *testing.T) {
 	e := &Engine{}
 	cfg := &config.DevContainerConfig{}
@@ -258,6 +496,48 @@ func TestBuildRunOptions_FeatureEntrypoints(t *testing.T) {
 	}
 }
 
+func TestBuildRunOptions_CustomKeepAlive(t *testing.T) {
+	e := &Engine{}
+	cfg := &config.DevContainerConfig{}
+	cfg.Customizations = map[string]any{
+		"crib": map[string]any{"keepAlive": []any{"tail", "-f", "/dev/null"}},
+	}
+
+	opts, err := e.buildRunOptions(cfg, "distroless", "/project", "/workspaces/project", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.Entrypoint != "tail" {
+		t.Errorf("Entrypoint = %q, want tail", opts.Entrypoint)
+	}
+	if want := []string{"-f", "/dev/null"}; !slices.Equal(opts.Cmd, want) {
+		t.Errorf("Cmd = %v, want %v", opts.Cmd, want)
+	}
+
+	// Feature entrypoints keep ENTRYPOINT; the whole keep-alive becomes CMD.
+	opts, err = e.buildRunOptions(cfg, "distroless", "/project", "/workspaces/project", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.Entrypoint != "" {
+		t.Errorf("Entrypoint = %q, want empty", opts.Entrypoint)
+	}
+	if want := []string{"tail", "-f", "/dev/null"}; !slices.Equal(opts.Cmd, want) {
+		t.Errorf("Cmd = %v, want %v", opts.Cmd, want)
+	}
+}
+
+func TestBuildRunOptions_InvalidKeepAlive(t *testing.T) {
+	e := &Engine{}
+	for _, v := range []any{"tail -f /dev/null", []any{}, []any{"tail", 1}} {
+		cfg := &config.DevContainerConfig{}
+		cfg.Customizations = map[string]any{"crib": map[string]any{"keepAlive": v}}
+		if _, err := e.buildRunOptions(cfg, "img", "/project", "/workspaces/project", false); err == nil {
+			t.Errorf("keepAlive %v: expected error", v)
+		}
+	}
+}
+
 func TestApplyFeatureMetadata(t *testing.T) {
 	opts := &driver.RunOptions{}
 	metadata := []*config.ImageMetadata{
@@ -369,6 +649,79 @@ func TestCollectPorts_RangeWithHost(t *testing.T) {
 	}
 }
 
+func TestCollectPorts_Protocol(t *testing.T) {
+	got := collectPorts(
+		config.StrIntArray{"53/udp", "8080/tcp"},
+		config.StrIntArray{"5353:53/UDP"},
+	)
+	want := []string{"53:53/udp", "8080:8080", "5353:53/udp"}
+	if !slices.Equal(got, want) {
+		t.Errorf("got = %v, want %v", got, want)
+	}
+}
+
+func TestCollectPorts_DedupProtocol(t *testing.T) {
+	// tcp and udp of the same port are distinct bindings; an explicit "/tcp"
+	// is the same as no suffix.
+	got := collectPorts(
+		config.StrIntArray{"53", "53/udp"},
+		config.StrIntArray{"53:53/tcp", "53:53/udp"},
+	)
+	want := []string{"53:53", "53:53/udp"}
+	if !slices.Equal(got, want) {
+		t.Errorf("got = %v, want %v", got, want)
+	}
+}
+
+func TestSplitServicePort(t *testing.T) {
+	tests := []struct {
+		spec    string
+		service string
+		port    string
+		ok      bool
+	}{
+		{"db:5432", "db", "5432", true},
+		{"dns:53/udp", "dns", "53/udp", true},
+		{"8080", "", "", false},
+		{"8080:80", "", "", false},
+		{"127.0.0.1:8080:80", "", "", false},
+		{"localhost:3000", "", "", false},
+		{"db:postgres", "", "", false},
+	}
+	for _, tt := range tests {
+		service, port, ok := splitServicePort(tt.spec)
+		if service != tt.service || port != tt.port || ok != tt.ok {
+			t.Errorf("splitServicePort(%q) = (%q, %q, %v), want (%q, %q, %v)",
+				tt.spec, service, port, ok, tt.service, tt.port, tt.ok)
+		}
+	}
+}
+
+func TestCollectPorts_ServiceAndLocalhost(t *testing.T) {
+	// "service:port" entries are compose-only and must not become an invalid
+	// --publish; "localhost:port" is the dev container's own port.
+	got := collectPorts(config.StrIntArray{"db:5432", "localhost:3000", "8080"}, nil)
+	want := []string{"3000:3000", "8080:8080"}
+	if !slices.Equal(got, want) {
+		t.Errorf("got = %v, want %v", got, want)
+	}
+}
+
+func TestBuildRunOptions_UDPAppPort(t *testing.T) {
+	e := &Engine{}
+	cfg := &config.DevContainerConfig{}
+	cfg.AppPort = config.StrIntArray{"53:53/udp", "8080"}
+
+	opts, err := e.buildRunOptions(cfg, "alpine:3.20", "/project", "/workspaces/project", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"53:53/udp", "8080:8080"}
+	if !slices.Equal(opts.Ports, want) {
+		t.Errorf("Ports = %v, want %v", opts.Ports, want)
+	}
+}
+
 func TestPortSpecToBindings(t *testing.T) {
 	specs := []string{"8080:80", "9090:3000"}
 	got := portSpecToBindings(specs)
@@ -383,6 +736,22 @@ func TestPortSpecToBindings(t *testing.T) {
 	}
 }
 
+func TestPortSpecToBindings_Protocol(t *testing.T) {
+	got := portSpecToBindings([]string{"53:53/udp", "8080:80", "7000-7010:7000-7010/udp"})
+	if len(got) != 3 {
+		t.Fatalf("len = %d, want 3", len(got))
+	}
+	if got[0].HostPort != 53 || got[0].ContainerPort != 53 || got[0].Protocol != "udp" {
+		t.Errorf("got[0] = %+v", got[0])
+	}
+	if got[1].Protocol != "tcp" {
+		t.Errorf("got[1].Protocol = %q, want tcp", got[1].Protocol)
+	}
+	if got[2].RawSpec != "7000-7010:7000-7010" || got[2].Protocol != "udp" {
+		t.Errorf("got[2] = %+v", got[2])
+	}
+}
+
 func TestPortSpecToBindings_Empty(t *testing.T) {
 	got := portSpecToBindings(nil)
 	if len(got) != 0 {
@@ -526,6 +895,93 @@ func TestWorkspaceFolderVariableExpansion(t *testing.T) {
 	}
 }
 
+func TestSetWorkspaceFolder(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{"", "", false},
+		{"/src", "/src", false},
+		{"/workspaces/app/", "/workspaces/app", false},
+		{"src", "", true},
+		{"./src", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			e := &Engine{}
+			err := e.SetWorkspaceFolder(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SetWorkspaceFolder(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if e.workspaceFolder != tt.want {
+				t.Errorf("workspaceFolder = %q, want %q", e.workspaceFolder, tt.want)
+			}
+		})
+	}
+}
+
+func TestWorkspaceFolderOverride(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    string
+		wantMount config.Mount
+	}{
+		{
+			name:   "default mount",
+			config: `{"image": "alpine:3.20", "workspaceFolder": "/workspaces/app", "containerEnv": {"APP_DIR": "${containerWorkspaceFolder}", "APP_NAME": "${containerWorkspaceFolderBasename}"}}`,
+			wantMount: config.Mount{
+				Type: "bind", Source: "SRC", Target: "/src/override",
+			},
+		},
+		{
+			name:   "custom workspaceMount",
+			config: `{"image": "alpine:3.20", "workspaceMount": "source=${localWorkspaceFolder},target=/workspaces/app,type=bind,readonly", "workspaceFolder": "/workspaces/app", "containerEnv": {"APP_DIR": "${containerWorkspaceFolder}", "APP_NAME": "${containerWorkspaceFolderBasename}"}}`,
+			wantMount: config.Mount{
+				Type: "bind", Source: "SRC", Target: "/src/override", ReadOnly: true,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := t.TempDir()
+			devcontainerDir := filepath.Join(src, ".devcontainer")
+			if err := os.MkdirAll(devcontainerDir, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(devcontainerDir, "devcontainer.json"), []byte(tt.config), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			e := &Engine{}
+			if err := e.SetWorkspaceFolder("/src/override"); err != nil {
+				t.Fatal(err)
+			}
+			ws := &workspace.Workspace{ID: "ws1", Source: src, DevContainerPath: ".devcontainer/devcontainer.json"}
+			cfg, workspaceFolder, err := e.parseAndSubstitute(ws)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if workspaceFolder != "/src/override" {
+				t.Errorf("workspaceFolder = %q, want /src/override", workspaceFolder)
+			}
+			if cfg.ContainerEnv["APP_DIR"] != "/src/override" || cfg.ContainerEnv["APP_NAME"] != "override" {
+				t.Errorf("containerEnv = %v, want ${containerWorkspaceFolder} substituted with the override", cfg.ContainerEnv)
+			}
+
+			opts, err := e.buildRunOptions(cfg, "alpine:3.20", src, workspaceFolder, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := tt.wantMount
+			want.Source = src
+			if opts.WorkspaceMount != want {
+				t.Errorf("WorkspaceMount = %+v, want %+v", opts.WorkspaceMount, want)
+			}
+		})
+	}
+}
+
 func TestDetectContainerUser_NonRoot(t *testing.T) {
 	mockDrv := &mockDriver{
 		responses: map[string]string{
@@ -866,6 +1322,72 @@ func TestUpExisting_StoppedContainer_UsesResumePath(t *testing.T) {
 	}
 }
 
+func TestUpExisting_PostStartOnlyWhenStarted(t *testing.T) {
+	tests := []struct {
+		status        string
+		wantPostStart bool
+	}{
+		{"exited", true},
+		{"running", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.status, func(t *testing.T) {
+			store := workspace.NewStoreAt(t.TempDir())
+			ws := &workspace.Workspace{ID: "ws-poststart", Source: "/home/user/project"}
+			if err := store.Save(ws); err != nil {
+				t.Fatal(err)
+			}
+			if err := store.SaveResult(ws.ID, &workspace.Result{
+				ImageName:   "ruby:3.2",
+				ContainerID: "c-1",
+				RemoteUser:  "vscode",
+				RemoteEnv:   map[string]string{"PATH": "/usr/local/bin:/usr/bin"},
+			}); err != nil {
+				t.Fatal(err)
+			}
+
+			container := &driver.ContainerDetails{
+				ID:    "c-1",
+				State: driver.ContainerState{Status: tt.status},
+			}
+			drv := &fixedFindContainerDriver{container: container}
+			eng := &Engine{
+				driver:      drv,
+				store:       store,
+				runtimeName: "docker",
+				logger:      slog.Default(),
+				stdout:      io.Discard,
+				stderr:      io.Discard,
+				progress:    func(ProgressEvent) {},
+			}
+
+			cfg := &config.DevContainerConfig{}
+			cfg.Image = "ruby:3.2"
+			cfg.RemoteUser = "vscode"
+			cfg.PostStartCommand = config.LifecycleHook{"": {"echo postStart"}}
+			cfg.PostAttachCommand = config.LifecycleHook{"": {"echo postAttach"}}
+
+			b := eng.newBackend(ws, cfg, "/workspaces/project")
+			if _, err := eng.upExisting(context.Background(), ws, cfg, "/workspaces/project", b, container); err != nil {
+				t.Fatalf("upExisting: %v", err)
+			}
+
+			var ranPostStart, ranPostAttach bool
+			for _, call := range drv.execCalls {
+				cmdStr := strings.Join(call.cmd, " ")
+				ranPostStart = ranPostStart || strings.Contains(cmdStr, "echo postStart")
+				ranPostAttach = ranPostAttach || strings.Contains(cmdStr, "echo postAttach")
+			}
+			if ranPostStart != tt.wantPostStart {
+				t.Errorf("postStartCommand ran = %v, want %v", ranPostStart, tt.wantPostStart)
+			}
+			if !ranPostAttach {
+				t.Error("postAttachCommand should run on every up")
+			}
+		})
+	}
+}
+
 // postCreateTrackingPlugin tracks whether PostContainerCreate was called.
 type postCreateTrackingPlugin struct {
 	plugin.BasePlugin
@@ -901,3 +1423,191 @@ func TestConfigRemoteUser(t *testing.T) {
 		}
 	}
 }
+
+func TestBuildRunOptions_ExtraHosts(t *testing.T) {
+	origOS := hostOS
+	t.Cleanup(func() { hostOS = origOS })
+
+	newCfg := func(runArgs ...string) *config.DevContainerConfig {
+		cfg := &config.DevContainerConfig{}
+		cfg.Customizations = map[string]any{"crib": map[string]any{
+			"extraHosts":         []any{"api.local:10.0.0.5", "v6.local:::1"},
+			"hostDockerInternal": true,
+		}}
+		cfg.RunArgs = runArgs
+		return cfg
+	}
+
+	tests := []struct {
+		name    string
+		runtime string
+		os      string
+		cli     []string
+		runArgs []string
+		want    []string
+	}{
+		{
+			"config and cli", "podman", "linux", []string{"db.local:10.0.0.6"}, nil,
+			[]string{"api.local:10.0.0.5", "v6.local:::1", "db.local:10.0.0.6"},
+		},
+		{
+			"docker on linux maps host.docker.internal", "docker", "linux", nil, nil,
+			[]string{"api.local:10.0.0.5", "v6.local:::1", "host.docker.internal:host-gateway"},
+		},
+		{
+			"docker on macos", "docker", "darwin", nil, nil,
+			[]string{"api.local:10.0.0.5", "v6.local:::1"},
+		},
+		{
+			"user maps host.docker.internal", "docker", "linux", []string{"host.docker.internal:172.17.0.1"}, nil,
+			[]string{"api.local:10.0.0.5", "v6.local:::1", "host.docker.internal:172.17.0.1"},
+		},
+		{
+			"runArgs map host.docker.internal", "docker", "linux", nil, []string{"--add-host=host.docker.internal:172.17.0.1"},
+			[]string{"api.local:10.0.0.5", "v6.local:::1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hostOS = tt.os
+			e := &Engine{runtimeName: tt.runtime}
+			if err := e.SetExtraHosts(tt.cli); err != nil {
+				t.Fatal(err)
+			}
+			opts, err := e.buildRunOptions(newCfg(tt.runArgs...), "alpine:3.20", "/project", "/workspaces/project", false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(opts.ExtraHosts, tt.want) {
+				t.Errorf("ExtraHosts = %v, want %v", opts.ExtraHosts, tt.want)
+			}
+		})
+	}
+
+	// Without the option nothing is added, even with Docker on Linux.
+	hostOS = "linux"
+	cfg := newCfg()
+	delete(cfg.Customizations["crib"].(map[string]any), "hostDockerInternal")
+	opts, err := (&Engine{runtimeName: "docker"}).buildRunOptions(cfg, "alpine:3.20", "/project", "/workspaces/project", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"api.local:10.0.0.5", "v6.local:::1"}; !slices.Equal(opts.ExtraHosts, want) {
+		t.Errorf("ExtraHosts without hostDockerInternal = %v, want %v", opts.ExtraHosts, want)
+	}
+}
+
+func TestBuildRunOptions_Tmpfs(t *testing.T) {
+	cfg := &config.DevContainerConfig{}
+	cfg.Customizations = map[string]any{"crib": map[string]any{
+		"tmpfs": []any{"/tmp:size=512m", "/run"},
+	}}
+
+	e := &Engine{}
+	if err := e.SetTmpfs([]string{"/tmp:size=1g", "/scratch:size=64m,mode=1777"}); err != nil {
+		t.Fatal(err)
+	}
+	opts, err := e.buildRunOptions(cfg, "alpine:3.20", "/project", "/workspaces/project", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// --tmpfs /tmp replaces the config's entry for the same path.
+	want := []string{"/run", "/tmp:size=1g", "/scratch:size=64m,mode=1777"}
+	if !slices.Equal(opts.Tmpfs, want) {
+		t.Errorf("Tmpfs = %v, want %v", opts.Tmpfs, want)
+	}
+}
+
+func TestTmpfs_Invalid(t *testing.T) {
+	e := &Engine{}
+	for _, s := range []string{"tmp", "", "/tmp:", "relative/path:size=1g"} {
+		if err := e.SetTmpfs([]string{s}); err == nil {
+			t.Errorf("SetTmpfs(%q): expected error", s)
+		}
+	}
+
+	for _, raw := range []any{"/tmp", []any{42}, []any{"tmp"}} {
+		cfg := &config.DevContainerConfig{}
+		cfg.Customizations = map[string]any{"crib": map[string]any{"tmpfs": raw}}
+		if _, err := e.buildRunOptions(cfg, "alpine:3.20", "/project", "/workspaces/project", false); err == nil {
+			t.Errorf("tmpfs %v: expected error", raw)
+		}
+	}
+}
+
+func TestBuildRunOptions_Network(t *testing.T) {
+	tests := []struct {
+		name    string
+		custom  any
+		cli     string
+		want    string
+		wantErr bool
+	}{
+		{"unset", nil, "", "", false},
+		{"config", "shared", "", "shared", false},
+		{"cli overrides config", "shared", "other", "other", false},
+		{"not a string", 42, "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.DevContainerConfig{}
+			if tt.custom != nil {
+				cfg.Customizations = map[string]any{"crib": map[string]any{"network": tt.custom}}
+			}
+			e := &Engine{}
+			e.SetNetwork(tt.cli)
+			opts, err := e.buildRunOptions(cfg, "alpine:3.20", "/project", "/workspaces/project", false)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && opts.Network != tt.want {
+				t.Errorf("Network = %q, want %q", opts.Network, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtraHosts_Invalid(t *testing.T) {
+	e := &Engine{}
+	for _, h := range []string{"api.local", ":10.0.0.5", "api.local:not-an-ip", "api.local:"} {
+		if err := e.SetExtraHosts([]string{h}); err == nil {
+			t.Errorf("SetExtraHosts(%q): expected error", h)
+		}
+	}
+
+	cfg := &config.DevContainerConfig{}
+	cfg.Customizations = map[string]any{"crib": map[string]any{"extraHosts": "api.local:10.0.0.5"}}
+	if _, err := e.buildRunOptions(cfg, "alpine:3.20", "/project", "/workspaces/project", false); err == nil {
+		t.Error("expected error for non-array extraHosts")
+	}
+}
+
+func TestGPURequest(t *testing.T) {
+	tests := []struct {
+		name string
+		flag string
+		gpu  any
+		want string
+	}{
+		{"none requested", "", nil, ""},
+		{"required", "", true, "all"},
+		{"required with specs", "", map[string]any{"cores": float64(1000)}, "all"},
+		{"optional", "", "optional", ""},
+		{"not required", "", false, ""},
+		{"flag", "device=0", nil, "device=0"},
+		{"flag overrides requirement", "device=1", true, "device=1"},
+		{"flag none disables requirement", "none", true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &Engine{gpus: tt.flag}
+			cfg := &config.DevContainerConfig{}
+			if tt.gpu != nil {
+				cfg.HostRequirements = &config.HostRequirements{GPU: tt.gpu}
+			}
+			if got := e.gpuRequest(cfg); got != tt.want {
+				t.Errorf("gpuRequest = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
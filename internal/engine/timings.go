@@ -0,0 +1,63 @@
+package engine
+
+import (
+	"sync"
+	"time"
+)
+
+// StepTiming records how long one step of Up took.
+type StepTiming struct {
+	// Name identifies the step, e.g. "build", "create" or a lifecycle hook
+	// name such as "postCreateCommand".
+	Name string
+
+	Duration time.Duration
+}
+
+// timingCollector records the duration of each step of an Up in the order
+// the steps finish. Each step is also reported as a PhaseTimings progress
+// event. A nil collector records nothing, so code shared with commands that
+// do not collect timings (e.g. Restart) can call it unconditionally.
+type timingCollector struct {
+	mu       sync.Mutex
+	steps    []StepTiming
+	progress func(ProgressEvent)
+	now      func() time.Time // replaced in tests
+}
+
+func newTimingCollector(progress func(ProgressEvent)) *timingCollector {
+	return &timingCollector{progress: progress, now: time.Now}
+}
+
+// start begins timing the step name. Calling the returned function records
+// it.
+func (c *timingCollector) start(name string) func() {
+	if c == nil {
+		return func() {}
+	}
+	started := c.now()
+	return func() { c.record(name, c.now().Sub(started)) }
+}
+
+// record adds a finished step.
+func (c *timingCollector) record(name string, d time.Duration) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	c.steps = append(c.steps, StepTiming{Name: name, Duration: d})
+	c.mu.Unlock()
+	if c.progress != nil {
+		c.progress(ProgressEvent{Phase: PhaseTimings, Message: name, Duration: d})
+	}
+}
+
+// list returns a copy of the steps recorded so far.
+func (c *timingCollector) list() []StepTiming {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]StepTiming(nil), c.steps...)
+}
@@ -0,0 +1,94 @@
+package engine
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestTimingCollector(t *testing.T) {
+	var events []ProgressEvent
+	c := newTimingCollector(func(ev ProgressEvent) { events = append(events, ev) })
+	clock := time.Unix(0, 0)
+	c.now = func() time.Time { return clock }
+
+	stopBuild := c.start("build")
+	clock = clock.Add(3 * time.Second)
+	stopBuild()
+	stopCreate := c.start("create")
+	clock = clock.Add(500 * time.Millisecond)
+	stopCreate()
+
+	want := []StepTiming{{"build", 3 * time.Second}, {"create", 500 * time.Millisecond}}
+	if got := c.list(); !slices.Equal(got, want) {
+		t.Errorf("list() = %v, want %v", got, want)
+	}
+	if len(events) != 2 {
+		t.Fatalf("got %d progress events, want 2", len(events))
+	}
+	if ev := events[0]; ev.Phase != PhaseTimings || ev.Message != "build" || ev.Duration != 3*time.Second {
+		t.Errorf("events[0] = %+v, want timings/build/3s", ev)
+	}
+}
+
+func TestTimingCollector_Nil(t *testing.T) {
+	var c *timingCollector
+	c.start("build")()
+	c.record("create", time.Second)
+	if got := c.list(); got != nil {
+		t.Errorf("list() = %v, want nil", got)
+	}
+}
+
+func TestUp_RecordsTimings(t *testing.T) {
+	drv := &slowUpDriver{}
+	e, ws := newUpTimeoutTestEngine(t, drv, `{
+		"image": "alpine",
+		"initializeCommand": "true",
+		"onCreateCommand": "echo create",
+		"postStartCommand": "echo start"
+	}`)
+	var events []string
+	e.SetProgress(func(ev ProgressEvent) {
+		if ev.Phase == PhaseTimings {
+			events = append(events, ev.Message)
+		}
+	})
+
+	result, err := e.Up(context.Background(), ws, UpOptions{})
+	if err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+
+	var steps []string
+	for _, s := range result.Timings {
+		steps = append(steps, s.Name)
+	}
+	want := []string{
+		"initializeCommand", "build", "create", "env probe",
+		"onCreateCommand", "postStartCommand", "env probe (after hooks)",
+	}
+	if !slices.Equal(steps, want) {
+		t.Errorf("steps = %v, want %v", steps, want)
+	}
+	if !slices.Equal(events, want) {
+		t.Errorf("timing events = %v, want %v", events, want)
+	}
+	if e.timings != nil {
+		t.Error("timings should be cleared after Up")
+	}
+
+	// A second up finds the running container and only runs resume hooks.
+	result, err = e.Up(context.Background(), ws, UpOptions{})
+	if err != nil {
+		t.Fatalf("second Up: %v", err)
+	}
+	steps = steps[:0]
+	for _, s := range result.Timings {
+		steps = append(steps, s.Name)
+	}
+	if slices.Contains(steps, "build") || slices.Contains(steps, "onCreateCommand") {
+		t.Errorf("second up steps = %v, want no build or onCreateCommand", steps)
+	}
+}
@@ -0,0 +1,256 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	composetypes "github.com/compose-spec/compose-go/v2/types"
+	composehelper "github.com/fgrehm/crib/internal/compose"
+	"github.com/fgrehm/crib/internal/config"
+	"github.com/fgrehm/crib/internal/workspace"
+)
+
+// TrustPrompt asks the user whether ws may create a container with the
+// given dangerous settings. It returns false when the user declines.
+type TrustPrompt func(ws *workspace.Workspace, settings []string) (bool, error)
+
+// dangerousCaps are capabilities that let a container escape or take over
+// the host.
+var dangerousCaps = []string{
+	"ALL", "SYS_ADMIN", "SYS_MODULE", "SYS_PTRACE", "SYS_RAWIO",
+	"SYS_BOOT", "DAC_READ_SEARCH", "NET_ADMIN", "BPF",
+}
+
+// SetTrustPrompt installs the callback consulted before a container is
+// created with privileged mode, dangerous capabilities or a container
+// runtime socket mount. Settings the user accepts are recorded on the
+// workspace, so the prompt only comes back when new ones show up. Without a
+// prompt (the default) every setting is allowed.
+func (e *Engine) SetTrustPrompt(fn TrustPrompt) {
+	e.trustPrompt = fn
+}
+
+// CheckTrust parses the workspace config and runs the trust check for its
+// dangerous settings. Callers use it before destructive steps (e.g. removing
+// the old container on rebuild) so that declining leaves the workspace as
+// it was.
+func (e *Engine) CheckTrust(ws *workspace.Workspace) error {
+	cfg, workspaceFolder, err := e.parseAndSubstitute(ws)
+	if err != nil {
+		return err
+	}
+	return e.checkTrust(ws, cfg, workspaceFolder, nil)
+}
+
+// checkTrust prompts for any dangerous setting the workspace has not trusted
+// yet, and records the accepted settings in the store. Settings come from
+// cfg, the feature and image metadata merged into it, the primary compose
+// service's definition and the socket mount from --mount-docker-socket.
+// Metadata is only known once the image is built, so callers check again
+// with it before creating the container; settings accepted earlier are not
+// asked for twice. Settings the existing container was already created with
+// (from the stored result) count as trusted, so workspaces set up before the
+// trust check existed keep working without a prompt.
+func (e *Engine) checkTrust(ws *workspace.Workspace, cfg *config.DevContainerConfig, workspaceFolder string, metadata []*config.ImageMetadata) error {
+	if e.trustPrompt == nil {
+		return nil
+	}
+	var settings dangerSet
+	settings.addConfig(cfg)
+	for _, m := range metadata {
+		if m != nil {
+			settings.addMetadata(m)
+		}
+	}
+	if svc := e.trustComposeService(ws, cfg, workspaceFolder); svc != nil {
+		settings.addComposeService(svc)
+	}
+	if e.dockerSocket != nil {
+		settings.add("mount: " + e.dockerSocket.Source)
+	}
+	var existing dangerSet
+	if stored, err := e.store.LoadResult(ws.ID); err == nil {
+		if storedCfg := storedConfig(stored); storedCfg != nil {
+			existing.addConfig(storedCfg)
+		}
+	}
+	var untrusted, grandfathered []string
+	for _, s := range settings {
+		switch {
+		case slices.Contains(ws.TrustedSettings, s):
+		case slices.Contains(existing, s):
+			grandfathered = append(grandfathered, s)
+		default:
+			untrusted = append(untrusted, s)
+		}
+	}
+	if len(untrusted) == 0 {
+		if len(grandfathered) == 0 {
+			return nil
+		}
+		return e.recordTrust(ws, grandfathered)
+	}
+
+	ok, err := e.trustPrompt(ws, untrusted)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return &ErrUntrusted{WorkspaceID: ws.ID, Settings: untrusted}
+	}
+	return e.recordTrust(ws, append(grandfathered, untrusted...))
+}
+
+// recordTrust adds settings to the workspace's trusted list and saves it.
+func (e *Engine) recordTrust(ws *workspace.Workspace, settings []string) error {
+	ws.TrustedSettings = append(ws.TrustedSettings, settings...)
+	slices.Sort(ws.TrustedSettings)
+	if err := e.store.Save(ws); err != nil {
+		return fmt.Errorf("saving workspace trust: %w", err)
+	}
+	return nil
+}
+
+// trustComposeService loads the primary service's definition from the
+// compose files, or returns nil for non-compose configs and files that do
+// not load (compose itself reports those).
+func (e *Engine) trustComposeService(ws *workspace.Workspace, cfg *config.DevContainerConfig, workspaceFolder string) *composetypes.ServiceConfig {
+	if len(cfg.DockerComposeFile) == 0 || cfg.Service == "" {
+		return nil
+	}
+	inv := newComposeInvocation(ws, cfg, workspaceFolder)
+	project, err := composehelper.LoadProject(context.Background(), inv.files, nil, inv.env)
+	if err != nil {
+		e.logger.Debug("failed to load compose files for trust check", "error", err)
+		return nil
+	}
+	svc, err := project.GetService(cfg.Service)
+	if err != nil {
+		return nil
+	}
+	return &svc
+}
+
+// dangerousSettings lists the settings in cfg that give the container
+// elevated access to the host: privileged mode, dangerous capabilities and
+// docker/podman socket mounts, whether set directly or through runArgs.
+// Each entry is a short description such as "capAdd: SYS_ADMIN".
+func dangerousSettings(cfg *config.DevContainerConfig) []string {
+	var found dangerSet
+	found.addConfig(cfg)
+	return found
+}
+
+// dangerSet collects dangerous settings in the order they are found,
+// without duplicates.
+type dangerSet []string
+
+func (d *dangerSet) add(s string) {
+	if !slices.Contains(*d, s) {
+		*d = append(*d, s)
+	}
+}
+
+func (d *dangerSet) addCaps(caps []string) {
+	for _, c := range caps {
+		if isDangerousCap(c) {
+			d.add("capAdd: " + normalizeCap(c))
+		}
+	}
+}
+
+func (d *dangerSet) addMountSource(src string) {
+	if isRuntimeSocket(src) {
+		d.add("mount: " + src)
+	}
+}
+
+func (d *dangerSet) addConfig(cfg *config.DevContainerConfig) {
+	if cfg.Privileged != nil && *cfg.Privileged {
+		d.add("privileged")
+	}
+	d.addCaps(cfg.CapAdd)
+	for _, m := range cfg.Mounts {
+		d.addMountSource(m.Source)
+	}
+
+	args := cfg.RunArgs
+	for i := 0; i < len(args); i++ {
+		var flag, value string
+		var hasValue bool
+		if src, ok := strings.CutPrefix(args[i], "-v"); ok && src != "" && src[0] != '=' {
+			// Short flag with its value attached: -v/var/run/docker.sock:...
+			flag, value, hasValue = "-v", src, true
+		} else {
+			flag, value, hasValue = strings.Cut(args[i], "=")
+		}
+		if !hasValue && i+1 < len(args) && takesValue(flag) {
+			value = args[i+1]
+			i++
+		}
+		switch flag {
+		case "--privileged":
+			if value != "false" {
+				d.add("privileged")
+			}
+		case "--cap-add":
+			d.addCaps([]string{value})
+		case "-v", "--volume":
+			src, _, _ := strings.Cut(value, ":")
+			d.addMountSource(src)
+		case "--mount":
+			if m, err := config.ParseMount(value); err == nil {
+				d.addMountSource(m.Source)
+			}
+		}
+	}
+}
+
+// addMetadata adds the settings a feature or image devcontainer.metadata
+// entry contributes to the merged config.
+func (d *dangerSet) addMetadata(m *config.ImageMetadata) {
+	if m.Privileged != nil && *m.Privileged {
+		d.add("privileged")
+	}
+	d.addCaps(m.CapAdd)
+	for _, mount := range m.Mounts {
+		d.addMountSource(mount.Source)
+	}
+}
+
+func (d *dangerSet) addComposeService(svc *composetypes.ServiceConfig) {
+	if svc.Privileged {
+		d.add("privileged")
+	}
+	d.addCaps(svc.CapAdd)
+	for _, v := range svc.Volumes {
+		d.addMountSource(v.Source)
+	}
+}
+
+// takesValue reports whether a runArgs flag inspected by dangerousSettings
+// reads its value from the next argument when written without "=".
+func takesValue(flag string) bool {
+	switch flag {
+	case "--cap-add", "-v", "--volume", "--mount":
+		return true
+	}
+	return false
+}
+
+// normalizeCap upper-cases a capability name and strips the CAP_ prefix.
+func normalizeCap(c string) string {
+	return strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(c)), "CAP_")
+}
+
+func isDangerousCap(c string) bool {
+	return slices.Contains(dangerousCaps, normalizeCap(c))
+}
+
+// isRuntimeSocket reports whether a mount source is a docker or podman API
+// socket, which gives full control over the host's containers.
+func isRuntimeSocket(src string) bool {
+	return strings.HasSuffix(src, "/docker.sock") || strings.HasSuffix(src, "/podman.sock")
+}
@@ -0,0 +1,262 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/fgrehm/crib/internal/config"
+	"github.com/fgrehm/crib/internal/workspace"
+)
+
+func TestDangerousSettings(t *testing.T) {
+	yes, no := true, false
+	tests := []struct {
+		name  string
+		setup func(cfg *config.DevContainerConfig)
+		want  []string
+	}{
+		{"none", func(*config.DevContainerConfig) {}, nil},
+		{"privileged false", func(cfg *config.DevContainerConfig) { cfg.Privileged = &no }, nil},
+		{
+			"privileged and caps",
+			func(cfg *config.DevContainerConfig) {
+				cfg.Privileged = &yes
+				cfg.CapAdd = []string{"cap_sys_admin", "NET_BIND_SERVICE"}
+			},
+			[]string{"privileged", "capAdd: SYS_ADMIN"},
+		},
+		{
+			"docker socket mount",
+			func(cfg *config.DevContainerConfig) {
+				cfg.Mounts = []config.Mount{
+					{Type: "bind", Source: "/var/run/docker.sock", Target: "/var/run/docker.sock"},
+					{Type: "volume", Source: "cache", Target: "/cache"},
+				}
+			},
+			[]string{"mount: /var/run/docker.sock"},
+		},
+		{
+			"runArgs",
+			func(cfg *config.DevContainerConfig) {
+				cfg.RunArgs = []string{
+					"--privileged", "--cap-add", "NET_ADMIN", "--cap-add=SYS_PTRACE", "--cap-add=CHOWN",
+					"-v", "/run/podman/podman.sock:/var/run/docker.sock", "--memory", "2g",
+				}
+			},
+			[]string{"privileged", "capAdd: NET_ADMIN", "capAdd: SYS_PTRACE", "mount: /run/podman/podman.sock"},
+		},
+		{
+			"runArgs joined volume",
+			func(cfg *config.DevContainerConfig) {
+				cfg.RunArgs = []string{"-v/var/run/docker.sock:/var/run/docker.sock", "-v=/run/podman/podman.sock:/p.sock"}
+			},
+			[]string{"mount: /var/run/docker.sock", "mount: /run/podman/podman.sock"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.DevContainerConfig{}
+			tt.setup(cfg)
+			got := dangerousSettings(cfg)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("dangerousSettings = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckTrust(t *testing.T) {
+	store := workspace.NewStoreAt(t.TempDir())
+	ws := &workspace.Workspace{ID: "ws-trust", Source: t.TempDir()}
+	if err := store.Save(ws); err != nil {
+		t.Fatal(err)
+	}
+
+	var prompted [][]string
+	answer := false
+	e := &Engine{store: store}
+	e.SetTrustPrompt(func(_ *workspace.Workspace, settings []string) (bool, error) {
+		prompted = append(prompted, settings)
+		return answer, nil
+	})
+
+	yes := true
+	cfg := &config.DevContainerConfig{}
+	cfg.Privileged = &yes
+
+	// Declining fails with ErrUntrusted and records nothing.
+	err := e.checkTrust(ws, cfg, "", nil)
+	var untrusted *ErrUntrusted
+	if !errors.As(err, &untrusted) {
+		t.Fatalf("expected ErrUntrusted, got %v", err)
+	}
+	if len(ws.TrustedSettings) != 0 {
+		t.Errorf("TrustedSettings = %q after declining", ws.TrustedSettings)
+	}
+
+	// Accepting records the settings in the store.
+	answer = true
+	if err := e.checkTrust(ws, cfg, "", nil); err != nil {
+		t.Fatalf("checkTrust: %v", err)
+	}
+	stored, err := store.Load(ws.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(stored.TrustedSettings, []string{"privileged"}) {
+		t.Errorf("stored TrustedSettings = %q, want [privileged]", stored.TrustedSettings)
+	}
+
+	// A trusted workspace skips the prompt.
+	if err := e.checkTrust(stored, cfg, "", nil); err != nil {
+		t.Fatalf("checkTrust on trusted workspace: %v", err)
+	}
+	if len(prompted) != 2 {
+		t.Errorf("prompted %d times, want 2", len(prompted))
+	}
+
+	// New dangerous settings prompt again, listing only what is new.
+	cfg.CapAdd = []string{"SYS_ADMIN"}
+	if err := e.checkTrust(stored, cfg, "", nil); err != nil {
+		t.Fatalf("checkTrust with new setting: %v", err)
+	}
+	if last := prompted[len(prompted)-1]; !slices.Equal(last, []string{"capAdd: SYS_ADMIN"}) {
+		t.Errorf("prompted for %q, want only the new capability", last)
+	}
+}
+
+func TestCheckTrust_ExistingContainerSettingsTrusted(t *testing.T) {
+	store := workspace.NewStoreAt(t.TempDir())
+	ws := &workspace.Workspace{ID: "ws-existing", Source: t.TempDir()}
+	if err := store.Save(ws); err != nil {
+		t.Fatal(err)
+	}
+	// The container was created before the trust check existed.
+	if err := store.SaveResult(ws.ID, &workspace.Result{
+		MergedConfig: json.RawMessage(`{"privileged":true}`),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	var prompted [][]string
+	e := &Engine{store: store}
+	e.SetTrustPrompt(func(_ *workspace.Workspace, settings []string) (bool, error) {
+		prompted = append(prompted, settings)
+		return false, nil
+	})
+
+	yes := true
+	cfg := &config.DevContainerConfig{}
+	cfg.Privileged = &yes
+	if err := e.checkTrust(ws, cfg, "", nil); err != nil {
+		t.Fatalf("checkTrust: %v", err)
+	}
+	if len(prompted) != 0 {
+		t.Errorf("prompted for %q, want no prompt for settings of the existing container", prompted)
+	}
+	stored, err := store.Load(ws.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(stored.TrustedSettings, []string{"privileged"}) {
+		t.Errorf("stored TrustedSettings = %q, want [privileged]", stored.TrustedSettings)
+	}
+
+	// Settings the existing container does not have still prompt.
+	cfg.CapAdd = []string{"SYS_ADMIN"}
+	var untrusted *ErrUntrusted
+	if err := e.checkTrust(ws, cfg, "", nil); !errors.As(err, &untrusted) {
+		t.Fatalf("expected ErrUntrusted, got %v", err)
+	}
+	if len(prompted) != 1 || !slices.Equal(prompted[0], []string{"capAdd: SYS_ADMIN"}) {
+		t.Errorf("prompted for %q, want only the new capability", prompted)
+	}
+}
+
+func TestCheckTrust_NoPrompt(t *testing.T) {
+	yes := true
+	cfg := &config.DevContainerConfig{}
+	cfg.Privileged = &yes
+	e := &Engine{}
+	if err := e.checkTrust(&workspace.Workspace{ID: "ws"}, cfg, "", nil); err != nil {
+		t.Errorf("checkTrust without a prompt should allow everything, got %v", err)
+	}
+}
+
+func TestCheckTrust_MetadataAndComposeService(t *testing.T) {
+	project := t.TempDir()
+	dcDir := filepath.Join(project, ".devcontainer")
+	if err := os.MkdirAll(dcDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	composeYAML := `services:
+  app:
+    image: alpine
+    privileged: true
+    volumes:
+      - /var/run/docker.sock:/var/run/docker.sock
+`
+	if err := os.WriteFile(filepath.Join(dcDir, "compose.yml"), []byte(composeYAML), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	store := workspace.NewStoreAt(t.TempDir())
+	ws := &workspace.Workspace{ID: "ws-trust", Source: project, DevContainerPath: ".devcontainer/devcontainer.json"}
+	if err := store.Save(ws); err != nil {
+		t.Fatal(err)
+	}
+
+	var prompted []string
+	e := &Engine{store: store, logger: slog.Default()}
+	e.SetTrustPrompt(func(_ *workspace.Workspace, settings []string) (bool, error) {
+		prompted = settings
+		return true, nil
+	})
+
+	cfg := &config.DevContainerConfig{}
+	cfg.DockerComposeFile = []string{"compose.yml"}
+	cfg.Service = "app"
+	if err := e.checkTrust(ws, cfg, "/workspaces/project", nil); err != nil {
+		t.Fatalf("checkTrust: %v", err)
+	}
+	if want := []string{"privileged", "mount: /var/run/docker.sock"}; !slices.Equal(prompted, want) {
+		t.Errorf("prompted for %q, want %q", prompted, want)
+	}
+
+	// Feature metadata only prompts for what it adds.
+	yes := true
+	prompted = nil
+	metadata := []*config.ImageMetadata{nil, {}}
+	metadata[1].Privileged = &yes
+	metadata[1].CapAdd = []string{"SYS_ADMIN"}
+	if err := e.checkTrust(ws, cfg, "/workspaces/project", metadata); err != nil {
+		t.Fatalf("checkTrust with metadata: %v", err)
+	}
+	if want := []string{"capAdd: SYS_ADMIN"}; !slices.Equal(prompted, want) {
+		t.Errorf("prompted for %q, want %q", prompted, want)
+	}
+}
+
+func TestUp_TrustCheckedBeforeInitializeCommand(t *testing.T) {
+	e, ws := newUpTimeoutTestEngine(t, &slowUpDriver{}, `{
+		"image": "alpine",
+		"privileged": true,
+		"initializeCommand": "touch init-ran"
+	}`)
+	e.SetTrustPrompt(func(*workspace.Workspace, []string) (bool, error) { return false, nil })
+
+	_, err := e.Up(context.Background(), ws, UpOptions{})
+	var untrusted *ErrUntrusted
+	if !errors.As(err, &untrusted) {
+		t.Fatalf("expected ErrUntrusted, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(ws.Source, "init-ran")); err == nil {
+		t.Error("initializeCommand ran before the trust prompt was declined")
+	}
+}
@@ -0,0 +1,351 @@
+package engine
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/fgrehm/crib/internal/config"
+	"github.com/fgrehm/crib/internal/driver"
+	"github.com/fgrehm/crib/internal/workspace"
+)
+
+// recreateImageDriver simulates a workspace whose container runs a cached
+// build. Every image name resolves to imageID; BuildImage replaces it with
+// builtID, as a rebuild would retag the image.
+type recreateImageDriver struct {
+	mockDriver
+	container      *driver.ContainerDetails
+	imageID        string
+	builtID        string
+	builds         int
+	deleted        []string
+	runs           []*driver.RunOptions
+	removedVolumes []string
+}
+
+func (m *recreateImageDriver) FindContainer(_ context.Context, _ string) (*driver.ContainerDetails, error) {
+	return m.container, nil
+}
+
+func (m *recreateImageDriver) RunContainer(_ context.Context, wsID string, opts *driver.RunOptions) (string, error) {
+	m.runs = append(m.runs, opts)
+	m.container = &driver.ContainerDetails{ID: "crib-" + wsID, Image: m.imageID, State: driver.ContainerState{Status: "running"}}
+	return m.container.ID, nil
+}
+
+func (m *recreateImageDriver) DeleteContainer(_ context.Context, _, containerID string) error {
+	m.deleted = append(m.deleted, containerID)
+	m.container = nil
+	return nil
+}
+
+func (m *recreateImageDriver) BuildImage(_ context.Context, _ string, _ *driver.BuildOptions) error {
+	m.builds++
+	m.imageID = m.builtID
+	return nil
+}
+
+func (m *recreateImageDriver) InspectImage(_ context.Context, _ string) (*driver.ImageDetails, error) {
+	return &driver.ImageDetails{ID: m.imageID}, nil
+}
+
+func (m *recreateImageDriver) RemoveVolume(_ context.Context, name string) error {
+	m.removedVolumes = append(m.removedVolumes, name)
+	return nil
+}
+
+const recreateTestConfig = `{
+	"build": {"dockerfile": "Dockerfile"},
+	"mounts": ["type=volume,source=app-cache,target=/cache"]
+}`
+
+func newRecreateImageDriver(builtID string) *recreateImageDriver {
+	return &recreateImageDriver{
+		container: &driver.ContainerDetails{ID: "old-container", Image: "sha256:old", State: driver.ContainerState{Status: "running"}},
+		imageID:   "sha256:old",
+		builtID:   builtID,
+	}
+}
+
+func TestUp_RebuildImage_RecreatesWhenImageChanged(t *testing.T) {
+	drv := newRecreateImageDriver("sha256:new")
+	e, ws := newUpTimeoutTestEngine(t, drv, recreateTestConfig)
+
+	if _, err := e.Up(context.Background(), ws, UpOptions{RebuildImage: true}); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	if drv.builds != 1 {
+		t.Errorf("builds = %d, want 1 despite the cached image", drv.builds)
+	}
+	if !slices.Equal(drv.deleted, []string{"old-container"}) {
+		t.Errorf("deleted = %v, want [old-container]", drv.deleted)
+	}
+	if len(drv.runs) != 1 {
+		t.Fatalf("runs = %d, want 1", len(drv.runs))
+	}
+	if len(drv.removedVolumes) != 0 {
+		t.Errorf("removed volumes = %v, want none", drv.removedVolumes)
+	}
+	want := config.Mount{Type: "volume", Source: "app-cache", Target: "/cache"}
+	if !slices.Contains(drv.runs[0].Mounts, want) {
+		t.Errorf("new container mounts = %+v, want the app-cache volume", drv.runs[0].Mounts)
+	}
+}
+
+func TestUp_RebuildImage_KeepsContainerWhenImageUnchanged(t *testing.T) {
+	drv := newRecreateImageDriver("sha256:old")
+	e, ws := newUpTimeoutTestEngine(t, drv, recreateTestConfig)
+
+	if _, err := e.Up(context.Background(), ws, UpOptions{RebuildImage: true}); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	if drv.builds != 1 {
+		t.Errorf("builds = %d, want 1", drv.builds)
+	}
+	if len(drv.deleted) != 0 || len(drv.runs) != 0 {
+		t.Errorf("container should be kept, got deleted=%v runs=%d", drv.deleted, len(drv.runs))
+	}
+}
+
+func TestUp_Recreate_ReusesCachedImage(t *testing.T) {
+	drv := newRecreateImageDriver("sha256:new")
+	e, ws := newUpTimeoutTestEngine(t, drv, recreateTestConfig)
+
+	if _, err := e.Up(context.Background(), ws, UpOptions{Recreate: true}); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	if drv.builds != 0 {
+		t.Errorf("builds = %d, want 0 (cached image reused)", drv.builds)
+	}
+	if !slices.Equal(drv.deleted, []string{"old-container"}) {
+		t.Errorf("deleted = %v, want [old-container]", drv.deleted)
+	}
+	if len(drv.removedVolumes) != 0 {
+		t.Errorf("removed volumes = %v, want none", drv.removedVolumes)
+	}
+}
+
+// storeMergedConfig records cfgJSON as the config of the workspace's last up.
+func storeMergedConfig(t *testing.T, e *Engine, wsID, cfgJSON string) {
+	t.Helper()
+	if err := e.store.SaveResult(wsID, &workspace.Result{MergedConfig: []byte(cfgJSON)}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUp_RebuildIfChanged(t *testing.T) {
+	tests := []struct {
+		name        string
+		stored      string
+		wantRebuild bool
+	}{
+		{
+			name:        "build args changed",
+			stored:      `{"build": {"dockerfile": "Dockerfile", "args": {"VERSION": "1"}}, "mounts": ["type=volume,source=app-cache,target=/cache"]}`,
+			wantRebuild: true,
+		},
+		{
+			name:        "features changed",
+			stored:      `{"build": {"dockerfile": "Dockerfile"}, "features": {"ghcr.io/devcontainers/features/node:1": {}}, "mounts": ["type=volume,source=app-cache,target=/cache"]}`,
+			wantRebuild: true,
+		},
+		{
+			name:   "unchanged",
+			stored: recreateTestConfig,
+		},
+		{
+			// Recreating for a safe change is left to crib restart.
+			name:   "only containerEnv changed",
+			stored: `{"build": {"dockerfile": "Dockerfile"}, "containerEnv": {"A": "1"}, "mounts": ["type=volume,source=app-cache,target=/cache"]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			drv := newRecreateImageDriver("sha256:new")
+			e, ws := newUpTimeoutTestEngine(t, drv, recreateTestConfig)
+			storeMergedConfig(t, e, ws.ID, tt.stored)
+
+			if _, err := e.Up(context.Background(), ws, UpOptions{RebuildIfChanged: true}); err != nil {
+				t.Fatalf("Up: %v", err)
+			}
+			if !tt.wantRebuild {
+				if drv.builds != 0 || len(drv.deleted) != 0 || len(drv.runs) != 0 {
+					t.Errorf("container should be reused, got builds=%d deleted=%v runs=%d", drv.builds, drv.deleted, len(drv.runs))
+				}
+				return
+			}
+			// The image is cached under its name, but a rebuild builds anyway.
+			if drv.builds != 1 {
+				t.Errorf("builds = %d, want 1", drv.builds)
+			}
+			if !slices.Equal(drv.deleted, []string{"old-container"}) {
+				t.Errorf("deleted = %v, want [old-container]", drv.deleted)
+			}
+			if len(drv.runs) != 1 {
+				t.Errorf("runs = %d, want 1", len(drv.runs))
+			}
+		})
+	}
+}
+
+func TestUp_RebuildIfChanged_DockerfileContent(t *testing.T) {
+	for _, edit := range []bool{false, true} {
+		drv := newRecreateImageDriver("sha256:new")
+		e, ws := newUpTimeoutTestEngine(t, drv, recreateTestConfig)
+		cfg, _, err := e.parseAndSubstitute(ws)
+		if err != nil {
+			t.Fatal(err)
+		}
+		built, err := e.buildImage(context.Background(), ws, cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := e.store.SaveResult(ws.ID, &workspace.Result{
+			ContainerID:  "old-container",
+			ImageName:    built.imageName,
+			MergedConfig: []byte(recreateTestConfig),
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if edit {
+			dockerfile := filepath.Join(ws.Source, ".devcontainer", "Dockerfile")
+			if err := os.WriteFile(dockerfile, []byte("FROM alpine\nRUN apk add git\n"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if _, err := e.Up(context.Background(), ws, UpOptions{RebuildIfChanged: true}); err != nil {
+			t.Fatalf("Up: %v", err)
+		}
+		if !edit {
+			if len(drv.deleted) != 0 || len(drv.runs) != 0 {
+				t.Errorf("unchanged Dockerfile: container should be reused, got deleted=%v runs=%d", drv.deleted, len(drv.runs))
+			}
+			continue
+		}
+		if !slices.Equal(drv.deleted, []string{"old-container"}) {
+			t.Errorf("edited Dockerfile: deleted = %v, want [old-container]", drv.deleted)
+		}
+		if len(drv.runs) != 1 {
+			t.Errorf("edited Dockerfile: runs = %d, want 1", len(drv.runs))
+		}
+	}
+}
+
+func TestUp_ImageChangeWithoutRebuildIfChanged(t *testing.T) {
+	drv := newRecreateImageDriver("sha256:new")
+	e, ws := newUpTimeoutTestEngine(t, drv, recreateTestConfig)
+	storeMergedConfig(t, e, ws.ID, `{"image": "alpine"}`)
+
+	if _, err := e.Up(context.Background(), ws, UpOptions{}); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	if drv.builds != 0 || len(drv.deleted) != 0 {
+		t.Errorf("plain up should keep the container, got builds=%d deleted=%v", drv.builds, drv.deleted)
+	}
+}
+
+const replaceTestConfig = `{
+	"build": {"dockerfile": "Dockerfile"},
+	"onCreateCommand": "echo onCreate",
+	"postStartCommand": "echo postStart"
+}`
+
+// ranHook reports whether drv executed a hook running cmd.
+func ranHook(drv *recreateImageDriver, cmd string) bool {
+	for _, call := range drv.execCalls {
+		if strings.Contains(strings.Join(call.cmd, " "), cmd) {
+			return true
+		}
+	}
+	return false
+}
+
+// storeSnapshot records the result of a previous up that committed a
+// snapshot for the workspace's current hooks.
+func storeSnapshot(t *testing.T, e *Engine, ws *workspace.Workspace) {
+	t.Helper()
+	cfg, _, err := e.parseAndSubstitute(ws)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.store.SaveResult(ws.ID, &workspace.Result{
+		ContainerID:      "old-container",
+		ImageName:        "crib-" + ws.ID + ":cached",
+		SnapshotImage:    "crib-" + ws.ID + ":snapshot",
+		SnapshotHookHash: computeHookHash(cfg, nil),
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUp_Replace_ResumesFromSnapshot(t *testing.T) {
+	drv := newRecreateImageDriver("sha256:new")
+	e, ws := newUpTimeoutTestEngine(t, drv, replaceTestConfig)
+	storeSnapshot(t, e, ws)
+
+	result, err := e.Up(context.Background(), ws, UpOptions{Replace: true})
+	if err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	if !slices.Equal(drv.deleted, []string{"old-container"}) {
+		t.Errorf("deleted = %v, want [old-container]", drv.deleted)
+	}
+	if result.ContainerID == "old-container" {
+		t.Errorf("container ID = %q, want a new container", result.ContainerID)
+	}
+	if drv.builds != 0 {
+		t.Errorf("builds = %d, want 0 (cached image reused)", drv.builds)
+	}
+	if len(drv.runs) != 1 || drv.runs[0].Image != "crib-"+ws.ID+":snapshot" {
+		t.Fatalf("runs = %+v, want one run from the snapshot image", drv.runs)
+	}
+	if ranHook(drv, "echo onCreate") {
+		t.Error("onCreateCommand should not run when replacing from a snapshot")
+	}
+	if !ranHook(drv, "echo postStart") {
+		t.Error("postStartCommand should run in the replaced container")
+	}
+}
+
+func TestUp_Replace_WithoutSnapshotRunsFullSetup(t *testing.T) {
+	drv := newRecreateImageDriver("sha256:new")
+	e, ws := newUpTimeoutTestEngine(t, drv, replaceTestConfig)
+	if err := e.store.MarkHookDone(ws.ID, "onCreateCommand", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := e.Up(context.Background(), ws, UpOptions{Replace: true}); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	if !slices.Equal(drv.deleted, []string{"old-container"}) {
+		t.Errorf("deleted = %v, want [old-container]", drv.deleted)
+	}
+	if drv.builds != 0 {
+		t.Errorf("builds = %d, want 0 (cached image reused)", drv.builds)
+	}
+	// The new container has none of the old one's create-time effects.
+	if !ranHook(drv, "echo onCreate") {
+		t.Error("onCreateCommand should run without a snapshot to resume from")
+	}
+}
+
+func TestUp_Replace_IgnoredWithRecreate(t *testing.T) {
+	drv := newRecreateImageDriver("sha256:new")
+	e, ws := newUpTimeoutTestEngine(t, drv, replaceTestConfig)
+	storeSnapshot(t, e, ws)
+
+	if _, err := e.Up(context.Background(), ws, UpOptions{Recreate: true, Replace: true}); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	if len(drv.runs) != 1 || drv.runs[0].Image == "crib-"+ws.ID+":snapshot" {
+		t.Errorf("runs = %+v, want a fresh container that bypasses the snapshot", drv.runs)
+	}
+	if !ranHook(drv, "echo onCreate") {
+		t.Error("onCreateCommand should run on --recreate")
+	}
+}
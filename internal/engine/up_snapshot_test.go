@@ -10,6 +10,7 @@ import (
 	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/fgrehm/crib/internal/config"
 	"github.com/fgrehm/crib/internal/driver"
@@ -59,12 +60,16 @@ func (m *snapshotUpMockDriver) RunContainer(_ context.Context, wsID string, opts
 
 func (m *snapshotUpMockDriver) DeleteContainer(_ context.Context, _, _ string) error { return nil }
 func (m *snapshotUpMockDriver) StartContainer(_ context.Context, _, _ string) error  { return nil }
-func (m *snapshotUpMockDriver) StopContainer(_ context.Context, _, _ string) error   { return nil }
+func (m *snapshotUpMockDriver) StopContainer(_ context.Context, _, _ string, _ *time.Duration) error {
+	return nil
+}
+func (m *snapshotUpMockDriver) PauseContainer(_ context.Context, _, _ string) error   { return nil }
+func (m *snapshotUpMockDriver) UnpauseContainer(_ context.Context, _, _ string) error { return nil }
 func (m *snapshotUpMockDriver) RestartContainer(_ context.Context, _, _ string) error {
 	return nil
 }
 
-func (m *snapshotUpMockDriver) ExecContainer(_ context.Context, _, _ string, cmd []string, _ io.Reader, stdout io.Writer, _ io.Writer, env []string, _ string) error {
+func (m *snapshotUpMockDriver) ExecContainer(_ context.Context, _, _ string, cmd []string, _ io.Reader, stdout io.Writer, _ io.Writer, env []string, _, _ string) error {
 	m.mu.Lock()
 	m.execCalls = append(m.execCalls, mockExecCall{cmd: cmd, env: env})
 	m.mu.Unlock()
@@ -79,6 +84,15 @@ func (m *snapshotUpMockDriver) ExecContainer(_ context.Context, _, _ string, cmd
 func (m *snapshotUpMockDriver) ContainerLogs(_ context.Context, _, _ string, _, _ io.Writer, _ *driver.LogsOptions) error {
 	return nil
 }
+func (m *snapshotUpMockDriver) AttachContainer(_ context.Context, _, _ string, _, _ io.Writer) error {
+	return nil
+}
+func (m *snapshotUpMockDriver) CopyToContainer(_ context.Context, _, _, _, _ string) error {
+	return driver.ErrCopyUnsupported
+}
+func (m *snapshotUpMockDriver) CopyFromContainer(_ context.Context, _, _, _, _ string) error {
+	return driver.ErrCopyUnsupported
+}
 func (m *snapshotUpMockDriver) BuildImage(_ context.Context, _ string, _ *driver.BuildOptions) error {
 	return nil
 }
@@ -100,6 +114,12 @@ func (m *snapshotUpMockDriver) CommitContainer(_ context.Context, _, _, _ string
 	m.mu.Unlock()
 	return nil
 }
+func (m *snapshotUpMockDriver) ExportContainer(_ context.Context, _, _, _ string) error {
+	return nil
+}
+func (m *snapshotUpMockDriver) PullImage(_ context.Context, _ string, _, _ io.Writer) error {
+	return nil
+}
 func (m *snapshotUpMockDriver) RemoveImage(_ context.Context, _ string) error { return nil }
 func (m *snapshotUpMockDriver) ListImages(_ context.Context, _ string) ([]driver.ImageInfo, error) {
 	return nil, nil
@@ -108,6 +128,9 @@ func (m *snapshotUpMockDriver) ListVolumes(_ context.Context, _ string) ([]drive
 	return nil, nil
 }
 func (m *snapshotUpMockDriver) RemoveVolume(_ context.Context, _ string) error { return nil }
+func (m *snapshotUpMockDriver) ContainerChanges(_ context.Context, _, _ string) ([]driver.FileChange, error) {
+	return nil, nil
+}
 
 func TestUpCreate_FromSnapshot_PreservesEnv(t *testing.T) {
 	store := workspace.NewStoreAt(t.TempDir())
@@ -162,7 +185,7 @@ func TestUpCreate_FromSnapshot_PreservesEnv(t *testing.T) {
 	cfg.RemoteUser = "vscode"
 
 	b := eng.newBackend(ws, cfg, "/workspaces/project")
-	result, err := eng.upCreate(context.Background(), ws, cfg, "/workspaces/project", b, false)
+	result, err := eng.upCreate(context.Background(), ws, cfg, "/workspaces/project", b, false, nil)
 	if err != nil {
 		t.Fatalf("upCreate: %v", err)
 	}
@@ -243,7 +266,7 @@ func TestUpCreate_FromSnapshot_RunsResumeHooksOnly(t *testing.T) {
 	}
 
 	b := eng.newBackend(ws, cfg, "/workspaces/project")
-	_, err := eng.upCreate(context.Background(), ws, cfg, "/workspaces/project", b, false)
+	_, err := eng.upCreate(context.Background(), ws, cfg, "/workspaces/project", b, false, nil)
 	if err != nil {
 		t.Fatalf("upCreate: %v", err)
 	}
@@ -335,7 +358,7 @@ func TestUpCreate_FromSnapshot_RecreateBypassesSnapshot(t *testing.T) {
 	// path was NOT taken by checking that RunContainer was NOT called with
 	// the snapshot image. Since buildImage will fail, we expect an error.
 	b := eng.newBackend(ws, cfg, "/workspaces/project")
-	_, err := eng.upCreate(context.Background(), ws, cfg, "/workspaces/project", b, true)
+	_, err := eng.upCreate(context.Background(), ws, cfg, "/workspaces/project", b, true, nil)
 	// We expect an error from buildImage since we can't actually build.
 	// The key assertion is that the snapshot path was not taken.
 	if err == nil {
@@ -400,7 +423,7 @@ func TestUpCreate_FromSnapshot_StaleSnapshotFallsThrough(t *testing.T) {
 	// With a stale snapshot, upCreate should fall through to the build path,
 	// which will fail in tests since we can't actually build images.
 	b := eng.newBackend(ws, cfg, "/workspaces/project")
-	_, err := eng.upCreate(context.Background(), ws, cfg, "/workspaces/project", b, false)
+	_, err := eng.upCreate(context.Background(), ws, cfg, "/workspaces/project", b, false, nil)
 	if err == nil {
 		// If somehow it succeeded, verify it didn't use the snapshot.
 		if len(mockDrv.runCalls) > 0 && mockDrv.runCalls[0].Image == "crib-ws-up-stale:snapshot" {
@@ -466,7 +489,7 @@ func TestUpCreate_FromSnapshot_PluginCopiesExecuted(t *testing.T) {
 	}
 
 	b := eng.newBackend(ws, cfg, "/workspaces/project")
-	_, err := eng.upCreate(context.Background(), ws, cfg, "/workspaces/project", b, false)
+	_, err := eng.upCreate(context.Background(), ws, cfg, "/workspaces/project", b, false, nil)
 	if err != nil {
 		t.Fatalf("upCreate: %v", err)
 	}
@@ -523,7 +546,7 @@ func TestUpCreate_FromSnapshot_PreservesImageName(t *testing.T) {
 	}
 
 	b := eng.newBackend(ws, cfg, "/workspaces/project")
-	result, err := eng.upCreate(context.Background(), ws, cfg, "/workspaces/project", b, false)
+	result, err := eng.upCreate(context.Background(), ws, cfg, "/workspaces/project", b, false, nil)
 	if err != nil {
 		t.Fatalf("upCreate: %v", err)
 	}
@@ -582,7 +605,7 @@ func TestUpCreate_FromSnapshot_PreservesFeatureEntrypoints(t *testing.T) {
 	}
 
 	b := eng.newBackend(ws, cfg, "/workspaces/project")
-	_, err := eng.upCreate(context.Background(), ws, cfg, "/workspaces/project", b, false)
+	_, err := eng.upCreate(context.Background(), ws, cfg, "/workspaces/project", b, false, nil)
 	if err != nil {
 		t.Fatalf("upCreate: %v", err)
 	}
@@ -656,7 +679,7 @@ func TestUpCreate_FromSnapshot_ResolvesConfigEnv(t *testing.T) {
 	}
 
 	b := eng.newBackend(ws, cfg, "/workspaces/project")
-	_, err := eng.upCreate(context.Background(), ws, cfg, "/workspaces/project", b, false)
+	_, err := eng.upCreate(context.Background(), ws, cfg, "/workspaces/project", b, false, nil)
 	if err != nil {
 		t.Fatalf("upCreate: %v", err)
 	}
@@ -0,0 +1,145 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fgrehm/crib/internal/driver"
+	"github.com/fgrehm/crib/internal/workspace"
+)
+
+// slowUpDriver simulates an up that hangs: either the image build or an exec
+// whose command contains slowCmd blocks until the context is cancelled.
+type slowUpDriver struct {
+	mockDriver
+	slowBuild bool
+	slowCmd   string
+	created   bool
+	deleted   []string
+}
+
+func (m *slowUpDriver) FindContainer(_ context.Context, wsID string) (*driver.ContainerDetails, error) {
+	if !m.created {
+		return nil, nil
+	}
+	return &driver.ContainerDetails{ID: "crib-" + wsID, State: driver.ContainerState{Status: "running"}}, nil
+}
+
+func (m *slowUpDriver) RunContainer(_ context.Context, wsID string, _ *driver.RunOptions) (string, error) {
+	m.created = true
+	return "crib-" + wsID, nil
+}
+
+func (m *slowUpDriver) DeleteContainer(_ context.Context, _, containerID string) error {
+	m.created = false
+	m.deleted = append(m.deleted, containerID)
+	return nil
+}
+
+func (m *slowUpDriver) BuildImage(ctx context.Context, _ string, _ *driver.BuildOptions) error {
+	if m.slowBuild {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	return nil
+}
+
+func (m *slowUpDriver) InspectImage(_ context.Context, name string) (*driver.ImageDetails, error) {
+	return nil, fmt.Errorf("image %s not found", name)
+}
+
+func (m *slowUpDriver) ExecContainer(ctx context.Context, wsID, containerID string, cmd []string, stdin io.Reader, stdout, stderr io.Writer, env []string, user, workdir string) error {
+	if m.slowCmd != "" && strings.Contains(strings.Join(cmd, " "), m.slowCmd) {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	return m.mockDriver.ExecContainer(ctx, wsID, containerID, cmd, stdin, stdout, stderr, env, user, workdir)
+}
+
+func newUpTimeoutTestEngine(t *testing.T, drv driver.Driver, devcontainerJSON string) (*Engine, *workspace.Workspace) {
+	t.Helper()
+	project := t.TempDir()
+	dcDir := filepath.Join(project, ".devcontainer")
+	if err := os.MkdirAll(dcDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dcDir, "devcontainer.json"), []byte(devcontainerJSON), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dcDir, "Dockerfile"), []byte("FROM alpine\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	store := workspace.NewStoreAt(t.TempDir())
+	ws := &workspace.Workspace{ID: "test-timeout", Source: project, DevContainerPath: ".devcontainer/devcontainer.json"}
+	if err := store.Save(ws); err != nil {
+		t.Fatal(err)
+	}
+	return &Engine{
+		driver:      drv,
+		store:       store,
+		runtimeName: "docker",
+		logger:      slog.Default(),
+		stdout:      io.Discard,
+		stderr:      io.Discard,
+	}, ws
+}
+
+func TestUp_Timeout_CancelsSlowBuild(t *testing.T) {
+	drv := &slowUpDriver{slowBuild: true}
+	e, ws := newUpTimeoutTestEngine(t, drv, `{"build": {"dockerfile": "Dockerfile"}}`)
+
+	_, err := e.Up(context.Background(), ws, UpOptions{Timeout: 50 * time.Millisecond})
+
+	var te *ErrUpTimeout
+	if !errors.As(err, &te) {
+		t.Fatalf("expected ErrUpTimeout, got: %v", err)
+	}
+	if te.Timeout != 50*time.Millisecond {
+		t.Errorf("Timeout = %s, want 50ms", te.Timeout)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected error to wrap context.DeadlineExceeded, got: %v", err)
+	}
+	if drv.created {
+		t.Error("no container should have been created")
+	}
+}
+
+func TestUp_Timeout_RemovesPartialContainer(t *testing.T) {
+	drv := &slowUpDriver{slowCmd: "slow-hook"}
+	e, ws := newUpTimeoutTestEngine(t, drv, `{"image": "alpine", "onCreateCommand": "slow-hook"}`)
+
+	_, err := e.Up(context.Background(), ws, UpOptions{Timeout: 50 * time.Millisecond})
+
+	var te *ErrUpTimeout
+	if !errors.As(err, &te) {
+		t.Fatalf("expected ErrUpTimeout, got: %v", err)
+	}
+	if len(drv.deleted) != 1 || drv.deleted[0] != "crib-test-timeout" {
+		t.Errorf("deleted = %v, want the partially created container", drv.deleted)
+	}
+	if drv.created {
+		t.Error("container should have been removed after the timeout")
+	}
+}
+
+func TestUp_Timeout_NotTriggered(t *testing.T) {
+	drv := &slowUpDriver{}
+	e, ws := newUpTimeoutTestEngine(t, drv, `{"image": "alpine"}`)
+
+	if _, err := e.Up(context.Background(), ws, UpOptions{Timeout: time.Minute}); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	if len(drv.deleted) != 0 {
+		t.Errorf("deleted = %v, want none", drv.deleted)
+	}
+}
@@ -0,0 +1,116 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	composehelper "github.com/fgrehm/crib/internal/compose"
+	"github.com/fgrehm/crib/internal/config"
+	"github.com/fgrehm/crib/internal/feature"
+	"github.com/fgrehm/crib/internal/workspace"
+)
+
+// Validate checks the workspace's devcontainer config without building or
+// starting anything: exactly one of image, Dockerfile, or compose is set,
+// the files it references exist, and the compose service is defined. It
+// returns one message per problem found. The error is non-nil only when the
+// config cannot be parsed at all.
+func (e *Engine) Validate(ctx context.Context, ws *workspace.Workspace) ([]string, error) {
+	cfg, workspaceFolder, err := e.parseAndSubstitute(ws)
+	if err != nil {
+		return nil, err
+	}
+
+	var problems []string
+	addf := func(format string, args ...any) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	// Exactly one way to get a container.
+	var sources []string
+	if cfg.Image != "" {
+		sources = append(sources, "image")
+	}
+	dockerfile := config.GetDockerfilePath(cfg)
+	if dockerfile != "" {
+		sources = append(sources, "build.dockerfile")
+	}
+	if len(cfg.DockerComposeFile) > 0 {
+		sources = append(sources, "dockerComposeFile")
+	}
+	switch len(sources) {
+	case 0:
+		addf("one of image, build.dockerfile, or dockerComposeFile must be set")
+	case 1:
+	default:
+		addf("only one of image, build.dockerfile, or dockerComposeFile may be set (found %s)", strings.Join(sources, ", "))
+	}
+
+	if dockerfile != "" {
+		if !isFile(dockerfile) {
+			addf("Dockerfile not found: %s", dockerfile)
+		}
+		if contextPath := config.GetContextPath(cfg); !isDir(contextPath) {
+			addf("build context not found: %s", contextPath)
+		}
+	}
+
+	if len(cfg.DockerComposeFile) > 0 {
+		files := resolveComposeFiles(configDir(ws), cfg.DockerComposeFile)
+		missing := false
+		for _, f := range files {
+			if !isFile(f) {
+				addf("compose file not found: %s", f)
+				missing = true
+			}
+		}
+		switch {
+		case cfg.Service == "":
+			problems = append(problems, (&ErrComposeServiceMissing{}).Error())
+		case !missing:
+			env := devcontainerEnv(ws.ID, ws.Source, workspaceFolder)
+			if _, err := composehelper.GetServiceInfo(ctx, files, cfg.Service, env); err != nil {
+				addf("compose service %q: %v", cfg.Service, err)
+			}
+		}
+	}
+
+	// Local features must point at a directory next to the config.
+	ids := make([]string, 0, len(cfg.Features))
+	for id := range cfg.Features {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	local := &feature.LocalResolver{}
+	for _, id := range ids {
+		if !strings.HasPrefix(id, "./") && !strings.HasPrefix(id, "../") {
+			continue
+		}
+		if _, err := local.Resolve(id, configDir(ws)); err != nil {
+			addf("feature %s: %v", id, err)
+		}
+	}
+
+	// remoteEnv entries may reference each other, but not in a loop.
+	if err := resolveBareVarRefs(maps.Clone(cfg.RemoteEnv), nil); err != nil {
+		addf("%v", err)
+	}
+
+	return problems, nil
+}
+
+// isFile reports whether path exists and is a regular file.
+func isFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
+
+// isDir reports whether path exists and is a directory.
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
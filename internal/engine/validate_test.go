@@ -0,0 +1,141 @@
+package engine
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fgrehm/crib/internal/workspace"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		files  map[string]string // relative to .devcontainer
+		want   []string          // substrings, one per expected problem
+	}{
+		{
+			name:   "valid image",
+			config: `{"image": "alpine"}`,
+		},
+		{
+			name:   "valid dockerfile",
+			config: `{"build": {"dockerfile": "Dockerfile"}}`,
+			files:  map[string]string{"Dockerfile": "FROM alpine\n"},
+		},
+		{
+			name:   "valid compose",
+			config: `{"dockerComposeFile": "compose.yml", "service": "app"}`,
+			files:  map[string]string{"compose.yml": "services:\n  app:\n    image: alpine\n"},
+		},
+		{
+			name:   "compose service from x-crib",
+			config: `{"dockerComposeFile": "compose.yml"}`,
+			files:  map[string]string{"compose.yml": "x-crib:\n  service: app\nservices:\n  app:\n    image: alpine\n"},
+		},
+		{
+			name:   "nothing to run",
+			config: `{"name": "empty"}`,
+			want:   []string{"one of image, build.dockerfile, or dockerComposeFile must be set"},
+		},
+		{
+			name:   "image and dockerfile",
+			config: `{"image": "alpine", "build": {"dockerfile": "Dockerfile"}}`,
+			files:  map[string]string{"Dockerfile": "FROM alpine\n"},
+			want:   []string{"only one of image, build.dockerfile, or dockerComposeFile may be set (found image, build.dockerfile)"},
+		},
+		{
+			name:   "remoteEnv cycle",
+			config: `{"image": "alpine", "remoteEnv": {"A": "${B}", "B": "${A}/x"}}`,
+			want:   []string{"remoteEnv references form a cycle: A -> B -> A"},
+		},
+		{
+			name:   "missing dockerfile",
+			config: `{"build": {"dockerfile": "Dockerfile.dev"}}`,
+			want:   []string{"Dockerfile not found"},
+		},
+		{
+			name:   "missing build context",
+			config: `{"build": {"dockerfile": "Dockerfile", "context": "../app"}}`,
+			files:  map[string]string{"Dockerfile": "FROM alpine\n"},
+			want:   []string{"build context not found"},
+		},
+		{
+			name:   "missing compose file",
+			config: `{"dockerComposeFile": ["compose.yml", "compose.dev.yml"], "service": "app"}`,
+			files:  map[string]string{"compose.yml": "services:\n  app:\n    image: alpine\n"},
+			want:   []string{filepath.Join(".devcontainer", "compose.dev.yml")},
+		},
+		{
+			name:   "compose without service",
+			config: `{"dockerComposeFile": "compose.yml"}`,
+			files:  map[string]string{"compose.yml": "services:\n  app:\n    image: alpine\n"},
+			want:   []string{"service is not specified"},
+		},
+		{
+			name:   "undefined compose service",
+			config: `{"dockerComposeFile": "compose.yml", "service": "web"}`,
+			files:  map[string]string{"compose.yml": "services:\n  app:\n    image: alpine\n"},
+			want:   []string{`compose service "web"`},
+		},
+		{
+			name:   "missing local feature",
+			config: `{"image": "alpine", "features": {"./tools": {}, "ghcr.io/devcontainers/features/go:1": {}}}`,
+			want:   []string{"feature ./tools"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := t.TempDir()
+			dcDir := filepath.Join(source, ".devcontainer")
+			if err := os.MkdirAll(dcDir, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			files := map[string]string{"devcontainer.json": tt.config}
+			for name, content := range tt.files {
+				files[name] = content
+			}
+			for name, content := range files {
+				if err := os.WriteFile(filepath.Join(dcDir, name), []byte(content), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			ws := &workspace.Workspace{ID: "ws-validate", Source: source, DevContainerPath: ".devcontainer/devcontainer.json"}
+			e := &Engine{logger: slog.Default()}
+
+			problems, err := e.Validate(context.Background(), ws)
+			if err != nil {
+				t.Fatalf("Validate: %v", err)
+			}
+			if len(problems) != len(tt.want) {
+				t.Fatalf("problems = %q, want %d matching %q", problems, len(tt.want), tt.want)
+			}
+			for i, want := range tt.want {
+				if !strings.Contains(problems[i], want) {
+					t.Errorf("problem %d = %q, want it to contain %q", i, problems[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestValidate_UnparsableConfig(t *testing.T) {
+	source := t.TempDir()
+	dcDir := filepath.Join(source, ".devcontainer")
+	if err := os.MkdirAll(dcDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dcDir, "devcontainer.json"), []byte(`{"image": `), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ws := &workspace.Workspace{ID: "ws-validate", Source: source, DevContainerPath: ".devcontainer/devcontainer.json"}
+	e := &Engine{logger: slog.Default()}
+
+	if _, err := e.Validate(context.Background(), ws); err == nil {
+		t.Fatal("expected parse error")
+	}
+}
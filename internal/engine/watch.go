@@ -0,0 +1,235 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/fgrehm/crib/internal/config"
+	"github.com/fgrehm/crib/internal/workspace"
+)
+
+// ReloadAction is what crib watch does after the workspace's config files
+// change.
+type ReloadAction int
+
+const (
+	ReloadNone    ReloadAction = iota // Nothing that affects the container changed.
+	ReloadRestart                     // Restart, recreating the container for safe changes.
+	ReloadRebuild                     // Image, Dockerfile or features changed; full rebuild.
+)
+
+func (a ReloadAction) String() string {
+	switch a {
+	case ReloadRestart:
+		return "restart"
+	case ReloadRebuild:
+		return "rebuild"
+	}
+	return "none"
+}
+
+// reloadActionFor maps a config change classification to the action that
+// applies it.
+func reloadActionFor(kind configChangeKind) ReloadAction {
+	switch kind {
+	case changeNeedsRebuild:
+		return ReloadRebuild
+	case changeSafe:
+		return ReloadRestart
+	}
+	return ReloadNone
+}
+
+// WatchFiles returns the files Watch polls for ws: devcontainer.json, the
+// Dockerfile it builds from and its compose files. When the config cannot
+// be parsed (e.g. mid-edit) only devcontainer.json is returned, along with
+// the parse error.
+func (e *Engine) WatchFiles(ws *workspace.Workspace) ([]string, error) {
+	files := []string{filepath.Join(ws.Source, ws.DevContainerPath)}
+	cfg, _, err := e.parseAndSubstitute(ws)
+	if err != nil {
+		return files, err
+	}
+	if dockerfile := config.GetDockerfilePath(cfg); dockerfile != "" {
+		files = append(files, dockerfile)
+	}
+	for _, f := range resolveComposeFiles(configDir(ws), cfg.DockerComposeFile) {
+		if !slices.Contains(files, f) {
+			files = append(files, f)
+		}
+	}
+	return files, nil
+}
+
+// PlanReload decides how to apply the config as it is now to the container
+// from the last up. changed lists the files that changed since then; an
+// edited Dockerfile always needs a rebuild since its content is not part of
+// the config. Compose file contents are compared like Restart does.
+func (e *Engine) PlanReload(ws *workspace.Workspace, changed []string) (ReloadAction, error) {
+	stored, err := e.store.LoadResult(ws.ID)
+	if err != nil {
+		return ReloadNone, fmt.Errorf("loading workspace result: %w", err)
+	}
+	if stored == nil {
+		return ReloadNone, fmt.Errorf("no previous result found for workspace %s (run 'crib up' first)", ws.ID)
+	}
+	cfg, _, err := e.parseAndSubstitute(ws)
+	if err != nil {
+		return ReloadNone, err
+	}
+
+	if dockerfile := config.GetDockerfilePath(cfg); dockerfile != "" && slices.Contains(changed, dockerfile) {
+		return ReloadRebuild, nil
+	}
+
+	var storedCfg config.DevContainerConfig
+	if err := json.Unmarshal(stored.MergedConfig, &storedCfg); err != nil {
+		return ReloadNone, fmt.Errorf("unmarshaling stored config: %w", err)
+	}
+	change := detectConfigChange(&storedCfg, cfg)
+	if change == changeNone && len(cfg.DockerComposeFile) > 0 {
+		files := resolveComposeFiles(configDir(ws), cfg.DockerComposeFile)
+		if computeComposeFilesHash(files) != stored.ComposeFilesHash {
+			change = changeSafe
+		}
+	}
+	return reloadActionFor(change), nil
+}
+
+// Watch polls the files from WatchFiles every interval and calls fn once
+// they have stopped changing for quiet, so a burst of saves from an editor
+// results in a single reload. fn gets the action from PlanReload and the
+// files that changed; when PlanReload fails (e.g. invalid JSON while the
+// user is still typing) the error is logged and the next change is awaited.
+// Watch returns when ctx is done (with nil) or when fn returns an error.
+func (e *Engine) Watch(ctx context.Context, ws *workspace.Workspace, interval, quiet time.Duration, fn func(ReloadAction, []string) error) error {
+	files, err := e.WatchFiles(ws)
+	if err != nil {
+		e.logger.Warn("watching devcontainer.json only", "error", err)
+	}
+	snap := snapshotFiles(files)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var d debouncer
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-ticker.C:
+			current := snapshotFiles(files)
+			d.observe(now, changedFiles(snap, current))
+			snap = current
+
+			changed, ok := d.ready(now, quiet)
+			if !ok {
+				continue
+			}
+			e.logger.Debug("watched files changed", "files", changed)
+			action, err := e.PlanReload(ws, changed)
+			if err != nil {
+				e.logger.Warn("not reloading", "error", err)
+			} else if err := fn(action, changed); err != nil {
+				return err
+			}
+
+			// The Dockerfile or compose files may have moved. Files already
+			// watched keep their state from before the reload, so saves made
+			// while it ran are picked up on the next tick.
+			if files, err = e.WatchFiles(ws); err != nil {
+				e.logger.Debug("watching devcontainer.json only", "error", err)
+			}
+			snap = extendSnapshot(snap, files)
+		}
+	}
+}
+
+// fileState is what snapshotFiles records per file to notice changes.
+type fileState struct {
+	exists  bool
+	size    int64
+	modTime time.Time
+}
+
+// snapshotFiles stats each path. Missing files are recorded as such, so
+// creating or deleting one counts as a change.
+func snapshotFiles(paths []string) map[string]fileState {
+	snap := make(map[string]fileState, len(paths))
+	for _, p := range paths {
+		fi, err := os.Stat(p)
+		if err != nil {
+			snap[p] = fileState{}
+			continue
+		}
+		snap[p] = fileState{exists: true, size: fi.Size(), modTime: fi.ModTime()}
+	}
+	return snap
+}
+
+// extendSnapshot returns a snapshot of paths that reuses the states in snap
+// and stats only the paths snap does not have.
+func extendSnapshot(snap map[string]fileState, paths []string) map[string]fileState {
+	var added []string
+	next := make(map[string]fileState, len(paths))
+	for _, p := range paths {
+		if st, ok := snap[p]; ok {
+			next[p] = st
+		} else {
+			added = append(added, p)
+		}
+	}
+	maps.Copy(next, snapshotFiles(added))
+	return next
+}
+
+// changedFiles returns the paths in current whose state differs from old,
+// sorted.
+func changedFiles(old, current map[string]fileState) []string {
+	var changed []string
+	for p, st := range current {
+		if prev, ok := old[p]; !ok || prev != st {
+			changed = append(changed, p)
+		}
+	}
+	slices.Sort(changed)
+	return changed
+}
+
+// debouncer collects changed files until no new change has been observed
+// for a quiet period.
+type debouncer struct {
+	pending []string
+	last    time.Time
+}
+
+// observe records the files that changed at now.
+func (d *debouncer) observe(now time.Time, changed []string) {
+	if len(changed) == 0 {
+		return
+	}
+	for _, p := range changed {
+		if !slices.Contains(d.pending, p) {
+			d.pending = append(d.pending, p)
+		}
+	}
+	d.last = now
+}
+
+// ready returns the pending files and resets the debouncer once quiet has
+// passed since the last change.
+func (d *debouncer) ready(now time.Time, quiet time.Duration) ([]string, bool) {
+	if len(d.pending) == 0 || now.Sub(d.last) < quiet {
+		return nil, false
+	}
+	changed := d.pending
+	slices.Sort(changed)
+	d.pending = nil
+	return changed, true
+}
@@ -0,0 +1,285 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/fgrehm/crib/internal/workspace"
+)
+
+func TestReloadActionFor(t *testing.T) {
+	tests := []struct {
+		kind configChangeKind
+		want ReloadAction
+	}{
+		{changeNone, ReloadNone},
+		{changeSafe, ReloadRestart},
+		{changeNeedsRebuild, ReloadRebuild},
+	}
+	for _, tt := range tests {
+		if got := reloadActionFor(tt.kind); got != tt.want {
+			t.Errorf("reloadActionFor(%d) = %s, want %s", tt.kind, got, tt.want)
+		}
+	}
+}
+
+func TestDebouncer(t *testing.T) {
+	var d debouncer
+	t0 := time.Unix(0, 0)
+	quiet := time.Second
+
+	if _, ok := d.ready(t0, quiet); ok {
+		t.Fatal("ready with nothing pending")
+	}
+
+	// A burst of saves keeps pushing the reload back.
+	d.observe(t0, []string{"devcontainer.json"})
+	d.observe(t0.Add(400*time.Millisecond), []string{"Dockerfile", "devcontainer.json"})
+	d.observe(t0.Add(600*time.Millisecond), nil)
+	if _, ok := d.ready(t0.Add(1200*time.Millisecond), quiet); ok {
+		t.Fatal("ready before the files were quiet for the whole period")
+	}
+
+	got, ok := d.ready(t0.Add(1400*time.Millisecond), quiet)
+	if !ok {
+		t.Fatal("not ready after the quiet period")
+	}
+	if want := []string{"Dockerfile", "devcontainer.json"}; !slices.Equal(got, want) {
+		t.Errorf("changed = %v, want %v", got, want)
+	}
+	if _, ok := d.ready(t0.Add(5*time.Second), quiet); ok {
+		t.Error("ready again without new changes")
+	}
+}
+
+func TestChangedFiles(t *testing.T) {
+	dir := t.TempDir()
+	kept := filepath.Join(dir, "devcontainer.json")
+	edited := filepath.Join(dir, "Dockerfile")
+	created := filepath.Join(dir, "compose.yml")
+	for _, p := range []string{kept, edited} {
+		if err := os.WriteFile(p, []byte("v1"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	paths := []string{kept, edited, created}
+
+	before := snapshotFiles(paths)
+	if got := changedFiles(before, snapshotFiles(paths)); len(got) != 0 {
+		t.Fatalf("changed = %v with no edits", got)
+	}
+
+	if err := os.WriteFile(edited, []byte("FROM alpine"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(created, []byte("services: {}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got := changedFiles(before, snapshotFiles(paths))
+	if want := []string{edited, created}; !slices.Equal(got, want) {
+		t.Errorf("changed = %v, want %v", got, want)
+	}
+}
+
+func TestPlanReload(t *testing.T) {
+	project := t.TempDir()
+	writeFiles(t, project, ".devcontainer/Dockerfile")
+	configPath := filepath.Join(project, ".devcontainer", "devcontainer.json")
+	writeConfig := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(configPath, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeConfig(`{"build": {"dockerfile": "Dockerfile"}, "containerEnv": {"A": "1"}}`)
+
+	store := workspace.NewStoreAt(t.TempDir())
+	ws := &workspace.Workspace{ID: "ws-watch", Source: project, DevContainerPath: ".devcontainer/devcontainer.json"}
+	if err := store.Save(ws); err != nil {
+		t.Fatal(err)
+	}
+	e := &Engine{store: store, logger: slog.Default()}
+
+	if _, err := e.PlanReload(ws, nil); err == nil {
+		t.Fatal("expected error before the first up")
+	}
+
+	cfg, _, err := e.parseAndSubstitute(ws)
+	if err != nil {
+		t.Fatal(err)
+	}
+	merged, err := json.Marshal(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SaveResult(ws.ID, &workspace.Result{MergedConfig: merged}); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := e.WatchFiles(ws)
+	if err != nil {
+		t.Fatalf("WatchFiles: %v", err)
+	}
+	dockerfile := filepath.Join(project, ".devcontainer", "Dockerfile")
+	if want := []string{configPath, dockerfile}; !slices.Equal(files, want) {
+		t.Errorf("WatchFiles = %v, want %v", files, want)
+	}
+
+	tests := []struct {
+		name    string
+		config  string
+		changed []string
+		want    ReloadAction
+	}{
+		{"unchanged", `{"build": {"dockerfile": "Dockerfile"}, "containerEnv": {"A": "1"}}`, []string{configPath}, ReloadNone},
+		{"hook only", `{"build": {"dockerfile": "Dockerfile"}, "containerEnv": {"A": "1"}, "postStartCommand": "true"}`, []string{configPath}, ReloadNone},
+		{"env", `{"build": {"dockerfile": "Dockerfile"}, "containerEnv": {"A": "2"}}`, []string{configPath}, ReloadRestart},
+		{"build args", `{"build": {"dockerfile": "Dockerfile", "args": {"V": "1"}}, "containerEnv": {"A": "1"}}`, []string{configPath}, ReloadRebuild},
+		{"dockerfile edited", `{"build": {"dockerfile": "Dockerfile"}, "containerEnv": {"A": "1"}}`, []string{dockerfile}, ReloadRebuild},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writeConfig(tt.config)
+			got, err := e.PlanReload(ws, tt.changed)
+			if err != nil {
+				t.Fatalf("PlanReload: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("PlanReload = %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	writeConfig(`{"build": `)
+	if _, err := e.PlanReload(ws, []string{configPath}); err == nil {
+		t.Error("expected error for a config that does not parse")
+	}
+}
+
+func TestWatch_DebouncesSaves(t *testing.T) {
+	project := t.TempDir()
+	configPath := filepath.Join(project, ".devcontainer", "devcontainer.json")
+	writeFiles(t, project, ".devcontainer/devcontainer.json")
+	writeConfig := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(configPath, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeConfig(`{"image": "alpine"}`)
+
+	store := workspace.NewStoreAt(t.TempDir())
+	ws := &workspace.Workspace{ID: "ws-watch-loop", Source: project, DevContainerPath: ".devcontainer/devcontainer.json"}
+	if err := store.Save(ws); err != nil {
+		t.Fatal(err)
+	}
+	e := &Engine{store: store, logger: slog.Default()}
+	if err := store.SaveResult(ws.ID, &workspace.Result{MergedConfig: []byte(`{"image": "alpine"}`)}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	calls := make(chan ReloadAction, 10)
+	done := make(chan error, 1)
+	go func() {
+		done <- e.Watch(ctx, ws, 5*time.Millisecond, 100*time.Millisecond, func(action ReloadAction, changed []string) error {
+			if !slices.Equal(changed, []string{configPath}) {
+				t.Errorf("changed = %v, want %v", changed, []string{configPath})
+			}
+			calls <- action
+			return nil
+		})
+	}()
+
+	// Give the watcher time to take its first snapshot, then save a few
+	// times in a row.
+	time.Sleep(30 * time.Millisecond)
+	writeConfig(`{"image": "alpine", "containerEnv": {"A": "1"}}`)
+	time.Sleep(20 * time.Millisecond)
+	writeConfig(`{"image": "alpine", "containerEnv": {"A": "12"}}`)
+	time.Sleep(20 * time.Millisecond)
+	writeConfig(`{"image": "alpine", "containerEnv": {"A": "123"}}`)
+
+	select {
+	case action := <-calls:
+		if action != ReloadRestart {
+			t.Errorf("action = %s, want restart", action)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no reload after the saves")
+	}
+	select {
+	case action := <-calls:
+		t.Errorf("got a second reload (%s) for one burst of saves", action)
+	case <-time.After(300 * time.Millisecond):
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Watch returned %v after cancel", err)
+	}
+}
+
+func TestWatch_SaveDuringReload(t *testing.T) {
+	project := t.TempDir()
+	configPath := filepath.Join(project, ".devcontainer", "devcontainer.json")
+	writeFiles(t, project, ".devcontainer/devcontainer.json")
+	writeConfig := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(configPath, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeConfig(`{"image": "alpine"}`)
+
+	store := workspace.NewStoreAt(t.TempDir())
+	ws := &workspace.Workspace{ID: "ws-watch-during", Source: project, DevContainerPath: ".devcontainer/devcontainer.json"}
+	if err := store.Save(ws); err != nil {
+		t.Fatal(err)
+	}
+	e := &Engine{store: store, logger: slog.Default()}
+	if err := store.SaveResult(ws.ID, &workspace.Result{MergedConfig: []byte(`{"image": "alpine"}`)}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	calls := make(chan ReloadAction, 10)
+	done := make(chan error, 1)
+	first := true
+	go func() {
+		done <- e.Watch(ctx, ws, 5*time.Millisecond, 20*time.Millisecond, func(action ReloadAction, _ []string) error {
+			if first {
+				// The user saves again while the first reload runs.
+				first = false
+				writeConfig(`{"image": "alpine", "containerEnv": {"A": "during reload"}}`)
+				time.Sleep(50 * time.Millisecond)
+			}
+			calls <- action
+			return nil
+		})
+	}()
+
+	time.Sleep(30 * time.Millisecond)
+	writeConfig(`{"image": "alpine", "containerEnv": {"A": "1"}}`)
+
+	for i := range 2 {
+		select {
+		case <-calls:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("got %d reloads, want 2 (the save during the reload was missed)", i)
+		}
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Watch returned %v after cancel", err)
+	}
+}
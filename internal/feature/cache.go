@@ -73,6 +73,25 @@ func (c *FeatureCache) Store(key string, populate func(dir string) error) (strin
 	return p, nil
 }
 
+// digestSuffix is appended to a cache entry's path to name the file that
+// records the manifest digest the entry was extracted from.
+const digestSuffix = ".digest"
+
+// SetDigest records the manifest digest the entry for key was pulled from.
+func (c *FeatureCache) SetDigest(key, digest string) error {
+	return os.WriteFile(c.Path(key)+digestSuffix, []byte(digest+"\n"), 0o644)
+}
+
+// Digest returns the digest recorded for key, or "" if none was (e.g. for
+// entries cached by older crib versions).
+func (c *FeatureCache) Digest(key string) string {
+	data, err := os.ReadFile(c.Path(key) + digestSuffix)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
 // BaseDir returns the absolute path to the feature cache directory.
 func (c *FeatureCache) BaseDir() string {
 	return c.baseDir
@@ -102,8 +121,14 @@ func (c *FeatureCache) Clean() error {
 }
 
 // ociCacheKey converts an OCI ref like "ghcr.io/org/repo:tag" to a safe
-// filesystem path key like "ghcr.io/org/repo/tag".
+// filesystem path key like "ghcr.io/org/repo/tag". Digest-pinned refs
+// ("ghcr.io/org/repo@sha256:abc", with or without a tag) map to
+// "ghcr.io/org/repo/@sha256-abc"; tags cannot contain "@", so the entry
+// never collides with a tag's.
 func ociCacheKey(ref string) string {
+	if repo, digest, ok := strings.Cut(ref, "@"); ok {
+		return normalizeID(repo) + "/@" + strings.Replace(digest, ":", "-", 1)
+	}
 	// Replace the last colon (tag separator) with a slash to avoid colons
 	// in path components. Only the tag colon needs replacing — registry ports
 	// are part of the hostname and appear before the first slash.
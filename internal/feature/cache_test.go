@@ -78,6 +78,14 @@ func TestOCICacheKey(t *testing.T) {
 			ref:  "registry.example.com:5000/features/go:1",
 			want: "registry.example.com:5000/features/go/1",
 		},
+		{
+			ref:  "ghcr.io/devcontainers/features/go@sha256:abc123",
+			want: "ghcr.io/devcontainers/features/go/@sha256-abc123",
+		},
+		{
+			ref:  "registry.example.com:5000/features/go:1@sha256:abc123",
+			want: "registry.example.com:5000/features/go/@sha256-abc123",
+		},
 	}
 	for _, tc := range tests {
 		got := ociCacheKey(tc.ref)
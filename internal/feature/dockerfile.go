@@ -71,6 +71,7 @@ func GenerateDockerfile(features []*FeatureSet, containerUser, remoteUser string
 		for _, target := range cacheMounts {
 			fmt.Fprintf(&b, "--mount=type=cache,target=%s ", target)
 		}
+		fmt.Fprintf(&b, "echo '%s%s' && ", FeatureMarkerPrefix, escapeQuotes(f.ConfigID))
 		fmt.Fprintf(&b, "chmod +x /tmp/build-features/%d/devcontainer-features-install.sh ", i)
 		fmt.Fprintf(&b, "&& /tmp/build-features/%d/devcontainer-features-install.sh\n", i)
 		b.WriteString("\n")
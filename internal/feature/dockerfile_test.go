@@ -41,6 +41,9 @@ func TestGenerateDockerfileSingle(t *testing.T) {
 	if !strings.Contains(content, "devcontainer-features-install.sh") {
 		t.Error("content missing install script reference")
 	}
+	if !strings.Contains(content, "echo '### crib feature: my-feature' && ") {
+		t.Error("content missing feature marker")
+	}
 	if !strings.Contains(content, "ARG _DEV_CONTAINERS_IMAGE_USER=vscode") {
 		t.Error("content missing user restore ARG")
 	}
@@ -72,6 +75,32 @@ func TestGenerateDockerfileMultiple(t *testing.T) {
 	}
 }
 
+func TestGenerateDockerfileOptionsNotBuildArgs(t *testing.T) {
+	versionOption := map[string]FeatureOption{"version": {Default: "latest"}}
+	features := []*FeatureSet{
+		{
+			ConfigID: "feature-a",
+			Config:   &FeatureConfig{ID: "feature-a", Options: versionOption},
+			Options:  map[string]any{"version": "1.2"},
+		},
+		{
+			ConfigID: "feature-b",
+			Config:   &FeatureConfig{ID: "feature-b", Options: versionOption},
+			Options:  map[string]any{"version": "3.4"},
+		},
+	}
+
+	content, prefix := GenerateDockerfile(features, "root", "root", nil)
+
+	// Options are exported by each feature's own install wrapper; as ARG or
+	// ENV they would carry over into the layers of later features.
+	for _, s := range []string{"VERSION", "1.2", "3.4"} {
+		if strings.Contains(prefix+content, s) {
+			t.Errorf("Dockerfile should not contain option %q:\n%s%s", s, prefix, content)
+		}
+	}
+}
+
 func TestGenerateDockerfileContainerEnv(t *testing.T) {
 	features := []*FeatureSet{
 		{
@@ -0,0 +1,93 @@
+package feature
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// FeatureMarkerPrefix is echoed at the start of each feature install RUN
+// step in the generated Dockerfile. It shows up in build output both in the
+// step header (the RUN instruction) and in the step's own output, which lets
+// FailureDetector attribute a failed build to a feature.
+const FeatureMarkerPrefix = "### crib feature: "
+
+// dockerfileExcerptLine matches the Dockerfile excerpt BuildKit prints around
+// a failing instruction (e.g. "  25 |     RUN ..."). Lines marked with ">>>" are
+// the failing instruction; the others are only context and may mention the
+// next feature.
+var dockerfileExcerptLine = regexp.MustCompile(`^\s*\d+\s*\|`)
+
+// FailureDetector is an io.Writer that watches build output for feature
+// markers and remembers which feature's install step ran last. It is safe for
+// concurrent use, so it can be shared between stdout and stderr.
+type FailureDetector struct {
+	mu      sync.Mutex
+	partial []byte
+	last    string
+	failed  string
+}
+
+// Write scans complete lines of p for feature markers. It never fails.
+func (d *FailureDetector) Write(p []byte) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.partial = append(d.partial, p...)
+	for {
+		i := bytes.IndexAny(d.partial, "\r\n")
+		if i < 0 {
+			break
+		}
+		d.scanLine(string(d.partial[:i]))
+		d.partial = d.partial[i+1:]
+	}
+	return len(p), nil
+}
+
+// FailedFeature returns the ID of the feature whose install step most likely
+// failed: the one named in the build error when present, otherwise the last
+// feature marker seen. Returns "" when no marker was seen.
+func (d *FailureDetector) FailedFeature() string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if len(d.partial) > 0 {
+		d.scanLine(string(d.partial))
+		d.partial = nil
+	}
+	if d.failed != "" {
+		return d.failed
+	}
+	return d.last
+}
+
+func (d *FailureDetector) scanLine(line string) {
+	id := markerFeatureID(line)
+	if id == "" {
+		return
+	}
+	switch {
+	case strings.Contains(line, "did not complete successfully"), strings.Contains(line, ">>>"):
+		d.failed = id
+	case dockerfileExcerptLine.MatchString(line):
+		// Context around the failing instruction, not an executed step.
+	default:
+		d.last = id
+	}
+}
+
+// markerFeatureID extracts the feature ID following FeatureMarkerPrefix in
+// line, or "" when the line has no marker. In the RUN instruction the ID is
+// followed by the closing quote of the echo argument.
+func markerFeatureID(line string) string {
+	_, rest, ok := strings.Cut(line, FeatureMarkerPrefix)
+	if !ok {
+		return ""
+	}
+	if i := strings.IndexAny(rest, `'"\`); i >= 0 {
+		rest = rest[:i]
+	}
+	return strings.TrimSpace(rest)
+}
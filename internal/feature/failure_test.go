@@ -0,0 +1,86 @@
+package feature
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestFailureDetector(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   string
+	}{
+		{
+			name:   "no markers",
+			output: "#5 [base 1/2] FROM docker.io/library/alpine\n#5 DONE 0.1s\n",
+			want:   "",
+		},
+		{
+			name: "buildkit, last step failed",
+			output: `#9 [dev_containers_target_stage 4/6] RUN --mount=type=bind,from=dev_containers_base_stage,source=/,target=/build-context echo '### crib feature: ghcr.io/devcontainers/features/node:1' && chmod +x /tmp/build-features/0/devcontainer-features-install.sh && /tmp/build-features/0/devcontainer-features-install.sh
+#9 0.201 ### crib feature: ghcr.io/devcontainers/features/node:1
+#9 DONE 12.3s
+
+#10 [dev_containers_target_stage 5/6] RUN --mount=type=bind,from=dev_containers_base_stage,source=/,target=/build-context echo '### crib feature: ./local-tool' && chmod +x /tmp/build-features/1/devcontainer-features-install.sh && /tmp/build-features/1/devcontainer-features-install.sh
+#10 0.180 ### crib feature: ./local-tool
+#10 0.350 curl: (6) Could not resolve host: example.com
+#10 ERROR: process "/bin/sh -c echo '### crib feature: ./local-tool' && chmod +x /tmp/build-features/1/devcontainer-features-install.sh && /tmp/build-features/1/devcontainer-features-install.sh" did not complete successfully: exit code: 6
+`,
+			want: "./local-tool",
+		},
+		{
+			name: "buildkit excerpt mentions the next feature",
+			output: `#9 0.201 ### crib feature: ghcr.io/devcontainers/features/node:1
+#9 ERROR: process "/bin/sh -c echo '### crib feature: ghcr.io/devcontainers/features/node:1' && ./install.sh" did not complete successfully: exit code: 1
+Dockerfile:20
+--------------------
+  19 |
+  20 | >>> RUN echo '### crib feature: ghcr.io/devcontainers/features/node:1' && ./install.sh
+  21 |
+  22 |     RUN echo '### crib feature: ghcr.io/devcontainers/features/go:1' && ./install.sh
+--------------------
+`,
+			want: "ghcr.io/devcontainers/features/node:1",
+		},
+		{
+			name: "buildah step output",
+			output: `STEP 5/9: RUN echo '### crib feature: ghcr.io/devcontainers/features/go:1' && chmod +x /tmp/build-features/0/devcontainer-features-install.sh && /tmp/build-features/0/devcontainer-features-install.sh
+### crib feature: ghcr.io/devcontainers/features/go:1
+Feature: ghcr.io/devcontainers/features/go:1
+go: download failed
+Error: building at STEP "RUN echo ...": exit status 1
+`,
+			want: "ghcr.io/devcontainers/features/go:1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var d FailureDetector
+			fmt.Fprint(&d, tt.output)
+			if got := d.FailedFeature(); got != tt.want {
+				t.Errorf("FailedFeature() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFailureDetector_SplitWrites(t *testing.T) {
+	var d FailureDetector
+	line := "#9 0.201 ### crib feature: ghcr.io/devcontainers/features/node:1\r\n#9 DONE 1.0s\n"
+	for i := range len(line) {
+		fmt.Fprint(&d, line[i:i+1])
+	}
+	if got := d.FailedFeature(); got != "ghcr.io/devcontainers/features/node:1" {
+		t.Errorf("FailedFeature() = %q", got)
+	}
+}
+
+func TestFailureDetector_UnterminatedLine(t *testing.T) {
+	var d FailureDetector
+	fmt.Fprint(&d, "### crib feature: ./tool")
+	if got := d.FailedFeature(); got != "./tool" {
+		t.Errorf("FailedFeature() = %q, want ./tool", got)
+	}
+}
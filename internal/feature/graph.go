@@ -3,7 +3,8 @@ package feature
 import (
 	"fmt"
 	"maps"
-	"sort"
+	"slices"
+	"strings"
 )
 
 // Graph is a generic directed acyclic graph that supports topological sorting
@@ -61,11 +62,36 @@ func (g *Graph[T]) HasNode(key string) bool {
 	return ok
 }
 
+// Ancestors returns the keys of every node that must come before key,
+// directly or transitively, in sorted order.
+func (g *Graph[T]) Ancestors(key string) []string {
+	seen := make(map[string]bool)
+	var visit func(string)
+	visit = func(k string) {
+		for from, tos := range g.edges {
+			if tos[k] && !seen[from] {
+				seen[from] = true
+				visit(from)
+			}
+		}
+	}
+	visit(key)
+	delete(seen, key)
+	return slices.Sorted(maps.Keys(seen))
+}
+
 // Sort returns nodes in topological order using Kahn's algorithm.
 // When multiple nodes have zero in-degree, they are processed in sorted
 // key order for deterministic output. Returns an error if the graph
 // contains a cycle.
 func (g *Graph[T]) Sort() ([]T, error) {
+	return g.SortFunc(strings.Compare)
+}
+
+// SortFunc is like Sort, but when multiple nodes have zero in-degree they
+// are processed in the order given by cmp, which compares node keys. Edges
+// always win over cmp. The error for a cycle names the nodes involved.
+func (g *Graph[T]) SortFunc(cmp func(a, b string) int) ([]T, error) {
 	if len(g.nodes) == 0 {
 		return nil, nil
 	}
@@ -74,17 +100,16 @@ func (g *Graph[T]) Sort() ([]T, error) {
 	inDegree := make(map[string]int, len(g.inDegree))
 	maps.Copy(inDegree, g.inDegree)
 
-	// Kahn's algorithm with sorted queue for deterministic output.
-	// The queue uses slice reslicing (O(1) pop) and sorted merge (O(n) insert).
-	// This is O(V*E) worst case but V is typically <10 for DevContainer Features.
-	// Collect initial zero-degree nodes, sorted for determinism.
+	// Kahn's algorithm with an ordered queue for deterministic output.
+	// Re-sorting the queue after each step is O(V^2 log V) worst case, but
+	// V is typically <10 for DevContainer Features.
 	var queue []string
 	for key := range g.nodes {
 		if inDegree[key] == 0 {
 			queue = append(queue, key)
 		}
 	}
-	sort.Strings(queue)
+	slices.SortFunc(queue, cmp)
 
 	var result []T
 	for len(queue) > 0 {
@@ -92,42 +117,27 @@ func (g *Graph[T]) Sort() ([]T, error) {
 		key := queue[0]
 		queue = queue[1:]
 		result = append(result, g.nodes[key])
+		delete(inDegree, key)
 
-		// Collect neighbors whose in-degree drops to zero.
-		var newZero []string
+		// Queue neighbors whose in-degree drops to zero.
+		added := false
 		for neighbor := range g.edges[key] {
 			inDegree[neighbor]--
 			if inDegree[neighbor] == 0 {
-				newZero = append(newZero, neighbor)
+				queue = append(queue, neighbor)
+				added = true
 			}
 		}
-
-		// Sort new zero-degree nodes and insert into queue in sorted position.
-		sort.Strings(newZero)
-		queue = sortedMerge(queue, newZero)
+		if added {
+			slices.SortFunc(queue, cmp)
+		}
 	}
 
 	if len(result) != len(g.nodes) {
-		return nil, fmt.Errorf("circular dependency detected")
+		// Nodes left over are on a cycle or wait on one.
+		stuck := slices.Sorted(maps.Keys(inDegree))
+		return nil, fmt.Errorf("circular dependency detected among %s", strings.Join(stuck, ", "))
 	}
 
 	return result, nil
 }
-
-// sortedMerge merges two sorted slices into a single sorted slice.
-func sortedMerge(a, b []string) []string {
-	result := make([]string, 0, len(a)+len(b))
-	i, j := 0, 0
-	for i < len(a) && j < len(b) {
-		if a[i] <= b[j] {
-			result = append(result, a[i])
-			i++
-		} else {
-			result = append(result, b[j])
-			j++
-		}
-	}
-	result = append(result, a[i:]...)
-	result = append(result, b[j:]...)
-	return result
-}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"math/rand"
 	"slices"
+	"strings"
 	"testing"
 )
 
@@ -206,6 +207,44 @@ func TestGraphSortCircular(t *testing.T) {
 	}
 }
 
+func TestGraphSortFunc(t *testing.T) {
+	g := NewGraph[string]()
+	for _, k := range []string{"a", "b", "c", "d"} {
+		g.AddNode(k, k)
+	}
+	if err := g.AddEdge("c", "a"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reverse key order, but c must still precede a.
+	result, err := g.SortFunc(func(x, y string) int { return strings.Compare(y, x) })
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := strings.Join(result, ""); got != "dcba" {
+		t.Errorf("SortFunc = %q, want dcba", got)
+	}
+}
+
+func TestGraphAncestors(t *testing.T) {
+	g := NewGraph[string]()
+	for _, k := range []string{"a", "b", "c", "d"} {
+		g.AddNode(k, k)
+	}
+	for _, e := range [][2]string{{"a", "b"}, {"b", "c"}, {"d", "c"}} {
+		if err := g.AddEdge(e[0], e[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := g.Ancestors("c"); !slices.Equal(got, []string{"a", "b", "d"}) {
+		t.Errorf("Ancestors(c) = %v, want [a b d]", got)
+	}
+	if got := g.Ancestors("a"); len(got) != 0 {
+		t.Errorf("Ancestors(a) = %v, want none", got)
+	}
+}
+
 func TestGraphSortEmpty(t *testing.T) {
 	g := NewGraph[string]()
 	result, err := g.Sort()
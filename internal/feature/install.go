@@ -6,23 +6,35 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 )
 
 // PrepareContext creates the feature installation directory within the build
 // context. It copies feature folders as numbered directories (0, 1, 2, ...),
 // writes environment files, and generates wrapper installation scripts.
-// Returns the path to the features folder within the context.
+// Files that are never needed to install a feature (see prunedFeatureEntries)
+// are left out to keep the build context small. On error, the partially
+// staged folder is removed. Returns the path to the features folder within
+// the context.
 func PrepareContext(contextPath string, features []*FeatureSet, containerUser, remoteUser string) (string, error) {
 	featuresDir := filepath.Join(contextPath, ContextFeatureFolder)
+	if err := prepareContext(featuresDir, features, containerUser, remoteUser); err != nil {
+		_ = os.RemoveAll(featuresDir)
+		return "", err
+	}
+	return featuresDir, nil
+}
 
+// prepareContext stages the features into featuresDir for PrepareContext.
+func prepareContext(featuresDir string, features []*FeatureSet, containerUser, remoteUser string) error {
 	// Clean up any existing features directory.
 	if err := os.RemoveAll(featuresDir); err != nil {
-		return "", fmt.Errorf("removing existing features dir: %w", err)
+		return fmt.Errorf("removing existing features dir: %w", err)
 	}
 
 	if err := os.MkdirAll(featuresDir, 0o755); err != nil {
-		return "", fmt.Errorf("creating features dir: %w", err)
+		return fmt.Errorf("creating features dir: %w", err)
 	}
 
 	// Write builtin env file with container/remote user info.
@@ -33,14 +45,14 @@ func PrepareContext(contextPath string, features []*FeatureSet, containerUser, r
 	)
 	builtinEnvPath := filepath.Join(featuresDir, builtinEnvFile)
 	if err := os.WriteFile(builtinEnvPath, []byte(builtinEnv), 0o644); err != nil {
-		return "", fmt.Errorf("writing builtin env: %w", err)
+		return fmt.Errorf("writing builtin env: %w", err)
 	}
 
 	// Copy each feature and generate its installation files.
 	for i, f := range features {
 		destDir := filepath.Join(featuresDir, fmt.Sprintf("%d", i))
-		if err := copyDir(f.Folder, destDir); err != nil {
-			return "", fmt.Errorf("copying feature %q: %w", f.ConfigID, err)
+		if err := copyFeatureDir(f.Folder, destDir); err != nil {
+			return fmt.Errorf("copying feature %q: %w", f.ConfigID, err)
 		}
 
 		// Write per-feature env file with option variables.
@@ -51,18 +63,18 @@ func PrepareContext(contextPath string, features []*FeatureSet, containerUser, r
 		}
 		envPath := filepath.Join(destDir, featureEnvFile)
 		if err := os.WriteFile(envPath, []byte(envContent), 0o644); err != nil {
-			return "", fmt.Errorf("writing feature env for %q: %w", f.ConfigID, err)
+			return fmt.Errorf("writing feature env for %q: %w", f.ConfigID, err)
 		}
 
 		// Write wrapper installation script.
 		script := installWrapperScript(f.ConfigID, f.Config, envVars)
 		scriptPath := filepath.Join(destDir, "devcontainer-features-install.sh")
 		if err := os.WriteFile(scriptPath, []byte(script), 0o755); err != nil {
-			return "", fmt.Errorf("writing install script for %q: %w", f.ConfigID, err)
+			return fmt.Errorf("writing install script for %q: %w", f.ConfigID, err)
 		}
 	}
 
-	return featuresDir, nil
+	return nil
 }
 
 // installWrapperScript generates a shell script that sources environment
@@ -88,7 +100,13 @@ func installWrapperScript(featureID string, fc *FeatureConfig, envVars []string)
 	b.WriteString(". /tmp/build-features/devcontainer-features.builtin.env\n")
 	b.WriteString("\n")
 
-	// Export feature-specific env vars.
+	// Export feature-specific env vars. Each feature installs in its own
+	// RUN, so these never reach later features; declared options without
+	// a value are unset so install.sh doesn't pick up a same-named ENV
+	// from the base image or an earlier feature's containerEnv.
+	if unset := unsetOptionVars(fc, envVars); len(unset) > 0 {
+		fmt.Fprintf(&b, "unset %s\n", strings.Join(unset, " "))
+	}
 	for _, envVar := range envVars {
 		fmt.Fprintf(&b, "export %s\n", envVar)
 	}
@@ -106,13 +124,51 @@ func installWrapperScript(featureID string, fc *FeatureConfig, envVars []string)
 	return b.String()
 }
 
+// unsetOptionVars returns the sorted variable names of fc's options that
+// have no value in envVars.
+func unsetOptionVars(fc *FeatureConfig, envVars []string) []string {
+	set := make(map[string]bool, len(envVars))
+	for _, envVar := range envVars {
+		name, _, _ := strings.Cut(envVar, "=")
+		set[name] = true
+	}
+	var unset []string
+	for id := range fc.Options {
+		if name := safeID(id); !set[name] && !slices.Contains(unset, name) {
+			unset = append(unset, name)
+		}
+	}
+	slices.Sort(unset)
+	return unset
+}
+
 // escapeQuotes escapes single quotes for safe inclusion in shell strings.
 func escapeQuotes(s string) string {
 	return strings.ReplaceAll(s, "'", "'\\''")
 }
 
-// copyDir recursively copies a directory tree from src to dst.
-func copyDir(src, dst string) error {
+// prunedFeatureEntries lists files and directories left out when staging a
+// feature. Features don't declare which files their install.sh uses, so
+// only entries that can't be part of an install are skipped: VCS metadata,
+// OS clutter, and crib's own generated build files (a local feature folder
+// may contain the build context).
+var prunedFeatureEntries = map[string]bool{
+	".git":               true,
+	".hg":                true,
+	".svn":               true,
+	".DS_Store":          true,
+	ContextFeatureFolder: true,
+	".crib-Dockerfile":   true,
+}
+
+// copyFeatureDir copies a feature folder, skipping prunedFeatureEntries.
+func copyFeatureDir(src, dst string) error {
+	return copyDir(src, dst, func(name string) bool { return prunedFeatureEntries[name] })
+}
+
+// copyDir recursively copies a directory tree from src to dst, skipping
+// files and directories whose base name satisfies skip.
+func copyDir(src, dst string, skip func(name string) bool) error {
 	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err
@@ -122,6 +178,12 @@ func copyDir(src, dst string) error {
 		if err != nil {
 			return err
 		}
+		if relPath != "." && skip(d.Name()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
 		destPath := filepath.Join(dst, relPath)
 
 		if d.IsDir() {
@@ -1,8 +1,11 @@
 package feature
 
 import (
+	"encoding/json"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"testing"
@@ -146,6 +149,41 @@ func TestPrepareContextFeatureEnv(t *testing.T) {
 	}
 }
 
+func TestPrepareContextFeatureEnvNonStringOptions(t *testing.T) {
+	contextDir := t.TempDir()
+	featureDir := setupFeatureDir(t, "node")
+
+	// Options as decoded from devcontainer.json: numbers are float64.
+	var opts map[string]any
+	if err := json.Unmarshal([]byte(`{"version": 18, "installYarn": true, "maxMem": 2000000}`), &opts); err != nil {
+		t.Fatal(err)
+	}
+	features := []*FeatureSet{
+		{
+			ConfigID: "node",
+			Folder:   featureDir,
+			Config:   &FeatureConfig{ID: "node"},
+			Options:  opts,
+		},
+	}
+
+	featuresPath, err := PrepareContext(contextDir, features, "root", "root")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(featuresPath, "0", featureEnvFile))
+	if err != nil {
+		t.Fatalf("reading feature env: %v", err)
+	}
+	content := string(data)
+	for _, want := range []string{`VERSION="18"`, `INSTALLYARN="true"`, `MAXMEM="2000000"`} {
+		if !strings.Contains(content, want) {
+			t.Errorf("missing %s, got:\n%s", want, content)
+		}
+	}
+}
+
 func TestPrepareContextWrapperScript(t *testing.T) {
 	contextDir := t.TempDir()
 	featureDir := setupFeatureDir(t, "test-feature")
@@ -273,6 +311,91 @@ func TestPrepareContextMultipleFeatures(t *testing.T) {
 	}
 }
 
+func TestPrepareContextPrunesUnusedFiles(t *testing.T) {
+	contextDir := t.TempDir()
+	featureDir := setupFeatureDir(t, "test-feature")
+	for rel, content := range map[string]string{
+		"devcontainer-feature.json":   `{"id": "test-feature"}`,
+		"scripts/helper.sh":           "#!/bin/sh\n",
+		".git/config":                 "[core]\n",
+		".DS_Store":                   "junk",
+		"scripts/.DS_Store":           "junk",
+		".crib-features/0/install.sh": "stale",
+		".crib-Dockerfile":            "FROM stale",
+	} {
+		path := filepath.Join(featureDir, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	features := []*FeatureSet{
+		{
+			ConfigID: "test-feature",
+			Folder:   featureDir,
+			Config:   &FeatureConfig{ID: "test-feature"},
+		},
+	}
+
+	featuresPath, err := PrepareContext(contextDir, features, "root", "root")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var got []string
+	stageDir := filepath.Join(featuresPath, "0")
+	err = filepath.WalkDir(stageDir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, _ := filepath.Rel(stageDir, path)
+		got = append(got, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"devcontainer-feature.json",
+		"devcontainer-features-install.sh",
+		"devcontainer-features.env",
+		"install.sh",
+		"scripts/helper.sh",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("staged files = %v, want %v", got, want)
+	}
+}
+
+func TestPrepareContextRemovesPartialContextOnError(t *testing.T) {
+	contextDir := t.TempDir()
+	featureDir := setupFeatureDir(t, "feature-a")
+
+	features := []*FeatureSet{
+		{
+			ConfigID: "feature-a",
+			Folder:   featureDir,
+			Config:   &FeatureConfig{ID: "feature-a"},
+		},
+		{
+			ConfigID: "missing",
+			Folder:   filepath.Join(t.TempDir(), "does-not-exist"),
+			Config:   &FeatureConfig{ID: "missing"},
+		},
+	}
+
+	if _, err := PrepareContext(contextDir, features, "root", "root"); err == nil {
+		t.Fatal("expected error for missing feature folder")
+	}
+	if _, err := os.Stat(filepath.Join(contextDir, ContextFeatureFolder)); !os.IsNotExist(err) {
+		t.Errorf("partial features dir should be removed, stat err = %v", err)
+	}
+}
+
 func TestEscapeQuotes(t *testing.T) {
 	tests := []struct {
 		input string
@@ -312,6 +435,73 @@ func TestInstallWrapperScriptIgnoresEntrypoint(t *testing.T) {
 	}
 }
 
+func TestPrepareContextOptionsScopedPerFeature(t *testing.T) {
+	contextDir := t.TempDir()
+	versionOption := map[string]FeatureOption{"version": {Default: "latest"}}
+	features := []*FeatureSet{
+		{
+			ConfigID: "feature-a",
+			Folder:   setupEchoFeatureDir(t, "feature-a"),
+			Config:   &FeatureConfig{ID: "feature-a", Options: versionOption},
+			Options:  map[string]any{"version": "1.2"},
+		},
+		{
+			ConfigID: "feature-b",
+			Folder:   setupEchoFeatureDir(t, "feature-b"),
+			Config:   &FeatureConfig{ID: "feature-b", Options: versionOption},
+			Options:  map[string]any{"version": "3.4"},
+		},
+		{
+			// Declares version without a default and gets no value.
+			ConfigID: "feature-c",
+			Folder:   setupEchoFeatureDir(t, "feature-c"),
+			Config:   &FeatureConfig{ID: "feature-c", Options: map[string]FeatureOption{"version": {}}},
+		},
+	}
+
+	featuresPath, err := PrepareContext(contextDir, features, "root", "root")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Run each wrapper the way its RUN layer would, with VERSION already in
+	// the environment as if an earlier layer had set it with ENV.
+	for i, want := range []string{"VERSION=1.2", "VERSION=3.4", "VERSION=<unset>"} {
+		dir := filepath.Join(featuresPath, strconv.Itoa(i))
+		data, err := os.ReadFile(filepath.Join(dir, "devcontainer-features-install.sh"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		script := strings.ReplaceAll(string(data), "/tmp/build-features/", featuresPath+"/")
+		scriptPath := filepath.Join(dir, "run.sh")
+		if err := os.WriteFile(scriptPath, []byte(script), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		cmd := exec.Command("sh", scriptPath)
+		cmd.Env = append(os.Environ(), "VERSION=leaked")
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("feature %d: %v\n%s", i, err, out)
+		}
+		if !strings.Contains(string(out), want+"\n") {
+			t.Errorf("feature %d saw %q, want %s", i, out, want)
+		}
+	}
+}
+
+// setupEchoFeatureDir creates a feature whose install.sh prints the VERSION
+// option it was given.
+func setupEchoFeatureDir(t *testing.T, name string) string {
+	t.Helper()
+	dir := setupFeatureDir(t, name)
+	installScript := "#!/bin/sh\necho \"VERSION=${VERSION-<unset>}\"\n"
+	if err := os.WriteFile(filepath.Join(dir, "install.sh"), []byte(installScript), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
 // setupFeatureDir creates a temporary feature directory with a minimal install.sh.
 func setupFeatureDir(t *testing.T, name string) string {
 	t.Helper()
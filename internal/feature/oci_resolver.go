@@ -23,6 +23,9 @@ type OCIResolver struct {
 
 // Resolve downloads and caches the feature at the given OCI ref.
 // configDir is unused for OCI refs but kept for interface compatibility.
+// A digest-pinned ref (e.g. "ghcr.io/org/feature@sha256:...") is fetched by
+// that digest without looking up any tag, and the pulled manifest must
+// match it.
 func (r *OCIResolver) Resolve(ref, configDir string) (string, error) {
 	return r.resolveWithOptions(ref, configDir, remote.WithAuthFromKeychain(authn.DefaultKeychain))
 }
@@ -45,6 +48,13 @@ func (r *OCIResolver) resolveWithOptions(ref, _ string, opts ...remote.Option) (
 	if err != nil {
 		return "", fmt.Errorf("pulling OCI image %q: %w", ref, err)
 	}
+	digest, err := img.Digest()
+	if err != nil {
+		return "", fmt.Errorf("computing digest of OCI image %q: %w", ref, err)
+	}
+	if pinned, ok := parsed.(name.Digest); ok && pinned.DigestStr() != digest.String() {
+		return "", fmt.Errorf("OCI feature %q: registry returned digest %s", ref, digest)
+	}
 
 	path, err := r.Cache.Store(key, func(dir string) error {
 		return extractOCIImage(img, dir)
@@ -59,9 +69,18 @@ func (r *OCIResolver) resolveWithOptions(ref, _ string, opts ...remote.Option) (
 		return "", fmt.Errorf("OCI feature %q missing %s after extraction", ref, FeatureFileName)
 	}
 
+	if err := r.Cache.SetDigest(key, digest.String()); err != nil {
+		return "", fmt.Errorf("recording digest of OCI feature %q: %w", ref, err)
+	}
 	return path, nil
 }
 
+// Digest returns the manifest digest the cached copy of ref was pulled
+// from, or "" when it is unknown.
+func (r *OCIResolver) Digest(ref string) string {
+	return r.Cache.Digest(ociCacheKey(ref))
+}
+
 // extractOCIImage extracts all layers of img into dir, merging their contents.
 func extractOCIImage(img v1.Image, dir string) error {
 	layers, err := img.Layers()
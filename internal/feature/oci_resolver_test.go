@@ -7,6 +7,7 @@ import (
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/google/go-containerregistry/pkg/authn"
@@ -122,3 +123,95 @@ func TestOCIResolverDownload(t *testing.T) {
 		t.Errorf("feature file content = %q, want %q", string(got), featureJSON)
 	}
 }
+
+// pushFeatureImage pushes a feature image with featureJSON to the local
+// registry as ref and returns its manifest digest.
+func pushFeatureImage(t *testing.T, srv *httptest.Server, ref, featureJSON string) v1.Hash {
+	t.Helper()
+	img := buildFeatureImage(t, featureJSON)
+	parsed, err := name.ParseReference(ref, name.Insecure)
+	if err != nil {
+		t.Fatalf("parsing ref: %v", err)
+	}
+	if err := remote.Write(parsed, img,
+		remote.WithTransport(srv.Client().Transport),
+		remote.WithAuth(authn.Anonymous),
+	); err != nil {
+		t.Fatalf("pushing image: %v", err)
+	}
+	digest, err := img.Digest()
+	if err != nil {
+		t.Fatalf("computing digest: %v", err)
+	}
+	return digest
+}
+
+func TestOCIResolverDigestPinned(t *testing.T) {
+	srv := httptest.NewServer(registry.New())
+	t.Cleanup(srv.Close)
+	host := srv.Listener.Addr().String()
+
+	// The tag moves on after the pinned version was published.
+	const pinnedJSON = `{"id":"go","version":"1.0.0"}`
+	pinned := pushFeatureImage(t, srv, host+"/features/go:1", pinnedJSON)
+	latest := pushFeatureImage(t, srv, host+"/features/go:1", `{"id":"go","version":"1.1.0"}`)
+	if pinned == latest {
+		t.Fatal("test images should differ")
+	}
+
+	cache := NewFeatureCacheAt(t.TempDir())
+	resolver := &OCIResolver{Cache: cache}
+	opts := []remote.Option{remote.WithTransport(srv.Client().Transport), remote.WithAuth(authn.Anonymous)}
+
+	for _, ref := range []string{
+		host + "/features/go@" + pinned.String(),
+		host + "/features/go:1@" + pinned.String(),
+	} {
+		t.Run(ref, func(t *testing.T) {
+			path, err := resolver.resolveWithOptions(ref, "", opts...)
+			if err != nil {
+				t.Fatalf("Resolve failed: %v", err)
+			}
+			got, err := os.ReadFile(filepath.Join(path, FeatureFileName))
+			if err != nil {
+				t.Fatalf("reading extracted feature file: %v", err)
+			}
+			if string(got) != pinnedJSON {
+				t.Errorf("feature file content = %q, want the pinned %q", got, pinnedJSON)
+			}
+			if d := resolver.Digest(ref); d != pinned.String() {
+				t.Errorf("recorded digest = %q, want %q", d, pinned)
+			}
+		})
+	}
+
+	// The tag resolves to the latest image and records its digest.
+	tagRef := host + "/features/go:1"
+	if _, err := resolver.resolveWithOptions(tagRef, "", opts...); err != nil {
+		t.Fatalf("Resolve tag failed: %v", err)
+	}
+	if d := resolver.Digest(tagRef); d != latest.String() {
+		t.Errorf("recorded tag digest = %q, want %q", d, latest)
+	}
+}
+
+func TestOCIResolverDigestPinnedUnknown(t *testing.T) {
+	srv := httptest.NewServer(registry.New())
+	t.Cleanup(srv.Close)
+	host := srv.Listener.Addr().String()
+	pushFeatureImage(t, srv, host+"/features/go:1", `{"id":"go"}`)
+
+	cache := NewFeatureCacheAt(t.TempDir())
+	resolver := &OCIResolver{Cache: cache}
+	ref := host + "/features/go@sha256:" + strings.Repeat("0", 64)
+	_, err := resolver.resolveWithOptions(ref, "",
+		remote.WithTransport(srv.Client().Transport),
+		remote.WithAuth(authn.Anonymous),
+	)
+	if err == nil {
+		t.Fatal("expected an error for a digest the registry does not have")
+	}
+	if _, ok := cache.Get(ociCacheKey(ref)); ok {
+		t.Error("failed pull should not leave a cache entry")
+	}
+}
@@ -1,9 +1,11 @@
 package feature
 
 import (
+	"encoding/json"
 	"fmt"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -50,7 +52,7 @@ func mergeOptions(fc *FeatureConfig, userOptions any) map[string]string {
 	switch opts := userOptions.(type) {
 	case map[string]any:
 		for k, v := range opts {
-			result[k] = fmt.Sprintf("%v", v)
+			result[k] = optionString(v)
 		}
 	case string:
 		result["version"] = opts
@@ -58,3 +60,21 @@ func mergeOptions(fc *FeatureConfig, userOptions any) map[string]string {
 
 	return result
 }
+
+// optionString renders a user-provided option value the way feature install
+// scripts expect it. JSON numbers decode as float64, which %v would print in
+// scientific notation for large values (e.g. 1e+06).
+func optionString(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case json.Number:
+		return v.String()
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
@@ -1,6 +1,7 @@
 package feature
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/fgrehm/crib/internal/config"
@@ -123,3 +124,24 @@ func TestFeatureEnvVarsSorted(t *testing.T) {
 		}
 	}
 }
+
+func TestOptionString(t *testing.T) {
+	tests := []struct {
+		in   any
+		want string
+	}{
+		{"3.12", "3.12"},
+		{true, "true"},
+		{false, "false"},
+		{float64(18), "18"},
+		{float64(1.5), "1.5"},
+		{float64(1e21), "1000000000000000000000"},
+		{json.Number("20"), "20"},
+		{42, "42"},
+	}
+	for _, tt := range tests {
+		if got := optionString(tt.in); got != tt.want {
+			t.Errorf("optionString(%#v) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
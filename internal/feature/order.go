@@ -6,8 +6,10 @@ import (
 )
 
 // OrderFeatures sorts features respecting hard dependencies (DependsOn) and
-// soft dependencies (InstallsAfter). Features listed in overrideOrder are
-// moved to the front in that order, while still respecting hard dependencies.
+// soft dependencies (InstallsAfter). Features listed in overrideOrder install
+// as early as their dependencies allow, in that order; the rest follow in ID
+// order. It returns an error if the dependencies form a cycle or if
+// overrideOrder asks for an order they rule out.
 func OrderFeatures(features []*FeatureSet, overrideOrder []string) ([]*FeatureSet, error) {
 	if len(features) == 0 {
 		return nil, nil
@@ -29,15 +31,19 @@ func OrderFeatures(features []*FeatureSet, overrideOrder []string) ([]*FeatureSe
 	}
 	addSoftDependencies(g, features, lookup, byID)
 
-	sorted, err := g.Sort()
+	rank, err := overrideRanks(g, overrideOrder, lookup)
 	if err != nil {
-		return nil, fmt.Errorf("ordering features: %w", err)
+		return nil, err
 	}
-
-	if len(overrideOrder) > 0 {
-		sorted = applyOverrideOrder(sorted, overrideOrder)
+	sorted, err := g.SortFunc(func(a, b string) int {
+		if rank[a] != rank[b] {
+			return rank[a] - rank[b]
+		}
+		return strings.Compare(a, b)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ordering features: %w", err)
 	}
-
 	return sorted, nil
 }
 
@@ -97,32 +103,45 @@ func hasHardDep(f *FeatureSet, depID string, lookup map[string]string) bool {
 	return false
 }
 
-// applyOverrideOrder moves features matching overrideOrder IDs to the front,
-// preserving their relative order. Features not in overrideOrder follow in
-// their original sorted order.
-func applyOverrideOrder(features []*FeatureSet, overrideOrder []string) []*FeatureSet {
-	indexed := make(map[string]*FeatureSet, len(features))
-	for _, f := range features {
-		indexed[f.ConfigID] = f
+// overrideRanks returns the install priority of every feature in g for
+// overrideOrder: a listed feature ranks at its position in the list, and
+// anything a listed feature installs after inherits that rank so it is pulled
+// forward with it. Unlisted features rank last. IDs match with or without a
+// version, like dependencies do. It returns an error if a feature must
+// install after one listed later.
+func overrideRanks(g *Graph[*FeatureSet], overrideOrder []string, lookup map[string]string) (map[string]int, error) {
+	listed := make(map[string]int, len(overrideOrder))
+	var order []string
+	for i, id := range overrideOrder {
+		if !g.HasNode(id) {
+			var ok bool
+			if id, ok = lookup[normalizeID(id)]; !ok {
+				continue
+			}
+		}
+		if _, dup := listed[id]; !dup {
+			listed[id] = i
+			order = append(order, id)
+		}
 	}
 
-	overridden := make(map[string]bool, len(overrideOrder))
-	var front []*FeatureSet
-	for _, id := range overrideOrder {
-		if f, ok := indexed[id]; ok {
-			front = append(front, f)
-			overridden[id] = true
+	rank := make(map[string]int, len(g.nodes))
+	for id := range g.nodes {
+		rank[id] = len(overrideOrder)
+		if r, ok := listed[id]; ok {
+			rank[id] = r
 		}
 	}
-
-	var rest []*FeatureSet
-	for _, f := range features {
-		if !overridden[f.ConfigID] {
-			rest = append(rest, f)
+	for _, id := range order {
+		r := listed[id]
+		for _, dep := range g.Ancestors(id) {
+			if depRank, ok := listed[dep]; ok && depRank > r {
+				return nil, fmt.Errorf("overrideFeatureInstallOrder lists %q before %q, but %q installs after %q (dependsOn or installsAfter)", id, dep, id, dep)
+			}
+			rank[dep] = min(rank[dep], r)
 		}
 	}
-
-	return append(front, rest...)
+	return rank, nil
 }
 
 // normalizeID strips version tags (@digest or :tag) from OCI feature
@@ -1,6 +1,7 @@
 package feature
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -156,6 +157,104 @@ func TestOrderFeaturesOverrideNonexistent(t *testing.T) {
 	assertOrder(t, result, want)
 }
 
+func TestOrderFeaturesInstallsAfterWithPartialOverride(t *testing.T) {
+	const commonUtils = "ghcr.io/devcontainers/features/common-utils:2"
+	tests := []struct {
+		name     string
+		override []string
+		want     []string
+	}{
+		{"no override", nil, []string{"aaa", commonUtils, "node", "zsh"}},
+		{"dependent listed", []string{"node"}, []string{commonUtils, "node", "aaa", "zsh"}},
+		{"dependent listed first", []string{"node", "zsh"}, []string{commonUtils, "node", "zsh", "aaa"}},
+		{"dependency listed without version", []string{"ghcr.io/devcontainers/features/common-utils", "node"}, []string{commonUtils, "node", "aaa", "zsh"}},
+		{"unrelated listed", []string{"zsh"}, []string{"zsh", "aaa", commonUtils, "node"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			features := []*FeatureSet{
+				makeFeatureSet("node", nil, []string{"ghcr.io/devcontainers/features/common-utils"}),
+				makeFeatureSet(commonUtils, nil, nil),
+				makeFeatureSet("zsh", nil, nil),
+				makeFeatureSet("aaa", nil, nil),
+			}
+
+			result, err := OrderFeatures(features, tt.override)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			assertOrder(t, result, tt.want)
+		})
+	}
+}
+
+func TestOrderFeaturesInstallsAfterTransitive(t *testing.T) {
+	// app installs after lib, which depends on base. Listing app alone pulls
+	// both forward.
+	features := []*FeatureSet{
+		makeFeatureSet("app", nil, []string{"lib"}),
+		makeFeatureSet("lib", map[string]any{"base": map[string]any{}}, nil),
+		makeFeatureSet("base", nil, nil),
+		makeFeatureSet("aaa", nil, nil),
+	}
+
+	result, err := OrderFeatures(features, []string{"app"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertOrder(t, result, []string{"base", "lib", "app", "aaa"})
+}
+
+func TestOrderFeaturesOverrideConflict(t *testing.T) {
+	tests := []struct {
+		name     string
+		features []*FeatureSet
+	}{
+		{"installsAfter", []*FeatureSet{
+			makeFeatureSet("node", nil, []string{"common-utils"}),
+			makeFeatureSet("common-utils", nil, nil),
+		}},
+		{"dependsOn", []*FeatureSet{
+			makeFeatureSet("node", map[string]any{"common-utils": map[string]any{}}, nil),
+			makeFeatureSet("common-utils", nil, nil),
+		}},
+		{"transitive", []*FeatureSet{
+			makeFeatureSet("node", nil, []string{"lib"}),
+			makeFeatureSet("lib", nil, []string{"common-utils"}),
+			makeFeatureSet("common-utils", nil, nil),
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := OrderFeatures(tt.features, []string{"node", "common-utils"})
+			if err == nil {
+				t.Fatal("expected error for conflicting overrideFeatureInstallOrder")
+			}
+			for _, want := range []string{"overrideFeatureInstallOrder", `"node" before "common-utils"`} {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q does not mention %s", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestOrderFeaturesInstallsAfterCycle(t *testing.T) {
+	features := []*FeatureSet{
+		makeFeatureSet("a", nil, []string{"b"}),
+		makeFeatureSet("b", nil, []string{"a"}),
+		makeFeatureSet("c", nil, nil),
+	}
+
+	_, err := OrderFeatures(features, nil)
+	if err == nil {
+		t.Fatal("expected error for installsAfter cycle")
+	}
+	if !strings.Contains(err.Error(), "among a, b") {
+		t.Errorf("error %q should name the features on the cycle", err)
+	}
+}
+
 func TestOrderFeaturesEmpty(t *testing.T) {
 	result, err := OrderFeatures(nil, nil)
 	if err != nil {
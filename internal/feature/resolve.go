@@ -74,6 +74,15 @@ func (r *CompositeResolver) Resolve(ref, configDir string) (string, error) {
 	}
 }
 
+// Digest returns the manifest digest a resolved OCI feature was pulled
+// from, or "" for other kinds of refs and when the digest is unknown.
+func (r *CompositeResolver) Digest(ref string) string {
+	if !isOCIRef(ref) {
+		return ""
+	}
+	return r.OCI.Digest(ref)
+}
+
 // isOCIRef returns true for refs like "ghcr.io/org/repo:tag".
 // A ref is treated as OCI when it has no URL scheme, looks like host/path,
 // and the first path segment contains a dot or colon (indicating a hostname).
@@ -92,6 +92,7 @@ func TestIsOCIRef(t *testing.T) {
 		{"ghcr.io/devcontainers/features/go:1", true},
 		{"registry.example.com/org/repo:latest", true},
 		{"localhost:5000/features/go:1", true},
+		{"ghcr.io/devcontainers/features/go@sha256:0123abcd", true},
 		{"./features/node", false},
 		{"../features/node", false},
 		{"https://example.com/feature.tar.gz", false},
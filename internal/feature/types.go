@@ -37,6 +37,7 @@ type FeatureConfig struct {
 	DependsOn     DependsOn                `json:"dependsOn,omitempty"`
 	InstallsAfter []string                 `json:"installsAfter,omitempty"`
 	CapAdd        []string                 `json:"capAdd,omitempty"`
+	CapDrop       []string                 `json:"capDrop,omitempty"`
 	Init          *bool                    `json:"init,omitempty"`
 	Privileged    *bool                    `json:"privileged,omitempty"`
 	SecurityOpt   []string                 `json:"securityOpt,omitempty"`
@@ -59,6 +59,12 @@ type CribRC struct {
 
 	// Workspace is the per-project workspace section (env, mounts, run_args).
 	Workspace WorkspaceConfig `toml:"workspace"`
+
+	// Defaults holds default values for command-line flags, keyed by flag
+	// name (e.g. `hook-retries = 3`). A nested table keyed by command name
+	// (e.g. `[defaults.up]`) applies only to that command. Explicit flags
+	// on the command line always win.
+	Defaults map[string]any `toml:"defaults"`
 }
 
 // DotfilesRC mirrors DotfilesConfig plus a `dotfiles = "false"` kill switch
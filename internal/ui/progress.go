@@ -0,0 +1,231 @@
+package ui
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// ProgressMode selects how progress events are rendered.
+type ProgressMode string
+
+const (
+	// ProgressAuto uses a spinner on a TTY and plain lines otherwise.
+	ProgressAuto ProgressMode = "auto"
+	// ProgressPlain prints one dimmed line per event.
+	ProgressPlain ProgressMode = "plain"
+	// ProgressJSON emits one JSON object per event for machine consumption.
+	ProgressJSON ProgressMode = "json"
+)
+
+// ParseProgressMode validates a --progress flag value.
+func ParseProgressMode(s string) (ProgressMode, error) {
+	switch m := ProgressMode(s); m {
+	case ProgressAuto, ProgressPlain, ProgressJSON:
+		return m, nil
+	default:
+		return "", fmt.Errorf("invalid progress mode %q (want auto, plain, or json)", s)
+	}
+}
+
+// ProgressRenderer displays a sequence of progress events. Stop must be
+// called once the operation finishes to flush any pending output.
+type ProgressRenderer interface {
+	Event(phase, message string)
+
+	// Timing reports that a step took d. Only the JSON renderer shows
+	// timings as they happen; the others leave them to the final summary.
+	Timing(step string, d time.Duration)
+
+	// Writer wraps w so subprocess output written while the renderer is
+	// active does not collide with it.
+	Writer(w io.Writer) io.Writer
+
+	Stop()
+}
+
+// NewProgress returns a renderer for mode writing to the UI's output.
+// ProgressAuto falls back to plain output when stdout is not a terminal.
+// ProgressJSON keeps stdout for the JSON events alone: the UI's own output
+// and subprocess output go to its error output from then on.
+func (u *UI) NewProgress(mode ProgressMode) ProgressRenderer {
+	switch mode {
+	case ProgressJSON:
+		p := &jsonProgress{enc: json.NewEncoder(u.out), errOut: u.errOut}
+		u.out = u.errOut
+		return p
+	case ProgressAuto:
+		if u.isTTY {
+			return newSpinnerProgress(u, 100*time.Millisecond)
+		}
+	}
+	return &plainProgress{u: u}
+}
+
+// plainProgress prints each event as an indented, dimmed line. Events may
+// come from several goroutines (e.g. the waitFor timeout), so they are
+// serialized.
+type plainProgress struct {
+	mu sync.Mutex
+	u  *UI
+}
+
+func (p *plainProgress) Event(_, message string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.u.Dim("  " + message)
+}
+
+func (p *plainProgress) Timing(string, time.Duration) {}
+func (p *plainProgress) Writer(w io.Writer) io.Writer { return w }
+func (p *plainProgress) Stop()                        {}
+
+// jsonProgress emits {"event":"progress","phase":...,"message":...} lines,
+// and {"event":"timing","step":...,"duration_ms":...} lines for timings.
+type jsonProgress struct {
+	mu     sync.Mutex
+	enc    *json.Encoder
+	errOut io.Writer
+}
+
+type progressJSONEvent struct {
+	Event   string `json:"event"`
+	Phase   string `json:"phase,omitempty"`
+	Message string `json:"message"`
+}
+
+func (p *jsonProgress) Event(phase, message string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_ = p.enc.Encode(progressJSONEvent{Event: "progress", Phase: phase, Message: message})
+}
+
+type timingJSONEvent struct {
+	Event      string `json:"event"`
+	Step       string `json:"step"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+func (p *jsonProgress) Timing(step string, d time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	_ = p.enc.Encode(timingJSONEvent{Event: "timing", Step: step, DurationMS: d.Milliseconds()})
+}
+
+func (p *jsonProgress) Writer(io.Writer) io.Writer { return p.errOut }
+func (p *jsonProgress) Stop()                      {}
+
+// spinnerFrames are the animation frames for the TTY spinner.
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+// spinnerProgress animates the current event on a single line. When the next
+// event arrives, the previous message is committed as a dimmed line so the
+// scrollback still shows every step.
+type spinnerProgress struct {
+	u       *UI
+	mu      sync.Mutex
+	current string
+	frame   int
+	midLine bool // passthrough output left the cursor mid-line; don't repaint
+	done    chan struct{}
+	stopped sync.WaitGroup
+}
+
+func newSpinnerProgress(u *UI, interval time.Duration) *spinnerProgress {
+	s := &spinnerProgress{u: u, done: make(chan struct{})}
+	s.stopped.Add(1)
+	go s.loop(interval)
+	return s
+}
+
+func (s *spinnerProgress) loop(interval time.Duration) {
+	defer s.stopped.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			s.mu.Lock()
+			if s.current != "" && !s.midLine {
+				s.frame = (s.frame + 1) % len(spinnerFrames)
+				s.draw()
+			}
+			s.mu.Unlock()
+		}
+	}
+}
+
+// draw repaints the spinner line. Caller must hold s.mu.
+func (s *spinnerProgress) draw() {
+	_, _ = fmt.Fprintf(s.u.out, "\r\033[K  %s %s", spinnerFrames[s.frame], s.current)
+}
+
+// commit replaces the spinner line with a dimmed copy of the current
+// message. Caller must hold s.mu.
+func (s *spinnerProgress) commit() {
+	if s.current == "" {
+		return
+	}
+	if s.midLine {
+		_, _ = fmt.Fprintln(s.u.out)
+		s.midLine = false
+	}
+	_, _ = fmt.Fprint(s.u.out, "\r\033[K")
+	s.u.Dim("  " + s.current)
+	s.current = ""
+}
+
+func (s *spinnerProgress) Event(_, message string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.commit()
+	s.current = message
+	s.frame = 0
+	if !s.midLine {
+		s.draw()
+	}
+}
+
+func (s *spinnerProgress) Timing(string, time.Duration) {}
+
+func (s *spinnerProgress) Writer(w io.Writer) io.Writer {
+	return &spinnerWriter{s: s, w: w}
+}
+
+// spinnerWriter clears the spinner line before forwarding writes so
+// subprocess output lands on a clean line. The spinner is redrawn on the
+// next tick once the output ends with a newline.
+type spinnerWriter struct {
+	s *spinnerProgress
+	w io.Writer
+}
+
+func (sw *spinnerWriter) Write(b []byte) (int, error) {
+	sw.s.mu.Lock()
+	defer sw.s.mu.Unlock()
+	if len(b) == 0 {
+		return 0, nil
+	}
+	if sw.s.current != "" && !sw.s.midLine {
+		_, _ = fmt.Fprint(sw.s.u.out, "\r\033[K")
+	}
+	sw.s.midLine = b[len(b)-1] != '\n'
+	return sw.w.Write(b)
+}
+
+func (s *spinnerProgress) Stop() {
+	select {
+	case <-s.done:
+		return
+	default:
+		close(s.done)
+	}
+	s.stopped.Wait()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.commit()
+}
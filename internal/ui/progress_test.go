@@ -0,0 +1,179 @@
+package ui
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+type testEvent struct{ phase, message string }
+
+var testEvents = []testEvent{
+	{"build", "Building image..."},
+	{"create", "Creating container..."},
+	{"hooks", "Running postCreateCommand..."},
+}
+
+func renderEvents(p ProgressRenderer) {
+	for _, ev := range testEvents {
+		p.Event(ev.phase, ev.message)
+	}
+	p.Stop()
+}
+
+func TestParseProgressMode(t *testing.T) {
+	for _, s := range []string{"auto", "plain", "json"} {
+		if m, err := ParseProgressMode(s); err != nil || string(m) != s {
+			t.Errorf("ParseProgressMode(%q) = %q, %v", s, m, err)
+		}
+	}
+	if _, err := ParseProgressMode("fancy"); err == nil {
+		t.Error("expected error for unknown mode")
+	}
+}
+
+func TestProgress_Plain(t *testing.T) {
+	u, out, _ := newTestUI()
+	renderEvents(u.NewProgress(ProgressPlain))
+
+	want := "  Building image...\n  Creating container...\n  Running postCreateCommand...\n"
+	if got := out.String(); got != want {
+		t.Errorf("plain output = %q, want %q", got, want)
+	}
+}
+
+func TestProgress_PlainConcurrentEvents(t *testing.T) {
+	u, out, _ := newTestUI()
+	p := u.NewProgress(ProgressPlain)
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.Event("hooks", "Container ready.")
+		}()
+	}
+	wg.Wait()
+	p.Stop()
+
+	if got := strings.Count(out.String(), "  Container ready.\n"); got != 10 {
+		t.Errorf("got %d complete lines, want 10:\n%s", got, out.String())
+	}
+}
+
+func TestProgress_AutoNonTTYIsPlain(t *testing.T) {
+	u, out, _ := newTestUI()
+	p := u.NewProgress(ProgressAuto)
+	if _, ok := p.(*plainProgress); !ok {
+		t.Fatalf("auto on non-TTY = %T, want *plainProgress", p)
+	}
+	renderEvents(p)
+	if strings.Contains(out.String(), "\r") {
+		t.Errorf("non-TTY output should not contain carriage returns, got %q", out.String())
+	}
+}
+
+func TestProgress_JSON(t *testing.T) {
+	u, out, _ := newTestUI()
+	renderEvents(u.NewProgress(ProgressJSON))
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != len(testEvents) {
+		t.Fatalf("expected %d lines, got %d: %q", len(testEvents), len(lines), out.String())
+	}
+	for i, line := range lines {
+		var got map[string]string
+		if err := json.Unmarshal([]byte(line), &got); err != nil {
+			t.Fatalf("line %d not valid JSON: %v", i, err)
+		}
+		if got["event"] != "progress" || got["phase"] != testEvents[i].phase || got["message"] != testEvents[i].message {
+			t.Errorf("line %d = %v, want event=progress phase=%s message=%s", i, got, testEvents[i].phase, testEvents[i].message)
+		}
+	}
+}
+
+func TestProgress_JSONTiming(t *testing.T) {
+	u, out, _ := newTestUI()
+	u.NewProgress(ProgressJSON).Timing("postCreateCommand", 1500*time.Millisecond)
+
+	var got map[string]any
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatalf("not valid JSON: %v (%q)", err, out.String())
+	}
+	if got["event"] != "timing" || got["step"] != "postCreateCommand" || got["duration_ms"] != float64(1500) {
+		t.Errorf("timing = %v, want event=timing step=postCreateCommand duration_ms=1500", got)
+	}
+}
+
+func TestProgress_JSONMovesOtherOutputToStderr(t *testing.T) {
+	u, out, errOut := newTestUI()
+	p := u.NewProgress(ProgressJSON)
+	_, _ = io.WriteString(p.Writer(out), "Step 1/3 : FROM alpine\n")
+	p.Event("build", "Building image")
+	p.Stop()
+	u.Header("Timings")
+
+	var got map[string]string
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatalf("stdout is not a single JSON event: %v (%q)", err, out.String())
+	}
+	for _, want := range []string{"Step 1/3", "Timings"} {
+		if !strings.Contains(errOut.String(), want) {
+			t.Errorf("stderr = %q, want it to contain %q", errOut.String(), want)
+		}
+	}
+}
+
+func TestProgress_PlainIgnoresTiming(t *testing.T) {
+	u, out, _ := newTestUI()
+	u.NewProgress(ProgressPlain).Timing("build", time.Second)
+	if out.Len() != 0 {
+		t.Errorf("plain renderer printed a timing: %q", out.String())
+	}
+}
+
+func newTTYTestUI() (*UI, *bytes.Buffer) {
+	out := &bytes.Buffer{}
+	return &UI{out: out, errOut: &bytes.Buffer{}, isTTY: true, renderer: lipgloss.NewRenderer(out)}, out
+}
+
+func TestProgress_Spinner(t *testing.T) {
+	u, out := newTTYTestUI()
+	renderEvents(newSpinnerProgress(u, time.Hour))
+
+	got := out.String()
+	for _, ev := range testEvents {
+		// Each message is drawn with a spinner frame, then committed as a line.
+		if !strings.Contains(got, spinnerFrames[0]+" "+ev.message) {
+			t.Errorf("output missing spinner frame for %q: %q", ev.message, got)
+		}
+		if !strings.Contains(got, "  "+ev.message+"\n") {
+			t.Errorf("output missing committed line for %q: %q", ev.message, got)
+		}
+	}
+	if !strings.HasSuffix(got, "Running postCreateCommand...\n") {
+		t.Errorf("Stop should commit the last message, got %q", got)
+	}
+}
+
+func TestProgress_SpinnerWriterClearsLine(t *testing.T) {
+	u, out := newTTYTestUI()
+	p := newSpinnerProgress(u, time.Hour)
+	w := p.Writer(out)
+
+	p.Event("hooks", "Running postCreateCommand...")
+	_, _ = w.Write([]byte("added 42 packages\n"))
+	p.Stop()
+
+	got := out.String()
+	if !strings.Contains(got, "\r\033[Kadded 42 packages\n") {
+		t.Errorf("expected spinner line cleared before passthrough output, got %q", got)
+	}
+}
@@ -0,0 +1,118 @@
+package workspace
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// historyFile holds a workspace's operation log, one JSON entry per line.
+const historyFile = "history.jsonl"
+
+// maxHistoryEntries bounds how many operations are kept per workspace. Once
+// exceeded, the oldest entries are dropped.
+const maxHistoryEntries = 100
+
+// Outcomes recorded in HistoryEntry.Outcome.
+const (
+	HistoryOK    = "ok"
+	HistoryError = "error"
+)
+
+// HistoryEntry records one crib operation on a workspace.
+type HistoryEntry struct {
+	Time      time.Time     `json:"time"`
+	Operation string        `json:"operation"` // e.g. "up", "down", "rebuild", "restart"
+	Outcome   string        `json:"outcome"`   // HistoryOK or HistoryError
+	Duration  time.Duration `json:"duration"`
+	Detail    string        `json:"detail,omitempty"` // e.g. "recreated container"
+	Error     string        `json:"error,omitempty"`
+}
+
+// AppendHistory adds entry to the workspace's operation log. Entries are
+// appended in place; when the log grows past maxHistoryEntries it is
+// rewritten with only the most recent ones.
+func (s *Store) AppendHistory(id string, entry HistoryEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("marshaling history entry: %w", err)
+	}
+	data = append(data, '\n')
+
+	dir := s.WorkspaceDir(id)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("creating workspace directory: %w", err)
+	}
+	path := filepath.Join(dir, historyFile)
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("opening history: %w", err)
+	}
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("writing history: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("writing history: %w", err)
+	}
+	return truncateHistory(path)
+}
+
+// truncateHistory rewrites the log at path with its last maxHistoryEntries
+// lines once it holds more than that.
+func truncateHistory(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading history: %w", err)
+	}
+	lines := bytes.SplitAfter(data, []byte("\n"))
+	if len(lines) > 0 && len(lines[len(lines)-1]) == 0 {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) <= maxHistoryEntries {
+		return nil
+	}
+
+	kept := bytes.Join(lines[len(lines)-maxHistoryEntries:], nil)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, kept, 0o644); err != nil {
+		return fmt.Errorf("writing history: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("writing history: %w", err)
+	}
+	return nil
+}
+
+// History returns the workspace's recorded operations, oldest first. A
+// workspace without history returns nil. Lines that can't be parsed (e.g.
+// a write cut short by a crash) are skipped.
+func (s *Store) History(id string) ([]HistoryEntry, error) {
+	f, err := os.Open(filepath.Join(s.WorkspaceDir(id), historyFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading history: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	var entries []HistoryEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var entry HistoryEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading history: %w", err)
+	}
+	return entries, nil
+}
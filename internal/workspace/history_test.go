@@ -0,0 +1,103 @@
+package workspace
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHistory_AppendAndRead(t *testing.T) {
+	store := NewStoreAt(t.TempDir())
+	base := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	want := []HistoryEntry{
+		{Time: base, Operation: "up", Outcome: HistoryOK, Duration: 42 * time.Second},
+		{Time: base.Add(time.Hour), Operation: "restart", Outcome: HistoryOK, Detail: "recreated container"},
+		{Time: base.Add(2 * time.Hour), Operation: "down", Outcome: HistoryError, Error: "boom"},
+	}
+	for _, e := range want {
+		if err := store.AppendHistory("ws1", e); err != nil {
+			t.Fatalf("AppendHistory: %v", err)
+		}
+	}
+
+	got, err := store.History("ws1")
+	if err != nil {
+		t.Fatalf("History: %v", err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d entries, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if !got[i].Time.Equal(want[i].Time) || got[i].Operation != want[i].Operation ||
+			got[i].Outcome != want[i].Outcome || got[i].Duration != want[i].Duration ||
+			got[i].Detail != want[i].Detail || got[i].Error != want[i].Error {
+			t.Errorf("entry %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestHistory_Missing(t *testing.T) {
+	store := NewStoreAt(t.TempDir())
+	got, err := store.History("nope")
+	if err != nil || got != nil {
+		t.Errorf("History = %v, %v; want nil, nil", got, err)
+	}
+}
+
+func TestHistory_TruncatesToMostRecent(t *testing.T) {
+	store := NewStoreAt(t.TempDir())
+	base := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	total := maxHistoryEntries + 15
+	for i := range total {
+		e := HistoryEntry{Time: base.Add(time.Duration(i) * time.Minute), Operation: fmt.Sprintf("op%d", i), Outcome: HistoryOK}
+		if err := store.AppendHistory("ws1", e); err != nil {
+			t.Fatalf("AppendHistory: %v", err)
+		}
+	}
+
+	got, err := store.History("ws1")
+	if err != nil {
+		t.Fatalf("History: %v", err)
+	}
+	if len(got) != maxHistoryEntries {
+		t.Fatalf("got %d entries, want %d", len(got), maxHistoryEntries)
+	}
+	if first := got[0].Operation; first != fmt.Sprintf("op%d", total-maxHistoryEntries) {
+		t.Errorf("oldest kept = %s, want op%d", first, total-maxHistoryEntries)
+	}
+	if last := got[len(got)-1].Operation; last != fmt.Sprintf("op%d", total-1) {
+		t.Errorf("newest = %s, want op%d", last, total-1)
+	}
+	if _, err := os.Stat(filepath.Join(store.WorkspaceDir("ws1"), historyFile+".tmp")); !os.IsNotExist(err) {
+		t.Errorf("temporary file left behind: %v", err)
+	}
+}
+
+func TestHistory_SkipsCorruptLines(t *testing.T) {
+	store := NewStoreAt(t.TempDir())
+	if err := store.AppendHistory("ws1", HistoryEntry{Operation: "up", Outcome: HistoryOK}); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(store.WorkspaceDir("ws1"), historyFile)
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = f.WriteString("{\"operation\":\"do\n")
+	_ = f.Close()
+	if err := store.AppendHistory("ws1", HistoryEntry{Operation: "down", Outcome: HistoryOK}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := store.History("ws1")
+	if err != nil {
+		t.Fatalf("History: %v", err)
+	}
+	if len(got) != 2 || got[0].Operation != "up" || got[1].Operation != "down" {
+		t.Errorf("History = %+v, want up and down", got)
+	}
+}
@@ -0,0 +1,99 @@
+package workspace
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// hookLogTimeFormat is the timestamp layout embedded in hook log file names.
+// It sorts lexically in chronological order.
+const hookLogTimeFormat = "20060102T150405Z"
+
+// maxHookLogsPerHook bounds how many runs of each hook are kept on disk.
+const maxHookLogsPerHook = 5
+
+// HookLog describes a persisted lifecycle hook log file.
+type HookLog struct {
+	Hook      string    // lifecycle stage, e.g. "postCreateCommand"
+	StartedAt time.Time // when the run that produced the log started
+	Path      string
+}
+
+// hookLogDir returns the directory holding hook output logs. It is separate
+// from the hooks/ marker directory so logs survive marker resets.
+func (s *Store) hookLogDir(id string) string {
+	return filepath.Join(s.WorkspaceDir(id), "hook-logs")
+}
+
+// OpenHookLog opens the log file for a hook run for appending, creating it if
+// needed. All output of one stage within one run (including retries and
+// feature hooks) goes to the same file, keyed by hook name and startedAt.
+// Older runs beyond the retention limit are removed.
+func (s *Store) OpenHookLog(id, hookName string, startedAt time.Time) (*os.File, error) {
+	dir := s.hookLogDir(id)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating hook log directory: %w", err)
+	}
+	name := hookName + "-" + startedAt.UTC().Format(hookLogTimeFormat) + ".log"
+	f, err := os.OpenFile(filepath.Join(dir, name), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("opening hook log: %w", err)
+	}
+	s.pruneHookLogs(id, hookName)
+	return f, nil
+}
+
+// HookLogs lists persisted hook logs for a workspace, oldest first. When
+// hookName is non-empty only that hook's logs are returned.
+func (s *Store) HookLogs(id, hookName string) ([]HookLog, error) {
+	entries, err := os.ReadDir(s.hookLogDir(id))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading hook log directory: %w", err)
+	}
+
+	var logs []HookLog
+	for _, entry := range entries {
+		base, ok := strings.CutSuffix(entry.Name(), ".log")
+		if !ok || entry.IsDir() {
+			continue
+		}
+		i := strings.LastIndex(base, "-")
+		if i < 0 {
+			continue
+		}
+		startedAt, err := time.Parse(hookLogTimeFormat, base[i+1:])
+		if err != nil {
+			continue
+		}
+		hook := base[:i]
+		if hookName != "" && hook != hookName {
+			continue
+		}
+		logs = append(logs, HookLog{
+			Hook:      hook,
+			StartedAt: startedAt,
+			Path:      filepath.Join(s.hookLogDir(id), entry.Name()),
+		})
+	}
+	slices.SortStableFunc(logs, func(a, b HookLog) int { return a.StartedAt.Compare(b.StartedAt) })
+	return logs, nil
+}
+
+// pruneHookLogs removes the oldest logs for hookName beyond
+// maxHookLogsPerHook. Best-effort: errors are ignored.
+func (s *Store) pruneHookLogs(id, hookName string) {
+	logs, err := s.HookLogs(id, hookName)
+	if err != nil || len(logs) <= maxHookLogsPerHook {
+		return
+	}
+	for _, l := range logs[:len(logs)-maxHookLogsPerHook] {
+		_ = os.Remove(l.Path)
+	}
+}
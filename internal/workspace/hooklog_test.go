@@ -0,0 +1,97 @@
+package workspace
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestOpenHookLog_AppendsPerRun(t *testing.T) {
+	dir := t.TempDir()
+	store := NewStoreAt(dir)
+	started := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	// Two opens for the same run (e.g. a retry) append to one file.
+	for _, line := range []string{"first\n", "second\n"} {
+		f, err := store.OpenHookLog("ws1", "postCreateCommand", started)
+		if err != nil {
+			t.Fatalf("OpenHookLog: %v", err)
+		}
+		_, _ = f.WriteString(line)
+		_ = f.Close()
+	}
+
+	path := filepath.Join(dir, "ws1", "hook-logs", "postCreateCommand-20260501T120000Z.log")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("expected log at %s: %v", path, err)
+	}
+	if string(data) != "first\nsecond\n" {
+		t.Errorf("log = %q", data)
+	}
+}
+
+func TestHookLogs_FilterAndOrder(t *testing.T) {
+	store := NewStoreAt(t.TempDir())
+	base := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	for i, hook := range []string{"postCreateCommand", "onCreateCommand", "postCreateCommand"} {
+		f, err := store.OpenHookLog("ws1", hook, base.Add(time.Duration(i)*time.Minute))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = f.Close()
+	}
+
+	all, err := store.HookLogs("ws1", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 3 {
+		t.Fatalf("got %d logs, want 3", len(all))
+	}
+
+	logs, err := store.HookLogs("ws1", "postCreateCommand")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logs) != 2 {
+		t.Fatalf("got %d postCreateCommand logs, want 2", len(logs))
+	}
+	if !logs[0].StartedAt.Before(logs[1].StartedAt) {
+		t.Errorf("logs not oldest first: %v", logs)
+	}
+}
+
+func TestHookLogs_Missing(t *testing.T) {
+	store := NewStoreAt(t.TempDir())
+	logs, err := store.HookLogs("nope", "")
+	if err != nil || logs != nil {
+		t.Errorf("HookLogs = %v, %v; want nil, nil", logs, err)
+	}
+}
+
+func TestOpenHookLog_PrunesOldRuns(t *testing.T) {
+	store := NewStoreAt(t.TempDir())
+	base := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	for i := range maxHookLogsPerHook + 2 {
+		f, err := store.OpenHookLog("ws1", "onCreateCommand", base.Add(time.Duration(i)*time.Hour))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = f.Close()
+	}
+
+	logs, err := store.HookLogs("ws1", "onCreateCommand")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logs) != maxHookLogsPerHook {
+		t.Fatalf("kept %d logs, want %d", len(logs), maxHookLogsPerHook)
+	}
+	if want := base.Add(time.Duration(maxHookLogsPerHook+1) * time.Hour); !logs[len(logs)-1].StartedAt.Equal(want) {
+		t.Errorf("newest log = %v, want %v", logs[len(logs)-1].StartedAt, want)
+	}
+}
@@ -4,7 +4,11 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"path/filepath"
+	"slices"
 	"time"
+
+	"github.com/fgrehm/crib/internal/config"
 )
 
 // LookupOptions controls how Lookup resolves and (optionally) creates a workspace.
@@ -14,12 +18,27 @@ type LookupOptions struct {
 	Cwd       string // working directory fallback when ConfigDir and Dir are both empty
 	Version   string // crib binary version recorded in the workspace CribVersion field
 	Create    bool   // create the workspace if it does not exist in the store
+	Name      string // explicit workspace ID (from --name); overrides the derived ID
 }
 
 // Lookup resolves a workspace from the given options. It checks ConfigDir, Dir,
 // and Cwd (in that order) to locate the devcontainer config, then loads the
 // workspace from the store. If the workspace does not exist and Create is true,
 // it creates a new one. If Create is false, it returns ErrWorkspaceNotFound.
+//
+// When the project has several subfolder configs and none was picked
+// explicitly, the config used by the most recent workspace for the project is
+// reused; without one, the *config.ErrMultipleConfigs from Find is returned.
+//
+// When Name is set it is used as the workspace ID instead of the one derived
+// from the project directory. Without Name, workspaces previously created with
+// a custom name for the same project and config are found too; if several
+// match (e.g. a default and a custom-named one), the most recently used wins.
+//
+// Subfolder configs used to share the project's workspace ID (or, with
+// --config, use the .devcontainer folder as the project root). A workspace
+// stored under such a legacy ID for the same config is reused, so upgrading
+// crib does not orphan its container.
 func Lookup(store *Store, opts LookupOptions, logger *slog.Logger) (*Workspace, error) {
 	var (
 		rr  *ResolveResult
@@ -34,15 +53,45 @@ func Lookup(store *Store, opts LookupOptions, logger *slog.Logger) (*Workspace,
 	default:
 		rr, err = Resolve(opts.Cwd)
 	}
+	var multi *config.ErrMultipleConfigs
+	if errors.As(err, &multi) {
+		if prev := previousConfigChoice(store, multi); prev != nil {
+			logger.Debug("multiple devcontainer configs, reusing previous choice", "config", prev.RelativeConfigPath)
+			rr, err = prev, nil
+		}
+	}
 	if err != nil {
 		return nil, err
 	}
 
+	if opts.Name != "" {
+		if err := ValidateName(opts.Name); err != nil {
+			return nil, err
+		}
+		rr.WorkspaceID = opts.Name
+	}
+
 	ws, err := store.Load(rr.WorkspaceID)
 	if err != nil && !errors.Is(err, ErrWorkspaceNotFound) {
 		return nil, err
 	}
 
+	if opts.Name != "" && ws != nil && ws.Source != rr.ProjectRoot {
+		return nil, fmt.Errorf("workspace name %q is already used by %s", opts.Name, ws.Source)
+	}
+	if opts.Name == "" {
+		if named := findNamedForSource(store, rr, logger); named != nil && (ws == nil || named.LastUsedAt.After(ws.LastUsedAt)) {
+			logger.Debug("using custom-named workspace for this directory", "id", named.ID)
+			ws = named
+		}
+		if ws == nil {
+			if legacy := findLegacySubfolderWorkspace(store, rr); legacy != nil {
+				logger.Debug("using workspace created under the legacy subfolder config ID", "id", legacy.ID)
+				ws = legacy
+			}
+		}
+	}
+
 	if ws == nil {
 		if !opts.Create {
 			return nil, fmt.Errorf("no workspace for this directory (run 'crib up' first): %w", ErrWorkspaceNotFound)
@@ -62,6 +111,11 @@ func Lookup(store *Store, opts LookupOptions, logger *slog.Logger) (*Workspace,
 	} else {
 		// Refresh fields that may have drifted from stored state.
 		var changed bool
+		if ws.Source != rr.ProjectRoot && opts.Name == "" {
+			logger.Debug("project root changed", "old", ws.Source, "new", rr.ProjectRoot)
+			ws.Source = rr.ProjectRoot
+			changed = true
+		}
 		if ws.DevContainerPath != rr.RelativeConfigPath {
 			logger.Debug("devcontainer config path changed",
 				"old", ws.DevContainerPath, "new", rr.RelativeConfigPath)
@@ -82,3 +136,85 @@ func Lookup(store *Store, opts LookupOptions, logger *slog.Logger) (*Workspace,
 
 	return ws, nil
 }
+
+// findNamedForSource returns the most recently used workspace created with a
+// custom name (see LookupOptions.Name) for the same project root and config
+// path as rr, or nil if there is none.
+func findNamedForSource(store *Store, rr *ResolveResult, logger *slog.Logger) *Workspace {
+	ids, err := store.List()
+	if err != nil {
+		logger.Debug("listing workspaces for name lookup", "error", err)
+		return nil
+	}
+	var best *Workspace
+	for _, id := range ids {
+		if id == rr.WorkspaceID {
+			continue
+		}
+		ws, err := store.Load(id)
+		if err != nil || ws.Source != rr.ProjectRoot || ws.DevContainerPath != rr.RelativeConfigPath {
+			continue
+		}
+		if best == nil || ws.LastUsedAt.After(best.LastUsedAt) {
+			best = ws
+		}
+	}
+	return best
+}
+
+// findLegacySubfolderWorkspace returns the workspace a subfolder config
+// (.devcontainer/<name>/devcontainer.json) was stored under before subfolder
+// configs got IDs of their own, or nil. That is GenerateID of the project
+// root when the config was found by walking up, or of the .devcontainer
+// folder when it was picked with --config.
+func findLegacySubfolderWorkspace(store *Store, rr *ResolveResult) *Workspace {
+	dir := filepath.Dir(rr.RelativeConfigPath)
+	if filepath.Dir(dir) != ".devcontainer" {
+		return nil
+	}
+	devcontainerDir := filepath.Join(rr.ProjectRoot, ".devcontainer")
+	legacy := []struct{ source, configPath string }{
+		{rr.ProjectRoot, rr.RelativeConfigPath},
+		{devcontainerDir, filepath.Join(filepath.Base(dir), "devcontainer.json")},
+	}
+	for _, l := range legacy {
+		ws, err := store.Load(GenerateID(l.source))
+		if err != nil || ws.Source != l.source || ws.DevContainerPath != l.configPath {
+			continue
+		}
+		return ws
+	}
+	return nil
+}
+
+// previousConfigChoice returns a ResolveResult for the config used by the most
+// recently used workspace of multi.Dir, provided that config is still one of
+// the candidates. Returns nil if there is no such workspace.
+func previousConfigChoice(store *Store, multi *config.ErrMultipleConfigs) *ResolveResult {
+	ids, err := store.List()
+	if err != nil {
+		return nil
+	}
+	var best *Workspace
+	for _, id := range ids {
+		ws, err := store.Load(id)
+		if err != nil || ws.Source != multi.Dir {
+			continue
+		}
+		if !slices.Contains(multi.Candidates, filepath.Join(multi.Dir, ws.DevContainerPath)) {
+			continue
+		}
+		if best == nil || ws.LastUsedAt.After(best.LastUsedAt) {
+			best = ws
+		}
+	}
+	if best == nil {
+		return nil
+	}
+	return &ResolveResult{
+		ProjectRoot:        multi.Dir,
+		ConfigPath:         filepath.Join(multi.Dir, best.DevContainerPath),
+		RelativeConfigPath: best.DevContainerPath,
+		WorkspaceID:        configID(multi.Dir, best.DevContainerPath),
+	}
+}
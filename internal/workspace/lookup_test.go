@@ -6,6 +6,8 @@ import (
 	"path/filepath"
 	"testing"
 	"time"
+
+	"github.com/fgrehm/crib/internal/config"
 )
 
 func TestLookup_CwdResolvesWorkspace(t *testing.T) {
@@ -144,3 +146,200 @@ func TestLookup_RefreshesCribVersion(t *testing.T) {
 		t.Errorf("CribVersion = %q, want %q", ws.CribVersion, "v1.0.0")
 	}
 }
+
+func newNamedLookupDir(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	mkdirAll(t, filepath.Join(dir, ".devcontainer"))
+	writeFile(t, filepath.Join(dir, ".devcontainer", "devcontainer.json"), `{"image":"alpine"}`)
+	return dir
+}
+
+func TestLookup_Name_CreatesWithExplicitID(t *testing.T) {
+	dir := newNamedLookupDir(t)
+	store := NewStoreAt(t.TempDir())
+
+	ws, err := Lookup(store, LookupOptions{Cwd: dir, Name: "api", Create: true}, slog.Default())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ws.ID != "api" {
+		t.Errorf("ID = %q, want api", ws.ID)
+	}
+	if !store.Exists("api") {
+		t.Error("expected workspace to be persisted under its name")
+	}
+	if store.Exists(GenerateID(dir)) {
+		t.Error("default workspace should not be created when --name is given")
+	}
+}
+
+func TestLookup_Name_Invalid(t *testing.T) {
+	dir := newNamedLookupDir(t)
+	store := NewStoreAt(t.TempDir())
+
+	if _, err := Lookup(store, LookupOptions{Cwd: dir, Name: "Bad Name", Create: true}, slog.Default()); err == nil {
+		t.Fatal("expected error for invalid name")
+	}
+}
+
+func TestLookup_Name_UsedByOtherProject(t *testing.T) {
+	store := NewStoreAt(t.TempDir())
+	if _, err := Lookup(store, LookupOptions{Cwd: newNamedLookupDir(t), Name: "api", Create: true}, slog.Default()); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := Lookup(store, LookupOptions{Cwd: newNamedLookupDir(t), Name: "api", Create: true}, slog.Default())
+	if err == nil {
+		t.Fatal("expected error when the name belongs to another project")
+	}
+}
+
+func TestLookup_NoName_FindsNamedWorkspace(t *testing.T) {
+	dir := newNamedLookupDir(t)
+	store := NewStoreAt(t.TempDir())
+	if _, err := Lookup(store, LookupOptions{Cwd: dir, Name: "api", Create: true}, slog.Default()); err != nil {
+		t.Fatal(err)
+	}
+
+	ws, err := Lookup(store, LookupOptions{Cwd: dir, Create: false}, slog.Default())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ws.ID != "api" {
+		t.Errorf("ID = %q, want api (custom-named workspace for this directory)", ws.ID)
+	}
+}
+
+func TestLookup_NoName_DefaultAndNamed_MostRecentWins(t *testing.T) {
+	dir := newNamedLookupDir(t)
+	store := NewStoreAt(t.TempDir())
+	rel := filepath.Join(".devcontainer", "devcontainer.json")
+	old := time.Now().Add(-time.Hour)
+
+	for _, ws := range []*Workspace{
+		{ID: GenerateID(dir), Source: dir, DevContainerPath: rel, LastUsedAt: time.Now()},
+		{ID: "api", Source: dir, DevContainerPath: rel, LastUsedAt: old},
+	} {
+		if err := store.Save(ws); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ws, err := Lookup(store, LookupOptions{Cwd: dir}, slog.Default())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ws.ID != GenerateID(dir) {
+		t.Errorf("ID = %q, want default (more recently used)", ws.ID)
+	}
+
+	// Explicit --name still selects the custom workspace.
+	ws, err = Lookup(store, LookupOptions{Cwd: dir, Name: "api"}, slog.Default())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ws.ID != "api" {
+		t.Errorf("ID = %q, want api", ws.ID)
+	}
+}
+
+func TestLookup_NoName_IgnoresNamedWorkspaceForOtherConfig(t *testing.T) {
+	dir := newNamedLookupDir(t)
+	store := NewStoreAt(t.TempDir())
+	if err := store.Save(&Workspace{ID: "other", Source: dir, DevContainerPath: "other/devcontainer.json", LastUsedAt: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Lookup(store, LookupOptions{Cwd: dir}, slog.Default()); !errors.Is(err, ErrWorkspaceNotFound) {
+		t.Errorf("expected ErrWorkspaceNotFound, got %v", err)
+	}
+}
+
+func TestLookup_MultipleConfigs(t *testing.T) {
+	dir := t.TempDir()
+	for _, sub := range []string{"go", "python"} {
+		mkdirAll(t, filepath.Join(dir, ".devcontainer", sub))
+		writeFile(t, filepath.Join(dir, ".devcontainer", sub, "devcontainer.json"), `{"image":"alpine"}`)
+	}
+	store := NewStoreAt(t.TempDir())
+
+	// Nothing picked yet: the ambiguity is surfaced.
+	_, err := Lookup(store, LookupOptions{Cwd: dir, Create: true}, slog.Default())
+	var multi *config.ErrMultipleConfigs
+	if !errors.As(err, &multi) {
+		t.Fatalf("expected *config.ErrMultipleConfigs, got %v", err)
+	}
+	if len(multi.Candidates) != 2 {
+		t.Errorf("Candidates = %v, want 2 entries", multi.Candidates)
+	}
+
+	// Picking one explicitly creates the workspace for the project root.
+	picked, err := Lookup(store, LookupOptions{ConfigDir: filepath.Join(dir, ".devcontainer", "python"), Create: true}, slog.Default())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if picked.Source != dir {
+		t.Errorf("Source = %q, want %q", picked.Source, dir)
+	}
+
+	// Later lookups from the project directory reuse that choice.
+	ws, err := Lookup(store, LookupOptions{Cwd: dir}, slog.Default())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ws.ID != picked.ID {
+		t.Errorf("ID = %q, want %q", ws.ID, picked.ID)
+	}
+	if want := filepath.Join(".devcontainer", "python", "devcontainer.json"); ws.DevContainerPath != want {
+		t.Errorf("DevContainerPath = %q, want %q", ws.DevContainerPath, want)
+	}
+
+	// The other config gets a workspace of its own.
+	other, err := Lookup(store, LookupOptions{ConfigDir: filepath.Join(dir, ".devcontainer", "go"), Create: true}, slog.Default())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if other.ID == picked.ID {
+		t.Errorf("both configs share workspace ID %q", other.ID)
+	}
+}
+
+func TestLookup_SubfolderConfig_ReusesLegacyID(t *testing.T) {
+	dir := t.TempDir()
+	cfgDir := filepath.Join(dir, ".devcontainer", "python")
+	mkdirAll(t, cfgDir)
+	writeFile(t, filepath.Join(cfgDir, "devcontainer.json"), `{"image":"alpine"}`)
+	relPath := filepath.Join(".devcontainer", "python", "devcontainer.json")
+
+	tests := []struct {
+		name   string
+		legacy *Workspace
+	}{
+		{"walk-up", &Workspace{ID: GenerateID(dir), Source: dir, DevContainerPath: relPath}},
+		{"config dir", &Workspace{
+			ID:               GenerateID(filepath.Join(dir, ".devcontainer")),
+			Source:           filepath.Join(dir, ".devcontainer"),
+			DevContainerPath: filepath.Join("python", "devcontainer.json"),
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewStoreAt(t.TempDir())
+			if err := store.Save(tt.legacy); err != nil {
+				t.Fatal(err)
+			}
+
+			ws, err := Lookup(store, LookupOptions{ConfigDir: cfgDir}, slog.Default())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if ws.ID != tt.legacy.ID {
+				t.Errorf("ID = %q, want legacy ID %q", ws.ID, tt.legacy.ID)
+			}
+			if ws.Source != dir || ws.DevContainerPath != relPath {
+				t.Errorf("Source, DevContainerPath = %q, %q; want %q, %q", ws.Source, ws.DevContainerPath, dir, relPath)
+			}
+		})
+	}
+}
@@ -43,10 +43,10 @@ func Resolve(startDir string) (*ResolveResult, error) {
 	dir := absDir
 	for {
 		configPath, err := config.Find(dir)
-		if err != nil {
+		if err != nil && !errors.Is(err, config.ErrNotFound) {
 			return nil, fmt.Errorf("searching for devcontainer config: %w", err)
 		}
-		if configPath != "" {
+		if err == nil {
 			relPath, err := filepath.Rel(dir, configPath)
 			if err != nil {
 				return nil, fmt.Errorf("computing relative config path: %w", err)
@@ -55,7 +55,7 @@ func Resolve(startDir string) (*ResolveResult, error) {
 				ProjectRoot:        dir,
 				ConfigPath:         configPath,
 				RelativeConfigPath: relPath,
-				WorkspaceID:        GenerateID(dir),
+				WorkspaceID:        configID(dir, relPath),
 			}, nil
 		}
 
@@ -70,7 +70,10 @@ func Resolve(startDir string) (*ResolveResult, error) {
 
 // ResolveConfigDir resolves workspace info when the devcontainer config directory
 // is explicitly given (bypasses the walk-up). The config directory must contain
-// a devcontainer.json directly. The project root is the parent of configDir.
+// a devcontainer.json directly, or be a project root with a .devcontainer.json.
+// The project root is the parent of configDir, or the parent of .devcontainer/
+// for subfolder configs such as .devcontainer/python, or configDir itself for
+// a root-level .devcontainer.json.
 func ResolveConfigDir(configDir string) (*ResolveResult, error) {
 	absDir, err := filepath.Abs(configDir)
 	if err != nil {
@@ -78,13 +81,20 @@ func ResolveConfigDir(configDir string) (*ResolveResult, error) {
 	}
 
 	configPath := filepath.Join(absDir, "devcontainer.json")
+	projectRoot := filepath.Dir(absDir)
+	if filepath.Base(projectRoot) == ".devcontainer" {
+		projectRoot = filepath.Dir(projectRoot)
+	}
 	if _, err := os.Stat(configPath); os.IsNotExist(err) {
-		return nil, fmt.Errorf("no devcontainer.json found in %s: %w", absDir, ErrNoDevContainer)
+		rootConfig := filepath.Join(absDir, ".devcontainer.json")
+		if _, err := os.Stat(rootConfig); err != nil {
+			return nil, fmt.Errorf("no devcontainer.json found in %s: %w", absDir, ErrNoDevContainer)
+		}
+		configPath = rootConfig
+		projectRoot = absDir
 	} else if err != nil {
 		return nil, fmt.Errorf("checking devcontainer.json: %w", err)
 	}
-
-	projectRoot := filepath.Dir(absDir)
 	relPath, err := filepath.Rel(projectRoot, configPath)
 	if err != nil {
 		return nil, fmt.Errorf("computing relative config path: %w", err)
@@ -94,18 +104,48 @@ func ResolveConfigDir(configDir string) (*ResolveResult, error) {
 		ProjectRoot:        projectRoot,
 		ConfigPath:         configPath,
 		RelativeConfigPath: relPath,
-		WorkspaceID:        GenerateID(projectRoot),
+		WorkspaceID:        configID(projectRoot, relPath),
 	}, nil
 }
 
 var nonAlphanumeric = regexp.MustCompile(`[^a-z0-9-]+`)
 
+// validName matches a workspace name: lowercase letters, digits, and hyphens,
+// starting and ending with a letter or digit, at most 48 characters.
+var validName = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,46}[a-z0-9])?$`)
+
+// ValidateName checks that name is usable as a workspace ID (it ends up in
+// container names, compose project names, and store paths).
+func ValidateName(name string) error {
+	if !validName.MatchString(name) {
+		return fmt.Errorf("invalid workspace name %q: use lowercase letters, digits, and hyphens (max 48 chars, no leading or trailing hyphen)", name)
+	}
+	return nil
+}
+
 // GenerateID creates a workspace ID from the project root's absolute path.
 // Format: {slugified-basename}-{7-char-sha256-of-full-path}.
 // The hash suffix guarantees uniqueness across directories with the same name.
 func GenerateID(projectRoot string) string {
-	slug := Slugify(filepath.Base(projectRoot))
-	hash := fmt.Sprintf("%x", sha256.Sum256([]byte(projectRoot)))[:7]
+	return generateID(filepath.Base(projectRoot), projectRoot)
+}
+
+// configID returns the workspace ID for the config at relConfigPath in
+// projectRoot. Subfolder configs (.devcontainer/<name>/devcontainer.json)
+// get an ID of their own, {slug}-{name}-{hash}, so several configs of one
+// project don't share a container; other configs use GenerateID.
+func configID(projectRoot, relConfigPath string) string {
+	dir := filepath.Dir(relConfigPath)
+	if filepath.Dir(dir) != ".devcontainer" {
+		return GenerateID(projectRoot)
+	}
+	return generateID(filepath.Base(projectRoot)+"-"+filepath.Base(dir), filepath.Join(projectRoot, dir))
+}
+
+// generateID slugifies name and appends a hash of key.
+func generateID(name, key string) string {
+	slug := Slugify(name)
+	hash := fmt.Sprintf("%x", sha256.Sum256([]byte(key)))[:7]
 
 	const maxLen = 48
 	const hashSuffixLen = 8 // "-" + 7 hex chars
@@ -238,6 +238,61 @@ func TestResolveConfigDir_UsesGenerateID(t *testing.T) {
 	}
 }
 
+func TestResolveConfigDir_SubfolderConfig(t *testing.T) {
+	dir := t.TempDir()
+	configDir := filepath.Join(dir, ".devcontainer", "python")
+	mkdirAll(t, configDir)
+	writeFile(t, filepath.Join(configDir, "devcontainer.json"), `{"image":"python"}`)
+
+	result, err := ResolveConfigDir(configDir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.ProjectRoot != dir {
+		t.Errorf("ProjectRoot = %q, want %q", result.ProjectRoot, dir)
+	}
+	if want := filepath.Join(".devcontainer", "python", "devcontainer.json"); result.RelativeConfigPath != want {
+		t.Errorf("RelativeConfigPath = %q, want %q", result.RelativeConfigPath, want)
+	}
+	if result.WorkspaceID == GenerateID(dir) {
+		t.Errorf("WorkspaceID = %q, want an ID of its own, not the project root's", result.WorkspaceID)
+	}
+	if !strings.Contains(result.WorkspaceID, "-python-") {
+		t.Errorf("WorkspaceID = %q, want it to name the python config", result.WorkspaceID)
+	}
+}
+
+func TestResolveConfigDir_DotDevContainerJSON(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, ".devcontainer.json"), `{"image":"ubuntu"}`)
+
+	result, err := ResolveConfigDir(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.ProjectRoot != dir {
+		t.Errorf("ProjectRoot = %q, want %q", result.ProjectRoot, dir)
+	}
+	if result.RelativeConfigPath != ".devcontainer.json" {
+		t.Errorf("RelativeConfigPath = %q, want %q", result.RelativeConfigPath, ".devcontainer.json")
+	}
+}
+
+func TestResolve_PrefersDevContainerDir(t *testing.T) {
+	dir := t.TempDir()
+	mkdirAll(t, filepath.Join(dir, ".devcontainer"))
+	writeFile(t, filepath.Join(dir, ".devcontainer", "devcontainer.json"), `{"image":"ubuntu"}`)
+	writeFile(t, filepath.Join(dir, ".devcontainer.json"), `{"image":"other"}`)
+
+	result, err := Resolve(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := filepath.Join(".devcontainer", "devcontainer.json"); result.RelativeConfigPath != want {
+		t.Errorf("RelativeConfigPath = %q, want %q", result.RelativeConfigPath, want)
+	}
+}
+
 // slugHash returns the first 7 chars of the sha256 hex hash of name.
 func slugHash(name string) string {
 	slug := Slugify(name)
@@ -260,3 +315,18 @@ func writeFile(t *testing.T, path string, content string) {
 		t.Fatal(err)
 	}
 }
+
+func TestValidateName(t *testing.T) {
+	valid := []string{"api", "my-app", "a", "web2", strings.Repeat("a", 48)}
+	for _, name := range valid {
+		if err := ValidateName(name); err != nil {
+			t.Errorf("ValidateName(%q) = %v, want nil", name, err)
+		}
+	}
+	invalid := []string{"", "API", "my app", "-api", "api-", "a_b", "a/b", strings.Repeat("a", 49)}
+	for _, name := range invalid {
+		if err := ValidateName(name); err == nil {
+			t.Errorf("ValidateName(%q) = nil, want error", name)
+		}
+	}
+}
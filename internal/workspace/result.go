@@ -19,6 +19,10 @@ type Result struct {
 	// callers should fall back to crib-<ws-id> when it's empty.
 	ContainerName string `json:"containerName,omitempty"`
 
+	// Name is the devcontainer.json "name" property, shown next to the
+	// workspace ID in crib list and crib status. Empty when unset.
+	Name string `json:"name,omitempty"`
+
 	// ImageName is the name of the built/pulled image.
 	ImageName string `json:"imageName"`
 
@@ -57,6 +61,12 @@ type Result struct {
 	// invisible to devcontainer.json config comparison.
 	ComposeFilesHash string `json:"composeFilesHash,omitempty"`
 
+	// HookOutput holds the tail of each lifecycle stage's combined
+	// stdout/stderr from the last time it ran, keyed by stage name (e.g.
+	// "postCreateCommand"). Bounded so the result file stays small; the
+	// full output is in the hook logs.
+	HookOutput map[string]string `json:"hookOutput,omitempty"`
+
 	// Feature lifecycle hooks, stored so the resume/restart path can dispatch
 	// them without re-resolving features from OCI registries. These are the
 	// hooks declared in devcontainer-feature.json files, NOT the user's hooks
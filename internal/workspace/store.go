@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/gofrs/flock"
@@ -175,13 +176,14 @@ func (s *Store) LoadResult(id string) (*Result, error) {
 }
 
 // MarkHookDone records that a lifecycle hook has been executed for a workspace.
-func (s *Store) MarkHookDone(id, hookName string) error {
+// hash identifies the hook content that ran; see HookDoneHash.
+func (s *Store) MarkHookDone(id, hookName, hash string) error {
 	dir := filepath.Join(s.WorkspaceDir(id), "hooks")
 	if err := os.MkdirAll(dir, 0o755); err != nil {
 		return fmt.Errorf("creating hooks directory: %w", err)
 	}
 	path := filepath.Join(dir, hookName+".done")
-	if err := os.WriteFile(path, nil, 0o644); err != nil {
+	if err := os.WriteFile(path, []byte(hash), 0o644); err != nil {
 		return fmt.Errorf("writing hook marker: %w", err)
 	}
 	return nil
@@ -194,6 +196,17 @@ func (s *Store) IsHookDone(id, hookName string) bool {
 	return err == nil
 }
 
+// HookDoneHash returns the content hash recorded when a lifecycle hook was
+// marked done. Returns "" when there is no marker or the marker predates
+// content hashes.
+func (s *Store) HookDoneHash(id, hookName string) string {
+	data, err := os.ReadFile(filepath.Join(s.WorkspaceDir(id), "hooks", hookName+".done"))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
 // ClearHookMarkers removes all lifecycle hook markers for a workspace,
 // allowing hooks to run again (used on recreate).
 func (s *Store) ClearHookMarkers(id string) error {
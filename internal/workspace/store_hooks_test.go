@@ -10,7 +10,7 @@ func TestMarkHookDone(t *testing.T) {
 	dir := t.TempDir()
 	store := NewStoreAt(dir)
 
-	if err := store.MarkHookDone("ws1", "onCreateCommand"); err != nil {
+	if err := store.MarkHookDone("ws1", "onCreateCommand", ""); err != nil {
 		t.Fatalf("MarkHookDone: %v", err)
 	}
 
@@ -29,7 +29,7 @@ func TestIsHookDone(t *testing.T) {
 		t.Fatal("expected IsHookDone to return false before marking")
 	}
 
-	if err := store.MarkHookDone("ws1", "onCreateCommand"); err != nil {
+	if err := store.MarkHookDone("ws1", "onCreateCommand", ""); err != nil {
 		t.Fatalf("MarkHookDone: %v", err)
 	}
 
@@ -54,7 +54,7 @@ func TestClearHookMarkers(t *testing.T) {
 
 	// Mark several hooks.
 	for _, hook := range []string{"onCreateCommand", "updateContentCommand", "postCreateCommand"} {
-		if err := store.MarkHookDone("ws1", hook); err != nil {
+		if err := store.MarkHookDone("ws1", hook, ""); err != nil {
 			t.Fatalf("MarkHookDone(%s): %v", hook, err)
 		}
 	}
@@ -79,3 +79,17 @@ func TestClearHookMarkersNoDir(t *testing.T) {
 		t.Fatalf("ClearHookMarkers on missing dir: %v", err)
 	}
 }
+
+func TestHookDoneHash(t *testing.T) {
+	store := NewStoreAt(t.TempDir())
+
+	if got := store.HookDoneHash("ws1", "onCreateCommand"); got != "" {
+		t.Errorf("HookDoneHash without marker = %q, want empty", got)
+	}
+	if err := store.MarkHookDone("ws1", "onCreateCommand", "abc123"); err != nil {
+		t.Fatal(err)
+	}
+	if got := store.HookDoneHash("ws1", "onCreateCommand"); got != "abc123" {
+		t.Errorf("HookDoneHash = %q, want %q", got, "abc123")
+	}
+}
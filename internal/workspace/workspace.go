@@ -23,4 +23,9 @@ type Workspace struct {
 
 	// LastUsedAt is when this workspace was last accessed.
 	LastUsedAt time.Time `json:"lastUsedAt"`
+
+	// TrustedSettings are the dangerous config settings (privileged mode,
+	// capabilities, runtime socket mounts) the user has allowed for this
+	// workspace, as described by the engine's trust check.
+	TrustedSettings []string `json:"trustedSettings,omitempty"`
 }